@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/lf-edge/eden/pkg/openevec"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newApplyCmd(configName, verbosity *string) *cobra.Command {
+	cfg := &openevec.EdenSetupArgs{}
+	var file string
+	var dryRun, prune bool
+
+	var applyCmd = &cobra.Command{
+		Use:   "apply -f <manifest.yaml>",
+		Short: "apply manifest with desired state of edge node",
+		Long: `Apply manifest with desired state of edge node: apps, network instances, volumes, datastores,
+base OS, config items and device model. Only changes against the current state are sent to controller,
+so the command may be repeated safely.`,
+		PersistentPreRunE: preRunViperLoadFunction(cfg, configName, verbosity),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdenApply(file, dryRun, prune, cfg); err != nil {
+				log.Fatalf("apply failed: %s", err)
+			}
+		},
+	}
+
+	applyCmd.Flags().StringVarP(&file, "file", "f", "", "manifest file, '-' to read from stdin")
+	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print changes without applying them")
+	applyCmd.Flags().BoolVar(&prune, "prune", false, "delete apps, network instances and volumes not defined in manifest and config items removed from it")
+	_ = applyCmd.MarkFlagRequired("file")
+
+	return applyCmd
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"github.com/lf-edge/eden/pkg/openevec"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newEdgeNodeAttest() *cobra.Command {
+	var attestCmd = &cobra.Command{
+		Use:   "attest",
+		Short: "inspect measured boot of EVE",
+		Long:  `Inspect TPM event log of EVE, generate PCR templates and run attestation scenarios.`,
+	}
+
+	attestCmd.AddCommand(newAttestEventLog())
+	attestCmd.AddCommand(newAttestPCRs())
+	attestCmd.AddCommand(newAttestTemplate())
+	attestCmd.AddCommand(newAttestScenario())
+	return attestCmd
+}
+
+func newAttestEventLog() *cobra.Command {
+	return &cobra.Command{
+		Use:   "event-log",
+		Short: "print TPM event log received from EVE",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdenAttestEventLog(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+}
+
+func newAttestPCRs() *cobra.Command {
+	var algo string
+
+	var pcrsCmd = &cobra.Command{
+		Use:   "pcrs",
+		Short: "compute PCR values from TPM event log of EVE",
+		Long:  `Compute PCR values replaying TPM event log of EVE and compare them with received PCR template.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdenAttestPCRs(algo); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	pcrsCmd.Flags().StringVar(&algo, "algo", "sha256", "hash bank to compute [sha1|sha256|sha512]")
+	return pcrsCmd
+}
+
+func newAttestTemplate() *cobra.Command {
+	var pcrs []uint
+	var fromEventLog, apply, enforce bool
+	var algo, file string
+
+	var templateCmd = &cobra.Command{
+		Use:   "template",
+		Short: "generate PCR template from EVE",
+		Long:  `Generate PCR template from template or TPM event log received from EVE and optionally set it into controller.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdenAttestTemplate(pcrs, fromEventLog, algo, apply, enforce, file); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	templateCmd.Flags().UintSliceVar(&pcrs, "pcr", nil, "PCR indexes to include, all if empty")
+	templateCmd.Flags().BoolVar(&fromEventLog, "from-event-log", false, "compute PCR values from event log instead of received template")
+	templateCmd.Flags().StringVar(&algo, "algo", "sha256", "hash bank to compute with --from-event-log [sha1|sha256|sha512]")
+	templateCmd.Flags().BoolVar(&apply, "apply", false, "set template into controller")
+	templateCmd.Flags().BoolVar(&enforce, "enforce", true, "enforce template attestation with --apply")
+	templateCmd.Flags().StringVar(&file, "file", "", "save template to file")
+	return templateCmd
+}
+
+func newAttestScenario() *cobra.Command {
+	return &cobra.Command{
+		Use:   "scenario <file>...",
+		Short: "run attestation scenarios",
+		Long: `Run pass/fail attestation scenarios from YAML files.
+Scenarios with simulated device are verified locally, others change templates in controller
+and wait for attestation result of EVE.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdenAttestScenario(args); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+}
@@ -27,6 +27,7 @@ func newControllerCmd(configName, verbosity *string) *cobra.Command {
 		{
 			Message: "Basic Commands",
 			Commands: []*cobra.Command{
+				newEdgeNodeList(),
 				newEdgeNodeReboot(controllerMode),
 				newEdgeNodeShutdown(controllerMode),
 				newEdgeNodeEVEImageUpdate(controllerMode, cfg),
@@ -35,8 +36,10 @@ func newControllerCmd(configName, verbosity *string) *cobra.Command {
 				newEdgeNodeUpdate(controllerMode),
 				newEdgeNodeGetConfig(controllerMode),
 				newEdgeNodeSetConfig(),
+				newEdgeNodeConfig(),
 				newEdgeNodeGetOptions(controllerMode),
 				newEdgeNodeSetOptions(controllerMode),
+				newEdgeNodeAttest(),
 			},
 		},
 	}
@@ -53,6 +56,20 @@ func newControllerCmd(configName, verbosity *string) *cobra.Command {
 	return controllerCmd
 }
 
+func newEdgeNodeList() *cobra.Command {
+	var edgeNodeList = &cobra.Command{
+		Use:   "list",
+		Short: "list EVE instances",
+		Long:  `List EVE instances known by controller. Use name or UUID from the list with --node flag to select instance.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdgeNodeList(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	return edgeNodeList
+}
+
 func newEdgeNodeReboot(controllerMode string) *cobra.Command {
 	var edgeNodeReboot = &cobra.Command{
 		Use:   "reboot",
@@ -166,6 +183,50 @@ Supported keys: global_profile,local_profile_server,profile_server_token`
 	return edgeNodeUpdate
 }
 
+func newEdgeNodeConfig() *cobra.Command {
+	var edgeNodeConfig = &cobra.Command{
+		Use:   "config",
+		Short: "work with history of EVE configs",
+		Long:  `Work with history of configs submitted into controller for EVE.`,
+	}
+
+	edgeNodeConfig.AddCommand(newEdgeNodeConfigHistory())
+	edgeNodeConfig.AddCommand(newEdgeNodeConfigDiff())
+
+	return edgeNodeConfig
+}
+
+func newEdgeNodeConfigHistory() *cobra.Command {
+	var edgeNodeConfigHistory = &cobra.Command{
+		Use:   "history",
+		Short: "list versions of EVE config",
+		Long:  `List versions of configs submitted into controller for EVE.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdgeNodeConfigHistory(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	return edgeNodeConfigHistory
+}
+
+func newEdgeNodeConfigDiff() *cobra.Command {
+	var edgeNodeConfigDiff = &cobra.Command{
+		Use:   "diff [v1] [v2]",
+		Short: "show changes between versions of EVE config",
+		Long: `Show added, removed and modified apps, volumes, network instances, datastores and config items
+between versions of EVE config. Without arguments two latest versions are compared,
+with one argument the version is compared with the latest one.`,
+		Args: cobra.MaximumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdgeNodeConfigDiff(args); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	return edgeNodeConfigDiff
+}
+
 func newEdgeNodeGetOptions(controllerMode string) *cobra.Command {
 	var fileWithConfig string
 
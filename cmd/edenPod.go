@@ -78,7 +78,7 @@ func newPodDeployCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
 	var pc openevec.PodConfig
 
 	var podDeployCmd = &cobra.Command{
-		Use:   "deploy (docker|http(s)|file|directory)://(<TAG|PATH>[:<VERSION>] | <URL for qcow2 image> | <path to qcow2 image>)",
+		Use:   "deploy (docker|http(s)|file|directory|s3|azure)://(<TAG|PATH>[:<VERSION>] | <URL for qcow2 image> | <path to qcow2 image> | <BUCKET>/<KEY or path to qcow2 image>)",
 		Short: "Deploy app in pod",
 		Long:  `Deploy app in pod.`,
 		Args:  cobra.ExactArgs(1),
@@ -52,6 +52,8 @@ func newStartCmd(configName, verbosity *string) *cobra.Command {
 	startCmd.Flags().IntVarP(&cfg.Eden.EServer.Port, "eserver-port", "", defaults.DefaultEserverPort, "eserver port")
 	startCmd.Flags().StringVarP(&cfg.Eden.EServer.Tag, "eserver-tag", "", defaults.DefaultEServerTag, "tag of eserver container to pull")
 	startCmd.Flags().BoolVarP(&cfg.Eden.EServer.Force, "eserver-force", "", cfg.Eden.EServer.Force, "eserver force rebuild")
+	startCmd.Flags().BoolVarP(&cfg.Eden.EServer.TLS, "eserver-tls", "", cfg.Eden.EServer.TLS, "serve https with eden certificates")
+	startCmd.Flags().BoolVarP(&cfg.Eden.EServer.Registry, "eserver-registry", "", cfg.Eden.EServer.Registry, "serve images with eserver instead of registry container")
 
 	startCmd.Flags().IntVarP(&cfg.Eve.QemuCpus, "cpus", "", defaults.DefaultCpus, "cpus count")
 	startCmd.Flags().IntVarP(&cfg.Eve.QemuMemory, "memory", "", defaults.DefaultMemory, "memory size (MB)")
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/eden"
@@ -27,6 +28,28 @@ func newEserverCmd(configName, verbosity *string) *cobra.Command {
 				newStatusEserverCmd(cfg),
 			},
 		},
+		{
+			Message: "Storage Commands",
+			Commands: []*cobra.Command{
+				newListEserverCmd(cfg),
+				newRemoveEserverCmd(cfg),
+				newPinEserverCmd(cfg),
+				newGCEserverCmd(cfg),
+				newSignEserverCmd(cfg),
+			},
+		},
+		{
+			Message: "Registry Commands",
+			Commands: []*cobra.Command{
+				newOCIEserverCmd(cfg),
+			},
+		},
+		{
+			Message: "Fault Injection Commands",
+			Commands: []*cobra.Command{
+				newFaultEserverCmd(cfg),
+			},
+		},
 	}
 
 	groups.AddTo(eserverCmd)
@@ -47,10 +70,8 @@ func newStartEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
 			}
 			log.Infof("Executable path: %s", command)
 
-			if err := eden.StartEServer(cfg.Eden.EServer.Port, cfg.Eden.Images.EServerImageDist, cfg.Eden.EServer.Force, cfg.Eden.EServer.Tag); err != nil {
-				log.Errorf("cannot start eserver: %s", err)
-			} else {
-				log.Infof("Eserver is running and accesible on port %d", cfg.Eden.EServer.Port)
+			if err := openevec.StartEServer(*cfg); err != nil {
+				log.Error(err)
 			}
 		},
 	}
@@ -59,6 +80,8 @@ func newStartEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
 	startEserverCmd.Flags().IntVarP(&cfg.Eden.EServer.Port, "eserver-port", "", defaults.DefaultEserverPort, "eserver port")
 	startEserverCmd.Flags().StringVarP(&cfg.Eden.EServer.Tag, "eserver-tag", "", defaults.DefaultEServerTag, "tag of eserver container to pull")
 	startEserverCmd.Flags().BoolVarP(&cfg.Eden.EServer.Force, "eserver-force", "", false, "eserver force rebuild")
+	startEserverCmd.Flags().BoolVarP(&cfg.Eden.EServer.TLS, "eserver-tls", "", false, "serve https with eden certificates")
+	startEserverCmd.Flags().BoolVarP(&cfg.Eden.EServer.Registry, "eserver-registry", "", false, "serve images with OCI distribution API")
 
 	return startEserverCmd
 }
@@ -98,3 +121,246 @@ func newStatusEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
 	}
 	return statusEserverCmd
 }
+
+func newListEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var listEserverCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "list files in eserver",
+		Long:  `List files stored in eserver.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EServerList(cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	return listEserverCmd
+}
+
+func newRemoveEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sha256 string
+
+	var removeEserverCmd = &cobra.Command{
+		Use:   "rm [name]...",
+		Short: "remove files from eserver",
+		Long:  `Remove files from eserver by names or all names of content with sha256.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 && sha256 == "" {
+				log.Fatal("name or --sha256 required")
+			}
+			if err := openevec.EServerRemove(cfg, args, sha256); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	removeEserverCmd.Flags().StringVar(&sha256, "sha256", "", "remove all names of content with sha256")
+
+	return removeEserverCmd
+}
+
+func newPinEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var unpin bool
+
+	var pinEserverCmd = &cobra.Command{
+		Use:   "pin <name>...",
+		Short: "protect files in eserver from garbage collection",
+		Long:  `Protect content of files in eserver from garbage collection.`,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EServerPin(cfg, args, !unpin); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	pinEserverCmd.Flags().BoolVar(&unpin, "unpin", false, "remove protection")
+
+	return pinEserverCmd
+}
+
+func newGCEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var maxSize string
+	var partial, dryRun bool
+
+	var gcEserverCmd = &cobra.Command{
+		Use:   "gc",
+		Short: "collect garbage in eserver",
+		Long: `Collect garbage in eserver: remove content without names and the least recently used
+not pinned content until total size fits into --max-size.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EServerGC(cfg, maxSize, partial, dryRun); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	gcEserverCmd.Flags().StringVar(&maxSize, "max-size", "", "max total size of content (e.g. 20GB), not limited if empty")
+	gcEserverCmd.Flags().BoolVar(&partial, "partial", false, "remove partially downloaded files")
+	gcEserverCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show what will be removed")
+
+	return gcEserverCmd
+}
+
+func newSignEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var expires time.Duration
+
+	var signEserverCmd = &cobra.Command{
+		Use:   "sign <name>",
+		Short: "print signed URL of file in eserver",
+		Long:  `Print URL of file in eserver signed with sign-key from config, the URL allows download without credentials.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EServerSign(cfg, args[0], expires); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	signEserverCmd.Flags().DurationVar(&expires, "expires", time.Hour, "validity of signature")
+
+	return signEserverCmd
+}
+
+func newFaultEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var faultEserverCmd = &cobra.Command{
+		Use:   "fault",
+		Short: "inject faults into downloads from eserver",
+		Long:  `Inject faults into downloads from eserver to test retry and verification logic of clients.`,
+	}
+
+	faultEserverCmd.AddCommand(newFaultSetEserverCmd(cfg))
+	faultEserverCmd.AddCommand(newFaultListEserverCmd(cfg))
+	faultEserverCmd.AddCommand(newFaultClearEserverCmd(cfg))
+
+	return faultEserverCmd
+}
+
+func newFaultSetEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	args := &openevec.EServerFaultArgs{}
+
+	var faultSetEserverCmd = &cobra.Command{
+		Use:   "set <name>",
+		Short: "inject fault into downloads of file",
+		Long: `Inject fault into downloads of file with name or names matched pattern (e.g. 'apps/*').
+Replaces fault set before for the same name and resets its counters.`,
+		Example: `  eden eserver fault set disk.qcow2 --rate 1MB
+  eden eserver fault set disk.qcow2 --drop-after 10MB --count 2
+  eden eserver fault set '*' --status 503 --every 3`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, names []string) {
+			if err := openevec.EServerFaultSet(cfg, names[0], args); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	faultSetEserverCmd.Flags().StringVar(&args.Rate, "rate", "", "limit bandwidth to size per second (e.g. 100KB)")
+	faultSetEserverCmd.Flags().BoolVar(&args.Stall, "stall", false, "stop sending data")
+	faultSetEserverCmd.Flags().StringVar(&args.StallAfter, "stall-after", "", "stall after sending of size (e.g. 1MB)")
+	faultSetEserverCmd.Flags().DurationVar(&args.StallFor, "stall-for", 0, "duration of stall, until client disconnects if 0")
+	faultSetEserverCmd.Flags().StringVar(&args.DropAfter, "drop-after", "", "close connection after sending of size (e.g. 1MB)")
+	faultSetEserverCmd.Flags().IntVar(&args.Status, "status", 0, "return HTTP status code instead of file (e.g. 503 or 404)")
+	faultSetEserverCmd.Flags().BoolVar(&args.Corrupt, "corrupt", false, "corrupt sent data")
+	faultSetEserverCmd.Flags().IntVar(&args.Every, "every", 0, "inject fault into each n-th request only")
+	faultSetEserverCmd.Flags().IntVar(&args.Count, "count", 0, "inject fault into n requests only, not limited if 0")
+
+	return faultSetEserverCmd
+}
+
+func newFaultListEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var faultListEserverCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "list faults injected into downloads",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EServerFaultList(cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	return faultListEserverCmd
+}
+
+func newFaultClearEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var faultClearEserverCmd = &cobra.Command{
+		Use:   "clear [name]",
+		Short: "remove fault or all faults",
+		Long:  `Remove fault set for name (or pattern) or all faults if name is not provided.`,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			if err := openevec.EServerFaultClear(cfg, name); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	return faultClearEserverCmd
+}
+
+func newOCIEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var ociEserverCmd = &cobra.Command{
+		Use:   "oci",
+		Short: "manage images served by eserver",
+		Long:  `Manage images served by eserver with OCI distribution API (eden.eserver.registry in config).`,
+	}
+
+	ociEserverCmd.AddCommand(newOCIImportEserverCmd(cfg))
+	ociEserverCmd.AddCommand(newOCILoadEserverCmd(cfg))
+	ociEserverCmd.AddCommand(newOCIListEserverCmd(cfg))
+
+	return ociEserverCmd
+}
+
+func newOCIImportEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var name string
+
+	var ociImportEserverCmd = &cobra.Command{
+		Use:   "import <archive>",
+		Short: "import images from archive",
+		Long:  `Import images from archive created by 'docker save' or from OCI layout archive.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EServerOCIImport(cfg, args[0], name); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	ociImportEserverCmd.Flags().StringVar(&name, "name", "", "name (repository:tag) of image in archive with single image")
+
+	return ociImportEserverCmd
+}
+
+func newOCILoadEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var ociLoadEserverCmd = &cobra.Command{
+		Use:   "load <image>",
+		Short: "load image into eserver",
+		Long:  `Load image into eserver from local docker image cache or pull it from remote registry.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EServerOCILoad(cfg, args[0]); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	return ociLoadEserverCmd
+}
+
+func newOCIListEserverCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var ociListEserverCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "list images in eserver",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EServerOCIList(cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	return ociListEserverCmd
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/lf-edge/eden/pkg/openevec"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newEventsCmd() *cobra.Command {
+	var rulesFile string
+	var webhooks, sockets, types []string
+	var allDevices bool
+
+	var eventsCmd = &cobra.Command{
+		Use:   "events",
+		Short: "Watch state change events of EVE devices",
+		Long: `
+Derives typed events (app.state, app.error, baseos.activated, network-instance.error,
+device.reboot, device.state, volume.state) from the info reports of EVE devices,
+prints them and delivers them as JSON to HTTP webhooks or unix sockets.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdenEvents(rulesFile, webhooks, sockets, types, allDevices); err != nil {
+				log.Fatalf("Events failed: %s", err)
+			}
+		},
+	}
+
+	eventsCmd.Flags().StringVar(&rulesFile, "rules", "", "YAML file with routing rules of events")
+	eventsCmd.Flags().StringSliceVar(&webhooks, "webhook", nil, "URL to post events to")
+	eventsCmd.Flags().StringSliceVar(&sockets, "socket", nil, "Unix socket to write events to")
+	eventsCmd.Flags().StringSliceVar(&types, "type", nil, "Types of events to print and send with --webhook and --socket, all if empty")
+	eventsCmd.Flags().BoolVar(&allDevices, "all", false, "Watch events of all devices known by controller")
+	return eventsCmd
+}
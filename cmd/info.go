@@ -13,13 +13,19 @@ func newInfoCmd() *cobra.Command {
 	var infoTail uint
 	var follow bool
 	var printFields []string
+	var since, until, cursor string
+	var where string
 
 	var infoCmd = &cobra.Command{
 		Use:   "info [field:regexp ...]",
 		Short: "Get information reports from a running EVE device",
 		Long:  ` Scans the ADAM Info for correspondence with regular expressions requests to json fields.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := openevec.EdenInfo(outputFormat, infoTail, follow, printFields, args); err != nil {
+			rng, err := parseRange(since, until, cursor)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := openevec.EdenInfo(outputFormat, infoTail, follow, rng, where, printFields, args); err != nil {
 				log.Fatal("Eden info failed ", err)
 			}
 		},
@@ -28,6 +34,8 @@ func newInfoCmd() *cobra.Command {
 	infoCmd.Flags().UintVar(&infoTail, "tail", 0, "Show only last N lines")
 	infoCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Monitor changes in selected directory")
 	infoCmd.Flags().StringSliceVarP(&printFields, "out", "o", nil, "Fields to print. Whole message if empty.")
+	addRangeFlags(infoCmd, &since, &until, &cursor)
+	infoCmd.Flags().StringVar(&where, "where", "", "Filter by expression, i.e. 'dinfo.ncpu > 2 and not exists(ainfo)'")
 
 	infoCmd.Flags().Var(
 		enumflag.New(&outputFormat, "format", outputFormatIds, enumflag.EnumCaseInsensitive),
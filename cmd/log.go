@@ -3,6 +3,7 @@ package cmd
 import (
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/openevec"
+	"github.com/lf-edge/eden/pkg/utils"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/thediveo/enumflag"
@@ -13,18 +14,43 @@ var outputFormatIds = map[types.OutputFormat][]string{
 	types.OutputFormatJSON:  {"json"},
 }
 
+// addRangeFlags adds flags to define time range and cursor for processing of existing objects
+func addRangeFlags(cmd *cobra.Command, since, until, cursor *string) {
+	cmd.Flags().StringVar(since, "since", "", "Show objects received since time (RFC3339) or duration ago (i.e. 10m)")
+	cmd.Flags().StringVar(until, "until", "", "Show objects received before time (RFC3339) or duration ago (i.e. 5m)")
+	cmd.Flags().StringVar(cursor, "cursor", "", "Continue from the cursor printed by the previous call with --since, --until or --cursor")
+}
+
+// parseRange returns LoaderRange from flags defined by addRangeFlags
+func parseRange(since, until, cursor string) (rng types.LoaderRange, err error) {
+	if rng.Since, err = utils.ParseTimeOrDuration(since); err != nil {
+		return rng, err
+	}
+	if rng.Until, err = utils.ParseTimeOrDuration(until); err != nil {
+		return rng, err
+	}
+	rng.Cursor = cursor
+	return rng, nil
+}
+
 func newLogCmd() *cobra.Command {
 	var outputFormat types.OutputFormat
 	var follow bool
 	var printFields []string
 	var logTail uint
+	var since, until, cursor string
+	var where string
 
 	var logCmd = &cobra.Command{
 		Use:   "log [field:regexp ...]",
 		Short: "Get logs from a running EVE device",
 		Long:  ` Scans the ADAM logs for correspondence with regular expressions requests to json fields.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := openevec.EdenLog(outputFormat, follow, logTail, printFields, args); err != nil {
+			rng, err := parseRange(since, until, cursor)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := openevec.EdenLog(outputFormat, follow, logTail, rng, where, printFields, args); err != nil {
 				log.Fatalf("Log eden failed: %s", err)
 			}
 		},
@@ -33,6 +59,8 @@ func newLogCmd() *cobra.Command {
 	logCmd.Flags().UintVar(&logTail, "tail", 0, "Show only last N lines")
 	logCmd.Flags().StringSliceVarP(&printFields, "out", "o", nil, "Fields to print. Whole message if empty.")
 	logCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Monitor changes in selected directory")
+	addRangeFlags(logCmd, &since, &until, &cursor)
+	logCmd.Flags().StringVar(&where, "where", "", "Filter by expression, i.e. 'severity == error or source =~ ^pillar'")
 
 	logCmd.Flags().Var(
 		enumflag.New(&outputFormat, "format", outputFormatIds, enumflag.EnumCaseInsensitive),
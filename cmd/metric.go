@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/openevec"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -14,6 +17,8 @@ func newMetricCmd(configName, verbosity *string) *cobra.Command {
 	var follow bool
 	var printFields []string
 	var metricTail uint
+	var since, until, cursor string
+	var where string
 
 	var metricCmd = &cobra.Command{
 		Use:   "metric [field:regexp ...]",
@@ -22,7 +27,11 @@ func newMetricCmd(configName, verbosity *string) *cobra.Command {
 Scans the ADAM metrics for correspondence with regular expressions requests to json fields.`,
 		PersistentPreRunE: preRunViperLoadFunction(cfg, configName, verbosity),
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := openevec.EdenMetric(cfg, outputFormat, follow, metricTail, printFields, args); err != nil {
+			rng, err := parseRange(since, until, cursor)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := openevec.EdenMetric(cfg, outputFormat, follow, metricTail, rng, where, printFields, args); err != nil {
 				log.Fatalf("Metric eden failed: %s", err)
 			}
 		},
@@ -31,10 +40,35 @@ Scans the ADAM metrics for correspondence with regular expressions requests to j
 	metricCmd.Flags().UintVar(&metricTail, "tail", 0, "Show only last N lines")
 	metricCmd.Flags().StringSliceVarP(&printFields, "out", "o", nil, "Fields to print. Whole message if empty.")
 	metricCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Monitor changes in selected metrics")
+	addRangeFlags(metricCmd, &since, &until, &cursor)
+	metricCmd.Flags().StringVar(&where, "where", "", "Filter by expression, i.e. 'dm.memory.usedMem > 500'")
 
 	metricCmd.Flags().Var(
 		enumflag.New(&outputFormat, "format", outputFormatIds, enumflag.EnumCaseInsensitive),
 		"format",
 		"Format to print logs, supports: lines, json")
+	metricCmd.AddCommand(newMetricExportCmd())
 	return metricCmd
 }
+
+func newMetricExportCmd() *cobra.Command {
+	var listen, where string
+	var allDevices bool
+
+	var metricExportCmd = &cobra.Command{
+		Use:   "export [field:regexp ...]",
+		Short: "Export metrics from EVE devices in OpenMetrics format",
+		Long: `
+Serves the last metrics received from EVE devices on http://<listen>/metrics to be scraped by Prometheus.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.EdenMetricExport(listen, allDevices, where, args); err != nil {
+				log.Fatalf("Metric export failed: %s", err)
+			}
+		},
+	}
+
+	metricExportCmd.Flags().StringVar(&listen, "listen", fmt.Sprintf(":%d", defaults.DefaultMetricExportPort), "Address to serve metrics on")
+	metricExportCmd.Flags().BoolVar(&allDevices, "all", false, "Export metrics of all devices known by controller")
+	metricExportCmd.Flags().StringVar(&where, "where", "", "Filter by expression, i.e. 'exists(dm)'")
+	return metricExportCmd
+}
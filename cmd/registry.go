@@ -98,7 +98,7 @@ func newLoadRegistryCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
 		Args: cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			ref := args[0]
-			if err := openevec.RegistryLoad(ref, &cfg.Registry); err != nil {
+			if err := openevec.RegistryLoad(ref, cfg); err != nil {
 				log.Fatalf("Load registry failed %s", err)
 			}
 		},
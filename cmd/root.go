@@ -13,13 +13,16 @@ import (
 )
 
 func NewEdenCommand() *cobra.Command {
-	var configName, verbosity string
+	var configName, verbosity, node, record string
 
 	rootCmd := &cobra.Command{
 		Use: "eden",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return openevec.SetUpLogs(verbosity)
 		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return openevec.CloseTelemetryArchive()
+		},
 	}
 
 	groups := CommandGroups{
@@ -35,6 +38,7 @@ func NewEdenCommand() *cobra.Command {
 				newCleanCmd(&configName, &verbosity),
 				newConfigCmd(&configName, &verbosity),
 				newSdnCmd(&configName, &verbosity),
+				newApplyCmd(&configName, &verbosity),
 			},
 		},
 		{
@@ -44,6 +48,7 @@ func NewEdenCommand() *cobra.Command {
 				newLogCmd(),
 				newNetStatCmd(&configName, &verbosity),
 				newMetricCmd(&configName, &verbosity),
+				newEventsCmd(),
 				newAdamCmd(&configName, &verbosity),
 				newRegistryCmd(&configName, &verbosity),
 				newRedisCmd(&configName, &verbosity),
@@ -64,6 +69,17 @@ func NewEdenCommand() *cobra.Command {
 
 	rootCmd.PersistentFlags().StringVar(&configName, "config", defaults.DefaultContext, "Name of config")
 	rootCmd.PersistentFlags().StringVarP(&verbosity, "verbosity", "v", log.InfoLevel.String(), "Log level (debug, info, warn, error, fatal, panic")
+	rootCmd.PersistentFlags().StringVar(&node, "node", "", "Name or UUID of edge node to work with, current one if empty")
+	rootCmd.PersistentFlags().StringVar(&record, "record", "", "Record telemetry obtained from controller into archive file")
+
+	cobra.OnInitialize(func() {
+		if err := openevec.SelectNode(node); err != nil {
+			log.Fatalf("cannot select node %s: %s", node, err)
+		}
+		if err := openevec.RecordTelemetry(record); err != nil {
+			log.Fatalf("cannot record telemetry into %s: %s", record, err)
+		}
+	})
 
 	return rootCmd
 }
@@ -36,6 +36,10 @@ func newSdnCmd(configName, verbosity *string) *cobra.Command {
 				newSdnMgmtIPCmd(cfg),
 				newSdnEndpointCmd(cfg),
 				newSdnFwdCmd(cfg),
+				newSdnImpairCmd(cfg),
+				newSdnPortalCmd(cfg),
+				newSdnCaptureCmd(cfg),
+				newSdnScenarioCmd(cfg),
 			},
 		},
 	}
@@ -288,6 +292,244 @@ This is currently limited to TCP port forwarding (i.e. not working with UDP)!`,
 	return sdnFwdCmd
 }
 
+func newSdnImpairCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnImpairCmd = &cobra.Command{
+		Use:   "impair",
+		Short: "Emulate poor connectivity by impairing links in Eden-SDN",
+		Long: `Emulate poor connectivity (e.g. cellular or satellite uplinks) by adding delay,
+jitter, packet loss, duplication, reordering and rate limit to traffic of a port, bridge
+or network of the running Eden-SDN. Impairment is applied to traffic in both directions.
+It can be also declared in the network model (see Impairment in sdn/vm/api/netModel.go).`,
+	}
+
+	groups := CommandGroups{
+		{
+			Message: "Basic Commands",
+			Commands: []*cobra.Command{
+				newSdnImpairSetCmd(cfg),
+				newSdnImpairClearCmd(cfg),
+				newSdnImpairListCmd(cfg),
+			},
+		},
+	}
+
+	groups.AddTo(sdnImpairCmd)
+
+	return sdnImpairCmd
+}
+
+func newSdnImpairSetCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var impairArgs openevec.SdnImpairArgs
+
+	var sdnImpairSetCmd = &cobra.Command{
+		Use:   "set <port|bridge|network> <logical-label>",
+		Short: "Set impairment of a port, bridge or network",
+		Long: `Set impairment of a port, bridge or network, replacing the previous one.
+Impairment of a bridge applies to all bridged ports without their own impairment.
+Impairment of a network applies on top of impairment of its bridge and ports.
+For example, to emulate satellite uplink for all networks of bridge "bridge0":
+	eden sdn impair set bridge bridge0 --delay 300ms --jitter 20ms --loss 0.5 --rate 2mbit`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnImpairSet(args[0], args[1], &impairArgs, cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnImpairSetCmd, cfg)
+	sdnImpairSetCmd.Flags().DurationVar(&impairArgs.Delay, "delay", 0, "latency added to every packet")
+	sdnImpairSetCmd.Flags().DurationVar(&impairArgs.Jitter, "jitter", 0, "random variation of the delay")
+	sdnImpairSetCmd.Flags().Float32Var(&impairArgs.Loss, "loss", 0, "percentage of packets to drop")
+	sdnImpairSetCmd.Flags().Float32Var(&impairArgs.Duplicate, "duplicate", 0, "percentage of packets to duplicate")
+	sdnImpairSetCmd.Flags().Float32Var(&impairArgs.Reorder, "reorder", 0, "percentage of packets to send without delay (out of order)")
+	sdnImpairSetCmd.Flags().StringVar(&impairArgs.Rate, "rate", "", "bandwidth limit (e.g. 512kbit, 10mbit)")
+
+	return sdnImpairSetCmd
+}
+
+func newSdnImpairClearCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnImpairClearCmd = &cobra.Command{
+		Use:   "clear <port|bridge|network> <logical-label>",
+		Short: "Remove impairment of a port, bridge or network",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnImpairClear(args[0], args[1], cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnImpairClearCmd, cfg)
+
+	return sdnImpairClearCmd
+}
+
+func newSdnImpairListCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnImpairListCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "List impairments of ports, bridges and networks",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnImpairList(cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnImpairListCmd, cfg)
+
+	return sdnImpairListCmd
+}
+
+func newSdnCaptureCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var captureArgs openevec.SdnCaptureArgs
+
+	var sdnCaptureCmd = &cobra.Command{
+		Use:   "capture <port|network|endpoint> <logical-label>",
+		Short: "Capture packets of a port, network or endpoint of Eden-SDN",
+		Long: `Capture packets of a port, network or endpoint of the running Eden-SDN
+and save them into a file in the pcapng format (readable by Wireshark, tcpdump, etc.).
+Capture runs until interrupted (Ctrl-C), unless limited with --duration or --count.
+For example, to capture DHCP traffic of network "net0" for one minute:
+	eden sdn capture network net0 --filter "udp port 67 or udp port 68" --duration 1m -o dhcp.pcapng
+Use "eden sdn capture ring" to capture packets in the background and save them later,
+e.g. only if a test fails.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnCapture(args[0], args[1], &captureArgs, cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnCaptureCmd, cfg)
+	addSdnCaptureOpts(sdnCaptureCmd, &captureArgs)
+	sdnCaptureCmd.Flags().StringVarP(&captureArgs.Output, "output", "o", "", "file to write captured packets into (\"-\" for stdout)")
+	sdnCaptureCmd.Flags().Uint64Var(&captureArgs.Count, "count", 0, "stop after capturing this many packets")
+	sdnCaptureCmd.Flags().DurationVar(&captureArgs.Duration, "duration", 0, "stop capture after this time")
+	_ = sdnCaptureCmd.MarkFlagRequired("output")
+
+	groups := CommandGroups{
+		{
+			Message: "Basic Commands",
+			Commands: []*cobra.Command{
+				newSdnRingCaptureCmd(cfg),
+			},
+		},
+	}
+
+	groups.AddTo(sdnCaptureCmd)
+
+	return sdnCaptureCmd
+}
+
+func newSdnRingCaptureCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnRingCaptureCmd = &cobra.Command{
+		Use:   "ring",
+		Short: "Capture packets in the background, keeping only the most recent ones",
+	}
+
+	groups := CommandGroups{
+		{
+			Message: "Basic Commands",
+			Commands: []*cobra.Command{
+				newSdnRingCaptureStartCmd(cfg),
+				newSdnRingCaptureSaveCmd(cfg),
+				newSdnRingCaptureStopCmd(cfg),
+				newSdnRingCaptureListCmd(cfg),
+			},
+		},
+	}
+
+	groups.AddTo(sdnRingCaptureCmd)
+
+	return sdnRingCaptureCmd
+}
+
+func newSdnRingCaptureStartCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var captureArgs openevec.SdnCaptureArgs
+
+	var sdnRingCaptureStartCmd = &cobra.Command{
+		Use:   "start <port|network|endpoint> <logical-label>",
+		Short: "Start ring capture on a port, network or endpoint",
+		Long: `Start ring capture on a port, network or endpoint, replacing the previous one.
+Only the most recently captured packets are kept, up to the given size.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnRingCaptureStart(args[0], args[1], &captureArgs, cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnRingCaptureStartCmd, cfg)
+	addSdnCaptureOpts(sdnRingCaptureStartCmd, &captureArgs)
+	sdnRingCaptureStartCmd.Flags().StringVar(&captureArgs.RingSize, "size", "10MB", "maximum size of buffered packets")
+	sdnRingCaptureStartCmd.Flags().Uint64Var(&captureArgs.Count, "count", 0, "stop capturing after this many packets, keeping them buffered")
+	sdnRingCaptureStartCmd.Flags().DurationVar(&captureArgs.Duration, "duration", 0, "stop capturing after this time, keeping captured packets buffered")
+
+	return sdnRingCaptureStartCmd
+}
+
+func newSdnRingCaptureSaveCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var output string
+
+	var sdnRingCaptureSaveCmd = &cobra.Command{
+		Use:   "save <port|network|endpoint> <logical-label>",
+		Short: "Save packets buffered by ring capture in the pcapng format",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnRingCaptureSave(args[0], args[1], output, cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnRingCaptureSaveCmd, cfg)
+	sdnRingCaptureSaveCmd.Flags().StringVarP(&output, "output", "o", "", "file to write captured packets into (\"-\" for stdout)")
+	_ = sdnRingCaptureSaveCmd.MarkFlagRequired("output")
+
+	return sdnRingCaptureSaveCmd
+}
+
+func newSdnRingCaptureStopCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnRingCaptureStopCmd = &cobra.Command{
+		Use:   "stop <port|network|endpoint> <logical-label>",
+		Short: "Stop ring capture and discard buffered packets",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnRingCaptureStop(args[0], args[1], cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnRingCaptureStopCmd, cfg)
+
+	return sdnRingCaptureStopCmd
+}
+
+func newSdnRingCaptureListCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnRingCaptureListCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "List ring captures",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnRingCaptureList(cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnRingCaptureListCmd, cfg)
+
+	return sdnRingCaptureListCmd
+}
+
+func addSdnCaptureOpts(parentCmd *cobra.Command, captureArgs *openevec.SdnCaptureArgs) {
+	parentCmd.Flags().StringVar(&captureArgs.Filter, "filter", "", "capture filter in the pcap-filter syntax (e.g. \"tcp port 443\")")
+	parentCmd.Flags().Uint32Var(&captureArgs.SnapLen, "snaplen", 0, "maximum number of bytes captured from each packet (0 for entire packets)")
+}
+
 func addSdnPidOpt(parentCmd *cobra.Command, cfg *openevec.EdenSetupArgs) {
 	currentPath, err := os.Getwd()
 	if err != nil {
@@ -338,3 +580,148 @@ func addSdnConfigDirOpt(parentCmd *cobra.Command, cfg *openevec.EdenSetupArgs) {
 func addSdnLinuxkitOpt(parentCmd *cobra.Command, cfg *openevec.EdenSetupArgs) {
 	parentCmd.Flags().StringVarP(&cfg.Sdn.LinuxkitBin, "sdn-linuxkit-bin", "", "", "path to linuxkit binary used to build SDN VM")
 }
+
+func newSdnPortalCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnPortalCmd = &cobra.Command{
+		Use:   "portal",
+		Short: "Manage clients of captive portals in Eden-SDN",
+		Long: `Manage clients of captive portals emulated by Eden-SDN
+(see CaptivePortal in sdn/vm/api/endpoints.go).
+Clients (e.g. EVE) are referenced by IP addresses from networks with the portal attached.
+Alternatively, a client can be logged in by submitting the login form, for example
+from inside of an Eden-SDN endpoint:
+	eden sdn endpoint exec <endpoint> -- curl -d client=<client-ip> http://<portal-ip>/login`,
+	}
+
+	groups := CommandGroups{
+		{
+			Message: "Basic Commands",
+			Commands: []*cobra.Command{
+				newSdnPortalLoginCmd(cfg),
+				newSdnPortalLogoutCmd(cfg),
+				newSdnPortalListCmd(cfg),
+			},
+		},
+	}
+
+	groups.AddTo(sdnPortalCmd)
+
+	return sdnPortalCmd
+}
+
+func newSdnPortalLoginCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnPortalLoginCmd = &cobra.Command{
+		Use:   "login <portal> <client-ip>",
+		Short: "Log client in through the captive portal",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnPortalLogin(args[0], args[1], cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnPortalLoginCmd, cfg)
+
+	return sdnPortalLoginCmd
+}
+
+func newSdnPortalLogoutCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnPortalLogoutCmd = &cobra.Command{
+		Use:   "logout <portal> <client-ip>",
+		Short: "Log client out from the captive portal",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnPortalLogout(args[0], args[1], cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnPortalLogoutCmd, cfg)
+
+	return sdnPortalLogoutCmd
+}
+
+func newSdnPortalListCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnPortalListCmd = &cobra.Command{
+		Use:   "ls",
+		Short: "List clients logged in through captive portals",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnPortalList(cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnPortalListCmd, cfg)
+
+	return sdnPortalListCmd
+}
+
+func newSdnScenarioCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var sdnScenarioCmd = &cobra.Command{
+		Use:   "scenario",
+		Short: "Run scenarios of timed network model changes in Eden-SDN",
+		Long: `Run scenarios of timed network model changes in Eden-SDN.
+Scenario file contains one step per line, formatted as "t=<offset> <action> [<args>...]",
+where offset is the time since the scenario start. Supported actions:
+	port <logical-label> up|down : change the administrative state of a port
+	apply <model-file>           : replace the network model with the model from the file
+	patch <patch-file>           : modify the network model using a JSON merge patch (RFC 7386)
+For example:
+	t=30s port eth1 down
+	t=60s patch proxy-rules.json
+	t=90s port eth1 up`,
+	}
+
+	groups := CommandGroups{
+		{
+			Message: "Basic Commands",
+			Commands: []*cobra.Command{
+				newSdnScenarioRunCmd(cfg),
+				newSdnScenarioCheckCmd(),
+			},
+		},
+	}
+
+	groups.AddTo(sdnScenarioCmd)
+
+	return sdnScenarioCmd
+}
+
+func newSdnScenarioRunCmd(cfg *openevec.EdenSetupArgs) *cobra.Command {
+	var reportFile string
+
+	var sdnScenarioRunCmd = &cobra.Command{
+		Use:   "run <scenario-file>",
+		Short: "Run scenario and print (or save) the timeline report",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnScenarioRun(args[0], reportFile, cfg); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	addSdnPortOpts(sdnScenarioRunCmd, cfg)
+	sdnScenarioRunCmd.Flags().StringVar(&reportFile, "report", "",
+		"file to save the timeline report into (JSON if the file has the .json extension)")
+
+	return sdnScenarioRunCmd
+}
+
+func newSdnScenarioCheckCmd() *cobra.Command {
+	var sdnScenarioCheckCmd = &cobra.Command{
+		Use:   "check <scenario-file>",
+		Short: "Parse scenario and print its steps without running them",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := openevec.SdnScenarioCheck(args[0]); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	return sdnScenarioCheckCmd
+}
@@ -1,21 +1,146 @@
 package api
 
+import "time"
+
 //URLArg is packet to send into eserver for downloading of external file
 type URLArg struct {
 	//URL contains link to file
 	URL string `json:"url,omitempty"`
+	//Sha256 is expected checksum of file, not checked if empty
+	Sha256 string `json:"sha256,omitempty"`
 }
 
+//FileStatus is the state of file in eserver
+type FileStatus string
+
+const (
+	//FileStatusDownloading means that file is downloading or waiting for the next attempt
+	FileStatusDownloading FileStatus = "downloading"
+	//FileStatusReady means that file is ready to serve
+	FileStatusReady FileStatus = "ready"
+	//FileStatusFailed means that download failed and will not be retried
+	FileStatusFailed FileStatus = "failed"
+)
+
 //FileInfo contains information about downloading or downloaded file
 type FileInfo struct {
 	//Sha256 of file
 	Sha256 string `json:"sha256,omitempty"`
-	//Size of file in bytes
+	//Size of file in bytes, bytes done for downloading file
 	Size int64 `json:"size,omitempty"`
+	//Total is expected size of downloading file in bytes, 0 if unknown
+	Total int64 `json:"total,omitempty"`
 	//FileName is link for access file
 	FileName string `json:"filename,omitempty"`
 	//ISReady indicates status of image
 	ISReady bool `json:"ready"`
+	//Status of file, empty for unknown file
+	Status FileStatus `json:"status,omitempty"`
+	//Attempts is count of download attempts done
+	Attempts int `json:"attempts,omitempty"`
 	//Error contains errors
 	Error string `json:"error,omitempty"`
 }
+
+//FileEntry describes file stored in eserver
+type FileEntry struct {
+	//Name is alias of content used to access file
+	Name string `json:"name,omitempty"`
+	//Sha256 of content
+	Sha256 string `json:"sha256"`
+	//Size of content in bytes
+	Size int64 `json:"size"`
+	//URL file was downloaded from, empty for uploaded files
+	URL string `json:"url,omitempty"`
+	//Pinned content is never removed by garbage collection
+	Pinned bool `json:"pinned,omitempty"`
+	//LastAccess is the last time content was added or served
+	LastAccess time.Time `json:"lastAccess"`
+}
+
+//DeleteArg is packet to send into eserver to delete file by name or all names of content with sha256
+type DeleteArg struct {
+	Name   string `json:"name,omitempty"`
+	Sha256 string `json:"sha256,omitempty"`
+}
+
+//PinArg is packet to send into eserver to pin or unpin content of file
+type PinArg struct {
+	Name   string `json:"name"`
+	Pinned bool   `json:"pinned"`
+}
+
+//GCArg is packet to send into eserver to collect garbage
+type GCArg struct {
+	//MaxSize removes the least recently used not pinned content until total size fits, not limited if 0
+	MaxSize int64 `json:"maxSize,omitempty"`
+	//Partial removes partially downloaded files which are not downloading now
+	Partial bool `json:"partial,omitempty"`
+	//DryRun only reports what will be removed
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+//GCResult is result of garbage collection
+type GCResult struct {
+	//Removed contains removed names and content without names
+	Removed []*FileEntry `json:"removed,omitempty"`
+	//Freed is count of freed bytes
+	Freed int64 `json:"freed"`
+	//Total is size of content after garbage collection
+	Total int64 `json:"total"`
+}
+
+//SignArg is packet to send into eserver to get signed path to file
+type SignArg struct {
+	Name string `json:"name"`
+	//Expires is validity of signature in seconds
+	Expires int64 `json:"expires"`
+}
+
+//SignResult contains signed path to file
+type SignResult struct {
+	//Path is relative to eserver root, i.e. eserver/<name>?expires=<unix time>&signature=<hmac>
+	Path    string    `json:"path"`
+	Expires time.Time `json:"expires"`
+}
+
+//Fault describes misbehavior of eserver on download of files to test clients
+type Fault struct {
+	//Name of file or pattern of names (see path.Match) the fault applies to
+	Name string `json:"name"`
+	//Rate limits bandwidth in bytes per second, not limited if 0
+	Rate int64 `json:"rate,omitempty"`
+	//Stall stops sending of data after StallAfter bytes for StallForMs milliseconds
+	Stall      bool  `json:"stall,omitempty"`
+	StallAfter int64 `json:"stallAfter,omitempty"`
+	//StallForMs is duration of stall in milliseconds, stall lasts until client disconnects if 0
+	StallForMs int64 `json:"stallForMs,omitempty"`
+	//DropAfter closes connection after sending of bytes, not closed if 0
+	DropAfter int64 `json:"dropAfter,omitempty"`
+	//Status is HTTP status code returned instead of file (e.g. 503 or 404)
+	Status int `json:"status,omitempty"`
+	//Corrupt flips bytes of sent data, so checksum verification fails
+	Corrupt bool `json:"corrupt,omitempty"`
+	//Every applies fault to each Every-th request only, to every request if 0 or 1
+	Every int `json:"every,omitempty"`
+	//Count limits number of requests the fault applied to, not limited if 0
+	Count int `json:"count,omitempty"`
+	//Requests is count of requests matched the fault, filled by eserver
+	Requests int `json:"requests,omitempty"`
+	//Injected is count of requests the fault applied to, filled by eserver
+	Injected int `json:"injected,omitempty"`
+}
+
+//FaultClearArg is packet to send into eserver to remove fault by name or all faults if name is empty
+type FaultClearArg struct {
+	Name string `json:"name,omitempty"`
+}
+
+//OCIImage describes tag of image stored in eserver
+type OCIImage struct {
+	//Repository of image without registry host, e.g. library/alpine
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	//Digest of manifest or index of image
+	Digest string `json:"digest"`
+}
@@ -18,6 +18,18 @@ var (
 	serverSFTPUser     string
 	serverSFTPPassword string
 	serverSFTPReadOnly bool
+	serverMaxSize      int64
+
+	serverCertFile         string
+	serverKeyFile          string
+	serverAdminToken       string
+	serverAdminUser        string
+	serverAdminPassword    string
+	serverDownloadUser     string
+	serverDownloadPassword string
+	serverSignKey          string
+	serverSignedOnly       bool
+	serverRegistry         bool
 )
 
 var serverCmd = &cobra.Command{
@@ -31,7 +43,18 @@ var serverCmd = &cobra.Command{
 			User:     serverSFTPUser,
 			Password: serverSFTPPassword,
 			ReadOnly: serverSFTPReadOnly,
-			Manager:  &manager.EServerManager{Dir: serverDir},
+			Manager:  &manager.EServerManager{Dir: serverDir, MaxSize: serverMaxSize},
+
+			CertFile:         serverCertFile,
+			KeyFile:          serverKeyFile,
+			AdminToken:       serverAdminToken,
+			AdminUser:        serverAdminUser,
+			AdminPassword:    serverAdminPassword,
+			DownloadUser:     serverDownloadUser,
+			DownloadPassword: serverDownloadPassword,
+			SignKey:          serverSignKey,
+			SignedOnly:       serverSignedOnly,
+			Registry:         serverRegistry,
 		}
 		server.Start()
 	},
@@ -44,4 +67,15 @@ func serverInit() {
 	serverCmd.Flags().StringVar(&serverSFTPUser, "user", "user", "user for sftp")
 	serverCmd.Flags().StringVar(&serverSFTPPassword, "password", "password", "password for sftp")
 	serverCmd.Flags().BoolVar(&serverSFTPReadOnly, "readonly", true, "Read only access via sftp")
+	serverCmd.Flags().StringVar(&serverCertFile, "cert", "", "certificate file to serve https on the same port, plain http is served too")
+	serverCmd.Flags().StringVar(&serverKeyFile, "key", "", "private key file of certificate")
+	serverCmd.Flags().StringVar(&serverAdminToken, "admin-token", "", "bearer token to access /admin endpoints")
+	serverCmd.Flags().StringVar(&serverAdminUser, "admin-user", "", "user to access /admin endpoints with basic auth")
+	serverCmd.Flags().StringVar(&serverAdminPassword, "admin-password", "", "password to access /admin endpoints with basic auth")
+	serverCmd.Flags().StringVar(&serverDownloadUser, "download-user", "", "user to download files with basic auth")
+	serverCmd.Flags().StringVar(&serverDownloadPassword, "download-password", "", "password to download files with basic auth")
+	serverCmd.Flags().StringVar(&serverSignKey, "sign-key", "", "key to sign URLs of files")
+	serverCmd.Flags().BoolVar(&serverSignedOnly, "signed-only", false, "download files only with signed URLs or credentials")
+	serverCmd.Flags().BoolVar(&serverRegistry, "registry", false, "serve stored images with OCI distribution API on /v2")
+	serverCmd.Flags().Int64Var(&serverMaxSize, "max-size", 0, "Max total size of files in bytes, the least recently used are removed, not limited if 0")
 }
@@ -0,0 +1,293 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eden/eserver/api"
+)
+
+const (
+	downloadAttempts   = 10
+	downloadBackoff    = time.Second
+	downloadMaxBackoff = time.Minute
+)
+
+// errPermanent marks errors which will not be fixed by the next attempt
+type errPermanent struct {
+	err error
+}
+
+func (e *errPermanent) Error() string { return e.err.Error() }
+
+func (e *errPermanent) Unwrap() error { return e.err }
+
+func permanent(format string, a ...interface{}) error {
+	return &errPermanent{err: fmt.Errorf(format, a...)}
+}
+
+// download stores state of file downloading from url
+type download struct {
+	sync.Mutex
+	url      string
+	sha256   string
+	done     int64
+	total    int64
+	attempts int
+	err      error
+	finished bool
+	// commit stores downloaded file
+	commit func(tmpPath, sum string) error
+	// validator is ETag or Last-Modified of the first response to check
+	// that resumed download continues the same content
+	validator string
+}
+
+func (d *download) fileInfo() *api.FileInfo {
+	d.Lock()
+	defer d.Unlock()
+	result := &api.FileInfo{
+		Size:     d.done,
+		Total:    d.total,
+		Attempts: d.attempts,
+		Status:   api.FileStatusDownloading,
+	}
+	if d.finished && d.err != nil {
+		result.Status = api.FileStatusFailed
+	}
+	if d.err != nil {
+		result.Error = d.err.Error()
+	}
+	return result
+}
+
+func (d *download) setProgress(done, total int64) {
+	if total < 0 {
+		total = 0
+	}
+	d.Lock()
+	d.done, d.total = done, total
+	d.Unlock()
+}
+
+// progressWriter tracks bytes written into file
+type progressWriter struct {
+	d     *download
+	done  int64
+	total int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.done += int64(len(p))
+	w.d.setProgress(w.done, w.total)
+	return len(p), nil
+}
+
+var downloadClient = &http.Client{
+	Transport: func() http.RoundTripper {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		return transport
+	}(),
+}
+
+// run downloads file with retries and backoff
+func (d *download) run(filePath string) error {
+	backoff := downloadBackoff
+	for {
+		d.Lock()
+		d.attempts++
+		attempt := d.attempts
+		// error of the previous attempt is not actual anymore
+		d.err = nil
+		d.Unlock()
+		err := d.attempt(filePath)
+		if err == nil {
+			return nil
+		}
+		var perm *errPermanent
+		if errors.As(err, &perm) || attempt >= downloadAttempts {
+			return err
+		}
+		log.Printf("Download attempt %d of %s failed: %s, retry in %s", attempt, d.url, err, backoff)
+		d.Lock()
+		d.err = err
+		d.Unlock()
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > downloadMaxBackoff {
+			backoff = downloadMaxBackoff
+		}
+	}
+}
+
+// attempt continues download into filePath.tmp from its current size and
+// verifies checksum after completion
+func (d *download) attempt(filePath string) error {
+	tmpPath := filePath + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(tmpPath), 0755); err != nil {
+		return permanent("cannot create dir for %s: %w", tmpPath, err)
+	}
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return permanent("cannot open %s: %w", tmpPath, err)
+	}
+	defer out.Close()
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return permanent("cannot seek %s: %w", tmpPath, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, d.url, nil)
+	if err != nil {
+		return permanent("cannot create request: %w", err)
+	}
+	d.Lock()
+	validator := d.validator
+	d.Unlock()
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var total int64
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// full content: server does not support ranges or content changed
+		if offset > 0 {
+			log.Printf("Restart download of %s from zero", d.url)
+		}
+		if err = out.Truncate(0); err != nil {
+			return permanent("cannot truncate %s: %w", tmpPath, err)
+		}
+		if _, err = out.Seek(0, io.SeekStart); err != nil {
+			return permanent("cannot seek %s: %w", tmpPath, err)
+		}
+		offset = 0
+		total = resp.ContentLength
+		d.Lock()
+		d.validator = responseValidator(resp)
+		d.Unlock()
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return err
+		}
+		if start != offset {
+			return fmt.Errorf("unexpected range start %d instead of %d", start, offset)
+		}
+		total = size
+		log.Printf("Resume download of %s from %d", d.url, offset)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// nothing left to download if size of file is equal to offset
+		_, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || size != offset {
+			if err := out.Truncate(0); err != nil {
+				return permanent("cannot truncate %s: %w", tmpPath, err)
+			}
+			return fmt.Errorf("range not satisfiable, restart from zero")
+		}
+		total = size
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	default:
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+		return permanent("unexpected status: %s", resp.Status)
+	}
+
+	progress := &progressWriter{d: d, done: offset, total: total}
+	d.setProgress(offset, total)
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		if _, err = io.Copy(out, io.TeeReader(resp.Body, progress)); err != nil {
+			return err
+		}
+	}
+	if total > 0 && progress.done != total {
+		return fmt.Errorf("downloaded %d bytes of %d", progress.done, total)
+	}
+	if err = out.Close(); err != nil {
+		return permanent("cannot close %s: %w", tmpPath, err)
+	}
+	return d.complete(filePath)
+}
+
+// complete verifies checksum of downloaded file and moves it into place
+func (d *download) complete(filePath string) error {
+	tmpPath := filePath + ".tmp"
+	sum, err := fileSha256(tmpPath)
+	if err != nil {
+		return permanent("cannot calculate sha256: %w", err)
+	}
+	if d.sha256 != "" && !strings.EqualFold(d.sha256, sum) {
+		// drop file to not resume corrupted content next time
+		_ = os.Remove(tmpPath)
+		return permanent("sha256 mismatch: expected %s, got %s", d.sha256, sum)
+	}
+	if err = d.commit(tmpPath, sum); err != nil {
+		return permanent("cannot store %s: %w", tmpPath, err)
+	}
+	return nil
+}
+
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// parseContentRange parses "bytes start-end/size" or "bytes */size"
+func parseContentRange(value string) (start, size int64, err error) {
+	rng := strings.TrimPrefix(value, "bytes ")
+	parts := strings.SplitN(rng, "/", 2)
+	if rng == value || len(parts) != 2 {
+		return 0, 0, fmt.Errorf("cannot parse Content-Range %q", value)
+	}
+	size = -1
+	if parts[1] != "*" {
+		if size, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("cannot parse Content-Range %q: %w", value, err)
+		}
+	}
+	if parts[0] == "*" {
+		return 0, size, nil
+	}
+	bounds := strings.SplitN(parts[0], "-", 2)
+	if start, err = strconv.ParseInt(bounds[0], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("cannot parse Content-Range %q: %w", value, err)
+	}
+	return start, size, nil
+}
+
+func fileSha256(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,220 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/eserver/api"
+)
+
+var testContent = bytes.Repeat([]byte("0123456789"), 1000)
+
+func testSha256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newTestDownload returns download which stores committed file content and its checksum
+func newTestDownload(url, expectedSha256 string) (*download, *string) {
+	committed := new(string)
+	d := &download{url: url, sha256: expectedSha256}
+	d.commit = func(tmpPath, sum string) error {
+		*committed = sum
+		return os.Remove(tmpPath)
+	}
+	return d, committed
+}
+
+func TestDownloadVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(testContent))
+	}))
+	defer srv.Close()
+	filePath := filepath.Join(t.TempDir(), "file")
+
+	d, committed := newTestDownload(srv.URL, strings.ToUpper(testSha256(testContent)))
+	if err := d.run(filePath); err != nil {
+		t.Fatalf("download failed: %s", err)
+	}
+	if *committed != testSha256(testContent) {
+		t.Errorf("committed sha256 %s, expected %s", *committed, testSha256(testContent))
+	}
+
+	d, committed = newTestDownload(srv.URL, testSha256([]byte("other")))
+	err := d.run(filePath)
+	var perm *errPermanent
+	if !errors.As(err, &perm) {
+		t.Fatalf("expected permanent error for sha256 mismatch, got %v", err)
+	}
+	if d.attempts != 1 {
+		t.Errorf("permanent error retried %d times", d.attempts)
+	}
+	if *committed != "" {
+		t.Error("file with wrong sha256 committed")
+	}
+	if _, err := os.Stat(filePath + ".tmp"); !os.IsNotExist(err) {
+		t.Error("file with wrong sha256 is not removed")
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(testContent))
+	}))
+	defer srv.Close()
+	filePath := filepath.Join(t.TempDir(), "file")
+	half := len(testContent) / 2
+	if err := os.WriteFile(filePath+".tmp", testContent[:half], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var data []byte
+	d := &download{url: srv.URL, sha256: testSha256(testContent)}
+	d.commit = func(tmpPath, sum string) (err error) {
+		data, err = os.ReadFile(tmpPath)
+		return err
+	}
+	if err := d.run(filePath); err != nil {
+		t.Fatalf("download failed: %s", err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=5000-" {
+		t.Errorf("unexpected range requests: %q", ranges)
+	}
+	if !bytes.Equal(data, testContent) {
+		t.Error("resumed content differs")
+	}
+	info := d.fileInfo()
+	if info.Size != int64(len(testContent)) || info.Total != int64(len(testContent)) {
+		t.Errorf("unexpected progress %d/%d", info.Size, info.Total)
+	}
+}
+
+func TestDownloadRetryClearsError(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(testContent))
+	}))
+	defer srv.Close()
+
+	d, committed := newTestDownload(srv.URL, "")
+	var errDuringRetry string
+	d.commit = func(tmpPath, sum string) error {
+		errDuringRetry = d.fileInfo().Error
+		*committed = sum
+		return os.Remove(tmpPath)
+	}
+	if err := d.run(filepath.Join(t.TempDir(), "file")); err != nil {
+		t.Fatalf("download failed: %s", err)
+	}
+	if d.attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", d.attempts)
+	}
+	if errDuringRetry != "" {
+		t.Errorf("error of previous attempt reported during retry: %s", errDuringRetry)
+	}
+	if info := d.fileInfo(); info.Error != "" || info.Status != api.FileStatusDownloading {
+		t.Errorf("unexpected status after retry: %+v", info)
+	}
+	if *committed != testSha256(testContent) {
+		t.Error("file is not committed after retry")
+	}
+}
+
+func TestDownloadPermanentStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	d, _ := newTestDownload(srv.URL, "")
+	err := d.run(filepath.Join(t.TempDir(), "file"))
+	var perm *errPermanent
+	if !errors.As(err, &perm) {
+		t.Fatalf("expected permanent error for 404, got %v", err)
+	}
+	if d.attempts != 1 {
+		t.Errorf("not found retried %d times", d.attempts)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value   string
+		start   int64
+		size    int64
+		wantErr bool
+	}{
+		{value: "bytes 100-199/1000", start: 100, size: 1000},
+		{value: "bytes 0-99/*", start: 0, size: -1},
+		{value: "bytes */1000", start: 0, size: 1000},
+		{value: "100-199/1000", wantErr: true},
+		{value: "bytes 100-199", wantErr: true},
+		{value: "bytes x-199/1000", wantErr: true},
+		{value: "bytes 100-199/x", wantErr: true},
+	}
+	for _, tt := range tests {
+		start, size, err := parseContentRange(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", tt.value, err)
+			continue
+		}
+		if start != tt.start || size != tt.size {
+			t.Errorf("%q: got %d/%d, expected %d/%d", tt.value, start, size, tt.start, tt.size)
+		}
+	}
+}
+
+func TestAddFileDoesNotReuseUploadedFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.img", time.Time{}, bytes.NewReader(testContent))
+	}))
+	defer srv.Close()
+	mgr := newTestManager(t)
+	uploadedSha := addTestFile(t, mgr, "file.img", "", "uploaded content")
+
+	url := srv.URL + "/images/file.img"
+	name, err := mgr.AddFile(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name == "file.img" {
+		t.Fatal("download resolved to the uploaded file with the same base name")
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for !mgr.GetFileInfo(name).ISReady {
+		if time.Now().After(deadline) {
+			t.Fatalf("download of %s did not finish: %+v", name, mgr.GetFileInfo(name))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info := mgr.GetFileInfo(name); info.Sha256 != testSha256(testContent) {
+		t.Errorf("downloaded file has sha256 %s, expected %s", info.Sha256, testSha256(testContent))
+	}
+	if info := mgr.GetFileInfo("file.img"); info.Sha256 != uploadedSha {
+		t.Errorf("uploaded file was replaced by download: sha256 %s", info.Sha256)
+	}
+	// The same url resolves to the same name.
+	if again, err := mgr.AddFile(url, ""); err != nil || again != name {
+		t.Errorf("repeated download resolved to %s (%v), expected %s", again, err, name)
+	}
+}
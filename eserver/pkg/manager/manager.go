@@ -2,16 +2,17 @@ package manager
 
 import (
 	"crypto/sha256"
-	"crypto/tls"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
-	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/lf-edge/eden/eserver/api"
 )
@@ -19,6 +20,15 @@ import (
 // EServerManager for process files
 type EServerManager struct {
 	Dir string
+	// MaxSize limits total size of stored content, the least recently used content
+	// is removed after adding of new one, not limited if 0
+	MaxSize int64
+
+	mu        sync.Mutex
+	index     *index
+	downloads map[string]*download
+	// flushTimer is set if index has changes not saved yet
+	flushTimer *time.Timer
 }
 
 // Init directories for EServerManager
@@ -28,16 +38,18 @@ func (mgr *EServerManager) Init() {
 			log.Fatal(err)
 		}
 	}
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	if err := mgr.loadIndex(); err != nil {
+		log.Fatal(err)
+	}
+	mgr.downloads = map[string]*download{}
 }
 
-// ListFileNames list downloaded files
+// ListFileNames list stored files
 func (mgr *EServerManager) ListFileNames() (result []string) {
-	files, err := os.ReadDir(mgr.Dir)
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, f := range files {
-		result = append(result, f.Name())
+	for _, el := range mgr.ListFiles() {
+		result = append(result, el.Name)
 	}
 	return
 }
@@ -51,68 +63,80 @@ func getFileSize(filePath string) int64 {
 	return fi.Size()
 }
 
-// downloadFile downloads a url to a local file.
-func downloadFile(filePath, url string) error {
-	out, err := os.Create(filePath + ".tmp")
-	if err != nil {
-		return err
-	}
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	hash := sha256.New()
-	_, err = io.Copy(hash, io.TeeReader(resp.Body, out))
-	if err != nil {
-		return err
-	}
-	if err = os.WriteFile(fmt.Sprintf("%s.sha256", filePath), []byte(hex.EncodeToString(hash.Sum(nil))), 0666); err != nil {
-		return err
-	}
-	if err = os.Rename(filePath+".tmp", filePath); err != nil {
-		return err
-	}
-	return out.Close()
+// urlName returns name for url: base name of url or base name prefixed
+// with hash of url if the base name is used by file from another url
+// (uploaded files have no url and are never reused for downloads)
+func (mgr *EServerManager) urlName(url string) string {
+	name := path.Base(url)
+	alias, stored := mgr.index.Aliases[name]
+	d, downloading := mgr.downloads[name]
+	if (stored && alias.URL != url) || (downloading && d.url != url) {
+		hash := sha256.Sum256([]byte(url))
+		name = path.Join(hex.EncodeToString(hash[:])[:12], name)
+	}
+	return name
 }
 
 // AddFile starts file download and return name of file for fileinfo requests
-func (mgr *EServerManager) AddFile(url string) (string, error) {
+// partially downloaded file is resumed, sha256 of file is verified if expectedSha256 is not empty
+func (mgr *EServerManager) AddFile(url, expectedSha256 string) (string, error) {
 	log.Println("Starting download of image from ", url)
-	filePath := filepath.Join(mgr.Dir, path.Base(url))
-	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	name := mgr.urlName(url)
+	filePath := mgr.aliasPath(name)
+	if alias, ok := mgr.index.Aliases[name]; ok {
 		log.Println("file already exists ", filePath)
-	} else {
-		go func() {
-			http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-			if err := downloadFile(filePath, url); err != nil {
-				log.Fatal(err)
-			}
-			log.Println("Download done for ", url)
-		}()
-	}
-	return path.Base(url), nil
+		if expectedSha256 != "" && !strings.EqualFold(alias.Sha256, expectedSha256) {
+			return "", fmt.Errorf("file %s already exists with sha256 %s", name, alias.Sha256)
+		}
+		return name, nil
+	}
+	expectedSha256 = strings.ToLower(expectedSha256)
+	if _, ok := mgr.index.Blobs[expectedSha256]; ok {
+		log.Println("content already exists ", expectedSha256)
+		return name, mgr.commitLocked(name, url, "", expectedSha256)
+	}
+	if d, ok := mgr.downloads[name]; ok {
+		d.Lock()
+		running := !d.finished
+		d.Unlock()
+		if running {
+			log.Println("download already in progress ", filePath)
+			return name, nil
+		}
+	}
+	d := &download{url: url, sha256: expectedSha256}
+	d.commit = func(tmpPath, sum string) error {
+		return mgr.commit(name, url, tmpPath, sum)
+	}
+	mgr.downloads[name] = d
+	go func() {
+		err := d.run(filePath)
+		d.Lock()
+		d.err = err
+		d.finished = true
+		d.Unlock()
+		if err != nil {
+			log.Printf("Download failed for %s: %s", url, err)
+			return
+		}
+		log.Println("Download done for ", url)
+	}()
+	return name, nil
 }
 
 // AddFileFromMultipart adds file from multipart.Part and returns information
 func (mgr *EServerManager) AddFileFromMultipart(part *multipart.Part) *api.FileInfo {
 	result := &api.FileInfo{ISReady: false}
 	log.Println("Starting copy image from ", part.FileName())
-	filePath := filepath.Join(mgr.Dir, part.FileName())
-	if err := os.MkdirAll(filepath.Dir(filePath), os.ModeDir); err != nil {
+	filePath := mgr.aliasPath(part.FileName())
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		log.Println("cannot create dir for ", filePath)
 		result.Error = err.Error()
 		return result
 	}
-	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
-		log.Println("file already exists ", filePath)
-		// remove file if exists, we have new file in request
-		if err := os.Remove(filePath); err != nil {
-			result.Error = err.Error()
-			return result
-		}
-	}
-	filePathTemp := filepath.Join(mgr.Dir, fmt.Sprintf("%s.tmp", part.FileName()))
+	filePathTemp := filePath + ".tmp"
 	out, err := os.Create(filePathTemp)
 	if err != nil {
 		result.Error = err.Error()
@@ -125,11 +149,12 @@ func (mgr *EServerManager) AddFileFromMultipart(part *multipart.Part) *api.FileI
 		result.Error = err.Error()
 		return result
 	}
-	if err = os.WriteFile(fmt.Sprintf("%s.sha256", filePath), []byte(hex.EncodeToString(hash.Sum(nil))), 0666); err != nil {
+	if err = out.Close(); err != nil {
 		result.Error = err.Error()
 		return result
 	}
-	if err = os.Rename(filePathTemp, filePath); err != nil {
+	// we have new file in request, it replaces the existing one with the same name
+	if err = mgr.commit(part.FileName(), "", filePathTemp, hex.EncodeToString(hash.Sum(nil))); err != nil {
 		result.Error = err.Error()
 		return result
 	}
@@ -139,9 +164,19 @@ func (mgr *EServerManager) AddFileFromMultipart(part *multipart.Part) *api.FileI
 // GetFileInfo checks status of file and returns information
 func (mgr *EServerManager) GetFileInfo(name string) *api.FileInfo {
 	result := &api.FileInfo{ISReady: false}
-	filePath := filepath.Join(mgr.Dir, name)
-	filePathTMP := filePath + ".tmp"
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	mgr.mu.Lock()
+	alias, stored := mgr.index.Aliases[name]
+	d, downloading := mgr.downloads[name]
+	var entry *api.FileEntry
+	if stored {
+		entry = mgr.entry(name, alias)
+	}
+	mgr.mu.Unlock()
+	if !stored {
+		if downloading {
+			return d.fileInfo()
+		}
+		filePathTMP := mgr.aliasPath(name) + ".tmp"
 		if _, err := os.Stat(filePathTMP); os.IsNotExist(err) {
 			result.Error = err.Error()
 			return result
@@ -150,27 +185,31 @@ func (mgr *EServerManager) GetFileInfo(name string) *api.FileInfo {
 		return &api.FileInfo{
 			Size:    fileSize,
 			ISReady: false,
+			Status:  api.FileStatusDownloading,
 		}
 	}
-	fileSize := getFileSize(filePath)
-	sha, err := os.ReadFile(fmt.Sprintf("%s.sha256", filePath))
-	if err != nil {
-		result.Error = err.Error()
-		return result
-	}
 	return &api.FileInfo{
-		Sha256:   string(sha),
-		Size:     fileSize,
+		Sha256:   entry.Sha256,
+		Size:     entry.Size,
+		Total:    entry.Size,
 		FileName: path.Join("eserver", name),
 		ISReady:  true,
+		Status:   api.FileStatusReady,
 	}
 }
 
 // GetFilePath returns path to file for serve
 func (mgr *EServerManager) GetFilePath(name string) (string, error) {
-	filePath := filepath.Join(mgr.Dir, name)
+	mgr.mu.Lock()
+	alias, ok := mgr.index.Aliases[name]
+	mgr.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("file %s not found", name)
+	}
+	filePath := mgr.blobPath(alias.Sha256)
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return "", err
 	}
+	mgr.touch(name)
 	return filePath, nil
 }
@@ -0,0 +1,587 @@
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/eden/eserver/api"
+)
+
+// Images are stored as files with names:
+// oci/<repository>/manifests/<sha256> for manifests and indexes,
+// oci/<repository>/blobs/<sha256> for configs and layers,
+// oci/<repository>/tags/<tag> pointed to content of manifest.
+const (
+	ociDir          = "oci"
+	ociManifestsDir = "manifests"
+	ociBlobsDir     = "blobs"
+	ociTagsDir      = "tags"
+
+	// MediaTypeOCIManifest is media type of OCI image manifest
+	MediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeOCIIndex is media type of OCI image index
+	MediaTypeOCIIndex = "application/vnd.oci.image.index.v1+json"
+
+	mediaTypeOCIConfig       = "application/vnd.oci.image.config.v1+json"
+	mediaTypeOCILayerGzip    = "application/vnd.oci.image.layer.v1.tar+gzip"
+	ociAnnotationRefName     = "org.opencontainers.image.ref.name"
+	containerdAnnotationName = "io.containerd.image.name"
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType,omitempty"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest contains fields of manifest and index we are interested in
+type ociManifest struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	MediaType     string           `json:"mediaType,omitempty"`
+	Config        *ociDescriptor   `json:"config,omitempty"`
+	Layers        []*ociDescriptor `json:"layers,omitempty"`
+	Manifests     []*ociDescriptor `json:"manifests,omitempty"`
+}
+
+// dockerSaveEntry is element of manifest.json inside archive created by docker save
+type dockerSaveEntry struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+func ociName(repository, kind, name string) string {
+	return path.Join(ociDir, repository, kind, name)
+}
+
+// ociRepository returns repository of image content stored with name
+func ociRepository(name string) (string, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) < 4 || parts[0] != ociDir {
+		return "", false
+	}
+	switch parts[len(parts)-2] {
+	case ociManifestsDir, ociBlobsDir, ociTagsDir:
+		return strings.Join(parts[1:len(parts)-2], "/"), true
+	}
+	return "", false
+}
+
+func digestHex(digest string) (string, error) {
+	hexPart := strings.TrimPrefix(digest, "sha256:")
+	if hexPart == digest || len(hexPart) != sha256.Size*2 {
+		return "", fmt.Errorf("unsupported digest %s", digest)
+	}
+	if _, err := hex.DecodeString(hexPart); err != nil {
+		return "", fmt.Errorf("malformed digest %s", digest)
+	}
+	return strings.ToLower(hexPart), nil
+}
+
+// tagRegexp matches valid tags of images as defined by OCI distribution spec
+var tagRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]{0,127}$`)
+
+// ParseImageName returns repository and tag of image reference without registry host,
+// i.e. docker.io/alpine:3.18 is library/alpine and 3.18
+func ParseImageName(ref string) (repository, tag string, err error) {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	tag = "latest"
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, tag = ref[:i], ref[i+1:]
+	}
+	parts := strings.Split(ref, "/")
+	host := ""
+	if len(parts) > 1 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		host, parts = parts[0], parts[1:]
+	}
+	if len(parts) == 1 && (host == "" || host == "docker.io" || host == "index.docker.io") {
+		parts = append([]string{"library"}, parts...)
+	}
+	repository = strings.Join(parts, "/")
+	if repository == "" || strings.ToLower(repository) != repository || strings.Contains(repository, "..") {
+		return "", "", fmt.Errorf("invalid image name %s", ref)
+	}
+	if !tagRegexp.MatchString(tag) {
+		return "", "", fmt.Errorf("invalid tag %s of image %s", tag, ref)
+	}
+	return repository, tag, nil
+}
+
+// importFile is regular file extracted from archive into temporary directory
+type importFile struct {
+	tmpPath string
+	sha     string
+	size    int64
+	gzip    bool
+}
+
+// ociImport collects content of images from archive to commit into storage
+type ociImport struct {
+	files map[string]*importFile
+	links map[string]string
+	// byDigest maps sha256 of content to file in archive
+	byDigest map[string]*importFile
+	// compressed maps sha256 of uncompressed layer to compressed one
+	compressed map[string]*importFile
+	// names to commit, mapped to sha256 of content
+	names  map[string]string
+	images []*api.OCIImage
+}
+
+func (imp *ociImport) extract(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for i := 0; ; i++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read archive: %w", err)
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			imp.links[name] = strings.TrimPrefix(path.Clean("/"+path.Join(path.Dir(name), hdr.Linkname)), "/")
+		case tar.TypeLink:
+			imp.links[name] = strings.TrimPrefix(path.Clean("/"+hdr.Linkname), "/")
+		case tar.TypeReg:
+			f := &importFile{tmpPath: filepath.Join(dir, fmt.Sprintf("%d", i))}
+			out, err := os.Create(f.tmpPath)
+			if err != nil {
+				return err
+			}
+			hash := sha256.New()
+			var head bytes.Buffer
+			f.size, err = io.Copy(io.MultiWriter(out, hash, &limitedBuffer{buf: &head, limit: 2}), tr)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("cannot extract %s: %w", name, err)
+			}
+			f.sha = hex.EncodeToString(hash.Sum(nil))
+			f.gzip = bytes.Equal(head.Bytes(), []byte{0x1f, 0x8b})
+			imp.files[name] = f
+			imp.byDigest[f.sha] = f
+		}
+	}
+}
+
+// limitedBuffer keeps only the first limit bytes written
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if rest := b.limit - b.buf.Len(); rest > 0 {
+		if len(p) < rest {
+			rest = len(p)
+		}
+		b.buf.Write(p[:rest])
+	}
+	return len(p), nil
+}
+
+// compress returns gzip compressed copy of layer, docker save stores layers uncompressed,
+// but not all clients support uncompressed layers, diff_ids in config are not changed by compression
+func (imp *ociImport) compress(layer *importFile) (*importFile, error) {
+	if f, ok := imp.compressed[layer.sha]; ok {
+		return f, nil
+	}
+	in, err := os.Open(layer.tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	f := &importFile{tmpPath: layer.tmpPath + ".gz", gzip: true}
+	out, err := os.Create(f.tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	hash := sha256.New()
+	counter := &countWriter{}
+	zw, err := gzip.NewWriterLevel(io.MultiWriter(out, hash, counter), gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(zw, in); err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	if err = out.Close(); err != nil {
+		return nil, err
+	}
+	f.sha, f.size = hex.EncodeToString(hash.Sum(nil)), counter.n
+	imp.compressed[layer.sha] = f
+	imp.byDigest[f.sha] = f
+	return f, nil
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// file returns file from archive by name following links
+func (imp *ociImport) file(name string) (*importFile, error) {
+	for i := 0; i < 16; i++ {
+		if f, ok := imp.files[name]; ok {
+			return f, nil
+		}
+		target, ok := imp.links[name]
+		if !ok {
+			break
+		}
+		name = target
+	}
+	return nil, fmt.Errorf("%s not found in archive", name)
+}
+
+func (imp *ociImport) readJSON(f *importFile, obj interface{}) error {
+	data, err := os.ReadFile(f.tmpPath)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
+
+// addContent adds file with sha256 into repository
+func (imp *ociImport) addContent(repository, kind, sha string) error {
+	if _, ok := imp.byDigest[sha]; !ok {
+		return fmt.Errorf("content sha256:%s not found in archive", sha)
+	}
+	imp.names[ociName(repository, kind, sha)] = sha
+	return nil
+}
+
+// addManifest adds manifest or index with digest and referenced content into repository,
+// children of index absent in archive (e.g. other platforms) are skipped
+func (imp *ociImport) addManifest(repository, digest string) error {
+	sha, err := digestHex(digest)
+	if err != nil {
+		return err
+	}
+	f, ok := imp.byDigest[sha]
+	if !ok {
+		return fmt.Errorf("manifest %s not found in archive", digest)
+	}
+	var manifest ociManifest
+	if err := imp.readJSON(f, &manifest); err != nil {
+		return fmt.Errorf("cannot parse manifest %s: %w", digest, err)
+	}
+	for _, child := range manifest.Manifests {
+		childSha, err := digestHex(child.Digest)
+		if err != nil {
+			return err
+		}
+		if _, ok := imp.byDigest[childSha]; !ok {
+			log.Printf("skip manifest %s absent in archive", child.Digest)
+			continue
+		}
+		if err := imp.addManifest(repository, child.Digest); err != nil {
+			return err
+		}
+	}
+	var blobs []*ociDescriptor
+	if manifest.Config != nil {
+		blobs = append(blobs, manifest.Config)
+	}
+	for _, desc := range append(blobs, manifest.Layers...) {
+		blobSha, err := digestHex(desc.Digest)
+		if err != nil {
+			return err
+		}
+		if err := imp.addContent(repository, ociBlobsDir, blobSha); err != nil {
+			return err
+		}
+	}
+	return imp.addContent(repository, ociManifestsDir, sha)
+}
+
+func (imp *ociImport) tag(ref, digest string) error {
+	repository, tag, err := ParseImageName(ref)
+	if err != nil {
+		return err
+	}
+	if err := imp.addManifest(repository, digest); err != nil {
+		return err
+	}
+	sha, _ := digestHex(digest)
+	imp.names[ociName(repository, ociTagsDir, tag)] = sha
+	imp.images = append(imp.images, &api.OCIImage{Repository: repository, Tag: tag, Digest: digest})
+	return nil
+}
+
+// importLayout imports images from index.json of OCI layout
+func (imp *ociImport) importLayout(index *importFile, name string) error {
+	var manifest ociManifest
+	if err := imp.readJSON(index, &manifest); err != nil {
+		return fmt.Errorf("cannot parse index.json: %w", err)
+	}
+	if name != "" && len(manifest.Manifests) != 1 {
+		return fmt.Errorf("name is allowed for archive with single image only, found %d", len(manifest.Manifests))
+	}
+	for _, desc := range manifest.Manifests {
+		ref := name
+		if ref == "" {
+			ref = desc.Annotations[containerdAnnotationName]
+		}
+		if ref == "" {
+			// ref.name may be tag only
+			ref = desc.Annotations[ociAnnotationRefName]
+			if ref != "" && !strings.ContainsAny(ref, "/:") {
+				return fmt.Errorf("no repository for tag %s of %s, provide name", ref, desc.Digest)
+			}
+		}
+		if ref == "" {
+			return fmt.Errorf("no name for %s, provide name", desc.Digest)
+		}
+		if err := imp.tag(ref, desc.Digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importDockerSave converts images from manifest.json created by docker save into OCI manifests
+func (imp *ociImport) importDockerSave(manifestFile *importFile, name string) error {
+	var entries []*dockerSaveEntry
+	if err := imp.readJSON(manifestFile, &entries); err != nil {
+		return fmt.Errorf("cannot parse manifest.json: %w", err)
+	}
+	if name != "" && len(entries) != 1 {
+		return fmt.Errorf("name is allowed for archive with single image only, found %d", len(entries))
+	}
+	for _, entry := range entries {
+		config, err := imp.file(entry.Config)
+		if err != nil {
+			return err
+		}
+		manifest := &ociManifest{
+			SchemaVersion: 2,
+			MediaType:     MediaTypeOCIManifest,
+			Config:        &ociDescriptor{MediaType: mediaTypeOCIConfig, Digest: "sha256:" + config.sha, Size: config.size},
+		}
+		for _, el := range entry.Layers {
+			layer, err := imp.file(el)
+			if err != nil {
+				return err
+			}
+			if !layer.gzip {
+				if layer, err = imp.compress(layer); err != nil {
+					return fmt.Errorf("cannot compress %s: %w", el, err)
+				}
+			}
+			manifest.Layers = append(manifest.Layers, &ociDescriptor{MediaType: mediaTypeOCILayerGzip, Digest: "sha256:" + layer.sha, Size: layer.size})
+		}
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		f := &importFile{tmpPath: config.tmpPath + ".manifest", sha: hex.EncodeToString(sum[:]), size: int64(len(data))}
+		if err := os.WriteFile(f.tmpPath, data, 0644); err != nil {
+			return err
+		}
+		imp.byDigest[f.sha] = f
+		refs := entry.RepoTags
+		if name != "" {
+			refs = []string{name}
+		}
+		if len(refs) == 0 {
+			return fmt.Errorf("no name for image with config %s, provide name", entry.Config)
+		}
+		for _, ref := range refs {
+			if err := imp.tag(ref, "sha256:"+f.sha); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ImportOCI imports images from archive in OCI layout or created by docker save,
+// name (repository:tag) replaces name of image inside archive with single image
+func (mgr *EServerManager) ImportOCI(r io.Reader, name string) ([]*api.OCIImage, error) {
+	dir, err := os.MkdirTemp(mgr.Dir, ".oci-import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	imp := &ociImport{
+		files:      map[string]*importFile{},
+		links:      map[string]string{},
+		byDigest:   map[string]*importFile{},
+		compressed: map[string]*importFile{},
+		names:      map[string]string{},
+	}
+	if err := imp.extract(r, dir); err != nil {
+		return nil, err
+	}
+	if index, err := imp.file("index.json"); err == nil {
+		err = imp.importLayout(index, name)
+		if err != nil {
+			return nil, err
+		}
+	} else if manifest, err := imp.file("manifest.json"); err == nil {
+		if err = imp.importDockerSave(manifest, name); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("neither index.json nor manifest.json found in archive")
+	}
+	var names []string
+	for n := range imp.names {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	var imported []string
+	for _, n := range names {
+		sha := imp.names[n]
+		tmpPath := ""
+		if f := imp.byDigest[sha]; f.tmpPath != "" {
+			// move content from archive on the first use only
+			tmpPath, f.tmpPath = f.tmpPath, ""
+		}
+		if err := mgr.commitLocked(n, "", tmpPath, sha); err != nil {
+			return nil, err
+		}
+		imported = append(imported, sha)
+	}
+	if mgr.MaxSize > 0 {
+		if _, err := mgr.gcLocked(&api.GCArg{MaxSize: mgr.MaxSize}, imported...); err != nil {
+			log.Printf("GC failed: %s", err)
+		}
+	}
+	for _, el := range imp.images {
+		log.Printf("Imported image %s:%s (%s)", el.Repository, el.Tag, el.Digest)
+	}
+	return imp.images, nil
+}
+
+// ListOCIImages returns tags of stored images
+func (mgr *EServerManager) ListOCIImages() []*api.OCIImage {
+	var result []*api.OCIImage
+	for _, el := range mgr.ListFiles() {
+		parts := strings.Split(el.Name, "/")
+		if len(parts) < 4 || parts[0] != ociDir || parts[len(parts)-2] != ociTagsDir {
+			continue
+		}
+		result = append(result, &api.OCIImage{
+			Repository: strings.Join(parts[1:len(parts)-2], "/"),
+			Tag:        parts[len(parts)-1],
+			Digest:     "sha256:" + el.Sha256,
+		})
+	}
+	return result
+}
+
+// OCIRepositories returns names of repositories with tags
+func (mgr *EServerManager) OCIRepositories() []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, el := range mgr.ListOCIImages() {
+		if !seen[el.Repository] {
+			seen[el.Repository] = true
+			result = append(result, el.Repository)
+		}
+	}
+	return result
+}
+
+// OCITags returns tags of repository
+func (mgr *EServerManager) OCITags(repository string) []string {
+	var result []string
+	for _, el := range mgr.ListOCIImages() {
+		if el.Repository == repository {
+			result = append(result, el.Tag)
+		}
+	}
+	return result
+}
+
+// OCIManifest returns path to manifest of repository by tag or digest, its digest and media type
+func (mgr *EServerManager) OCIManifest(repository, reference string) (filePath, digest, mediaType string, err error) {
+	name := ociName(repository, ociTagsDir, reference)
+	if !strings.Contains(reference, ":") && !tagRegexp.MatchString(reference) {
+		return "", "", "", fmt.Errorf("invalid tag %s", reference)
+	}
+	if strings.Contains(reference, ":") {
+		sha, err := digestHex(reference)
+		if err != nil {
+			return "", "", "", err
+		}
+		name = ociName(repository, ociManifestsDir, sha)
+	}
+	info := mgr.GetFileInfo(name)
+	if !info.ISReady {
+		return "", "", "", fmt.Errorf("manifest %s not found in %s", reference, repository)
+	}
+	if filePath, err = mgr.GetFilePath(name); err != nil {
+		return "", "", "", err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", "", "", err
+	}
+	var manifest ociManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return "", "", "", fmt.Errorf("cannot parse manifest %s: %w", reference, err)
+	}
+	mediaType = manifest.MediaType
+	if mediaType == "" {
+		mediaType = MediaTypeOCIManifest
+		if manifest.Manifests != nil {
+			mediaType = MediaTypeOCIIndex
+		}
+	}
+	return filePath, "sha256:" + info.Sha256, mediaType, nil
+}
+
+// OCIBlobName returns name of file with blob of repository
+func OCIBlobName(repository, digest string) (string, error) {
+	sha, err := digestHex(digest)
+	if err != nil {
+		return "", err
+	}
+	return ociName(repository, ociBlobsDir, sha), nil
+}
+
+// OCIBlob returns path to blob of repository with digest, manifests are available as blobs too
+func (mgr *EServerManager) OCIBlob(repository, digest string) (string, error) {
+	name, err := OCIBlobName(repository, digest)
+	if err != nil {
+		return "", err
+	}
+	if filePath, err := mgr.GetFilePath(name); err == nil {
+		return filePath, nil
+	}
+	sha, _ := digestHex(digest)
+	return mgr.GetFilePath(ociName(repository, ociManifestsDir, sha))
+}
@@ -0,0 +1,301 @@
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/eserver/api"
+)
+
+func TestParseImageName(t *testing.T) {
+	tests := []struct {
+		ref        string
+		repository string
+		tag        string
+		wantErr    bool
+	}{
+		{ref: "alpine", repository: "library/alpine", tag: "latest"},
+		{ref: "alpine:3.18", repository: "library/alpine", tag: "3.18"},
+		{ref: "docker.io/alpine:3.18", repository: "library/alpine", tag: "3.18"},
+		{ref: "docker.io/lfedge/eve:latest", repository: "lfedge/eve", tag: "latest"},
+		{ref: "lfedge/eve", repository: "lfedge/eve", tag: "latest"},
+		{ref: "localhost:5000/app:1.0", repository: "app", tag: "1.0"},
+		{ref: "registry.local/group/app", repository: "group/app", tag: "latest"},
+		{ref: "alpine:3.18@sha256:abcd", repository: "library/alpine", tag: "3.18"},
+		{ref: "Alpine", wantErr: true},
+		{ref: "alpine:", wantErr: true},
+		{ref: "a/../b", wantErr: true},
+		{ref: "alpine:.", wantErr: true},
+		{ref: "alpine:..", wantErr: true},
+		{ref: "alpine:-rc", wantErr: true},
+		{ref: "alpine:" + strings.Repeat("a", 129), wantErr: true},
+		{ref: "alpine:_1.0-rc.1", repository: "library/alpine", tag: "_1.0-rc.1"},
+	}
+	for _, tt := range tests {
+		repository, tag, err := ParseImageName(tt.ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.ref, err)
+			continue
+		}
+		if repository != tt.repository || tag != tt.tag {
+			t.Errorf("%s: got %s:%s, expected %s:%s", tt.ref, repository, tag, tt.repository, tt.tag)
+		}
+	}
+}
+
+func TestOCIRepository(t *testing.T) {
+	for name, expected := range map[string]string{
+		"oci/library/alpine/tags/3.18": "library/alpine",
+		"oci/app/blobs/0123":           "app",
+		"oci/a/b/c/manifests/0123":     "a/b/c",
+		"oci/app/other/0123":           "",
+		"images/library/alpine/tags/1": "",
+		"oci/tags/1":                   "",
+	} {
+		repository, ok := ociRepository(name)
+		if repository != expected || ok != (expected != "") {
+			t.Errorf("%s: got %q, expected %q", name, repository, expected)
+		}
+	}
+}
+
+// testArchive returns tar with files
+func testArchive(t *testing.T, files map[string][]byte) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func mustJSON(t *testing.T, obj interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// readManifest returns manifest of repository by tag
+func readManifest(t *testing.T, mgr *EServerManager, repository, tag string) *ociManifest {
+	t.Helper()
+	filePath, _, mediaType, err := mgr.OCIManifest(repository, tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != MediaTypeOCIManifest {
+		t.Errorf("media type %s", mediaType)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := &ociManifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		t.Fatal(err)
+	}
+	return manifest
+}
+
+func TestImportDockerSave(t *testing.T) {
+	mgr := newTestManager(t)
+	config := []byte(`{"architecture":"amd64"}`)
+	layer := []byte("uncompressed layer")
+	archive := testArchive(t, map[string][]byte{
+		"config.json": config,
+		"layer.tar":   layer,
+		"manifest.json": mustJSON(t, []*dockerSaveEntry{
+			{Config: "config.json", RepoTags: []string{"app:1.0"}, Layers: []string{"layer.tar"}},
+		}),
+	})
+	images, err := mgr.ImportOCI(archive, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images[0].Repository != "library/app" || images[0].Tag != "1.0" {
+		t.Fatalf("unexpected images %+v", images)
+	}
+	manifest := readManifest(t, mgr, "library/app", "1.0")
+	if manifest.Config.Digest != "sha256:"+testSha256(config) || len(manifest.Layers) != 1 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	if manifest.Layers[0].MediaType != mediaTypeOCILayerGzip || manifest.Layers[0].Digest == "sha256:"+testSha256(layer) {
+		t.Errorf("layer is not compressed: %+v", manifest.Layers[0])
+	}
+	for _, desc := range append(manifest.Layers, manifest.Config) {
+		if _, err := mgr.OCIBlob("library/app", desc.Digest); err != nil {
+			t.Errorf("blob %s: %v", desc.Digest, err)
+		}
+	}
+	if tags := mgr.OCITags("library/app"); len(tags) != 1 || tags[0] != "1.0" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
+
+// testLayout returns OCI layout archive with single image and its manifest
+func testLayout(t *testing.T, annotations map[string]string) (*bytes.Buffer, []byte) {
+	t.Helper()
+	config := []byte(`{"architecture":"arm64"}`)
+	layer := []byte{0x1f, 0x8b, 1, 2, 3}
+	manifest := mustJSON(t, &ociManifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIManifest,
+		Config:        &ociDescriptor{MediaType: mediaTypeOCIConfig, Digest: "sha256:" + testSha256(config), Size: int64(len(config))},
+		Layers:        []*ociDescriptor{{MediaType: mediaTypeOCILayerGzip, Digest: "sha256:" + testSha256(layer), Size: int64(len(layer))}},
+	})
+	index := mustJSON(t, &ociManifest{
+		SchemaVersion: 2,
+		Manifests: []*ociDescriptor{{MediaType: MediaTypeOCIManifest, Digest: "sha256:" + testSha256(manifest),
+			Size: int64(len(manifest)), Annotations: annotations}},
+	})
+	return testArchive(t, map[string][]byte{
+		"oci-layout":                           []byte(`{"imageLayoutVersion":"1.0.0"}`),
+		"index.json":                           index,
+		"blobs/sha256/" + testSha256(config):   config,
+		"blobs/sha256/" + testSha256(layer):    layer,
+		"blobs/sha256/" + testSha256(manifest): manifest,
+	}), manifest
+}
+
+func TestImportLayout(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		imageName   string
+		repository  string
+		tag         string
+		wantErr     bool
+	}{
+		{name: "containerd name", annotations: map[string]string{containerdAnnotationName: "docker.io/lfedge/app:2.0"},
+			repository: "lfedge/app", tag: "2.0"},
+		{name: "ref name", annotations: map[string]string{ociAnnotationRefName: "lfedge/app:3.0"},
+			repository: "lfedge/app", tag: "3.0"},
+		{name: "provided name", annotations: map[string]string{ociAnnotationRefName: "3.0"}, imageName: "other:1",
+			repository: "library/other", tag: "1"},
+		{name: "tag only", annotations: map[string]string{ociAnnotationRefName: "3.0"}, wantErr: true},
+		{name: "no name", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newTestManager(t)
+			archive, manifest := testLayout(t, tt.annotations)
+			images, err := mgr.ImportOCI(archive, tt.imageName)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(images) != 1 || images[0].Repository != tt.repository || images[0].Tag != tt.tag ||
+				images[0].Digest != "sha256:"+testSha256(manifest) {
+				t.Fatalf("unexpected images %+v", images[0])
+			}
+			readManifest(t, mgr, tt.repository, tt.tag)
+		})
+	}
+}
+
+func TestImportInvalidArchive(t *testing.T) {
+	mgr := newTestManager(t)
+	if _, err := mgr.ImportOCI(testArchive(t, map[string][]byte{"file": []byte("data")}), ""); err == nil {
+		t.Error("expected error for archive without index.json and manifest.json")
+	}
+}
+
+func TestStoreGCKeepsImage(t *testing.T) {
+	mgr := newTestManager(t)
+	archive, _ := testLayout(t, map[string]string{ociAnnotationRefName: "lfedge/app:1.0"})
+	if _, err := mgr.ImportOCI(archive, ""); err != nil {
+		t.Fatal(err)
+	}
+	addTestFile(t, mgr, "file.img", "", "file content which is larger than image")
+	// the least recently used layer does not make image partially collected
+	old := time.Now().Add(-time.Hour)
+	image := map[string]*blobMeta{}
+	for name, alias := range mgr.index.Aliases {
+		if _, ok := ociRepository(name); ok {
+			image[alias.Sha256] = mgr.index.Blobs[alias.Sha256]
+			image[alias.Sha256].LastAccess = time.Now()
+		}
+	}
+	var imageSize int64
+	for _, blob := range image {
+		imageSize += blob.Size
+	}
+	layer, _ := OCIBlobName("lfedge/app", "sha256:"+testSha256([]byte{0x1f, 0x8b, 1, 2, 3}))
+	mgr.index.Blobs[mgr.index.Aliases[layer].Sha256].LastAccess = old
+	mgr.index.Blobs[testSha256([]byte("file content which is larger than image"))].LastAccess = old.Add(time.Minute)
+
+	result, err := mgr.GC(&api.GCArg{MaxSize: imageSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].Name != "file.img" {
+		t.Fatalf("unexpected removed %+v", result.Removed)
+	}
+	readManifest(t, mgr, "lfedge/app", "1.0")
+	if _, ok := mgr.index.Aliases[layer]; !ok {
+		t.Error("layer of image removed")
+	}
+
+	// pin of tag keeps the whole image
+	if err = mgr.Pin("oci/lfedge/app/tags/1.0", true); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = mgr.GC(&api.GCArg{MaxSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 0 {
+		t.Errorf("pinned image removed %+v", result.Removed)
+	}
+	if err = mgr.Pin("oci/lfedge/app/tags/1.0", false); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = mgr.GC(&api.GCArg{MaxSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// tag, manifest, config and layer are removed together
+	if len(result.Removed) != 4 || len(mgr.index.Aliases) != 0 || len(mgr.index.Blobs) != 0 {
+		t.Errorf("image is not removed as a whole: %+v", result.Removed)
+	}
+}
+
+func TestImportOCIMaxSize(t *testing.T) {
+	mgr := newTestManager(t)
+	addTestFile(t, mgr, "file.img", "", "file content")
+	mgr.MaxSize = 1
+	archive, _ := testLayout(t, map[string]string{ociAnnotationRefName: "lfedge/app:1.0"})
+	if _, err := mgr.ImportOCI(archive, ""); err != nil {
+		t.Fatal(err)
+	}
+	// imported image is kept even if it does not fit into MaxSize
+	if info := mgr.GetFileInfo("file.img"); info.ISReady {
+		t.Error("file is not collected after import")
+	}
+	readManifest(t, mgr, "lfedge/app", "1.0")
+}
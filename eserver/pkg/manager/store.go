@@ -0,0 +1,473 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eden/eserver/api"
+)
+
+// Files are stored by content: <Dir>/.blobs/sha256/<sha256>.
+// Names are relative symlinks <Dir>/<name> to content, so files are accessible via sftp as before.
+// Names, source URLs, pins and access times are kept in <Dir>/.index.json.
+const (
+	blobsDir  = ".blobs/sha256"
+	indexFile = ".index.json"
+	// indexFlushDelay is a delay to save access times updated by downloads of files
+	indexFlushDelay = 10 * time.Second
+)
+
+// aliasMeta describes name of content
+type aliasMeta struct {
+	Sha256 string `json:"sha256"`
+	URL    string `json:"url,omitempty"`
+}
+
+// blobMeta describes content
+type blobMeta struct {
+	Size       int64     `json:"size"`
+	Pinned     bool      `json:"pinned,omitempty"`
+	LastAccess time.Time `json:"lastAccess"`
+}
+
+type index struct {
+	Aliases map[string]*aliasMeta `json:"aliases"`
+	Blobs   map[string]*blobMeta  `json:"blobs"`
+}
+
+func (mgr *EServerManager) blobPath(sha string) string {
+	return filepath.Join(mgr.Dir, blobsDir, sha)
+}
+
+func (mgr *EServerManager) aliasPath(name string) string {
+	return filepath.Join(mgr.Dir, filepath.FromSlash(name))
+}
+
+// loadIndex reads index and imports files stored by name by previous versions of eserver
+func (mgr *EServerManager) loadIndex() error {
+	mgr.index = &index{Aliases: map[string]*aliasMeta{}, Blobs: map[string]*blobMeta{}}
+	if err := os.MkdirAll(filepath.Join(mgr.Dir, blobsDir), 0755); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(mgr.Dir, indexFile))
+	if err == nil {
+		if err = json.Unmarshal(data, mgr.index); err != nil {
+			return fmt.Errorf("cannot parse index: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	var legacy []string
+	err = filepath.Walk(mgr.Dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(mgr.Dir, filePath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") && rel != "." {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() && !strings.HasSuffix(rel, ".sha256") && !strings.HasSuffix(rel, ".tmp") &&
+			rel != indexFile {
+			legacy = append(legacy, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range legacy {
+		filePath := mgr.aliasPath(name)
+		sum, err := fileSha256(filePath)
+		if err != nil {
+			return err
+		}
+		log.Printf("Import %s into content storage", name)
+		if err = mgr.commitLocked(name, "", filePath, sum); err != nil {
+			return err
+		}
+		if err = os.Remove(filePath + ".sha256"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveIndex writes index atomically
+func (mgr *EServerManager) saveIndex() error {
+	if mgr.flushTimer != nil {
+		mgr.flushTimer.Stop()
+		mgr.flushTimer = nil
+	}
+	data, err := json.MarshalIndent(mgr.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(mgr.Dir, indexFile+".tmp")
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(mgr.Dir, indexFile))
+}
+
+// commit moves file from tmpPath into content storage and points name to it
+func (mgr *EServerManager) commit(name, url, tmpPath, sum string) error {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	if err := mgr.commitLocked(name, url, tmpPath, sum); err != nil {
+		return err
+	}
+	if mgr.MaxSize > 0 {
+		if _, err := mgr.gcLocked(&api.GCArg{MaxSize: mgr.MaxSize}, sum); err != nil {
+			log.Printf("GC failed: %s", err)
+		}
+	}
+	return nil
+}
+
+// commitLocked moves file from tmpPath into content storage and points name to it,
+// name is pointed to already stored content if tmpPath is empty
+func (mgr *EServerManager) commitLocked(name, url, tmpPath, sum string) error {
+	blobPath := mgr.blobPath(sum)
+	if tmpPath != "" {
+		if _, err := os.Stat(blobPath); err == nil {
+			// the same content is already stored
+			if err = os.Remove(tmpPath); err != nil {
+				return err
+			}
+		} else if err = os.Rename(tmpPath, blobPath); err != nil {
+			return err
+		}
+	}
+	fi, err := os.Stat(blobPath)
+	if err != nil {
+		return err
+	}
+	aliasPath := mgr.aliasPath(name)
+	if err = os.MkdirAll(filepath.Dir(aliasPath), 0755); err != nil {
+		return err
+	}
+	target, err := filepath.Rel(filepath.Dir(aliasPath), blobPath)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(aliasPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = os.Symlink(target, aliasPath); err != nil {
+		return err
+	}
+	mgr.index.Aliases[name] = &aliasMeta{Sha256: sum, URL: url}
+	blob, ok := mgr.index.Blobs[sum]
+	if !ok {
+		blob = &blobMeta{Size: fi.Size()}
+		mgr.index.Blobs[sum] = blob
+	}
+	blob.LastAccess = time.Now()
+	return mgr.saveIndex()
+}
+
+// touch updates access time of content with name
+// index is saved with delay to not rewrite it on every download
+func (mgr *EServerManager) touch(name string) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	alias, ok := mgr.index.Aliases[name]
+	if !ok {
+		return
+	}
+	if blob, ok := mgr.index.Blobs[alias.Sha256]; ok {
+		blob.LastAccess = time.Now()
+		if mgr.flushTimer == nil {
+			mgr.flushTimer = time.AfterFunc(indexFlushDelay, mgr.flushIndex)
+		}
+	}
+}
+
+// flushIndex saves access times updated after the last save of index
+func (mgr *EServerManager) flushIndex() {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	if mgr.flushTimer == nil {
+		return
+	}
+	if err := mgr.saveIndex(); err != nil {
+		log.Printf("cannot save index: %s", err)
+	}
+}
+
+// Close saves access times not saved yet
+func (mgr *EServerManager) Close() error {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	if mgr.flushTimer == nil {
+		return nil
+	}
+	return mgr.saveIndex()
+}
+
+func (mgr *EServerManager) entry(name string, alias *aliasMeta) *api.FileEntry {
+	result := &api.FileEntry{Name: name, Sha256: alias.Sha256, URL: alias.URL}
+	if blob, ok := mgr.index.Blobs[alias.Sha256]; ok {
+		result.Size, result.Pinned, result.LastAccess = blob.Size, blob.Pinned, blob.LastAccess
+	}
+	return result
+}
+
+// ListFiles returns stored files sorted by name
+func (mgr *EServerManager) ListFiles() []*api.FileEntry {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	var result []*api.FileEntry
+	for name, alias := range mgr.index.Aliases {
+		result = append(result, mgr.entry(name, alias))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Delete removes file by name or all names of content with sha256,
+// content is removed when no names point to it
+func (mgr *EServerManager) Delete(arg *api.DeleteArg) ([]*api.FileEntry, error) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	var removed []*api.FileEntry
+	for name, alias := range mgr.index.Aliases {
+		if (arg.Name != "" && name == arg.Name) || (arg.Sha256 != "" && strings.EqualFold(alias.Sha256, arg.Sha256)) {
+			removed = append(removed, mgr.entry(name, alias))
+		}
+	}
+	if len(removed) == 0 {
+		return nil, fmt.Errorf("file not found")
+	}
+	for _, el := range removed {
+		if err := mgr.removeAliasLocked(el.Name); err != nil {
+			return nil, err
+		}
+		if !mgr.referencedLocked(el.Sha256) {
+			if err := mgr.removeBlobLocked(el.Sha256); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return removed, mgr.saveIndex()
+}
+
+// Pin protects content of file from garbage collection
+func (mgr *EServerManager) Pin(name string, pinned bool) error {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	alias, ok := mgr.index.Aliases[name]
+	if !ok {
+		return fmt.Errorf("file %s not found", name)
+	}
+	blob, ok := mgr.index.Blobs[alias.Sha256]
+	if !ok {
+		return fmt.Errorf("content of %s not found", name)
+	}
+	blob.Pinned = pinned
+	return mgr.saveIndex()
+}
+
+// GC removes content without names, partial downloads if requested and the least recently used
+// content which is not pinned until total size fits into MaxSize,
+// images are removed or kept with all their manifests, configs and layers
+func (mgr *EServerManager) GC(arg *api.GCArg) (*api.GCResult, error) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	return mgr.gcLocked(arg)
+}
+
+// gcUnits groups content to remove together: content of OCI repository goes into one unit
+// with all content sharing names with it, so manifests never point to removed configs or layers,
+// other content forms unit per sha256
+func (mgr *EServerManager) gcUnits(names map[string][]string) map[string][]string {
+	parent := map[string]string{}
+	var find func(key string) string
+	find = func(key string) string {
+		p, ok := parent[key]
+		if !ok || p == key {
+			return key
+		}
+		root := find(p)
+		parent[key] = root
+		return root
+	}
+	for sha := range mgr.index.Blobs {
+		for _, name := range names[sha] {
+			if repository, ok := ociRepository(name); ok {
+				parent[find(sha)] = find(ociDir + "/" + repository)
+			}
+		}
+	}
+	units := map[string][]string{}
+	for sha := range mgr.index.Blobs {
+		root := find(sha)
+		units[root] = append(units[root], sha)
+	}
+	return units
+}
+
+// gcLocked collects garbage keeping content with sha256 from keep and content removed together with it
+func (mgr *EServerManager) gcLocked(arg *api.GCArg, keep ...string) (*api.GCResult, error) {
+	kept := map[string]bool{}
+	for _, sha := range keep {
+		kept[sha] = true
+	}
+	result := &api.GCResult{}
+	names := map[string][]string{}
+	for name, alias := range mgr.index.Aliases {
+		names[alias.Sha256] = append(names[alias.Sha256], name)
+	}
+	remove := func(sha string) error {
+		blob := mgr.index.Blobs[sha]
+		for _, name := range names[sha] {
+			result.Removed = append(result.Removed, mgr.entry(name, mgr.index.Aliases[name]))
+			if !arg.DryRun {
+				if err := mgr.removeAliasLocked(name); err != nil {
+					return err
+				}
+			}
+		}
+		if len(names[sha]) == 0 {
+			result.Removed = append(result.Removed, &api.FileEntry{
+				Sha256: sha, Size: blob.Size, Pinned: blob.Pinned, LastAccess: blob.LastAccess})
+		}
+		result.Freed += blob.Size
+		result.Total -= blob.Size
+		if !arg.DryRun {
+			return mgr.removeBlobLocked(sha)
+		}
+		return nil
+	}
+	// unit is used at time of the last access to any of its content
+	type unit struct {
+		blobs      []string
+		lastAccess time.Time
+		keep       bool
+	}
+	var units []*unit
+	for _, blobs := range mgr.gcUnits(names) {
+		u := &unit{}
+		for _, sha := range blobs {
+			blob := mgr.index.Blobs[sha]
+			result.Total += blob.Size
+			if len(names[sha]) == 0 {
+				// content without names
+				continue
+			}
+			u.blobs = append(u.blobs, sha)
+			if blob.LastAccess.After(u.lastAccess) {
+				u.lastAccess = blob.LastAccess
+			}
+			u.keep = u.keep || blob.Pinned || kept[sha]
+		}
+		units = append(units, u)
+	}
+	for sha := range mgr.index.Blobs {
+		if len(names[sha]) == 0 {
+			if err := remove(sha); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].lastAccess.Before(units[j].lastAccess)
+	})
+	for _, u := range units {
+		if arg.MaxSize <= 0 || result.Total <= arg.MaxSize {
+			break
+		}
+		if u.keep {
+			continue
+		}
+		sort.Strings(u.blobs)
+		for _, sha := range u.blobs {
+			if err := remove(sha); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if arg.Partial {
+		if err := mgr.gcPartialLocked(arg.DryRun, result); err != nil {
+			return nil, err
+		}
+	}
+	if arg.DryRun {
+		return result, nil
+	}
+	return result, mgr.saveIndex()
+}
+
+// gcPartialLocked removes .tmp files which are not downloading now
+func (mgr *EServerManager) gcPartialLocked(dryRun bool, result *api.GCResult) error {
+	return filepath.Walk(mgr.Dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") && filePath != mgr.Dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") || !strings.HasSuffix(filePath, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(mgr.Dir, filePath)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(rel), ".tmp")
+		if d, ok := mgr.downloads[name]; ok {
+			d.Lock()
+			running := !d.finished
+			d.Unlock()
+			if running {
+				return nil
+			}
+		}
+		result.Removed = append(result.Removed, &api.FileEntry{Name: filepath.ToSlash(rel), Size: info.Size(), LastAccess: info.ModTime()})
+		result.Freed += info.Size()
+		if dryRun {
+			return nil
+		}
+		return os.Remove(filePath)
+	})
+}
+
+func (mgr *EServerManager) referencedLocked(sha string) bool {
+	for _, alias := range mgr.index.Aliases {
+		if alias.Sha256 == sha {
+			return true
+		}
+	}
+	return false
+}
+
+func (mgr *EServerManager) removeAliasLocked(name string) error {
+	delete(mgr.index.Aliases, name)
+	if err := os.Remove(mgr.aliasPath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (mgr *EServerManager) removeBlobLocked(sha string) error {
+	delete(mgr.index.Blobs, sha)
+	if err := os.Remove(mgr.blobPath(sha)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package manager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/eserver/api"
+)
+
+func newTestManager(t *testing.T) *EServerManager {
+	t.Helper()
+	mgr := &EServerManager{Dir: t.TempDir()}
+	mgr.Init()
+	return mgr
+}
+
+// addTestFile stores content under name as downloaded from url
+func addTestFile(t *testing.T, mgr *EServerManager, name, url, content string) string {
+	t.Helper()
+	tmpPath := mgr.aliasPath(name) + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(tmpPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tmpPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := testSha256([]byte(content))
+	if err := mgr.commit(name, url, tmpPath, sum); err != nil {
+		t.Fatal(err)
+	}
+	return sum
+}
+
+func readIndex(t *testing.T, mgr *EServerManager) *index {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(mgr.Dir, indexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := &index{}
+	if err = json.Unmarshal(data, idx); err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func TestStoreAliases(t *testing.T) {
+	mgr := newTestManager(t)
+	sum := addTestFile(t, mgr, "a.img", "http://host/a.img", "content")
+	addTestFile(t, mgr, "dir/b.img", "", "content")
+
+	if len(mgr.index.Blobs) != 1 {
+		t.Fatalf("the same content stored %d times", len(mgr.index.Blobs))
+	}
+	for _, name := range []string{"a.img", "dir/b.img"} {
+		filePath, err := mgr.GetFilePath(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filePath != mgr.blobPath(sum) {
+			t.Errorf("%s points to %s", name, filePath)
+		}
+		data, err := os.ReadFile(mgr.aliasPath(name))
+		if err != nil || string(data) != "content" {
+			t.Errorf("cannot read %s by name: %v", name, err)
+		}
+	}
+	if info := mgr.GetFileInfo("a.img"); !info.ISReady || info.Sha256 != sum || info.Size != int64(len("content")) {
+		t.Errorf("unexpected file info %+v", info)
+	}
+
+	// content is removed with the last name
+	if _, err := mgr.Delete(&api.DeleteArg{Name: "a.img"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(mgr.blobPath(sum)); err != nil {
+		t.Error("content removed while referenced")
+	}
+	if _, err := mgr.Delete(&api.DeleteArg{Sha256: sum}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(mgr.blobPath(sum)); !os.IsNotExist(err) {
+		t.Error("content without names is not removed")
+	}
+	if _, err := mgr.Delete(&api.DeleteArg{Name: "a.img"}); err == nil {
+		t.Error("expected error on delete of unknown file")
+	}
+}
+
+func TestStoreGC(t *testing.T) {
+	mgr := newTestManager(t)
+	addTestFile(t, mgr, "old", "", "old-content")
+	addTestFile(t, mgr, "pinned", "", "pinned-content")
+	addTestFile(t, mgr, "new", "", "new-content")
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"pinned", "old", "new"} {
+		mgr.index.Blobs[mgr.index.Aliases[name].Sha256].LastAccess = base.Add(time.Duration(i) * time.Minute)
+	}
+	if err := mgr.Pin("pinned", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mgr.Dir, "partial.tmp"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	maxSize := int64(len("pinned-content") + len("new-content"))
+	result, err := mgr.GC(&api.GCArg{MaxSize: maxSize, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].Name != "old" {
+		t.Fatalf("unexpected dry run result %+v", result.Removed)
+	}
+	if _, ok := mgr.index.Aliases["old"]; !ok {
+		t.Error("dry run removed file")
+	}
+
+	result, err = mgr.GC(&api.GCArg{MaxSize: maxSize, Partial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var removed []string
+	for _, el := range result.Removed {
+		removed = append(removed, el.Name)
+	}
+	if len(removed) != 2 || removed[0] != "old" || removed[1] != "partial.tmp" {
+		t.Errorf("unexpected removed files %q", removed)
+	}
+	if result.Total != maxSize {
+		t.Errorf("total %d, expected %d", result.Total, maxSize)
+	}
+
+	// pinned content stays even if limit is not reached
+	if _, err = mgr.GC(&api.GCArg{MaxSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.GetFilePath("pinned"); err != nil {
+		t.Error("pinned file removed")
+	}
+	if _, err := mgr.GetFilePath("new"); err == nil {
+		t.Error("not pinned file is not removed")
+	}
+}
+
+func TestStoreLegacyImport(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"a.img": "a", "sub/b.img": "b"}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p+".sha256", []byte(testSha256([]byte(content))), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "c.img.tmp"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := &EServerManager{Dir: dir}
+	mgr.Init()
+	names := mgr.ListFileNames()
+	if len(names) != 2 || names[0] != "a.img" || names[1] != "sub/b.img" {
+		t.Fatalf("unexpected imported files %q", names)
+	}
+	for name, content := range files {
+		fi, err := os.Lstat(mgr.aliasPath(name))
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("%s is not replaced with link to content", name)
+		}
+		if _, err := os.Stat(mgr.aliasPath(name) + ".sha256"); !os.IsNotExist(err) {
+			t.Errorf("legacy checksum of %s is not removed", name)
+		}
+		data, err := os.ReadFile(mgr.aliasPath(name))
+		if err != nil || string(data) != content {
+			t.Errorf("wrong content of %s", name)
+		}
+	}
+
+	// index is loaded on restart without import
+	mgr = &EServerManager{Dir: dir}
+	mgr.Init()
+	if len(mgr.ListFileNames()) != 2 {
+		t.Errorf("unexpected files after restart %q", mgr.ListFileNames())
+	}
+}
+
+func TestStoreTouchDelaysSave(t *testing.T) {
+	mgr := newTestManager(t)
+	sum := addTestFile(t, mgr, "a.img", "", "content")
+	saved := readIndex(t, mgr).Blobs[sum].LastAccess
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := mgr.GetFilePath("a.img"); err != nil {
+		t.Fatal(err)
+	}
+	accessed := mgr.index.Blobs[sum].LastAccess
+	if !accessed.After(saved) {
+		t.Fatal("access time is not updated")
+	}
+	if !readIndex(t, mgr).Blobs[sum].LastAccess.Equal(saved) {
+		t.Error("index saved on every access")
+	}
+	if mgr.flushTimer == nil {
+		t.Error("save of index is not scheduled")
+	}
+
+	if err := mgr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !readIndex(t, mgr).Blobs[sum].LastAccess.Equal(accessed) {
+		t.Error("access time is not saved on close")
+	}
+	if mgr.flushTimer != nil {
+		t.Error("save of index is still scheduled after close")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/lf-edge/eden/eserver/api"
@@ -14,6 +15,7 @@ import (
 
 type adminHandler struct {
 	manager *manager.EServerManager
+	server  *EServer
 }
 
 func (h *adminHandler) list(w http.ResponseWriter, _ *http.Request) {
@@ -37,7 +39,7 @@ func (h *adminHandler) addFromURL(w http.ResponseWriter, r *http.Request) {
 		wrapError(err, w)
 		return
 	}
-	name, err := h.manager.AddFile(data.URL)
+	name, err := h.manager.AddFile(data.URL, data.Sha256)
 	if err != nil {
 		wrapError(err, w)
 		return
@@ -89,3 +91,101 @@ func (h *adminHandler) getFileStatus(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(out)
 }
+
+func (h *adminHandler) files(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, h.manager.ListFiles())
+}
+
+func (h *adminHandler) delete(w http.ResponseWriter, r *http.Request) {
+	var data api.DeleteArg
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		wrapError(err, w)
+		return
+	}
+	if data.Name == "" && data.Sha256 == "" {
+		wrapError(fmt.Errorf("name or sha256 required"), w)
+		return
+	}
+	removed, err := h.manager.Delete(&data)
+	if err != nil {
+		wrapError(err, w)
+		return
+	}
+	writeJSON(w, removed)
+}
+
+func (h *adminHandler) pin(w http.ResponseWriter, r *http.Request) {
+	var data api.PinArg
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		wrapError(err, w)
+		return
+	}
+	if err := h.manager.Pin(data.Name, data.Pinned); err != nil {
+		wrapError(err, w)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *adminHandler) gc(w http.ResponseWriter, r *http.Request) {
+	var data api.GCArg
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		wrapError(err, w)
+		return
+	}
+	result, err := h.manager.GC(&data)
+	if err != nil {
+		wrapError(err, w)
+		return
+	}
+	writeJSON(w, result)
+}
+
+func (h *adminHandler) sign(w http.ResponseWriter, r *http.Request) {
+	var data api.SignArg
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		wrapError(err, w)
+		return
+	}
+	if _, err := h.manager.GetFilePath(data.Name); err != nil {
+		wrapError(err, w)
+		return
+	}
+	expires := time.Now().Add(time.Duration(data.Expires) * time.Second)
+	signed, err := h.server.signPath(data.Name, expires)
+	if err != nil {
+		wrapError(err, w)
+		return
+	}
+	writeJSON(w, &api.SignResult{Path: signed, Expires: expires})
+}
+
+func (h *adminHandler) listFaults(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, h.server.faults.list())
+}
+
+func (h *adminHandler) setFault(w http.ResponseWriter, r *http.Request) {
+	var data api.Fault
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		wrapError(err, w)
+		return
+	}
+	if err := h.server.faults.set(&data); err != nil {
+		wrapError(err, w)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *adminHandler) clearFaults(w http.ResponseWriter, r *http.Request) {
+	var data api.FaultClearArg
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		wrapError(err, w)
+		return
+	}
+	if err := h.server.faults.clear(data.Name); err != nil {
+		wrapError(err, w)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
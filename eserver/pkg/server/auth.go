@@ -0,0 +1,133 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	expiresParam   = "expires"
+	signatureParam = "signature"
+
+	// maxClockSkew is allowed difference between time of signed request and time of server
+	maxClockSkew = 15 * time.Minute
+)
+
+func equalSecret(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// adminAuthorized checks bearer token or basic credentials of admin
+func (s *EServer) adminAuthorized(r *http.Request) bool {
+	if s.AdminToken != "" {
+		if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != r.Header.Get("Authorization") {
+			if equalSecret(token, s.AdminToken) {
+				return true
+			}
+		}
+	}
+	if s.AdminUser != "" {
+		if user, password, ok := r.BasicAuth(); ok && equalSecret(user, s.AdminUser) && equalSecret(password, s.AdminPassword) {
+			return true
+		}
+	}
+	return false
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	log.Printf("unauthorized request %s from %s", r.URL.Path, r.RemoteAddr)
+	w.Header().Set("WWW-Authenticate", `Basic realm="eserver"`)
+	w.Header().Add(contentType, mimeTextPlain)
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("forbidden request %s from %s: %s", r.URL.Path, r.RemoteAddr, err)
+	w.Header().Add(contentType, mimeTextPlain)
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = w.Write([]byte(err.Error()))
+}
+
+// adminAuth allows requests with admin token or credentials if any of them is set
+func (s *EServer) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (s.AdminToken != "" || s.AdminUser != "") && !s.adminAuthorized(r) {
+			unauthorized(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// downloadAuth allows requests with valid signature, download or admin credentials,
+// requests without them are allowed if neither download credentials nor signed URLs are required
+func (s *EServer) downloadAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get(signatureParam) != "" {
+			if err := s.verifySignature(mux.Vars(r)["filename"], r.URL.Query()); err != nil {
+				forbidden(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.DownloadUser != "" {
+			if user, password, ok := r.BasicAuth(); ok && equalSecret(user, s.DownloadUser) && equalSecret(password, s.DownloadPassword) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if (s.DownloadUser != "" || s.SignedOnly) && !s.adminAuthorized(r) {
+			unauthorized(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *EServer) signature(name string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.SignKey))
+	_, _ = fmt.Fprintf(mac, "%s\n%d", name, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signPath returns path to file with signature valid until expires
+func (s *EServer) signPath(name string, expires time.Time) (string, error) {
+	if s.SignKey == "" {
+		return "", fmt.Errorf("signed URLs are not enabled")
+	}
+	query := url.Values{}
+	query.Set(expiresParam, strconv.FormatInt(expires.Unix(), 10))
+	query.Set(signatureParam, s.signature(name, expires.Unix()))
+	return fmt.Sprintf("%s?%s", path.Join("eserver", name), query.Encode()), nil
+}
+
+func (s *EServer) verifySignature(name string, query url.Values) error {
+	if s.SignKey == "" {
+		return fmt.Errorf("signed URLs are not enabled")
+	}
+	expires, err := strconv.ParseInt(query.Get(expiresParam), 10, 64)
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %w", expiresParam, err)
+	}
+	if time.Now().Unix() > expires {
+		return fmt.Errorf("signature expired")
+	}
+	if !hmac.Equal([]byte(query.Get(signatureParam)), []byte(s.signature(name, expires))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// downloadRouter returns router serving /eserver/{filename} behind downloadAuth of s
+func downloadRouter(s *EServer) http.Handler {
+	router := mux.NewRouter()
+	router.Handle("/eserver/{filename:[A-Za-z0-9_\\-.\\/]*}", s.downloadAuth(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+	return router
+}
+
+func TestDownloadAuth(t *testing.T) {
+	s := &EServer{
+		DownloadUser:     "user",
+		DownloadPassword: "password",
+		AdminToken:       "token",
+		SignKey:          "key",
+	}
+	signed, err := s.signPath("dir/file.img", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.signPath("dir/file.img", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse("/" + signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		target string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{name: "anonymous", target: "/eserver/dir/file.img", status: http.StatusUnauthorized},
+		{name: "download credentials", target: "/eserver/dir/file.img", status: http.StatusOK,
+			setup: func(r *http.Request) { r.SetBasicAuth("user", "password") }},
+		{name: "wrong password", target: "/eserver/dir/file.img", status: http.StatusUnauthorized,
+			setup: func(r *http.Request) { r.SetBasicAuth("user", "wrong") }},
+		{name: "admin token", target: "/eserver/dir/file.img", status: http.StatusOK,
+			setup: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }},
+		{name: "signed", target: "/" + signed, status: http.StatusOK},
+		{name: "signed for other file", target: "/eserver/other.img?" + u.RawQuery, status: http.StatusForbidden},
+		{name: "expired", target: "/" + expired, status: http.StatusForbidden},
+		{name: "wrong signature", target: "/eserver/dir/file.img?expires=9999999999&signature=00", status: http.StatusForbidden},
+	}
+	router := downloadRouter(s)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.setup != nil {
+				tt.setup(r)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("status %d, expected %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestDownloadAuthOpen(t *testing.T) {
+	for name, tt := range map[string]struct {
+		server *EServer
+		status int
+	}{
+		"no protection": {server: &EServer{}, status: http.StatusOK},
+		"sign key only": {server: &EServer{SignKey: "key"}, status: http.StatusOK},
+		"signed only":   {server: &EServer{SignKey: "key", SignedOnly: true}, status: http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		downloadRouter(tt.server).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/eserver/file.img", nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, expected %d", name, w.Code, tt.status)
+		}
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	s := &EServer{AdminToken: "token", AdminUser: "admin", AdminPassword: "password"}
+	handler := s.adminAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for name, tt := range map[string]struct {
+		setup  func(r *http.Request)
+		status int
+	}{
+		"anonymous":      {setup: func(r *http.Request) {}, status: http.StatusUnauthorized},
+		"token":          {setup: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, status: http.StatusOK},
+		"wrong token":    {setup: func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") }, status: http.StatusUnauthorized},
+		"token no type":  {setup: func(r *http.Request) { r.Header.Set("Authorization", "token") }, status: http.StatusUnauthorized},
+		"basic":          {setup: func(r *http.Request) { r.SetBasicAuth("admin", "password") }, status: http.StatusOK},
+		"download creds": {setup: func(r *http.Request) { r.SetBasicAuth("user", "password") }, status: http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/admin/list", nil)
+		tt.setup(r)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, expected %d", name, w.Code, tt.status)
+		}
+	}
+}
+
+func TestSignPath(t *testing.T) {
+	if _, err := (&EServer{}).signPath("file.img", time.Now()); err == nil {
+		t.Error("expected error without sign key")
+	}
+	s := &EServer{SignKey: "key"}
+	expires := time.Unix(1700000000, 0)
+	signed, err := s.signPath("file.img", expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "eserver/file.img" || u.Query().Get(expiresParam) != "1700000000" {
+		t.Errorf("unexpected signed path %s", signed)
+	}
+	if other := (&EServer{SignKey: "other"}).signature("file.img", expires.Unix()); other == u.Query().Get(signatureParam) {
+		t.Error("signature does not depend on key")
+	}
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lf-edge/eden/eserver/pkg/manager"
+)
+
+// azureHandler serves files as blobs of Azure-Blob-compatible API with path-style requests,
+// blob in container is name of file with container as prefix: /azure/<account>/<container>/<blob> is <container>/<blob>
+type azureHandler struct {
+	manager *manager.EServerManager
+	server  *EServer
+}
+
+const azureVersion = "2020-04-08"
+
+type azureError struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+type azureBlobProperties struct {
+	LastModified  string `xml:"Last-Modified"`
+	Etag          string `xml:"Etag"`
+	ContentLength int64  `xml:"Content-Length"`
+	BlobType      string `xml:"BlobType"`
+}
+
+type azureBlob struct {
+	Name       string               `xml:"Name"`
+	Properties *azureBlobProperties `xml:"Properties"`
+}
+
+type azureEnumerationResults struct {
+	XMLName         xml.Name     `xml:"EnumerationResults"`
+	ServiceEndpoint string       `xml:"ServiceEndpoint,attr"`
+	ContainerName   string       `xml:"ContainerName,attr"`
+	Prefix          string       `xml:"Prefix"`
+	Blobs           []*azureBlob `xml:"Blobs>Blob"`
+	NextMarker      string       `xml:"NextMarker"`
+}
+
+func azureWriteError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("x-ms-error-code", code)
+	writeXML(w, status, &azureError{Code: code, Message: message})
+}
+
+// azureHeaders sets common headers of responses
+func azureHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-version", azureVersion)
+		if id := r.Header.Get("x-ms-client-request-id"); id != "" {
+			w.Header().Set("x-ms-client-request-id", id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func azureEtag(sha string) string {
+	return fmt.Sprintf("\"0x%s\"", strings.ToUpper(sha[:16]))
+}
+
+func (h *azureHandler) container(w http.ResponseWriter, r *http.Request) {
+	container := mux.Vars(r)["container"]
+	query := r.URL.Query()
+	if query.Get("restype") != "container" {
+		azureWriteError(w, http.StatusBadRequest, "InvalidQueryParameterValue", "restype=container expected")
+		return
+	}
+	prefix := query.Get("prefix")
+	result := &azureEnumerationResults{
+		ServiceEndpoint: fmt.Sprintf("http://%s/azure/%s/", r.Host, mux.Vars(r)["account"]),
+		ContainerName:   container,
+		Prefix:          prefix,
+	}
+	found := false
+	for _, el := range h.manager.ListFiles() {
+		blob := strings.TrimPrefix(el.Name, container+"/")
+		if blob == el.Name {
+			continue
+		}
+		found = true
+		if !strings.HasPrefix(blob, prefix) {
+			continue
+		}
+		result.Blobs = append(result.Blobs, &azureBlob{
+			Name: blob,
+			Properties: &azureBlobProperties{
+				LastModified:  el.LastAccess.UTC().Format(http.TimeFormat),
+				Etag:          azureEtag(el.Sha256),
+				ContentLength: el.Size,
+				BlobType:      "BlockBlob",
+			},
+		})
+	}
+	if !found {
+		azureWriteError(w, http.StatusNotFound, "ContainerNotFound", "The specified container does not exist.")
+		return
+	}
+	if query.Get("comp") != "list" {
+		// container properties
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+func (h *azureHandler) blob(w http.ResponseWriter, r *http.Request) {
+	name := path.Join(mux.Vars(r)["container"], mux.Vars(r)["blob"])
+	info := h.manager.GetFileInfo(name)
+	if !info.ISReady {
+		azureWriteError(w, http.StatusNotFound, "BlobNotFound", "The specified blob does not exist.")
+		return
+	}
+	filePath, err := h.manager.GetFilePath(name)
+	if err != nil {
+		azureWriteError(w, http.StatusNotFound, "BlobNotFound", err.Error())
+		return
+	}
+	if rng := r.Header.Get("x-ms-range"); rng != "" {
+		r.Header.Set("Range", rng)
+	}
+	w.Header().Set("ETag", azureEtag(info.Sha256))
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("x-ms-blob-type", "BlockBlob")
+	w.Header().Set("x-ms-server-encrypted", "false")
+	http.ServeFile(w, r, filePath)
+}
+
+// azureAuth verifies Shared Key in Authorization header if download credentials are set,
+// DownloadUser is account name and DownloadPassword is account key, clients use it base64 encoded
+func (h *azureHandler) azureAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.server.DownloadUser == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := h.verify(r); err != nil {
+			azureWriteError(w, http.StatusForbidden, "AuthenticationFailed", err.Error())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *azureHandler) verify(r *http.Request) error {
+	account := mux.Vars(r)["account"]
+	if !equalSecret(account, h.server.DownloadUser) {
+		return fmt.Errorf("unknown account %s", account)
+	}
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "SharedKey ")
+	parts := strings.SplitN(auth, ":", 2)
+	if auth == r.Header.Get("Authorization") || len(parts) != 2 || parts[0] != account {
+		return fmt.Errorf("SharedKey authorization for account %s required", account)
+	}
+	date, err := azureDate(r)
+	if err != nil {
+		return fmt.Errorf("invalid x-ms-date or Date: %w", err)
+	}
+	if skew := time.Since(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("request time is out of range")
+	}
+	contentLength := r.Header.Get("Content-Length")
+	if contentLength == "0" {
+		contentLength = ""
+	}
+	stringToSign := strings.Join([]string{
+		r.Method,
+		r.Header.Get("Content-Encoding"),
+		r.Header.Get("Content-Language"),
+		contentLength,
+		r.Header.Get("Content-MD5"),
+		r.Header.Get("Content-Type"),
+		r.Header.Get("Date"),
+		r.Header.Get("If-Modified-Since"),
+		r.Header.Get("If-Match"),
+		r.Header.Get("If-None-Match"),
+		r.Header.Get("If-Unmodified-Since"),
+		r.Header.Get("Range"),
+		azureCanonicalHeaders(r) + azureCanonicalResource(account, r),
+	}, "\n")
+	mac := hmac.New(sha256.New, []byte(h.server.DownloadPassword))
+	_, _ = mac.Write([]byte(stringToSign))
+	if !hmac.Equal([]byte(base64.StdEncoding.EncodeToString(mac.Sum(nil))), []byte(parts[1])) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func azureCanonicalHeaders(r *http.Request) string {
+	var names []string
+	for name := range r.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	var result strings.Builder
+	for _, name := range names {
+		result.WriteString(fmt.Sprintf("%s:%s\n", name, strings.TrimSpace(r.Header.Get(name))))
+	}
+	return result.String()
+}
+
+func azureCanonicalResource(account string, r *http.Request) string {
+	result := fmt.Sprintf("/%s%s", account, r.URL.Path)
+	query := r.URL.Query()
+	params := map[string][]string{}
+	var keys []string
+	for key, values := range query {
+		lower := strings.ToLower(key)
+		if _, ok := params[lower]; !ok {
+			keys = append(keys, lower)
+		}
+		params[lower] = append(params[lower], values...)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := params[key]
+		sort.Strings(values)
+		result += fmt.Sprintf("\n%s:%s", key, strings.Join(values, ","))
+	}
+	return result
+}
+
+// azureDate returns time of request from x-ms-date or Date headers
+func azureDate(r *http.Request) (time.Time, error) {
+	value := r.Header.Get("x-ms-date")
+	if value == "" {
+		value = r.Header.Get("Date")
+	}
+	return http.ParseTime(value)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// azureSign signs r with SharedKey the way Azure storage clients do
+func azureSign(r *http.Request, account, key string, date time.Time) {
+	r.Header.Set("x-ms-date", date.UTC().Format(http.TimeFormat))
+	r.Header.Set("x-ms-version", "2020-04-08")
+	stringToSign := fmt.Sprintf("%s\n\n\n\n\n\n\n\n\n\n\n%s\n%s%s", r.Method, r.Header.Get("Range"),
+		azureCanonicalHeaders(r), azureCanonicalResource(account, r))
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = mac.Write([]byte(stringToSign))
+	r.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", account, base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+}
+
+func TestAzureCanonicalResource(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/azure/acc/container?restype=container&comp=list&Include=b&include=a", nil)
+	expected := "/acc/azure/acc/container\ncomp:list\ninclude:a,b\nrestype:container"
+	if got := azureCanonicalResource("acc", r); got != expected {
+		t.Errorf("canonical resource %q, expected %q", got, expected)
+	}
+}
+
+func TestAzureCanonicalHeaders(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Ms-Version", "2020-04-08")
+	r.Header.Set("X-Ms-Date", " Mon, 02 Jan 2006 15:04:05 GMT ")
+	r.Header.Set("Range", "bytes=0-1")
+	expected := "x-ms-date:Mon, 02 Jan 2006 15:04:05 GMT\nx-ms-version:2020-04-08\n"
+	if got := azureCanonicalHeaders(r); got != expected {
+		t.Errorf("canonical headers %q, expected %q", got, expected)
+	}
+}
+
+func TestAzureAuth(t *testing.T) {
+	h := &azureHandler{server: &EServer{DownloadUser: "acc", DownloadPassword: "secret"}}
+	router := mux.NewRouter()
+	router.PathPrefix("/azure/{account:[A-Za-z0-9]+}").Handler(h.azureAuth(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+	tests := []struct {
+		name   string
+		target string
+		sign   func(r *http.Request)
+		status int
+	}{
+		{name: "valid", target: "/azure/acc/container/blob.img", status: http.StatusOK,
+			sign: func(r *http.Request) { azureSign(r, "acc", "secret", time.Now()) }},
+		{name: "valid with range", target: "/azure/acc/container/blob.img", status: http.StatusOK,
+			sign: func(r *http.Request) {
+				r.Header.Set("Range", "bytes=0-9")
+				azureSign(r, "acc", "secret", time.Now())
+			}},
+		{name: "unsigned", target: "/azure/acc/container/blob.img", status: http.StatusForbidden,
+			sign: func(r *http.Request) {}},
+		{name: "unknown account", target: "/azure/other/container/blob.img", status: http.StatusForbidden,
+			sign: func(r *http.Request) { azureSign(r, "other", "secret", time.Now()) }},
+		{name: "wrong key", target: "/azure/acc/container/blob.img", status: http.StatusForbidden,
+			sign: func(r *http.Request) { azureSign(r, "acc", "other", time.Now()) }},
+		{name: "too old", target: "/azure/acc/container/blob.img", status: http.StatusForbidden,
+			sign: func(r *http.Request) { azureSign(r, "acc", "secret", time.Now().Add(-time.Hour)) }},
+		{name: "tampered range", target: "/azure/acc/container/blob.img", status: http.StatusForbidden,
+			sign: func(r *http.Request) {
+				azureSign(r, "acc", "secret", time.Now())
+				r.Header.Set("Range", "bytes=0-9")
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			tt.sign(r)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("status %d, expected %d: %s", w.Code, tt.status, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
@@ -9,6 +10,8 @@ import (
 const (
 	contentType   = "Content-Type"
 	mimeTextPlain = "text/plain"
+
+	mimeApplicationJSON = "application/json"
 )
 
 func wrapError(err error, w http.ResponseWriter) {
@@ -17,3 +20,14 @@ func wrapError(err error, w http.ResponseWriter) {
 	w.WriteHeader(http.StatusInternalServerError)
 	_, _ = w.Write([]byte(err.Error()))
 }
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	out, err := json.Marshal(obj)
+	if err != nil {
+		wrapError(err, w)
+		return
+	}
+	w.Header().Add(contentType, mimeApplicationJSON)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(out)
+}
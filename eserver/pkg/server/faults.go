@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lf-edge/eden/eserver/api"
+	"github.com/lf-edge/eden/eserver/pkg/manager"
+)
+
+// rateChunks is number of chunks per second of throttled response
+const rateChunks = 10
+
+// faultInjector stores faults to apply on download of files
+type faultInjector struct {
+	sync.Mutex
+	faults map[string]*api.Fault
+}
+
+func newFaultInjector() *faultInjector {
+	return &faultInjector{faults: map[string]*api.Fault{}}
+}
+
+// set adds or replaces fault with the same name and resets its counters
+func (fi *faultInjector) set(fault *api.Fault) error {
+	if fault.Name == "" {
+		return fmt.Errorf("name required")
+	}
+	if _, err := path.Match(fault.Name, ""); err != nil {
+		return fmt.Errorf("bad pattern %s: %w", fault.Name, err)
+	}
+	if fault.Status != 0 && (fault.Status < 100 || fault.Status > 599) {
+		return fmt.Errorf("bad status %d", fault.Status)
+	}
+	if fault.Rate < 0 || fault.StallAfter < 0 || fault.StallForMs < 0 || fault.DropAfter < 0 || fault.Every < 0 || fault.Count < 0 {
+		return fmt.Errorf("negative values are not allowed")
+	}
+	f := *fault
+	f.Requests, f.Injected = 0, 0
+	fi.Lock()
+	defer fi.Unlock()
+	fi.faults[f.Name] = &f
+	return nil
+}
+
+// clear removes fault with name or all faults if name is empty
+func (fi *faultInjector) clear(name string) error {
+	fi.Lock()
+	defer fi.Unlock()
+	if name == "" {
+		fi.faults = map[string]*api.Fault{}
+		return nil
+	}
+	if _, ok := fi.faults[name]; !ok {
+		return fmt.Errorf("no fault for %s", name)
+	}
+	delete(fi.faults, name)
+	return nil
+}
+
+// list returns faults sorted by name
+func (fi *faultInjector) list() []*api.Fault {
+	fi.Lock()
+	defer fi.Unlock()
+	result := make([]*api.Fault, 0, len(fi.faults))
+	for _, f := range fi.faults {
+		c := *f
+		result = append(result, &c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// match returns fault to apply to the request of file with name, nil if no faults for the request
+// fault with exact name takes precedence over patterns, patterns are checked in lexical order
+func (fi *faultInjector) match(name string) *api.Fault {
+	fi.Lock()
+	defer fi.Unlock()
+	fault, ok := fi.faults[name]
+	if !ok {
+		var patterns []string
+		for pattern := range fi.faults {
+			patterns = append(patterns, pattern)
+		}
+		sort.Strings(patterns)
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, name); matched {
+				fault = fi.faults[pattern]
+				break
+			}
+		}
+	}
+	if fault == nil {
+		return nil
+	}
+	fault.Requests++
+	if fault.Every > 1 && fault.Requests%fault.Every != 0 {
+		return nil
+	}
+	if fault.Count > 0 && fault.Injected >= fault.Count {
+		return nil
+	}
+	fault.Injected++
+	c := *fault
+	return &c
+}
+
+// requestedFile returns name of file requested from /eserver, S3, Azure or registry endpoint
+func requestedFile(r *http.Request) string {
+	vars := mux.Vars(r)
+	if key, ok := vars["key"]; ok {
+		return path.Join(vars["bucket"], key)
+	}
+	if blob, ok := vars["blob"]; ok {
+		return path.Join(vars["container"], blob)
+	}
+	if digest, ok := vars["digest"]; ok {
+		name, _ := manager.OCIBlobName(vars["name"], digest)
+		return name
+	}
+	return vars["filename"]
+}
+
+// injectFaults applies fault matched requested file to response of GET request
+func (s *EServer) injectFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		fault := s.faults.match(requestedFile(r))
+		if fault == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		log.Printf("inject fault %s into request %s from %s", fault.Name, r.URL.Path, r.RemoteAddr)
+		if fault.Status != 0 {
+			w.Header().Add(contentType, mimeTextPlain)
+			w.WriteHeader(fault.Status)
+			_, _ = w.Write([]byte(http.StatusText(fault.Status)))
+			return
+		}
+		next.ServeHTTP(&faultWriter{ResponseWriter: w, ctx: r.Context(), fault: fault, start: time.Now()}, r)
+	})
+}
+
+// faultWriter throttles, stalls, drops or corrupts response according to fault
+type faultWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	fault   *api.Fault
+	start   time.Time
+	written int64
+	stalled bool
+}
+
+func (fw *faultWriter) flush() {
+	if f, ok := fw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// wait sleeps for duration or until client disconnects if duration is 0
+func (fw *faultWriter) wait(duration time.Duration) error {
+	var timer <-chan time.Time
+	if duration > 0 {
+		t := time.NewTimer(duration)
+		defer t.Stop()
+		timer = t.C
+	}
+	select {
+	case <-timer:
+		return nil
+	case <-fw.ctx.Done():
+		return fw.ctx.Err()
+	}
+}
+
+func (fw *faultWriter) Write(p []byte) (int, error) {
+	f := fw.fault
+	n := 0
+	for len(p) > 0 {
+		chunk := p
+		if f.Stall && !fw.stalled {
+			if limit := f.StallAfter - fw.written; limit <= 0 {
+				fw.stalled = true
+				fw.flush()
+				if err := fw.wait(time.Duration(f.StallForMs) * time.Millisecond); err != nil {
+					return n, err
+				}
+				continue
+			} else if int64(len(chunk)) > limit {
+				chunk = chunk[:limit]
+			}
+		}
+		if f.DropAfter > 0 {
+			if limit := f.DropAfter - fw.written; limit <= 0 {
+				fw.flush()
+				// close connection without completion of response
+				panic(http.ErrAbortHandler)
+			} else if int64(len(chunk)) > limit {
+				chunk = chunk[:limit]
+			}
+		}
+		if f.Rate > 0 {
+			if limit := f.Rate/rateChunks + 1; int64(len(chunk)) > limit {
+				chunk = chunk[:limit]
+			}
+		}
+		if f.Corrupt {
+			chunk = append([]byte{}, chunk...)
+			chunk[0] ^= 0xff
+		}
+		m, err := fw.ResponseWriter.Write(chunk)
+		n += m
+		fw.written += int64(m)
+		p = p[m:]
+		if err != nil {
+			return n, err
+		}
+		if f.Rate > 0 {
+			fw.flush()
+			expected := time.Duration(float64(fw.written) / float64(f.Rate) * float64(time.Second))
+			if delay := time.Until(fw.start.Add(expected)); delay > 0 {
+				if err := fw.wait(delay); err != nil {
+					return n, err
+				}
+			}
+		}
+	}
+	return n, nil
+}
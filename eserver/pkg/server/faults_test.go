@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/eserver/api"
+)
+
+func TestFaultInjectorMatch(t *testing.T) {
+	fi := newFaultInjector()
+	for _, f := range []*api.Fault{
+		{Name: "apps/*", Status: http.StatusNotFound},
+		{Name: "*", Status: http.StatusServiceUnavailable},
+		{Name: "apps/disk.img", Status: http.StatusInternalServerError},
+	} {
+		if err := fi.set(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: "apps/disk.img", status: http.StatusInternalServerError},
+		{name: "apps/other.img", status: http.StatusNotFound},
+		{name: "other.img", status: http.StatusServiceUnavailable},
+		{name: "dir/other.img", status: 0},
+	}
+	for _, tt := range tests {
+		fault := fi.match(tt.name)
+		status := 0
+		if fault != nil {
+			status = fault.Status
+		}
+		if status != tt.status {
+			t.Errorf("%s: status %d, expected %d", tt.name, status, tt.status)
+		}
+	}
+}
+
+func TestFaultInjectorEveryCount(t *testing.T) {
+	fi := newFaultInjector()
+	if err := fi.set(&api.Fault{Name: "file", Status: http.StatusServiceUnavailable, Every: 2, Count: 2}); err != nil {
+		t.Fatal(err)
+	}
+	var got []bool
+	for i := 0; i < 6; i++ {
+		got = append(got, fi.match("file") != nil)
+	}
+	expected := []bool{false, true, false, true, false, false}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("injected %v, expected %v", got, expected)
+		}
+	}
+	faults := fi.list()
+	if len(faults) != 1 || faults[0].Requests != 6 || faults[0].Injected != 2 {
+		t.Errorf("unexpected counters %+v", faults)
+	}
+}
+
+func TestFaultInjectorSet(t *testing.T) {
+	fi := newFaultInjector()
+	for _, f := range []*api.Fault{
+		{},
+		{Name: "[", Status: http.StatusNotFound},
+		{Name: "file", Status: 99},
+		{Name: "file", StallForMs: -1},
+	} {
+		if err := fi.set(f); err == nil {
+			t.Errorf("expected error for %+v", f)
+		}
+	}
+	if err := fi.clear("file"); err == nil {
+		t.Error("expected error on clear of missing fault")
+	}
+}
+
+// writeFault writes data through faultWriter with fault and returns received bytes
+func writeFault(ctx context.Context, fault *api.Fault, data []byte) ([]byte, error) {
+	w := httptest.NewRecorder()
+	fw := &faultWriter{ResponseWriter: w, ctx: ctx, fault: fault, start: time.Now()}
+	_, err := fw.Write(data)
+	return w.Body.Bytes(), err
+}
+
+func TestFaultWriterCorrupt(t *testing.T) {
+	data := []byte("content")
+	got, err := writeFault(context.Background(), &api.Fault{Corrupt: true}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(data) || bytes.Equal(got, data) {
+		t.Errorf("content is not corrupted: %q", got)
+	}
+	if string(data) != "content" {
+		t.Error("source buffer modified")
+	}
+}
+
+func TestFaultWriterStallFor(t *testing.T) {
+	start := time.Now()
+	got, err := writeFault(context.Background(), &api.Fault{Stall: true, StallAfter: 2, StallForMs: 100}, []byte("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "content" {
+		t.Errorf("received %q", got)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("stall lasted %s, expected 100ms", elapsed)
+	}
+}
+
+func TestFaultWriterStallUntilDisconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	got, err := writeFault(ctx, &api.Fault{Stall: true, StallAfter: 2}, []byte("content"))
+	if err == nil {
+		t.Error("expected error on disconnect")
+	}
+	if string(got) != "co" {
+		t.Errorf("received %q before stall", got)
+	}
+}
+
+func TestFaultWriterDrop(t *testing.T) {
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("expected abort of handler, got %v", r)
+		}
+	}()
+	_, _ = writeFault(context.Background(), &api.Fault{DropAfter: 3}, []byte("content"))
+}
+
+func TestFaultWriterRate(t *testing.T) {
+	start := time.Now()
+	data := bytes.Repeat([]byte{1}, 100)
+	got, err := writeFault(context.Background(), &api.Fault{Rate: 1000}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("content changed by throttling")
+	}
+	// 100 bytes at 1000 bytes per second
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("throttled write took %s only", elapsed)
+	}
+}
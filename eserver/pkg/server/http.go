@@ -15,20 +15,67 @@ func (s *EServer) serveHTTP(listener net.Listener, errorChan chan error) {
 
 	admin := &adminHandler{
 		manager: s.Manager,
+		server:  s,
 	}
 
 	router := mux.NewRouter()
 
 	ad := router.PathPrefix("/admin").Subrouter()
+	ad.Use(s.adminAuth)
 
 	router.Use(logRequest)
 
 	ad.HandleFunc("/list", admin.list).Methods("GET")
 	ad.HandleFunc("/add-from-url", admin.addFromURL).Methods("POST")
 	ad.HandleFunc("/add-from-file", admin.addFromFile).Methods("POST")
+	ad.HandleFunc("/files", admin.files).Methods("GET")
+	ad.HandleFunc("/delete", admin.delete).Methods("POST")
+	ad.HandleFunc("/pin", admin.pin).Methods("POST")
+	ad.HandleFunc("/gc", admin.gc).Methods("POST")
+	ad.HandleFunc("/sign", admin.sign).Methods("POST")
+	ad.HandleFunc("/faults", admin.listFaults).Methods("GET")
+	ad.HandleFunc("/faults", admin.setFault).Methods("POST")
+	ad.HandleFunc("/faults/clear", admin.clearFaults).Methods("POST")
+	ad.HandleFunc("/oci/import", admin.importOCI).Methods("POST")
+	ad.HandleFunc("/oci/images", admin.listOCI).Methods("GET")
 	ad.HandleFunc("/status/{filename:[A-Za-z0-9_\\-.\\/]*}", admin.getFileStatus).Methods("GET")
 
-	router.HandleFunc("/eserver/{filename:[A-Za-z0-9_\\-.\\/]*}", api.getFile).Methods("GET")
+	s3 := &s3Handler{
+		manager: s.Manager,
+		server:  s,
+	}
+	s3Router := router.PathPrefix("/s3").Subrouter()
+	s3Router.Use(s3.s3Auth)
+	s3Router.HandleFunc("/", s3.listBuckets).Methods("GET")
+	s3Router.HandleFunc("/{bucket:[A-Za-z0-9_\\-.]+}", s3.bucket).Methods("GET", "HEAD")
+	s3Router.HandleFunc("/{bucket:[A-Za-z0-9_\\-.]+}/", s3.bucket).Methods("GET", "HEAD")
+	s3Router.Handle("/{bucket:[A-Za-z0-9_\\-.]+}/{key:[A-Za-z0-9_\\-.\\/]+}", s.injectFaults(http.HandlerFunc(s3.object))).Methods("GET", "HEAD")
+
+	azure := &azureHandler{
+		manager: s.Manager,
+		server:  s,
+	}
+	azureRouter := router.PathPrefix("/azure/{account:[A-Za-z0-9]+}").Subrouter()
+	azureRouter.Use(azureHeaders, azure.azureAuth)
+	azureRouter.HandleFunc("/{container:[A-Za-z0-9\\-]+}", azure.container).Methods("GET", "HEAD")
+	azureRouter.Handle("/{container:[A-Za-z0-9\\-]+}/{blob:[A-Za-z0-9_\\-.\\/]+}", s.injectFaults(http.HandlerFunc(azure.blob))).Methods("GET", "HEAD")
+
+	if s.Registry {
+		registry := &registryHandler{
+			manager: s.Manager,
+		}
+		const repository = "/{name:[a-z0-9]+(?:[._\\-/][a-z0-9]+)*}"
+		v2 := router.PathPrefix("/v2").Subrouter()
+		v2.Use(registryHeaders, s.downloadAuth)
+		v2.HandleFunc("/", registry.base).Methods("GET", "HEAD")
+		v2.HandleFunc("/_catalog", registry.catalog).Methods("GET")
+		v2.HandleFunc(repository+"/tags/list", registry.tags).Methods("GET")
+		v2.HandleFunc(repository+"/manifests/{reference:[A-Za-z0-9_\\-.:]+}", registry.manifest).Methods("GET", "HEAD")
+		v2.Handle(repository+"/blobs/{digest:sha256:[a-fA-F0-9]+}", s.injectFaults(http.HandlerFunc(registry.blob))).Methods("GET", "HEAD")
+		v2.PathPrefix("/").HandlerFunc(registry.unsupported).Methods("POST", "PUT", "PATCH", "DELETE")
+	}
+
+	router.Handle("/eserver/{filename:[A-Za-z0-9_\\-.\\/]*}", s.downloadAuth(s.injectFaults(http.HandlerFunc(api.getFile)))).Methods("GET")
 
 	server := &http.Server{
 		Handler: router,
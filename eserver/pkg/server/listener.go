@@ -53,7 +53,24 @@ func (b bufferedConn) Read(p []byte) (int, error) {
 // MuxListener creates two net.Listener one of them accepts connections that start with "SSH",
 // and another that accepts all others.
 func MuxListener(l net.Listener) (ssh net.Listener, other net.Listener) {
-	sshListener, otherListener := newListener(l), newListener(l)
+	return muxListener(l, 3, func(prefix []byte) bool {
+		return string(prefix) == "SSH"
+	})
+}
+
+// MuxTLSListener creates two net.Listener one of them accepts connections that start with
+// TLS handshake record, and another that accepts all others.
+func MuxTLSListener(l net.Listener) (tls net.Listener, other net.Listener) {
+	return muxListener(l, 1, func(prefix []byte) bool {
+		// content type of TLS handshake record
+		return prefix[0] == 0x16
+	})
+}
+
+// muxListener creates two net.Listener one of them accepts connections with the first
+// prefixLen bytes matched, and another that accepts all others.
+func muxListener(l net.Listener, prefixLen int, match func(prefix []byte) bool) (matched net.Listener, other net.Listener) {
+	matchedListener, otherListener := newListener(l), newListener(l)
 	go func() {
 		for {
 			conn, err := l.Accept()
@@ -65,8 +82,8 @@ func MuxListener(l net.Listener) (ssh net.Listener, other net.Listener) {
 				log.Println("Error SetReadDeadline:", err)
 				continue
 			}
-			bconn := bufferedConn{conn, bufio.NewReaderSize(conn, 3)}
-			p, err := bconn.Peek(3)
+			bconn := bufferedConn{conn, bufio.NewReaderSize(conn, prefixLen)}
+			p, err := bconn.Peek(prefixLen)
 			if err != nil {
 				log.Println("Error peeking into conn:", err)
 				continue
@@ -75,15 +92,14 @@ func MuxListener(l net.Listener) (ssh net.Listener, other net.Listener) {
 				log.Println("Error SetReadDeadline:", err)
 				continue
 			}
-			prefix := string(p)
 			selectedListener := otherListener
-			if prefix == "SSH" {
-				selectedListener = sshListener
+			if match(p) {
+				selectedListener = matchedListener
 			}
 			if selectedListener.accept != nil {
 				selectedListener.accept <- bconn
 			}
 		}
 	}()
-	return sshListener, otherListener
+	return matchedListener, otherListener
 }
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/lf-edge/eden/eserver/api"
+	"github.com/lf-edge/eden/eserver/pkg/manager"
+)
+
+// registryHandler serves stored images with read-only subset of OCI distribution API
+type registryHandler struct {
+	manager *manager.EServerManager
+}
+
+const (
+	registryVersionHeader = "Docker-Distribution-API-Version"
+	registryDigestHeader  = "Docker-Content-Digest"
+)
+
+type registryError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type registryErrors struct {
+	Errors []*registryError `json:"errors"`
+}
+
+type registryTags struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+type registryCatalog struct {
+	Repositories []string `json:"repositories"`
+}
+
+func registryWriteError(w http.ResponseWriter, status int, code string, err error) {
+	out, _ := json.Marshal(&registryErrors{Errors: []*registryError{{Code: code, Message: err.Error()}}})
+	w.Header().Set(contentType, mimeApplicationJSON)
+	w.WriteHeader(status)
+	_, _ = w.Write(out)
+}
+
+// registryHeaders sets version of API in responses
+func registryHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(registryVersionHeader, "registry/2.0")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *registryHandler) base(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, struct{}{})
+}
+
+func (h *registryHandler) catalog(w http.ResponseWriter, _ *http.Request) {
+	result := &registryCatalog{Repositories: h.manager.OCIRepositories()}
+	if result.Repositories == nil {
+		result.Repositories = []string{}
+	}
+	writeJSON(w, result)
+}
+
+func (h *registryHandler) tags(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	tags := h.manager.OCITags(name)
+	if len(tags) == 0 {
+		registryWriteError(w, http.StatusNotFound, "NAME_UNKNOWN", fmt.Errorf("repository %s not found", name))
+		return
+	}
+	writeJSON(w, &registryTags{Name: name, Tags: tags})
+}
+
+func (h *registryHandler) manifest(w http.ResponseWriter, r *http.Request) {
+	filePath, digest, mediaType, err := h.manager.OCIManifest(mux.Vars(r)["name"], mux.Vars(r)["reference"])
+	if err != nil {
+		registryWriteError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", err)
+		return
+	}
+	w.Header().Set(contentType, mediaType)
+	w.Header().Set(registryDigestHeader, digest)
+	w.Header().Set("ETag", strconv.Quote(digest))
+	http.ServeFile(w, r, filePath)
+}
+
+func (h *registryHandler) blob(w http.ResponseWriter, r *http.Request) {
+	digest := mux.Vars(r)["digest"]
+	filePath, err := h.manager.OCIBlob(mux.Vars(r)["name"], digest)
+	if err != nil {
+		registryWriteError(w, http.StatusNotFound, "BLOB_UNKNOWN", err)
+		return
+	}
+	w.Header().Set(contentType, "application/octet-stream")
+	w.Header().Set(registryDigestHeader, digest)
+	w.Header().Set("ETag", strconv.Quote(digest))
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeFile(w, r, filePath)
+}
+
+// unsupported rejects requests to modify images, they are added with /admin/oci/import
+func (h *registryHandler) unsupported(w http.ResponseWriter, _ *http.Request) {
+	registryWriteError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", fmt.Errorf("push is not supported, use import"))
+}
+
+// importOCI imports images from archive in body of request
+func (h *adminHandler) importOCI(w http.ResponseWriter, r *http.Request) {
+	images, err := h.manager.ImportOCI(r.Body, r.URL.Query().Get("name"))
+	if err != nil {
+		wrapError(err, w)
+		return
+	}
+	writeJSON(w, images)
+}
+
+func (h *adminHandler) listOCI(w http.ResponseWriter, _ *http.Request) {
+	images := h.manager.ListOCIImages()
+	if images == nil {
+		images = []*api.OCIImage{}
+	}
+	writeJSON(w, images)
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lf-edge/eden/eserver/pkg/manager"
+)
+
+// s3Handler serves files as objects of S3-compatible API with path-style requests,
+// object key in bucket is name of file with bucket as prefix: /s3/<bucket>/<key> is <bucket>/<key>
+type s3Handler struct {
+	manager *manager.EServerManager
+	server  *EServer
+}
+
+const (
+	s3Namespace    = "http://s3.amazonaws.com/doc/2006-03-01/"
+	s3Algorithm    = "AWS4-HMAC-SHA256"
+	s3TimeFormat   = "20060102T150405Z"
+	s3UnsignedBody = "UNSIGNED-PAYLOAD"
+)
+
+type s3Error struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+type s3Object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type s3ListBucketResult struct {
+	XMLName     xml.Name    `xml:"ListBucketResult"`
+	Xmlns       string      `xml:"xmlns,attr"`
+	Name        string      `xml:"Name"`
+	Prefix      string      `xml:"Prefix"`
+	KeyCount    int         `xml:"KeyCount"`
+	MaxKeys     int         `xml:"MaxKeys"`
+	IsTruncated bool        `xml:"IsTruncated"`
+	Contents    []*s3Object `xml:"Contents"`
+}
+
+type s3Bucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type s3ListAllMyBucketsResult struct {
+	XMLName xml.Name    `xml:"ListAllMyBucketsResult"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Owner   string      `xml:"Owner>ID"`
+	Buckets []*s3Bucket `xml:"Buckets>Bucket"`
+}
+
+func writeXML(w http.ResponseWriter, status int, obj interface{}) {
+	out, err := xml.Marshal(obj)
+	if err != nil {
+		wrapError(err, w)
+		return
+	}
+	w.Header().Set(contentType, "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(out)
+}
+
+func s3WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	writeXML(w, status, &s3Error{Code: code, Message: message, Resource: r.URL.Path})
+}
+
+// buckets returns names of buckets, i.e. the first directories of files
+func (h *s3Handler) buckets() map[string]time.Time {
+	result := map[string]time.Time{}
+	for _, el := range h.manager.ListFiles() {
+		parts := strings.SplitN(el.Name, "/", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		if t, ok := result[parts[0]]; !ok || el.LastAccess.Before(t) {
+			result[parts[0]] = el.LastAccess
+		}
+	}
+	return result
+}
+
+func (h *s3Handler) listBuckets(w http.ResponseWriter, _ *http.Request) {
+	result := &s3ListAllMyBucketsResult{Xmlns: s3Namespace, Owner: "eserver"}
+	for name, created := range h.buckets() {
+		result.Buckets = append(result.Buckets, &s3Bucket{Name: name, CreationDate: created.UTC().Format(time.RFC3339)})
+	}
+	sort.Slice(result.Buckets, func(i, j int) bool { return result.Buckets[i].Name < result.Buckets[j].Name })
+	writeXML(w, http.StatusOK, result)
+}
+
+func (h *s3Handler) bucket(w http.ResponseWriter, r *http.Request) {
+	bucket := mux.Vars(r)["bucket"]
+	if _, ok := h.buckets()[bucket]; !ok {
+		s3WriteError(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+	result := &s3ListBucketResult{Xmlns: s3Namespace, Name: bucket, Prefix: prefix, MaxKeys: 1000}
+	for _, el := range h.manager.ListFiles() {
+		key := strings.TrimPrefix(el.Name, bucket+"/")
+		if key == el.Name || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		result.Contents = append(result.Contents, &s3Object{
+			Key:          key,
+			LastModified: el.LastAccess.UTC().Format(time.RFC3339),
+			ETag:         fmt.Sprintf("%q", el.Sha256),
+			Size:         el.Size,
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents)
+	writeXML(w, http.StatusOK, result)
+}
+
+func (h *s3Handler) object(w http.ResponseWriter, r *http.Request) {
+	name := path.Join(mux.Vars(r)["bucket"], mux.Vars(r)["key"])
+	info := h.manager.GetFileInfo(name)
+	if !info.ISReady {
+		s3WriteError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+		return
+	}
+	filePath, err := h.manager.GetFilePath(name)
+	if err != nil {
+		s3WriteError(w, r, http.StatusNotFound, "NoSuchKey", err.Error())
+		return
+	}
+	w.Header().Set("ETag", fmt.Sprintf("%q", info.Sha256))
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeFile(w, r, filePath)
+}
+
+// s3Auth verifies AWS signature version 4 in Authorization header if download credentials are set,
+// DownloadUser is access key id and DownloadPassword is secret access key
+func (h *s3Handler) s3Auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.server.DownloadUser == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := h.verify(r); err != nil {
+			s3WriteError(w, r, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *s3Handler) verify(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, s3Algorithm+" ") {
+		return fmt.Errorf("%s authorization required", s3Algorithm)
+	}
+	params := map[string]string{}
+	for _, el := range strings.Split(strings.TrimPrefix(auth, s3Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(el), "=", 2)
+		if len(kv) == 2 {
+			params[kv[0]] = kv[1]
+		}
+	}
+	credential := strings.SplitN(params["Credential"], "/", 2)
+	if len(credential) != 2 || params["SignedHeaders"] == "" || params["Signature"] == "" {
+		return fmt.Errorf("malformed authorization header")
+	}
+	if !equalSecret(credential[0], h.server.DownloadUser) {
+		return fmt.Errorf("invalid access key id")
+	}
+	scope := credential[1]
+	amzDate := r.Header.Get("X-Amz-Date")
+	date, err := time.Parse(s3TimeFormat, amzDate)
+	if err != nil {
+		return fmt.Errorf("invalid X-Amz-Date: %w", err)
+	}
+	if skew := time.Since(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("request time is out of range")
+	}
+	signedHeaders := strings.Split(params["SignedHeaders"], ";")
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(fmt.Sprintf("%s:%s\n", name, strings.Join(strings.Fields(value), " ")))
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = s3UnsignedBody
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		s3CanonicalQuery(r.URL.Query()),
+		canonicalHeaders.String(),
+		params["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+	key := []byte("AWS4" + h.server.DownloadPassword)
+	for _, el := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, el)
+	}
+	if !hmac.Equal([]byte(hex.EncodeToString(hmacSHA256(key, stringToSign))), []byte(params["Signature"])) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery returns query sorted by key with values encoded according to RFC 3986
+func s3CanonicalQuery(query url.Values) string {
+	var result []string
+	for key, values := range query {
+		for _, value := range values {
+			result = append(result, fmt.Sprintf("%s=%s", s3Escape(key), s3Escape(value)))
+		}
+	}
+	sort.Strings(result)
+	return strings.Join(result, "&")
+}
+
+func s3Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
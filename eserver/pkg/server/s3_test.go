@@ -0,0 +1,109 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// s3Sign signs r with AWS signature version 4 the way S3 clients do
+func s3Sign(r *http.Request, accessKey, secretKey string, date time.Time) {
+	amzDate := date.UTC().Format(s3TimeFormat)
+	scope := fmt.Sprintf("%s/us-east-1/s3/aws4_request", amzDate[:8])
+	r.Header.Set("X-Amz-Date", amzDate)
+	r.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		s3CanonicalQuery(r.URL.Query()),
+		fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", r.Host, s3UnsignedBody, amzDate),
+		signedHeaders,
+		s3UnsignedBody,
+	}, "\n")
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(sha256Sum(canonicalRequest))}, "\n")
+	key := hmacSHA256([]byte("AWS4"+secretKey), amzDate[:8])
+	key = hmacSHA256(key, "us-east-1")
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	r.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, accessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+func sha256Sum(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+func TestS3SigningKey(t *testing.T) {
+	// example of signing key derivation from AWS documentation
+	key := hmacSHA256([]byte("AWS4wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"), "20120215")
+	for _, el := range []string{"us-east-1", "iam", "aws4_request"} {
+		key = hmacSHA256(key, el)
+	}
+	expected := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != expected {
+		t.Errorf("signing key %s, expected %s", got, expected)
+	}
+}
+
+func TestS3CanonicalQuery(t *testing.T) {
+	query := url.Values{"prefix": {"a b/c"}, "list-type": {"2"}, "delimiter": {"/"}}
+	expected := "delimiter=%2F&list-type=2&prefix=a%20b%2Fc"
+	if got := s3CanonicalQuery(query); got != expected {
+		t.Errorf("canonical query %s, expected %s", got, expected)
+	}
+}
+
+func TestS3Verify(t *testing.T) {
+	h := &s3Handler{server: &EServer{DownloadUser: "access", DownloadPassword: "secret"}}
+	tests := []struct {
+		name    string
+		sign    func(r *http.Request)
+		wantErr bool
+	}{
+		{name: "valid", sign: func(r *http.Request) { s3Sign(r, "access", "secret", time.Now()) }},
+		{name: "unsigned", sign: func(r *http.Request) {}, wantErr: true},
+		{name: "wrong access key", sign: func(r *http.Request) { s3Sign(r, "other", "secret", time.Now()) }, wantErr: true},
+		{name: "wrong secret", sign: func(r *http.Request) { s3Sign(r, "access", "other", time.Now()) }, wantErr: true},
+		{name: "too old", sign: func(r *http.Request) { s3Sign(r, "access", "secret", time.Now().Add(-time.Hour)) }, wantErr: true},
+		{name: "tampered query", sign: func(r *http.Request) {
+			s3Sign(r, "access", "secret", time.Now())
+			r.URL.RawQuery = "prefix=other"
+		}, wantErr: true},
+		{name: "malformed", sign: func(r *http.Request) {
+			r.Header.Set("Authorization", s3Algorithm+" Credential=access")
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/s3/bucket/?prefix=dir%2F", nil)
+			tt.sign(r)
+			err := h.verify(r)
+			if tt.wantErr && err == nil {
+				t.Error("expected error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestS3AuthOpen(t *testing.T) {
+	h := &s3Handler{server: &EServer{}}
+	handler := h.s3Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/s3/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status %d without download credentials", w.Code)
+	}
+}
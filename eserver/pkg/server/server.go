@@ -1,10 +1,14 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/lf-edge/eden/eserver/pkg/manager"
 )
@@ -17,6 +21,25 @@ type EServer struct {
 	User     string
 	Password string
 	ReadOnly bool
+
+	// CertFile and KeyFile enables TLS on the same port with plain HTTP
+	CertFile string
+	KeyFile  string
+	// AdminToken and AdminUser with AdminPassword protect /admin endpoints
+	AdminToken    string
+	AdminUser     string
+	AdminPassword string
+	// DownloadUser and DownloadPassword protect /eserver endpoint
+	DownloadUser     string
+	DownloadPassword string
+	// SignKey enables signed URLs for /eserver endpoint
+	SignKey string
+	// SignedOnly requires signed URL or credentials for /eserver endpoint
+	SignedOnly bool
+	// Registry enables read-only OCI distribution API on /v2 endpoint
+	Registry bool
+
+	faults *faultInjector
 }
 
 // log the request and client
@@ -31,14 +54,27 @@ func logRequest(next http.Handler) http.Handler {
 //  /admin/list endpoint returns list of files
 //  /admin/add-from-url endpoint fires download
 //  /admin/status/{filename} returns fileinfo
+//  /admin/files returns stored files
+//  /admin/delete removes file
+//  /admin/pin protects file from garbage collection
+//  /admin/gc collects garbage
+//  /admin/sign returns signed path to file
+//  /admin/faults returns or sets faults on download of files
+//  /admin/faults/clear removes faults
+//  /admin/oci/import imports images from archive
+//  /admin/oci/images returns stored images
 //  /eserver/{filename} returns file
+//  /v2/ serves stored images if Registry is enabled
 func (s *EServer) Start() {
 
 	s.Manager.Init()
+	s.faults = newFaultInjector()
 
 	log.Println("Starting eserver:")
 	log.Printf("\tIP:Port: %s:%s\n", s.Address, s.Port)
 	log.Printf("\tDirectory: %s\n", s.Manager.Dir)
+	log.Printf("\tTLS: %t\n", s.CertFile != "")
+	log.Printf("\tRegistry: %t\n", s.Registry)
 
 	// server both services (sftp and http) on the same port
 	l, err := net.Listen("tcp", fmt.Sprintf("%s:%s", s.Address, s.Port))
@@ -47,7 +83,26 @@ func (s *EServer) Start() {
 	}
 	sshListener, httpListener := MuxListener(l)
 	errorChan := make(chan error)
+	if s.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			log.Fatalf("LoadX509KeyPair error: %s", err)
+		}
+		tlsListener, plainListener := MuxTLSListener(httpListener)
+		go s.serveHTTP(tls.NewListener(tlsListener, &tls.Config{Certificates: []tls.Certificate{cert}}), errorChan)
+		httpListener = plainListener
+	}
 	go s.serveHTTP(httpListener, errorChan)
 	go s.serveSFTP(sshListener, errorChan)
-	log.Println(<-errorChan)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errorChan:
+		log.Println(err)
+	case sig := <-sigChan:
+		log.Printf("Received %s, stopping", sig)
+	}
+	if err := s.Manager.Close(); err != nil {
+		log.Printf("Cannot save index: %s", err)
+	}
 }
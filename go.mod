@@ -23,6 +23,7 @@ require (
 	github.com/moby/term v0.0.0-20210619224110-3f7ff695adc6
 	github.com/nerd2/gexto v0.0.0-20190529073929-39468ec063f6
 	github.com/packethost/packngo v0.25.0
+	github.com/prometheus/client_golang v1.12.2
 	github.com/rogpeppe/go-internal v1.6.2
 	github.com/satori/go.uuid v1.2.1-0.20181028125025-b2ce2384e17b
 	github.com/sirupsen/logrus v1.8.1
@@ -104,7 +105,6 @@ require (
 	github.com/pelletier/go-toml/v2 v2.0.2 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/prometheus/client_golang v1.12.2 // indirect
 	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.36.0 // indirect
 	github.com/prometheus/procfs v0.7.3 // indirect
@@ -130,6 +130,8 @@ require (
 	gotest.tools/v3 v3.3.0 // indirect
 )
 
+replace github.com/lf-edge/eden/eserver => ./eserver
+
 replace github.com/lf-edge/eden/sdn/vm => ./sdn/vm
 
 replace github.com/lf-edge/eve/libs/depgraph => github.com/lf-edge/eve/libs/depgraph v0.0.0-20220711144346-0659e3b03496
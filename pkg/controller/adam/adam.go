@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lf-edge/eden/pkg/controller/archive"
 	"github.com/lf-edge/eden/pkg/controller/cachers"
 	"github.com/lf-edge/eden/pkg/controller/eapps"
 	"github.com/lf-edge/eden/pkg/controller/eflowlog"
@@ -39,11 +40,12 @@ type Ctx struct {
 	serverCA          string
 	insecureTLS       bool
 	AdamRemote        bool
-	AdamRemoteRedis   bool   //use redis for obtain logs and info
-	AdamRedisURLEden  string //string with redis url for obtain logs and info
-	AdamCaching       bool   //enable caching of adam`s logs/info
-	AdamCachingRedis  bool   //caching to redis instead of files
-	AdamCachingPrefix string //custom prefix for file or stream naming for cache
+	AdamRemoteRedis   bool            //use redis for obtain logs and info
+	AdamRedisURLEden  string          //string with redis url for obtain logs and info
+	AdamCaching       bool            //enable caching of adam`s logs/info
+	AdamCachingRedis  bool            //caching to redis instead of files
+	AdamCachingPrefix string          //custom prefix for file or stream naming for cache
+	Archive           *archive.Writer //record objects delivered by loaders into archive if set
 }
 
 // parseRedisURL try to use string from config to obtain redis url
@@ -131,6 +133,9 @@ func (adam *Ctx) getLoader() (loader loaders.Loader) {
 		}
 		loader.SetRemoteCache(cache)
 	}
+	if adam.Archive != nil {
+		loader = archive.NewRecorder(loader, adam.Archive)
+	}
 	return
 }
 
@@ -146,6 +151,13 @@ func (adam *Ctx) InitWithVars(vars *utils.ConfigVars) error {
 	adam.AdamCachingRedis = vars.AdamCachingRedis
 	adam.AdamCachingPrefix = vars.AdamCachingPrefix
 	adam.AdamRedisURLEden = vars.AdamRedisURLEden
+	if archivePath := os.Getenv(defaults.DefaultArchiveEnv); archivePath != "" && adam.Archive == nil {
+		w, err := archive.OpenShared(archivePath)
+		if err != nil {
+			return err
+		}
+		adam.Archive = w
+	}
 	return nil
 }
 
@@ -221,6 +233,11 @@ func (adam *Ctx) LogAppsLastCallback(devUUID uuid.UUID, appUUID uuid.UUID, q map
 	return eapps.LogLast(loader, q, handler)
 }
 
+// LogAppsRange process app logs inside of the time range and after the cursor from rng and returns cursor to resume from
+func (adam *Ctx) LogAppsRange(devUUID uuid.UUID, appUUID uuid.UUID, q map[string]string, handler eapps.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return eapps.LogRange(adam.getLoader(), devUUID, appUUID, q, handler, rng)
+}
+
 // LogChecker check logs by pattern from existence files with LogLast and use LogWatchWithTimeout with timeout for observe new files
 func (adam *Ctx) LogChecker(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, mode elog.LogCheckerMode, timeout time.Duration) (err error) {
 	return elog.LogChecker(adam.getLoader(), devUUID, q, handler, mode, timeout)
@@ -233,6 +250,11 @@ func (adam *Ctx) LogLastCallback(devUUID uuid.UUID, q map[string]string, handler
 	return elog.LogLast(loader, q, handler)
 }
 
+// LogRange process logs inside of the time range and after the cursor from rng and returns cursor to resume from
+func (adam *Ctx) LogRange(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return elog.LogRange(adam.getLoader(), devUUID, q, handler, rng)
+}
+
 // FlowLogChecker check FlowLogs by pattern from existence files with FlowLogLast and use FlowLogWatchWithTimeout with timeout for observe new files
 func (adam *Ctx) FlowLogChecker(devUUID uuid.UUID, q map[string]string, handler eflowlog.HandlerFunc, mode eflowlog.FlowLogCheckerMode, timeout time.Duration) (err error) {
 	return eflowlog.FlowLogChecker(adam.getLoader(), devUUID, q, handler, mode, timeout)
@@ -245,6 +267,11 @@ func (adam *Ctx) FlowLogLastCallback(devUUID uuid.UUID, q map[string]string, han
 	return eflowlog.FlowLogLast(loader, q, handler)
 }
 
+// FlowLogRange process FlowLogs inside of the time range and after the cursor from rng and returns cursor to resume from
+func (adam *Ctx) FlowLogRange(devUUID uuid.UUID, q map[string]string, handler eflowlog.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return eflowlog.FlowLogRange(adam.getLoader(), devUUID, q, handler, rng)
+}
+
 // InfoChecker checks the information in the regular expression pattern 'query' and processes the info.ZInfoMsg found by the function 'handler' from existing files (mode=einfo.InfoExist), new files (mode=einfo.InfoNew) or any of them (mode=einfo.InfoAny) with timeout.
 func (adam *Ctx) InfoChecker(devUUID uuid.UUID, q map[string]string, handler einfo.HandlerFunc, mode einfo.InfoCheckerMode, timeout time.Duration) (err error) {
 	return einfo.InfoChecker(adam.getLoader(), devUUID, q, handler, mode, timeout)
@@ -257,6 +284,11 @@ func (adam *Ctx) InfoLastCallback(devUUID uuid.UUID, q map[string]string, handle
 	return einfo.InfoLast(loader, q, einfo.ZInfoFind, handler)
 }
 
+// InfoRange process info inside of the time range and after the cursor from rng and returns cursor to resume from
+func (adam *Ctx) InfoRange(devUUID uuid.UUID, q map[string]string, handler einfo.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return einfo.InfoRange(adam.getLoader(), devUUID, q, handler, rng)
+}
+
 // MetricChecker check metrics by pattern from existence files with LogLast and use LogWatchWithTimeout with timeout for observe new files
 func (adam *Ctx) MetricChecker(devUUID uuid.UUID, q map[string]string, handler emetric.HandlerFunc, mode emetric.MetricCheckerMode, timeout time.Duration) (err error) {
 	return emetric.MetricChecker(adam.getLoader(), devUUID, q, handler, mode, timeout)
@@ -269,6 +301,11 @@ func (adam *Ctx) MetricLastCallback(devUUID uuid.UUID, q map[string]string, hand
 	return emetric.MetricLast(loader, q, handler)
 }
 
+// MetricRange process metrics inside of the time range and after the cursor from rng and returns cursor to resume from
+func (adam *Ctx) MetricRange(devUUID uuid.UUID, q map[string]string, handler emetric.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return emetric.MetricRange(adam.getLoader(), devUUID, q, handler, rng)
+}
+
 // OnboardRemove remove onboard by onboardUUID
 func (adam *Ctx) OnboardRemove(onboardUUID string) (err error) {
 	return adam.deleteObj(path.Join("/admin/onboard", onboardUUID))
@@ -0,0 +1,180 @@
+// Package archive provides recording of objects delivered by loaders into single compressed file
+// and replaying of them with Player, which implements loaders.Loader.
+//
+// Archive is a sequence of gzip members, each of them contains one JSON-encoded Record.
+// Such file stays readable even if recording process was killed and can be appended by subsequent runs:
+// records already stored in the file are not duplicated.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/types"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Record is one object delivered by loader
+type Record struct {
+	Time   time.Time              `json:"time"`
+	Type   types.LoaderObjectType `json:"type"`
+	Device uuid.UUID              `json:"device"`
+	App    uuid.UUID              `json:"app"`
+	Data   []byte                 `json:"data"`
+}
+
+// Writer appends records into archive file
+type Writer struct {
+	mu   sync.Mutex
+	file *os.File
+	// seen contains hashes of recorded objects to not duplicate objects loaded several times
+	seen map[[sha256.Size]byte]struct{}
+	// shared is set for writers opened with OpenShared
+	shared bool
+}
+
+// Open opens archive file for appending records, file will be created if not exists
+// records already stored in the file are loaded to skip them on Write
+func Open(path string) (*Writer, error) {
+	seen := map[[sha256.Size]byte]struct{}{}
+	if _, err := os.Stat(path); err == nil {
+		records, err := Read(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			seen[rec.hash()] = struct{}{}
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open archive %s: %w", path, err)
+	}
+	return &Writer{file: f, seen: seen}, nil
+}
+
+var (
+	sharedMu      sync.Mutex
+	sharedWriters = map[string]*Writer{}
+)
+
+// OpenShared returns Writer for path shared by all users inside of process
+// writers opened this way must be closed with CloseShared
+func OpenShared(path string) (*Writer, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if w, ok := sharedWriters[path]; ok {
+		return w, nil
+	}
+	w, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	w.shared = true
+	sharedWriters[path] = w
+	return w, nil
+}
+
+// CloseShared closes all writers opened with OpenShared
+func CloseShared() error {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	var result error
+	for path, w := range sharedWriters {
+		if err := w.Close(); err != nil && result == nil {
+			result = fmt.Errorf("cannot close archive %s: %w", path, err)
+		}
+		delete(sharedWriters, path)
+	}
+	return result
+}
+
+// hash identifies record by type, device, app and data
+func (rec Record) hash() [sha256.Size]byte {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%d:%s:%s:", rec.Type, rec.Device, rec.App)
+	_, _ = h.Write(rec.Data)
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// Write appends record into archive as separate gzip member
+// records with the same type, device, app and data written before are skipped
+func (w *Writer) Write(rec Record) error {
+	sum := rec.hash()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.seen[sum]; ok {
+		return nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(rec); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if _, err := w.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	w.seen[sum] = struct{}{}
+	return nil
+}
+
+// Close closes archive file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// Read returns records from archive file sorted by time
+func Read(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open archive %s: %w", path, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot read archive %s: %w", path, err)
+	}
+	defer zr.Close()
+	var records []Record
+	dec := json.NewDecoder(zr)
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				// recording was interrupted, use records written before
+				log.Warnf("archive %s is truncated after %d records", path, len(records))
+				break
+			}
+			return nil, fmt.Errorf("cannot decode record %d of archive %s: %w", len(records), path, err)
+		}
+		records = append(records, rec)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
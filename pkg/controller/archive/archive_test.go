@@ -0,0 +1,194 @@
+package archive_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/archive"
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eve/api/go/flowlog"
+	"github.com/lf-edge/eve/api/go/info"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	devUUID := uuid.Must(uuid.NewV4())
+	path := filepath.Join(t.TempDir(), "telemetry.gz")
+	w, err := archive.Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	start := time.Now().Add(-time.Hour)
+	for i, data := range []string{"first", "second", "second", "third"} {
+		assert.NoError(t, w.Write(archive.Record{
+			Time:   start.Add(time.Duration(i) * time.Second),
+			Type:   types.InfoType,
+			Device: devUUID,
+			Data:   []byte(data),
+		}))
+	}
+	assert.NoError(t, w.Close())
+
+	records, err := archive.Read(path)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	recordedPath := filepath.Join(t.TempDir(), "recorded.gz")
+	recordedWriter, err := archive.Open(recordedPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	loader := archive.NewRecorder(archive.NewPlayer(records, 10), recordedWriter)
+	loader.SetUUID(devUUID)
+
+	var existing []string
+	assert.NoError(t, loader.ProcessExisting(func(data []byte) (bool, error) {
+		existing = append(existing, string(data))
+		return true, nil
+	}, types.InfoType))
+	assert.Equal(t, []string{"first"}, existing)
+
+	var streamed []string
+	err = loader.ProcessStream(func(data []byte) (bool, error) {
+		streamed = append(streamed, string(data))
+		return true, nil
+	}, types.InfoType, 10)
+	assert.ErrorIs(t, err, archive.ErrEndOfArchive)
+	assert.Equal(t, []string{"second", "third"}, streamed)
+
+	recorded, err := archive.Read(recordedPath)
+	assert.NoError(t, err)
+	assert.Len(t, recorded, 3)
+}
+
+func TestAppendAcrossRuns(t *testing.T) {
+	t.Parallel()
+
+	devUUID := uuid.Must(uuid.NewV4())
+	path := filepath.Join(t.TempDir(), "telemetry.gz")
+	for _, run := range [][]string{{"first", "second"}, {"second", "third"}} {
+		w, err := archive.Open(path)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, data := range run {
+			assert.NoError(t, w.Write(archive.Record{
+				Time:   time.Now(),
+				Type:   types.InfoType,
+				Device: devUUID,
+				Data:   []byte(data),
+			}))
+		}
+		assert.NoError(t, w.Close())
+	}
+	records, err := archive.Read(path)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestRecorderUsesObjectTime(t *testing.T) {
+	t.Parallel()
+
+	devUUID := uuid.Must(uuid.NewV4())
+	deviceTime := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	data, err := proto.Marshal(&info.ZInfoMsg{
+		DevId:       devUUID.String(),
+		AtTimeStamp: timestamppb.New(deviceTime),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	source := archive.NewPlayer([]archive.Record{{Time: time.Now(), Type: types.InfoType, Device: devUUID, Data: data}}, 1)
+
+	path := filepath.Join(t.TempDir(), "recorded.gz")
+	w, err := archive.Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	recorder := archive.NewRecorder(source, w)
+	recorder.SetUUID(devUUID)
+	assert.NoError(t, recorder.ProcessExisting(func([]byte) (bool, error) {
+		return true, nil
+	}, types.InfoType))
+	assert.NoError(t, recorder.Close())
+
+	records, err := archive.Read(path)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.True(t, deviceTime.Equal(records[0].Time), records[0].Time)
+	}
+}
+
+func TestRecorderUsesFlowLogTime(t *testing.T) {
+	t.Parallel()
+
+	devUUID := uuid.Must(uuid.NewV4())
+	start := time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC)
+	data, err := proto.Marshal(&flowlog.FlowMessage{
+		DevId: devUUID.String(),
+		Flows: []*flowlog.FlowRecord{{
+			StartTime: timestamppb.New(start),
+			EndTime:   timestamppb.New(start.Add(time.Minute)),
+		}},
+		DnsReqs: []*flowlog.DnsRequest{{RequestTime: timestamppb.New(start.Add(-time.Minute))}},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	source := archive.NewPlayer([]archive.Record{{Time: time.Now(), Type: types.FlowLogType, Device: devUUID, Data: data}}, 1)
+
+	path := filepath.Join(t.TempDir(), "recorded.gz")
+	w, err := archive.Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	recorder := archive.NewRecorder(source, w)
+	recorder.SetUUID(devUUID)
+	assert.NoError(t, recorder.ProcessExisting(func([]byte) (bool, error) {
+		return true, nil
+	}, types.FlowLogType))
+	assert.NoError(t, recorder.Close())
+
+	records, err := archive.Read(path)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.True(t, start.Add(time.Minute).Equal(records[0].Time), records[0].Time)
+	}
+}
+
+func TestRecorderKeepsSharedWriter(t *testing.T) {
+	t.Parallel()
+
+	devUUID := uuid.Must(uuid.NewV4())
+	path := filepath.Join(t.TempDir(), "shared.gz")
+	w, err := archive.OpenShared(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	record := func(at time.Time) {
+		data, err := proto.Marshal(&info.ZInfoMsg{DevId: devUUID.String(), AtTimeStamp: timestamppb.New(at)})
+		assert.NoError(t, err)
+		source := archive.NewPlayer([]archive.Record{{Time: at, Type: types.InfoType, Device: devUUID, Data: data}}, 1)
+		recorder := archive.NewRecorder(source, w)
+		recorder.SetUUID(devUUID)
+		assert.NoError(t, recorder.ProcessExisting(func([]byte) (bool, error) {
+			return true, nil
+		}, types.InfoType))
+		assert.NoError(t, recorder.Close())
+	}
+	now := time.Now()
+	record(now)
+	// the second user writes into the same archive after the first recorder closed
+	record(now.Add(time.Second))
+	assert.NoError(t, archive.CloseShared())
+
+	records, err := archive.Read(path)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+}
@@ -0,0 +1,213 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eden/pkg/controller/types"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrEndOfArchive returned from ProcessStream of Player when all records are replayed
+var ErrEndOfArchive = errors.New("end of archive")
+
+// clock maps real time into time of records
+// it starts from time of the first record on the first use
+type clock struct {
+	once    sync.Once
+	base    time.Time
+	started time.Time
+	speed   float64
+}
+
+func (c *clock) start() {
+	c.once.Do(func() {
+		c.started = time.Now()
+	})
+}
+
+// now returns current time of replay
+func (c *clock) now() time.Time {
+	c.start()
+	return c.base.Add(time.Duration(float64(time.Since(c.started)) * c.speed))
+}
+
+// until returns real duration to wait for replay of object with provided time
+func (c *clock) until(t time.Time) time.Duration {
+	c.start()
+	return time.Duration(float64(t.Sub(c.base))/c.speed) - time.Since(c.started)
+}
+
+// Player implements loaders.Loader which replays records from archive.
+// Records with time before current time of replay are available with ProcessExisting,
+// the rest ones are delivered by ProcessStream with the same intervals as recorded divided by speed.
+type Player struct {
+	records []Record
+	clock   *clock
+	devUUID uuid.UUID
+	appUUID uuid.UUID
+	cache   cachers.CacheProcessor
+	rng     types.LoaderRange
+	cursor  string
+}
+
+// NewPlayer returns Player for records sorted by time,
+// speed defines acceleration of replay, values less or equal 0 means real time
+func NewPlayer(records []Record, speed float64) *Player {
+	if speed <= 0 {
+		speed = 1
+	}
+	c := &clock{speed: speed}
+	if len(records) > 0 {
+		c.base = records[0].Time
+	}
+	return &Player{records: records, clock: c}
+}
+
+// OpenPlayer reads archive file and returns Player for it
+func OpenPlayer(path string, speed float64) (*Player, error) {
+	records, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlayer(records, speed), nil
+}
+
+// Devices returns UUIDs of devices found in archive
+func (p *Player) Devices() (devices []uuid.UUID) {
+	found := map[uuid.UUID]bool{}
+	for _, rec := range p.records {
+		if !found[rec.Device] {
+			found[rec.Device] = true
+			devices = append(devices, rec.Device)
+		}
+	}
+	return
+}
+
+// SetRemoteCache add cache layer
+func (p *Player) SetRemoteCache(cache cachers.CacheProcessor) {
+	p.cache = cache
+}
+
+// Clone create copy which shares records and time of replay
+func (p *Player) Clone() loaders.Loader {
+	return &Player{
+		records: p.records,
+		clock:   p.clock,
+		devUUID: p.devUUID,
+		appUUID: p.appUUID,
+		cache:   p.cache,
+		rng:     p.rng,
+		cursor:  p.cursor,
+	}
+}
+
+// SetRange set time range and cursor to process existing records
+func (p *Player) SetRange(rng types.LoaderRange) {
+	p.rng = rng
+	p.cursor = rng.Cursor
+}
+
+// GetCursor returns number of records in archive before the next one after the last processed
+func (p *Player) GetCursor() string {
+	return p.cursor
+}
+
+// SetUUID set device UUID
+func (p *Player) SetUUID(devUUID uuid.UUID) {
+	p.devUUID = devUUID
+}
+
+// SetAppUUID set app UUID
+func (p *Player) SetAppUUID(appUUID uuid.UUID) {
+	p.appUUID = appUUID
+}
+
+func (p *Player) match(rec Record, typeToProcess types.LoaderObjectType) bool {
+	if rec.Type != typeToProcess || !uuid.Equal(rec.Device, p.devUUID) {
+		return false
+	}
+	return typeToProcess != types.AppsType || uuid.Equal(rec.App, p.appUUID)
+}
+
+// processRecord sends data to cache and process function
+func (p *Player) processRecord(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType, ind int) (bool, error) {
+	p.cursor = strconv.Itoa(ind + 1)
+	data := p.records[ind].Data
+	if p.cache != nil {
+		if err := p.cache.CheckAndSave(p.devUUID, typeToProcess, data); err != nil {
+			log.Errorf("error in cache: %s", err)
+		}
+	}
+	return process(data)
+}
+
+// ProcessExisting for observe records with time before current time of replay
+func (p *Player) ProcessExisting(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType) error {
+	from := 0
+	if p.rng.Cursor != "" {
+		var err error
+		if from, err = strconv.Atoi(p.rng.Cursor); err != nil {
+			return fmt.Errorf("cannot parse cursor %s: %w", p.rng.Cursor, err)
+		}
+	}
+	now := p.clock.now()
+	for i := from; i < len(p.records); i++ {
+		rec := p.records[i]
+		if rec.Time.After(now) {
+			break
+		}
+		if !p.match(rec, typeToProcess) || !p.rng.Match(rec.Time) {
+			continue
+		}
+		doContinue, err := p.processRecord(process, typeToProcess, i)
+		if err != nil {
+			return err
+		}
+		if !doContinue {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ProcessStream for observe records with time after current time of replay
+// it returns ErrEndOfArchive if no more records to replay
+func (p *Player) ProcessStream(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType, timeoutSeconds time.Duration) error {
+	var timeout <-chan time.Time
+	if timeoutSeconds != 0 {
+		timer := time.NewTimer(timeoutSeconds * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	now := p.clock.now()
+	for i, rec := range p.records {
+		if !rec.Time.After(now) || !p.match(rec, typeToProcess) {
+			continue
+		}
+		if wait := p.clock.until(rec.Time); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-timeout:
+				timer.Stop()
+				return fmt.Errorf("timeout")
+			}
+		}
+		doContinue, err := p.processRecord(process, typeToProcess, i)
+		if err != nil {
+			return err
+		}
+		if !doContinue {
+			return nil
+		}
+	}
+	return ErrEndOfArchive
+}
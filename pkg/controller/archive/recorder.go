@@ -0,0 +1,160 @@
+package archive
+
+import (
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/controller/eapps"
+	"github.com/lf-edge/eden/pkg/controller/eflowlog"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/emetric"
+	"github.com/lf-edge/eden/pkg/controller/erequest"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eve/api/go/flowlog"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Recorder implements loaders.Loader on top of another loader
+// and writes every object delivered by it into archive
+type Recorder struct {
+	loader  loaders.Loader
+	writer  *Writer
+	devUUID uuid.UUID
+	appUUID uuid.UUID
+}
+
+// NewRecorder returns loader which records objects delivered by provided loader into writer
+func NewRecorder(loader loaders.Loader, writer *Writer) *Recorder {
+	return &Recorder{loader: loader, writer: writer}
+}
+
+// SetRemoteCache add cache layer
+func (r *Recorder) SetRemoteCache(cache cachers.CacheProcessor) {
+	r.loader.SetRemoteCache(cache)
+}
+
+// Clone create copy
+func (r *Recorder) Clone() loaders.Loader {
+	return &Recorder{
+		loader:  r.loader.Clone(),
+		writer:  r.writer,
+		devUUID: r.devUUID,
+		appUUID: r.appUUID,
+	}
+}
+
+// SetRange set time range and cursor to process existing objects
+func (r *Recorder) SetRange(rng types.LoaderRange) {
+	r.loader.SetRange(rng)
+}
+
+// GetCursor returns cursor of underlying loader
+func (r *Recorder) GetCursor() string {
+	return r.loader.GetCursor()
+}
+
+// SetUUID set device UUID
+func (r *Recorder) SetUUID(devUUID uuid.UUID) {
+	r.devUUID = devUUID
+	r.loader.SetUUID(devUUID)
+}
+
+// SetAppUUID set app UUID
+func (r *Recorder) SetAppUUID(appUUID uuid.UUID) {
+	r.appUUID = appUUID
+	r.loader.SetAppUUID(appUUID)
+}
+
+// Close closes archive writer of recorder
+// writers opened with OpenShared are used by other users and stay open until CloseShared
+func (r *Recorder) Close() error {
+	if r.writer.shared {
+		return nil
+	}
+	return r.writer.Close()
+}
+
+// objectTime returns time defined inside of object to keep timeline of device on replay
+// current time returned for objects without time inside
+func objectTime(typeToProcess types.LoaderObjectType, data []byte) time.Time {
+	var ts *timestamppb.Timestamp
+	switch typeToProcess {
+	case types.InfoType:
+		if im, err := einfo.ParseZInfoMsg(data); err == nil {
+			ts = im.GetAtTimeStamp()
+		}
+	case types.MetricsType:
+		if mm, err := emetric.ParseMetricsBundle(data); err == nil {
+			ts = mm.GetAtTimeStamp()
+		}
+	case types.LogsType:
+		if le, err := elog.ParseFullLogEntry(data); err == nil {
+			ts = le.GetTimestamp()
+		}
+	case types.AppsType:
+		if le, err := eapps.ParseLogEntry(data); err == nil {
+			ts = le.GetTimestamp()
+		}
+	case types.FlowLogType:
+		if fm, err := eflowlog.ParseFullLogEntry(data); err == nil {
+			ts = flowMessageTime(fm)
+		}
+	case types.RequestType:
+		if req, err := erequest.ParseRequestItem(data); err == nil && !req.Timestamp.IsZero() {
+			return req.Timestamp
+		}
+	}
+	if ts != nil && ts.IsValid() && ts.AsTime().Unix() > 0 {
+		return ts.AsTime()
+	}
+	return time.Now()
+}
+
+// flowMessageTime returns the latest time of flows and DNS requests inside of FlowMessage
+func flowMessageTime(fm *flowlog.FlowMessage) (ts *timestamppb.Timestamp) {
+	later := func(t *timestamppb.Timestamp) {
+		if t.IsValid() && (ts == nil || t.AsTime().After(ts.AsTime())) {
+			ts = t
+		}
+	}
+	for _, flow := range fm.GetFlows() {
+		later(flow.GetStartTime())
+		later(flow.GetEndTime())
+	}
+	for _, req := range fm.GetDnsReqs() {
+		later(req.GetRequestTime())
+	}
+	return ts
+}
+
+func (r *Recorder) record(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType) loaders.ProcessFunction {
+	return func(data []byte) (bool, error) {
+		rec := Record{
+			Time:   objectTime(typeToProcess, data),
+			Type:   typeToProcess,
+			Device: r.devUUID,
+			Data:   data,
+		}
+		if typeToProcess == types.AppsType {
+			rec.App = r.appUUID
+		}
+		if err := r.writer.Write(rec); err != nil {
+			log.Errorf("cannot write into archive: %s", err)
+		}
+		return process(data)
+	}
+}
+
+// ProcessExisting for observe existing objects
+func (r *Recorder) ProcessExisting(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType) error {
+	return r.loader.ProcessExisting(r.record(process, typeToProcess), typeToProcess)
+}
+
+// ProcessStream for observe new objects
+func (r *Recorder) ProcessStream(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType, timeoutSeconds time.Duration) error {
+	return r.loader.ProcessStream(r.record(process, typeToProcess), typeToProcess, timeoutSeconds)
+}
@@ -0,0 +1,111 @@
+package attestation_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/attestation"
+	"github.com/lf-edge/eden/pkg/controller/fake"
+	"github.com/lf-edge/eve/api/go/attest"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const scenarios = `
+scenarios:
+  - name: matching template
+    device:
+      eveVersion: 9.0.0
+      firmwareVersion: "1.0"
+    expect: pass
+    timeout: 1m
+  - name: wrong firmware
+    device:
+      eveVersion: 9.0.0
+      firmwareVersion: "1.0"
+    template:
+      firmwareVersion: "2.0"
+    expect: fail
+  - name: mismatched PCR
+    device:
+      eveVersion: 9.0.0
+      measurements:
+        - pcr: 0
+          type: EV_S_CRTM_VERSION
+          data: "1.0"
+    template:
+      pcrs:
+        0: "0000"
+    expect: fail
+  - name: any PCR value
+    device:
+      eveVersion: 9.0.0
+    template:
+      pcrs:
+        9: "*"
+    expect: pass
+`
+
+func TestEventLog(t *testing.T) {
+	t.Parallel()
+
+	sim := attestation.NewBootSimulator("9.0.0", "1.0")
+	pcrs, err := attestation.ComputePCRs(sim.EventLog(), attest.TpmHashAlgo_TPM_HASH_ALGO_SHA256)
+	assert.NoError(t, err)
+	assert.Equal(t, sim.PCRs(), pcrs)
+	assert.Len(t, pcrs, 10)
+
+	template, err := attestation.TemplateFromDevice(sim.DeviceOptions(), []uint32{0, 8}, true, attest.TpmHashAlgo_TPM_HASH_ALGO_SHA256)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", template.FirmwareVersion)
+	if assert.Len(t, template.PCRValues, 2) {
+		assert.Equal(t, pcrs[8], template.PCRValues[1].Value)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, attestation.PrintEventLog(&buf, sim.EventLog()))
+	assert.Contains(t, buf.String(), "EV_S_CRTM_VERSION")
+	assert.Contains(t, buf.String(), "/boot/kernel 9.0.0")
+}
+
+func TestScenarios(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "scenarios.yml")
+	assert.NoError(t, os.WriteFile(file, []byte(scenarios), 0644))
+	loaded, err := attestation.LoadScenarios(file)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, loaded, 4)
+	for _, sc := range loaded {
+		result, err := sc.Run(nil, uuid.Nil)
+		if assert.NoError(t, err) {
+			assert.True(t, result.Passed(), result.String())
+		}
+	}
+}
+
+func TestScenarioController(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	devUUID, _ := uuid.NewV4()
+	assert.NoError(t, ctrl.AddDevice(devUUID, uuid.Nil, ""))
+	opts := attestation.NewBootSimulator("9.0.0", "1.0").DeviceOptions()
+	opts.Attested = true
+	assert.NoError(t, ctrl.SetDeviceOptions(devUUID, opts))
+
+	// fake controller does not attest devices, so attestation is dropped by scenario
+	sc := &attestation.Scenario{Name: "reset", Expect: attestation.ExpectFail, Timeout: time.Millisecond}
+	result, err := sc.Run(ctrl, devUUID)
+	if assert.NoError(t, err) {
+		assert.True(t, result.Passed(), result.String())
+	}
+	global, err := ctrl.GetGlobalOptions()
+	assert.NoError(t, err)
+	assert.False(t, global.EnforceTemplateAttestation)
+}
@@ -0,0 +1,209 @@
+// Package attestation helps to test measured boot: it prints and replays TPM event logs,
+// builds PCR templates, simulates TPM of device and runs pass/fail scenarios against controller.
+package attestation
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/lf-edge/eve/api/go/attest"
+)
+
+// TCG event types used in event log
+const (
+	EvPostCode                 uint32 = 0x1
+	EvNoAction                 uint32 = 0x3
+	EvSeparator                uint32 = 0x4
+	EvAction                   uint32 = 0x5
+	EvEventTag                 uint32 = 0x6
+	EvSCRTMContents            uint32 = 0x7
+	EvSCRTMVersion             uint32 = 0x8
+	EvCPUMicrocode             uint32 = 0x9
+	EvPlatformConfigFlags      uint32 = 0xa
+	EvTableOfDevices           uint32 = 0xb
+	EvCompactHash              uint32 = 0xc
+	EvIPL                      uint32 = 0xd
+	EvIPLPartitionData         uint32 = 0xe
+	EvNonhostCode              uint32 = 0xf
+	EvNonhostConfig            uint32 = 0x10
+	EvNonhostInfo              uint32 = 0x11
+	EvOmitBootDeviceEvents     uint32 = 0x12
+	EvEFIVariableDriverConfig  uint32 = 0x80000001
+	EvEFIVariableBoot          uint32 = 0x80000002
+	EvEFIBootServicesApp       uint32 = 0x80000003
+	EvEFIBootServicesDriver    uint32 = 0x80000004
+	EvEFIRuntimeServicesDriver uint32 = 0x80000005
+	EvEFIGPTEvent              uint32 = 0x80000006
+	EvEFIAction                uint32 = 0x80000007
+	EvEFIPlatformFirmwareBlob  uint32 = 0x80000008
+	EvEFIHandoffTables         uint32 = 0x80000009
+	EvEFIHCRTMEvent            uint32 = 0x80000010
+	EvEFIVariableAuthority     uint32 = 0x800000e0
+)
+
+var eventTypeNames = map[uint32]string{
+	EvPostCode:                 "EV_POST_CODE",
+	EvNoAction:                 "EV_NO_ACTION",
+	EvSeparator:                "EV_SEPARATOR",
+	EvAction:                   "EV_ACTION",
+	EvEventTag:                 "EV_EVENT_TAG",
+	EvSCRTMContents:            "EV_S_CRTM_CONTENTS",
+	EvSCRTMVersion:             "EV_S_CRTM_VERSION",
+	EvCPUMicrocode:             "EV_CPU_MICROCODE",
+	EvPlatformConfigFlags:      "EV_PLATFORM_CONFIG_FLAGS",
+	EvTableOfDevices:           "EV_TABLE_OF_DEVICES",
+	EvCompactHash:              "EV_COMPACT_HASH",
+	EvIPL:                      "EV_IPL",
+	EvIPLPartitionData:         "EV_IPL_PARTITION_DATA",
+	EvNonhostCode:              "EV_NONHOST_CODE",
+	EvNonhostConfig:            "EV_NONHOST_CONFIG",
+	EvNonhostInfo:              "EV_NONHOST_INFO",
+	EvOmitBootDeviceEvents:     "EV_OMIT_BOOT_DEVICE_EVENTS",
+	EvEFIVariableDriverConfig:  "EV_EFI_VARIABLE_DRIVER_CONFIG",
+	EvEFIVariableBoot:          "EV_EFI_VARIABLE_BOOT",
+	EvEFIBootServicesApp:       "EV_EFI_BOOT_SERVICES_APPLICATION",
+	EvEFIBootServicesDriver:    "EV_EFI_BOOT_SERVICES_DRIVER",
+	EvEFIRuntimeServicesDriver: "EV_EFI_RUNTIME_SERVICES_DRIVER",
+	EvEFIGPTEvent:              "EV_EFI_GPT_EVENT",
+	EvEFIAction:                "EV_EFI_ACTION",
+	EvEFIPlatformFirmwareBlob:  "EV_EFI_PLATFORM_FIRMWARE_BLOB",
+	EvEFIHandoffTables:         "EV_EFI_HANDOFF_TABLES",
+	EvEFIHCRTMEvent:            "EV_EFI_HCRTM_EVENT",
+	EvEFIVariableAuthority:     "EV_EFI_VARIABLE_AUTHORITY",
+}
+
+// EventTypeName returns TCG name of event type
+func EventTypeName(eventType uint32) string {
+	if name, ok := eventTypeNames[eventType]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", eventType)
+}
+
+// ParseEventType parses TCG name or number of event type
+func ParseEventType(s string) (uint32, error) {
+	for t, name := range eventTypeNames {
+		if strings.EqualFold(name, s) {
+			return t, nil
+		}
+	}
+	var t uint32
+	if _, err := fmt.Sscan(s, &t); err != nil {
+		return 0, fmt.Errorf("unknown event type %q", s)
+	}
+	return t, nil
+}
+
+// ParseHashAlgo parses name of hash algorithm (sha1, sha256 or sha512)
+func ParseHashAlgo(s string) (attest.TpmHashAlgo, error) {
+	switch strings.ToLower(s) {
+	case "sha1":
+		return attest.TpmHashAlgo_TPM_HASH_ALGO_SHA1, nil
+	case "sha256", "":
+		return attest.TpmHashAlgo_TPM_HASH_ALGO_SHA256, nil
+	case "sha512":
+		return attest.TpmHashAlgo_TPM_HASH_ALGO_SHA512, nil
+	}
+	return attest.TpmHashAlgo_TPM_HASH_ALGO_INVALID, fmt.Errorf("unsupported hash algorithm %q", s)
+}
+
+func newHash(algo attest.TpmHashAlgo) (hash.Hash, error) {
+	switch algo {
+	case attest.TpmHashAlgo_TPM_HASH_ALGO_SHA1:
+		return sha1.New(), nil
+	case attest.TpmHashAlgo_TPM_HASH_ALGO_SHA256:
+		return sha256.New(), nil
+	case attest.TpmHashAlgo_TPM_HASH_ALGO_SHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm %s", algo)
+}
+
+// eventData returns printable representation of event data
+func eventData(entry *attest.TpmEventLogEntry) string {
+	if entry.GetEventDataString() != "" {
+		return entry.GetEventDataString()
+	}
+	data := entry.GetEventDataBinary()
+	printable := len(data) > 0
+	for _, r := range strings.TrimRight(string(data), "\x00") {
+		if !unicode.IsPrint(r) {
+			printable = false
+			break
+		}
+	}
+	if printable {
+		return strings.TrimRight(string(data), "\x00")
+	}
+	if len(data) > 32 {
+		return fmt.Sprintf("%s... (%d bytes)", hex.EncodeToString(data[:32]), len(data))
+	}
+	if len(data) == 0 && entry.GetEventBinarySize() > 0 {
+		return fmt.Sprintf("(%d bytes omitted)", entry.GetEventBinarySize())
+	}
+	return hex.EncodeToString(data)
+}
+
+// PrintEventLog writes event log in human-readable form
+func PrintEventLog(w io.Writer, eventLog []*attest.TpmEventLogEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tPCR\tTYPE\tALGO\tDIGEST\tDATA")
+	for _, entry := range eventLog {
+		algo := strings.TrimPrefix(entry.GetDigest().GetHashAlgo().String(), "TPM_HASH_ALGO_")
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\n",
+			entry.GetIndex(), entry.GetPcrIndex(), EventTypeName(entry.GetEventType()),
+			algo, hex.EncodeToString(entry.GetDigest().GetDigest()), eventData(entry))
+	}
+	return tw.Flush()
+}
+
+// ComputePCRs replays event log and returns hex-encoded values of PCRs
+// for events measured with algo; EV_NO_ACTION events are not extended
+func ComputePCRs(eventLog []*attest.TpmEventLogEntry, algo attest.TpmHashAlgo) (map[uint32]string, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return nil, err
+	}
+	pcrs := map[uint32][]byte{}
+	for _, entry := range eventLog {
+		if entry.GetEventType() == EvNoAction || entry.GetDigest().GetHashAlgo() != algo {
+			continue
+		}
+		if len(entry.GetDigest().GetDigest()) != h.Size() {
+			return nil, fmt.Errorf("event %d: digest size %d does not match %s",
+				entry.GetIndex(), len(entry.GetDigest().GetDigest()), algo)
+		}
+		pcr, ok := pcrs[entry.GetPcrIndex()]
+		if !ok {
+			pcr = make([]byte, h.Size())
+		}
+		h.Reset()
+		h.Write(pcr)
+		h.Write(entry.GetDigest().GetDigest())
+		pcrs[entry.GetPcrIndex()] = h.Sum(nil)
+	}
+	result := make(map[uint32]string, len(pcrs))
+	for index, value := range pcrs {
+		result[index] = hex.EncodeToString(value)
+	}
+	return result, nil
+}
+
+// sortedIndexes returns keys of PCR map in increasing order
+func sortedIndexes(pcrs map[uint32]string) []uint32 {
+	var indexes []uint32
+	for index := range pcrs {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	return indexes
+}
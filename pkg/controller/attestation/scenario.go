@@ -0,0 +1,238 @@
+package attestation
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/types"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// ExpectPass means that device must be attested
+	ExpectPass = "pass"
+	// ExpectFail means that device must not be attested
+	ExpectFail = "fail"
+
+	defaultScenarioTimeout = 5 * time.Minute
+	pollInterval           = 10 * time.Second
+)
+
+// OptionsController is the part of controller used by scenarios
+type OptionsController interface {
+	GetGlobalOptions() (*types.GlobalOptions, error)
+	SetGlobalOptions(*types.GlobalOptions) error
+	GetDeviceOptions(uuid.UUID) (*types.DeviceOptions, error)
+	SetDeviceOptions(uuid.UUID, *types.DeviceOptions) error
+}
+
+// Measurement of simulated device
+type Measurement struct {
+	PCR  uint32 `yaml:"pcr"`
+	Type string `yaml:"type"`
+	Data string `yaml:"data"`
+}
+
+// SimulatedDevice defines device with simulated TPM, typical boot is measured if no measurements defined
+type SimulatedDevice struct {
+	EveVersion      string        `yaml:"eveVersion"`
+	FirmwareVersion string        `yaml:"firmwareVersion"`
+	Measurements    []Measurement `yaml:"measurements"`
+}
+
+// TemplateChange modifies template received from device before setting it into controller
+type TemplateChange struct {
+	EveVersion      *string           `yaml:"eveVersion"`
+	FirmwareVersion *string           `yaml:"firmwareVersion"`
+	PCRs            map[uint32]string `yaml:"pcrs"`
+	Remove          []uint32          `yaml:"remove"`
+	Only            []uint32          `yaml:"only"`
+}
+
+// Scenario sets template derived from the one received from device into controller
+// and checks if device is attested
+type Scenario struct {
+	Name     string           `yaml:"name"`
+	Device   *SimulatedDevice `yaml:"device"`
+	Template TemplateChange   `yaml:"template"`
+	Enforce  *bool            `yaml:"enforce"`
+	Expect   string           `yaml:"expect"`
+	Timeout  time.Duration    `yaml:"timeout"`
+}
+
+// Result of scenario
+type Result struct {
+	Name     string
+	Expected string
+	Attested bool
+	Reason   string
+}
+
+// Passed returns true if attestation result is expected one
+func (r *Result) Passed() bool {
+	return r.Attested == (r.Expected == ExpectPass)
+}
+
+func (r *Result) String() string {
+	status := "PASS"
+	if !r.Passed() {
+		status = "FAIL"
+	}
+	s := fmt.Sprintf("%s: %s (expected %s, attested %t)", status, r.Name, r.Expected, r.Attested)
+	if r.Reason != "" {
+		s += ": " + r.Reason
+	}
+	return s
+}
+
+// LoadScenarios reads scenarios from yaml file with top-level scenarios list
+func LoadScenarios(file string) ([]*Scenario, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read scenarios: %w", err)
+	}
+	var scenarios struct {
+		Scenarios []*Scenario `yaml:"scenarios"`
+	}
+	if err = yaml.UnmarshalStrict(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("cannot parse scenarios %s: %w", file, err)
+	}
+	for i, sc := range scenarios.Scenarios {
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("scenario-%d", i+1)
+		}
+		if sc.Expect != ExpectPass && sc.Expect != ExpectFail {
+			return nil, fmt.Errorf("%s: expect must be %s or %s", sc.Name, ExpectPass, ExpectFail)
+		}
+	}
+	return scenarios.Scenarios, nil
+}
+
+// Apply returns copy of template with changes applied
+func (c *TemplateChange) Apply(template *types.PCRTemplate) *types.PCRTemplate {
+	pcrs := templatePCRs(template)
+	for index, value := range c.PCRs {
+		pcrs[index] = value
+	}
+	for _, index := range c.Remove {
+		delete(pcrs, index)
+	}
+	result := TemplateFromPCRs(template.EveVersion, template.FirmwareVersion, pcrs, c.Only)
+	if c.EveVersion != nil {
+		result.EveVersion = *c.EveVersion
+	}
+	if c.FirmwareVersion != nil {
+		result.FirmwareVersion = *c.FirmwareVersion
+	}
+	return result
+}
+
+// Simulator returns simulator of device
+func (d *SimulatedDevice) Simulator() (*Simulator, error) {
+	if len(d.Measurements) == 0 {
+		return NewBootSimulator(d.EveVersion, d.FirmwareVersion), nil
+	}
+	sim := NewSimulator(d.EveVersion, d.FirmwareVersion)
+	for _, m := range d.Measurements {
+		if err := sim.MeasureNamed(m.PCR, m.Type, []byte(m.Data)); err != nil {
+			return nil, err
+		}
+	}
+	return sim, nil
+}
+
+// GlobalOptions returns options to set into controller for template received from device
+func (sc *Scenario) GlobalOptions(received *types.PCRTemplate) *types.GlobalOptions {
+	enforce := sc.Enforce == nil || *sc.Enforce
+	return &types.GlobalOptions{
+		EnforceTemplateAttestation: enforce,
+		PCRTemplates:               []*types.PCRTemplate{sc.Template.Apply(received)},
+	}
+}
+
+// Run runs scenario: with simulated device templates are verified locally,
+// otherwise options are set into controller and device is expected to be (not) attested
+// during timeout; previous global options are restored after run
+func (sc *Scenario) Run(ctrl OptionsController, devUUID uuid.UUID) (*Result, error) {
+	result := &Result{Name: sc.Name, Expected: sc.Expect}
+	if sc.Device != nil {
+		sim, err := sc.Device.Simulator()
+		if err != nil {
+			return nil, err
+		}
+		// template in controller is derived from the one the simulated device reports
+		received := sim.Template()
+		err = Verify(sc.GlobalOptions(received), received)
+		result.Attested = err == nil
+		if err != nil {
+			result.Reason = err.Error()
+		}
+		return result, nil
+	}
+
+	devOptions, err := ctrl.GetDeviceOptions(devUUID)
+	if err != nil {
+		return nil, fmt.Errorf("GetDeviceOptions: %w", err)
+	}
+	if devOptions.ReceivedPCRTemplate == nil {
+		return nil, fmt.Errorf("device did not send PCR template yet")
+	}
+	global := sc.GlobalOptions(devOptions.ReceivedPCRTemplate)
+	previous, err := ctrl.GetGlobalOptions()
+	if err != nil {
+		return nil, fmt.Errorf("GetGlobalOptions: %w", err)
+	}
+	if err = ctrl.SetGlobalOptions(global); err != nil {
+		return nil, fmt.Errorf("SetGlobalOptions: %w", err)
+	}
+	defer func() {
+		if err := ctrl.SetGlobalOptions(previous); err != nil {
+			log.Errorf("cannot restore global options: %v", err)
+		}
+	}()
+	// drop result of previous attestation to make controller check device again
+	reset := *devOptions
+	reset.Attested = false
+	reset.IntegrityToken = ""
+	if err = ctrl.SetDeviceOptions(devUUID, &reset); err != nil {
+		return nil, fmt.Errorf("SetDeviceOptions: %w", err)
+	}
+
+	timeout := sc.Timeout
+	if timeout == 0 {
+		timeout = defaultScenarioTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		opts, err := ctrl.GetDeviceOptions(devUUID)
+		if err != nil {
+			return nil, fmt.Errorf("GetDeviceOptions: %w", err)
+		}
+		if opts.Attested {
+			result.Attested = true
+			break
+		}
+		if !time.Now().Before(deadline) {
+			var reasons []string
+			if err := Verify(global, opts.ReceivedPCRTemplate); err != nil {
+				reasons = append(reasons, err.Error())
+			}
+			reasons = append(reasons, fmt.Sprintf("not attested in %s", timeout))
+			result.Reason = strings.Join(reasons, ", ")
+			break
+		}
+		time.Sleep(minDuration(pollInterval, time.Until(deadline)))
+	}
+	return result, nil
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
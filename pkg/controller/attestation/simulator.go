@@ -0,0 +1,105 @@
+package attestation
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eve/api/go/attest"
+)
+
+// Simulator emulates TPM of device: it extends PCRs with measurements
+// and keeps event log in the form EVE sends it to controller
+type Simulator struct {
+	EveVersion      string
+	FirmwareVersion string
+	algo            attest.TpmHashAlgo
+	pcrs            map[uint32][]byte
+	eventLog        []*attest.TpmEventLogEntry
+}
+
+// NewSimulator returns Simulator with empty PCRs of sha256 bank
+func NewSimulator(eveVersion, firmwareVersion string) *Simulator {
+	return &Simulator{
+		EveVersion:      eveVersion,
+		FirmwareVersion: firmwareVersion,
+		algo:            attest.TpmHashAlgo_TPM_HASH_ALGO_SHA256,
+		pcrs:            map[uint32][]byte{},
+	}
+}
+
+// NewBootSimulator returns Simulator with measurements of typical boot:
+// firmware into PCR0-7 and EVE into PCR8-9
+func NewBootSimulator(eveVersion, firmwareVersion string) *Simulator {
+	sim := NewSimulator(eveVersion, firmwareVersion)
+	sim.Measure(0, EvSCRTMVersion, []byte(firmwareVersion))
+	sim.Measure(0, EvEFIPlatformFirmwareBlob, []byte("firmware "+firmwareVersion))
+	for pcr := uint32(0); pcr < 8; pcr++ {
+		sim.Measure(pcr, EvSeparator, []byte{0, 0, 0, 0})
+	}
+	sim.Measure(4, EvEFIBootServicesApp, []byte("grub"))
+	sim.Measure(8, EvIPL, []byte("grub_cmd linux /boot/kernel eve_version="+eveVersion))
+	sim.Measure(9, EvIPL, []byte("/boot/kernel "+eveVersion))
+	sim.Measure(9, EvIPL, []byte("/rootfs.img "+eveVersion))
+	return sim
+}
+
+// Measure extends pcr with digest of data and appends event into event log
+func (s *Simulator) Measure(pcr uint32, eventType uint32, data []byte) {
+	h, _ := newHash(s.algo)
+	h.Write(data)
+	digest := h.Sum(nil)
+	s.eventLog = append(s.eventLog, &attest.TpmEventLogEntry{
+		Index:           uint32(len(s.eventLog)),
+		PcrIndex:        pcr,
+		EventType:       eventType,
+		Digest:          &attest.TpmEventDigest{HashAlgo: s.algo, Digest: digest},
+		EventDataBinary: data,
+		EventBinarySize: uint32(len(data)),
+	})
+	value, ok := s.pcrs[pcr]
+	if !ok {
+		value = make([]byte, h.Size())
+	}
+	h.Reset()
+	h.Write(value)
+	h.Write(digest)
+	s.pcrs[pcr] = h.Sum(nil)
+}
+
+// MeasureNamed is Measure with TCG name or number of event type
+func (s *Simulator) MeasureNamed(pcr uint32, eventType string, data []byte) error {
+	t, err := ParseEventType(eventType)
+	if err != nil {
+		return fmt.Errorf("PCR%d: %w", pcr, err)
+	}
+	s.Measure(pcr, t, data)
+	return nil
+}
+
+// PCRs returns hex-encoded values of extended PCRs
+func (s *Simulator) PCRs() map[uint32]string {
+	result := make(map[uint32]string, len(s.pcrs))
+	for index, value := range s.pcrs {
+		result[index] = hex.EncodeToString(value)
+	}
+	return result
+}
+
+// EventLog returns measurements done
+func (s *Simulator) EventLog() []*attest.TpmEventLogEntry {
+	return s.eventLog
+}
+
+// Template returns template which device reports to controller
+func (s *Simulator) Template() *types.PCRTemplate {
+	return TemplateFromPCRs(s.EveVersion, s.FirmwareVersion, s.PCRs(), nil)
+}
+
+// DeviceOptions returns options as controller stores them after attestation request of device
+func (s *Simulator) DeviceOptions() *types.DeviceOptions {
+	return &types.DeviceOptions{
+		ReceivedPCRTemplate: s.Template(),
+		EventLog:            s.EventLog(),
+	}
+}
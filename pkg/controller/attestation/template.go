@@ -0,0 +1,115 @@
+package attestation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eve/api/go/attest"
+)
+
+// AnyValue in PCR template allows any value of PCR
+const AnyValue = "*"
+
+// TemplateFromPCRs builds template with selected PCRs, all PCRs are used if indexes is empty
+func TemplateFromPCRs(eveVersion, firmwareVersion string, pcrs map[uint32]string, indexes []uint32) *types.PCRTemplate {
+	template := &types.PCRTemplate{EveVersion: eveVersion, FirmwareVersion: firmwareVersion}
+	for _, index := range sortedIndexes(pcrs) {
+		if len(indexes) > 0 && !containsIndex(indexes, index) {
+			continue
+		}
+		template.PCRValues = append(template.PCRValues, &types.PCRValue{Index: index, Value: pcrs[index]})
+	}
+	return template
+}
+
+// TemplateFromDevice builds template from ReceivedPCRTemplate of device
+// or from PCRs computed with replay of its event log if fromEventLog is set
+func TemplateFromDevice(opts *types.DeviceOptions, indexes []uint32, fromEventLog bool, algo attest.TpmHashAlgo) (*types.PCRTemplate, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("no options for device")
+	}
+	received := opts.ReceivedPCRTemplate
+	if !fromEventLog {
+		if received == nil {
+			return nil, fmt.Errorf("device did not send PCR template yet")
+		}
+		return TemplateFromPCRs(received.EveVersion, received.FirmwareVersion, templatePCRs(received), indexes), nil
+	}
+	if len(opts.EventLog) == 0 {
+		return nil, fmt.Errorf("device did not send event log yet")
+	}
+	pcrs, err := ComputePCRs(opts.EventLog, algo)
+	if err != nil {
+		return nil, err
+	}
+	template := TemplateFromPCRs("", "", pcrs, indexes)
+	if received != nil {
+		template.EveVersion, template.FirmwareVersion = received.EveVersion, received.FirmwareVersion
+	}
+	return template, nil
+}
+
+// CompareTemplate returns mismatches of received template against expected one
+func CompareTemplate(expected, received *types.PCRTemplate) []string {
+	var mismatches []string
+	if expected.EveVersion != received.EveVersion {
+		mismatches = append(mismatches, fmt.Sprintf("eveVersion: expected %q, received %q",
+			expected.EveVersion, received.EveVersion))
+	}
+	if expected.FirmwareVersion != received.FirmwareVersion {
+		mismatches = append(mismatches, fmt.Sprintf("firmwareVersion: expected %q, received %q",
+			expected.FirmwareVersion, received.FirmwareVersion))
+	}
+	receivedPCRs := templatePCRs(received)
+	for _, pcr := range expected.PCRValues {
+		value, ok := receivedPCRs[pcr.Index]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("PCR%d: not received", pcr.Index))
+		case pcr.Value != AnyValue && !strings.EqualFold(pcr.Value, value):
+			mismatches = append(mismatches, fmt.Sprintf("PCR%d: expected %s, received %s", pcr.Index, pcr.Value, value))
+		}
+	}
+	return mismatches
+}
+
+// Verify checks received template the same way as controller does:
+// with enforced template attestation one of templates must match received one
+func Verify(global *types.GlobalOptions, received *types.PCRTemplate) error {
+	if global == nil || !global.EnforceTemplateAttestation {
+		return nil
+	}
+	if received == nil {
+		return fmt.Errorf("no template received from device")
+	}
+	if len(global.PCRTemplates) == 0 {
+		return fmt.Errorf("no templates defined in controller")
+	}
+	var reasons []string
+	for _, template := range global.PCRTemplates {
+		mismatches := CompareTemplate(template, received)
+		if len(mismatches) == 0 {
+			return nil
+		}
+		reasons = append(reasons, strings.Join(mismatches, ", "))
+	}
+	return fmt.Errorf("no matching template: %s", strings.Join(reasons, "; "))
+}
+
+func templatePCRs(template *types.PCRTemplate) map[uint32]string {
+	pcrs := map[uint32]string{}
+	for _, pcr := range template.PCRValues {
+		pcrs[pcr.Index] = pcr.Value
+	}
+	return pcrs
+}
+
+func containsIndex(indexes []uint32, index uint32) bool {
+	for _, el := range indexes {
+		if el == index {
+			return true
+		}
+	}
+	return false
+}
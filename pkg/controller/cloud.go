@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"sync"
+
 	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/models"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -25,6 +27,16 @@ type CloudCtx struct {
 	bondAdapters         map[string]*config.BondAdapter
 	applicationInstances []*config.AppInstanceConfig
 	vars                 *utils.ConfigVars
+	selector             string
+	deviceConfigs        map[uuid.UUID]*deviceConfig
+	deviceConfigsMutex   sync.Mutex
+}
+
+//deviceConfig keeps config state of device defined in eden context
+type deviceConfig struct {
+	onboardUUID string
+	context     string
+	vars        *utils.ConfigVars
 }
 
 //Cloud is an interface of cloud
@@ -32,6 +44,10 @@ type Cloud interface {
 	Controller
 	AddDevice(devUUID uuid.UUID) (dev *device.Ctx, err error)
 	GetDeviceUUID(devUUID uuid.UUID) (dev *device.Ctx, err error)
+	GetDevice(selector string) (dev *device.Ctx, err error)
+	ListDevices() []*device.Ctx
+	SetDeviceCurrent(selector string)
+	GetDeviceVars(dev *device.Ctx) *utils.ConfigVars
 	GetBaseOSConfig(id string) (baseOSConfig *config.BaseOSConfig, err error)
 	ListBaseOSConfig() []*config.BaseOSConfig
 	AddBaseOsConfig(baseOSConfig *config.BaseOSConfig) error
@@ -59,6 +75,8 @@ type Cloud interface {
 	GetConfigBytes(dev *device.Ctx, jsonFormat bool) ([]byte, error)
 	GetDeviceCurrent() (dev *device.Ctx, err error)
 	ConfigSync(dev *device.Ctx) (err error)
+	ConfigHistory(devUUID uuid.UUID) ([]ConfigHistoryEntry, error)
+	ConfigHistoryGet(devUUID uuid.UUID, version string) (*config.EdgeDevConfig, error)
 	ConfigParse(config *config.EdgeDevConfig) (dev *device.Ctx, err error)
 	GetNetworkConfig(id string) (networkConfig *config.NetworkConfig, err error)
 	AddNetworkConfig(networkInstanceConfig *config.NetworkConfig) error
@@ -0,0 +1,235 @@
+// Package configdiff provides semantic comparison of EdgeDevConfig
+package configdiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/eve/api/go/config"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ChangeType defines kind of change of object
+type ChangeType int
+
+const (
+	// Added object exists only in new config
+	Added ChangeType = iota
+	// Removed object exists only in old config
+	Removed
+	// Modified object exists in both configs with different content
+	Modified
+)
+
+// String returns sign of change
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// Change describes difference of one object between configs
+type Change struct {
+	Type   ChangeType
+	Kind   string   // kind of object, i.e. app or volume
+	ID     string   // UUID or key of object
+	Name   string   // display name of object if defined
+	Fields []string // paths of modified fields
+}
+
+// String returns representation of change in form of '<sign> <kind> <name> (<id>): <fields>'
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s", c.Type, c.Kind)
+	switch {
+	case c.Name != "" && c.ID != "":
+		s = fmt.Sprintf("%s %s (%s)", s, c.Name, c.ID)
+	case c.ID != "":
+		s = fmt.Sprintf("%s %s", s, c.ID)
+	}
+	if len(c.Fields) > 0 {
+		s = fmt.Sprintf("%s: %s", s, strings.Join(c.Fields, ", "))
+	}
+	return s
+}
+
+// object is an element of repeated field of config identified by id
+type object struct {
+	id   string
+	name string
+	msg  proto.Message
+}
+
+// collection describes repeated field of config to compare element by element
+type collection struct {
+	kind  string
+	field string
+	get   func(cfg *config.EdgeDevConfig) []object
+}
+
+var collections = []collection{{
+	kind:  "app",
+	field: "apps",
+	get: func(cfg *config.EdgeDevConfig) (res []object) {
+		for _, el := range cfg.GetApps() {
+			res = append(res, object{id: el.GetUuidandversion().GetUuid(), name: el.GetDisplayname(), msg: el})
+		}
+		return
+	},
+}, {
+	kind:  "volume",
+	field: "volumes",
+	get: func(cfg *config.EdgeDevConfig) (res []object) {
+		for _, el := range cfg.GetVolumes() {
+			res = append(res, object{id: el.GetUuid(), name: el.GetDisplayName(), msg: el})
+		}
+		return
+	},
+}, {
+	kind:  "content tree",
+	field: "contentInfo",
+	get: func(cfg *config.EdgeDevConfig) (res []object) {
+		for _, el := range cfg.GetContentInfo() {
+			res = append(res, object{id: el.GetUuid(), name: el.GetDisplayName(), msg: el})
+		}
+		return
+	},
+}, {
+	kind:  "network instance",
+	field: "networkInstances",
+	get: func(cfg *config.EdgeDevConfig) (res []object) {
+		for _, el := range cfg.GetNetworkInstances() {
+			res = append(res, object{id: el.GetUuidandversion().GetUuid(), name: el.GetDisplayname(), msg: el})
+		}
+		return
+	},
+}, {
+	kind:  "network",
+	field: "networks",
+	get: func(cfg *config.EdgeDevConfig) (res []object) {
+		for _, el := range cfg.GetNetworks() {
+			res = append(res, object{id: el.GetId(), msg: el})
+		}
+		return
+	},
+}, {
+	kind:  "datastore",
+	field: "datastores",
+	get: func(cfg *config.EdgeDevConfig) (res []object) {
+		for _, el := range cfg.GetDatastores() {
+			res = append(res, object{id: el.GetId(), name: el.GetFqdn(), msg: el})
+		}
+		return
+	},
+}, {
+	kind:  "config item",
+	field: "configItems",
+	get: func(cfg *config.EdgeDevConfig) (res []object) {
+		for _, el := range cfg.GetConfigItems() {
+			res = append(res, object{id: el.GetKey(), msg: el})
+		}
+		return
+	},
+}}
+
+// ignoredFields are not compared as they change with every config
+var ignoredFields = map[string]bool{"id": true}
+
+// Diff returns list of changes between oldConfig and newConfig
+// apps, volumes, content trees, network instances, networks, datastores and config items are compared by ID,
+// changes of other fields are reported as modification of device
+func Diff(oldConfig, newConfig *config.EdgeDevConfig) (changes []Change) {
+	compared := map[string]bool{}
+	for _, col := range collections {
+		compared[col.field] = true
+		changes = append(changes, diffObjects(col.kind, col.get(oldConfig), col.get(newConfig))...)
+	}
+	for k := range ignoredFields {
+		compared[k] = true
+	}
+	var fields []string
+	oldRef, newRef := oldConfig.ProtoReflect(), newConfig.ProtoReflect()
+	descFields := oldRef.Descriptor().Fields()
+	for i := 0; i < descFields.Len(); i++ {
+		fd := descFields.Get(i)
+		if compared[fd.JSONName()] {
+			continue
+		}
+		fields = append(fields, diffField(fd.JSONName(), fd, oldRef, newRef)...)
+	}
+	if len(fields) > 0 {
+		changes = append(changes, Change{Type: Modified, Kind: "device", Fields: fields})
+	}
+	return
+}
+
+func diffObjects(kind string, oldObjects, newObjects []object) (changes []Change) {
+	oldMap := map[string]object{}
+	for _, el := range oldObjects {
+		oldMap[el.id] = el
+	}
+	newMap := map[string]object{}
+	for _, el := range newObjects {
+		newMap[el.id] = el
+	}
+	for _, el := range oldObjects {
+		if _, ok := newMap[el.id]; !ok {
+			changes = append(changes, Change{Type: Removed, Kind: kind, ID: el.id, Name: el.name})
+		}
+	}
+	for _, el := range newObjects {
+		old, ok := oldMap[el.id]
+		if !ok {
+			changes = append(changes, Change{Type: Added, Kind: kind, ID: el.id, Name: el.name})
+			continue
+		}
+		if fields := diffMessage("", old.msg.ProtoReflect(), el.msg.ProtoReflect()); len(fields) > 0 {
+			changes = append(changes, Change{Type: Modified, Kind: kind, ID: el.id, Name: el.name, Fields: fields})
+		}
+	}
+	return
+}
+
+// diffMessage returns paths of fields with different values
+// nested messages are compared recursively, repeated and map fields are compared as a whole
+func diffMessage(prefix string, oldMsg, newMsg protoreflect.Message) (fields []string) {
+	descFields := oldMsg.Descriptor().Fields()
+	for i := 0; i < descFields.Len(); i++ {
+		fd := descFields.Get(i)
+		path := fd.JSONName()
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		fields = append(fields, diffField(path, fd, oldMsg, newMsg)...)
+	}
+	sort.Strings(fields)
+	return
+}
+
+func diffField(path string, fd protoreflect.FieldDescriptor, oldMsg, newMsg protoreflect.Message) []string {
+	oldHas, newHas := oldMsg.Has(fd), newMsg.Has(fd)
+	if !oldHas && !newHas {
+		return nil
+	}
+	if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() && oldHas && newHas {
+		return diffMessage(path, oldMsg.Get(fd).Message(), newMsg.Get(fd).Message())
+	}
+	if oldHas != newHas || !fieldEqual(fd, oldMsg, newMsg) {
+		return []string{path}
+	}
+	return nil
+}
+
+// fieldEqual compares values of field in messages of the same type
+func fieldEqual(fd protoreflect.FieldDescriptor, oldMsg, newMsg protoreflect.Message) bool {
+	oldField, newField := oldMsg.New(), newMsg.New()
+	oldField.Set(fd, oldMsg.Get(fd))
+	newField.Set(fd, newMsg.Get(fd))
+	return proto.Equal(oldField.Interface(), newField.Interface())
+}
@@ -0,0 +1,58 @@
+package configdiff_test
+
+import (
+	"testing"
+
+	"github.com/lf-edge/eden/pkg/controller/configdiff"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	oldConfig := &config.EdgeDevConfig{
+		Id: &config.UUIDandVersion{Uuid: "dev", Version: "1"},
+		Apps: []*config.AppInstanceConfig{{
+			Uuidandversion: &config.UUIDandVersion{Uuid: "app1"},
+			Displayname:    "eclient",
+			Fixedresources: &config.VmConfig{Memory: 512, Maxmem: 512},
+		}},
+		Volumes: []*config.Volume{{Uuid: "vol1", DisplayName: "old-volume"}},
+		ConfigItems: []*config.ConfigItem{
+			{Key: "timer.config.interval", Value: "60"},
+			{Key: "debug.enable.ssh", Value: "key"},
+		},
+	}
+	newConfig := &config.EdgeDevConfig{
+		Id: &config.UUIDandVersion{Uuid: "dev", Version: "2"},
+		Apps: []*config.AppInstanceConfig{{
+			Uuidandversion: &config.UUIDandVersion{Uuid: "app1"},
+			Displayname:    "eclient",
+			Fixedresources: &config.VmConfig{Memory: 1024, Maxmem: 512},
+		}},
+		NetworkInstances: []*config.NetworkInstanceConfig{{
+			Uuidandversion: &config.UUIDandVersion{Uuid: "ni1"},
+			Displayname:    "local",
+		}},
+		ConfigItems: []*config.ConfigItem{
+			{Key: "timer.config.interval", Value: "10"},
+			{Key: "debug.enable.ssh", Value: "key"},
+		},
+		Reboot: &config.DeviceOpsCmd{Counter: 1},
+	}
+
+	var changes []string
+	for _, el := range configdiff.Diff(oldConfig, newConfig) {
+		changes = append(changes, el.String())
+	}
+	assert.ElementsMatch(t, []string{
+		"~ app eclient (app1): fixedresources.memory",
+		"- volume old-volume (vol1)",
+		"+ network instance local (ni1)",
+		"~ config item timer.config.interval: value",
+		"~ device: reboot",
+	}, changes)
+
+	assert.Empty(t, configdiff.Diff(oldConfig, oldConfig))
+}
@@ -22,14 +22,19 @@ type Controller interface {
 	ConfigSet(devUUID uuid.UUID, devConfig []byte) (err error)
 	LogAppsChecker(devUUID uuid.UUID, appUUID uuid.UUID, q map[string]string, handler eapps.HandlerFunc, mode eapps.LogCheckerMode, timeout time.Duration) (err error)
 	LogAppsLastCallback(devUUID uuid.UUID, appUUID uuid.UUID, q map[string]string, handler eapps.HandlerFunc) (err error)
+	LogAppsRange(devUUID uuid.UUID, appUUID uuid.UUID, q map[string]string, handler eapps.HandlerFunc, rng types.LoaderRange) (cursor string, err error)
 	LogChecker(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, mode elog.LogCheckerMode, timeout time.Duration) (err error)
 	LogLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc) (err error)
+	LogRange(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, rng types.LoaderRange) (cursor string, err error)
 	FlowLogChecker(devUUID uuid.UUID, q map[string]string, handler eflowlog.HandlerFunc, mode eflowlog.FlowLogCheckerMode, timeout time.Duration) (err error)
 	FlowLogLastCallback(devUUID uuid.UUID, q map[string]string, handler eflowlog.HandlerFunc) (err error)
+	FlowLogRange(devUUID uuid.UUID, q map[string]string, handler eflowlog.HandlerFunc, rng types.LoaderRange) (cursor string, err error)
 	InfoChecker(devUUID uuid.UUID, q map[string]string, handler einfo.HandlerFunc, mode einfo.InfoCheckerMode, timeout time.Duration) (err error)
 	InfoLastCallback(devUUID uuid.UUID, q map[string]string, handler einfo.HandlerFunc) (err error)
+	InfoRange(devUUID uuid.UUID, q map[string]string, handler einfo.HandlerFunc, rng types.LoaderRange) (cursor string, err error)
 	MetricChecker(devUUID uuid.UUID, q map[string]string, handler emetric.HandlerFunc, mode emetric.MetricCheckerMode, timeout time.Duration) (err error)
 	MetricLastCallback(devUUID uuid.UUID, q map[string]string, handler emetric.HandlerFunc) (err error)
+	MetricRange(devUUID uuid.UUID, q map[string]string, handler emetric.HandlerFunc, rng types.LoaderRange) (cursor string, err error)
 	RequestLastCallback(devUUID uuid.UUID, q map[string]string, handler erequest.HandlerFunc) (err error)
 	DeviceList(types.DeviceStateFilter) (out []string, err error)
 	DeviceGetByOnboard(eveCert string) (devUUID uuid.UUID, err error)
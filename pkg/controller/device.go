@@ -271,8 +271,36 @@ func (cloud *CloudCtx) GetDeviceUUID(devUUID uuid.UUID) (dev *device.Ctx, err er
 	return nil, errors.New("no device found")
 }
 
+//GetDevice return device object by UUID or name
+//returns current device if selector is empty
+func (cloud *CloudCtx) GetDevice(selector string) (dev *device.Ctx, err error) {
+	if selector == "" {
+		return cloud.GetDeviceCurrent()
+	}
+	for _, el := range cloud.devices {
+		if el.Match(selector) {
+			return el, nil
+		}
+	}
+	return nil, fmt.Errorf("no device found for %s", selector)
+}
+
+//ListDevices return all known devices
+func (cloud *CloudCtx) ListDevices() []*device.Ctx {
+	return cloud.devices
+}
+
+//SetDeviceCurrent select device by UUID or name to return from GetDeviceCurrent
+//empty selector returns to device defined in config
+func (cloud *CloudCtx) SetDeviceCurrent(selector string) {
+	cloud.selector = selector
+}
+
 //GetDeviceCurrent return current device object
 func (cloud *CloudCtx) GetDeviceCurrent() (dev *device.Ctx, err error) {
+	if cloud.selector != "" {
+		return cloud.GetDevice(cloud.selector)
+	}
 	id, err := cloud.DeviceGetByOnboardUUID(cloud.vars.EveUUID)
 	if err != nil {
 		return nil, err
@@ -297,9 +325,67 @@ func (cloud *CloudCtx) processDev(id uuid.UUID, state device.EdgeNodeState) {
 	if err != nil {
 		log.Fatalf("configParse error: %s", err)
 	}
+	//ConfigParse adds device into cloud.devices if not exists
 	dev.SetState(state)
-	cloud.devices = append(cloud.devices, dev)
+}
+
+//resolveDeviceConfigs set names and contexts of devices from EVE defined in contexts
+func (cloud *CloudCtx) resolveDeviceConfigs() {
+	eves := map[string]utils.ContextEve{}
+	if ctx, err := utils.ContextLoad(); err == nil {
+		list, err := ctx.ListEve()
+		if err != nil {
+			log.Debugf("cannot list EVE from contexts: %s", err)
+		}
+		for _, el := range list {
+			eves[el.UUID] = el
+		}
+	}
+	if cloud.vars != nil && cloud.vars.EveUUID != "" && cloud.vars.EveName != "" {
+		el := eves[cloud.vars.EveUUID]
+		el.Name = cloud.vars.EveName
+		eves[cloud.vars.EveUUID] = el
+	}
+	cloud.deviceConfigsMutex.Lock()
+	defer cloud.deviceConfigsMutex.Unlock()
+	if cloud.deviceConfigs == nil {
+		cloud.deviceConfigs = map[uuid.UUID]*deviceConfig{}
+	}
+	for _, dev := range cloud.devices {
+		if _, ok := cloud.deviceConfigs[dev.GetID()]; ok {
+			continue
+		}
+		onboardUUID, err := cloud.DeviceGetOnboard(dev.GetID())
+		if err != nil {
+			log.Debugf("cannot get onboard UUID of %s: %s", dev.GetID(), err)
+			continue
+		}
+		el := eves[onboardUUID.String()]
+		cloud.deviceConfigs[dev.GetID()] = &deviceConfig{onboardUUID: onboardUUID.String(), context: el.Context}
+		if dev.GetName() == "" {
+			dev.SetName(el.Name)
+		}
+	}
+}
 
+//GetDeviceVars return variables from context of device
+//returns variables of controller for device defined in config or without context
+func (cloud *CloudCtx) GetDeviceVars(dev *device.Ctx) *utils.ConfigVars {
+	cloud.deviceConfigsMutex.Lock()
+	defer cloud.deviceConfigsMutex.Unlock()
+	cfg, ok := cloud.deviceConfigs[dev.GetID()]
+	if !ok || cloud.vars == nil || cfg.onboardUUID == cloud.vars.EveUUID || cfg.context == "" {
+		return cloud.vars
+	}
+	if cfg.vars == nil {
+		vars, err := utils.ContextVars(cfg.context)
+		if err != nil {
+			log.Errorf("cannot load config of device %s: %s", dev.GetID(), err)
+			return cloud.vars
+		}
+		cfg.vars = vars
+	}
+	return cfg.vars
 }
 
 //GetAllNodes obtains all devices from controller
@@ -326,6 +412,7 @@ func (cloud *CloudCtx) GetAllNodes() {
 		}
 		cloud.processDev(id, device.NotOnboarded)
 	}
+	cloud.resolveDeviceConfigs()
 }
 
 //AddDevice add device with specified devUUID
@@ -0,0 +1,257 @@
+package eevent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	subscriptionQueue = 100
+	sinkTimeout       = 10 * time.Second
+)
+
+// Sink receives events from Bus
+type Sink interface {
+	Send(ev *Event) error
+	Close() error
+}
+
+// SinkFunc adapts function to Sink
+type SinkFunc func(ev *Event) error
+
+// Send calls f(ev)
+func (f SinkFunc) Send(ev *Event) error { return f(ev) }
+
+// Close does nothing
+func (f SinkFunc) Close() error { return nil }
+
+// WebhookSink posts events as JSON to URL
+type WebhookSink struct {
+	URL    string
+	client *http.Client
+}
+
+// NewWebhookSink returns sink which posts events to url
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{URL: url, client: &http.Client{Timeout: sinkTimeout}}
+}
+
+// Send posts event to webhook
+func (s *WebhookSink) Send(ev *Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", s.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: unexpected status %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+// Close does nothing for webhook
+func (s *WebhookSink) Close() error { return nil }
+
+// SocketSink writes events as JSON lines into unix stream socket,
+// connection is re-established on the next event after failure
+type SocketSink struct {
+	Path string
+	conn net.Conn
+}
+
+// NewSocketSink returns sink which writes events to unix socket path
+func NewSocketSink(path string) *SocketSink {
+	return &SocketSink{Path: path}
+}
+
+// Send writes event to socket
+func (s *SocketSink) Send(ev *Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if s.conn == nil {
+		if s.conn, err = net.DialTimeout("unix", s.Path, sinkTimeout); err != nil {
+			return fmt.Errorf("socket %s: %w", s.Path, err)
+		}
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+	if _, err = s.conn.Write(append(data, '\n')); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("socket %s: %w", s.Path, err)
+	}
+	return nil
+}
+
+// Close closes connection to socket
+func (s *SocketSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// Match selects events for subscription, empty fields match everything
+type Match struct {
+	Types   []Type
+	Devices []string
+}
+
+func (m Match) matches(ev *Event) bool {
+	return matchString(string(ev.Type), typesToStrings(m.Types)) && matchString(ev.Device, m.Devices)
+}
+
+func typesToStrings(types []Type) []string {
+	var result []string
+	for _, t := range types {
+		result = append(result, string(t))
+	}
+	return result
+}
+
+func matchString(s string, list []string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, el := range list {
+		if el == s {
+			return true
+		}
+	}
+	return false
+}
+
+type subscription struct {
+	match  Match
+	sink   Sink
+	events chan *Event
+	done   chan struct{}
+}
+
+func (s *subscription) run() {
+	defer close(s.done)
+	for ev := range s.events {
+		if err := s.sink.Send(ev); err != nil {
+			log.Errorf("cannot send event %s: %v", ev.Type, err)
+		}
+	}
+	if err := s.sink.Close(); err != nil {
+		log.Errorf("cannot close sink: %v", err)
+	}
+}
+
+// Bus delivers published events to matching subscriptions.
+// Every subscription has own queue, events are dropped when it is full,
+// so slow sink does not block the others.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []*subscription
+}
+
+// NewBus returns Bus without subscriptions
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe sends events matching match to sink
+func (b *Bus) Subscribe(match Match, sink Sink) {
+	s := &subscription{
+		match:  match,
+		sink:   sink,
+		events: make(chan *Event, subscriptionQueue),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	b.mu.Lock()
+	b.subscriptions = append(b.subscriptions, s)
+	b.mu.Unlock()
+}
+
+// AddRule subscribes sinks defined in rule
+func (b *Bus) AddRule(rule Rule) error {
+	if rule.Webhook == "" && rule.Socket == "" {
+		return fmt.Errorf("rule must define webhook or socket")
+	}
+	match := Match{Devices: rule.Devices}
+	for _, el := range rule.Events {
+		t, err := ParseType(el)
+		if err != nil {
+			return err
+		}
+		match.Types = append(match.Types, t)
+	}
+	if rule.Webhook != "" {
+		b.Subscribe(match, NewWebhookSink(rule.Webhook))
+	}
+	if rule.Socket != "" {
+		b.Subscribe(match, NewSocketSink(rule.Socket))
+	}
+	return nil
+}
+
+// Publish queues event for all matching subscriptions
+func (b *Bus) Publish(ev *Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, s := range b.subscriptions {
+		if !s.match.matches(ev) {
+			continue
+		}
+		select {
+		case s.events <- ev:
+		default:
+			log.Warnf("subscription queue is full, drop event %s", ev)
+		}
+	}
+}
+
+// Close stops subscriptions after delivering queued events
+func (b *Bus) Close() {
+	b.mu.Lock()
+	subscriptions := b.subscriptions
+	b.subscriptions = nil
+	b.mu.Unlock()
+	for _, s := range subscriptions {
+		close(s.events)
+		<-s.done
+	}
+}
+
+// Rule routes events to webhook and/or unix socket
+type Rule struct {
+	Events  []string `yaml:"events"`
+	Devices []string `yaml:"devices"`
+	Webhook string   `yaml:"webhook"`
+	Socket  string   `yaml:"socket"`
+}
+
+// LoadRules reads rules from yaml file with top-level rules list
+func LoadRules(file string) ([]Rule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rules: %w", err)
+	}
+	var rules struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	if err = yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("cannot parse rules %s: %w", file, err)
+	}
+	return rules.Rules, nil
+}
@@ -0,0 +1,237 @@
+// Package eevent derives typed events from the info stream of edge nodes
+// and delivers them to subscribers (webhooks, unix sockets or callbacks).
+package eevent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eve/api/go/info"
+)
+
+// Type of event
+type Type string
+
+const (
+	// AppState is emitted when ZInfoApp.State changes
+	AppState Type = "app.state"
+	// AppError is emitted when new errors appear in ZInfoApp.AppErr
+	AppError Type = "app.error"
+	// BaseOSActivated is emitted when another baseOS partition becomes active
+	BaseOSActivated Type = "baseos.activated"
+	// NetworkInstanceError is emitted when new errors appear in ZInfoNetworkInstance.NetworkErr
+	NetworkInstanceError Type = "network-instance.error"
+	// DeviceReboot is emitted when LastRebootTime of device changes
+	DeviceReboot Type = "device.reboot"
+	// DeviceState is emitted when ZInfoDevice.State changes
+	DeviceState Type = "device.state"
+	// VolumeState is emitted when ZInfoVolume.State changes
+	VolumeState Type = "volume.state"
+)
+
+// Types returns all known event types
+func Types() []Type {
+	return []Type{AppState, AppError, BaseOSActivated, NetworkInstanceError, DeviceReboot, DeviceState, VolumeState}
+}
+
+// ParseType checks that s is known event type
+func ParseType(s string) (Type, error) {
+	for _, t := range Types() {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type %q", s)
+}
+
+// Event is a typed change derived from info messages
+type Event struct {
+	Type    Type      `json:"type"`
+	Device  string    `json:"device"`
+	Time    time.Time `json:"time"`
+	Object  string    `json:"object,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Old     string    `json:"old,omitempty"`
+	New     string    `json:"new,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+func (e *Event) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", e.Time.Format(time.RFC3339), e.Device, e.Type)
+	if e.Name != "" {
+		fmt.Fprintf(&b, " %s", e.Name)
+	} else if e.Object != "" {
+		fmt.Fprintf(&b, " %s", e.Object)
+	}
+	if e.Old != "" || e.New != "" {
+		fmt.Fprintf(&b, " %s -> %s", e.Old, e.New)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	return b.String()
+}
+
+// deviceState keeps the last observed values for one device
+type deviceState struct {
+	seen       bool
+	state      string
+	rebootTime time.Time
+	activeOS   string
+	apps       map[string]string
+	appErrors  map[string]time.Time
+	niErrors   map[string]time.Time
+	volumes    map[string]string
+}
+
+func newDeviceState() *deviceState {
+	return &deviceState{
+		apps:      map[string]string{},
+		appErrors: map[string]time.Time{},
+		niErrors:  map[string]time.Time{},
+		volumes:   map[string]string{},
+	}
+}
+
+// Tracker converts info messages into events comparing them with previous ones
+type Tracker struct {
+	mu      sync.Mutex
+	devices map[string]*deviceState
+}
+
+// NewTracker returns empty Tracker
+func NewTracker() *Tracker {
+	return &Tracker{devices: map[string]*deviceState{}}
+}
+
+// Process updates state with info message and returns derived events.
+// The first observation of app, volume or device state is reported with empty Old value,
+// reboots and baseOS activations are reported only after the first device info.
+func (t *Tracker) Process(im *info.ZInfoMsg) []*Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ds, ok := t.devices[im.GetDevId()]
+	if !ok {
+		ds = newDeviceState()
+		t.devices[im.GetDevId()] = ds
+	}
+	ts := im.GetAtTimeStamp().AsTime()
+	newEvent := func(typ Type) *Event {
+		return &Event{Type: typ, Device: im.GetDevId(), Time: ts}
+	}
+	var events []*Event
+	switch im.GetZtype() {
+	case info.ZInfoTypes_ZiDevice:
+		dinfo := im.GetDinfo()
+		if dinfo == nil {
+			return nil
+		}
+		if state := dinfo.GetState().String(); state != ds.state {
+			ev := newEvent(DeviceState)
+			ev.Old, ev.New = ds.state, state
+			events = append(events, ev)
+			ds.state = state
+		}
+		rebootTime := dinfo.GetLastRebootTime().AsTime()
+		activeOS := ""
+		for _, sw := range dinfo.GetSwList() {
+			if sw.GetActivated() {
+				activeOS = sw.GetShortVersion()
+			}
+		}
+		if ds.seen {
+			if dinfo.GetLastRebootTime() != nil && !rebootTime.Equal(ds.rebootTime) {
+				ev := newEvent(DeviceReboot)
+				ev.Time = rebootTime
+				ev.Message = dinfo.GetLastRebootReason()
+				events = append(events, ev)
+			}
+			if activeOS != "" && activeOS != ds.activeOS {
+				ev := newEvent(BaseOSActivated)
+				ev.Old, ev.New = ds.activeOS, activeOS
+				events = append(events, ev)
+			}
+		}
+		ds.seen = true
+		ds.rebootTime = rebootTime
+		if activeOS != "" {
+			ds.activeOS = activeOS
+		}
+	case info.ZInfoTypes_ZiApp:
+		ainfo := im.GetAinfo()
+		if ainfo == nil {
+			return nil
+		}
+		if state := ainfo.GetState().String(); state != ds.apps[ainfo.GetAppID()] {
+			ev := newEvent(AppState)
+			ev.Object, ev.Name = ainfo.GetAppID(), ainfo.GetAppName()
+			ev.Old, ev.New = ds.apps[ainfo.GetAppID()], state
+			events = append(events, ev)
+			ds.apps[ainfo.GetAppID()] = state
+		}
+		for _, ev := range newErrors(ds.appErrors, ainfo.GetAppID(), ainfo.GetAppErr()) {
+			ev.Type, ev.Device = AppError, im.GetDevId()
+			ev.Object, ev.Name = ainfo.GetAppID(), ainfo.GetAppName()
+			events = append(events, ev)
+		}
+	case info.ZInfoTypes_ZiNetworkInstance:
+		niinfo := im.GetNiinfo()
+		if niinfo == nil {
+			return nil
+		}
+		for _, ev := range newErrors(ds.niErrors, niinfo.GetNetworkID(), niinfo.GetNetworkErr()) {
+			ev.Type, ev.Device = NetworkInstanceError, im.GetDevId()
+			ev.Object, ev.Name = niinfo.GetNetworkID(), niinfo.GetDisplayname()
+			events = append(events, ev)
+		}
+	case info.ZInfoTypes_ZiVolume:
+		vinfo := im.GetVinfo()
+		if vinfo == nil {
+			return nil
+		}
+		if state := vinfo.GetState().String(); state != ds.volumes[vinfo.GetUuid()] {
+			ev := newEvent(VolumeState)
+			ev.Object, ev.Name = vinfo.GetUuid(), vinfo.GetDisplayName()
+			ev.Old, ev.New = ds.volumes[vinfo.GetUuid()], state
+			events = append(events, ev)
+			ds.volumes[vinfo.GetUuid()] = state
+		}
+	}
+	return events
+}
+
+// newErrors returns events for errors with timestamp after the last reported one for object
+func newErrors(last map[string]time.Time, object string, errs []*info.ErrorInfo) []*Event {
+	var events []*Event
+	latest := last[object]
+	for _, e := range errs {
+		if e.GetDescription() == "" {
+			continue
+		}
+		ts := e.GetTimestamp().AsTime()
+		if !ts.After(last[object]) {
+			continue
+		}
+		events = append(events, &Event{Time: ts, Message: e.GetDescription()})
+		if ts.After(latest) {
+			latest = ts
+		}
+	}
+	last[object] = latest
+	return events
+}
+
+// Handler returns einfo.HandlerFunc which feeds info messages into tracker
+// and passes derived events to publish; it never stops processing
+func (t *Tracker) Handler(publish func(*Event)) einfo.HandlerFunc {
+	return func(im *info.ZInfoMsg) bool {
+		for _, ev := range t.Process(im) {
+			publish(ev)
+		}
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package eevent_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/eevent"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func appInfo(state info.ZSwState, errs ...*info.ErrorInfo) *info.ZInfoMsg {
+	return &info.ZInfoMsg{
+		Ztype:       info.ZInfoTypes_ZiApp,
+		DevId:       "dev1",
+		AtTimeStamp: timestamppb.Now(),
+		InfoContent: &info.ZInfoMsg_Ainfo{Ainfo: &info.ZInfoApp{
+			AppID: "app-uuid", AppName: "eclient", State: state, AppErr: errs,
+		}},
+	}
+}
+
+func deviceInfo(version string, reboot time.Time) *info.ZInfoMsg {
+	return &info.ZInfoMsg{
+		Ztype:       info.ZInfoTypes_ZiDevice,
+		DevId:       "dev1",
+		AtTimeStamp: timestamppb.Now(),
+		InfoContent: &info.ZInfoMsg_Dinfo{Dinfo: &info.ZInfoDevice{
+			State:            info.ZDeviceState_ZDEVICE_STATE_ONLINE,
+			LastRebootTime:   timestamppb.New(reboot),
+			LastRebootReason: "reboot from controller",
+			SwList:           []*info.ZInfoDevSW{{Activated: true, ShortVersion: version}},
+		}},
+	}
+}
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := eevent.NewTracker()
+	events := tracker.Process(appInfo(info.ZSwState_INSTALLED))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, eevent.AppState, events[0].Type)
+		assert.Equal(t, "eclient", events[0].Name)
+		assert.Equal(t, "INSTALLED", events[0].New)
+	}
+	assert.Empty(t, tracker.Process(appInfo(info.ZSwState_INSTALLED)))
+	errInfo := &info.ErrorInfo{Description: "no space", Timestamp: timestamppb.Now()}
+	events = tracker.Process(appInfo(info.ZSwState_HALTED, errInfo))
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "INSTALLED", events[0].Old)
+		assert.Equal(t, "HALTED", events[0].New)
+		assert.Equal(t, eevent.AppError, events[1].Type)
+		assert.Equal(t, "no space", events[1].Message)
+	}
+	// the same error must not be reported twice
+	assert.Empty(t, tracker.Process(appInfo(info.ZSwState_HALTED, errInfo)))
+
+	boot := time.Now().Add(-time.Hour)
+	events = tracker.Process(deviceInfo("0.0.1", boot))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, eevent.DeviceState, events[0].Type)
+	}
+	events = tracker.Process(deviceInfo("0.0.2", time.Now()))
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, eevent.DeviceReboot, events[0].Type)
+		assert.Equal(t, "reboot from controller", events[0].Message)
+		assert.Equal(t, eevent.BaseOSActivated, events[1].Type)
+		assert.Equal(t, "0.0.2", events[1].New)
+	}
+}
+
+func TestBusWebhook(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan *eevent.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev eevent.Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- &ev
+	}))
+	defer server.Close()
+
+	bus := eevent.NewBus()
+	assert.NoError(t, bus.AddRule(eevent.Rule{Events: []string{"app.state"}, Webhook: server.URL}))
+	assert.Error(t, bus.AddRule(eevent.Rule{Events: []string{"unknown"}, Webhook: server.URL}))
+	bus.Publish(&eevent.Event{Type: eevent.DeviceReboot, Device: "dev1"})
+	bus.Publish(&eevent.Event{Type: eevent.AppState, Device: "dev1", New: "RUNNING"})
+	bus.Close()
+
+	close(received)
+	var events []*eevent.Event
+	for ev := range received {
+		events = append(events, ev)
+	}
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "RUNNING", events[0].New)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lf-edge/eden/pkg/controller/equery"
 	"github.com/lf-edge/eden/pkg/controller/loaders"
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -67,6 +68,12 @@ func FlowLogItemPrint(le *flowlog.FlowMessage, query []string) *types.PrintResul
 func FlowLogItemFind(le *flowlog.FlowMessage, query map[string]string) bool {
 	matched := true
 	for k, v := range query {
+		if k == equery.QueryKey {
+			if !equery.MatchString(v, le) {
+				return false
+			}
+			continue
+		}
 		// Uppercase of filed's name first letter
 		var n []string
 		caser := cases.Title(language.English, cases.NoLower)
@@ -159,6 +166,15 @@ func FlowLogLast(loader loaders.Loader, query map[string]string, handler Handler
 	return loader.ProcessExisting(flowLogProcess(query, handler), types.FlowLogType)
 }
 
+// FlowLogRange process FlowMessages received by controller inside of the time range and after the cursor defined in 'rng'
+// according to the 'query' reqexps using the 'handler' function. It returns cursor of the last processed message to resume from.
+func FlowLogRange(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, handler HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	loader.SetUUID(devUUID)
+	loader.SetRange(rng)
+	err = FlowLogLast(loader, query, handler)
+	return loader.GetCursor(), err
+}
+
 // FlowLogChecker check logs by pattern from existence files with FlowLogLast and use FlowLogWatchWithTimeout with timeout for observe new files
 func FlowLogChecker(loader loaders.Loader, devUUID uuid.UUID, q map[string]string, handler HandlerFunc, mode FlowLogCheckerMode, timeout time.Duration) (err error) {
 	loader.SetUUID(devUUID)
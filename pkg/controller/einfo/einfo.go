@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lf-edge/eden/pkg/controller/equery"
 	"github.com/lf-edge/eden/pkg/controller/loaders"
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -67,6 +68,12 @@ func HandleFactory(format types.OutputFormat, once bool) HandlerFunc {
 func processElem(value reflect.Value, query map[string]string) bool {
 	matched := true
 	for k, v := range query {
+		if k == equery.QueryKey {
+			if !equery.MatchString(v, value.Interface().(proto.Message)) {
+				return false
+			}
+			continue
+		}
 		// Uppercase of filed's name first letter
 		var n []string
 		caser := cases.Title(language.English, cases.NoLower)
@@ -166,6 +173,15 @@ func InfoWatch(loader loaders.Loader, query map[string]string, qhandler QHandler
 	return loader.ProcessStream(infoProcess(query, qhandler, handler), types.InfoType, timeoutSeconds)
 }
 
+// InfoRange processes info.ZInfoMsg received by controller inside of the time range and after the cursor defined in 'rng'
+// according to the 'query' reqexps using the 'handler' function. It returns cursor of the last processed message to resume from.
+func InfoRange(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, handler HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	loader.SetUUID(devUUID)
+	loader.SetRange(rng)
+	err = InfoLast(loader, query, ZInfoFind, handler)
+	return loader.GetCursor(), err
+}
+
 // InfoChecker checks the information in the regular expression pattern 'query' and processes the info.ZInfoMsg found by the function 'handler' from existing files (mode=InfoExist), new files (mode=InfoNew) or any of them (mode=InfoAny) with timeout (0 for infinite).
 func InfoChecker(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, handler HandlerFunc, mode InfoCheckerMode, timeout time.Duration) (err error) {
 	loader.SetUUID(devUUID)
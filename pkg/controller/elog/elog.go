@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lf-edge/eden/pkg/controller/equery"
 	"github.com/lf-edge/eden/pkg/controller/loaders"
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -72,6 +73,12 @@ func LogItemPrint(le *FullLogEntry, _ types.OutputFormat, query []string) *types
 func LogItemFind(le *FullLogEntry, query map[string]string) bool {
 	matched := true
 	for k, v := range query {
+		if k == equery.QueryKey {
+			if !equery.MatchString(v, &le.LogEntry) {
+				return false
+			}
+			continue
+		}
 		// Uppercase of filed's name first letter
 		var n []string
 		caser := cases.Title(language.English, cases.NoLower)
@@ -170,6 +177,15 @@ func LogLast(loader loaders.Loader, query map[string]string, handler HandlerFunc
 	return loader.ProcessExisting(logProcess(query, handler), types.LogsType)
 }
 
+// LogRange process Log entries received by controller inside of the time range and after the cursor defined in 'rng'
+// according to the 'query' reqexps using the 'handler' function. It returns cursor of the last processed entry to resume from.
+func LogRange(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, handler HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	loader.SetUUID(devUUID)
+	loader.SetRange(rng)
+	err = LogLast(loader, query, handler)
+	return loader.GetCursor(), err
+}
+
 // LogChecker check logs by pattern from existence files with LogLast and use LogWatchWithTimeout with timeout for observe new files
 func LogChecker(loader loaders.Loader, devUUID uuid.UUID, q map[string]string, handler HandlerFunc, mode LogCheckerMode, timeout time.Duration) (err error) {
 	loader.SetUUID(devUUID)
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lf-edge/eden/pkg/controller/equery"
 	"github.com/lf-edge/eden/pkg/controller/loaders"
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -70,6 +71,12 @@ func MetricItemPrint(mm *metrics.ZMetricMsg, query []string) *types.PrintResult
 func MetricItemFind(mm *metrics.ZMetricMsg, query map[string]string) bool {
 	matched := true
 	for k, v := range query {
+		if k == equery.QueryKey {
+			if !equery.MatchString(v, mm) {
+				return false
+			}
+			continue
+		}
 		splitRequest := strings.Split(k, ".")
 		if len(splitRequest) > 0 && strings.ToLower(splitRequest[0]) == "dm" { //dm is located in MetricContent
 			query[strings.Join(append([]string{"MetricContent"}, splitRequest...), ".")] = v
@@ -157,6 +164,15 @@ func MetricLast(loader loaders.Loader, query map[string]string, handler HandlerF
 	return loader.ProcessExisting(metricProcess(query, handler), types.MetricsType)
 }
 
+// MetricRange process metrics received by controller inside of the time range and after the cursor defined in 'rng'
+// according to the 'query' reqexps using the 'handler' function. It returns cursor of the last processed metric to resume from.
+func MetricRange(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, handler HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	loader.SetUUID(devUUID)
+	loader.SetRange(rng)
+	err = MetricLast(loader, query, handler)
+	return loader.GetCursor(), err
+}
+
 // MetricChecker check metrics by pattern from existence files with HandlerFunc with timeout for observe new files
 func MetricChecker(loader loaders.Loader, devUUID uuid.UUID, q map[string]string, handler HandlerFunc, mode MetricCheckerMode, timeout time.Duration) (err error) {
 	loader.SetUUID(devUUID)
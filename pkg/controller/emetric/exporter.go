@@ -0,0 +1,297 @@
+package emetric
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eve/api/go/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	namespace = "eve"
+	megabyte  = 1024 * 1024
+)
+
+var (
+	deviceLabels          = []string{"device"}
+	deviceInterfaceLabels = []string{"device", "interface"}
+	deviceDiskLabels      = []string{"device", "disk", "mount_path"}
+	appLabels             = []string{"device", "app_uuid", "app_name"}
+	appInterfaceLabels    = []string{"device", "app_uuid", "app_name", "interface"}
+	appDiskLabels         = []string{"device", "app_uuid", "app_name", "disk"}
+	networkInstanceLabels = []string{"device", "network_uuid", "network_name"}
+)
+
+func newDesc(subsystem, name, help string, labels []string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, labels, nil)
+}
+
+// interfaceDescs describes counters of network interface
+type interfaceDescs struct {
+	rxBytes, txBytes, rxPackets, txPackets, rxDrops, txDrops, rxErrors, txErrors *prometheus.Desc
+}
+
+func newInterfaceDescs(subsystem string, labels []string) interfaceDescs {
+	return interfaceDescs{
+		rxBytes:   newDesc(subsystem, "network_receive_bytes_total", "Bytes received by interface.", labels),
+		txBytes:   newDesc(subsystem, "network_transmit_bytes_total", "Bytes transmitted by interface.", labels),
+		rxPackets: newDesc(subsystem, "network_receive_packets_total", "Packets received by interface.", labels),
+		txPackets: newDesc(subsystem, "network_transmit_packets_total", "Packets transmitted by interface.", labels),
+		rxDrops:   newDesc(subsystem, "network_receive_drops_total", "Received packets dropped by interface.", labels),
+		txDrops:   newDesc(subsystem, "network_transmit_drops_total", "Transmitted packets dropped by interface.", labels),
+		rxErrors:  newDesc(subsystem, "network_receive_errors_total", "Receive errors of interface.", labels),
+		txErrors:  newDesc(subsystem, "network_transmit_errors_total", "Transmit errors of interface.", labels),
+	}
+}
+
+var (
+	timestampDesc = newDesc("", "metric_timestamp_seconds", "Time of the last metric message from device.", deviceLabels)
+
+	deviceCPUDesc       = newDesc("device", "cpu_seconds_total", "CPU time consumed by device.", deviceLabels)
+	deviceMemUsedDesc   = newDesc("device", "memory_used_bytes", "Memory used on device.", deviceLabels)
+	deviceMemAvailDesc  = newDesc("device", "memory_available_bytes", "Memory available on device.", deviceLabels)
+	deviceMemUsedPcDesc = newDesc("device", "memory_used_percent", "Percentage of used memory on device.", deviceLabels)
+	deviceInterface     = newInterfaceDescs("device", deviceInterfaceLabels)
+	deviceDiskRead      = newDesc("device", "disk_read_bytes_total", "Bytes read from disk.", deviceDiskLabels)
+	deviceDiskWrite     = newDesc("device", "disk_written_bytes_total", "Bytes written to disk.", deviceDiskLabels)
+	deviceDiskReads     = newDesc("device", "disk_reads_completed_total", "Read operations completed on disk.", deviceDiskLabels)
+	deviceDiskWrites    = newDesc("device", "disk_writes_completed_total", "Write operations completed on disk.", deviceDiskLabels)
+	deviceDiskTotal     = newDesc("device", "disk_size_bytes", "Size of disk or mount path.", deviceDiskLabels)
+	deviceDiskUsed      = newDesc("device", "disk_used_bytes", "Used space of disk or mount path.", deviceDiskLabels)
+	deviceDiskFree      = newDesc("device", "disk_free_bytes", "Free space of disk or mount path.", deviceDiskLabels)
+
+	appCPUDesc          = newDesc("app", "cpu_seconds_total", "CPU time consumed by app instance.", appLabels)
+	appMemUsedDesc      = newDesc("app", "memory_used_bytes", "Memory used by app instance.", appLabels)
+	appMemAvailDesc     = newDesc("app", "memory_available_bytes", "Memory available for app instance.", appLabels)
+	appMemAllocatedDesc = newDesc("app", "memory_allocated_bytes", "Memory allocated for app instance.", appLabels)
+	appInterface        = newInterfaceDescs("app", appInterfaceLabels)
+	appDiskProvisioned  = newDesc("app", "disk_provisioned_bytes", "Provisioned size of app instance disk.", appDiskLabels)
+	appDiskUsed         = newDesc("app", "disk_used_bytes", "Used space of app instance disk.", appDiskLabels)
+
+	niRxBytes   = newDesc("network_instance", "receive_bytes_total", "Bytes received by network instance.", networkInstanceLabels)
+	niTxBytes   = newDesc("network_instance", "transmit_bytes_total", "Bytes transmitted by network instance.", networkInstanceLabels)
+	niRxPackets = newDesc("network_instance", "receive_packets_total", "Packets received by network instance.", networkInstanceLabels)
+	niTxPackets = newDesc("network_instance", "transmit_packets_total", "Packets transmitted by network instance.", networkInstanceLabels)
+	niRxDrops   = newDesc("network_instance", "receive_drops_total", "Received packets dropped by network instance.", networkInstanceLabels)
+	niTxDrops   = newDesc("network_instance", "transmit_drops_total", "Transmitted packets dropped by network instance.", networkInstanceLabels)
+	niRxErrors  = newDesc("network_instance", "receive_errors_total", "Receive errors of network instance.", networkInstanceLabels)
+	niTxErrors  = newDesc("network_instance", "transmit_errors_total", "Transmit errors of network instance.", networkInstanceLabels)
+)
+
+// sample is one value of metric with labels
+type sample struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     float64
+	labels    []string
+}
+
+// Exporter converts ZMetricMsg into Prometheus metrics.
+// It keeps the last received values for every device and implements prometheus.Collector.
+type Exporter struct {
+	mu       sync.Mutex
+	samples  map[string][]sample
+	updated  map[string]time.Time // time of message samples of device come from
+	received *prometheus.CounterVec
+	registry *prometheus.Registry
+}
+
+// NewExporter creates Exporter with own registry
+func NewExporter() *Exporter {
+	exporter := &Exporter{
+		samples: map[string][]sample{},
+		updated: map[string]time.Time{},
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "metric_messages_received_total",
+			Help:      "Metric messages received from device.",
+		}, deviceLabels),
+		registry: prometheus.NewRegistry(),
+	}
+	exporter.registry.MustRegister(exporter.received, exporter)
+	return exporter
+}
+
+// Describe implements prometheus.Collector
+// it sends no descriptors as set of metrics depends on received messages
+func (exporter *Exporter) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (exporter *Exporter) Collect(ch chan<- prometheus.Metric) {
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	for _, samples := range exporter.samples {
+		for _, s := range samples {
+			ch <- prometheus.MustNewConstMetric(s.desc, s.valueType, s.value, s.labels...)
+		}
+	}
+}
+
+// Handler returns http.Handler to serve metrics in OpenMetrics or Prometheus text format
+func (exporter *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(exporter.registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+		ErrorHandling:     promhttp.ContinueOnError,
+		ErrorLog:          log.StandardLogger(),
+	})
+}
+
+// HandleFactory returns HandlerFunc which updates exporter with every processed message
+func (exporter *Exporter) HandleFactory() HandlerFunc {
+	return func(mm *metrics.ZMetricMsg) bool {
+		exporter.Update(mm)
+		return false
+	}
+}
+
+// Update replaces values of device with values from mm
+// messages older than the one values come from are only counted,
+// as existing and new messages may be processed concurrently
+func (exporter *Exporter) Update(mm *metrics.ZMetricMsg) {
+	b := &sampleBuilder{}
+	dev := mm.GetDevID()
+	var ts time.Time
+	if mm.GetAtTimeStamp() != nil {
+		ts = mm.GetAtTimeStamp().AsTime()
+		b.gauge(timestampDesc, float64(ts.UnixNano())/1e9, dev)
+	}
+	if dm := mm.GetDm(); dm != nil {
+		b.addDevice(dev, dm)
+	}
+	for _, am := range mm.GetAm() {
+		b.addApp(dev, am)
+	}
+	for _, nm := range mm.GetNm() {
+		b.addNetworkInstance(dev, nm)
+	}
+	exporter.received.WithLabelValues(dev).Inc()
+	exporter.mu.Lock()
+	if !ts.Before(exporter.updated[dev]) {
+		exporter.samples[dev] = b.samples
+		exporter.updated[dev] = ts
+	}
+	exporter.mu.Unlock()
+}
+
+// sampleBuilder collects samples of one message
+type sampleBuilder struct {
+	samples []sample
+	seen    map[string]int
+}
+
+// add appends sample and makes its label set unique
+// EVE may report several interfaces or disks with the same name and duplicated label sets fail the whole scrape,
+// so the last label of repeated set gets "#<n>" suffix
+func (b *sampleBuilder) add(desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labels []string) {
+	if b.seen == nil {
+		b.seen = map[string]int{}
+	}
+	key := desc.String() + "\xff" + strings.Join(labels, "\xff")
+	if n := b.seen[key]; n > 0 && len(labels) > 0 {
+		labels = append([]string{}, labels...)
+		labels[len(labels)-1] = fmt.Sprintf("%s#%d", labels[len(labels)-1], n)
+	}
+	b.seen[key]++
+	b.samples = append(b.samples, sample{desc: desc, valueType: valueType, value: value, labels: labels})
+}
+
+func (b *sampleBuilder) gauge(desc *prometheus.Desc, value float64, labels ...string) {
+	b.add(desc, prometheus.GaugeValue, value, labels)
+}
+
+func (b *sampleBuilder) counter(desc *prometheus.Desc, value float64, labels ...string) {
+	b.add(desc, prometheus.CounterValue, value, labels)
+}
+
+func cpuSeconds(cpu *metrics.AppCpuMetric) float64 {
+	if cpu.GetTotalNs() != 0 {
+		return float64(cpu.GetTotalNs()) / 1e9
+	}
+	return float64(cpu.GetTotal())
+}
+
+func (b *sampleBuilder) addInterfaces(descs interfaceDescs, network []*metrics.NetworkMetric, labels ...string) {
+	for _, nm := range network {
+		name := nm.GetLocalName()
+		if name == "" {
+			name = nm.GetIName()
+		}
+		l := append(append([]string{}, labels...), name)
+		b.counter(descs.rxBytes, float64(nm.GetRxBytes()), l...)
+		b.counter(descs.txBytes, float64(nm.GetTxBytes()), l...)
+		b.counter(descs.rxPackets, float64(nm.GetRxPkts()), l...)
+		b.counter(descs.txPackets, float64(nm.GetTxPkts()), l...)
+		b.counter(descs.rxDrops, float64(nm.GetRxDrops()), l...)
+		b.counter(descs.txDrops, float64(nm.GetTxDrops()), l...)
+		b.counter(descs.rxErrors, float64(nm.GetRxErrors()), l...)
+		b.counter(descs.txErrors, float64(nm.GetTxErrors()), l...)
+	}
+}
+
+func (b *sampleBuilder) addDevice(dev string, dm *metrics.DeviceMetric) {
+	if cpu := dm.GetCpuMetric(); cpu != nil {
+		b.counter(deviceCPUDesc, cpuSeconds(cpu), dev)
+	}
+	if mem := dm.GetMemory(); mem != nil {
+		b.gauge(deviceMemUsedDesc, float64(mem.GetUsedMem())*megabyte, dev)
+		b.gauge(deviceMemAvailDesc, float64(mem.GetAvailMem())*megabyte, dev)
+		b.gauge(deviceMemUsedPcDesc, mem.GetUsedPercentage(), dev)
+	}
+	b.addInterfaces(deviceInterface, dm.GetNetwork(), dev)
+	for _, disk := range dm.GetDisk() {
+		l := []string{dev, disk.GetDisk(), disk.GetMountPath()}
+		b.counter(deviceDiskRead, float64(disk.GetReadBytes())*megabyte, l...)
+		b.counter(deviceDiskWrite, float64(disk.GetWriteBytes())*megabyte, l...)
+		b.counter(deviceDiskReads, float64(disk.GetReadCount()), l...)
+		b.counter(deviceDiskWrites, float64(disk.GetWriteCount()), l...)
+		b.gauge(deviceDiskTotal, float64(disk.GetTotal())*megabyte, l...)
+		b.gauge(deviceDiskUsed, float64(disk.GetUsed())*megabyte, l...)
+		b.gauge(deviceDiskFree, float64(disk.GetFree())*megabyte, l...)
+	}
+}
+
+func (b *sampleBuilder) addApp(dev string, am *metrics.AppMetric) {
+	l := []string{dev, am.GetAppID(), am.GetAppName()}
+	if cpu := am.GetCpu(); cpu != nil {
+		b.counter(appCPUDesc, cpuSeconds(cpu), l...)
+	}
+	if mem := am.GetMemory(); mem != nil {
+		b.gauge(appMemUsedDesc, float64(mem.GetUsedMem())*megabyte, l...)
+		b.gauge(appMemAvailDesc, float64(mem.GetAvailMem())*megabyte, l...)
+	}
+	if mem := am.GetAppMemory(); mem != nil {
+		b.gauge(appMemAllocatedDesc, float64(mem.GetAllocatedMB())*megabyte, l...)
+	}
+	b.addInterfaces(appInterface, am.GetNetwork(), l...)
+	for _, disk := range am.GetDisk() {
+		dl := append(append([]string{}, l...), disk.GetDisk())
+		b.gauge(appDiskProvisioned, float64(disk.GetProvisioned())*megabyte, dl...)
+		b.gauge(appDiskUsed, float64(disk.GetUsed())*megabyte, dl...)
+	}
+}
+
+func (b *sampleBuilder) addNetworkInstance(dev string, nm *metrics.ZMetricNetworkInstance) {
+	stats := nm.GetNetworkStats()
+	if stats == nil {
+		return
+	}
+	l := []string{dev, nm.GetNetworkID(), nm.GetDisplayname()}
+	if rx := stats.GetRx(); rx != nil {
+		b.counter(niRxBytes, float64(rx.GetTotalBytes()), l...)
+		b.counter(niRxPackets, float64(rx.GetTotalPackets()), l...)
+		b.counter(niRxDrops, float64(rx.GetDrops()), l...)
+		b.counter(niRxErrors, float64(rx.GetErrors()), l...)
+	}
+	if tx := stats.GetTx(); tx != nil {
+		b.counter(niTxBytes, float64(tx.GetTotalBytes()), l...)
+		b.counter(niTxPackets, float64(tx.GetTotalPackets()), l...)
+		b.counter(niTxDrops, float64(tx.GetDrops()), l...)
+		b.counter(niTxErrors, float64(tx.GetErrors()), l...)
+	}
+}
@@ -0,0 +1,128 @@
+package emetric_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/emetric"
+	"github.com/lf-edge/eve/api/go/metrics"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestExporter(t *testing.T) {
+	t.Parallel()
+
+	exporter := emetric.NewExporter()
+	exporter.Update(&metrics.ZMetricMsg{
+		DevID:       "dev1",
+		AtTimeStamp: timestamppb.Now(),
+		MetricContent: &metrics.ZMetricMsg_Dm{Dm: &metrics.DeviceMetric{
+			Memory:    &metrics.MemoryMetric{UsedMem: 100, AvailMem: 900},
+			Network:   []*metrics.NetworkMetric{{LocalName: "eth0", RxBytes: 10, TxBytes: 20}},
+			CpuMetric: &metrics.AppCpuMetric{TotalNs: 3e9},
+		}},
+		Am: []*metrics.AppMetric{{
+			AppID:   "app-uuid",
+			AppName: "eclient",
+			Cpu:     &metrics.AppCpuMetric{Total: 7},
+		}},
+		Nm: []*metrics.ZMetricNetworkInstance{{
+			NetworkID:    "ni-uuid",
+			Displayname:  "local",
+			NetworkStats: &metrics.ZMetricNetworkStats{Rx: &metrics.NetworkStats{TotalBytes: 5}},
+		}},
+	})
+
+	server := httptest.NewServer(exporter.Handler())
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	for _, expected := range []string{
+		`eve_device_memory_used_bytes{device="dev1"} 1.048576e+08`,
+		`eve_device_cpu_seconds_total{device="dev1"} 3`,
+		`eve_device_network_receive_bytes_total{device="dev1",interface="eth0"} 10`,
+		`eve_app_cpu_seconds_total{app_name="eclient",app_uuid="app-uuid",device="dev1"} 7`,
+		`eve_network_instance_receive_bytes_total{device="dev1",network_name="local",network_uuid="ni-uuid"} 5`,
+		`eve_metric_messages_received_total{device="dev1"} 1`,
+	} {
+		assert.Contains(t, string(body), expected)
+	}
+}
+
+func TestExporterDuplicateLabels(t *testing.T) {
+	t.Parallel()
+
+	exporter := emetric.NewExporter()
+	exporter.Update(&metrics.ZMetricMsg{
+		DevID: "dev1",
+		MetricContent: &metrics.ZMetricMsg_Dm{Dm: &metrics.DeviceMetric{
+			Network: []*metrics.NetworkMetric{
+				{LocalName: "eth0", RxBytes: 10},
+				{LocalName: "eth0", RxBytes: 20},
+			},
+			Disk: []*metrics.DiskMetric{
+				{Disk: "sda", MountPath: "/", Total: 1},
+				{Disk: "sda", MountPath: "/", Total: 2},
+			},
+		}},
+	})
+
+	server := httptest.NewServer(exporter.Handler())
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	for _, expected := range []string{
+		`eve_device_network_receive_bytes_total{device="dev1",interface="eth0"} 10`,
+		`eve_device_network_receive_bytes_total{device="dev1",interface="eth0#1"} 20`,
+		`eve_device_disk_size_bytes{device="dev1",disk="sda",mount_path="/"} 1.048576e+06`,
+		`eve_device_disk_size_bytes{device="dev1",disk="sda",mount_path="/#1"} 2.097152e+06`,
+	} {
+		assert.Contains(t, string(body), expected)
+	}
+}
+
+func TestExporterKeepsNewerMessage(t *testing.T) {
+	t.Parallel()
+
+	exporter := emetric.NewExporter()
+	update := func(at time.Time, used uint32) {
+		exporter.Update(&metrics.ZMetricMsg{
+			DevID:         "dev1",
+			AtTimeStamp:   timestamppb.New(at),
+			MetricContent: &metrics.ZMetricMsg_Dm{Dm: &metrics.DeviceMetric{Memory: &metrics.MemoryMetric{UsedMem: used}}},
+		})
+	}
+	now := time.Now()
+	update(now, 2)
+	// existing message processed after the new one does not replace values
+	update(now.Add(-time.Minute), 1)
+
+	server := httptest.NewServer(exporter.Handler())
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `eve_device_memory_used_bytes{device="dev1"} 2.097152e+06`)
+	assert.Contains(t, string(body), `eve_metric_messages_received_total{device="dev1"} 2`)
+}
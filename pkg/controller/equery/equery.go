@@ -0,0 +1,367 @@
+// Package equery provides small expression language to filter info, log and metric messages.
+//
+// Expression consists of comparisons joined with boolean operators:
+//
+//	dinfo.ncpu >= 2 and (ztype == ZiDevice or not exists(ainfo))
+//	dm.memory.usedMem > 500 && dm.network[0].txBytes > 0
+//	ainfo.appName =~ "^eclient" || !dinfo.network[*].uplink
+//
+// Paths are built from proto or json field names (case insensitive, underscores are ignored),
+// repeated fields may be indexed with [N] or [*], maps with [key]. Repeated fields without index
+// are iterated implicitly. Comparison is true if any of resolved values satisfies it.
+// Supported operators: ==, !=, >, >=, <, <=, =~ (regexp match), !~ (regexp not match),
+// and/&&, or/||, not/!, exists(path). Path without comparison is true if it is set to non-zero value.
+package equery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+// QueryKey is a key inside of query map of checkers to pass expression instead of path:regexp pair
+const QueryKey = "@query"
+
+// node is an element of parsed expression
+type node interface {
+	eval(msg proto.Message) bool
+	String() string
+}
+
+// Query is parsed expression
+type Query struct {
+	root node
+	expr string
+}
+
+// Parse parses expression
+func Parse(expr string) (*Query, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s", p.peek())
+	}
+	return &Query{root: root, expr: expr}, nil
+}
+
+// MustParse parses expression and panics on error
+func MustParse(expr string) *Query {
+	q, err := Parse(expr)
+	if err != nil {
+		panic(fmt.Sprintf("equery: cannot parse %q: %s", expr, err))
+	}
+	return q
+}
+
+// Match evaluates expression against msg
+func (q *Query) Match(msg proto.Message) bool {
+	if q == nil || q.root == nil {
+		return true
+	}
+	return q.root.eval(msg)
+}
+
+// String returns normalized form of expression
+func (q *Query) String() string {
+	if q == nil || q.root == nil {
+		return ""
+	}
+	return q.root.String()
+}
+
+var cache sync.Map
+
+// MatchString parses expr (results are cached) and evaluates it against msg
+// it returns false if expression is not valid
+func MatchString(expr string, msg proto.Message) bool {
+	if q, ok := cache.Load(expr); ok {
+		return q.(*Query).Match(msg)
+	}
+	q, err := Parse(expr)
+	if err != nil {
+		log.Errorf("cannot parse query %q: %s", expr, err)
+		return false
+	}
+	cache.Store(expr, q)
+	return q.Match(msg)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func isKeyword(t token, keyword string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.value, keyword)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !isKeyword(t, "or") && !(t.kind == tokenOperator && t.value == "||") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !isKeyword(t, "and") && !(t.kind == tokenOperator && t.value == "&&") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	if isKeyword(t, "not") || (t.kind == tokenOperator && t.value == "!") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ) instead of %s", closing)
+		}
+		return inner, nil
+	case isKeyword(t, "exists") && p.peek().kind == tokenLParen:
+		p.next()
+		pathToken := p.next()
+		if pathToken.kind != tokenIdent {
+			return nil, fmt.Errorf("expected path instead of %s", pathToken)
+		}
+		pth, err := parsePath(pathToken.value)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ) instead of %s", closing)
+		}
+		return &existsNode{path: pth}, nil
+	case t.kind == tokenIdent:
+		pth, err := parsePath(t.value)
+		if err != nil {
+			return nil, err
+		}
+		op := p.peek()
+		if op.kind != tokenOperator || !isComparison(op.value) {
+			return &existsNode{path: pth}, nil
+		}
+		p.next()
+		lit := p.next()
+		if lit.kind != tokenIdent && lit.kind != tokenString && lit.kind != tokenNumber {
+			return nil, fmt.Errorf("expected value instead of %s", lit)
+		}
+		return newCompareNode(pth, op.value, lit)
+	default:
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "=", "!=", ">", ">=", "<", "<=", "=~", "!~":
+		return true
+	}
+	return false
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(msg proto.Message) bool {
+	return n.left.eval(msg) || n.right.eval(msg)
+}
+
+func (n *orNode) String() string {
+	return fmt.Sprintf("(%s or %s)", n.left, n.right)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(msg proto.Message) bool {
+	return n.left.eval(msg) && n.right.eval(msg)
+}
+
+func (n *andNode) String() string {
+	return fmt.Sprintf("(%s and %s)", n.left, n.right)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(msg proto.Message) bool {
+	return !n.operand.eval(msg)
+}
+
+func (n *notNode) String() string {
+	return fmt.Sprintf("not %s", n.operand)
+}
+
+type existsNode struct {
+	path path
+}
+
+func (n *existsNode) eval(msg proto.Message) bool {
+	for _, v := range n.path.resolve(msg) {
+		if !v.isZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *existsNode) String() string {
+	return fmt.Sprintf("exists(%s)", n.path)
+}
+
+type compareNode struct {
+	path    path
+	op      string
+	literal string
+	number  float64
+	numeric bool
+	re      *regexp.Regexp
+}
+
+func newCompareNode(pth path, op string, lit token) (*compareNode, error) {
+	n := &compareNode{path: pth, op: op, literal: lit.value}
+	if op == "=" {
+		n.op = "=="
+	}
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile(lit.value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile regexp %s: %w", lit, err)
+		}
+		n.re = re
+		return n, nil
+	}
+	if lit.kind != tokenString {
+		if f, err := strconv.ParseFloat(lit.value, 64); err == nil {
+			n.number = f
+			n.numeric = true
+		}
+	}
+	return n, nil
+}
+
+func (n *compareNode) eval(msg proto.Message) bool {
+	for _, v := range n.path.resolve(msg) {
+		if n.compare(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *compareNode) compare(v value) bool {
+	switch n.op {
+	case "=~":
+		return n.re.MatchString(v.String())
+	case "!~":
+		return !n.re.MatchString(v.String())
+	}
+	var cmp int
+	if f, ok := v.float(); ok && n.numeric {
+		switch {
+		case f < n.number:
+			cmp = -1
+		case f > n.number:
+			cmp = 1
+		}
+	} else if t, ok := v.time(); ok {
+		lt, err := parseTime(n.literal)
+		if err != nil {
+			return false
+		}
+		cmp = t.Compare(lt)
+	} else {
+		s := v.String()
+		if v.isEnum() && strings.EqualFold(s, n.literal) {
+			s = n.literal
+		}
+		cmp = strings.Compare(s, n.literal)
+	}
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+func (n *compareNode) String() string {
+	return fmt.Sprintf("%s %s %q", n.path, n.op, n.literal)
+}
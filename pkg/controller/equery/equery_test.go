@@ -0,0 +1,104 @@
+package equery_test
+
+import (
+	"testing"
+
+	"github.com/lf-edge/eden/pkg/controller/equery"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/metrics"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	deviceInfo := &info.ZInfoMsg{
+		Ztype: info.ZInfoTypes_ZiDevice,
+		DevId: "test",
+		InfoContent: &info.ZInfoMsg_Dinfo{Dinfo: &info.ZInfoDevice{
+			Ncpu: 4,
+			Network: []*info.ZInfoNetwork{
+				{LocalName: "eth0", Uplink: true, IPAddrs: []string{"192.168.0.10"}},
+				{LocalName: "eth1", Uplink: false},
+			},
+		}},
+		AtTimeStamp: timestamppb.New(timestamppb.Now().AsTime()),
+	}
+	uuidInfo := &info.ZInfoMsg{
+		DevId: "3f2a8d4e-9c1b-4e5f-8a7d-2b6c0e1f9a3d",
+		InfoContent: &info.ZInfoMsg_Dinfo{Dinfo: &info.ZInfoDevice{
+			Network: []*info.ZInfoNetwork{{MacAddr: "02:fd:00:00:00:01"}},
+		}},
+	}
+	metric := &metrics.ZMetricMsg{
+		MetricContent: &metrics.ZMetricMsg_Dm{Dm: &metrics.DeviceMetric{
+			Memory: &metrics.MemoryMetric{UsedMem: 600},
+		}},
+	}
+
+	testMatrix := map[string]struct {
+		expr     string
+		msg      interface{}
+		expected bool
+	}{
+		"enum":               {expr: "ztype == ZiDevice", msg: deviceInfo, expected: true},
+		"enum lowercase":     {expr: "ztype == zidevice", msg: deviceInfo, expected: true},
+		"enum other":         {expr: "ztype == ZiApp", msg: deviceInfo, expected: false},
+		"numeric":            {expr: "dinfo.ncpu > 2", msg: deviceInfo, expected: true},
+		"numeric false":      {expr: "dinfo.ncpu >= 5", msg: deviceInfo, expected: false},
+		"and":                {expr: "dinfo.ncpu > 2 and devId == test", msg: deviceInfo, expected: true},
+		"or":                 {expr: "ztype == ZiApp || devId == 'test'", msg: deviceInfo, expected: true},
+		"not":                {expr: "not ztype == ZiApp", msg: deviceInfo, expected: true},
+		"parentheses":        {expr: "!(ztype == ZiApp or dinfo.ncpu < 2)", msg: deviceInfo, expected: true},
+		"index":              {expr: "dinfo.network[1].localName == eth1", msg: deviceInfo, expected: true},
+		"index out":          {expr: "dinfo.network[2].localName == eth1", msg: deviceInfo, expected: false},
+		"implicit iterate":   {expr: "dinfo.network.uplink == false", msg: deviceInfo, expected: true},
+		"wildcard":           {expr: "dinfo.network[*].IPAddrs =~ '^192\\.168'", msg: deviceInfo, expected: true},
+		"exists":             {expr: "exists(dinfo)", msg: deviceInfo, expected: true},
+		"not exists":         {expr: "exists(ainfo)", msg: deviceInfo, expected: false},
+		"bare path":          {expr: "dinfo.network[0].uplink", msg: deviceInfo, expected: true},
+		"bare path false":    {expr: "dinfo.network[1].uplink", msg: deviceInfo, expected: false},
+		"snake case":         {expr: "at_time_stamp > '2020-01-01T00:00:00Z'", msg: deviceInfo, expected: true},
+		"metric":             {expr: "dm.memory.usedMem > 500", msg: metric, expected: true},
+		"metric not found":   {expr: "dm.memory.availMem > 500", msg: metric, expected: false},
+		"unknown field":      {expr: "unknown == 1", msg: metric, expected: false},
+		"regexp not match":   {expr: "devId !~ '^prod'", msg: deviceInfo, expected: true},
+		"single equal sign":  {expr: "devId = test", msg: deviceInfo, expected: true},
+		"uuid literal":       {expr: "devId == 3f2a8d4e-9c1b-4e5f-8a7d-2b6c0e1f9a3d", msg: uuidInfo, expected: true},
+		"uuid literal other": {expr: "devId == 3f2a8d4e-0000-4e5f-8a7d-2b6c0e1f9a3d", msg: uuidInfo, expected: false},
+		"mac literal":        {expr: "dinfo.network.macAddr == 02:fd:00:00:00:01", msg: uuidInfo, expected: true},
+	}
+	for name, tc := range testMatrix {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			q, err := equery.Parse(tc.expr)
+			assert.NoError(t, err)
+			switch msg := tc.msg.(type) {
+			case *info.ZInfoMsg:
+				assert.Equal(t, tc.expected, q.Match(msg), q.String())
+			case *metrics.ZMetricMsg:
+				assert.Equal(t, tc.expected, q.Match(msg), q.String())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		"",
+		"ztype ==",
+		"(ztype == ZiApp",
+		"ztype == 'ZiApp",
+		"devId =~ '['",
+		"exists(",
+		"ztype == ZiApp and",
+		"dinfo.network[0",
+	} {
+		_, err := equery.Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
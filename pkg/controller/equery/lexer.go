@@ -0,0 +1,123 @@
+package equery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q at position %d", t.value, t.pos)
+}
+
+// operators sorted to match the longest first
+var operators = []string{"==", "!=", ">=", "<=", "=~", "!~", "&&", "||", ">", "<", "!", "="}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == ':'
+}
+
+// tokenize splits expression into tokens
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, value: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, value: ",", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, value: sb.String(), pos: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			// literal started with digit may be a number or something like UUID or version
+			start := i
+			i++
+			for i < len(runes) && (isIdentRune(runes[i]) ||
+				(runes[i] == '+' && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			value := string(runes[start:i])
+			kind := tokenIdent
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				kind = tokenNumber
+			}
+			tokens = append(tokens, token{kind: kind, value: value, pos: start})
+		case isIdentRune(r) || r == '[':
+			// path may contain indexes in square brackets with any symbols inside
+			start := i
+			for i < len(runes) && (isIdentRune(runes[i]) || runes[i] == '[') {
+				if runes[i] == '[' {
+					end := i
+					for end < len(runes) && runes[end] != ']' {
+						end++
+					}
+					if end >= len(runes) {
+						return nil, fmt.Errorf("unterminated index at position %d", i)
+					}
+					i = end
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: string(runes[start:i]), pos: start})
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{kind: tokenOperator, value: op, pos: i})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected symbol %q at position %d", r, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
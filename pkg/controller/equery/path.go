@@ -0,0 +1,262 @@
+package equery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// segment is one element of path with optional index
+type segment struct {
+	name     string
+	index    string
+	hasIndex bool
+}
+
+// path to the field inside of proto message
+type path []segment
+
+func parsePath(s string) (path, error) {
+	var result path
+	for len(s) > 0 {
+		var seg segment
+		end := strings.IndexAny(s, ".[")
+		if end == -1 {
+			end = len(s)
+		}
+		seg.name = s[:end]
+		s = s[end:]
+		if strings.HasPrefix(s, "[") {
+			closing := strings.Index(s, "]")
+			if closing == -1 {
+				return nil, fmt.Errorf("unterminated index in %s", s)
+			}
+			seg.index = s[1:closing]
+			seg.hasIndex = seg.index != "" && seg.index != "*"
+			s = s[closing+1:]
+		}
+		s = strings.TrimPrefix(s, ".")
+		if seg.name == "" {
+			return nil, fmt.Errorf("empty field name in path")
+		}
+		result = append(result, seg)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return result, nil
+}
+
+func (p path) String() string {
+	var parts []string
+	for _, seg := range p {
+		if seg.hasIndex {
+			parts = append(parts, fmt.Sprintf("%s[%s]", seg.name, seg.index))
+		} else {
+			parts = append(parts, seg.name)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// value is resolved value of field
+type value struct {
+	v  protoreflect.Value
+	fd protoreflect.FieldDescriptor
+}
+
+func normalizeName(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+}
+
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	name = normalizeName(name)
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if normalizeName(string(fd.Name())) == name || normalizeName(fd.JSONName()) == name {
+			return fd
+		}
+	}
+	return nil
+}
+
+// resolve returns all values found by path inside of msg
+func (p path) resolve(msg proto.Message) []value {
+	if msg == nil {
+		return nil
+	}
+	return resolveMessage(msg.ProtoReflect(), p)
+}
+
+func resolveMessage(m protoreflect.Message, p path) []value {
+	if len(p) == 0 || !m.IsValid() {
+		return nil
+	}
+	seg := p[0]
+	fd := findField(m.Descriptor(), seg.name)
+	if fd == nil {
+		return nil
+	}
+	if (fd.Kind() == protoreflect.MessageKind || fd.ContainingOneof() != nil) && !fd.IsList() && !fd.IsMap() && !m.Has(fd) {
+		return nil
+	}
+	var elements []protoreflect.Value
+	fieldValue := m.Get(fd)
+	switch {
+	case fd.IsList():
+		list := fieldValue.List()
+		if seg.hasIndex {
+			index, err := strconv.Atoi(seg.index)
+			if err != nil || index < 0 || index >= list.Len() {
+				return nil
+			}
+			elements = append(elements, list.Get(index))
+		} else {
+			for i := 0; i < list.Len(); i++ {
+				elements = append(elements, list.Get(i))
+			}
+		}
+	case fd.IsMap():
+		mp := fieldValue.Map()
+		if seg.hasIndex {
+			key, ok := mapKey(fd.MapKey(), seg.index)
+			if !ok || !mp.Has(key) {
+				return nil
+			}
+			elements = append(elements, mp.Get(key))
+		} else {
+			mp.Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				elements = append(elements, v)
+				return true
+			})
+		}
+		fd = fd.MapValue()
+	default:
+		if seg.hasIndex {
+			return nil
+		}
+		elements = append(elements, fieldValue)
+	}
+	if len(p) == 1 {
+		result := make([]value, 0, len(elements))
+		for _, el := range elements {
+			result = append(result, value{v: el, fd: fd})
+		}
+		return result
+	}
+	if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+		return nil
+	}
+	var result []value
+	for _, el := range elements {
+		result = append(result, resolveMessage(el.Message(), p[1:])...)
+	}
+	return result
+}
+
+func mapKey(fd protoreflect.FieldDescriptor, s string) (protoreflect.MapKey, bool) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s).MapKey(), true
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(b).MapKey(), err == nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)).MapKey(), err == nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(i).MapKey(), err == nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(i)).MapKey(), err == nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(i).MapKey(), err == nil
+	}
+	return protoreflect.MapKey{}, false
+}
+
+func (v value) isEnum() bool {
+	return v.fd.Kind() == protoreflect.EnumKind
+}
+
+func (v value) timestamp() (*timestamppb.Timestamp, bool) {
+	if v.fd.Kind() != protoreflect.MessageKind {
+		return nil, false
+	}
+	ts, ok := v.v.Message().Interface().(*timestamppb.Timestamp)
+	return ts, ok
+}
+
+// float returns numeric representation of value
+func (v value) float() (float64, bool) {
+	switch v.fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return float64(v.v.Int()), true
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return float64(v.v.Uint()), true
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.v.Float(), true
+	case protoreflect.EnumKind:
+		return float64(v.v.Enum()), true
+	case protoreflect.BoolKind:
+		if v.v.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	if ts, ok := v.timestamp(); ok {
+		return float64(ts.AsTime().UnixNano()) / float64(time.Second), true
+	}
+	return 0, false
+}
+
+func (v value) time() (time.Time, bool) {
+	if ts, ok := v.timestamp(); ok {
+		return ts.AsTime(), true
+	}
+	return time.Time{}, false
+}
+
+func (v value) String() string {
+	switch v.fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := v.fd.Enum().Values().ByNumber(v.v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.v.Enum()))
+	case protoreflect.BytesKind:
+		return string(v.v.Bytes())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if t, ok := v.time(); ok {
+			return t.Format(time.RFC3339Nano)
+		}
+		return fmt.Sprint(v.v.Message().Interface())
+	}
+	return v.v.String()
+}
+
+func (v value) isZero() bool {
+	switch v.fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return !v.v.Message().IsValid()
+	case protoreflect.BytesKind:
+		return len(v.v.Bytes()) == 0
+	case protoreflect.StringKind:
+		return v.v.String() == ""
+	}
+	f, ok := v.float()
+	return ok && f == 0
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
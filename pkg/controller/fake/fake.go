@@ -0,0 +1,482 @@
+// Package fake provides in-memory implementation of controller.Controller
+// which does not require Adam, Redis or Docker and can be used in hermetic tests.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/eapps"
+	"github.com/lf-edge/eden/pkg/controller/eflowlog"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/emetric"
+	"github.com/lf-edge/eden/pkg/controller/erequest"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/config"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+// deviceRecord stores everything controller knows about one device
+type deviceRecord struct {
+	cert        types.DeviceCert
+	onboardUUID uuid.UUID
+	config      []byte
+	certs       string
+	options     *types.DeviceOptions
+}
+
+// Ctx stores state of in-memory controller
+type Ctx struct {
+	dir           string
+	mu            sync.Mutex
+	devices       map[uuid.UUID]*deviceRecord
+	onboards      map[uuid.UUID][]byte
+	globalOptions *types.GlobalOptions
+	store         *objectStore
+	loader        loaders.Loader
+}
+
+// New returns empty in-memory controller
+func New() *Ctx {
+	return &Ctx{
+		devices:       map[uuid.UUID]*deviceRecord{},
+		onboards:      map[uuid.UUID][]byte{},
+		globalOptions: &types.GlobalOptions{},
+		store:         newObjectStore(),
+	}
+}
+
+// init allows to use zero value of Ctx
+func (ctx *Ctx) init() {
+	if ctx.devices == nil {
+		ctx.devices = map[uuid.UUID]*deviceRecord{}
+	}
+	if ctx.onboards == nil {
+		ctx.onboards = map[uuid.UUID][]byte{}
+	}
+	if ctx.globalOptions == nil {
+		ctx.globalOptions = &types.GlobalOptions{}
+	}
+	if ctx.store == nil {
+		ctx.store = newObjectStore()
+	}
+}
+
+// getDevice returns record for devUUID, must be called with locked mutex
+func (ctx *Ctx) getDevice(devUUID uuid.UUID) (*deviceRecord, error) {
+	ctx.init()
+	dev, ok := ctx.devices[devUUID]
+	if !ok {
+		return nil, fmt.Errorf("device %s not found", devUUID)
+	}
+	return dev, nil
+}
+
+// getLoader returns loader set with SetLoader or loader which reads objects pushed into controller
+func (ctx *Ctx) getLoader() loaders.Loader {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.init()
+	if ctx.loader != nil {
+		return ctx.loader.Clone()
+	}
+	return newLoader(ctx.store)
+}
+
+// SetLoader sets loader to use instead of objects pushed into controller
+func (ctx *Ctx) SetLoader(loader loaders.Loader) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.loader = loader
+}
+
+// AddDevice registers device with provided devUUID and onboardUUID without onboarding certificate
+// and creates initial config for it, the same way as controller does on EVE registration
+func (ctx *Ctx) AddDevice(devUUID, onboardUUID uuid.UUID, serial string) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.init()
+	if _, ok := ctx.devices[devUUID]; ok {
+		return fmt.Errorf("device %s already exists", devUUID)
+	}
+	devConfig, err := proto.Marshal(&config.EdgeDevConfig{
+		Id: &config.UUIDandVersion{Uuid: devUUID.String(), Version: "1"},
+	})
+	if err != nil {
+		return err
+	}
+	ctx.devices[devUUID] = &deviceRecord{
+		cert:        types.DeviceCert{Onboard: ctx.onboards[onboardUUID], Serial: serial},
+		onboardUUID: onboardUUID,
+		config:      devConfig,
+		options:     &types.DeviceOptions{},
+	}
+	return nil
+}
+
+// SetCerts sets attest certs of device returned by CertsGet
+func (ctx *Ctx) SetCerts(devUUID uuid.UUID, certs *types.Zcerts) error {
+	b, err := json.Marshal(certs)
+	if err != nil {
+		return err
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	dev, err := ctx.getDevice(devUUID)
+	if err != nil {
+		return err
+	}
+	dev.certs = string(b)
+	return nil
+}
+
+// InitWithVars use variables from viper for init controller
+func (ctx *Ctx) InitWithVars(vars *utils.ConfigVars) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.init()
+	ctx.dir = vars.AdamDir
+	return nil
+}
+
+// GetDir return dir
+func (ctx *Ctx) GetDir() (dir string) {
+	return ctx.dir
+}
+
+// Register device in controller
+// In-memory controller onboards device immediately, no EVE required
+func (ctx *Ctx) Register(device *device.Ctx) error {
+	b, err := os.ReadFile(device.GetOnboardKey())
+	if err != nil {
+		return fmt.Errorf("error reading cert file %s: %w", device.GetOnboardKey(), err)
+	}
+	cert, err := utils.ParseFirstCertFromBlock(b)
+	if err != nil {
+		return err
+	}
+	onboardUUID, err := uuid.FromString(cert.Subject.CommonName)
+	if err != nil {
+		return err
+	}
+	ctx.mu.Lock()
+	ctx.init()
+	ctx.onboards[onboardUUID] = b
+	for _, dev := range ctx.devices {
+		if uuid.Equal(dev.onboardUUID, onboardUUID) {
+			ctx.mu.Unlock()
+			return nil
+		}
+	}
+	ctx.mu.Unlock()
+	devUUID := device.GetID()
+	if devUUID == uuid.Nil {
+		if devUUID, err = uuid.NewV4(); err != nil {
+			return err
+		}
+	}
+	return ctx.AddDevice(devUUID, onboardUUID, device.GetSerial())
+}
+
+// DeviceList return device list
+func (ctx *Ctx) DeviceList(filter types.DeviceStateFilter) (out []string, err error) {
+	if filter != types.RegisteredDeviceFilter && filter != types.AllDevicesFilter {
+		return []string{}, nil
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.init()
+	out = []string{}
+	for devUUID := range ctx.devices {
+		out = append(out, devUUID.String())
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// ConfigSet set config for devID
+func (ctx *Ctx) ConfigSet(devUUID uuid.UUID, devConfig []byte) (err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	dev, err := ctx.getDevice(devUUID)
+	if err != nil {
+		return err
+	}
+	dev.config = append([]byte{}, devConfig...)
+	return nil
+}
+
+// ConfigGet get config for devID
+func (ctx *Ctx) ConfigGet(devUUID uuid.UUID) (out string, err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	dev, err := ctx.getDevice(devUUID)
+	if err != nil {
+		return "", err
+	}
+	return string(dev.config), nil
+}
+
+// CertsGet get attest certs for devID
+func (ctx *Ctx) CertsGet(devUUID uuid.UUID) (out string, err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	dev, err := ctx.getDevice(devUUID)
+	if err != nil {
+		return "", err
+	}
+	if dev.certs == "" {
+		return "{}", nil
+	}
+	return dev.certs, nil
+}
+
+// RequestLastCallback check request by pattern from existence files with callback
+func (ctx *Ctx) RequestLastCallback(devUUID uuid.UUID, q map[string]string, handler erequest.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return erequest.RequestLast(loader, q, handler)
+}
+
+// LogAppsChecker check app logs by pattern from existence files with LogLast and use LogWatchWithTimeout with timeout for observe new files
+func (ctx *Ctx) LogAppsChecker(devUUID uuid.UUID, appUUID uuid.UUID, q map[string]string, handler eapps.HandlerFunc, mode eapps.LogCheckerMode, timeout time.Duration) (err error) {
+	return eapps.LogChecker(ctx.getLoader(), devUUID, appUUID, q, handler, mode, timeout)
+}
+
+// LogAppsLastCallback check app logs by pattern from existence files with callback
+func (ctx *Ctx) LogAppsLastCallback(devUUID uuid.UUID, appUUID uuid.UUID, q map[string]string, handler eapps.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	loader.SetAppUUID(appUUID)
+	return eapps.LogLast(loader, q, handler)
+}
+
+// LogAppsRange process app logs inside of the time range and after the cursor from rng and returns cursor to resume from
+func (ctx *Ctx) LogAppsRange(devUUID uuid.UUID, appUUID uuid.UUID, q map[string]string, handler eapps.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return eapps.LogRange(ctx.getLoader(), devUUID, appUUID, q, handler, rng)
+}
+
+// LogChecker check logs by pattern from existence files with LogLast and use LogWatchWithTimeout with timeout for observe new files
+func (ctx *Ctx) LogChecker(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, mode elog.LogCheckerMode, timeout time.Duration) (err error) {
+	return elog.LogChecker(ctx.getLoader(), devUUID, q, handler, mode, timeout)
+}
+
+// LogLastCallback check logs by pattern from existence files with callback
+func (ctx *Ctx) LogLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return elog.LogLast(loader, q, handler)
+}
+
+// LogRange process logs inside of the time range and after the cursor from rng and returns cursor to resume from
+func (ctx *Ctx) LogRange(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return elog.LogRange(ctx.getLoader(), devUUID, q, handler, rng)
+}
+
+// FlowLogChecker check FlowLogs by pattern from existence files with FlowLogLast and use FlowLogWatchWithTimeout with timeout for observe new files
+func (ctx *Ctx) FlowLogChecker(devUUID uuid.UUID, q map[string]string, handler eflowlog.HandlerFunc, mode eflowlog.FlowLogCheckerMode, timeout time.Duration) (err error) {
+	return eflowlog.FlowLogChecker(ctx.getLoader(), devUUID, q, handler, mode, timeout)
+}
+
+// FlowLogLastCallback check FlowLogs by pattern from existence files with callback
+func (ctx *Ctx) FlowLogLastCallback(devUUID uuid.UUID, q map[string]string, handler eflowlog.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return eflowlog.FlowLogLast(loader, q, handler)
+}
+
+// FlowLogRange process FlowLogs inside of the time range and after the cursor from rng and returns cursor to resume from
+func (ctx *Ctx) FlowLogRange(devUUID uuid.UUID, q map[string]string, handler eflowlog.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return eflowlog.FlowLogRange(ctx.getLoader(), devUUID, q, handler, rng)
+}
+
+// InfoChecker checks the information in the regular expression pattern 'query' and processes the info.ZInfoMsg found by the function 'handler' from existing files (mode=einfo.InfoExist), new files (mode=einfo.InfoNew) or any of them (mode=einfo.InfoAny) with timeout.
+func (ctx *Ctx) InfoChecker(devUUID uuid.UUID, q map[string]string, handler einfo.HandlerFunc, mode einfo.InfoCheckerMode, timeout time.Duration) (err error) {
+	return einfo.InfoChecker(ctx.getLoader(), devUUID, q, handler, mode, timeout)
+}
+
+// InfoLastCallback check info by pattern from existence files with callback
+func (ctx *Ctx) InfoLastCallback(devUUID uuid.UUID, q map[string]string, handler einfo.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return einfo.InfoLast(loader, q, einfo.ZInfoFind, handler)
+}
+
+// InfoRange process info inside of the time range and after the cursor from rng and returns cursor to resume from
+func (ctx *Ctx) InfoRange(devUUID uuid.UUID, q map[string]string, handler einfo.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return einfo.InfoRange(ctx.getLoader(), devUUID, q, handler, rng)
+}
+
+// MetricChecker check metrics by pattern from existence files with LogLast and use LogWatchWithTimeout with timeout for observe new files
+func (ctx *Ctx) MetricChecker(devUUID uuid.UUID, q map[string]string, handler emetric.HandlerFunc, mode emetric.MetricCheckerMode, timeout time.Duration) (err error) {
+	return emetric.MetricChecker(ctx.getLoader(), devUUID, q, handler, mode, timeout)
+}
+
+// MetricLastCallback check metrics by pattern from existence files with callback
+func (ctx *Ctx) MetricLastCallback(devUUID uuid.UUID, q map[string]string, handler emetric.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return emetric.MetricLast(loader, q, handler)
+}
+
+// MetricRange process metrics inside of the time range and after the cursor from rng and returns cursor to resume from
+func (ctx *Ctx) MetricRange(devUUID uuid.UUID, q map[string]string, handler emetric.HandlerFunc, rng types.LoaderRange) (cursor string, err error) {
+	return emetric.MetricRange(ctx.getLoader(), devUUID, q, handler, rng)
+}
+
+// OnboardRemove remove onboard by onboardUUID
+func (ctx *Ctx) OnboardRemove(onboardUUID string) (err error) {
+	id, err := uuid.FromString(onboardUUID)
+	if err != nil {
+		return err
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.init()
+	if _, ok := ctx.onboards[id]; !ok {
+		return fmt.Errorf("onboard %s not found", onboardUUID)
+	}
+	delete(ctx.onboards, id)
+	return nil
+}
+
+// DeviceRemove remove device by devUUID
+func (ctx *Ctx) DeviceRemove(devUUID uuid.UUID) (err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if _, err = ctx.getDevice(devUUID); err != nil {
+		return err
+	}
+	delete(ctx.devices, devUUID)
+	ctx.store.removeDevice(devUUID)
+	return nil
+}
+
+// DeviceGetOnboard get device onboardUUID for devUUID
+func (ctx *Ctx) DeviceGetOnboard(devUUID uuid.UUID) (onboardUUID uuid.UUID, err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	dev, err := ctx.getDevice(devUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return dev.onboardUUID, nil
+}
+
+// DeviceGetByOnboard try to get device by onboard eveCert
+func (ctx *Ctx) DeviceGetByOnboard(eveCert string) (devUUID uuid.UUID, err error) {
+	b, err := os.ReadFile(eveCert)
+	if err != nil {
+		log.Printf("error reading cert file %s: %v", eveCert, err)
+		return uuid.Nil, err
+	}
+	cert, err := utils.ParseFirstCertFromBlock(b)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return ctx.DeviceGetByOnboardUUID(cert.Subject.CommonName)
+}
+
+// DeviceGetByOnboardUUID try to get device by onboard uuid
+func (ctx *Ctx) DeviceGetByOnboardUUID(onboardUUID string) (devUUID uuid.UUID, err error) {
+	id, err := uuid.FromString(onboardUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.init()
+	for devUUID, dev := range ctx.devices {
+		if uuid.Equal(dev.onboardUUID, id) {
+			return devUUID, nil
+		}
+	}
+	return uuid.Nil, fmt.Errorf("no device found")
+}
+
+// GetDeviceCert gets deviceCert contains certificates and serial
+func (ctx *Ctx) GetDeviceCert(device *device.Ctx) (*types.DeviceCert, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	dev, err := ctx.getDevice(device.GetID())
+	if err != nil {
+		return nil, err
+	}
+	deviceCert := dev.cert
+	return &deviceCert, nil
+}
+
+// UploadDeviceCert registers device with provided deviceCert
+func (ctx *Ctx) UploadDeviceCert(deviceCert types.DeviceCert) error {
+	onboardUUID := uuid.Nil
+	if len(deviceCert.Onboard) > 0 {
+		cert, err := utils.ParseFirstCertFromBlock(deviceCert.Onboard)
+		if err != nil {
+			return err
+		}
+		if onboardUUID, err = uuid.FromString(cert.Subject.CommonName); err != nil {
+			return err
+		}
+	}
+	devUUID, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	if err := ctx.AddDevice(devUUID, onboardUUID, deviceCert.Serial); err != nil {
+		return err
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.devices[devUUID].cert = deviceCert
+	return nil
+}
+
+// SetDeviceOptions sets options for provided devUUID
+func (ctx *Ctx) SetDeviceOptions(devUUID uuid.UUID, options *types.DeviceOptions) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	dev, err := ctx.getDevice(devUUID)
+	if err != nil {
+		return err
+	}
+	dev.options = options
+	return nil
+}
+
+// GetDeviceOptions returns DeviceOptions for provided devUUID
+func (ctx *Ctx) GetDeviceOptions(devUUID uuid.UUID) (*types.DeviceOptions, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	dev, err := ctx.getDevice(devUUID)
+	if err != nil {
+		return nil, err
+	}
+	return dev.options, nil
+}
+
+// SetGlobalOptions sets global options for controller
+func (ctx *Ctx) SetGlobalOptions(options *types.GlobalOptions) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.init()
+	ctx.globalOptions = options
+	return nil
+}
+
+// GetGlobalOptions returns global options from controller
+func (ctx *Ctx) GetGlobalOptions() (*types.GlobalOptions, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.init()
+	return ctx.globalOptions, nil
+}
@@ -0,0 +1,390 @@
+package fake_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/archive"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/equery"
+	"github.com/lf-edge/eden/pkg/controller/fake"
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/logs"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ controller.Controller = (*fake.Ctx)(nil)
+
+func TestCloudWithFakeController(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	devUUID := uuid.Must(uuid.NewV4())
+	onboardUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(devUUID, onboardUUID, "serial"))
+
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{})
+	cloud.GetAllNodes()
+	dev, err := cloud.GetDeviceUUID(devUUID)
+	assert.NoError(t, err)
+	_, err = cloud.GetConfigBytes(dev, false)
+	assert.NoError(t, err)
+
+	found, err := ctrl.DeviceGetByOnboardUUID(onboardUUID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, devUUID, found)
+
+	list, err := ctrl.DeviceList(types.RegisteredDeviceFilter)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{devUUID.String()}, list)
+}
+
+func TestCloudMultipleDevices(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	firstUUID, firstOnboard := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	secondUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(firstUUID, firstOnboard, "first"))
+	assert.NoError(t, ctrl.AddDevice(secondUUID, uuid.Must(uuid.NewV4()), "second"))
+
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{EveUUID: firstOnboard.String(), EveName: "eve-first"})
+	cloud.GetAllNodes()
+	assert.Len(t, cloud.ListDevices(), 2)
+
+	dev, err := cloud.GetDevice("eve-first")
+	assert.NoError(t, err)
+	assert.Equal(t, firstUUID, dev.GetID())
+
+	dev, err = cloud.GetDeviceCurrent()
+	assert.NoError(t, err)
+	assert.Equal(t, firstUUID, dev.GetID())
+
+	cloud.SetDeviceCurrent(secondUUID.String())
+	dev, err = cloud.GetDeviceCurrent()
+	assert.NoError(t, err)
+	assert.Equal(t, secondUUID, dev.GetID())
+
+	_, err = cloud.GetDevice("unknown")
+	assert.Error(t, err)
+}
+
+func TestSelectedDeviceConfigAndState(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	firstUUID, firstOnboard := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	secondUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(firstUUID, firstOnboard, "first"))
+	assert.NoError(t, ctrl.AddDevice(secondUUID, uuid.Must(uuid.NewV4()), "second"))
+	for _, devUUID := range []uuid.UUID{firstUUID, secondUUID} {
+		assert.NoError(t, ctrl.PushInfo(devUUID, &info.ZInfoMsg{
+			Ztype: info.ZInfoTypes_ZiDevice,
+			DevId: devUUID.String(),
+		}))
+	}
+
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{EveUUID: firstOnboard.String(), EveName: "eve-first"})
+	cloud.GetAllNodes()
+
+	// apply config to the selected device the same way as commands do
+	applyConfig := func(selector, key, value string) uuid.UUID {
+		cloud.SetDeviceCurrent(selector)
+		dev, err := cloud.GetDeviceCurrent()
+		assert.NoError(t, err)
+		dev.SetConfigItem(key, value)
+		devConfig, err := cloud.GetConfigBytes(dev, false)
+		assert.NoError(t, err)
+		assert.NoError(t, ctrl.ConfigSet(dev.GetID(), devConfig))
+		return dev.GetID()
+	}
+	assert.Equal(t, secondUUID, applyConfig(secondUUID.String(), "timer.config.interval", "10"))
+	assert.Equal(t, firstUUID, applyConfig("eve-first", "timer.config.interval", "20"))
+
+	getInterval := func(devUUID uuid.UUID) string {
+		out, err := ctrl.ConfigGet(devUUID)
+		assert.NoError(t, err)
+		var devConfig config.EdgeDevConfig
+		assert.NoError(t, proto.Unmarshal([]byte(out), &devConfig))
+		for _, item := range devConfig.GetConfigItems() {
+			if item.GetKey() == "timer.config.interval" {
+				return item.GetValue()
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, "20", getInterval(firstUUID))
+	assert.Equal(t, "10", getInterval(secondUUID))
+
+	// state is read for the selected device
+	cloud.SetDeviceCurrent(secondUUID.String())
+	dev, err := cloud.GetDeviceCurrent()
+	assert.NoError(t, err)
+	var received *info.ZInfoMsg
+	assert.NoError(t, cloud.InfoLastCallback(dev.GetID(), nil, func(im *info.ZInfoMsg) bool {
+		received = im
+		return true
+	}))
+	assert.Equal(t, secondUUID.String(), received.GetDevId())
+
+	// empty selector returns to the device from config
+	cloud.SetDeviceCurrent("")
+	dev, err = cloud.GetDeviceCurrent()
+	assert.NoError(t, err)
+	assert.Equal(t, firstUUID, dev.GetID())
+}
+
+func TestSelectedDeviceVars(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(defaults.DefaultConfigEnv, "")
+
+	ctrl := fake.New()
+	firstUUID, firstOnboard := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	secondUUID, secondOnboard := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(firstUUID, firstOnboard, "first"))
+	assert.NoError(t, ctrl.AddDevice(secondUUID, secondOnboard, "second"))
+
+	contexts := filepath.Join(home, defaults.DefaultEdenHomeDir, defaults.DefaultContextDirectory)
+	assert.NoError(t, os.MkdirAll(contexts, 0755))
+	for name, el := range map[string]struct {
+		onboard uuid.UUID
+		arch    string
+	}{
+		defaults.DefaultContext: {onboard: firstOnboard, arch: "amd64"},
+		"second":                {onboard: secondOnboard, arch: "arm64"},
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(contexts, name+".yml"),
+			[]byte(fmt.Sprintf("eve:\n  uuid: %s\n  arch: %s\n", el.onboard, el.arch)), 0644))
+	}
+
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{EveUUID: firstOnboard.String(), EveName: "eve-first", ZArch: "amd64"})
+	cloud.GetAllNodes()
+	assert.Equal(t, "amd64", cloud.GetVars().ZArch)
+
+	// device from another context is named after the context and uses its config
+	cloud.SetDeviceCurrent("second")
+	dev, err := cloud.GetDeviceCurrent()
+	if assert.NoError(t, err) {
+		assert.Equal(t, secondUUID, dev.GetID())
+	}
+	assert.Equal(t, "arm64", cloud.GetVars().ZArch)
+	assert.Equal(t, secondOnboard.String(), cloud.GetVars().EveUUID)
+
+	// config of controller is used for device defined in it
+	cloud.SetDeviceCurrent(firstUUID.String())
+	assert.Equal(t, "eve-first", cloud.GetVars().EveName)
+	cloud.SetDeviceCurrent("")
+	assert.Equal(t, "eve-first", cloud.GetVars().EveName)
+}
+
+func TestInfoChecker(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	devUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(devUUID, uuid.Must(uuid.NewV4()), ""))
+
+	assert.NoError(t, ctrl.PushInfo(devUUID, &info.ZInfoMsg{
+		Ztype: info.ZInfoTypes_ZiDevice,
+		DevId: devUUID.String(),
+	}))
+
+	var existing []*info.ZInfoMsg
+	err := ctrl.InfoChecker(devUUID, map[string]string{"ztype": "ZiDevice"},
+		func(im *info.ZInfoMsg) bool {
+			existing = append(existing, im)
+			return true
+		}, einfo.InfoExist, 0)
+	assert.NoError(t, err)
+	assert.Len(t, existing, 1)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, ctrl.PushInfo(devUUID, &info.ZInfoMsg{
+			Ztype: info.ZInfoTypes_ZiApp,
+			DevId: devUUID.String(),
+		}))
+	}()
+	var received *info.ZInfoMsg
+	err = ctrl.InfoChecker(devUUID, map[string]string{"ztype": "ZiApp"},
+		func(im *info.ZInfoMsg) bool {
+			received = im
+			return true
+		}, einfo.InfoNew, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, info.ZInfoTypes_ZiApp, received.GetZtype())
+
+	err = ctrl.InfoChecker(devUUID, map[string]string{"ztype": "ZiVolume"},
+		func(im *info.ZInfoMsg) bool {
+			return true
+		}, einfo.InfoNew, 1)
+	assert.Error(t, err)
+}
+
+func TestLogTail(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	devUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(devUUID, uuid.Must(uuid.NewV4()), ""))
+
+	for _, content := range []string{"first", "second", "third"} {
+		assert.NoError(t, ctrl.PushLog(devUUID, &logs.LogEntry{Source: "test", Content: content}))
+	}
+	var contents []string
+	err := ctrl.LogChecker(devUUID, map[string]string{"source": "test"},
+		func(le *elog.FullLogEntry) bool {
+			contents = append(contents, le.Content)
+			return false
+		}, elog.LogTail(2), 0)
+	assert.NoError(t, err)
+	assert.Len(t, contents, 2)
+}
+
+func TestInfoRangeCursor(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	devUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(devUUID, uuid.Must(uuid.NewV4()), ""))
+
+	since := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ctrl.PushInfo(devUUID, &info.ZInfoMsg{Ztype: info.ZInfoTypes_ZiDevice}))
+	}
+
+	count := 0
+	handler := func(im *info.ZInfoMsg) bool {
+		count++
+		return count == 2
+	}
+	cursor, err := ctrl.InfoRange(devUUID, map[string]string{}, handler, types.LoaderRange{Since: since})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	cursor, err = ctrl.InfoRange(devUUID, map[string]string{}, handler, types.LoaderRange{Cursor: cursor})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, "3", cursor)
+
+	_, err = ctrl.InfoRange(devUUID, map[string]string{}, handler, types.LoaderRange{Until: since})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestInfoQuery(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	devUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(devUUID, uuid.Must(uuid.NewV4()), ""))
+
+	for _, ncpu := range []uint32{1, 4} {
+		assert.NoError(t, ctrl.PushInfo(devUUID, &info.ZInfoMsg{
+			Ztype:       info.ZInfoTypes_ZiDevice,
+			DevId:       devUUID.String(),
+			InfoContent: &info.ZInfoMsg_Dinfo{Dinfo: &info.ZInfoDevice{Ncpu: ncpu}},
+		}))
+	}
+
+	var found []*info.ZInfoMsg
+	err := ctrl.InfoChecker(devUUID, map[string]string{
+		"ztype":         "ZiDevice",
+		equery.QueryKey: "dinfo.ncpu > 2 or exists(ainfo)",
+	}, func(im *info.ZInfoMsg) bool {
+		found = append(found, im)
+		return false
+	}, einfo.InfoExist, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, uint32(4), found[0].GetDinfo().GetNcpu())
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	devUUID := uuid.Must(uuid.NewV4())
+	path := filepath.Join(t.TempDir(), "telemetry.gz")
+	w, err := archive.Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	start := time.Now()
+	for i, ztype := range []info.ZInfoTypes{info.ZInfoTypes_ZiDevice, info.ZInfoTypes_ZiApp} {
+		data, err := proto.Marshal(&info.ZInfoMsg{Ztype: ztype, DevId: devUUID.String()})
+		assert.NoError(t, err)
+		assert.NoError(t, w.Write(archive.Record{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Type:   types.InfoType,
+			Device: devUUID,
+			Data:   data,
+		}))
+	}
+	assert.NoError(t, w.Close())
+
+	ctrl, err := fake.NewReplay(path, 600)
+	if !assert.NoError(t, err) {
+		return
+	}
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{})
+	cloud.GetAllNodes()
+	_, err = cloud.GetDevice(devUUID.String())
+	assert.NoError(t, err)
+
+	var received *info.ZInfoMsg
+	err = cloud.InfoChecker(devUUID, map[string]string{"ztype": "ZiApp"},
+		func(im *info.ZInfoMsg) bool {
+			received = im
+			return true
+		}, einfo.InfoNew, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, info.ZInfoTypes_ZiApp, received.GetZtype())
+}
+
+func TestConfigHistory(t *testing.T) {
+	t.Parallel()
+
+	ctrl := fake.New()
+	devUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(devUUID, uuid.Must(uuid.NewV4()), ""))
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{AdamDir: t.TempDir()})
+
+	for _, version := range []string{"2", "10"} {
+		devConfig, err := proto.Marshal(&config.EdgeDevConfig{
+			Id: &config.UUIDandVersion{Uuid: devUUID.String(), Version: version},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, cloud.ConfigSet(devUUID, devConfig))
+	}
+
+	history, err := cloud.ConfigHistory(devUUID)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, "2", history[0].Version)
+		assert.Equal(t, "10", history[1].Version)
+	}
+	devConfig, err := cloud.ConfigHistoryGet(devUUID, "10")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", devConfig.GetId().GetVersion())
+	_, err = cloud.ConfigHistoryGet(devUUID, "3")
+	assert.Error(t, err)
+}
@@ -0,0 +1,208 @@
+package fake
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eden/pkg/controller/types"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// objectKey identifies sequence of objects of one type
+type objectKey struct {
+	devUUID uuid.UUID
+	appUUID uuid.UUID
+	objType types.LoaderObjectType
+}
+
+// storedObject is an object with time of receiving by controller
+type storedObject struct {
+	data     []byte
+	received time.Time
+}
+
+// objectStore keeps objects pushed into controller in order of arrival
+type objectStore struct {
+	mu      sync.Mutex
+	objects map[objectKey][]storedObject
+	// updated is closed and replaced on every push to wake up stream readers
+	updated chan struct{}
+}
+
+func newObjectStore() *objectStore {
+	return &objectStore{
+		objects: map[objectKey][]storedObject{},
+		updated: make(chan struct{}),
+	}
+}
+
+func (store *objectStore) push(key objectKey, data []byte) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.objects[key] = append(store.objects[key], storedObject{data: data, received: time.Now()})
+	close(store.updated)
+	store.updated = make(chan struct{})
+}
+
+// get returns objects starting from index 'from' and channel to wait for the next ones
+func (store *objectStore) get(key objectKey, from int) ([]storedObject, <-chan struct{}) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	objects := store.objects[key]
+	if from >= len(objects) {
+		return nil, store.updated
+	}
+	return objects[from:], store.updated
+}
+
+func (store *objectStore) count(key objectKey) int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return len(store.objects[key])
+}
+
+func (store *objectStore) removeDevice(devUUID uuid.UUID) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for key := range store.objects {
+		if uuid.Equal(key.devUUID, devUUID) {
+			delete(store.objects, key)
+		}
+	}
+}
+
+// Loader implements loaders.Loader on top of objects stored inside in-memory controller
+type Loader struct {
+	store   *objectStore
+	devUUID uuid.UUID
+	appUUID uuid.UUID
+	cache   cachers.CacheProcessor
+	rng     types.LoaderRange
+	cursor  string
+}
+
+func newLoader(store *objectStore) *Loader {
+	log.Debugf("fake Loader init")
+	return &Loader{store: store}
+}
+
+// SetRemoteCache add cache layer
+func (loader *Loader) SetRemoteCache(cache cachers.CacheProcessor) {
+	loader.cache = cache
+}
+
+// Clone create copy
+func (loader *Loader) Clone() loaders.Loader {
+	return &Loader{
+		store:   loader.store,
+		devUUID: loader.devUUID,
+		appUUID: loader.appUUID,
+		cache:   loader.cache,
+		rng:     loader.rng,
+		cursor:  loader.cursor,
+	}
+}
+
+// SetRange set time range and cursor to process existing objects
+func (loader *Loader) SetRange(rng types.LoaderRange) {
+	loader.rng = rng
+	loader.cursor = rng.Cursor
+}
+
+// GetCursor returns offset of the next object after the last processed one
+func (loader *Loader) GetCursor() string {
+	return loader.cursor
+}
+
+// SetUUID set device UUID
+func (loader *Loader) SetUUID(devUUID uuid.UUID) {
+	loader.devUUID = devUUID
+}
+
+// SetAppUUID set app UUID
+func (loader *Loader) SetAppUUID(appUUID uuid.UUID) {
+	loader.appUUID = appUUID
+}
+
+func (loader *Loader) getKey(typeToProcess types.LoaderObjectType) objectKey {
+	key := objectKey{devUUID: loader.devUUID, objType: typeToProcess}
+	if typeToProcess == types.AppsType {
+		key.appUUID = loader.appUUID
+	}
+	return key
+}
+
+// processData sends data to cache and process function
+func (loader *Loader) processData(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType, data []byte) (bool, error) {
+	if loader.cache != nil {
+		if err := loader.cache.CheckAndSave(loader.devUUID, typeToProcess, data); err != nil {
+			log.Errorf("error in cache: %s", err)
+		}
+	}
+	return process(data)
+}
+
+// ProcessExisting for observe objects stored in controller in order of arrival
+func (loader *Loader) ProcessExisting(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType) error {
+	from := 0
+	if loader.rng.Cursor != "" {
+		var err error
+		if from, err = strconv.Atoi(loader.rng.Cursor); err != nil {
+			return fmt.Errorf("cannot parse cursor %s: %w", loader.rng.Cursor, err)
+		}
+	}
+	objects, _ := loader.store.get(loader.getKey(typeToProcess), from)
+	for i, obj := range objects {
+		if !loader.rng.Match(obj.received) {
+			continue
+		}
+		loader.cursor = strconv.Itoa(from + i + 1)
+		doContinue, err := loader.processData(process, typeToProcess, obj.data)
+		if err != nil {
+			return err
+		}
+		if !doContinue {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ProcessStream for observe objects pushed into controller after the call
+func (loader *Loader) ProcessStream(process loaders.ProcessFunction, typeToProcess types.LoaderObjectType, timeoutSeconds time.Duration) error {
+	key := loader.getKey(typeToProcess)
+	position := loader.store.count(key)
+	var timeout <-chan time.Time
+	if timeoutSeconds != 0 {
+		timer := time.NewTimer(timeoutSeconds * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		objects, updated := loader.store.get(key, position)
+		for _, obj := range objects {
+			position++
+			loader.cursor = strconv.Itoa(position)
+			doContinue, err := loader.processData(process, typeToProcess, obj.data)
+			if err != nil {
+				return err
+			}
+			if !doContinue {
+				return nil
+			}
+		}
+		if len(objects) > 0 {
+			continue
+		}
+		select {
+		case <-updated:
+		case <-timeout:
+			return fmt.Errorf("timeout")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package fake
+
+import (
+	"encoding/json"
+
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eve/api/go/flowlog"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/metrics"
+	uuid "github.com/satori/go.uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Push stores raw object of typeToProcess for devUUID
+// data must be encoded the same way as controller stores it
+func (ctx *Ctx) Push(devUUID uuid.UUID, typeToProcess types.LoaderObjectType, data []byte) {
+	ctx.pushApp(devUUID, uuid.Nil, typeToProcess, data)
+}
+
+func (ctx *Ctx) pushApp(devUUID, appUUID uuid.UUID, typeToProcess types.LoaderObjectType, data []byte) {
+	ctx.mu.Lock()
+	ctx.init()
+	store := ctx.store
+	ctx.mu.Unlock()
+	store.push(objectKey{devUUID: devUUID, appUUID: appUUID, objType: typeToProcess}, data)
+}
+
+// PushInfo stores info message for devUUID to be consumed by einfo checkers
+func (ctx *Ctx) PushInfo(devUUID uuid.UUID, im *info.ZInfoMsg) error {
+	data, err := proto.Marshal(im)
+	if err != nil {
+		return err
+	}
+	ctx.Push(devUUID, types.InfoType, data)
+	return nil
+}
+
+// PushMetric stores metric message for devUUID to be consumed by emetric checkers
+func (ctx *Ctx) PushMetric(devUUID uuid.UUID, mm *metrics.ZMetricMsg) error {
+	data, err := proto.Marshal(mm)
+	if err != nil {
+		return err
+	}
+	ctx.Push(devUUID, types.MetricsType, data)
+	return nil
+}
+
+// PushLog stores log entry for devUUID to be consumed by elog checkers
+func (ctx *Ctx) PushLog(devUUID uuid.UUID, le *logs.LogEntry) error {
+	data, err := protojson.Marshal(le)
+	if err != nil {
+		return err
+	}
+	ctx.Push(devUUID, types.LogsType, data)
+	return nil
+}
+
+// PushFlowLog stores flow message for devUUID to be consumed by eflowlog checkers
+func (ctx *Ctx) PushFlowLog(devUUID uuid.UUID, fm *flowlog.FlowMessage) error {
+	data, err := proto.Marshal(fm)
+	if err != nil {
+		return err
+	}
+	ctx.Push(devUUID, types.FlowLogType, data)
+	return nil
+}
+
+// PushAppLog stores log entry of appUUID running on devUUID to be consumed by eapps checkers
+func (ctx *Ctx) PushAppLog(devUUID, appUUID uuid.UUID, le *logs.LogEntry) error {
+	data, err := protojson.Marshal(le)
+	if err != nil {
+		return err
+	}
+	ctx.pushApp(devUUID, appUUID, types.AppsType, data)
+	return nil
+}
+
+// PushRequest stores request from EVE for devUUID to be consumed by erequest checkers
+func (ctx *Ctx) PushRequest(devUUID uuid.UUID, request *types.APIRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	ctx.Push(devUUID, types.RequestType, data)
+	return nil
+}
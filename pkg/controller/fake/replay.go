@@ -0,0 +1,23 @@
+package fake
+
+import (
+	"github.com/lf-edge/eden/pkg/controller/archive"
+	uuid "github.com/satori/go.uuid"
+)
+
+// NewReplay returns in-memory controller which replays telemetry recorded into archive
+// with provided speed, devices found in archive are registered in controller
+func NewReplay(path string, speed float64) (*Ctx, error) {
+	player, err := archive.OpenPlayer(path, speed)
+	if err != nil {
+		return nil, err
+	}
+	ctx := New()
+	ctx.SetLoader(player)
+	for _, devUUID := range player.Devices() {
+		if err := ctx.AddDevice(devUUID, uuid.Nil, ""); err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
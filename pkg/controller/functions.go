@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/lf-edge/eden/pkg/controller/adam"
+	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/models"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -33,12 +34,21 @@ func CloudPrepare() (Cloud, error) {
 		return nil, fmt.Errorf("cloud.InitWithVars: %s", err)
 	}
 	ctx.GetAllNodes()
+	ctx.SetDeviceCurrent(os.Getenv(defaults.DefaultNodeEnv))
 	return ctx, nil
 }
 
 // GetVars returns variables of controller
+// if device is selected with SetDeviceCurrent, variables come from its context
 func (cloud *CloudCtx) GetVars() *utils.ConfigVars {
-	return cloud.vars
+	if cloud.selector == "" {
+		return cloud.vars
+	}
+	dev, err := cloud.GetDevice(cloud.selector)
+	if err != nil {
+		return cloud.vars
+	}
+	return cloud.GetDeviceVars(dev)
 }
 
 // SetVars sets variables of controller
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eve/api/go/config"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+//ConfigHistoryEntry describes config submitted into controller
+type ConfigHistoryEntry struct {
+	Version   string
+	Submitted time.Time
+}
+
+//configHistoryDir returns directory to store history of configs of device
+//returns empty string if directory of controller is not defined
+func (cloud *CloudCtx) configHistoryDir(devUUID uuid.UUID) string {
+	if cloud.vars == nil || cloud.vars.AdamDir == "" {
+		return ""
+	}
+	return filepath.Join(cloud.vars.AdamDir, defaults.DefaultConfigHistoryDir, devUUID.String())
+}
+
+//ConfigSet set config for device in controller and save it into history of configs
+func (cloud *CloudCtx) ConfigSet(devUUID uuid.UUID, devConfig []byte) error {
+	if err := cloud.Controller.ConfigSet(devUUID, devConfig); err != nil {
+		return err
+	}
+	if err := cloud.configHistoryAdd(devUUID, devConfig); err != nil {
+		log.Warnf("cannot save config into history: %s", err)
+	}
+	return nil
+}
+
+func (cloud *CloudCtx) configHistoryAdd(devUUID uuid.UUID, devConfig []byte) error {
+	dir := cloud.configHistoryDir(devUUID)
+	if dir == "" {
+		return nil
+	}
+	var deviceConfig config.EdgeDevConfig
+	if err := proto.Unmarshal(devConfig, &deviceConfig); err != nil {
+		return fmt.Errorf("unmarshal error: %w", err)
+	}
+	version := deviceConfig.GetId().GetVersion()
+	if version == "" || strings.ContainsAny(version, `/\`) {
+		return fmt.Errorf("cannot use version %q as history entry", version)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, version), devConfig, 0644)
+}
+
+//ConfigHistory returns configs submitted for device sorted by version
+func (cloud *CloudCtx) ConfigHistory(devUUID uuid.UUID) ([]ConfigHistoryEntry, error) {
+	dir := cloud.configHistoryDir(devUUID)
+	if dir == "" {
+		return nil, fmt.Errorf("directory of controller is not defined")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var history []ConfigHistoryEntry
+	for _, el := range entries {
+		if el.IsDir() {
+			continue
+		}
+		fInfo, err := el.Info()
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, ConfigHistoryEntry{Version: el.Name(), Submitted: fInfo.ModTime()})
+	}
+	sort.Slice(history, func(i, j int) bool {
+		vi, errI := strconv.Atoi(history[i].Version)
+		vj, errJ := strconv.Atoi(history[j].Version)
+		if errI == nil && errJ == nil {
+			return vi < vj
+		}
+		return history[i].Submitted.Before(history[j].Submitted)
+	})
+	return history, nil
+}
+
+//ConfigHistoryGet returns config of device with provided version from history
+func (cloud *CloudCtx) ConfigHistoryGet(devUUID uuid.UUID, version string) (*config.EdgeDevConfig, error) {
+	dir := cloud.configHistoryDir(devUUID)
+	if dir == "" {
+		return nil, fmt.Errorf("directory of controller is not defined")
+	}
+	if strings.ContainsAny(version, `/\`) {
+		return nil, fmt.Errorf("wrong version %q", version)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, version))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("version %s not found in history of device %s", version, devUUID)
+		}
+		return nil, err
+	}
+	var deviceConfig config.EdgeDevConfig
+	if err := proto.Unmarshal(data, &deviceConfig); err != nil {
+		return nil, fmt.Errorf("unmarshal error: %w", err)
+	}
+	return &deviceConfig, nil
+}
@@ -14,6 +14,8 @@ type Loader interface {
 	ProcessStream(process ProcessFunction, typeToProcess types.LoaderObjectType, timeoutSeconds time.Duration) error
 	ProcessExisting(process ProcessFunction, typeToProcess types.LoaderObjectType) error
 	SetRemoteCache(cache cachers.CacheProcessor)
+	SetRange(rng types.LoaderRange)
+	GetCursor() string
 	Clone() Loader
 }
 
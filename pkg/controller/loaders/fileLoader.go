@@ -6,6 +6,8 @@ import (
 	"os"
 	"path"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -21,6 +23,8 @@ type FileLoader struct {
 	devUUID uuid.UUID
 	getters types.DirGetters
 	cache   cachers.CacheProcessor
+	rng     types.LoaderRange
+	cursor  string
 }
 
 // NewFileLoader return loader from files
@@ -41,9 +45,45 @@ func (loader *FileLoader) Clone() Loader {
 		devUUID: loader.devUUID,
 		appUUID: loader.appUUID,
 		cache:   loader.cache,
+		rng:     loader.rng,
+		cursor:  loader.cursor,
 	}
 }
 
+// SetRange set time range and cursor to process existing files
+func (loader *FileLoader) SetRange(rng types.LoaderRange) {
+	loader.rng = rng
+	loader.cursor = rng.Cursor
+}
+
+// GetCursor returns position of the last processed file in form of <modification time in ns>:<file name>
+func (loader *FileLoader) GetCursor() string {
+	return loader.cursor
+}
+
+func fileCursor(file fs.FileInfo) string {
+	return fmt.Sprintf("%d:%s", file.ModTime().UnixNano(), file.Name())
+}
+
+// afterCursor checks if file located after the cursor
+func afterCursor(file fs.FileInfo, cursor string) (bool, error) {
+	if cursor == "" {
+		return true, nil
+	}
+	split := strings.SplitN(cursor, ":", 2)
+	if len(split) != 2 {
+		return false, fmt.Errorf("cannot parse file cursor %s", cursor)
+	}
+	modTime, err := strconv.ParseInt(split[0], 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse file cursor %s: %w", cursor, err)
+	}
+	if file.ModTime().UnixNano() != modTime {
+		return file.ModTime().UnixNano() > modTime, nil
+	}
+	return file.Name() > split[1], nil
+}
+
 func (loader *FileLoader) getFilePath(typeToProcess types.LoaderObjectType) string {
 	switch typeToProcess {
 	case types.LogsType:
@@ -75,6 +115,9 @@ func (loader *FileLoader) SetAppUUID(appUUID uuid.UUID) {
 
 // ProcessExisting for observe existing files
 func (loader *FileLoader) ProcessExisting(process ProcessFunction, typeToProcess types.LoaderObjectType) error {
+	if err := loader.rng.Validate(); err != nil {
+		return err
+	}
 	entries, err := os.ReadDir(loader.getFilePath(typeToProcess))
 	if err != nil {
 		return err
@@ -87,14 +130,35 @@ func (loader *FileLoader) ProcessExisting(process ProcessFunction, typeToProcess
 		}
 		files = append(files, fInfo)
 	}
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].ModTime().Unix() > files[j].ModTime().Unix()
-	})
+	if loader.rng.IsEmpty() {
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].ModTime().Unix() > files[j].ModTime().Unix()
+		})
+	} else {
+		// process in chronological order to be able to resume from the cursor
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].ModTime().Equal(files[j].ModTime()) {
+				return files[i].Name() < files[j].Name()
+			}
+			return files[i].ModTime().Before(files[j].ModTime())
+		})
+	}
 	time.Sleep(1 * time.Second) // wait for write ends
 	for _, file := range files {
 		if file.IsDir() {
 			continue
 		}
+		if !loader.rng.Match(file.ModTime()) {
+			continue
+		}
+		after, err := afterCursor(file, loader.rng.Cursor)
+		if err != nil {
+			return err
+		}
+		if !after {
+			continue
+		}
+		loader.cursor = fileCursor(file)
 		fileFullPath := path.Join(loader.getFilePath(typeToProcess), file.Name())
 		log.Debugf("local controller parse %s", fileFullPath)
 		data, err := os.ReadFile(fileFullPath)
@@ -149,6 +213,9 @@ func (loader *FileLoader) ProcessStream(process ProcessFunction, typeToProcess t
 						continue
 					}
 					log.Debugf("local controller parse %s", event.Name)
+					if fInfo, err := os.Stat(event.Name); err == nil {
+						loader.cursor = fileCursor(fInfo)
+					}
 					if loader.cache != nil {
 						if err = loader.cache.CheckAndSave(loader.devUUID, typeToProcess, data); err != nil {
 							log.Errorf("error in cache: %s", err)
@@ -0,0 +1,185 @@
+package loaders
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eve/api/go/info"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var rangeBase = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func writeRangeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for i, name := range names {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := rangeBase.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func collectFiles(t *testing.T, loader *FileLoader, rng types.LoaderRange) []string {
+	t.Helper()
+	var got []string
+	loader.SetRange(rng)
+	err := loader.ProcessExisting(func(data []byte) (bool, error) {
+		got = append(got, string(data))
+		return true, nil
+	}, types.InfoType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestFileLoaderRange(t *testing.T) {
+	dir := t.TempDir()
+	writeRangeFiles(t, dir, "c", "a", "d", "b")
+	loader := NewFileLoader(types.DirGetters{
+		InfoGetter: func(uuid.UUID) string { return dir },
+	})
+
+	got := collectFiles(t, loader, types.LoaderRange{Since: rangeBase.Add(time.Minute), Until: rangeBase.Add(3 * time.Minute)})
+	assert.Equal(t, []string{"a", "d"}, got)
+	cursor := loader.GetCursor()
+	assert.Equal(t, fmt.Sprintf("%d:d", rangeBase.Add(2*time.Minute).UnixNano()), cursor)
+
+	// resume from the cursor returns only objects after it
+	got = collectFiles(t, loader, types.LoaderRange{Cursor: cursor})
+	assert.Equal(t, []string{"b"}, got)
+
+	// cursor after the last file returns nothing and keeps the cursor
+	last := loader.GetCursor()
+	got = collectFiles(t, loader, types.LoaderRange{Cursor: last})
+	assert.Empty(t, got)
+	assert.Equal(t, last, loader.GetCursor())
+}
+
+func TestAfterCursor(t *testing.T) {
+	dir := t.TempDir()
+	writeRangeFiles(t, dir, "b")
+	fInfo, err := os.Stat(filepath.Join(dir, "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := rangeBase.UnixNano()
+	tests := []struct {
+		name    string
+		cursor  string
+		after   bool
+		wantErr bool
+	}{
+		{name: "empty", cursor: "", after: true},
+		{name: "older time", cursor: fmt.Sprintf("%d:z", ns-1), after: true},
+		{name: "newer time", cursor: fmt.Sprintf("%d:a", ns+1), after: false},
+		{name: "same time smaller name", cursor: fmt.Sprintf("%d:a", ns), after: true},
+		{name: "same file", cursor: fmt.Sprintf("%d:b", ns), after: false},
+		{name: "no separator", cursor: "123", wantErr: true},
+		{name: "bad time", cursor: "abc:b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after, err := afterCursor(fInfo, tt.cursor)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.after, after)
+		})
+	}
+}
+
+func TestRedisLoaderBounds(t *testing.T) {
+	since := time.UnixMilli(1000)
+	until := time.UnixMilli(2000)
+	tests := []struct {
+		name    string
+		rng     types.LoaderRange
+		start   string
+		end     string
+		wantErr bool
+	}{
+		{name: "empty", start: "-", end: "+"},
+		{name: "since", rng: types.LoaderRange{Since: since}, start: "1000-0", end: "+"},
+		{name: "until is exclusive", rng: types.LoaderRange{Until: until}, start: "-", end: "1999"},
+		{name: "cursor", rng: types.LoaderRange{Until: until, Cursor: "1500-3"}, start: "1500-4", end: "1999"},
+		{name: "cursor with since", rng: types.LoaderRange{Since: since, Cursor: "1500-3"}, wantErr: true},
+		{name: "bad cursor", rng: types.LoaderRange{Cursor: "1500"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewRedisLoader("", "", 0, types.StreamGetters{})
+			loader.SetRange(tt.rng)
+			start, end, err := loader.getBounds()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+			assert.Equal(t, tt.rng.Cursor, loader.GetCursor())
+		})
+	}
+}
+
+func TestRemoteLoaderRange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		for i := 0; i < 4; i++ {
+			msg := &info.ZInfoMsg{
+				DevId:       fmt.Sprintf("dev%d", i),
+				AtTimeStamp: timestamppb.New(rangeBase.Add(time.Duration(i) * time.Minute)),
+			}
+			if err := enc.Encode(msg); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	collect := func(rng types.LoaderRange) ([]string, string) {
+		loader := NewRemoteLoader(srv.Client, types.URLGetters{
+			URLInfo: func(uuid.UUID) string { return srv.URL },
+		})
+		loader.SetRange(rng)
+		var got []string
+		err := loader.ProcessExisting(func(data []byte) (bool, error) {
+			var msg info.ZInfoMsg
+			if err := protojson.Unmarshal(data, &msg); err != nil {
+				return false, err
+			}
+			got = append(got, msg.DevId)
+			return true, nil
+		}, types.InfoType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got, loader.GetCursor()
+	}
+
+	got, cursor := collect(types.LoaderRange{Since: rangeBase.Add(time.Minute), Until: rangeBase.Add(3 * time.Minute)})
+	assert.Equal(t, []string{"dev1", "dev2"}, got)
+	assert.Equal(t, "3", cursor)
+
+	got, cursor = collect(types.LoaderRange{Cursor: cursor})
+	assert.Equal(t, []string{"dev3"}, got)
+	assert.Equal(t, "4", cursor)
+}
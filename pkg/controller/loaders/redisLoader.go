@@ -26,6 +26,7 @@ type RedisLoader struct {
 	cache         cachers.CacheProcessor
 	devUUID       uuid.UUID
 	appUUID       uuid.UUID
+	rng           types.LoaderRange
 }
 
 //NewRedisLoader return loader from redis
@@ -55,9 +56,54 @@ func (loader *RedisLoader) Clone() Loader {
 		cache:         loader.cache,
 		devUUID:       loader.devUUID,
 		appUUID:       loader.appUUID,
+		rng:           loader.rng,
 	}
 }
 
+//SetRange set time range and cursor to process existing objects
+func (loader *RedisLoader) SetRange(rng types.LoaderRange) {
+	loader.rng = rng
+	loader.lastID = rng.Cursor
+}
+
+//GetCursor returns ID of the last processed object in redis stream
+func (loader *RedisLoader) GetCursor() string {
+	return loader.lastID
+}
+
+//nextStreamID returns the smallest stream ID greater than id
+func nextStreamID(id string) (string, error) {
+	splitted := strings.Split(id, "-")
+	if len(splitted) != 2 {
+		return "", fmt.Errorf("cannot parse stream ID %s", id)
+	}
+	counter, err := strconv.ParseUint(splitted[1], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse stream ID %s: %w", id, err)
+	}
+	return fmt.Sprintf("%s-%d", splitted[0], counter+1), nil
+}
+
+//getBounds returns start and end stream IDs according to range
+func (loader *RedisLoader) getBounds() (start, end string, err error) {
+	if err = loader.rng.Validate(); err != nil {
+		return "", "", err
+	}
+	start, end = "-", "+"
+	if !loader.rng.Since.IsZero() {
+		start = fmt.Sprintf("%d-0", loader.rng.Since.UnixMilli())
+	}
+	if loader.rng.Cursor != "" {
+		if start, err = nextStreamID(loader.rng.Cursor); err != nil {
+			return "", "", err
+		}
+	}
+	if !loader.rng.Until.IsZero() {
+		end = fmt.Sprintf("%d", loader.rng.Until.UnixMilli()-1)
+	}
+	return start, end, nil
+}
+
 func (loader *RedisLoader) getStream(typeToProcess types.LoaderObjectType) string {
 	switch typeToProcess {
 	case types.LogsType:
@@ -91,9 +137,12 @@ func (loader *RedisLoader) process(process ProcessFunction, typeToProcess types.
 	OrderStream := loader.getStream(typeToProcess)
 	log.Debugf("XRead from %s", OrderStream)
 	if !stream {
-		start := "-"
+		start, end, err := loader.getBounds()
+		if err != nil {
+			return false, false, err
+		}
 		for {
-			rr, err := loader.client.XRangeN(context.Background(), OrderStream, start, "+", 10).Result()
+			rr, err := loader.client.XRangeN(context.Background(), OrderStream, start, end, 10).Result()
 			if err != nil {
 				return false, false, fmt.Errorf("XRange error: %s", err)
 			}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/lf-edge/eden/pkg/controller/cachers"
@@ -39,6 +40,9 @@ type RemoteLoader struct {
 	getClient    getClient
 	client       *http.Client
 	cache        cachers.CacheProcessor
+	rng          types.LoaderRange
+	rangeOffset  uint64
+	cursor       string
 }
 
 //NewRemoteLoader return loader from files
@@ -69,9 +73,33 @@ func (loader *RemoteLoader) Clone() Loader {
 		appUUID:      loader.appUUID,
 		client:       loader.getClient(),
 		cache:        loader.cache,
+		rng:          loader.rng,
+		rangeOffset:  loader.rangeOffset,
+		cursor:       loader.cursor,
 	}
 }
 
+//SetRange set time range and cursor to process existing objects
+//cursor for RemoteLoader is an offset of object inside of response
+func (loader *RemoteLoader) SetRange(rng types.LoaderRange) {
+	loader.rng = rng
+	loader.cursor = rng.Cursor
+	loader.rangeOffset = 0
+	if rng.Cursor != "" {
+		offset, err := strconv.ParseUint(rng.Cursor, 10, 64)
+		if err != nil {
+			log.Errorf("cannot parse cursor %s: %s", rng.Cursor, err)
+			return
+		}
+		loader.rangeOffset = offset
+	}
+}
+
+//GetCursor returns offset of the next object after the last processed one
+func (loader *RemoteLoader) GetCursor() string {
+	return loader.cursor
+}
+
 func (loader *RemoteLoader) getURL(typeToProcess types.LoaderObjectType) string {
 	switch typeToProcess {
 	case types.LogsType:
@@ -103,6 +131,7 @@ func (loader *RemoteLoader) SetAppUUID(appUUID uuid.UUID) {
 
 func (loader *RemoteLoader) processNext(decoder *json.Decoder, process ProcessFunction, typeToProcess types.LoaderObjectType, stream bool) (processed, tocontinue bool, err error) {
 	var buf []byte
+	var objTime *timestamppb.Timestamp
 	switch typeToProcess {
 	case types.LogsType:
 		var emp logs.LogBundle
@@ -114,6 +143,7 @@ func (loader *RemoteLoader) processNext(decoder *json.Decoder, process ProcessFu
 		if buf, err = protojson.Marshal(&emp); err != nil {
 			return false, false, err
 		}
+		objTime = emp.GetTimestamp()
 	case types.InfoType:
 		var emp info.ZInfoMsg
 		if err := decoder.Decode(&emp); err == io.EOF {
@@ -124,6 +154,7 @@ func (loader *RemoteLoader) processNext(decoder *json.Decoder, process ProcessFu
 		if buf, err = protojson.Marshal(&emp); err != nil {
 			return false, false, err
 		}
+		objTime = emp.GetAtTimeStamp()
 	}
 	if loader.cache != nil {
 		if err = loader.cache.CheckAndSave(loader.devUUID, typeToProcess, buf); err != nil {
@@ -134,7 +165,14 @@ func (loader *RemoteLoader) processNext(decoder *json.Decoder, process ProcessFu
 		loader.curCount++
 		return false, true, nil
 	}
+	if !loader.rng.IsEmpty() && !stream {
+		if loader.curCount < loader.rangeOffset || (objTime != nil && !loader.rng.Match(objTime.AsTime())) {
+			loader.curCount++
+			return false, true, nil
+		}
+	}
 	tocontinue, err = process(buf)
+	loader.cursor = strconv.FormatUint(loader.curCount+1, 10)
 	if stream {
 		time.Sleep(1 * time.Second) //wait for load all data from buffer
 	}
@@ -219,6 +257,9 @@ repeatLoop:
 
 //ProcessExisting for observe existing files
 func (loader *RemoteLoader) ProcessExisting(process ProcessFunction, typeToProcess types.LoaderObjectType) error {
+	if err := loader.rng.Validate(); err != nil {
+		return err
+	}
 	return loader.repeatableConnection(process, typeToProcess, false)
 }
 
@@ -123,6 +123,42 @@ var AppsType LoaderObjectType = 5
 //FlowLogType for observe FlowMessages
 var FlowLogType LoaderObjectType = 6
 
+//LoaderRange restricts objects processed by loaders from existing ones
+//Since and Until compare with time of object receiving by controller if loader knows it
+//(ID of redis stream, modification time of file), RemoteLoader compares them with time inside of object;
+//zero value means no limit
+//Cursor is an opaque position returned by loader (redis stream ID, file or object offset),
+//processing resumes from the next object after it, so it cannot be combined with Since
+type LoaderRange struct {
+	Since  time.Time
+	Until  time.Time
+	Cursor string
+}
+
+//Match checks if time of object is inside of range
+func (r LoaderRange) Match(t time.Time) bool {
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && !t.Before(r.Until) {
+		return false
+	}
+	return true
+}
+
+//Validate returns error if range defines both start of processing by Since and Cursor
+func (r LoaderRange) Validate() error {
+	if r.Cursor != "" && !r.Since.IsZero() {
+		return fmt.Errorf("cursor cannot be combined with since")
+	}
+	return nil
+}
+
+//IsEmpty returns true if no limits defined
+func (r LoaderRange) IsEmpty() bool {
+	return r.Since.IsZero() && r.Until.IsZero() && r.Cursor == ""
+}
+
 //APIRequest stores information about requests from EVE
 type APIRequest struct {
 	Timestamp time.Time `json:"timestamp"`
@@ -29,11 +29,15 @@ const (
 	DefaultConfigSaved      = "config_saved.yml" //file to save config during 'eden setup'
 	DefaultSwtpmSockFile    = "swtpm-sock"       //file to communicate with swtpm
 	DefaultAdditionalDisks  = 0                  //number of disks to use alongside with bootable one
+	DefaultConfigHistoryDir = "config-history"   //directory inside adam dist to save history of configs of devices
+	DefaultApplyStateDir    = "apply-state"      //directory inside adam dist to save objects applied from manifests
 
 	DefaultContext = "default" //default context name
 
 	DefaultConfigEnv   = "EDEN_CONFIG"    //default env for set config
 	DefaultTestArgsEnv = "EDEN_TEST_ARGS" //default env for test arguments
+	DefaultNodeEnv     = "EDEN_NODE"      //default env for select edge node by name or UUID
+	DefaultArchiveEnv  = "EDEN_ARCHIVE"   //default env for path of archive to record telemetry into
 )
 
 // domains, ips, ports
@@ -51,6 +55,7 @@ const (
 	DefaultRedisPort            = 6379
 	DefaultAdamPort             = 3333
 	DefaultRegistryPort         = 5050
+	DefaultMetricExportPort     = 9123
 
 	//tags, versions, repos
 	DefaultEVETag               = "10.4.0" // DefaultEVETag tag for EVE image
@@ -103,7 +108,7 @@ const (
 	DefaultTestScenario          = ""
 	DefaultRootFSVersionPattern  = `^.*-(xen|kvm|acrn|rpi|rpi-xen|rpi-kvm)-(amd64|arm64)$`
 	DefaultControllerModePattern = `^(?P<Type>(file|proto|adam|zedcloud)):\/\/(?P<URL>.*)$`
-	DefaultPodLinkPattern        = `^(?P<TYPE>(oci|docker|http[s]{0,1}|file|directory)):\/\/(?P<TAG>[^:]+):*(?P<VERSION>.*)$`
+	DefaultPodLinkPattern        = `^(?P<TYPE>(oci|docker|http[s]{0,1}|file|directory|s3|azure)):\/\/(?P<TAG>[^:]+):*(?P<VERSION>.*)$`
 	DefaultRedisContainerName    = "eden_redis"
 	DefaultAdamContainerName     = "eden_adam"
 	DefaultRegistryContainerName = "eden_registry"
@@ -265,19 +270,21 @@ var (
 		"eve.qemu.monitor-port":       "qemu-monitor-port",
 		"eve.qemu.netdev-socket-port": "qemu-netdev-socket-port",
 
-		"eden.images.dist":   "image-dist",
-		"eden.images.docker": "docker-yml",
-		"eden.images.vm":     "vm-yml",
-		"eden.download":      "download",
-		"eden.eserver.ip":    "eserver-ip",
-		"eden.eserver.port":  "eserver-port",
-		"eden.eserver.tag":   "eserver-tag",
-		"eden.eserver.force": "eserver-force",
-		"eden.certs-dist":    "certs-dist",
-		"eden.bin-dist":      "bin-dist",
-		"eden.ssh-key":       "ssh-key",
-		"eden.test-bin":      "prog",
-		"eden.test-scenario": "scenario",
+		"eden.images.dist":      "image-dist",
+		"eden.images.docker":    "docker-yml",
+		"eden.images.vm":        "vm-yml",
+		"eden.download":         "download",
+		"eden.eserver.ip":       "eserver-ip",
+		"eden.eserver.port":     "eserver-port",
+		"eden.eserver.tag":      "eserver-tag",
+		"eden.eserver.force":    "eserver-force",
+		"eden.eserver.tls":      "eserver-tls",
+		"eden.eserver.registry": "eserver-registry",
+		"eden.certs-dist":       "certs-dist",
+		"eden.bin-dist":         "bin-dist",
+		"eden.ssh-key":          "ssh-key",
+		"eden.test-bin":         "prog",
+		"eden.test-scenario":    "scenario",
 
 		"config": "config",
 
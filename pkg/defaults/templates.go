@@ -218,6 +218,22 @@ eden:
         #force eserver rebuild
         force: {{parse "eden.eserver.force"}}
 
+        #serve https with eden certificates on the same port and use it for EVE
+        tls: {{parse "eden.eserver.tls"}}
+
+        #bearer token to access admin endpoints of eserver, not required if empty
+        token: '{{parse "eden.eserver.token"}}'
+
+        #credentials to download files from eserver, not required if user is empty
+        user: '{{parse "eden.eserver.user"}}'
+        password: '{{parse "eden.eserver.password"}}'
+
+        #key to sign URLs of files, signed URLs are disabled if empty
+        sign-key: '{{parse "eden.eserver.sign-key"}}'
+
+        #serve images with OCI distribution API of eserver instead of registry container
+        registry: {{parse "eden.eserver.registry"}}
+
     #eclient is tool we use in tests
     eclient:
         #tag of eclient container
@@ -22,6 +22,7 @@ var (
 type Ctx struct {
 	onboardKey                 string
 	serial                     string
+	name                       string
 	state                      EdgeNodeState
 	project                    string
 	hash                       [32]byte
@@ -314,6 +315,27 @@ func (cfg *Ctx) SetSerial(serial string) {
 	cfg.serial = serial
 }
 
+//GetName getter
+func (cfg *Ctx) GetName() string {
+	return cfg.name
+}
+
+//SetName setter
+func (cfg *Ctx) SetName(name string) {
+	cfg.name = name
+}
+
+//Match returns true if selector is equal to UUID or name of device
+func (cfg *Ctx) Match(selector string) bool {
+	if selector == "" {
+		return false
+	}
+	if selector == cfg.id.String() {
+		return true
+	}
+	return cfg.name != "" && cfg.name == selector
+}
+
 //GetOnboardKey getter
 func (cfg *Ctx) GetOnboardKey() string {
 	return cfg.onboardKey
@@ -3,6 +3,7 @@ package eden
 import (
 	"bytes"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -10,8 +11,10 @@ import (
 	"math/big"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -322,12 +325,60 @@ func StatusRegistry() (status string, err error) {
 	return state, nil
 }
 
+// EServerOptions contains TLS, access and registry settings of eserver
+type EServerOptions struct {
+	// TLS enables https with server certificate of eden
+	TLS bool
+	// Token to access admin endpoints
+	Token string
+	// User and Password to download files
+	User     string
+	Password string
+	// SignKey enables signed URLs of files
+	SignKey string
+	// Registry enables OCI distribution API to serve images
+	Registry bool
+}
+
+// args returns arguments of eserver with certificates from certsDir mounted into container
+func (opts *EServerOptions) args(certsDir string) []string {
+	var args []string
+	if opts.TLS {
+		args = append(args,
+			"--cert", path.Join(certsDir, "server.pem"),
+			"--key", path.Join(certsDir, "server-key.pem"))
+	}
+	if opts.Token != "" {
+		args = append(args, "--admin-token", opts.Token)
+	}
+	if opts.User != "" {
+		args = append(args, "--download-user", opts.User, "--download-password", opts.Password)
+	}
+	if opts.SignKey != "" {
+		args = append(args, "--sign-key", opts.SignKey)
+	}
+	if opts.Registry {
+		args = append(args, "--registry")
+	}
+	return args
+}
+
 // StartEServer function run eserver in docker
 // if eserverForce is set, it recreates container
-func StartEServer(serverPort int, imageDist string, eserverForce bool, eserverTag string) (err error) {
+// opts are applied only on creation of container, they may be nil
+func StartEServer(serverPort int, imageDist string, eserverForce bool, eserverTag string, opts *EServerOptions) (err error) {
 	portMap := map[string]string{"8888": strconv.Itoa(serverPort)}
 	volumeMap := map[string]string{"/eserver/run/eserver/": imageDist}
 	eserverServerCommand := strings.Fields("server")
+	if opts != nil {
+		edenHome, err := utils.DefaultEdenDir()
+		if err != nil {
+			return err
+		}
+		certsDir := "/eserver/certs"
+		volumeMap[certsDir] = filepath.Join(edenHome, defaults.DefaultCertsDist)
+		eserverServerCommand = append(eserverServerCommand, opts.args(certsDir)...)
+	}
 	// lets make sure eserverImageDist exists
 	if imageDist != "" && os.MkdirAll(imageDist, os.ModePerm) != nil {
 		return fmt.Errorf("StartEServer: %s does not exist and can not be created", imageDist)
@@ -1026,26 +1077,80 @@ func CleanEden(eveDist, adamDist, certsDist, imagesDist, eserverDist, redisDist,
 type EServer struct {
 	EServerIP   string
 	EServerPort string
+	// TLS enables https, certificate of eserver is verified with CA if set
+	TLS bool
+	CA  string
+	// Token to access admin endpoints
+	Token string
+}
+
+// NewEServer returns EServer for connection with parameters from vars
+func NewEServer(vars *utils.ConfigVars) *EServer {
+	return &EServer{
+		EServerIP:   vars.EServerIP,
+		EServerPort: vars.EServerPort,
+		TLS:         vars.EServerTLS,
+		CA:          vars.AdamCA,
+		Token:       vars.EServerToken,
+	}
+}
+
+func (server *EServer) baseURL() string {
+	scheme := "http"
+	if server.TLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%s", scheme, server.EServerIP, server.EServerPort)
+}
+
+// tokenTransport adds bearer token into requests
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	return t.next.RoundTrip(req)
 }
 
 func (server *EServer) getHTTPClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		ResponseHeaderTimeout: defaults.DefaultRepeatTimeout * defaults.DefaultRepeatCount,
+	}
+	if server.TLS {
+		tlsConfig := &tls.Config{InsecureSkipVerify: server.CA == ""}
+		if server.CA != "" {
+			caCert, err := os.ReadFile(server.CA)
+			if err != nil {
+				log.Fatalf("unable to read eserver CA file at %s: %v", server.CA, err)
+			}
+			caCertPool := x509.NewCertPool()
+			caCertPool.AppendCertsFromPEM(caCert)
+			tlsConfig.RootCAs = caCertPool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
 	return &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			ResponseHeaderTimeout: defaults.DefaultRepeatTimeout * defaults.DefaultRepeatCount,
-		},
+		Timeout:   timeout,
+		Transport: &tokenTransport{token: server.Token, next: transport},
 	}
 }
 
 // EServerAddFileURL send url to download image into eserver
-func (server *EServer) EServerAddFileURL(url string) (name string) {
-	u, err := utils.ResolveURL(fmt.Sprintf("http://%s:%s", server.EServerIP, server.EServerPort), "admin/add-from-url")
+// sha256 of downloaded file is verified by eserver if expectedSha256 is not empty
+func (server *EServer) EServerAddFileURL(url, expectedSha256 string) (name string) {
+	u, err := utils.ResolveURL(server.baseURL(), "admin/add-from-url")
 	if err != nil {
 		log.Fatalf("error constructing URL: %v", err)
 	}
 	client := server.getHTTPClient(defaults.DefaultRepeatTimeout)
 	objToSend := api.URLArg{
-		URL: url,
+		URL:    url,
+		Sha256: expectedSha256,
 	}
 	body, err := json.Marshal(objToSend)
 	if err != nil {
@@ -1069,7 +1174,7 @@ func (server *EServer) EServerAddFileURL(url string) (name string) {
 
 // EServerCheckStatus checks status of image in eserver
 func (server *EServer) EServerCheckStatus(name string) (fileInfo *api.FileInfo) {
-	u, err := utils.ResolveURL(fmt.Sprintf("http://%s:%s", server.EServerIP, server.EServerPort), fmt.Sprintf("admin/status/%s", name))
+	u, err := utils.ResolveURL(server.baseURL(), fmt.Sprintf("admin/status/%s", name))
 	if err != nil {
 		log.Fatalf("EServerAddFileURL: error constructing URL: %v", err)
 	}
@@ -1095,7 +1200,7 @@ func (server *EServer) EServerCheckStatus(name string) (fileInfo *api.FileInfo)
 
 // EServerAddFile send file with image into eserver
 func (server *EServer) EServerAddFile(filepath, prefix string) (fileInfo *api.FileInfo) {
-	u, err := utils.ResolveURL(fmt.Sprintf("http://%s:%s", server.EServerIP, server.EServerPort), "admin/add-from-file")
+	u, err := utils.ResolveURL(server.baseURL(), "admin/add-from-file")
 	if err != nil {
 		log.Fatalf("EServerAddFile: error constructing URL: %v", err)
 	}
@@ -1114,6 +1219,160 @@ func (server *EServer) EServerAddFile(filepath, prefix string) (fileInfo *api.Fi
 	return
 }
 
+// adminRequest sends request with obj encoded into json to admin endpoint of eserver
+// and decodes response into result if it is not nil
+func (server *EServer) adminRequest(method, endpoint string, obj, result interface{}) error {
+	u, err := utils.ResolveURL(server.baseURL(), path.Join("admin", endpoint))
+	if err != nil {
+		return fmt.Errorf("error constructing URL: %w", err)
+	}
+	var body io.Reader
+	if obj != nil {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("error encoding json: %w", err)
+		}
+		body = bytes.NewBuffer(data)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return fmt.Errorf("unable to create new http request: %w", err)
+	}
+	response, err := server.getHTTPClient(defaults.DefaultRepeatTimeout).Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send request: %w", err)
+	}
+	defer response.Body.Close()
+	buf, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read data from URL %s: %w", u, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(buf)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(buf, result); err != nil {
+		return fmt.Errorf("cannot unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// EServerListFiles returns files stored in eserver
+func (server *EServer) EServerListFiles() (files []*api.FileEntry, err error) {
+	err = server.adminRequest(http.MethodGet, "files", nil, &files)
+	return
+}
+
+// EServerDelete removes file from eserver by name or all names of content with sha256
+func (server *EServer) EServerDelete(arg *api.DeleteArg) (removed []*api.FileEntry, err error) {
+	err = server.adminRequest(http.MethodPost, "delete", arg, &removed)
+	return
+}
+
+// EServerPin protects content of file in eserver from garbage collection or removes protection
+func (server *EServer) EServerPin(name string, pinned bool) error {
+	return server.adminRequest(http.MethodPost, "pin", &api.PinArg{Name: name, Pinned: pinned}, nil)
+}
+
+// EServerSignURL returns URL of file in eserver signed for expires duration
+func (server *EServer) EServerSignURL(name string, expires time.Duration) (string, error) {
+	var result api.SignResult
+	if err := server.adminRequest(http.MethodPost, "sign", &api.SignArg{Name: name, Expires: int64(expires.Seconds())}, &result); err != nil {
+		return "", err
+	}
+	return utils.ResolveURL(server.baseURL(), result.Path)
+}
+
+// EServerGC runs garbage collection in eserver
+func (server *EServer) EServerGC(arg *api.GCArg) (result *api.GCResult, err error) {
+	err = server.adminRequest(http.MethodPost, "gc", arg, &result)
+	return
+}
+
+// EServerListFaults returns faults injected into downloads from eserver
+func (server *EServer) EServerListFaults() (faults []*api.Fault, err error) {
+	err = server.adminRequest(http.MethodGet, "faults", nil, &faults)
+	return
+}
+
+// EServerSetFault adds or replaces fault injected into downloads from eserver
+func (server *EServer) EServerSetFault(fault *api.Fault) error {
+	return server.adminRequest(http.MethodPost, "faults", fault, nil)
+}
+
+// EServerClearFaults removes fault by name or all faults if name is empty
+func (server *EServer) EServerClearFaults(name string) error {
+	return server.adminRequest(http.MethodPost, "faults/clear", &api.FaultClearArg{Name: name}, nil)
+}
+
+// EServerImportImage imports images from tarFile in OCI layout or docker save format into registry of eserver,
+// name (repository:tag) replaces name of image inside archive with single image
+func (server *EServer) EServerImportImage(tarFile, name string) (images []*api.OCIImage, err error) {
+	u, err := utils.ResolveURL(server.baseURL(), "admin/oci/import")
+	if err != nil {
+		return nil, fmt.Errorf("error constructing URL: %w", err)
+	}
+	if name != "" {
+		u = fmt.Sprintf("%s?%s", u, url.Values{"name": []string{name}}.Encode())
+	}
+	file, err := os.Open(tarFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	req, err := http.NewRequest(http.MethodPost, u, file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-tar")
+	response, err := server.getHTTPClient(0).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer response.Body.Close()
+	buf, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read data from URL %s: %w", u, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(buf)))
+	}
+	if err := json.Unmarshal(buf, &images); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal response: %w", err)
+	}
+	return images, nil
+}
+
+// EServerLoadImage saves local image or pulls remote one and imports it into registry of eserver,
+// returns digest of manifest
+func (server *EServer) EServerLoadImage(image string) (string, error) {
+	dir, err := os.MkdirTemp("", "edenSave")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temporary dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	tarFile := filepath.Join(dir, "image.tar")
+	if err := utils.SaveImageToArchive(image, tarFile); err != nil {
+		return "", err
+	}
+	images, err := server.EServerImportImage(tarFile, image)
+	if err != nil {
+		return "", fmt.Errorf("unable to import %s: %w", image, err)
+	}
+	if len(images) == 0 {
+		return "", fmt.Errorf("no images imported from %s", image)
+	}
+	return images[0].Digest, nil
+}
+
+// EServerListImages returns images stored in registry of eserver
+func (server *EServer) EServerListImages() (images []*api.OCIImage, err error) {
+	err = server.adminRequest(http.MethodGet, "oci/images", nil, &images)
+	return
+}
+
 // ReadFileInSquashFS returns the content of a single file (filePath) inside squashfs (squashFSPath)
 func ReadFileInSquashFS(squashFSPath, filePath string) (content []byte, err error) {
 	tmpdir, err := os.MkdirTemp("", "squashfs-unpack")
@@ -0,0 +1,216 @@
+package edensdn
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	model "github.com/lf-edge/eden/sdn/vm/api"
+)
+
+// CaptureOpts : options for packet capture.
+type CaptureOpts struct {
+	// Filter : capture filter in the pcap-filter syntax (see "man pcap-filter").
+	// Leave empty to capture all packets.
+	Filter string
+	// SnapLen : maximum number of bytes to capture from each packet.
+	// Zero means to capture entire packets.
+	SnapLen uint32
+	// Count : stop capture after receiving this many packets. Zero means no limit.
+	// Ring capture keeps buffered packets after it stops.
+	Count uint64
+	// Duration : stop capture after this time. Zero means no limit.
+	// Ring capture keeps buffered packets after it stops.
+	Duration time.Duration
+	// RingSize : maximum number of bytes of captured packets kept by ring capture.
+	// Zero means to use the default size selected by SDN agent.
+	RingSize uint64
+}
+
+func (opts CaptureOpts) query() string {
+	query := url.Values{}
+	if opts.Filter != "" {
+		query.Set("filter", opts.Filter)
+	}
+	if opts.SnapLen != 0 {
+		query.Set("snaplen", strconv.FormatUint(uint64(opts.SnapLen), 10))
+	}
+	if opts.Count != 0 {
+		query.Set("count", strconv.FormatUint(opts.Count, 10))
+	}
+	if opts.Duration != 0 {
+		query.Set("duration", opts.Duration.String())
+	}
+	if opts.RingSize != 0 {
+		query.Set("size", strconv.FormatUint(opts.RingSize, 10))
+	}
+	return query.Encode()
+}
+
+// Capture : capture packets of a port, network or endpoint and write them
+// into out in the pcapng format. Capture is stopped when the context is canceled,
+// the duration elapses or the requested number of packets was captured.
+func (client *SdnClient) Capture(ctx context.Context, itemType, logicalLabel string,
+	opts CaptureOpts, out io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("http://localhost:%d/capture/%s/%s?%s", client.MgmtPort,
+			url.PathEscape(itemType), url.PathEscape(logicalLabel), opts.query()), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build HTTP request: %w", err)
+	}
+	httpClient := &http.Client{}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("request to GET capture failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return respError("GET capture", resp)
+	}
+	err = copyPcapngBlocks(out, resp.Body)
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to receive captured packets: %w", err)
+	}
+	return nil
+}
+
+// StartRingCapture : start capturing packets of a port, network or endpoint
+// in the background, keeping only the most recent packets (up to opts.RingSize bytes).
+// Previously started ring capture of the same item is replaced.
+// This is useful for tests, which can save the capture only if they fail.
+func (client *SdnClient) StartRingCapture(itemType, logicalLabel string,
+	opts CaptureOpts) error {
+	resp, err := client.ringCaptureRequest(http.MethodPut, itemType, logicalLabel,
+		opts.query())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return respError("PUT ring capture", resp)
+	}
+	return nil
+}
+
+// SaveRingCapture : write packets currently buffered by the ring capture
+// into out in the pcapng format. Ring capture continues running.
+func (client *SdnClient) SaveRingCapture(itemType, logicalLabel string,
+	out io.Writer) error {
+	resp, err := client.ringCaptureRequest(http.MethodGet, itemType, logicalLabel, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return respError("GET ring capture", resp)
+	}
+	if _, err = io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("failed to receive ring capture: %w", err)
+	}
+	return nil
+}
+
+// StopRingCapture : stop ring capture and discard all buffered packets.
+func (client *SdnClient) StopRingCapture(itemType, logicalLabel string) error {
+	resp, err := client.ringCaptureRequest(http.MethodDelete, itemType, logicalLabel, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return respError("DELETE ring capture", resp)
+	}
+	return nil
+}
+
+// ListRingCaptures : list ring captures running in Eden-SDN.
+func (client *SdnClient) ListRingCaptures() (captures []model.RingCapture, err error) {
+	resp, err := http.Get(
+		fmt.Sprintf("http://localhost:%d/ring-captures.json", client.MgmtPort))
+	if err != nil {
+		err = fmt.Errorf("request to GET ring captures failed: %w", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = respError("GET ring captures", resp)
+		return
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = fmt.Errorf("failed to read retrieved ring captures: %w", err)
+		return
+	}
+	err = json.Unmarshal(data, &captures)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal ring captures: %w", err)
+		return
+	}
+	return
+}
+
+func (client *SdnClient) ringCaptureRequest(method, itemType, logicalLabel,
+	query string) (*http.Response, error) {
+	req, err := http.NewRequest(method,
+		fmt.Sprintf("http://localhost:%d/ring-capture/%s/%s?%s", client.MgmtPort,
+			url.PathEscape(itemType), url.PathEscape(logicalLabel), query), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build HTTP request: %w", err)
+	}
+	httpClient := &http.Client{}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s ring capture failed: %w", method, err)
+	}
+	return resp, nil
+}
+
+func respError(request string, resp *http.Response) error {
+	var response string
+	respBytes, err := io.ReadAll(resp.Body)
+	if err == nil {
+		response = string(respBytes)
+	} else {
+		response = fmt.Sprintf("failed to read response: %v", err)
+	}
+	return fmt.Errorf("request to %s failed with code=%d, response: %s",
+		request, resp.StatusCode, response)
+}
+
+// copyPcapngBlocks copies pcapng blocks one by one, never writing incomplete
+// block into out. This ensures that the output remains valid even if the capture
+// is interrupted.
+func copyPcapngBlocks(out io.Writer, in io.Reader) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(in, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		// SDN agent always writes pcapng in the little-endian byte order.
+		blockLen := binary.LittleEndian.Uint32(header[4:8])
+		if blockLen < 12 || blockLen%4 != 0 {
+			return fmt.Errorf("invalid pcapng block length: %d", blockLen)
+		}
+		block := make([]byte, blockLen)
+		copy(block, header)
+		if _, err := io.ReadFull(in, block[8:]); err != nil {
+			return err
+		}
+		if _, err := out.Write(block); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package edensdn
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/sdn/vm/pkg/pcapng"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCapture(t *testing.T) []byte {
+	var buf bytes.Buffer
+	writer, err := pcapng.NewWriter(&buf, "test", pcapng.Interface{Name: "eth0", LinkType: 1, SnapLen: 65535})
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, writer.WritePacket(pcapng.Packet{
+			Timestamp: time.Unix(1700000000, int64(i)),
+			OrigLen:   3,
+			Data:      []byte{1, 2, byte(i)},
+		}))
+	}
+	return buf.Bytes()
+}
+
+func TestCopyPcapngBlocks(t *testing.T) {
+	capture := testCapture(t)
+	var out bytes.Buffer
+	assert.NoError(t, copyPcapngBlocks(&out, bytes.NewReader(capture)))
+	assert.Equal(t, capture, out.Bytes())
+}
+
+func TestCopyPcapngBlocksInterrupted(t *testing.T) {
+	capture := testCapture(t)
+	// the last packet block is 36 bytes long
+	complete := capture[:len(capture)-36]
+	for _, cut := range []int{4, 20} {
+		var out bytes.Buffer
+		err := copyPcapngBlocks(&out, bytes.NewReader(capture[:len(capture)-cut]))
+		assert.Error(t, err)
+		assert.Equal(t, complete, out.Bytes(), "cut %d bytes", cut)
+	}
+}
+
+func TestCopyPcapngBlocksInvalidLength(t *testing.T) {
+	var out bytes.Buffer
+	block := []byte{1, 0, 0, 0, 10, 0, 0, 0, 0, 0, 0, 0}
+	assert.Error(t, copyPcapngBlocks(&out, bytes.NewReader(block)))
+	assert.Empty(t, out.Bytes())
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -103,6 +104,89 @@ func (client *SdnClient) ApplyNetworkModel(netModel model.NetworkModel) (err err
 	return
 }
 
+// SetImpairment : change impairment of a port, bridge or network without re-applying
+// the whole network model. Use nil impairment to remove it.
+func (client *SdnClient) SetImpairment(itemType, logicalLabel string,
+	impairment *model.Impairment) (err error) {
+	json, err := json.Marshal(impairment)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal impairment: %w", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPut,
+		fmt.Sprintf("http://localhost:%d/impairment/%s/%s", client.MgmtPort,
+			url.PathEscape(itemType), url.PathEscape(logicalLabel)),
+		bytes.NewBuffer(json))
+	if err != nil {
+		err = fmt.Errorf("failed to build HTTP request: %w", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpClient := &http.Client{}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("request to PUT impairment failed: %w", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var respBytes []byte
+		var response string
+		respBytes, err = io.ReadAll(resp.Body)
+		if err == nil {
+			response = string(respBytes)
+		} else {
+			response = fmt.Sprintf("failed to read response: %v", err)
+		}
+		err = fmt.Errorf("request to PUT impairment failed with code=%d, "+
+			"response: %s", resp.StatusCode, response)
+		return
+	}
+	return
+}
+
+// CaptivePortalLogin : log a client (referenced by IP address) in through
+// the captive portal without submitting the login form.
+func (client *SdnClient) CaptivePortalLogin(portal, clientIP string) error {
+	return client.setCaptivePortalClient(http.MethodPut, portal, clientIP)
+}
+
+// CaptivePortalLogout : log out a client previously logged in through the captive portal.
+func (client *SdnClient) CaptivePortalLogout(portal, clientIP string) error {
+	return client.setCaptivePortalClient(http.MethodDelete, portal, clientIP)
+}
+
+func (client *SdnClient) setCaptivePortalClient(method, portal, clientIP string) (err error) {
+	req, err := http.NewRequest(method,
+		fmt.Sprintf("http://localhost:%d/captive-portal/%s/client/%s", client.MgmtPort,
+			url.PathEscape(portal), url.PathEscape(clientIP)), nil)
+	if err != nil {
+		err = fmt.Errorf("failed to build HTTP request: %w", err)
+		return
+	}
+	httpClient := &http.Client{}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("request to %s captive portal client failed: %w", method, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var respBytes []byte
+		var response string
+		respBytes, err = io.ReadAll(resp.Body)
+		if err == nil {
+			response = string(respBytes)
+		} else {
+			response = fmt.Sprintf("failed to read response: %v", err)
+		}
+		err = fmt.Errorf("request to %s captive portal client failed with code=%d, "+
+			"response: %s", method, resp.StatusCode, response)
+		return
+	}
+	return
+}
+
 // GetNetworkConfigGraph : get network config applied by Eden-SDN.
 // Network config items and their dependencies are depicted using a DOT graph.
 func (client *SdnClient) GetNetworkConfigGraph() (config string, err error) {
@@ -0,0 +1,371 @@
+package edensdn
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+)
+
+// Scenario : timeline of network model mutations to apply to a running Eden-SDN.
+// Can be used to test how EVE handles network changes (e.g. failover between uplinks).
+//
+// Scenario file contains one step per line, formatted as:
+//
+//	t=<offset> <action> [<args>...]
+//
+// where offset is the time elapsed since the scenario start (e.g. 30s, 1m30s).
+// Supported actions:
+//
+//	port <logical-label> up|down : change the administrative state of a port
+//	apply <model-file>           : replace the network model with the model from the file
+//	patch <patch-file>           : modify the network model using a JSON merge patch (RFC 7386)
+//
+// Empty lines and lines starting with '#' are ignored. Relative paths of files are
+// resolved against the directory of the scenario file. Steps are executed in the order
+// of offsets, steps with the same offset in the order of appearance.
+type Scenario struct {
+	// Name of the scenario (base name of the scenario file).
+	Name string
+	// Steps of the scenario sorted by offsets.
+	Steps []ScenarioStep
+}
+
+// ScenarioStep : single step of a scenario.
+type ScenarioStep struct {
+	// Offset : time since the scenario start at which the step should be executed.
+	Offset time.Duration
+	// Line : line of the scenario file with the step.
+	Line int
+	// Action : step as written in the scenario file (without the offset).
+	Action string
+	// mutate applies the step to the network model.
+	mutate func(netModel *sdnapi.NetworkModel) error
+}
+
+// LoadScenarioFromFile loads scenario from a file.
+func LoadScenarioFromFile(path string) (scenario Scenario, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return scenario, fmt.Errorf("failed to open scenario file '%s': %w", path, err)
+	}
+	defer file.Close()
+	scenario, err = ParseScenario(file, filepath.Dir(path))
+	if err != nil {
+		return scenario, fmt.Errorf("failed to parse scenario file '%s': %w", path, err)
+	}
+	scenario.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return scenario, nil
+}
+
+// ParseScenario parses scenario steps. Files referenced by steps are loaded immediately,
+// with relative paths resolved against baseDir.
+func ParseScenario(r io.Reader, baseDir string) (scenario Scenario, err error) {
+	scanner := bufio.NewScanner(r)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		step, err := parseScenarioStep(line, baseDir)
+		if err != nil {
+			return scenario, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		step.Line = lineNum
+		scenario.Steps = append(scenario.Steps, step)
+	}
+	if err = scanner.Err(); err != nil {
+		return scenario, err
+	}
+	sort.SliceStable(scenario.Steps, func(i, j int) bool {
+		return scenario.Steps[i].Offset < scenario.Steps[j].Offset
+	})
+	return scenario, nil
+}
+
+func parseScenarioStep(line, baseDir string) (step ScenarioStep, err error) {
+	fields := strings.Fields(line)
+	if !strings.HasPrefix(fields[0], "t=") {
+		return step, fmt.Errorf("step should start with t=<offset>")
+	}
+	step.Offset, err = time.ParseDuration(strings.TrimPrefix(fields[0], "t="))
+	if err != nil {
+		return step, fmt.Errorf("invalid offset: %w", err)
+	}
+	if step.Offset < 0 {
+		return step, fmt.Errorf("negative offset %v", step.Offset)
+	}
+	args := fields[1:]
+	if len(args) == 0 {
+		return step, errors.New("missing action")
+	}
+	step.Action = strings.Join(args, " ")
+	switch args[0] {
+	case "port":
+		if len(args) != 3 || (args[2] != "up" && args[2] != "down") {
+			return step, errors.New("expected: port <logical-label> up|down")
+		}
+		logicalLabel, adminUP := args[1], args[2] == "up"
+		step.mutate = func(netModel *sdnapi.NetworkModel) error {
+			for i := range netModel.Ports {
+				if netModel.Ports[i].LogicalLabel == logicalLabel {
+					netModel.Ports[i].AdminUP = adminUP
+					return nil
+				}
+			}
+			return fmt.Errorf("port %s is not in the network model", logicalLabel)
+		}
+	case "apply":
+		if len(args) != 2 {
+			return step, errors.New("expected: apply <model-file>")
+		}
+		content, err := readScenarioFile(args[1], baseDir)
+		if err != nil {
+			return step, err
+		}
+		if err = json.Unmarshal(content, &sdnapi.NetworkModel{}); err != nil {
+			return step, fmt.Errorf("failed to unmarshal network model from '%s': %w",
+				args[1], err)
+		}
+		step.mutate = func(netModel *sdnapi.NetworkModel) error {
+			// Host config is filled in by Eden, keep the currently applied one.
+			host := netModel.Host
+			*netModel = sdnapi.NetworkModel{}
+			if err := json.Unmarshal(content, netModel); err != nil {
+				return err
+			}
+			netModel.Host = host
+			addMissingMACs(netModel)
+			return nil
+		}
+	case "patch":
+		if len(args) != 2 {
+			return step, errors.New("expected: patch <patch-file>")
+		}
+		content, err := readScenarioFile(args[1], baseDir)
+		if err != nil {
+			return step, err
+		}
+		var patch map[string]interface{}
+		if err = json.Unmarshal(content, &patch); err != nil {
+			return step, fmt.Errorf("failed to unmarshal JSON object from '%s': %w",
+				args[1], err)
+		}
+		step.mutate = func(netModel *sdnapi.NetworkModel) error {
+			return patchNetModel(netModel, patch)
+		}
+	default:
+		return step, fmt.Errorf("unknown action '%s'", args[0])
+	}
+	return step, nil
+}
+
+func readScenarioFile(path, baseDir string) ([]byte, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return content, nil
+}
+
+// patchNetModel applies JSON merge patch (RFC 7386) to the network model.
+// Note that arrays (e.g. list of ports) can only be replaced as a whole.
+func patchNetModel(netModel *sdnapi.NetworkModel, patch map[string]interface{}) error {
+	modelJSON, err := json.Marshal(netModel)
+	if err != nil {
+		return fmt.Errorf("failed to marshal network model: %w", err)
+	}
+	var modelObj interface{}
+	if err = json.Unmarshal(modelJSON, &modelObj); err != nil {
+		return fmt.Errorf("failed to unmarshal network model: %w", err)
+	}
+	modelJSON, err = json.Marshal(mergePatch(modelObj, patch))
+	if err != nil {
+		return fmt.Errorf("failed to marshal patched network model: %w", err)
+	}
+	*netModel = sdnapi.NetworkModel{}
+	if err = json.Unmarshal(modelJSON, netModel); err != nil {
+		return fmt.Errorf("failed to unmarshal patched network model: %w", err)
+	}
+	return nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, isObj := patch.(map[string]interface{})
+	if !isObj {
+		return patch
+	}
+	targetObj, isObj := target.(map[string]interface{})
+	if !isObj {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// NetModelClient : client able to get and apply network model of Eden-SDN.
+// Implemented by SdnClient.
+type NetModelClient interface {
+	GetNetworkModel() (sdnapi.NetworkModel, error)
+	ApplyNetworkModel(netModel sdnapi.NetworkModel) error
+}
+
+var _ NetModelClient = &SdnClient{}
+
+// ScenarioRunner executes scenario steps at their scheduled times, recording
+// what happened when.
+type ScenarioRunner struct {
+	Client NetModelClient
+	// RequiresVMRestart : optional check for network model changes which cannot be
+	// applied without restarting SDN and EVE VMs. Such steps fail.
+	RequiresVMRestart func(oldModel, newModel sdnapi.NetworkModel) bool
+	// OnEvent : optional callback called after every executed step.
+	OnEvent func(event ScenarioEvent)
+}
+
+// ScenarioEvent : record of an executed scenario step.
+type ScenarioEvent struct {
+	// Offset : scheduled time of the step relative to the scenario start.
+	Offset time.Duration `json:"offset"`
+	// Line : line of the scenario file with the step.
+	Line int `json:"line"`
+	// Action : executed action.
+	Action string `json:"action"`
+	// Started : when the step execution started.
+	Started time.Time `json:"started"`
+	// Finished : when the network model was applied (or the step failed).
+	Finished time.Time `json:"finished"`
+	// Error : error message if the step failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ScenarioReport : timeline of an executed scenario.
+type ScenarioReport struct {
+	// Scenario : name of the scenario.
+	Scenario string `json:"scenario"`
+	// Started : when the scenario started (time zero for step offsets).
+	Started time.Time `json:"started"`
+	// Finished : when the scenario finished (or was canceled).
+	Finished time.Time `json:"finished"`
+	// Events : executed steps in the order of execution.
+	Events []ScenarioEvent `json:"events"`
+	// Canceled : true if the scenario was canceled before all steps were executed.
+	Canceled bool `json:"canceled,omitempty"`
+}
+
+// Run executes the scenario. Failed steps do not stop the scenario, but error
+// is returned at the end if any step failed or if the scenario was canceled.
+func (r *ScenarioRunner) Run(ctx context.Context, scenario Scenario) (report ScenarioReport, err error) {
+	report.Scenario = scenario.Name
+	report.Started = time.Now()
+	defer func() {
+		report.Finished = time.Now()
+	}()
+	var failed int
+	for _, step := range scenario.Steps {
+		wait := time.Until(report.Started.Add(step.Offset))
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				report.Canceled = true
+				return report, fmt.Errorf("scenario canceled before step '%s' (line %d): %w",
+					step.Action, step.Line, ctx.Err())
+			case <-timer.C:
+			}
+		}
+		event := ScenarioEvent{
+			Offset:  step.Offset,
+			Line:    step.Line,
+			Action:  step.Action,
+			Started: time.Now(),
+		}
+		if stepErr := r.runStep(step); stepErr != nil {
+			event.Error = stepErr.Error()
+			failed++
+		}
+		event.Finished = time.Now()
+		report.Events = append(report.Events, event)
+		if r.OnEvent != nil {
+			r.OnEvent(event)
+		}
+	}
+	if failed > 0 {
+		return report, fmt.Errorf("%d of %d scenario steps failed", failed, len(scenario.Steps))
+	}
+	return report, nil
+}
+
+func (r *ScenarioRunner) runStep(step ScenarioStep) error {
+	oldModel, err := r.Client.GetNetworkModel()
+	if err != nil {
+		return err
+	}
+	// Mutate a deep copy to keep oldModel intact.
+	modelJSON, err := json.Marshal(oldModel)
+	if err != nil {
+		return fmt.Errorf("failed to marshal network model: %w", err)
+	}
+	var newModel sdnapi.NetworkModel
+	if err = json.Unmarshal(modelJSON, &newModel); err != nil {
+		return fmt.Errorf("failed to unmarshal network model: %w", err)
+	}
+	if err = step.mutate(&newModel); err != nil {
+		return err
+	}
+	if r.RequiresVMRestart != nil && r.RequiresVMRestart(oldModel, newModel) {
+		return errors.New("network model change requires to restart SDN and EVE VMs")
+	}
+	return r.Client.ApplyNetworkModel(newModel)
+}
+
+// WriteText writes the report as a human-readable timeline.
+// Times are in UTC to simplify correlation with EVE logs.
+func (report ScenarioReport) WriteText(w io.Writer) error {
+	const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+	_, err := fmt.Fprintf(w, "Scenario %s: started %s, finished %s\n", report.Scenario,
+		report.Started.UTC().Format(timeFormat), report.Finished.UTC().Format(timeFormat))
+	if err != nil {
+		return err
+	}
+	for _, event := range report.Events {
+		result := "OK"
+		if event.Error != "" {
+			result = "FAILED: " + event.Error
+		}
+		// Delay of the step execution wrt. the scheduled time.
+		delay := event.Started.Sub(report.Started.Add(event.Offset)).Round(time.Millisecond)
+		took := event.Finished.Sub(event.Started).Round(time.Millisecond)
+		_, err = fmt.Fprintf(w, "%s t=%v (+%v) %s: %s (took %v)\n",
+			event.Started.UTC().Format(timeFormat), event.Offset, delay, event.Action,
+			result, took)
+		if err != nil {
+			return err
+		}
+	}
+	if report.Canceled {
+		_, err = fmt.Fprintf(w, "Scenario was canceled\n")
+	}
+	return err
+}
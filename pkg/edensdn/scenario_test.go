@@ -0,0 +1,107 @@
+package edensdn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNetModelClient struct {
+	netModel sdnapi.NetworkModel
+	applied  []sdnapi.NetworkModel
+}
+
+func (c *fakeNetModelClient) GetNetworkModel() (sdnapi.NetworkModel, error) {
+	return c.netModel, nil
+}
+
+func (c *fakeNetModelClient) ApplyNetworkModel(netModel sdnapi.NetworkModel) error {
+	if len(netModel.Ports) == 0 {
+		return errors.New("no ports")
+	}
+	c.netModel = netModel
+	c.applied = append(c.applied, netModel)
+	return nil
+}
+
+func TestScenarioRun(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "proxy.json"),
+		[]byte(`{"endpoints": {"explicitProxies": [{"logicalLabel": "proxy1"}]}}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "model.json"),
+		[]byte(`{"ports": [{"logicalLabel": "eth0", "adminUP": true}]}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "failover.scenario"), []byte(`
+# fail over from eth1 to eth0
+t=20ms port eth1 down
+t=0s   port eth0 up
+t=20ms patch proxy.json
+t=30ms patch proxy.json
+t=40ms port eth2 down
+t=50ms apply model.json
+`), 0644))
+	scenario, err := LoadScenarioFromFile(filepath.Join(dir, "failover.scenario"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "failover", scenario.Name)
+	var lines []int
+	for _, step := range scenario.Steps {
+		lines = append(lines, step.Line)
+	}
+	assert.Equal(t, []int{4, 3, 5, 6, 7, 8}, lines)
+
+	client := &fakeNetModelClient{netModel: sdnapi.NetworkModel{
+		Ports: []sdnapi.Port{
+			{LogicalLabel: "eth0"},
+			{LogicalLabel: "eth1", AdminUP: true},
+		},
+		Host: &sdnapi.HostConfig{ControllerPort: 3333},
+	}}
+	var events int
+	runner := &ScenarioRunner{
+		Client:  client,
+		OnEvent: func(event ScenarioEvent) { events++ },
+	}
+	report, err := runner.Run(context.Background(), scenario)
+	assert.EqualError(t, err, "1 of 6 scenario steps failed")
+	assert.Equal(t, 6, events)
+	assert.Len(t, client.applied, 5)
+	assert.True(t, client.applied[0].Ports[0].AdminUP)
+	assert.False(t, client.applied[1].Ports[1].AdminUP)
+	assert.Len(t, client.applied[2].Endpoints.ExplicitProxies, 1)
+	assert.Len(t, client.applied[2].Ports, 2)
+	assert.Equal(t, "port eth2 is not in the network model", report.Events[4].Error)
+	assert.False(t, report.Events[4].Started.Before(report.Started.Add(scenario.Steps[4].Offset)))
+	assert.Len(t, client.netModel.Ports, 1)
+	assert.NotEmpty(t, client.netModel.Ports[0].MAC)
+	assert.Equal(t, uint16(3333), client.netModel.Host.ControllerPort)
+
+	var text bytes.Buffer
+	assert.NoError(t, report.WriteText(&text))
+	assert.Equal(t, 7, strings.Count(text.String(), "\n"))
+	assert.Contains(t, text.String(), "t=40ms")
+	assert.Contains(t, text.String(), "port eth2 down: FAILED")
+}
+
+func TestScenarioParseErrors(t *testing.T) {
+	for input, expErr := range map[string]string{
+		"port eth0 down":          "line 1: step should start with t=<offset>",
+		"\nt=5 port eth0 down":    "line 2: invalid offset",
+		"t=5s":                    "line 1: missing action",
+		"t=5s port eth0 blink":    "line 1: expected: port <logical-label> up|down",
+		"t=5s reboot":             "line 1: unknown action 'reboot'",
+		"t=5s apply missing.json": "line 1: failed to read file",
+	} {
+		_, err := ParseScenario(strings.NewReader(input), t.TempDir())
+		if assert.Error(t, err, input) {
+			assert.Contains(t, err.Error(), expErr)
+		}
+	}
+}
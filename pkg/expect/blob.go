@@ -0,0 +1,130 @@
+package expect
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/lf-edge/eden/pkg/eden"
+	"github.com/lf-edge/eve/api/go/config"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//defaultS3Region is region of S3 datastore pointed onto EServer
+	defaultS3Region = "us-east-1"
+	//defaultAzureAccount is account of Azure datastore pointed onto EServer without credentials
+	defaultAzureAccount = "eserver"
+)
+
+// blobLocation returns bucket (container) and key (blob name) of s3:// or azure:// appLink,
+// localPath is not empty if key points onto local file to upload into EServer
+func (exp *AppExpectation) blobLocation() (bucket, key, localPath string) {
+	parts := strings.SplitN(exp.appURL, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		log.Fatalf("cannot parse <bucket>/<key> from %s", exp.appURL)
+	}
+	bucket, key = parts[0], parts[1]
+	if fi, err := os.Stat(key); err == nil && fi.Mode().IsRegular() {
+		return bucket, filepath.Base(key), key
+	}
+	return bucket, key, ""
+}
+
+// azureAccount returns account of Azure datastore pointed onto EServer
+func (exp *AppExpectation) azureAccount() string {
+	if exp.ctrl.GetVars().EServerUser != "" {
+		return exp.ctrl.GetVars().EServerUser
+	}
+	return defaultAzureAccount
+}
+
+// blobDataStoreFqdn returns endpoint of S3 or Azure facade of EServer for access from EVE
+func (exp *AppExpectation) blobDataStoreFqdn() string {
+	if exp.appType == azureApp {
+		return fmt.Sprintf("%s/azure/%s", exp.eserverURL(), exp.azureAccount())
+	}
+	return fmt.Sprintf("%s/s3", exp.eserverURL())
+}
+
+// createDataStoreBlob creates datastore, pointed onto EServer S3 or Azure endpoint
+func (exp *AppExpectation) createDataStoreBlob(id uuid.UUID) *config.DatastoreConfig {
+	bucket, _, _ := exp.blobLocation()
+	vars := exp.ctrl.GetVars()
+	ds := &config.DatastoreConfig{
+		Id:    id.String(),
+		Fqdn:  exp.blobDataStoreFqdn(),
+		Dpath: bucket,
+	}
+	switch exp.appType {
+	case s3App:
+		ds.DType = config.DsType_DsS3
+		ds.Region = defaultS3Region
+		ds.ApiKey = vars.EServerUser
+		ds.Password = vars.EServerPassword
+	case azureApp:
+		ds.DType = config.DsType_DsAzureBlob
+		ds.ApiKey = exp.azureAccount()
+		// clients decode account key from base64, EServer signs with password as is
+		if vars.EServerPassword != "" {
+			ds.Password = base64.StdEncoding.EncodeToString([]byte(vars.EServerPassword))
+		}
+	}
+	if exp.datastoreOverride != "" {
+		ds.Fqdn = exp.datastoreOverride
+	}
+	return ds
+}
+
+// checkDataStoreBlob checks if provided ds match expectation
+func (exp *AppExpectation) checkDataStoreBlob(ds *config.DatastoreConfig) bool {
+	bucket, _, _ := exp.blobLocation()
+	dType := config.DsType_DsS3
+	if exp.appType == azureApp {
+		dType = config.DsType_DsAzureBlob
+	}
+	fqdn := exp.blobDataStoreFqdn()
+	if exp.datastoreOverride != "" {
+		fqdn = exp.datastoreOverride
+	}
+	return ds.DType == dType && ds.Fqdn == fqdn && ds.Dpath == bucket
+}
+
+// createImageBlob uploads image into EServer from file if needed and creates Image with key of object in bucket
+func (exp *AppExpectation) createImageBlob(id uuid.UUID, dsID string) *config.Image {
+	server := eden.NewEServer(exp.ctrl.GetVars())
+	bucket, key, localPath := exp.blobLocation()
+	name := fmt.Sprintf("%s/%s", bucket, key)
+	var err error
+	status := server.EServerCheckStatus(name)
+	if localPath != "" {
+		if status, err = eden.AddFileIntoEServer(server, localPath, bucket); err != nil {
+			log.Fatalf("cannot upload %s into eserver: %s", localPath, err)
+		}
+	}
+	if !status.ISReady {
+		log.Fatalf("%s not found in eserver: %s", name, status.Error)
+	}
+	log.Infof("Object %s in bucket %s with size %s and sha256 %s", key, bucket, humanize.Bytes(uint64(status.Size)), status.Sha256)
+	return &config.Image{
+		Uuidandversion: &config.UUIDandVersion{
+			Uuid:    id.String(),
+			Version: "1",
+		},
+		Name:      key,
+		Iformat:   exp.imageFormatEnum(),
+		DsId:      dsID,
+		SizeBytes: status.Size,
+		Sha256:    status.Sha256,
+	}
+}
+
+// checkImageBlob checks if provided img match expectation
+func (exp *AppExpectation) checkImageBlob(img *config.Image, dsID string) bool {
+	_, key, _ := exp.blobLocation()
+	return img.DsId == dsID && img.Name == key
+}
@@ -19,6 +19,8 @@ func (exp *AppExpectation) checkDataStore(ds *config.DatastoreConfig) bool {
 		return exp.checkDataStoreHTTP(ds)
 	case directoryApp:
 		return exp.checkDataStoreDirectory(ds)
+	case s3App, azureApp:
+		return exp.checkDataStoreBlob(ds)
 	}
 	return false
 }
@@ -39,6 +41,8 @@ func (exp *AppExpectation) createDataStore() (*config.DatastoreConfig, error) {
 		return exp.createDataStoreHTTP(id), nil
 	case directoryApp:
 		return exp.createDataStoreDirectory(id), nil
+	case s3App, azureApp:
+		return exp.createDataStoreBlob(id), nil
 	default:
 		return nil, fmt.Errorf("not supported appType")
 	}
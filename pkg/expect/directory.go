@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/lf-edge/eden/pkg/eden"
 	"github.com/lf-edge/eden/pkg/utils"
 	"github.com/lf-edge/eve/api/go/config"
 	uuid "github.com/satori/go.uuid"
@@ -20,7 +21,11 @@ func (exp *AppExpectation) createImageDirectory(id uuid.UUID, dsID string) *conf
 	if err := utils.CreateImage(exp.appURL, tag, exp.ctrl.GetVars().ZArch); err != nil {
 		log.Fatalf("createImageDirectory CreateImage: %v", err)
 	}
-	if _, err := utils.LoadRegistry(tag, fmt.Sprintf("%s:%s", exp.ctrl.GetVars().RegistryIP, exp.ctrl.GetVars().RegistryPort)); err != nil {
+	if exp.ctrl.GetVars().EServerRegistry {
+		if _, err := eden.NewEServer(exp.ctrl.GetVars()).EServerLoadImage(tag); err != nil {
+			log.Fatalf("createImageDirectory EServerLoadImage: %s", err)
+		}
+	} else if _, err := utils.LoadRegistry(tag, fmt.Sprintf("%s:%s", exp.ctrl.GetVars().RegistryIP, exp.ctrl.GetVars().RegistryPort)); err != nil {
 		log.Fatalf("createImageDirectory LoadRegistry: %s", err)
 	}
 	return &config.Image{
@@ -27,6 +27,8 @@ var (
 	httpsApp     appType = 3 //for application with image from https link
 	fileApp      appType = 4 //for application with image from file path
 	directoryApp appType = 5 //for application with files from directory
+	s3App        appType = 6 //for application with image from S3 facade of eserver
+	azureApp     appType = 7 //for application with image from Azure facade of eserver
 )
 
 // ACE is an access control entry (a single entry of ACL).
@@ -226,7 +228,7 @@ func AppExpectationFromURL(ctrl controller.Cloud, device *device.Ctx, appLink st
 	//parse provided appLink to obtain params
 	params := utils.GetParams(appLink, defaults.DefaultPodLinkPattern)
 	if len(params) == 0 {
-		log.Fatalf("fail to parse (oci|docker|http(s)|file|directory)://(<TAG>[:<VERSION>] | <URL> | <PATH>) from argument (%s)", appLink)
+		log.Fatalf("fail to parse (oci|docker|http(s)|file|directory|s3|azure)://(<TAG>[:<VERSION>] | <URL> | <PATH> | <BUCKET>/<KEY>) from argument (%s)", appLink)
 	}
 	expectation.appType = 0
 	expectation.appURL = ""
@@ -247,6 +249,10 @@ func AppExpectationFromURL(ctrl controller.Cloud, device *device.Ctx, appLink st
 		expectation.appType = fileApp
 	case "directory":
 		expectation.appType = directoryApp
+	case "s3":
+		expectation.appType = s3App
+	case "azure":
+		expectation.appType = azureApp
 	case "":
 		expectation.appType = dockerApp
 	default:
@@ -14,10 +14,7 @@ import (
 
 //createImageFile uploads image into EServer from file and calculates size and sha256 of image
 func (exp *AppExpectation) createImageFile(id uuid.UUID, dsID string) *config.Image {
-	server := &eden.EServer{
-		EServerIP:   exp.ctrl.GetVars().EServerIP,
-		EServerPort: exp.ctrl.GetVars().EServerPort,
-	}
+	server := eden.NewEServer(exp.ctrl.GetVars())
 	var fileSize int64
 	sha256 := ""
 	filePath := ""
@@ -3,12 +3,14 @@ package expect
 import (
 	"fmt"
 	"net/url"
+	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/lf-edge/eden/eserver/api"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/eden"
 	"github.com/lf-edge/eve/api/go/config"
@@ -19,10 +21,7 @@ import (
 //createImageHTTP downloads image into EServer directory from http/https endpoint and calculates size and sha256 of image
 func (exp *AppExpectation) createImageHTTP(id uuid.UUID, dsID string) *config.Image {
 	log.Infof("Starting download of image from %s", exp.appLink)
-	server := &eden.EServer{
-		EServerIP:   exp.ctrl.GetVars().EServerIP,
-		EServerPort: exp.ctrl.GetVars().EServerPort,
-	}
+	server := eden.NewEServer(exp.ctrl.GetVars())
 	var fileSize int64
 	sha256 := ""
 	filePath := ""
@@ -30,15 +29,26 @@ func (exp *AppExpectation) createImageHTTP(id uuid.UUID, dsID string) *config.Im
 		sha256 = el.Sha256
 		fileSize = el.Size
 	} else {
-		name := server.EServerAddFileURL(exp.appLink)
+		expectedSha256 := ""
+		if stored {
+			expectedSha256 = el.Sha256
+		}
+		name := server.EServerAddFileURL(exp.appLink, expectedSha256)
 		log.Infof("Start download into eserver of %s", name)
 
 		delayTime := defaults.DefaultRepeatTimeout
 
 		for {
 			status := server.EServerCheckStatus(name)
+			if status.Status == api.FileStatusFailed {
+				log.Fatalf("Download of %s failed after %d attempt(s): %s", exp.appLink, status.Attempts, status.Error)
+			}
 			if !status.ISReady {
-				log.Infof("Downloading... Ready %s", humanize.Bytes(uint64(status.Size)))
+				if status.Total > 0 {
+					log.Infof("Downloading... Ready %s of %s", humanize.Bytes(uint64(status.Size)), humanize.Bytes(uint64(status.Total)))
+				} else {
+					log.Infof("Downloading... Ready %s", humanize.Bytes(uint64(status.Size)))
+				}
 			} else {
 				sha256 = status.Sha256
 				fileSize = status.Size
@@ -89,7 +99,7 @@ func (exp *AppExpectation) checkDataStoreHTTP(ds *config.DatastoreConfig) bool {
 			return true
 		}
 	} else if ds.DType == config.DsType_DsHttp || ds.DType == config.DsType_DsHttps {
-		if !exp.httpDirectLoad && ds.Fqdn == fmt.Sprintf("http://%s:%s", exp.ctrl.GetVars().AdamDomain, exp.ctrl.GetVars().EServerPort) {
+		if !exp.httpDirectLoad && ds.Fqdn == exp.eserverURL() {
 			return true
 		}
 		u, err := url.Parse(exp.appLink)
@@ -144,7 +154,28 @@ func (exp *AppExpectation) createDataStoreHTTP(id uuid.UUID) *config.DatastoreCo
 		}
 		ds.Fqdn = fmt.Sprintf("%s://%s", u.Scheme, u.Hostname())
 	} else {
-		ds.Fqdn = fmt.Sprintf("http://%s:%s", exp.ctrl.GetVars().AdamDomain, exp.ctrl.GetVars().EServerPort)
+		vars := exp.ctrl.GetVars()
+		ds.Fqdn = exp.eserverURL()
+		if vars.EServerTLS {
+			caCert, err := os.ReadFile(vars.AdamCA)
+			if err != nil {
+				log.Fatalf("cannot read CA of eserver: %s", err)
+			}
+			ds.DType = config.DsType_DsHttps
+			ds.DsCertPEM = [][]byte{caCert}
+		}
+		// credentials are encrypted with applyDatastoreCipher if device supports it
+		ds.ApiKey = vars.EServerUser
+		ds.Password = vars.EServerPassword
 	}
 	return ds
 }
+
+//eserverURL returns URL of EServer for access from EVE
+func (exp *AppExpectation) eserverURL() string {
+	scheme := "http"
+	if exp.ctrl.GetVars().EServerTLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%s", scheme, exp.ctrl.GetVars().AdamDomain, exp.ctrl.GetVars().EServerPort)
+}
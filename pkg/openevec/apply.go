@@ -0,0 +1,849 @@
+package openevec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eve/api/go/config"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest declares desired state of edge node for 'eden apply'
+type Manifest struct {
+	Node        string              `yaml:"node"`  // name or UUID of edge node, current one if empty
+	Model       string              `yaml:"model"` // device model
+	BaseOS      *ManifestBaseOS     `yaml:"baseOS"`
+	ConfigItems map[string]string   `yaml:"configItems"`
+	Datastores  []ManifestDatastore `yaml:"datastores"`
+	Networks    []ManifestNetwork   `yaml:"networks"`
+	Volumes     []ManifestVolume    `yaml:"volumes"`
+	Apps        []ManifestApp       `yaml:"apps"`
+}
+
+// ManifestBaseOS declares base OS image of edge node
+type ManifestBaseOS struct {
+	Image    string `yaml:"image"`
+	Version  string `yaml:"version"`
+	Registry string `yaml:"registry"`
+	Activate *bool  `yaml:"activate"` // true if not defined
+	Drive    *bool  `yaml:"drive"`    // true if not defined
+}
+
+// ManifestDatastore declares datastore to use for images of apps and volumes instead of default one
+// EVE receives datastores only as references of content trees, so datastore appears on edge node
+// with the first app or volume which uses it and disappears with the last one;
+// apps and volumes are replaced if url of their datastore changed
+type ManifestDatastore struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// ManifestNetwork declares network instance
+type ManifestNetwork struct {
+	Name      string   `yaml:"name"`
+	Type      string   `yaml:"type"` // local if not defined
+	Subnet    string   `yaml:"subnet"`
+	Uplink    string   `yaml:"uplink"` // eth0 if not defined
+	StaticDNS []string `yaml:"staticDNS"`
+}
+
+// ManifestVolume declares volume
+type ManifestVolume struct {
+	Name      string `yaml:"name"`
+	Image     string `yaml:"image"` // link to image or blank
+	Size      string `yaml:"size"`
+	Format    string `yaml:"format"`
+	Registry  string `yaml:"registry"`
+	Datastore string `yaml:"datastore"` // name of datastore from manifest
+	Sftp      bool   `yaml:"sftp"`
+	Direct    *bool  `yaml:"direct"` // true if not defined
+}
+
+// ManifestApp declares application instance, fields have the same meaning as flags of 'eden pod deploy'
+type ManifestApp struct {
+	Name              string   `yaml:"name"`
+	Image             string   `yaml:"image"`
+	Registry          string   `yaml:"registry"`
+	Datastore         string   `yaml:"datastore"` // name of datastore from manifest
+	Format            string   `yaml:"format"`
+	Memory            string   `yaml:"memory"`
+	CPUs              uint32   `yaml:"cpus"`
+	DiskSize          string   `yaml:"diskSize"`
+	VolumeSize        string   `yaml:"volumeSize"`
+	VolumeType        string   `yaml:"volumeType"`
+	Metadata          string   `yaml:"metadata"`
+	Networks          []string `yaml:"networks"`
+	Ports             []string `yaml:"ports"`
+	ACL               []string `yaml:"acl"`
+	VLANs             []string `yaml:"vlans"`
+	Mounts            []string `yaml:"mounts"`
+	Disks             []string `yaml:"disks"`
+	Profiles          []string `yaml:"profiles"`
+	Adapters          []string `yaml:"adapters"`
+	OnlyHost          bool     `yaml:"onlyHost"`
+	NoHyper           bool     `yaml:"noHyper"`
+	OpenStackMetadata bool     `yaml:"openStackMetadata"`
+	PinCPUs           bool     `yaml:"pinCpus"`
+	VNCDisplay        uint32   `yaml:"vncDisplay"`
+	VNCPassword       string   `yaml:"vncPassword"`
+	StartDelay        uint32   `yaml:"startDelay"`
+	Sftp              bool     `yaml:"sftp"`
+	Direct            *bool    `yaml:"direct"` // true if not defined
+}
+
+func boolOrTrue(b *bool) bool {
+	return b == nil || *b
+}
+
+func stringOr(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// podConfig converts app from manifest into PodConfig with defaults of 'eden pod deploy'
+func (app ManifestApp) podConfig(datastoreOverride string) PodConfig {
+	cpus := app.CPUs
+	if cpus == 0 {
+		cpus = defaults.DefaultAppCPU
+	}
+	return PodConfig{
+		Name:              app.Name,
+		Metadata:          app.Metadata,
+		Registry:          stringOr(app.Registry, "remote"),
+		Networks:          app.Networks,
+		PortPublish:       app.Ports,
+		ACL:               app.ACL,
+		Vlans:             app.VLANs,
+		Mount:             app.Mounts,
+		Disks:             app.Disks,
+		Profiles:          app.Profiles,
+		AppAdapters:       app.Adapters,
+		NoHyper:           app.NoHyper,
+		VncDisplay:        app.VNCDisplay,
+		VncPassword:       app.VNCPassword,
+		DiskSize:          stringOr(app.DiskSize, humanize.Bytes(0)),
+		VolumeSize:        stringOr(app.VolumeSize, humanize.IBytes(defaults.DefaultVolumeSize)),
+		AppMemory:         stringOr(app.Memory, humanize.Bytes(defaults.DefaultAppMem*1024)),
+		VolumeType:        stringOr(app.VolumeType, "qcow2"),
+		AppCpus:           cpus,
+		StartDelay:        app.StartDelay,
+		PinCpus:           app.PinCPUs,
+		ImageFormat:       app.Format,
+		SftpLoad:          app.Sftp,
+		DirectLoad:        boolOrTrue(app.Direct),
+		OpenStackMetadata: app.OpenStackMetadata,
+		DatastoreOverride: datastoreOverride,
+		ACLOnlyHost:       app.OnlyHost,
+	}
+}
+
+// LoadManifest reads manifest from file, '-' means stdin
+func LoadManifest(file string) (*Manifest, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read manifest: %w", err)
+	}
+	var m Manifest
+	if err = yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, fmt.Errorf("cannot parse manifest: %w", err)
+	}
+	if err = m.validate(); err != nil {
+		return nil, fmt.Errorf("wrong manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func (m *Manifest) validate() error {
+	names := map[string]bool{}
+	checkName := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("%s without name", kind)
+		}
+		key := kind + "/" + name
+		if names[key] {
+			return fmt.Errorf("duplicate %s %s", kind, name)
+		}
+		names[key] = true
+		return nil
+	}
+	for _, el := range m.Datastores {
+		if err := checkName("datastore", el.Name); err != nil {
+			return err
+		}
+		if el.URL == "" {
+			return fmt.Errorf("datastore %s without url", el.Name)
+		}
+	}
+	for _, el := range m.Networks {
+		if err := checkName("network", el.Name); err != nil {
+			return err
+		}
+	}
+	for _, el := range m.Volumes {
+		if err := checkName("volume", el.Name); err != nil {
+			return err
+		}
+		if el.Image == "" {
+			return fmt.Errorf("volume %s without image", el.Name)
+		}
+	}
+	for _, el := range m.Apps {
+		if err := checkName("app", el.Name); err != nil {
+			return err
+		}
+		if el.Image == "" {
+			return fmt.Errorf("app %s without image", el.Name)
+		}
+	}
+	if m.BaseOS != nil && m.BaseOS.Image == "" {
+		return fmt.Errorf("baseOS without image")
+	}
+	usedDatastores := map[string]bool{}
+	for _, el := range m.Volumes {
+		usedDatastores[el.Datastore] = true
+	}
+	for _, el := range m.Apps {
+		usedDatastores[el.Datastore] = true
+	}
+	for _, el := range m.Datastores {
+		if !usedDatastores[el.Name] {
+			return fmt.Errorf("datastore %s is not used by any app or volume, "+
+				"datastores are sent to edge node only with images which use them", el.Name)
+		}
+	}
+	return nil
+}
+
+// datastoreURL returns url of datastore with provided name
+func (m *Manifest) datastoreURL(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	for _, el := range m.Datastores {
+		if el.Name == name {
+			return el.URL, nil
+		}
+	}
+	return "", fmt.Errorf("datastore %s not found in manifest", name)
+}
+
+// withDatastore adds url of datastore into spec to replace object if url changed
+func withDatastore(spec interface{}, datastoreURL string) interface{} {
+	if datastoreURL == "" {
+		return spec
+	}
+	return struct {
+		Spec         interface{}
+		DatastoreURL string
+	}{Spec: spec, DatastoreURL: datastoreURL}
+}
+
+// applyAction is a change of edge node required to converge to manifest
+type applyAction struct {
+	op   string // create, replace, adopt, update, delete or unchanged
+	kind string
+	name string
+	do   func() error
+}
+
+func (a applyAction) String() string {
+	return fmt.Sprintf("%s %s %s", a.kind, a.name, a.op)
+}
+
+// applyState keeps hashes of objects applied from manifest to detect their changes
+type applyState map[string]string
+
+func specHash(spec interface{}) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// applyStatePath returns path of file with applyState of device
+func applyStatePath(ctrl controller.Cloud, dev *device.Ctx) string {
+	vars := ctrl.GetVars()
+	if vars == nil || vars.AdamDir == "" {
+		return ""
+	}
+	return filepath.Join(vars.AdamDir, defaults.DefaultApplyStateDir, fmt.Sprintf("%s.json", dev.GetID()))
+}
+
+func loadApplyState(path string) (applyState, error) {
+	state := applyState{}
+	if path == "" {
+		return state, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveApplyState(path string, state applyState) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// namedObjects returns map of display name to ID of objects of device
+// objects are matched with manifest by names, so names must be unique
+func namedObjects(kind string, ids []string, getName func(id string) (string, error)) (map[string]string, error) {
+	res := map[string]string{}
+	for _, id := range ids {
+		name, err := getName(id)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := res[name]; ok {
+			return nil, fmt.Errorf("edge node has several %ss with name %s (%s and %s), "+
+				"remove or rename them before apply", kind, name, other, id)
+		}
+		res[name] = id
+	}
+	return res, nil
+}
+
+func removeID(ids []string, id string) (res []string) {
+	for _, el := range ids {
+		if el != id {
+			res = append(res, el)
+		}
+	}
+	return
+}
+
+// sameImage reports if url of image or content tree in controller was created from link
+// names of images in controller depend on datastore, so only base names are compared
+func sameImage(url, link string) bool {
+	if i := strings.Index(link, "://"); i >= 0 {
+		link = link[i+len("://"):]
+	}
+	base := func(s string) string {
+		return strings.TrimSuffix(path.Base(s), ":latest")
+	}
+	return url != "" && base(url) == base(link)
+}
+
+// staticDNS returns map of hostname to addresses from entries in HOSTNAME:IP[,IP] format
+func staticDNS(entries []string) map[string]string {
+	res := map[string]string{}
+	for _, entry := range entries {
+		mapping := strings.SplitN(entry, ":", 2)
+		if len(mapping) == 2 {
+			res[mapping[0]] = mapping[1]
+		}
+	}
+	return res
+}
+
+// networkMatches reports if network instance in controller matches network from manifest
+func networkMatches(ni *config.NetworkInstanceConfig, network ManifestNetwork) bool {
+	switch stringOr(network.Type, "local") {
+	case "local":
+		if ni.InstType != config.ZNetworkInstType_ZnetInstLocal || ni.GetIp().GetSubnet() != network.Subnet {
+			return false
+		}
+	case "switch":
+		if ni.InstType != config.ZNetworkInstType_ZnetInstSwitch {
+			return false
+		}
+	default:
+		return false
+	}
+	if uplink := stringOr(network.Uplink, "eth0"); uplink == "none" {
+		if ni.Port != nil {
+			return false
+		}
+	} else if ni.GetPort().GetName() != uplink {
+		return false
+	}
+	dns := map[string]string{}
+	for _, el := range ni.Dns {
+		dns[el.HostName] = strings.Join(el.Address, ",")
+	}
+	expected := staticDNS(network.StaticDNS)
+	if len(dns) != len(expected) {
+		return false
+	}
+	for hostname, addresses := range expected {
+		if dns[hostname] != addresses {
+			return false
+		}
+	}
+	return true
+}
+
+// volumeMatches reports if volume in controller matches volume from manifest
+func volumeMatches(ctrl controller.Cloud, volume *config.Volume, el ManifestVolume) bool {
+	size, err := humanize.ParseBytes(stringOr(el.Size, humanize.Bytes(0)))
+	if err != nil || volume.Maxsizebytes != int64(size) {
+		return false
+	}
+	if el.Image == "blank" {
+		return volume.GetOrigin().GetType() == config.VolumeContentOriginType_VCOT_BLANK
+	}
+	contentTree, err := ctrl.GetContentTree(volume.GetOrigin().GetDownloadContentTreeID())
+	return err == nil && sameImage(contentTree.URL, el.Image)
+}
+
+// appMatches reports if app in controller matches app from manifest
+func appMatches(ctrl controller.Cloud, app *config.AppInstanceConfig, el ManifestApp) bool {
+	pc := el.podConfig("")
+	memory, err := humanize.ParseBytes(pc.AppMemory)
+	if err != nil || app.GetFixedresources().GetMemory() != uint32(memory/1000) ||
+		app.GetFixedresources().GetVcpus() != pc.AppCpus {
+		return false
+	}
+	if len(app.Drives) == 0 || !sameImage(app.Drives[0].GetImage().GetName(), el.Image) {
+		return false
+	}
+	if len(el.Networks) == 0 {
+		return true
+	}
+	if len(app.Interfaces) != len(el.Networks) {
+		return false
+	}
+	for i, iface := range app.Interfaces {
+		ni, err := ctrl.GetNetworkInstanceConfig(iface.NetworkId)
+		if err != nil || ni.Displayname != strings.SplitN(el.Networks[i], ":", 2)[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// planManifest returns actions required to converge device to manifest and new state to save after them
+// objects are matched by names, objects are replaced if manifest for them changed since the last apply;
+// objects created outside of apply are adopted if they match manifest and replaced otherwise;
+// with prune objects not defined in manifest are deleted
+func planManifest(ctrl controller.Cloud, dev *device.Ctx, m *Manifest, state applyState, prune bool, cfg *EdenSetupArgs) ([]applyAction, applyState, error) {
+	var actions []applyAction
+	newState := applyState{}
+	// state is modified below, keep the one of caller untouched
+	state = func() applyState {
+		res := applyState{}
+		for k, v := range state {
+			res[k] = v
+		}
+		return res
+	}()
+
+	// plan checks hash of spec and adds action to create, adopt or replace object
+	// matches is used for objects not applied before and may be nil if object cannot be adopted
+	plan := func(kind, name string, spec interface{}, exists bool, matches func() bool, remove, create func() error) (string, error) {
+		key := kind + "/" + name
+		hash, err := specHash(spec)
+		if err != nil {
+			return "", err
+		}
+		newState[key] = hash
+		_, applied := state[key]
+		switch {
+		case !exists:
+			actions = append(actions, applyAction{op: "create", kind: kind, name: name, do: create})
+			return "create", nil
+		case !applied && matches != nil && matches():
+			actions = append(actions, applyAction{op: "adopt", kind: kind, name: name})
+			return "adopt", nil
+		case state[key] != hash:
+			actions = append(actions, applyAction{op: "replace", kind: kind, name: name, do: func() error {
+				if err := remove(); err != nil {
+					return err
+				}
+				return create()
+			}})
+			return "replace", nil
+		default:
+			actions = append(actions, applyAction{op: "unchanged", kind: kind, name: name})
+			return "unchanged", nil
+		}
+	}
+
+	if m.Model != "" {
+		if dev.GetDevModel() != m.Model {
+			actions = append(actions, applyAction{op: "update", kind: "device", name: "model", do: func() error {
+				dev.SetDevModel(m.Model)
+				return nil
+			}})
+		}
+	}
+
+	var configItemKeys []string
+	for k := range m.ConfigItems {
+		configItemKeys = append(configItemKeys, k)
+	}
+	sort.Strings(configItemKeys)
+	for _, k := range configItemKeys {
+		key, val := k, m.ConfigItems[k]
+		newState["configItem/"+key] = val
+		if current, ok := dev.GetConfigItems()[key]; !ok || current != val {
+			actions = append(actions, applyAction{op: "update", kind: "configItem", name: key, do: func() error {
+				dev.SetConfigItem(key, val)
+				return nil
+			}})
+		}
+	}
+	if prune {
+		// remove only config items applied before, the rest ones are defaults of eden
+		var applied []string
+		for k := range state {
+			if strings.HasPrefix(k, "configItem/") {
+				applied = append(applied, strings.TrimPrefix(k, "configItem/"))
+			}
+		}
+		sort.Strings(applied)
+		for _, key := range applied {
+			key := key
+			if _, ok := m.ConfigItems[key]; ok {
+				continue
+			}
+			actions = append(actions, applyAction{op: "delete", kind: "configItem", name: key, do: func() error {
+				delete(dev.GetConfigItems(), key)
+				return nil
+			}})
+		}
+	}
+
+	// base OS is updated in place and never pruned as edge node cannot stay without it
+	if m.BaseOS != nil {
+		baseOS := *m.BaseOS
+		key := "baseOS/" + baseOS.Image
+		hash, err := specHash(baseOS)
+		if err != nil {
+			return nil, nil, err
+		}
+		newState[key] = hash
+		if dev.GetBaseOSContentTree() == "" || state[key] != hash {
+			actions = append(actions, applyAction{op: "update", kind: "baseOS", name: baseOS.Image, do: func() error {
+				eveImageUpdate(ctrl, dev, baseOS.Image, baseOS.Version, stringOr(baseOS.Registry, "remote"),
+					boolOrTrue(baseOS.Activate), boolOrTrue(baseOS.Drive))
+				return nil
+			}})
+		} else {
+			actions = append(actions, applyAction{op: "unchanged", kind: "baseOS", name: baseOS.Image})
+		}
+	}
+
+	apps, err := namedObjects("app", dev.GetApplicationInstances(), func(id string) (string, error) {
+		app, err := ctrl.GetApplicationInstanceConfig(id)
+		if err != nil {
+			return "", fmt.Errorf("no app in cloud %s: %w", id, err)
+		}
+		return app.Displayname, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	volumes, err := namedObjects("volume", dev.GetVolumes(), func(id string) (string, error) {
+		volume, err := ctrl.GetVolume(id)
+		if err != nil {
+			return "", fmt.Errorf("no volume in cloud %s: %w", id, err)
+		}
+		return volume.DisplayName, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	networks, err := namedObjects("network", dev.GetNetworkInstances(), func(id string) (string, error) {
+		ni, err := ctrl.GetNetworkInstanceConfig(id)
+		if err != nil {
+			return "", fmt.Errorf("no network in cloud %s: %w", id, err)
+		}
+		return ni.Displayname, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	declaredVolumes := map[string]bool{}
+	for _, el := range m.Volumes {
+		declaredVolumes[el.Name] = true
+	}
+	declaredApps := map[string]bool{}
+	for _, el := range m.Apps {
+		declaredApps[el.Name] = true
+	}
+	declaredNetworks := map[string]bool{}
+	for _, el := range m.Networks {
+		declaredNetworks[el.Name] = true
+	}
+
+	// appVolumes returns IDs of volumes of app not declared in manifest
+	appVolumes := func(appID string) (ids []string) {
+		app, err := ctrl.GetApplicationInstanceConfig(appID)
+		if err != nil {
+			return nil
+		}
+		for _, ref := range app.VolumeRefList {
+			if volume, err := ctrl.GetVolume(ref.Uuid); err == nil && !declaredVolumes[volume.DisplayName] {
+				ids = append(ids, ref.Uuid)
+			}
+		}
+		return
+	}
+	removeApp := func(appID string) func() error {
+		return func() error {
+			dev.SetApplicationInstanceConfig(removeID(dev.GetApplicationInstances(), appID))
+			volumeIDs := dev.GetVolumes()
+			for _, id := range appVolumes(appID) {
+				volumeIDs = removeID(volumeIDs, id)
+			}
+			dev.SetVolumeConfigs(volumeIDs)
+			return nil
+		}
+	}
+	removeVolume := func(volumeID string) func() error {
+		return func() error {
+			dev.SetVolumeConfigs(removeID(dev.GetVolumes(), volumeID))
+			return nil
+		}
+	}
+	removeNetwork := func(networkID string) func() error {
+		return func() error {
+			dev.SetNetworkInstanceConfig(removeID(dev.GetNetworkInstances(), networkID))
+			return nil
+		}
+	}
+
+	// apps are removed before networks and volumes they use
+	var kept []string
+	if prune {
+		var names []string
+		for name := range apps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !declaredApps[name] {
+				actions = append(actions, applyAction{op: "delete", kind: "app", name: name, do: removeApp(apps[name])})
+			}
+		}
+	}
+
+	replacedNetworks := map[string]bool{}
+	for _, el := range m.Networks {
+		network := el
+		networkType := stringOr(network.Type, "local")
+		id, exists := networks[network.Name]
+		op, err := plan("network", network.Name, network, exists, func() bool {
+			ni, err := ctrl.GetNetworkInstanceConfig(id)
+			return err == nil && networkMatches(ni, network)
+		}, removeNetwork(id), func() error {
+			return networkCreate(ctrl, dev, network.Subnet, networkType, network.Name,
+				stringOr(network.Uplink, "eth0"), network.StaticDNS)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if op == "replace" {
+			replacedNetworks[network.Name] = true
+			if !prune {
+				// apps not defined in manifest are not recreated and would lose their network
+				for name, appID := range apps {
+					if declaredApps[name] {
+						continue
+					}
+					app, err := ctrl.GetApplicationInstanceConfig(appID)
+					if err != nil {
+						return nil, nil, fmt.Errorf("no app in cloud %s: %w", appID, err)
+					}
+					for _, iface := range app.Interfaces {
+						if iface.NetworkId == id {
+							return nil, nil, fmt.Errorf("cannot replace network %s used by app %s not defined in manifest, "+
+								"add app to manifest, remove it or use prune", network.Name, name)
+						}
+					}
+				}
+			}
+		}
+	}
+	if prune {
+		var names []string
+		for name := range networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !declaredNetworks[name] {
+				actions = append(actions, applyAction{op: "delete", kind: "network", name: name, do: removeNetwork(networks[name])})
+			}
+		}
+	}
+
+	for _, el := range m.Volumes {
+		volume := el
+		datastoreURL, err := m.datastoreURL(volume.Datastore)
+		if err != nil {
+			return nil, nil, err
+		}
+		id, exists := volumes[volume.Name]
+		// objects with datastore from manifest are not adopted as images may come from another one
+		var matches func() bool
+		if datastoreURL == "" {
+			matches = func() bool {
+				el, err := ctrl.GetVolume(id)
+				return err == nil && volumeMatches(ctrl, el, volume)
+			}
+		}
+		if _, err := plan("volume", volume.Name, withDatastore(volume, datastoreURL), exists, matches, removeVolume(id), func() error {
+			return volumeCreate(ctrl, dev, volume.Image, stringOr(volume.Registry, "remote"),
+				stringOr(volume.Size, humanize.Bytes(0)), volume.Name, volume.Format, datastoreURL,
+				volume.Sftp, boolOrTrue(volume.Direct))
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, el := range m.Apps {
+		app := el
+		datastoreURL, err := m.datastoreURL(app.Datastore)
+		if err != nil {
+			return nil, nil, err
+		}
+		id, exists := apps[app.Name]
+		key := "app/" + app.Name
+		var matches func() bool
+		if datastoreURL == "" {
+			matches = func() bool {
+				el, err := ctrl.GetApplicationInstanceConfig(id)
+				return err == nil && appMatches(ctrl, el, app)
+			}
+		}
+		for _, network := range app.Networks {
+			// app must be recreated to use new network instance
+			if replacedNetworks[strings.SplitN(network, ":", 2)[0]] {
+				delete(state, key)
+				matches = nil
+			}
+		}
+		op, err := plan("app", app.Name, withDatastore(app, datastoreURL), exists, matches, removeApp(id), func() error {
+			_, err := podDeploy(ctrl, dev, app.Image, app.podConfig(datastoreURL), cfg)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if op == "unchanged" || op == "adopt" {
+			kept = append(kept, id)
+		}
+	}
+
+	if prune {
+		// volumes of apps which stay on device are not pruned
+		used := map[string]bool{}
+		for _, appID := range kept {
+			for _, id := range appVolumes(appID) {
+				used[id] = true
+			}
+		}
+		for name, id := range apps {
+			if !declaredApps[name] {
+				// removed together with app
+				for _, volumeID := range appVolumes(id) {
+					used[volumeID] = true
+				}
+			}
+		}
+		var names []string
+		for name := range volumes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !declaredVolumes[name] && !used[volumes[name]] {
+				actions = append(actions, applyAction{op: "delete", kind: "volume", name: name, do: removeVolume(volumes[name])})
+			}
+		}
+	}
+
+	return actions, newState, nil
+}
+
+// EdenApply converges edge node to the state declared in manifest file
+func EdenApply(file string, dryRun, prune bool, cfg *EdenSetupArgs) error {
+	m, err := LoadManifest(file)
+	if err != nil {
+		return err
+	}
+	changer := &adamChanger{}
+	ctrl, err := changer.getController()
+	if err != nil {
+		return fmt.Errorf("getController: %w", err)
+	}
+	dev, err := ctrl.GetDevice(m.Node)
+	if err != nil {
+		return fmt.Errorf("GetDevice: %w", err)
+	}
+	statePath := applyStatePath(ctrl, dev)
+	state, err := loadApplyState(statePath)
+	if err != nil {
+		return fmt.Errorf("loadApplyState: %w", err)
+	}
+	actions, newState, err := planManifest(ctrl, dev, m, state, prune, cfg)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, action := range actions {
+		if dryRun {
+			fmt.Printf("%s (dry run)\n", action)
+			continue
+		}
+		if action.do != nil {
+			if err = action.do(); err != nil {
+				return fmt.Errorf("%s: %w", action, err)
+			}
+			changed = true
+		}
+		fmt.Println(action)
+	}
+	if dryRun {
+		return nil
+	}
+	if changed {
+		if err = changer.setControllerAndDev(ctrl, dev); err != nil {
+			return fmt.Errorf("setControllerAndDev: %w", err)
+		}
+	} else {
+		log.Info("edge node is up to date")
+	}
+	return saveApplyState(statePath, newState)
+}
@@ -0,0 +1,187 @@
+package openevec
+
+import (
+	"testing"
+
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/fake"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/config"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanManifest(t *testing.T) {
+	ctrl := fake.New()
+	devUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(devUUID, uuid.Must(uuid.NewV4()), ""))
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{AdamDir: t.TempDir(), ZArch: "amd64"})
+	cloud.GetAllNodes()
+	dev, err := cloud.GetDeviceUUID(devUUID)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	apply := func(m *Manifest, prune bool) (ops []string) {
+		path := applyStatePath(cloud, dev)
+		state, err := loadApplyState(path)
+		assert.NoError(t, err)
+		actions, newState, err := planManifest(cloud, dev, m, state, prune, &EdenSetupArgs{})
+		assert.NoError(t, err)
+		for _, action := range actions {
+			if action.do != nil {
+				assert.NoError(t, action.do())
+			}
+			ops = append(ops, action.String())
+		}
+		assert.NoError(t, saveApplyState(path, newState))
+		return ops
+	}
+
+	m := &Manifest{
+		Model:       "ZedVirtual-4G",
+		ConfigItems: map[string]string{"timer.config.interval": "10"},
+		Networks: []ManifestNetwork{
+			{Name: "n1", Subnet: "10.11.12.0/24"},
+			{Name: "n2", Subnet: "10.11.13.0/24"},
+		},
+	}
+	assert.Equal(t, []string{
+		"device model update",
+		"configItem timer.config.interval update",
+		"network n1 create",
+		"network n2 create",
+	}, apply(m, false))
+	assert.Len(t, dev.GetNetworkInstances(), 2)
+
+	// second apply of the same manifest changes nothing
+	assert.Equal(t, []string{"network n1 unchanged", "network n2 unchanged"}, apply(m, false))
+
+	m.ConfigItems = nil
+	m.Networks = []ManifestNetwork{{Name: "n1", Subnet: "10.11.14.0/24"}}
+	assert.Equal(t, []string{
+		"configItem timer.config.interval delete",
+		"network n1 replace",
+		"network n2 delete",
+	}, apply(m, true))
+	if assert.Len(t, dev.GetNetworkInstances(), 1) {
+		ni, err := cloud.GetNetworkInstanceConfig(dev.GetNetworkInstances()[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "10.11.14.0/24", ni.GetIp().GetSubnet())
+	}
+	assert.NotContains(t, dev.GetConfigItems(), "timer.config.interval")
+}
+
+func TestPlanManifestExistingObjects(t *testing.T) {
+	ctrl := fake.New()
+	devUUID := uuid.Must(uuid.NewV4())
+	assert.NoError(t, ctrl.AddDevice(devUUID, uuid.Must(uuid.NewV4()), ""))
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{AdamDir: t.TempDir(), ZArch: "amd64"})
+	cloud.GetAllNodes()
+	dev, err := cloud.GetDeviceUUID(devUUID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ops := func(actions []applyAction) (res []string) {
+		for _, action := range actions {
+			res = append(res, action.String())
+		}
+		return
+	}
+
+	// networks created before the first apply are adopted if they match manifest
+	assert.NoError(t, networkCreate(cloud, dev, "10.11.20.0/24", "local", "n1", "eth0", []string{"host:10.11.20.5"}))
+	assert.NoError(t, networkCreate(cloud, dev, "10.11.21.0/24", "local", "n2", "eth0", nil))
+	m := &Manifest{Networks: []ManifestNetwork{
+		{Name: "n1", Subnet: "10.11.20.0/24", StaticDNS: []string{"host:10.11.20.5"}},
+		{Name: "n2", Subnet: "10.11.22.0/24"},
+	}}
+	actions, newState, err := planManifest(cloud, dev, m, applyState{}, false, &EdenSetupArgs{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"network n1 adopt", "network n2 replace"}, ops(actions))
+	assert.Contains(t, newState, "network/n1")
+
+	// network used by app not defined in manifest is not replaced without prune
+	n2 := dev.GetNetworkInstances()[1]
+	appID := uuid.Must(uuid.NewV4()).String()
+	assert.NoError(t, cloud.AddApplicationInstanceConfig(&config.AppInstanceConfig{
+		Uuidandversion: &config.UUIDandVersion{Uuid: appID, Version: "1"},
+		Displayname:    "a1",
+		Interfaces:     []*config.NetworkAdapter{{NetworkId: n2}},
+	}))
+	dev.SetApplicationInstanceConfig([]string{appID})
+	_, _, err = planManifest(cloud, dev, m, applyState{}, false, &EdenSetupArgs{})
+	assert.Error(t, err)
+	actions, _, err = planManifest(cloud, dev, m, applyState{}, true, &EdenSetupArgs{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app a1 delete", "network n1 adopt", "network n2 replace"}, ops(actions))
+
+	// apps using replaced network are recreated without changes of state of caller
+	m.Apps = []ManifestApp{{Name: "a1", Image: "docker://nginx", Networks: []string{"n2"}}}
+	state := applyState{"network/n2": "old", "app/a1": "old"}
+	actions, _, err = planManifest(cloud, dev, m, state, false, &EdenSetupArgs{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"network n1 adopt", "network n2 replace", "app a1 replace"}, ops(actions))
+	assert.Equal(t, applyState{"network/n2": "old", "app/a1": "old"}, state)
+}
+
+func TestManifestValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		manifest Manifest
+		wantErr  bool
+	}{
+		"valid": {manifest: Manifest{
+			Datastores: []ManifestDatastore{{Name: "mirror", URL: "mirror:5000"}},
+			Apps:       []ManifestApp{{Name: "a1", Image: "docker://nginx", Datastore: "mirror"}},
+		}},
+		"duplicate app": {manifest: Manifest{
+			Apps: []ManifestApp{{Name: "a1", Image: "docker://nginx"}, {Name: "a1", Image: "docker://redis"}},
+		}, wantErr: true},
+		"datastore without url": {manifest: Manifest{
+			Datastores: []ManifestDatastore{{Name: "mirror"}},
+			Apps:       []ManifestApp{{Name: "a1", Image: "docker://nginx", Datastore: "mirror"}},
+		}, wantErr: true},
+		"unused datastore": {manifest: Manifest{
+			Datastores: []ManifestDatastore{{Name: "mirror", URL: "mirror:5000"}},
+		}, wantErr: true},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			err := tc.manifest.validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNamedObjectsDuplicate(t *testing.T) {
+	names := map[string]string{"id1": "app", "id2": "other", "id3": "app"}
+	getName := func(id string) (string, error) { return names[id], nil }
+
+	res, err := namedObjects("app", []string{"id1", "id2"}, getName)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "id1", "other": "id2"}, res)
+
+	_, err = namedObjects("app", []string{"id1", "id2", "id3"}, getName)
+	assert.Error(t, err)
+}
+
+func TestWithDatastore(t *testing.T) {
+	volume := ManifestVolume{Name: "v1", Image: "docker://alpine", Datastore: "mirror"}
+	first, err := specHash(withDatastore(volume, "mirror-1:5000"))
+	assert.NoError(t, err)
+	second, err := specHash(withDatastore(volume, "mirror-2:5000"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	plain, err := specHash(volume)
+	assert.NoError(t, err)
+	same, err := specHash(withDatastore(volume, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, plain, same)
+}
@@ -0,0 +1,154 @@
+package openevec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/attestation"
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/lf-edge/eden/pkg/device"
+	log "github.com/sirupsen/logrus"
+)
+
+func attestPrepare() (controller.Cloud, *device.Ctx, *types.DeviceOptions, error) {
+	ctrl, err := controller.CloudPrepare()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("CloudPrepare error: %w", err)
+	}
+	dev, err := ctrl.GetDeviceCurrent()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("GetDeviceCurrent error: %w", err)
+	}
+	opts, err := ctrl.GetDeviceOptions(dev.GetID())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("GetDeviceOptions error: %w", err)
+	}
+	return ctrl, dev, opts, nil
+}
+
+// EdenAttestEventLog prints TPM event log received from device
+func EdenAttestEventLog() error {
+	_, _, opts, err := attestPrepare()
+	if err != nil {
+		return err
+	}
+	if len(opts.EventLog) == 0 {
+		return fmt.Errorf("device did not send event log yet")
+	}
+	return attestation.PrintEventLog(os.Stdout, opts.EventLog)
+}
+
+// EdenAttestPCRs prints PCR values computed from event log of device
+// and compares them with template received from device
+func EdenAttestPCRs(algo string) error {
+	_, _, opts, err := attestPrepare()
+	if err != nil {
+		return err
+	}
+	hashAlgo, err := attestation.ParseHashAlgo(algo)
+	if err != nil {
+		return err
+	}
+	pcrs, err := attestation.ComputePCRs(opts.EventLog, hashAlgo)
+	if err != nil {
+		return err
+	}
+	template := attestation.TemplateFromPCRs("", "", pcrs, nil)
+	for _, pcr := range template.PCRValues {
+		fmt.Printf("PCR%d: %s\n", pcr.Index, pcr.Value)
+	}
+	if opts.ReceivedPCRTemplate != nil {
+		template.EveVersion = opts.ReceivedPCRTemplate.EveVersion
+		template.FirmwareVersion = opts.ReceivedPCRTemplate.FirmwareVersion
+		for _, mismatch := range attestation.CompareTemplate(template, opts.ReceivedPCRTemplate) {
+			log.Warnf("Event log does not match received template: %s", mismatch)
+		}
+	}
+	return nil
+}
+
+// EdenAttestTemplate generates PCR template from device and prints it, saves it into file
+// or sets it into controller replacing template with the same versions
+func EdenAttestTemplate(pcrs []uint, fromEventLog bool, algo string, apply, enforce bool, file string) error {
+	ctrl, _, opts, err := attestPrepare()
+	if err != nil {
+		return err
+	}
+	hashAlgo, err := attestation.ParseHashAlgo(algo)
+	if err != nil {
+		return err
+	}
+	var indexes []uint32
+	for _, pcr := range pcrs {
+		indexes = append(indexes, uint32(pcr))
+	}
+	template, err := attestation.TemplateFromDevice(opts, indexes, fromEventLog, hashAlgo)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(template, "", "    ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal: %w", err)
+	}
+	if file != "" {
+		if err = os.WriteFile(file, data, 0644); err != nil {
+			return fmt.Errorf("WriteFile: %w", err)
+		}
+	} else {
+		fmt.Println(string(data))
+	}
+	if !apply {
+		return nil
+	}
+	global, err := ctrl.GetGlobalOptions()
+	if err != nil {
+		return fmt.Errorf("GetGlobalOptions error: %w", err)
+	}
+	updated := &types.GlobalOptions{EnforceTemplateAttestation: enforce}
+	for _, el := range global.PCRTemplates {
+		if el.EveVersion != template.EveVersion || el.FirmwareVersion != template.FirmwareVersion {
+			updated.PCRTemplates = append(updated.PCRTemplates, el)
+		}
+	}
+	updated.PCRTemplates = append(updated.PCRTemplates, template)
+	if err = ctrl.SetGlobalOptions(updated); err != nil {
+		return fmt.Errorf("cannot set global options: %w", err)
+	}
+	log.Infof("Template for EVE %s and firmware %s applied", template.EveVersion, template.FirmwareVersion)
+	return nil
+}
+
+// EdenAttestScenario runs attestation scenarios from files against current device
+func EdenAttestScenario(files []string) error {
+	ctrl, err := controller.CloudPrepare()
+	if err != nil {
+		return fmt.Errorf("CloudPrepare error: %w", err)
+	}
+	dev, err := ctrl.GetDeviceCurrent()
+	if err != nil {
+		return fmt.Errorf("GetDeviceCurrent error: %w", err)
+	}
+	failed := 0
+	for _, file := range files {
+		scenarios, err := attestation.LoadScenarios(file)
+		if err != nil {
+			return err
+		}
+		for _, sc := range scenarios {
+			result, err := sc.Run(ctrl, dev.GetID())
+			if err != nil {
+				return fmt.Errorf("scenario %s: %w", sc.Name, err)
+			}
+			fmt.Println(result)
+			if !result.Passed() {
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d scenario(s) failed", failed)
+	}
+	return nil
+}
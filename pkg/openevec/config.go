@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/lf-edge/eden/pkg/controller/archive"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/utils"
 	log "github.com/sirupsen/logrus"
@@ -21,6 +22,13 @@ type EServerConfig struct {
 	Tag    string       `mapstructure:"tag" cobraflag:"eserver-tag"`
 	IP     string       `mapstructure:"ip"`
 	Images ImagesConfig `mapstructure:"images"`
+
+	TLS      bool   `mapstructure:"tls" cobraflag:"eserver-tls"`
+	Token    string `mapstructure:"token"`
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	SignKey  string `mapstructure:"sign-key"`
+	Registry bool   `mapstructure:"registry" cobraflag:"eserver-registry"`
 }
 
 type ImagesConfig struct {
@@ -267,6 +275,50 @@ func SetUpLogs(level string) error {
 	return nil
 }
 
+// SelectNode selects edge node by name or UUID for commands and tests started from this process.
+// If node is defined in one of contexts by name, name of context or UUID, the context is selected as well.
+func SelectNode(node string) error {
+	if node == "" {
+		return nil
+	}
+	if err := os.Setenv(defaults.DefaultNodeEnv, node); err != nil {
+		return err
+	}
+	ctx, err := utils.ContextLoad()
+	if err != nil {
+		return fmt.Errorf("cannot load context: %w", err)
+	}
+	eves, err := ctx.ListEve()
+	if err != nil {
+		log.Debugf("cannot list EVE from contexts: %s", err)
+		return nil
+	}
+	for _, el := range eves {
+		if el.Name == node || el.Context == node || el.UUID == node {
+			return os.Setenv(defaults.DefaultConfigEnv, el.Context)
+		}
+	}
+	return nil
+}
+
+// RecordTelemetry sets archive to record telemetry obtained from controller into
+// it uses env variable to pass archive into tests run by eden
+func RecordTelemetry(archivePath string) error {
+	if archivePath == "" {
+		return nil
+	}
+	absPath, err := filepath.Abs(archivePath)
+	if err != nil {
+		return err
+	}
+	return os.Setenv(defaults.DefaultArchiveEnv, absPath)
+}
+
+// CloseTelemetryArchive closes archives opened to record telemetry in this process
+func CloseTelemetryArchive() error {
+	return archive.CloseShared()
+}
+
 func LoadConfig(configFile string) (*EdenSetupArgs, error) {
 	viperLoaded, err := utils.LoadConfigFile(configFile)
 	if err != nil {
@@ -0,0 +1,40 @@
+package openevec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectNodeSetsEnv(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(defaults.DefaultNodeEnv, "")
+	t.Setenv(defaults.DefaultConfigEnv, defaults.DefaultContext)
+
+	contexts := filepath.Join(home, defaults.DefaultEdenHomeDir, defaults.DefaultContextDirectory)
+	assert.NoError(t, os.MkdirAll(contexts, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(contexts, "default.yml"),
+		[]byte("eve:\n  name: eve-a\n  uuid: 7d2a5c8e-0e7c-4d7a-8f3c-5b9e2f1a0c11\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(contexts, "second.yml"),
+		[]byte("eve:\n  name: eve-b\n  uuid: 2f0b9c44-6d1e-4c55-9a7e-3e8d1c2b4a66\n"), 0644))
+
+	assert.NoError(t, SelectNode(""))
+	assert.Empty(t, os.Getenv(defaults.DefaultNodeEnv))
+	assert.Equal(t, defaults.DefaultContext, os.Getenv(defaults.DefaultConfigEnv))
+
+	// unknown node keeps context
+	assert.NoError(t, SelectNode("node-c"))
+	assert.Equal(t, "node-c", os.Getenv(defaults.DefaultNodeEnv))
+	assert.Equal(t, defaults.DefaultContext, os.Getenv(defaults.DefaultConfigEnv))
+
+	assert.NoError(t, SelectNode("eve-b"))
+	assert.Equal(t, "second", os.Getenv(defaults.DefaultConfigEnv))
+
+	assert.NoError(t, SelectNode("7d2a5c8e-0e7c-4d7a-8f3c-5b9e2f1a0c11"))
+	assert.Equal(t, "7d2a5c8e-0e7c-4d7a-8f3c-5b9e2f1a0c11", os.Getenv(defaults.DefaultNodeEnv))
+	assert.Equal(t, defaults.DefaultContext, os.Getenv(defaults.DefaultConfigEnv))
+}
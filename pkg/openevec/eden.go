@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path"
@@ -19,8 +20,10 @@ import (
 	"github.com/lf-edge/eden/pkg/controller/einfo"
 	"github.com/lf-edge/eden/pkg/controller/elog"
 	"github.com/lf-edge/eden/pkg/controller/emetric"
+	"github.com/lf-edge/eden/pkg/controller/equery"
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/eden"
 	"github.com/lf-edge/eden/pkg/models"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -215,17 +218,18 @@ func setupEve(netboot, installer bool, softSerial, ipxeOverride string, cfg Eden
 		if err := utils.DownloadEveNetBoot(eveDesc, filepath.Dir(cfg.Eve.ImageFile)); err != nil {
 			return fmt.Errorf("cannot download EVE: %w", err)
 		}
-		if err := eden.StartEServer(cfg.Eden.EServer.Port, cfg.Eden.EServer.Images.EServerImageDist, cfg.Eden.EServer.Force, cfg.Eden.EServer.Tag); err != nil {
+		if err := eden.StartEServer(cfg.Eden.EServer.Port, cfg.Eden.EServer.Images.EServerImageDist, cfg.Eden.EServer.Force, cfg.Eden.EServer.Tag, eserverOptions(&cfg)); err != nil {
 			log.Errorf("cannot start eserver: %s", err.Error())
 		} else {
 			log.Infof("Eserver is running and accessible on port %d", cfg.Eden.EServer.Port)
 		}
 		eServerIP := cfg.Adam.CertsEVEIP
 		eServerPort := strconv.Itoa(cfg.Eden.EServer.Port)
-		server := &eden.EServer{
-			EServerIP:   eServerIP,
-			EServerPort: eServerPort,
+		vars, err := InitVarsFromConfig(&cfg)
+		if err != nil {
+			return fmt.Errorf("cannot init vars: %w", err)
 		}
+		server := eden.NewEServer(vars)
 		// we should uncompress kernel for arm64
 		if cfg.Eve.Arch == "arm64" {
 			// rename to temp file
@@ -533,7 +537,10 @@ func EdenClean(cfg EdenSetupArgs, configName, configDist, vmName string, current
 	return nil
 }
 
-func EdenInfo(outputFormat types.OutputFormat, infoTail uint, follow bool, printFields []string, args []string) error {
+func EdenInfo(outputFormat types.OutputFormat, infoTail uint, follow bool, rng types.LoaderRange, where string, printFields []string, args []string) error {
+	if err := checkRangeFlags(infoTail, follow, rng); err != nil {
+		return err
+	}
 	ctrl, err := controller.CloudPrepare()
 	if err != nil {
 		return fmt.Errorf("CloudPrepare: %w", err)
@@ -548,6 +555,9 @@ func EdenInfo(outputFormat types.OutputFormat, infoTail uint, follow bool, print
 		s := strings.Split(a, ":")
 		q[s[0]] = s[1]
 	}
+	if err := addQuery(q, where); err != nil {
+		return err
+	}
 
 	handleInfo := func(im *info.ZInfoMsg) bool {
 		if printFields == nil {
@@ -561,6 +571,12 @@ func EdenInfo(outputFormat types.OutputFormat, infoTail uint, follow bool, print
 		if err = ctrl.InfoChecker(devUUID, q, handleInfo, einfo.InfoTail(infoTail), 0); err != nil {
 			return fmt.Errorf("InfoChecker: %w", err)
 		}
+	} else if !rng.IsEmpty() {
+		cursor, err := ctrl.InfoRange(devUUID, q, handleInfo, rng)
+		if err != nil {
+			return fmt.Errorf("InfoRange: %w", err)
+		}
+		printCursor(cursor)
 	} else {
 		if follow {
 			if err = ctrl.InfoChecker(devUUID, q, handleInfo, einfo.InfoNew, 0); err != nil {
@@ -575,7 +591,10 @@ func EdenInfo(outputFormat types.OutputFormat, infoTail uint, follow bool, print
 	return nil
 }
 
-func EdenLog(outputFormat types.OutputFormat, follow bool, logTail uint, printFields, args []string) error {
+func EdenLog(outputFormat types.OutputFormat, follow bool, logTail uint, rng types.LoaderRange, where string, printFields, args []string) error {
+	if err := checkRangeFlags(logTail, follow, rng); err != nil {
+		return err
+	}
 	ctrl, err := controller.CloudPrepare()
 	if err != nil {
 		return fmt.Errorf("CloudPrepare: %w", err)
@@ -592,6 +611,9 @@ func EdenLog(outputFormat types.OutputFormat, follow bool, logTail uint, printFi
 		s := strings.Split(a, ":")
 		q[s[0]] = s[1]
 	}
+	if err := addQuery(q, where); err != nil {
+		return err
+	}
 
 	handleFunc := func(le *elog.FullLogEntry) bool {
 		if printFields == nil {
@@ -606,6 +628,12 @@ func EdenLog(outputFormat types.OutputFormat, follow bool, logTail uint, printFi
 		if err = ctrl.LogChecker(devUUID, q, handleFunc, elog.LogTail(logTail), 0); err != nil {
 			return fmt.Errorf("LogChecker: %w", err)
 		}
+	} else if !rng.IsEmpty() {
+		cursor, err := ctrl.LogRange(devUUID, q, handleFunc, rng)
+		if err != nil {
+			return fmt.Errorf("LogRange: %w", err)
+		}
+		printCursor(cursor)
 	} else {
 		if follow {
 			// Monitoring of new files
@@ -667,7 +695,10 @@ func EdenNetStat(cfg *EdenSetupArgs, outputFormat types.OutputFormat, follow boo
 	return nil
 }
 
-func EdenMetric(cfg *EdenSetupArgs, outputFormat types.OutputFormat, follow bool, metricTail uint, printFields, args []string) error {
+func EdenMetric(cfg *EdenSetupArgs, outputFormat types.OutputFormat, follow bool, metricTail uint, rng types.LoaderRange, where string, printFields, args []string) error {
+	if err := checkRangeFlags(metricTail, follow, rng); err != nil {
+		return err
+	}
 	ctrl, err := controller.CloudPrepare()
 	if err != nil {
 		return fmt.Errorf("CloudPrepare: %w", err)
@@ -684,6 +715,9 @@ func EdenMetric(cfg *EdenSetupArgs, outputFormat types.OutputFormat, follow bool
 		s := strings.Split(a, ":")
 		q[s[0]] = s[1]
 	}
+	if err := addQuery(q, where); err != nil {
+		return err
+	}
 
 	handleFunc := func(le *metrics.ZMetricMsg) bool {
 		if printFields == nil {
@@ -698,6 +732,12 @@ func EdenMetric(cfg *EdenSetupArgs, outputFormat types.OutputFormat, follow bool
 		if err = ctrl.MetricChecker(devUUID, q, handleFunc, emetric.MetricTail(metricTail), 0); err != nil {
 			return fmt.Errorf("MetricChecker: %w", err)
 		}
+	} else if !rng.IsEmpty() {
+		cursor, err := ctrl.MetricRange(devUUID, q, handleFunc, rng)
+		if err != nil {
+			return fmt.Errorf("MetricRange: %w", err)
+		}
+		printCursor(cursor)
 	} else {
 		if follow {
 			// Monitoring of new files
@@ -713,6 +753,90 @@ func EdenMetric(cfg *EdenSetupArgs, outputFormat types.OutputFormat, follow bool
 	return nil
 }
 
+// EdenMetricExport serves metrics of current device (or all devices) in OpenMetrics format on listen address
+func EdenMetricExport(listen string, allDevices bool, where string, args []string) error {
+	ctrl, err := controller.CloudPrepare()
+	if err != nil {
+		return fmt.Errorf("CloudPrepare: %w", err)
+	}
+	var devices []*device.Ctx
+	if allDevices {
+		devices = ctrl.ListDevices()
+	} else {
+		dev, err := ctrl.GetDeviceCurrent()
+		if err != nil {
+			return fmt.Errorf("GetDeviceCurrent error: %w", err)
+		}
+		devices = append(devices, dev)
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no devices to export metrics from")
+	}
+
+	exporter := emetric.NewExporter()
+	done := make(chan error, len(devices)+1)
+	for _, dev := range devices {
+		q := make(map[string]string)
+		for _, a := range args {
+			s := strings.Split(a, ":")
+			q[s[0]] = s[1]
+		}
+		if err := addQuery(q, where); err != nil {
+			return err
+		}
+		go func(dev *device.Ctx) {
+			// watching of new metrics starts together with processing of existing ones to not miss metrics between them,
+			// checker returns after existing ones are processed and new ones are still watched
+			if err := ctrl.MetricChecker(dev.GetID(), q, exporter.HandleFactory(), emetric.MetricAny, 0); err != nil {
+				done <- fmt.Errorf("MetricChecker for %s: %w", dev.GetID(), err)
+			}
+		}(dev)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter.Handler())
+	go func() {
+		done <- http.ListenAndServe(listen, mux)
+	}()
+	log.Infof("Serving metrics of %d device(s) on http://%s/metrics", len(devices), listen)
+	return <-done
+}
+
+// checkRangeFlags rejects combination of range or cursor with tail or follow modes and of cursor with since
+func checkRangeFlags(tail uint, follow bool, rng types.LoaderRange) error {
+	if rng.IsEmpty() {
+		return nil
+	}
+	if tail > 0 {
+		return fmt.Errorf("--tail cannot be combined with --since, --until or --cursor")
+	}
+	if follow {
+		return fmt.Errorf("--follow cannot be combined with --since, --until or --cursor")
+	}
+	if rng.Validate() != nil {
+		return fmt.Errorf("--cursor cannot be combined with --since")
+	}
+	return nil
+}
+
+// printCursor prints cursor to stderr to not mix it with objects
+// it can be passed with --cursor flag to continue from the last processed object
+func printCursor(cursor string) {
+	fmt.Fprintf(os.Stderr, "cursor: %s\n", cursor)
+}
+
+// addQuery validates expression and adds it into query map of checkers
+func addQuery(q map[string]string, where string) error {
+	if where == "" {
+		return nil
+	}
+	if _, err := equery.Parse(where); err != nil {
+		return fmt.Errorf("cannot parse query %q: %w", where, err)
+	}
+	q[equery.QueryKey] = where
+	return nil
+}
+
 func EdenExport(tarFile string, cfg *EdenSetupArgs) error {
 	changer := &adamChanger{}
 	// we need to obtain information about EVE from Adam
@@ -3,9 +3,11 @@ package openevec
 import (
 	"fmt"
 
+	"github.com/lf-edge/eden/pkg/controller"
 	"github.com/lf-edge/eden/pkg/controller/eflowlog"
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/eve"
 	"github.com/lf-edge/eden/pkg/expect"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -94,17 +96,29 @@ func NetworkNetstat(niName string, outputFormat types.OutputFormat, outputTail u
 }
 
 func NetworkCreate(subnet, networkType, networkName, uplinkAdapter string, staticDNSEntries []string) error {
+	changer := &adamChanger{}
+	ctrl, dev, err := changer.getControllerAndDev()
+	if err != nil {
+		return fmt.Errorf("getControllerAndDev: %w", err)
+	}
+	if err = networkCreate(ctrl, dev, subnet, networkType, networkName, uplinkAdapter, staticDNSEntries); err != nil {
+		return err
+	}
+	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
+		return fmt.Errorf("setControllerAndDev: %w", err)
+	}
+
+	return nil
+}
+
+// networkCreate adds network instance into controller and device without sync of config
+func networkCreate(ctrl controller.Cloud, dev *device.Ctx, subnet, networkType, networkName, uplinkAdapter string, staticDNSEntries []string) error {
 	if networkType != "local" && networkType != "switch" {
 		return fmt.Errorf("network type %s not supported now", networkType)
 	}
 	if networkType == "local" && subnet == "" {
 		return fmt.Errorf("you must define subnet as first arg for local network")
 	}
-	changer := &adamChanger{}
-	ctrl, dev, err := changer.getControllerAndDev()
-	if err != nil {
-		return fmt.Errorf("getControllerAndDev: %w", err)
-	}
 	var opts []expect.ExpectationOption
 	opts = append(opts, expect.AddNetInstanceAndPortPublish(subnet, networkType, networkName, nil, uplinkAdapter))
 	opts = append(opts, expect.WithStaticDNSEntries(networkName, staticDNSEntries))
@@ -121,9 +135,5 @@ mainloop:
 		dev.SetNetworkInstanceConfig(append(dev.GetNetworkInstances(), el.Uuidandversion.Uuid))
 		log.Infof("deploy network %s with name %s request sent", el.Uuidandversion.Uuid, el.Displayname)
 	}
-	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
-		return fmt.Errorf("setControllerAndDev: %w", err)
-	}
-
 	return nil
 }
@@ -7,6 +7,8 @@ import (
 
 	"github.com/docker/docker/pkg/namesgenerator"
 	"github.com/dustin/go-humanize"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/eve"
 	"github.com/lf-edge/eden/pkg/expect"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -41,6 +43,17 @@ func VolumeCreate(appLink, registry, diskSize, volumeName, volumeType, datastore
 	if err != nil {
 		return fmt.Errorf("getControllerAndDev: %w", err)
 	}
+	if err = volumeCreate(ctrl, dev, appLink, registry, diskSize, volumeName, volumeType, datastoreOverride, sftpLoad, directLoad); err != nil {
+		return err
+	}
+	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
+		return fmt.Errorf("setControllerAndDev: %w", err)
+	}
+	return nil
+}
+
+// volumeCreate adds volume into controller and device without sync of config
+func volumeCreate(ctrl controller.Cloud, dev *device.Ctx, appLink, registry, diskSize, volumeName, volumeType, datastoreOverride string, sftpLoad, directLoad bool) error {
 	var opts []expect.ExpectationOption
 	diskSizeParsed, err := humanize.ParseBytes(diskSize)
 	if err != nil {
@@ -91,9 +104,6 @@ func VolumeCreate(appLink, registry, diskSize, volumeName, volumeType, datastore
 		volumeConfig := expectation.Volume()
 		log.Infof("create volume %s with %s request sent", volumeConfig.DisplayName, appLink)
 	}
-	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
-		return fmt.Errorf("setControllerAndDev: %w", err)
-	}
 	return nil
 }
 
@@ -0,0 +1,22 @@
+package openevec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/pkg/controller/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRangeFlags(t *testing.T) {
+	since := types.LoaderRange{Since: time.Now()}
+	cursor := types.LoaderRange{Cursor: "1-0"}
+	assert.NoError(t, checkRangeFlags(10, false, types.LoaderRange{}))
+	assert.NoError(t, checkRangeFlags(0, true, types.LoaderRange{}))
+	assert.NoError(t, checkRangeFlags(0, false, since))
+	assert.Error(t, checkRangeFlags(10, false, since))
+	assert.Error(t, checkRangeFlags(10, false, cursor))
+	assert.Error(t, checkRangeFlags(0, true, cursor))
+	assert.Error(t, checkRangeFlags(0, false, types.LoaderRange{Since: time.Now(), Cursor: "1-0"}))
+	assert.NoError(t, checkRangeFlags(0, false, types.LoaderRange{Until: time.Now(), Cursor: "1-0"}))
+}
@@ -10,19 +10,58 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/configdiff"
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/expect"
 	"github.com/lf-edge/eden/pkg/utils"
 	"github.com/lf-edge/eve/api/go/config"
+	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
+func EdgeNodeList() error {
+	ctrl, err := controller.CloudPrepare()
+	if err != nil {
+		return fmt.Errorf("CloudPrepare: %w", err)
+	}
+	var current uuid.UUID
+	if dev, err := ctrl.GetDeviceCurrent(); err == nil {
+		current = dev.GetID()
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "NAME\tUUID\tSTATE\tCURRENT"); err != nil {
+		return err
+	}
+	for _, dev := range ctrl.ListDevices() {
+		state := "onboarded"
+		if dev.GetState() == device.NotOnboarded {
+			state = "not onboarded"
+		}
+		name := dev.GetName()
+		if name == "" {
+			name = "-"
+		}
+		isCurrent := ""
+		if uuid.Equal(dev.GetID(), current) {
+			isCurrent = "*"
+		}
+		if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, dev.GetID(), state, isCurrent); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
 func EdgeNodeReboot(controllerMode string) error {
 	changer, err := changerByControllerMode(controllerMode)
 	if err != nil {
@@ -61,8 +100,6 @@ func EdgeNodeShutdown(controllerMode string) error {
 
 func EdgeNodeEVEImageUpdate(baseOSImage, baseOSVersion, registry, controllerMode string,
 	baseOSImageActivate, baseOSVDrive bool) error {
-
-	var opts []expect.ExpectationOption
 	changer, err := changerByControllerMode(controllerMode)
 	if err != nil {
 		return err
@@ -71,6 +108,17 @@ func EdgeNodeEVEImageUpdate(baseOSImage, baseOSVersion, registry, controllerMode
 	if err != nil {
 		return fmt.Errorf("getControllerAndDev error: %w", err)
 	}
+	eveImageUpdate(ctrl, dev, baseOSImage, baseOSVersion, registry, baseOSImageActivate, baseOSVDrive)
+	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
+		return fmt.Errorf("setControllerAndDev: %w", err)
+	}
+	return nil
+}
+
+// eveImageUpdate sets base OS image for device without sync of config
+func eveImageUpdate(ctrl controller.Cloud, dev *device.Ctx, baseOSImage, baseOSVersion, registry string,
+	baseOSImageActivate, baseOSVDrive bool) {
+	var opts []expect.ExpectationOption
 	registryToUse := registry
 	switch registry {
 	case "local":
@@ -90,11 +138,6 @@ func EdgeNodeEVEImageUpdate(baseOSImage, baseOSVersion, registry, controllerMode
 	dev.SetBaseOSContentTree(baseOS.ContentTreeUuid)
 	dev.SetBaseOSRetryCounter(0)
 	dev.SetBaseOSVersion(baseOS.BaseOsVersion)
-
-	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
-		return fmt.Errorf("setControllerAndDev: %w", err)
-	}
-	return nil
 }
 
 func EdgeNodeEVEImageUpdateRetry(controllerMode string) error {
@@ -272,6 +315,78 @@ func EdgeNodeGetConfig(controllerMode, fileWithConfig string) error {
 	return nil
 }
 
+// EdgeNodeConfigHistory prints versions of configs submitted for the current edge node
+func EdgeNodeConfigHistory() error {
+	ctrl, err := controller.CloudPrepare()
+	if err != nil {
+		return fmt.Errorf("CloudPrepare: %w", err)
+	}
+	dev, err := ctrl.GetDeviceCurrent()
+	if err != nil {
+		return fmt.Errorf("GetDeviceCurrent error: %w", err)
+	}
+	history, err := ctrl.ConfigHistory(dev.GetID())
+	if err != nil {
+		return fmt.Errorf("ConfigHistory: %w", err)
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "VERSION\tSUBMITTED"); err != nil {
+		return err
+	}
+	for _, el := range history {
+		if _, err = fmt.Fprintf(w, "%s\t%s\n", el.Version, el.Submitted.Format(time.RFC3339)); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// EdgeNodeConfigDiff prints changes between versions of configs of the current edge node
+// without versions it compares two latest configs, with one version it compares it with the latest config
+func EdgeNodeConfigDiff(versions []string) error {
+	ctrl, err := controller.CloudPrepare()
+	if err != nil {
+		return fmt.Errorf("CloudPrepare: %w", err)
+	}
+	dev, err := ctrl.GetDeviceCurrent()
+	if err != nil {
+		return fmt.Errorf("GetDeviceCurrent error: %w", err)
+	}
+	if len(versions) < 2 {
+		history, err := ctrl.ConfigHistory(dev.GetID())
+		if err != nil {
+			return fmt.Errorf("ConfigHistory: %w", err)
+		}
+		if len(history) == 0 || len(history) < 2-len(versions) {
+			return fmt.Errorf("not enough configs in history of device %s", dev.GetID())
+		}
+		latest := history[len(history)-1].Version
+		if len(versions) == 0 {
+			versions = []string{history[len(history)-2].Version, latest}
+		} else {
+			versions = append(versions, latest)
+		}
+	}
+	oldConfig, err := ctrl.ConfigHistoryGet(dev.GetID(), versions[0])
+	if err != nil {
+		return err
+	}
+	newConfig, err := ctrl.ConfigHistoryGet(dev.GetID(), versions[1])
+	if err != nil {
+		return err
+	}
+	changes := configdiff.Diff(oldConfig, newConfig)
+	if len(changes) == 0 {
+		fmt.Printf("no changes between versions %s and %s\n", versions[0], versions[1])
+		return nil
+	}
+	for _, el := range changes {
+		fmt.Println(el)
+	}
+	return nil
+}
+
 func EdgeNodeSetConfig(fileWithConfig string) error {
 	ctrl, err := controller.CloudPrepare()
 	if err != nil {
@@ -0,0 +1,379 @@
+package openevec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/lf-edge/eden/eserver/api"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/eden"
+	"github.com/lf-edge/eden/pkg/utils"
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	log "github.com/sirupsen/logrus"
+)
+
+func eserverOptions(cfg *EdenSetupArgs) *eden.EServerOptions {
+	return &eden.EServerOptions{
+		TLS:      cfg.Eden.EServer.TLS,
+		Token:    cfg.Eden.EServer.Token,
+		User:     cfg.Eden.EServer.User,
+		Password: cfg.Eden.EServer.Password,
+		SignKey:  cfg.Eden.EServer.SignKey,
+		Registry: cfg.Eden.EServer.Registry,
+	}
+}
+
+func eserverClient(cfg *EdenSetupArgs) (*eden.EServer, error) {
+	edenHome, err := utils.DefaultEdenDir()
+	if err != nil {
+		return nil, err
+	}
+	return &eden.EServer{
+		EServerIP:   cfg.Eden.EServer.IP,
+		EServerPort: strconv.Itoa(cfg.Eden.EServer.Port),
+		TLS:         cfg.Eden.EServer.TLS,
+		CA:          filepath.Join(edenHome, defaults.DefaultCertsDist, "root-certificate.pem"),
+		Token:       cfg.Eden.EServer.Token,
+	}, nil
+}
+
+// setEserverAccess informs Eden-SDN how to download files from eserver.
+func setEserverAccess(host *sdnapi.HostConfig, cfg *EdenSetupArgs) error {
+	host.EserverPort = uint16(cfg.Eden.EServer.Port)
+	host.EserverTLS = cfg.Eden.EServer.TLS
+	host.EserverUser = cfg.Eden.EServer.User
+	host.EserverPassword = cfg.Eden.EServer.Password
+	host.EserverCACertPEM = ""
+	if cfg.Eden.EServer.TLS {
+		edenHome, err := utils.DefaultEdenDir()
+		if err != nil {
+			return err
+		}
+		caPath := filepath.Join(edenHome, defaults.DefaultCertsDist, "root-certificate.pem")
+		caCert, err := os.ReadFile(caPath)
+		if err != nil {
+			return fmt.Errorf("failed to read eserver CA certificate %s: %w", caPath, err)
+		}
+		host.EserverCACertPEM = string(caCert)
+	}
+	return nil
+}
+
+func shortSha(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// EServerList prints files stored in eserver
+func EServerList(cfg *EdenSetupArgs) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	files, err := server.EServerListFiles()
+	if err != nil {
+		return fmt.Errorf("cannot list files: %w", err)
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "NAME\tSHA256\tSIZE\tPINNED\tLAST ACCESS"); err != nil {
+		return err
+	}
+	var total int64
+	seen := map[string]bool{}
+	for _, el := range files {
+		pinned := ""
+		if el.Pinned {
+			pinned = "*"
+		}
+		name := el.Name
+		if name == "" {
+			name = "-"
+		}
+		if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, shortSha(el.Sha256),
+			humanize.IBytes(uint64(el.Size)), pinned, humanize.Time(el.LastAccess)); err != nil {
+			return err
+		}
+		if !seen[el.Sha256] {
+			seen[el.Sha256] = true
+			total += el.Size
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("Total: %s\n", humanize.IBytes(uint64(total)))
+	return nil
+}
+
+// EServerRemove removes files from eserver by names or all names of content with sha256
+func EServerRemove(cfg *EdenSetupArgs, names []string, sha256 string) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	var args []*api.DeleteArg
+	for _, name := range names {
+		args = append(args, &api.DeleteArg{Name: name})
+	}
+	if sha256 != "" {
+		args = append(args, &api.DeleteArg{Sha256: sha256})
+	}
+	for _, arg := range args {
+		removed, err := server.EServerDelete(arg)
+		if err != nil {
+			return fmt.Errorf("cannot delete %s%s: %w", arg.Name, arg.Sha256, err)
+		}
+		for _, el := range removed {
+			log.Infof("Removed %s (%s)", el.Name, shortSha(el.Sha256))
+		}
+	}
+	return nil
+}
+
+// EServerPin protects content of files in eserver from garbage collection or removes protection
+func EServerPin(cfg *EdenSetupArgs, names []string, pinned bool) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := server.EServerPin(name, pinned); err != nil {
+			return fmt.Errorf("cannot pin %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// EServerGC runs garbage collection in eserver, maxSize is human-readable size limit
+func EServerGC(cfg *EdenSetupArgs, maxSize string, partial, dryRun bool) error {
+	arg := &api.GCArg{Partial: partial, DryRun: dryRun}
+	if maxSize != "" {
+		size, err := humanize.ParseBytes(maxSize)
+		if err != nil {
+			return fmt.Errorf("cannot parse max size: %w", err)
+		}
+		arg.MaxSize = int64(size)
+	}
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	result, err := server.EServerGC(arg)
+	if err != nil {
+		return fmt.Errorf("cannot collect garbage: %w", err)
+	}
+	action := "Removed"
+	if dryRun {
+		action = "Will remove"
+	}
+	for _, el := range result.Removed {
+		name := el.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Printf("%s %s (%s, %s)\n", action, name, shortSha(el.Sha256), humanize.IBytes(uint64(el.Size)))
+	}
+	fmt.Printf("Freed: %s, total: %s\n", humanize.IBytes(uint64(result.Freed)), humanize.IBytes(uint64(result.Total)))
+	return nil
+}
+
+// EServerSign prints URL of file in eserver signed for expires duration
+func EServerSign(cfg *EdenSetupArgs, name string, expires time.Duration) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	signed, err := server.EServerSignURL(name, expires)
+	if err != nil {
+		return fmt.Errorf("cannot sign %s: %w", name, err)
+	}
+	fmt.Println(signed)
+	return nil
+}
+
+// EServerFaultArgs contains settings of fault injected into downloads from eserver,
+// sizes are human-readable (e.g. 10MB)
+type EServerFaultArgs struct {
+	Rate       string
+	Stall      bool
+	StallAfter string
+	StallFor   time.Duration
+	DropAfter  string
+	Status     int
+	Corrupt    bool
+	Every      int
+	Count      int
+}
+
+func parseSize(name, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, err := humanize.ParseBytes(value)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %w", name, err)
+	}
+	return int64(size), nil
+}
+
+// EServerFaultSet injects fault into downloads of files from eserver matched name pattern
+func EServerFaultSet(cfg *EdenSetupArgs, name string, args *EServerFaultArgs) error {
+	// stall of 0 lasts until client disconnects, so do not round short stalls down to it
+	if args.StallFor < 0 || args.StallFor%time.Millisecond != 0 {
+		return fmt.Errorf("stall-for must be a non-negative whole number of milliseconds, got %s", args.StallFor)
+	}
+	fault := &api.Fault{
+		Name:       name,
+		Stall:      args.Stall || args.StallAfter != "" || args.StallFor > 0,
+		StallForMs: args.StallFor.Milliseconds(),
+		Status:     args.Status,
+		Corrupt:    args.Corrupt,
+		Every:      args.Every,
+		Count:      args.Count,
+	}
+	var err error
+	if fault.Rate, err = parseSize("rate", args.Rate); err != nil {
+		return err
+	}
+	if fault.StallAfter, err = parseSize("stall-after", args.StallAfter); err != nil {
+		return err
+	}
+	if fault.DropAfter, err = parseSize("drop-after", args.DropAfter); err != nil {
+		return err
+	}
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	if err := server.EServerSetFault(fault); err != nil {
+		return fmt.Errorf("cannot set fault for %s: %w", name, err)
+	}
+	return nil
+}
+
+// EServerFaultClear removes fault by name or all faults if name is empty
+func EServerFaultClear(cfg *EdenSetupArgs, name string) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	if err := server.EServerClearFaults(name); err != nil {
+		return fmt.Errorf("cannot clear faults: %w", err)
+	}
+	return nil
+}
+
+func faultDescription(fault *api.Fault) string {
+	var result []string
+	if fault.Status != 0 {
+		result = append(result, fmt.Sprintf("status %d", fault.Status))
+	}
+	if fault.Rate > 0 {
+		result = append(result, fmt.Sprintf("rate %s/s", humanize.IBytes(uint64(fault.Rate))))
+	}
+	if fault.Stall {
+		stallFor := "until disconnect"
+		if fault.StallForMs > 0 {
+			stallFor = "for " + (time.Duration(fault.StallForMs) * time.Millisecond).String()
+		}
+		result = append(result, fmt.Sprintf("stall after %s %s", humanize.IBytes(uint64(fault.StallAfter)), stallFor))
+	}
+	if fault.DropAfter > 0 {
+		result = append(result, fmt.Sprintf("drop after %s", humanize.IBytes(uint64(fault.DropAfter))))
+	}
+	if fault.Corrupt {
+		result = append(result, "corrupt")
+	}
+	if fault.Every > 1 {
+		result = append(result, fmt.Sprintf("every %d", fault.Every))
+	}
+	if fault.Count > 0 {
+		result = append(result, fmt.Sprintf("count %d", fault.Count))
+	}
+	return strings.Join(result, ", ")
+}
+
+// EServerFaultList prints faults injected into downloads from eserver
+func EServerFaultList(cfg *EdenSetupArgs) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	faults, err := server.EServerListFaults()
+	if err != nil {
+		return fmt.Errorf("cannot list faults: %w", err)
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "NAME\tFAULT\tREQUESTS\tINJECTED"); err != nil {
+		return err
+	}
+	for _, el := range faults {
+		if _, err = fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", el.Name, faultDescription(el), el.Requests, el.Injected); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// EServerOCIImport imports images from archive into registry of eserver
+func EServerOCIImport(cfg *EdenSetupArgs, archive, name string) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	images, err := server.EServerImportImage(archive, name)
+	if err != nil {
+		return fmt.Errorf("cannot import %s: %w", archive, err)
+	}
+	for _, el := range images {
+		fmt.Printf("%s:%s %s\n", el.Repository, el.Tag, el.Digest)
+	}
+	return nil
+}
+
+// EServerOCILoad loads local or remote image into registry of eserver
+func EServerOCILoad(cfg *EdenSetupArgs, image string) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	digest, err := server.EServerLoadImage(image)
+	if err != nil {
+		return fmt.Errorf("cannot load %s: %w", image, err)
+	}
+	fmt.Printf("image %s loaded with manifest hash %s\n", image, digest)
+	return nil
+}
+
+// EServerOCIList prints images stored in registry of eserver
+func EServerOCIList(cfg *EdenSetupArgs) error {
+	server, err := eserverClient(cfg)
+	if err != nil {
+		return err
+	}
+	images, err := server.EServerListImages()
+	if err != nil {
+		return fmt.Errorf("cannot list images: %w", err)
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "REPOSITORY\tTAG\tDIGEST"); err != nil {
+		return err
+	}
+	for _, el := range images {
+		if _, err = fmt.Fprintf(w, "%s\t%s\t%s\n", el.Repository, el.Tag, el.Digest); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,21 @@
+package openevec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/eden/eserver/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEServerFaultSetStallFor(t *testing.T) {
+	for _, stallFor := range []time.Duration{-time.Second, 1500 * time.Microsecond} {
+		err := EServerFaultSet(&EdenSetupArgs{}, "file", &EServerFaultArgs{StallFor: stallFor})
+		assert.ErrorContains(t, err, "stall-for", "stall-for %s", stallFor)
+	}
+}
+
+func TestFaultDescriptionStallFor(t *testing.T) {
+	assert.Equal(t, "stall after 0 B for 500ms", faultDescription(&api.Fault{Stall: true, StallForMs: 500}))
+	assert.Equal(t, "stall after 1.0 KiB until disconnect", faultDescription(&api.Fault{Stall: true, StallAfter: 1024}))
+}
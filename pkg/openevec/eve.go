@@ -72,6 +72,9 @@ func StartEveQemu(tapInterface string, cfg *EdenSetupArgs) error {
 		// than Adam is being used.
 		netModel.Host.ControllerPort = 443
 	}
+	if err = setEserverAccess(netModel.Host, cfg); err != nil {
+		return err
+	}
 	if isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
 		nets, err := utils.GetSubnetsNotUsed(1)
 		if err != nil {
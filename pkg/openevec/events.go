@@ -0,0 +1,81 @@
+package openevec
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/eevent"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/device"
+	log "github.com/sirupsen/logrus"
+)
+
+// EdenEvents derives events from info of devices and delivers them to sinks
+// defined in rulesFile, webhooks and sockets; events are printed to stdout as well
+func EdenEvents(rulesFile string, webhooks, sockets, types []string, allDevices bool) error {
+	var rules []eevent.Rule
+	if rulesFile != "" {
+		loaded, err := eevent.LoadRules(rulesFile)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, loaded...)
+	}
+	for _, webhook := range webhooks {
+		rules = append(rules, eevent.Rule{Events: types, Webhook: webhook})
+	}
+	for _, socket := range sockets {
+		rules = append(rules, eevent.Rule{Events: types, Socket: socket})
+	}
+	bus := eevent.NewBus()
+	defer bus.Close()
+	for _, rule := range rules {
+		if err := bus.AddRule(rule); err != nil {
+			return err
+		}
+	}
+	match := eevent.Match{}
+	for _, el := range types {
+		t, err := eevent.ParseType(el)
+		if err != nil {
+			return err
+		}
+		match.Types = append(match.Types, t)
+	}
+	bus.Subscribe(match, eevent.SinkFunc(func(ev *eevent.Event) error {
+		_, err := fmt.Fprintln(os.Stdout, ev)
+		return err
+	}))
+
+	ctrl, err := controller.CloudPrepare()
+	if err != nil {
+		return fmt.Errorf("CloudPrepare: %w", err)
+	}
+	var devices []*device.Ctx
+	if allDevices {
+		devices = ctrl.ListDevices()
+	} else {
+		dev, err := ctrl.GetDeviceCurrent()
+		if err != nil {
+			return fmt.Errorf("GetDeviceCurrent error: %w", err)
+		}
+		devices = append(devices, dev)
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no devices to watch events from")
+	}
+
+	tracker := eevent.NewTracker()
+	done := make(chan error, len(devices))
+	for _, dev := range devices {
+		go func(dev *device.Ctx) {
+			q := make(map[string]string)
+			if err := ctrl.InfoChecker(dev.GetID(), q, tracker.Handler(bus.Publish), einfo.InfoNew, 0); err != nil {
+				done <- fmt.Errorf("InfoChecker for %s: %w", dev.GetID(), err)
+			}
+		}(dev)
+	}
+	log.Infof("Watching events of %d device(s) with %d rule(s)", len(devices), len(rules))
+	return <-done
+}
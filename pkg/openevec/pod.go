@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/dustin/go-humanize"
+	"github.com/lf-edge/eden/pkg/controller"
 	"github.com/lf-edge/eden/pkg/controller/eapps"
 	"github.com/lf-edge/eden/pkg/controller/eflowlog"
 	"github.com/lf-edge/eden/pkg/controller/einfo"
@@ -16,6 +17,7 @@ import (
 	"github.com/lf-edge/eden/pkg/controller/emetric"
 	"github.com/lf-edge/eden/pkg/controller/types"
 	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/eve"
 	"github.com/lf-edge/eden/pkg/expect"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -72,6 +74,19 @@ func PodDeploy(appLink string, pc PodConfig, cfg *EdenSetupArgs) error {
 	if err != nil {
 		return fmt.Errorf("getControllerAndDev: %w", err)
 	}
+	appInstanceConfig, err := podDeploy(ctrl, dev, appLink, pc, cfg)
+	if err != nil {
+		return err
+	}
+	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
+		return fmt.Errorf("setControllerAndDev: %w", err)
+	}
+	log.Infof("deploy pod %s with %s request sent", appInstanceConfig.Displayname, appLink)
+	return nil
+}
+
+// podDeploy adds app defined by appLink and pc into controller and device without sync of config
+func podDeploy(ctrl controller.Cloud, dev *device.Ctx, appLink string, pc PodConfig, cfg *EdenSetupArgs) (*config.AppInstanceConfig, error) {
 	var opts []expect.ExpectationOption
 	opts = append(opts, expect.WithMetadata(pc.Metadata))
 	opts = append(opts, expect.WithVnc(pc.VncDisplay))
@@ -91,17 +106,17 @@ func PodDeploy(appLink string, pc PodConfig, cfg *EdenSetupArgs) error {
 	}
 	diskSizeParsed, err := humanize.ParseBytes(pc.DiskSize)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	opts = append(opts, expect.WithDiskSize(int64(diskSizeParsed)))
 	volumeSizeParsed, err := humanize.ParseBytes(pc.VolumeSize)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	opts = append(opts, expect.WithVolumeSize(int64(volumeSizeParsed)))
 	appMemoryParsed, err := humanize.ParseBytes(pc.AppMemory)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	opts = append(opts, expect.WithVolumeType(expect.VolumeTypeByName(pc.VolumeType)))
 	opts = append(opts, expect.WithResources(pc.AppCpus, uint32(appMemoryParsed/1000)))
@@ -115,7 +130,7 @@ func PodDeploy(appLink string, pc PodConfig, cfg *EdenSetupArgs) error {
 	}
 	vlansParsed, err := processVLANs(pc.Vlans)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	opts = append(opts, expect.WithVLANs(vlansParsed))
 	opts = append(opts, expect.WithSFTPLoad(pc.SftpLoad))
@@ -126,7 +141,7 @@ func PodDeploy(appLink string, pc PodConfig, cfg *EdenSetupArgs) error {
 	registryToUse := pc.Registry
 	switch pc.Registry {
 	case "local":
-		registryToUse = fmt.Sprintf("%s:%d", cfg.Registry.IP, cfg.Registry.Port)
+		registryToUse = localRegistry(cfg)
 	case "remote":
 		registryToUse = ""
 	}
@@ -142,11 +157,7 @@ func PodDeploy(appLink string, pc PodConfig, cfg *EdenSetupArgs) error {
 	expectation := expect.AppExpectationFromURL(ctrl, dev, appLink, pc.Name, opts...)
 	appInstanceConfig := expectation.Application()
 	dev.SetApplicationInstanceConfig(append(dev.GetApplicationInstances(), appInstanceConfig.Uuidandversion.Uuid))
-	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
-		return fmt.Errorf("setControllerAndDev: %w", err)
-	}
-	log.Infof("deploy pod %s with %s request sent", appInstanceConfig.Displayname, appLink)
-	return nil
+	return appInstanceConfig, nil
 }
 
 func PodPs(_ *EdenSetupArgs) error {
@@ -607,6 +618,9 @@ func PodPublish(appName, kernelFile, initrdFile, rootFile, formatStr, arch strin
 	)
 	ctx := context.TODO()
 	if local {
+		if cfg.Eden.EServer.Registry {
+			return fmt.Errorf("eserver does not support push of images, publish into remote registry or import archive with 'eden eserver oci import'")
+		}
 		_, remoteTarget, err = utils.NewRegistryHTTP(ctx)
 		if err != nil {
 			return fmt.Errorf("unexpected error when created NewRegistry resolver: %w", err)
@@ -22,9 +22,30 @@ func RegistryStart(cfg *RegistryConfig) error {
 	return nil
 }
 
-func RegistryLoad(ref string, cfg *RegistryConfig) error {
-	registry := fmt.Sprintf("%s:%d", cfg.IP, cfg.Port)
-	hash, err := utils.LoadRegistry(ref, registry)
+// localRegistry returns address of local registry, eserver serves images if registry is enabled in it
+func localRegistry(cfg *EdenSetupArgs) string {
+	if cfg.Eden.EServer.Registry {
+		return fmt.Sprintf("%s:%d", cfg.Eden.EServer.IP, cfg.Eden.EServer.Port)
+	}
+	return fmt.Sprintf("%s:%d", cfg.Registry.IP, cfg.Registry.Port)
+}
+
+func RegistryLoad(ref string, cfg *EdenSetupArgs) error {
+	var hash string
+	var err error
+	if cfg.Eden.EServer.Registry {
+		server, err := eserverClient(cfg)
+		if err != nil {
+			return err
+		}
+		hash, err = server.EServerLoadImage(ref)
+		if err != nil {
+			return fmt.Errorf("failed to load image %s into eserver: %w", ref, err)
+		}
+		fmt.Printf("image %s loaded with manifest hash %s\n", ref, hash)
+		return nil
+	}
+	hash, err = utils.LoadRegistry(ref, localRegistry(cfg))
 	if err != nil {
 		return fmt.Errorf("failed to load image %s: %w", ref, err)
 	}
@@ -1,12 +1,21 @@
 package openevec
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/edensdn"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -202,9 +211,157 @@ func SdnStatus(cfg *EdenSetupArgs) error {
 		fmt.Printf("\tHave configuration errors: %v\n", status.ConfigErrors)
 	}
 	fmt.Printf("\tManagement IPs: %v\n", strings.Join(status.MgmtIPs, ", "))
+	printSdnPortsStatus(status.Ports)
+	printSdnServicesStatus(status)
+	printSdnFirewallStatus(status.Firewall)
+	conntrack := status.Conntrack
+	fmt.Printf("\tConntrack: %d flows (%d IPv4, %d IPv6)", conntrack.Flows,
+		conntrack.IPv4Flows, conntrack.IPv6Flows)
+	if len(conntrack.FlowsByProto) > 0 {
+		fmt.Printf(", per protocol: %s", formatCounts(conntrack.FlowsByProto))
+	}
+	fmt.Println()
 	return nil
 }
 
+func printSdnPortsStatus(ports []sdnapi.PortStatus) {
+	if len(ports) == 0 {
+		return
+	}
+	fmt.Printf("\tPorts:\n")
+	for _, port := range ports {
+		if port.IfName == "" {
+			fmt.Printf("\t\t%s (MAC %s): interface not found\n",
+				port.LogicalLabel, port.MAC)
+			continue
+		}
+		adminState := "down"
+		if port.AdminUp {
+			adminState = "up"
+		}
+		c := port.Counters
+		fmt.Printf("\t\t%s (%s, MAC %s): admin %s, oper %s, MTU %d, "+
+			"RX %d packets/%d bytes (%d errors, %d dropped), "+
+			"TX %d packets/%d bytes (%d errors, %d dropped)\n",
+			port.LogicalLabel, port.IfName, port.MAC, adminState, port.OperState,
+			port.MTU, c.RxPackets, c.RxBytes, c.RxErrors, c.RxDropped,
+			c.TxPackets, c.TxBytes, c.TxErrors, c.TxDropped)
+	}
+}
+
+func printSdnServicesStatus(status sdnapi.SDNStatus) {
+	if len(status.DHCPServers) > 0 {
+		fmt.Printf("\tDHCP leases:\n")
+	}
+	for _, dhcpSrv := range status.DHCPServers {
+		if len(dhcpSrv.Leases) == 0 {
+			fmt.Printf("\t\t%s: no leases\n", dhcpSrv.Network)
+			continue
+		}
+		for _, lease := range dhcpSrv.Leases {
+			var details []string
+			if lease.MAC != "" {
+				details = append(details, "MAC "+lease.MAC)
+			}
+			if lease.Hostname != "" {
+				details = append(details, "hostname "+lease.Hostname)
+			}
+			if lease.Expiry.IsZero() {
+				details = append(details, "never expires")
+			} else {
+				details = append(details, "expires "+lease.Expiry.Format(time.RFC3339))
+			}
+			fmt.Printf("\t\t%s: %s (%s)\n", dhcpSrv.Network, lease.IP,
+				strings.Join(details, ", "))
+		}
+	}
+	if len(status.DNSServers) > 0 {
+		fmt.Printf("\tDNS servers:\n")
+	}
+	for _, dnsSrv := range status.DNSServers {
+		fmt.Printf("\t\t%s: %d queries (%d forwarded, %d static, %d cached, %d faulted)",
+			dnsSrv.Endpoint, dnsSrv.Queries, dnsSrv.Forwarded, dnsSrv.Static, dnsSrv.Cached,
+			dnsSrv.Faulted)
+		if len(dnsSrv.QueriedNames) > 0 {
+			fmt.Printf(", queried names: %s", formatCounts(dnsSrv.QueriedNames))
+		}
+		fmt.Println()
+		for _, rule := range dnsSrv.FaultRules {
+			queryType := rule.QueryType
+			if queryType == "" {
+				queryType = "any"
+			}
+			fmt.Printf("\t\t\tfault rule %s (%s) -> %s: %d hits\n", rule.FQDN, queryType,
+				sdnapi.DNSFaultActionToString[rule.Action], rule.Hits)
+		}
+	}
+	if len(status.HTTPProxies) > 0 {
+		fmt.Printf("\tHTTP proxies:\n")
+	}
+	for _, proxy := range status.HTTPProxies {
+		stats := proxy.Stats
+		fmt.Printf("\t\t%s: %d requests (%d forwarded, %d rejected, %d MITM, "+
+			"%d auth failures)", proxy.Endpoint, stats.Requests, stats.Forwarded,
+			stats.Rejected, stats.MITM, stats.AuthFailures)
+		if len(stats.Hosts) > 0 {
+			fmt.Printf(", hosts: %s", formatCounts(stats.Hosts))
+		}
+		fmt.Println()
+	}
+	if len(status.HTTPServers) > 0 {
+		fmt.Printf("\tHTTP servers:\n")
+	}
+	for _, httpSrv := range status.HTTPServers {
+		fmt.Printf("\t\t%s: %d requests", httpSrv.Endpoint, httpSrv.Requests)
+		if len(httpSrv.Paths) > 0 {
+			fmt.Printf(", paths: %s", formatCounts(httpSrv.Paths))
+		}
+		fmt.Println()
+		for _, req := range httpSrv.RecentRequests {
+			fmt.Printf("\t\t\t%s %s %s %s%s -> %d\n", req.Time.Format(time.RFC3339),
+				req.Client, req.Method, req.Host, req.URL, req.StatusCode)
+		}
+	}
+	if len(status.CaptivePortals) > 0 {
+		fmt.Printf("\tCaptive portals:\n")
+	}
+	for _, portal := range status.CaptivePortals {
+		fmt.Printf("\t\t%s: %d logged-in clients\n", portal.Endpoint, len(portal.Clients))
+		for _, client := range portal.Clients {
+			fmt.Printf("\t\t\t%s (network %s)\n", client.IP, client.Network)
+		}
+	}
+}
+
+func printSdnFirewallStatus(rules []sdnapi.FwRuleStatus) {
+	if len(rules) == 0 {
+		return
+	}
+	fmt.Printf("\tFirewall rules:\n")
+	for _, rule := range rules {
+		fmt.Printf("\t\t%s (%s): %d packets/%d bytes", rule.Name,
+			sdnapi.FwActionToString[rule.Action], rule.Packets, rule.Bytes)
+		if len(rule.DstFQDNIPs) > 0 {
+			fmt.Printf(", FQDN IPs: %s", strings.Join(rule.DstFQDNIPs, ", "))
+		}
+		fmt.Println()
+	}
+}
+
+// formatCounts : format map of counters sorted by keys, e.g. "tcp: 3, udp: 1".
+func formatCounts(counts map[string]uint64) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, fmt.Sprintf("%s: %d", key, counts[key]))
+	}
+	return strings.Join(items, ", ")
+}
+
 func SdnNetModelGet(cfg *EdenSetupArgs) (string, error) {
 	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
 		return "", fmt.Errorf("SDN is not enabled")
@@ -243,6 +400,9 @@ func SdnNetModelApply(ref string, cfg *EdenSetupArgs) error {
 		}
 	}
 	newNetModel.Host.ControllerPort = uint16(cfg.Adam.Port)
+	if err = setEserverAccess(newNetModel.Host, cfg); err != nil {
+		return err
+	}
 	client := &edensdn.SdnClient{
 		SSHPort:    uint16(cfg.Sdn.SSHPort),
 		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
@@ -354,3 +514,474 @@ func SdnEpExec(epName, command string, args []string, cfg *EdenSetupArgs) error
 	}
 	return nil
 }
+
+// SdnImpairArgs contains settings of link impairment emulated by Eden-SDN,
+// rate is human-readable (e.g. 512kbit, 10mbit)
+type SdnImpairArgs struct {
+	Delay     time.Duration
+	Jitter    time.Duration
+	Loss      float32
+	Duplicate float32
+	Reorder   float32
+	Rate      string
+}
+
+// parseBitRate returns rate in kbit/s, value without units is in kbit/s
+func parseBitRate(value string) (uint32, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "bit")
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(number, "k"):
+		number = strings.TrimSuffix(number, "k")
+	case strings.HasSuffix(number, "m"):
+		number = strings.TrimSuffix(number, "m")
+		multiplier = 1000
+	case strings.HasSuffix(number, "g"):
+		number = strings.TrimSuffix(number, "g")
+		multiplier = 1000 * 1000
+	}
+	rate, err := strconv.ParseFloat(number, 64)
+	if err != nil || rate < 0 {
+		return 0, fmt.Errorf("cannot parse rate %s", value)
+	}
+	if rate*multiplier > math.MaxUint32 {
+		return 0, fmt.Errorf("rate %s is too high", value)
+	}
+	return uint32(rate * multiplier), nil
+}
+
+// SdnImpairSet changes impairment of a port, bridge or network in the running Eden-SDN
+func SdnImpairSet(itemType, logicalLabel string, args *SdnImpairArgs, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	impairment := &sdnapi.Impairment{
+		Delay:     uint32(args.Delay.Milliseconds()),
+		Jitter:    uint32(args.Jitter.Milliseconds()),
+		Loss:      args.Loss,
+		Duplicate: args.Duplicate,
+		Reorder:   args.Reorder,
+	}
+	var err error
+	if impairment.Rate, err = parseBitRate(args.Rate); err != nil {
+		return err
+	}
+	if impairment.IsEmpty() {
+		return fmt.Errorf("no impairment specified, use clear to remove impairment")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	if err = client.SetImpairment(itemType, logicalLabel, impairment); err != nil {
+		return fmt.Errorf("failed to set impairment: %w", err)
+	}
+	log.Infof("Impairment of %s %s: %s", itemType, logicalLabel, impairmentDescription(impairment))
+	return nil
+}
+
+// SdnImpairClear removes impairment of a port, bridge or network in the running Eden-SDN
+func SdnImpairClear(itemType, logicalLabel string, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	if err := client.SetImpairment(itemType, logicalLabel, nil); err != nil {
+		return fmt.Errorf("failed to clear impairment: %w", err)
+	}
+	log.Infof("Impairment of %s %s removed", itemType, logicalLabel)
+	return nil
+}
+
+func impairmentDescription(impairment *sdnapi.Impairment) string {
+	var parts []string
+	if impairment.Delay > 0 {
+		delay := fmt.Sprintf("delay %dms", impairment.Delay)
+		if impairment.Jitter > 0 {
+			delay += fmt.Sprintf(" ±%dms", impairment.Jitter)
+		}
+		parts = append(parts, delay)
+	}
+	if impairment.Loss > 0 {
+		parts = append(parts, fmt.Sprintf("loss %v%%", impairment.Loss))
+	}
+	if impairment.Duplicate > 0 {
+		parts = append(parts, fmt.Sprintf("duplicate %v%%", impairment.Duplicate))
+	}
+	if impairment.Reorder > 0 {
+		parts = append(parts, fmt.Sprintf("reorder %v%%", impairment.Reorder))
+	}
+	if impairment.Rate > 0 {
+		parts = append(parts, fmt.Sprintf("rate %dkbit", impairment.Rate))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SdnImpairList prints impairments configured in the network model of the running Eden-SDN
+func SdnImpairList(cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	netModel, err := client.GetNetworkModel()
+	if err != nil {
+		return fmt.Errorf("failed to get network model: %w", err)
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "TYPE\tLOGICAL-LABEL\tIMPAIRMENT"); err != nil {
+		return err
+	}
+	printImpairment := func(item sdnapi.LabeledItem, impairment *sdnapi.Impairment) error {
+		if impairment == nil {
+			return nil
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", item.ItemType(), item.ItemLogicalLabel(),
+			impairmentDescription(impairment))
+		return err
+	}
+	for _, port := range netModel.Ports {
+		if err = printImpairment(port, port.Impairment); err != nil {
+			return err
+		}
+	}
+	for _, bridge := range netModel.Bridges {
+		if err = printImpairment(bridge, bridge.Impairment); err != nil {
+			return err
+		}
+	}
+	for _, network := range netModel.Networks {
+		if err = printImpairment(network, network.Impairment); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// SdnPortalLogin logs a client in through a captive portal of the running Eden-SDN
+func SdnPortalLogin(portal, clientIP string, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	if err := client.CaptivePortalLogin(portal, clientIP); err != nil {
+		return fmt.Errorf("failed to log in client %s: %w", clientIP, err)
+	}
+	log.Infof("Client %s logged in through captive portal %s", clientIP, portal)
+	return nil
+}
+
+// SdnPortalLogout logs out a client from a captive portal of the running Eden-SDN
+func SdnPortalLogout(portal, clientIP string, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	if err := client.CaptivePortalLogout(portal, clientIP); err != nil {
+		return fmt.Errorf("failed to log out client %s: %w", clientIP, err)
+	}
+	log.Infof("Client %s logged out from captive portal %s", clientIP, portal)
+	return nil
+}
+
+// SdnPortalList prints clients logged in through captive portals of the running Eden-SDN
+func SdnPortalList(cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	status, err := client.GetSdnStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get SDN status: %w", err)
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "PORTAL\tNETWORK\tCLIENT\tEXPIRES-IN"); err != nil {
+		return err
+	}
+	for _, portal := range status.CaptivePortals {
+		for _, portalClient := range portal.Clients {
+			expiresIn := "never"
+			if portalClient.Timeout > 0 {
+				expiresIn = (time.Duration(portalClient.Timeout) * time.Second).String()
+			}
+			if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", portal.Endpoint,
+				portalClient.Network, portalClient.IP, expiresIn); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// SdnCaptureArgs : arguments for packet capture in Eden-SDN.
+type SdnCaptureArgs struct {
+	Filter   string
+	Output   string
+	SnapLen  uint32
+	Count    uint64
+	Duration time.Duration
+	RingSize string
+}
+
+func (args *SdnCaptureArgs) captureOpts() (opts edensdn.CaptureOpts, err error) {
+	opts = edensdn.CaptureOpts{
+		Filter:   args.Filter,
+		SnapLen:  args.SnapLen,
+		Count:    args.Count,
+		Duration: args.Duration,
+	}
+	if args.RingSize != "" {
+		opts.RingSize, err = humanize.ParseBytes(args.RingSize)
+		if err != nil {
+			return opts, fmt.Errorf("invalid ring size %s: %w", args.RingSize, err)
+		}
+	}
+	return opts, nil
+}
+
+// SdnCapture captures packets of a port, network or endpoint of the running Eden-SDN
+// and writes them into the output file in the pcapng format.
+// Capture runs until interrupted, unless limited by duration or packet count.
+func SdnCapture(itemType, logicalLabel string, args *SdnCaptureArgs, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	opts, err := args.captureOpts()
+	if err != nil {
+		return err
+	}
+	out := os.Stdout
+	if args.Output != "-" {
+		out, err = os.Create(args.Output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer out.Close()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Infof("Capturing packets of %s %s (interrupt to stop)", itemType, logicalLabel)
+	if err = client.Capture(ctx, itemType, logicalLabel, opts, out); err != nil {
+		return fmt.Errorf("failed to capture packets: %w", err)
+	}
+	if args.Output != "-" {
+		log.Infof("Captured packets saved to %s", args.Output)
+	}
+	return nil
+}
+
+// SdnRingCaptureStart starts ring capture on a port, network or endpoint of the running Eden-SDN.
+func SdnRingCaptureStart(itemType, logicalLabel string, args *SdnCaptureArgs, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	opts, err := args.captureOpts()
+	if err != nil {
+		return err
+	}
+	if err = client.StartRingCapture(itemType, logicalLabel, opts); err != nil {
+		return fmt.Errorf("failed to start ring capture: %w", err)
+	}
+	log.Infof("Ring capture of %s %s started", itemType, logicalLabel)
+	return nil
+}
+
+// SdnRingCaptureSave saves packets buffered by a ring capture into the output file.
+func SdnRingCaptureSave(itemType, logicalLabel, output string, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	out := os.Stdout
+	if output != "-" {
+		var err error
+		out, err = os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer out.Close()
+	}
+	if err := client.SaveRingCapture(itemType, logicalLabel, out); err != nil {
+		return fmt.Errorf("failed to save ring capture: %w", err)
+	}
+	if output != "-" {
+		log.Infof("Ring capture of %s %s saved to %s", itemType, logicalLabel, output)
+	}
+	return nil
+}
+
+// SdnRingCaptureStop stops a ring capture and discards all buffered packets.
+func SdnRingCaptureStop(itemType, logicalLabel string, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	if err := client.StopRingCapture(itemType, logicalLabel); err != nil {
+		return fmt.Errorf("failed to stop ring capture: %w", err)
+	}
+	log.Infof("Ring capture of %s %s stopped", itemType, logicalLabel)
+	return nil
+}
+
+// SdnRingCaptureList lists ring captures running in Eden-SDN.
+func SdnRingCaptureList(cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	client := &edensdn.SdnClient{
+		SSHPort:    uint16(cfg.Sdn.SSHPort),
+		SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+		MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+	}
+	captures, err := client.ListRingCaptures()
+	if err != nil {
+		return fmt.Errorf("failed to list ring captures: %w", err)
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "TYPE\tLOGICAL-LABEL\tFILTER\tPACKETS\tSIZE\tDROPPED\tSTARTED\tERROR"); err != nil {
+		return err
+	}
+	for _, capture := range captures {
+		if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s/%s\t%d\t%s\t%s\n",
+			capture.ItemType, capture.LogicalLabel, capture.Filter, capture.Packets,
+			humanize.Bytes(uint64(capture.Size)), humanize.Bytes(uint64(capture.MaxSize)),
+			capture.Dropped, capture.Started.Format(time.RFC3339), capture.Error); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// SdnScenarioRun executes scenario of network model changes (see edensdn.Scenario)
+// against the running Eden-SDN. The timeline report is written into reportFile
+// (as JSON if the file has the .json extension), or printed to stdout if reportFile is empty.
+func SdnScenarioRun(scenarioFile, reportFile string, cfg *EdenSetupArgs) error {
+	if !isSdnEnabled(cfg.Sdn.Disable, cfg.Eve.Remote, cfg.Eve.DevModel) {
+		return fmt.Errorf("SDN is not enabled")
+	}
+	scenario, err := edensdn.LoadScenarioFromFile(scenarioFile)
+	if err != nil {
+		return err
+	}
+	vmRunner, err := edensdn.GetSdnVMRunner(cfg.Eve.DevModel, edensdn.SdnVMConfig{})
+	if err != nil {
+		return fmt.Errorf("failed to get SDN VM runner: %w", err)
+	}
+	runner := &edensdn.ScenarioRunner{
+		Client: &edensdn.SdnClient{
+			SSHPort:    uint16(cfg.Sdn.SSHPort),
+			SSHKeyPath: sdnSSHKeyPath(cfg.Sdn.SourceDir),
+			MgmtPort:   uint16(cfg.Sdn.MgmtPort),
+		},
+		RequiresVMRestart: vmRunner.RequiresVmRestart,
+		OnEvent: func(event edensdn.ScenarioEvent) {
+			if event.Error != "" {
+				log.Errorf("Step t=%v %s failed: %s", event.Offset, event.Action, event.Error)
+				return
+			}
+			log.Infof("Step t=%v %s done", event.Offset, event.Action)
+		},
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Infof("Running scenario %s with %d steps (interrupt to cancel)",
+		scenario.Name, len(scenario.Steps))
+	report, runErr := runner.Run(ctx, scenario)
+	if err = writeSdnScenarioReport(report, reportFile); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return fmt.Errorf("scenario %s: %w", scenario.Name, runErr)
+	}
+	return nil
+}
+
+func writeSdnScenarioReport(report edensdn.ScenarioReport, reportFile string) error {
+	if reportFile == "" {
+		return report.WriteText(os.Stdout)
+	}
+	out, err := os.Create(reportFile)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer out.Close()
+	if filepath.Ext(reportFile) == ".json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(out)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	log.Infof("Scenario report saved to %s", reportFile)
+	return nil
+}
+
+// SdnScenarioCheck parses scenario file and prints its steps without executing them.
+func SdnScenarioCheck(scenarioFile string) error {
+	scenario, err := edensdn.LoadScenarioFromFile(scenarioFile)
+	if err != nil {
+		return err
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if _, err = fmt.Fprintln(w, "OFFSET\tLINE\tACTION"); err != nil {
+		return err
+	}
+	for _, step := range scenario.Steps {
+		if _, err = fmt.Fprintf(w, "%v\t%d\t%s\n", step.Offset, step.Line,
+			step.Action); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
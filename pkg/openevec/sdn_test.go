@@ -0,0 +1,36 @@
+package openevec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBitRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		rate    uint32
+		wantErr bool
+	}{
+		{value: "", rate: 0},
+		{value: "512", rate: 512},
+		{value: "512kbit", rate: 512},
+		{value: "512k", rate: 512},
+		{value: "10mbit", rate: 10000},
+		{value: " 1.5Mbit ", rate: 1500},
+		{value: "1gbit", rate: 1000000},
+		{value: "fast", wantErr: true},
+		{value: "-1kbit", wantErr: true},
+		{value: "10tbit", wantErr: true},
+		{value: "5000gbit", wantErr: true},
+	}
+	for _, tt := range tests {
+		rate, err := parseBitRate(tt.value)
+		if tt.wantErr {
+			assert.Error(t, err, tt.value)
+			continue
+		}
+		assert.NoError(t, err, tt.value)
+		assert.Equal(t, tt.rate, rate, tt.value)
+	}
+}
@@ -62,7 +62,7 @@ func StartRegistry(cfg EdenSetupArgs) error {
 }
 
 func StartEServer(cfg EdenSetupArgs) error {
-	if err := eden.StartEServer(cfg.Eden.EServer.Port, cfg.Eden.Images.EServerImageDist, cfg.Eden.EServer.Force, cfg.Eden.EServer.Tag); err != nil {
+	if err := eden.StartEServer(cfg.Eden.EServer.Port, cfg.Eden.Images.EServerImageDist, cfg.Eden.EServer.Force, cfg.Eden.EServer.Tag, eserverOptions(&cfg)); err != nil {
 		return fmt.Errorf("cannot start eserver: %w", err)
 	}
 	log.Infof("Eserver is running and accesible on port %d", cfg.Eden.EServer.Port)
@@ -83,7 +83,9 @@ func StartEden(cfg *EdenSetupArgs, vmName, zedControlURL, tapInterface string) e
 			return fmt.Errorf("cannot start adam %w", err)
 		}
 
-		if err := StartRegistry(*cfg); err != nil {
+		if cfg.Eden.EServer.Registry {
+			log.Info("Registry is served by eserver")
+		} else if err := StartRegistry(*cfg); err != nil {
 			return fmt.Errorf("cannot start registry %w", err)
 		}
 
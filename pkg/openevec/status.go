@@ -32,7 +32,9 @@ func Status(cfg *EdenSetupArgs, vmName string, allConfigs bool) error {
 		fmt.Printf("\tFor local Adam you can run 'docker logs %s' to see logs\n", defaults.DefaultAdamContainerName)
 	}
 	statusRegistry, err := eden.StatusRegistry()
-	if err != nil {
+	if cfg.Eden.EServer.Registry {
+		fmt.Printf("%s Registry is served by eserver at %s\n", statusOK(), localRegistry(cfg))
+	} else if err != nil {
 		return fmt.Errorf("%s cannot obtain status of registry: %w", statusWarn(), err)
 	} else {
 		fmt.Printf("%s Registry status: %s\n", representContainerStatus(lastWord(statusRegistry)), statusRegistry)
@@ -61,6 +61,11 @@ func InitVarsFromConfig(cfg *EdenSetupArgs) (*utils.ConfigVars, error) {
 	cv.EServerImageDist = cfg.Eden.Images.EServerImageDist
 	cv.EServerPort = strconv.Itoa(cfg.Eden.EServer.Port)
 	cv.EServerIP = cfg.Eden.EServer.IP
+	cv.EServerTLS = cfg.Eden.EServer.TLS
+	cv.EServerToken = cfg.Eden.EServer.Token
+	cv.EServerUser = cfg.Eden.EServer.User
+	cv.EServerPassword = cfg.Eden.EServer.Password
+	cv.EServerRegistry = cfg.Eden.EServer.Registry
 
 	cv.EveCert = cfg.Eve.Cert
 	cv.EveDeviceCert = cfg.Eve.DeviceCert
@@ -82,6 +87,9 @@ func InitVarsFromConfig(cfg *EdenSetupArgs) (*utils.ConfigVars, error) {
 
 	cv.RegistryIP = cfg.Registry.IP
 	cv.RegistryPort = strconv.Itoa(cfg.Registry.Port)
+	if cv.EServerRegistry {
+		cv.RegistryIP, cv.RegistryPort = cv.EServerIP, cv.EServerPort
+	}
 
 	redisPasswordFile := filepath.Join(globalCertsDir, defaults.DefaultRedisPasswordFile)
 	pwd, err := os.ReadFile(redisPasswordFile)
@@ -4,6 +4,7 @@ import "github.com/lf-edge/eden/pkg/device"
 
 //EdgeNodeDescription must be defined in config file
 type EdgeNodeDescription struct {
+	Name   string
 	Key    string
 	Serial string
 	Model  string
@@ -21,6 +22,16 @@ func (nodeDescription *EdgeNodeDescription) GetEdgeNode(tc *TestContext) *device
 		if err != nil {
 			return nil
 		}
+		if dev.GetName() == "" {
+			dev.SetName(nodeDescription.Name)
+		}
+		return dev
+	}
+	if nodeDescription.Name != "" {
+		dev, err := ctrl.GetDevice(nodeDescription.Name)
+		if err != nil {
+			return nil
+		}
 		return dev
 	}
 	return nil
@@ -32,6 +43,7 @@ type EdgeNodeOption func(description *device.Ctx)
 //WithNodeDescription sets device info
 func (tc *TestContext) WithNodeDescription(nodeDescription *EdgeNodeDescription) EdgeNodeOption {
 	return func(d *device.Ctx) {
+		d.SetName(nodeDescription.Name)
 		d.SetDevModel(nodeDescription.Model)
 		d.SetOnboardKey(nodeDescription.Key)
 		d.SetSerial(nodeDescription.Serial)
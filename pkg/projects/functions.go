@@ -5,9 +5,13 @@ import (
 	"strings"
 
 	"github.com/lf-edge/eden/pkg/controller/eapps"
+	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/equery"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eve/api/go/info"
 	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/metrics"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/tmc/scp"
@@ -118,3 +122,55 @@ func SendFileSCP(ip *string, port *int, user, password, filename, destpath strin
 		return nil
 	}
 }
+
+//parseQuery parses expression for processing functions
+func parseQuery(expr string) *equery.Query {
+	q, err := equery.Parse(expr)
+	if err != nil {
+		log.Fatalf("cannot parse query %q: %s", expr, err)
+	}
+	return q
+}
+
+//InfoQuery returns ProcInfoFunc which stops processing when info matches the expression
+func InfoQuery(expr string, callbacks ...Callback) ProcInfoFunc {
+	q := parseQuery(expr)
+	return func(im *info.ZInfoMsg) error {
+		if !q.Match(im) {
+			return nil
+		}
+		for _, clb := range callbacks {
+			clb()
+		}
+		return fmt.Errorf("info matches query: %s", expr)
+	}
+}
+
+//LogQuery returns ProcLogFunc which stops processing when log matches the expression
+//Expression is evaluated against fields of LogEntry only, image and eveVersion are not available in it
+func LogQuery(expr string, callbacks ...Callback) ProcLogFunc {
+	q := parseQuery(expr)
+	return func(le *elog.FullLogEntry) error {
+		if !q.Match(&le.LogEntry) {
+			return nil
+		}
+		for _, clb := range callbacks {
+			clb()
+		}
+		return fmt.Errorf("log matches query: %s", expr)
+	}
+}
+
+//MetricQuery returns ProcMetricFunc which stops processing when metric matches the expression
+func MetricQuery(expr string, callbacks ...Callback) ProcMetricFunc {
+	q := parseQuery(expr)
+	return func(mm *metrics.ZMetricMsg) error {
+		if !q.Match(mm) {
+			return nil
+		}
+		for _, clb := range callbacks {
+			clb()
+		}
+		return fmt.Errorf("metric matches query: %s", expr)
+	}
+}
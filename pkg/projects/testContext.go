@@ -14,10 +14,13 @@ import (
 
 	"github.com/lf-edge/eden/pkg/controller"
 	"github.com/lf-edge/eden/pkg/controller/adam"
+	"github.com/lf-edge/eden/pkg/controller/eevent"
+	"github.com/lf-edge/eden/pkg/controller/fake"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/edensdn"
 	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/info"
 	"github.com/spf13/viper"
 )
 
@@ -127,6 +130,38 @@ func NewTestContext() *TestContext {
 	return tstCtx
 }
 
+//NewTestContextWithCloud creates new TestContext on top of provided cloud without loading of config
+//it is useful with in-memory controller from controller/fake package
+func NewTestContextWithCloud(cloud controller.Cloud) *TestContext {
+	tstCtx := &TestContext{
+		cloud: cloud,
+		tests: map[*device.Ctx]*testing.T{},
+	}
+	tstCtx.procBus = initBus(tstCtx)
+	return tstCtx
+}
+
+//NewTestContextFromArchive creates new TestContext which replays telemetry recorded into archive
+//devices found in archive are added into context, speed defines acceleration of replay,
+//values less or equal 0 means real time
+func NewTestContextFromArchive(path string, speed float64) (*TestContext, error) {
+	ctrl, err := fake.NewReplay(path, speed)
+	if err != nil {
+		return nil, err
+	}
+	cloud := &controller.CloudCtx{Controller: ctrl}
+	cloud.SetVars(&utils.ConfigVars{})
+	cloud.GetAllNodes()
+	tstCtx := NewTestContextWithCloud(cloud)
+	for i, dev := range cloud.ListDevices() {
+		if i == 0 {
+			cloud.SetDeviceCurrent(dev.GetID().String())
+		}
+		tstCtx.AddNode(dev)
+	}
+	return tstCtx, nil
+}
+
 //GetNodeDescriptions returns list of nodes from config
 func (tc *TestContext) GetNodeDescriptions() (nodes []*EdgeNodeDescription) {
 	if eveList := viper.GetStringMap("test.eve"); len(eveList) > 0 {
@@ -134,11 +169,12 @@ func (tc *TestContext) GetNodeDescriptions() (nodes []*EdgeNodeDescription) {
 			eveKey := viper.GetString(fmt.Sprintf("test.eve.%s.onboard-cert", name))
 			eveSerial := viper.GetString(fmt.Sprintf("test.eve.%s.serial", name))
 			eveModel := viper.GetString(fmt.Sprintf("test.eve.%s.model", name))
-			nodes = append(nodes, &EdgeNodeDescription{Key: eveKey, Serial: eveSerial, Model: eveModel})
+			nodes = append(nodes, &EdgeNodeDescription{Name: name, Key: eveKey, Serial: eveSerial, Model: eveModel})
 		}
 	} else {
 		log.Debug("NodeDescriptions not found. Will use default one.")
 		nodes = append(nodes, &EdgeNodeDescription{
+			Name:   viper.GetString("eve.name"),
 			Key:    utils.ResolveAbsPath(viper.GetString("eve.cert")),
 			Serial: viper.GetString("eve.serial"),
 			Model:  viper.GetString("eve.devModel"),
@@ -191,6 +227,13 @@ func (tc *TestContext) WithTest(t *testing.T) GetEdgeNodeOpts {
 	}
 }
 
+//WithNode selects device by name or UUID
+func (tc *TestContext) WithNode(selector string) GetEdgeNodeOpts {
+	return func(d *device.Ctx) bool {
+		return d.Match(selector)
+	}
+}
+
 //GetEdgeNode return node from context
 func (tc *TestContext) GetEdgeNode(opts ...GetEdgeNodeOpts) *device.Ctx {
 Node:
@@ -325,6 +368,19 @@ func (tc *TestContext) AddProcInfo(edgeNode *device.Ctx, processFunction ProcInf
 	tc.procBus.addProc(edgeNode, processFunction)
 }
 
+//AddProcEvent add processFunction, that will get events derived from info of edgeNode
+func (tc *TestContext) AddProcEvent(edgeNode *device.Ctx, processFunction ProcEventFunc) {
+	tracker := eevent.NewTracker()
+	tc.AddProcInfo(edgeNode, func(im *info.ZInfoMsg) error {
+		for _, ev := range tracker.Process(im) {
+			if err := processFunction(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 //AddProcMetric add processFunction, that will get all metrics for edgeNode
 func (tc *TestContext) AddProcMetric(edgeNode *device.Ctx, processFunction ProcMetricFunc) {
 	tc.procBus.addProc(edgeNode, processFunction)
@@ -9,6 +9,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/lf-edge/eden/pkg/controller/eapps"
+	"github.com/lf-edge/eden/pkg/controller/eevent"
 	"github.com/lf-edge/eden/pkg/controller/eflowlog"
 	"github.com/lf-edge/eden/pkg/controller/einfo"
 	"github.com/lf-edge/eden/pkg/controller/elog"
@@ -43,6 +44,9 @@ type Callback func()
 //ProcTimerFunc provides callback to process on timer event
 type ProcTimerFunc func() error
 
+//ProcEventFunc provides callback to process events derived from info
+type ProcEventFunc func(ev *eevent.Event) error
+
 type absFunc struct {
 	disabled bool
 	states   bool
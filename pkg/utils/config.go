@@ -58,6 +58,11 @@ type ConfigVars struct {
 	EServerImageDist  string
 	EServerPort       string
 	EServerIP         string
+	EServerTLS        bool
+	EServerToken      string
+	EServerUser       string
+	EServerPassword   string
+	EServerRegistry   bool
 	RegistryIP        string
 	RegistryPort      string
 	LogLevel          string
@@ -78,74 +83,118 @@ func InitVars() (*ConfigVars, error) {
 		}
 	}
 	if loaded {
-		edenHome, err := DefaultEdenDir()
-		if err != nil {
-			log.Fatal(err)
+		viperAccessMutex.RLock()
+		vars := varsFromViper(viper.GetViper())
+		viperAccessMutex.RUnlock()
+		return completeVars(vars), nil
+	}
+	return nil, nil
+}
+
+// ContextVars loads vars of context without changes of config used by viper
+func ContextVars(context string) (*ConfigVars, error) {
+	v := viper.New()
+	for _, config := range []string{GetConfig(defaults.DefaultContext), GetConfig(context)} {
+		if _, err := os.Stat(config); err != nil {
+			return nil, fmt.Errorf("cannot load config of context %s: %w", context, err)
 		}
-		globalCertsDir := filepath.Join(edenHome, defaults.DefaultCertsDist)
-		if _, err := os.Stat(globalCertsDir); os.IsNotExist(err) {
-			if err = os.MkdirAll(globalCertsDir, 0755); err != nil {
-				log.Fatal(err)
-			}
+		v.SetConfigFile(config)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", config, err)
 		}
-		caCertPath := filepath.Join(globalCertsDir, "root-certificate.pem")
-		viperAccessMutex.RLock()
-		var vars = &ConfigVars{
-			AdamIP:            viper.GetString("adam.ip"),
-			AdamPort:          viper.GetString("adam.port"),
-			AdamDomain:        viper.GetString("adam.domain"),
-			AdamDir:           ResolveAbsPath(viper.GetString("adam.dist")),
-			AdamCA:            caCertPath,
-			AdamRedisURLEden:  viper.GetString("adam.redis.eden"),
-			SSHKey:            ResolveAbsPath(viper.GetString("eden.ssh-key")),
-			EveCert:           ResolveAbsPath(viper.GetString("eve.cert")),
-			EveDeviceCert:     ResolveAbsPath(viper.GetString("eve.device-cert")),
-			EveSerial:         viper.GetString("eve.serial"),
-			EveDist:           viper.GetString("eve.dist"),
-			EveQemuConfig:     viper.GetString("eve.qemu-config"),
-			ZArch:             viper.GetString("eve.arch"),
-			EveSSID:           viper.GetString("eve.ssid"),
-			EveHV:             viper.GetString("eve.hv"),
-			DevModel:          viper.GetString("eve.devmodel"),
-			DevModelFIle:      viper.GetString("eve.devmodelfile"),
-			EveName:           viper.GetString("eve.name"),
-			EveUUID:           viper.GetString("eve.uuid"),
-			EveRemote:         viper.GetBool("eve.remote"),
-			EveRemoteAddr:     viper.GetString("eve.remote-addr"),
-			EveQemuPorts:      viper.GetStringMapString("eve.hostfwd"),
-			AdamRemote:        viper.GetBool("adam.remote.enabled"),
-			AdamRemoteRedis:   viper.GetBool("adam.remote.redis"),
-			AdamCaching:       viper.GetBool("adam.caching.enabled"),
-			AdamCachingPrefix: viper.GetString("adam.caching.prefix"),
-			AdamCachingRedis:  viper.GetBool("adam.caching.redis"),
-			EdenBinDir:        viper.GetString("eden.bin-dist"),
-			EdenProg:          viper.GetString("eden.eden-bin"),
-			TestProg:          viper.GetString("eden.test-bin"),
-			TestScenario:      viper.GetString("eden.test-scenario"),
-			EServerImageDist:  ResolveAbsPath(viper.GetString("eden.images.dist")),
-			EServerPort:       viper.GetString("eden.eserver.port"),
-			EServerIP:         viper.GetString("eden.eserver.ip"),
-			RegistryIP:        viper.GetString("registry.ip"),
-			RegistryPort:      viper.GetString("registry.port"),
-			LogLevel:          viper.GetString("eve.log-level"),
-			AdamLogLevel:      viper.GetString("eve.adam-log-level"),
+	}
+	return completeVars(varsFromViper(v)), nil
+}
+
+// varsFromViper returns vars with parameters from viper instance
+func varsFromViper(v *viper.Viper) *ConfigVars {
+	// the same as ResolveAbsPath, but with root from v
+	resolveAbsPath := func(curPath string) string {
+		curPath = strings.TrimSpace(curPath)
+		if curPath == "" || filepath.IsAbs(curPath) {
+			return curPath
 		}
-		viperAccessMutex.RUnlock()
-		redisPasswordFile := filepath.Join(globalCertsDir, defaults.DefaultRedisPasswordFile)
-		pwd, err := os.ReadFile(redisPasswordFile)
-		if err == nil {
-			vars.AdamRedisURLEden = fmt.Sprintf("redis://%s:%s@%s", string(pwd), string(pwd), vars.AdamRedisURLEden)
-		} else {
-			log.Errorf("cannot read redis password: %v", err)
-			vars.AdamRedisURLEden = fmt.Sprintf("redis://%s", vars.AdamRedisURLEden)
+		return filepath.Join(v.GetString("eden.root"), curPath)
+	}
+	return &ConfigVars{
+		AdamIP:            v.GetString("adam.ip"),
+		AdamPort:          v.GetString("adam.port"),
+		AdamDomain:        v.GetString("adam.domain"),
+		AdamDir:           resolveAbsPath(v.GetString("adam.dist")),
+		AdamRedisURLEden:  v.GetString("adam.redis.eden"),
+		SSHKey:            resolveAbsPath(v.GetString("eden.ssh-key")),
+		EveCert:           resolveAbsPath(v.GetString("eve.cert")),
+		EveDeviceCert:     resolveAbsPath(v.GetString("eve.device-cert")),
+		EveSerial:         v.GetString("eve.serial"),
+		EveDist:           v.GetString("eve.dist"),
+		EveQemuConfig:     v.GetString("eve.qemu-config"),
+		ZArch:             v.GetString("eve.arch"),
+		EveSSID:           v.GetString("eve.ssid"),
+		EveHV:             v.GetString("eve.hv"),
+		DevModel:          v.GetString("eve.devmodel"),
+		DevModelFIle:      v.GetString("eve.devmodelfile"),
+		EveName:           v.GetString("eve.name"),
+		EveUUID:           v.GetString("eve.uuid"),
+		EveRemote:         v.GetBool("eve.remote"),
+		EveRemoteAddr:     v.GetString("eve.remote-addr"),
+		EveQemuPorts:      v.GetStringMapString("eve.hostfwd"),
+		AdamRemote:        v.GetBool("adam.remote.enabled"),
+		AdamRemoteRedis:   v.GetBool("adam.remote.redis"),
+		AdamCaching:       v.GetBool("adam.caching.enabled"),
+		AdamCachingPrefix: v.GetString("adam.caching.prefix"),
+		AdamCachingRedis:  v.GetBool("adam.caching.redis"),
+		EdenBinDir:        v.GetString("eden.bin-dist"),
+		EdenProg:          v.GetString("eden.eden-bin"),
+		TestProg:          v.GetString("eden.test-bin"),
+		TestScenario:      v.GetString("eden.test-scenario"),
+		EServerImageDist:  resolveAbsPath(v.GetString("eden.images.dist")),
+		EServerPort:       v.GetString("eden.eserver.port"),
+		EServerIP:         v.GetString("eden.eserver.ip"),
+		EServerTLS:        v.GetBool("eden.eserver.tls"),
+		EServerToken:      v.GetString("eden.eserver.token"),
+		EServerUser:       v.GetString("eden.eserver.user"),
+		EServerPassword:   v.GetString("eden.eserver.password"),
+		EServerRegistry:   v.GetBool("eden.eserver.registry"),
+		RegistryIP:        v.GetString("registry.ip"),
+		RegistryPort:      v.GetString("registry.port"),
+		LogLevel:          v.GetString("eve.log-level"),
+		AdamLogLevel:      v.GetString("eve.adam-log-level"),
+	}
+}
+
+// completeVars sets vars which do not come from config file
+func completeVars(vars *ConfigVars) *ConfigVars {
+	edenHome, err := DefaultEdenDir()
+	if err != nil {
+		log.Fatal(err)
+	}
+	globalCertsDir := filepath.Join(edenHome, defaults.DefaultCertsDist)
+	if _, err := os.Stat(globalCertsDir); os.IsNotExist(err) {
+		if err = os.MkdirAll(globalCertsDir, 0755); err != nil {
+			log.Fatal(err)
 		}
-		return vars, nil
 	}
-	return nil, nil
+	vars.AdamCA = filepath.Join(globalCertsDir, "root-certificate.pem")
+	if vars.EServerRegistry {
+		// eserver serves images instead of registry container
+		vars.RegistryIP, vars.RegistryPort = vars.EServerIP, vars.EServerPort
+	}
+	redisPasswordFile := filepath.Join(globalCertsDir, defaults.DefaultRedisPasswordFile)
+	pwd, err := os.ReadFile(redisPasswordFile)
+	if err == nil {
+		vars.AdamRedisURLEden = fmt.Sprintf("redis://%s:%s@%s", string(pwd), string(pwd), vars.AdamRedisURLEden)
+	} else {
+		log.Errorf("cannot read redis password: %v", err)
+		vars.AdamRedisURLEden = fmt.Sprintf("redis://%s", vars.AdamRedisURLEden)
+	}
+	return vars
 }
 
 // DefaultEdenDir returns path to default directory
 func DefaultEdenDir() (string, error) {
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, defaults.DefaultEdenHomeDir), nil
+	}
 	usr, err := user.Current()
 	if err != nil {
 		return "", err
@@ -450,6 +499,18 @@ func generateConfigFileFromTemplate(filePath string, templateString string, cont
 			return defaults.DefaultEServerTag
 		case "eden.eserver.force":
 			return true
+		case "eden.eserver.tls":
+			return false
+		case "eden.eserver.token":
+			return ""
+		case "eden.eserver.user":
+			return ""
+		case "eden.eserver.password":
+			return ""
+		case "eden.eserver.sign-key":
+			return ""
+		case "eden.eserver.registry":
+			return false
 		case "eden.eclient.tag":
 			return defaults.DefaultEClientTag
 		case "eden.eclient.image":
@@ -107,3 +107,48 @@ func ContextLoad() (*Context, error) {
 	}
 	return ctx, nil
 }
+
+// ContextEve describes EVE defined in context
+type ContextEve struct {
+	Context string
+	Name    string
+	UUID    string
+}
+
+// ListEve returns EVE defined in available contexts
+func (ctx *Context) ListEve() ([]ContextEve, error) {
+	edenDir, err := DefaultEdenDir()
+	if err != nil {
+		return nil, fmt.Errorf("ListEve DefaultEdenDir error: %w", err)
+	}
+	files, err := os.ReadDir(filepath.Join(edenDir, ctx.Directory))
+	if err != nil {
+		return nil, fmt.Errorf("ListEve ReadDir error: %w", err)
+	}
+	var result []ContextEve
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".yml" {
+			continue
+		}
+		buf, err := os.ReadFile(filepath.Join(edenDir, ctx.Directory, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("ListEve ReadFile error: %w", err)
+		}
+		var cfg struct {
+			Eve struct {
+				Name string `yaml:"name"`
+				UUID string `yaml:"uuid"`
+			} `yaml:"eve"`
+		}
+		if err := yaml.Unmarshal(buf, &cfg); err != nil {
+			log.Debugf("ListEve: cannot parse %s: %s", file.Name(), err)
+			continue
+		}
+		contextName := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		if cfg.Eve.Name == "" {
+			cfg.Eve.Name = strings.ToLower(contextName)
+		}
+		result = append(result, ContextEve{Context: contextName, Name: cfg.Eve.Name, UUID: cfg.Eve.UUID})
+	}
+	return result, nil
+}
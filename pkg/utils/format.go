@@ -9,3 +9,18 @@ import (
 func AddTimestamp(inp string) string {
 	return fmt.Sprintf("time: %s out: %s", time.Now().Format(time.RFC3339Nano), inp)
 }
+
+// ParseTimeOrDuration parses RFC3339 time or duration relative to now (i.e. 10m means 10 minutes ago)
+func ParseTimeOrDuration(inp string) (time.Time, error) {
+	if inp == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, inp); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(inp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %s as RFC3339 time or duration", inp)
+	}
+	return time.Now().Add(-d), nil
+}
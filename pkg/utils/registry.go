@@ -87,6 +87,29 @@ func LoadRegistry(image, remote string) (string, error) {
 	return hash, nil
 }
 
+// SaveImageToArchive saves local image or pulls remote one into tarFile in docker save format
+func SaveImageToArchive(image, tarFile string) error {
+	localImage, err := HasImage(image)
+	if err != nil {
+		return fmt.Errorf("error checking for local image %s: %v", image, err)
+	}
+	if localImage {
+		return SaveImageToTar(image, tarFile)
+	}
+	tag, err := name.NewTag(image)
+	if err != nil {
+		return fmt.Errorf("invalid image tag %s: %v", image, err)
+	}
+	img, err := crane.Pull(image)
+	if err != nil {
+		return fmt.Errorf("unable to pull %s: %v", image, err)
+	}
+	if err := v1tarball.WriteToFile(tarFile, tag, img); err != nil {
+		return fmt.Errorf("unable to save %s into %s: %v", image, tarFile, err)
+	}
+	return nil
+}
+
 // RegistryHTTP for http access to local registry
 type RegistryHTTP struct {
 	remotes.Resolver
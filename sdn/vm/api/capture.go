@@ -0,0 +1,28 @@
+package api
+
+import "time"
+
+// RingCapture : packet capture running in the background on an SDN port,
+// network or endpoint, keeping only the most recently captured packets
+// (up to the configured size) in memory.
+type RingCapture struct {
+	// ItemType : type of the captured item (port, network or endpoint).
+	ItemType string `json:"itemType"`
+	// LogicalLabel : logical label of the captured item.
+	LogicalLabel string `json:"logicalLabel"`
+	// Filter : capture filter in the pcap-filter syntax.
+	Filter string `json:"filter,omitempty"`
+	// MaxSize : maximum number of bytes of captured packets to keep.
+	MaxSize int `json:"maxSize"`
+	// Size : number of bytes of currently buffered packets.
+	Size int `json:"size"`
+	// Packets : number of currently buffered packets.
+	Packets int `json:"packets"`
+	// Dropped : number of packets that were dropped from the buffer
+	// to make space for newer packets.
+	Dropped uint64 `json:"dropped"`
+	// Started : time when the capture was started.
+	Started time.Time `json:"started"`
+	// Error : non-empty if the capture has stopped due to an error.
+	Error string `json:"error,omitempty"`
+}
@@ -27,6 +27,9 @@ type Endpoints struct {
 	// NetbootServers : HTTP/TFTP servers providing artifacts needed to boot EVE OS
 	// over a network (using netboot/PXE + iPXE).
 	NetbootServers []NetbootServer `json:"netbootServers,omitempty"`
+	// CaptivePortals : endpoints emulating captive portals (e.g. of hotel or enterprise
+	// guest networks). Can be attached to networks (see Network.CaptivePortal).
+	CaptivePortals []CaptivePortal `json:"captivePortals,omitempty"`
 }
 
 // GetAll : returns all endpoints as one list.
@@ -54,6 +57,9 @@ func (eps Endpoints) GetAll() (all []Endpoint) {
 	for _, netBootSrv := range eps.NetbootServers {
 		all = append(all, netBootSrv.Endpoint)
 	}
+	for _, portal := range eps.CaptivePortals {
+		all = append(all, portal.Endpoint)
+	}
 	return all
 }
 
@@ -111,6 +117,11 @@ type DNSServer struct {
 	// UpstreamServers : list of IP addresses of public DNS servers to forward
 	// requests to (unless there is a static entry).
 	UpstreamServers []string `json:"upstreamServers"`
+	// FaultRules : rules for injecting faults into responses to queries for selected
+	// domain names. Can be used to test resolver fallbacks of DNS clients.
+	// Rules are evaluated in order and the first matching rule is applied.
+	// Queries not matched by any rule are served normally.
+	FaultRules []DNSFaultRule `json:"faultRules,omitempty"`
 }
 
 // ItemCategory
@@ -141,6 +152,29 @@ func (e DNSServer) ReferencesFromItem() []LogicalLabelRef {
 			})
 		}
 	}
+	for i, rule := range e.FaultRules {
+		if strings.HasPrefix(rule.FQDN, EndpointFQDNRefPrefix) {
+			refKey := fmt.Sprintf("dns-server-%s-fault-rule-%d-fqdn", e.LogicalLabel, i)
+			logicalLabel := strings.TrimPrefix(rule.FQDN, EndpointFQDNRefPrefix)
+			refs = append(refs, LogicalLabelRef{
+				ItemType:         Endpoint{}.ItemType(),
+				ItemLogicalLabel: logicalLabel,
+				RefKey:           refKey,
+			})
+		}
+		for j, ip := range rule.RotatingIPs {
+			if strings.HasPrefix(ip, EndpointIPRefPrefix) {
+				refKey := fmt.Sprintf("dns-server-%s-fault-rule-%d-ip-%d",
+					e.LogicalLabel, i, j)
+				logicalLabel := strings.TrimPrefix(ip, EndpointIPRefPrefix)
+				refs = append(refs, LogicalLabelRef{
+					ItemType:         Endpoint{}.ItemType(),
+					ItemLogicalLabel: logicalLabel,
+					RefKey:           refKey,
+				})
+			}
+		}
+	}
 	return refs
 }
 
@@ -171,6 +205,106 @@ type DNSEntry struct {
 	IP string `json:"ip"`
 }
 
+// DNSFaultRule : rule for injecting faults into DNS responses.
+type DNSFaultRule struct {
+	// FQDN : domain name that the rule applies to.
+	// Use "*." prefix to match all subdomains (e.g. "*.example.com").
+	// Can be a reference to endpoint FQDN:
+	//  - "endpoint-fqdn.<endpoint-logical-label>" - translated to endpoint's FQDN by Eden-SDN
+	FQDN string `json:"fqdn"`
+	// QueryType : type of DNS queries that the rule applies to (e.g. "A", "AAAA").
+	// Leave empty to match queries of any type.
+	QueryType string `json:"queryType,omitempty"`
+	// Probability : percentage of matching queries to apply the rule to.
+	// Zero value is interpreted as 100%.
+	Probability float32 `json:"probability,omitempty"`
+	// Action : how to respond to matching queries.
+	Action DNSFaultAction `json:"action"`
+	// Delay : delay (in milliseconds) added before the response is sent.
+	// Applies to all actions except DNSDrop.
+	Delay uint32 `json:"delay,omitempty"`
+	// TTL : TTL (in seconds) to set for all records in the response.
+	// Leave nil to keep TTLs unchanged (or to use 60 seconds for RotatingIPs).
+	TTL *uint32 `json:"ttl,omitempty"`
+	// RotatingIPs : answer A/AAAA queries with one of these IP addresses instead
+	// of resolving the name. Every response contains the next IP address (of the queried
+	// family) from the list (round-robin).
+	// Only used with DNSRespond action. IP addresses can be specified using the same
+	// symbolic references as in DNSEntry.IP.
+	RotatingIPs []string `json:"rotatingIPs,omitempty"`
+}
+
+// DNSFaultQueryTypes : DNS query types which can be used in DNSFaultRule.QueryType,
+// mapped to their numeric codes (RFC 1035, RFC 3596, RFC 2782).
+var DNSFaultQueryTypes = map[string]uint16{
+	"A":     1,
+	"NS":    2,
+	"CNAME": 5,
+	"SOA":   6,
+	"PTR":   12,
+	"MX":    15,
+	"TXT":   16,
+	"AAAA":  28,
+	"SRV":   33,
+	"ANY":   255,
+}
+
+// DNSFaultAction : action applied by DNS fault rule.
+type DNSFaultAction uint8
+
+const (
+	// DNSRespond : respond normally (possibly with added delay, overridden TTL
+	// or with rotating IPs).
+	DNSRespond DNSFaultAction = iota
+	// DNSNXDomain : respond with NXDOMAIN.
+	DNSNXDomain
+	// DNSServFail : respond with SERVFAIL.
+	DNSServFail
+	// DNSDrop : do not respond at all (query timeouts on the client side).
+	DNSDrop
+	// DNSTruncate : respond to queries received over UDP with an empty truncated
+	// response, forcing the client to retry over TCP. Queries received over TCP
+	// are served normally.
+	DNSTruncate
+)
+
+// DNSFaultActionToString : convert DNSFaultAction to string representation used in JSON.
+var DNSFaultActionToString = map[DNSFaultAction]string{
+	DNSRespond:  "respond",
+	DNSNXDomain: "nxdomain",
+	DNSServFail: "servfail",
+	DNSDrop:     "drop",
+	DNSTruncate: "truncate",
+}
+
+// DNSFaultActionToID : get DNSFaultAction from a string representation.
+var DNSFaultActionToID = map[string]DNSFaultAction{
+	"":         DNSRespond, // default value
+	"respond":  DNSRespond,
+	"nxdomain": DNSNXDomain,
+	"servfail": DNSServFail,
+	"drop":     DNSDrop,
+	"truncate": DNSTruncate,
+}
+
+// MarshalJSON marshals the enum as a quoted json string.
+func (s DNSFaultAction) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString(`"`)
+	buffer.WriteString(DNSFaultActionToString[s])
+	buffer.WriteString(`"`)
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON un-marshals a quoted json string to the enum value.
+func (s *DNSFaultAction) UnmarshalJSON(b []byte) error {
+	var j string
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = DNSFaultActionToID[j]
+	return nil
+}
+
 // HTTPServer : HTTP(s) server.
 type HTTPServer struct {
 	// Endpoint configuration.
@@ -216,9 +350,33 @@ type HTTPContent struct {
 	// ContentType : HTTP(S) Content-Type.
 	ContentType string `json:"contentType"`
 	// Content : content returned inside a HTTP(s) response body.
-	// It is a string, so binary content is not possible for now.
+	// It is a string, use ContentBase64 or ContentFromURL for binary content.
 	Content string `json:"content"`
-}
+	// ContentBase64 : content encoded with the standard base64 encoding.
+	// Takes precedence over Content.
+	ContentBase64 string `json:"contentBase64,omitempty"`
+	// ContentFromURL : HTTP(s) URL from where the content is downloaded and forwarded
+	// to the client with every request (range requests are supported).
+	// Takes precedence over Content and ContentBase64.
+	// Can be a reference to a file uploaded to eserver:
+	//  - "eserver-file.<filename>" - translated to URL of the file on eserver
+	ContentFromURL string `json:"contentFromURL,omitempty"`
+	// StatusCode : HTTP status code of the response.
+	// Zero value means 200 (OK), or 302 (Found) if RedirectTo is set, or the status
+	// code returned for ContentFromURL.
+	StatusCode int `json:"statusCode,omitempty"`
+	// Headers : additional HTTP headers to put into the response.
+	Headers map[string]string `json:"headers,omitempty"`
+	// RedirectTo : URL to redirect the client to (using the Location header).
+	// Content is not returned if set.
+	RedirectTo string `json:"redirectTo,omitempty"`
+	// Delay : delay (in milliseconds) before the response is sent.
+	Delay uint32 `json:"delay,omitempty"`
+}
+
+// EserverFileRefPrefix : prefix used to symbolically reference a file uploaded
+// to eserver. Can be used in HTTPContent.ContentFromURL.
+const EserverFileRefPrefix = "eserver-file." // Followed by the file name.
 
 // NTPServer : NTP server.
 type NTPServer struct {
@@ -349,6 +507,42 @@ func (e TransparentProxy) ReferencesFromItem() []LogicalLabelRef {
 	return refs
 }
 
+// CaptivePortal : endpoint emulating a captive portal.
+// Clients of a network with the captive portal attached are initially not allowed
+// to access anything but the portal. Their HTTP and HTTPS traffic is redirected
+// to the portal, which responds with a redirect to the login page. Once a client
+// logs in (by submitting the login form, or using the SDN agent API, see
+// "eden sdn portal login"), its traffic is allowed to pass.
+// Captive portal applies only to traffic of the same IP version as the portal IP.
+type CaptivePortal struct {
+	// Endpoint configuration.
+	Endpoint
+	// LoginPage : HTML page served to clients which have not logged in yet.
+	// The page should contain a form submitting POST request to "/login",
+	// with (optional) fields "username" and "password".
+	// Leave empty to use a simple default login page.
+	LoginPage string `json:"loginPage,omitempty"`
+	// Users : define for username/password authentication, leave empty
+	// to accept any login.
+	Users []UserCredentials `json:"users,omitempty"`
+	// InterceptDNS : answer every DNS query of a client which has not logged in yet
+	// with the IP address of the portal. Otherwise, DNS traffic is allowed to pass.
+	InterceptDNS bool `json:"interceptDNS,omitempty"`
+	// SessionTimeout : number of seconds after which a logged-in client is logged
+	// out automatically. Zero value means that sessions never expire.
+	SessionTimeout uint32 `json:"sessionTimeout,omitempty"`
+	// CertPEM : Certificate in the PEM format used for intercepted HTTPS connections.
+	// Leave empty to use a self-signed certificate generated for the portal FQDN.
+	CertPEM string `json:"certPEM,omitempty"`
+	// KeyPEM : Key in the PEM format used for intercepted HTTPS connections.
+	KeyPEM string `json:"keyPEM,omitempty"`
+}
+
+// ItemCategory
+func (e CaptivePortal) ItemCategory() string {
+	return "captive-portal"
+}
+
 // NetbootServer provides HTTP and TFTP server endpoints, serving all artifacts
 // needed to boot EVE OS over a network (using iPXE, potentially also supporting
 // older PXE-only clients).
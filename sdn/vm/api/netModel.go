@@ -96,6 +96,10 @@ type Port struct {
 	AdminUP bool `json:"adminUP"`
 	// EVEConnect : plug the other side of the port into a given EVE instance.
 	EVEConnect EVEConnect `json:"eveConnect"`
+	// Impairment : emulated degradation of the link between EVE and Eden-SDN.
+	// Overrides impairment configured for the bridge to which the port is attached
+	// (directly or via bond).
+	Impairment *Impairment `json:"impairment,omitempty"`
 }
 
 // ItemType
@@ -136,6 +140,9 @@ type Bridge struct {
 	Ports []string `json:"ports"`
 	// Logical labels of bonds.
 	Bonds []string `json:"bonds"`
+	// Impairment : emulated degradation of links of all bridged ports
+	// (unless overridden by port's own impairment).
+	Impairment *Impairment `json:"impairment,omitempty"`
 }
 
 // ItemType
@@ -196,10 +203,27 @@ type Network struct {
 	//        OR
 	//        -> Outside-of-SDN-VM
 	TransparentProxy string `json:"transparentProxy,omitempty"`
+	// CaptivePortal : Logical label of a CaptivePortal endpoint, intercepting traffic
+	// of clients from this network until they log in.
+	// Can be combined with TransparentProxy (traffic of logged-in clients is then
+	// forwarded through the proxy).
+	CaptivePortal string `json:"captivePortal,omitempty"`
 	// Router configuration. Every network has a separate routing context.
 	// Undefined (nil) means that everything should be routed and accessible.
 	// That includes all networks, endpoints and the outside of Eden SDN.
 	Router *Router `json:"router,omitempty"`
+	// Impairment : emulated degradation of the traffic entering and leaving the network.
+	// It is applied on top of impairment configured for the bridge and its ports.
+	Impairment *Impairment `json:"impairment,omitempty"`
+	// IPv6 : IPv6-specific configuration of the network.
+	// For a dual-stack network, Subnet and GwIP (above) should be IPv4 and the IPv6
+	// subnet with the IPv6 gateway are configured by IPv6.Subnet and IPv6.GwIP.
+	// For an IPv6-only network, Subnet and GwIP are IPv6 and IPv6.Subnet with IPv6.GwIP
+	// should be left empty. IPv6-only network without IPv6 config uses SLAAC with
+	// the default Router Advertisement settings and, if DHCP is enabled, also
+	// the stateless DHCPv6 (dhcpv6-stateless AddrMode) to announce DNS servers,
+	// domain name and NTP server.
+	IPv6 *NetworkIPv6 `json:"ipv6,omitempty"`
 }
 
 // ItemType
@@ -232,8 +256,8 @@ func (n Network) ReferencesFromItem() []LogicalLabelRef {
 		ItemLogicalLabel: n.Bridge,
 		RefKey:           bridgeRefKey,
 	})
-	// References from inside the DHCP config.
-	if n.DHCP.Enable {
+	// References from inside the DHCP config (also used for DHCPv6 and RA).
+	if n.DHCP.Enable || n.IPv6 != nil {
 		for _, dns := range n.DHCP.PrivateDNS {
 			refs = append(refs, LogicalLabelRef{
 				ItemType:         Endpoint{}.ItemType(),
@@ -286,6 +310,15 @@ func (n Network) ReferencesFromItem() []LogicalLabelRef {
 			RefKey:           "network-tproxy-" + n.LogicalLabel,
 		})
 	}
+	// Reference to a CaptivePortal.
+	if n.CaptivePortal != "" {
+		refs = append(refs, LogicalLabelRef{
+			ItemType:         Endpoint{}.ItemType(),
+			ItemCategory:     CaptivePortal{}.ItemCategory(),
+			ItemLogicalLabel: n.CaptivePortal,
+			RefKey:           "network-captive-portal-" + n.LogicalLabel,
+		})
+	}
 	return refs
 }
 
@@ -294,7 +327,8 @@ func (n Network) ReferencesFromItem() []LogicalLabelRef {
 // all this information to hosts on the network.
 // But DHCPv6 is still needed and used to convey NTP and netboot configuration (if provided).
 type DHCP struct {
-	// Enable DHCP. Set to false to use EVE with static IP addressing.
+	// Enable DHCP (for IPv4). Set to false to use EVE with static IP addressing.
+	// For IPv6, address configuration is selected with Network.IPv6.AddrMode.
 	Enable bool `json:"enable"`
 	// IPRange : a range of IP addresses to allocate from.
 	// Not applicable for IPv6.
@@ -327,6 +361,143 @@ type DHCP struct {
 	NetbootServer string `json:"netbootServer"`
 }
 
+// NetworkIPv6 : IPv6 configuration of a network.
+// DNS servers (only those with IPv6 addresses), domain name and NTP server
+// announced by DHCPv6 and Router Advertisements are taken from Network.DHCP
+// (DHCP.Enable only concerns DHCPv4).
+type NetworkIPv6 struct {
+	// Subnet : IPv6 network address + netmask of a dual-stack network.
+	// Leave empty for an IPv6-only network.
+	Subnet string `json:"subnet,omitempty"`
+	// GwIP : IPv6 gateway address of a dual-stack network. Should be inside the Subnet.
+	// Leave empty for an IPv6-only network.
+	GwIP string `json:"gwIP,omitempty"`
+	// AddrMode : how clients obtain IPv6 addresses.
+	AddrMode IPv6AddrMode `json:"addrMode"`
+	// DHCPv6Range : a range of IPv6 addresses to allocate from with the stateful DHCPv6.
+	// If not defined, addresses <subnet>::100 - <subnet>::1ff are used.
+	DHCPv6Range IPRange `json:"dhcpv6Range"`
+	// RA : configuration for Router Advertisements sent to the network.
+	RA RouterAdvert `json:"ra"`
+	// PrefixDelegation : delegate IPv6 prefixes to clients using DHCPv6 (IA_PD).
+	// Leave nil to disable prefix delegation.
+	PrefixDelegation *PrefixDelegation `json:"prefixDelegation,omitempty"`
+	// NAT64 : translate traffic sent to the NAT64 prefix into IPv4 and route it
+	// outside of Eden SDN. Leave nil to disable NAT64.
+	NAT64 *NAT64 `json:"nat64,omitempty"`
+}
+
+// RouterAdvert : configuration for IPv6 Router Advertisements (RA).
+// Values left as zero are replaced with defaults selected by radvd.
+type RouterAdvert struct {
+	// Disable : do not send Router Advertisements at all.
+	// Note that without RA clients will not learn the default gateway
+	// (DHCPv6 is not able to announce it).
+	Disable bool `json:"disable,omitempty"`
+	// ManagedFlag : override the M flag (addresses are available via DHCPv6).
+	// By default, it is set only with the dhcpv6-stateful AddrMode.
+	ManagedFlag *bool `json:"managedFlag,omitempty"`
+	// OtherFlag : override the O flag (other configuration is available via DHCPv6).
+	// By default, it is set with the dhcpv6-stateless and dhcpv6-stateful AddrMode.
+	OtherFlag *bool `json:"otherFlag,omitempty"`
+	// AutonomousFlag : override the A flag of the advertised prefix (SLAAC enabled).
+	// By default, it is set with the slaac and dhcpv6-stateless AddrMode.
+	AutonomousFlag *bool `json:"autonomousFlag,omitempty"`
+	// Interval : maximum time between unsolicited RAs in seconds (4-1800).
+	Interval uint32 `json:"interval,omitempty"`
+	// RouterLifetime : lifetime of the default route in seconds.
+	// Use NotDefaultRouter to advertise zero lifetime.
+	RouterLifetime uint32 `json:"routerLifetime,omitempty"`
+	// NotDefaultRouter : announce that the gateway should not be used as a default router.
+	NotDefaultRouter bool `json:"notDefaultRouter,omitempty"`
+	// ValidLifetime : valid lifetime of the advertised prefix in seconds.
+	ValidLifetime uint32 `json:"validLifetime,omitempty"`
+	// PreferredLifetime : preferred lifetime of the advertised prefix in seconds.
+	PreferredLifetime uint32 `json:"preferredLifetime,omitempty"`
+	// MTU : link MTU to advertise. Zero means that MTU is not advertised.
+	MTU uint16 `json:"mtu,omitempty"`
+	// RDNSS : announce IPv6 DNS servers of the network using the RDNSS option (RFC 8106).
+	RDNSS bool `json:"rdnss,omitempty"`
+	// DNSSL : announce the network domain name using the DNSSL option (RFC 8106).
+	DNSSL bool `json:"dnssl,omitempty"`
+}
+
+// PrefixDelegation : DHCPv6 prefix delegation (RFC 8415).
+// Delegated prefixes are routed towards the requesting client.
+type PrefixDelegation struct {
+	// Prefix : pool of IPv6 addresses to delegate prefixes from (e.g. "fd00:1000::/48").
+	Prefix string `json:"prefix"`
+	// DelegatedLength : length of delegated prefixes (e.g. 56 or 64).
+	DelegatedLength uint8 `json:"delegatedLength"`
+}
+
+// NAT64 : stateful NAT64 gateway (RFC 6146), translating IPv6 traffic sent to the NAT64
+// prefix into IPv4 and S-NATing it behind the SDN VM IPv4 address.
+// This allows IPv6-only clients to access IPv4-only endpoints and the outside
+// of Eden SDN (when combined with a DNS server returning synthesized AAAA records).
+type NAT64 struct {
+	// Prefix : IPv6 prefix used to represent IPv4 addresses. Only /96 is supported.
+	// Leave empty to use the well-known prefix 64:ff9b::/96.
+	Prefix string `json:"prefix,omitempty"`
+	// AnnouncePrefix : announce the NAT64 prefix in Router Advertisements
+	// using the PREF64 option (RFC 8781).
+	AnnouncePrefix bool `json:"announcePrefix,omitempty"`
+}
+
+// DefaultNAT64Prefix : the well-known NAT64 prefix (RFC 6052).
+const DefaultNAT64Prefix = "64:ff9b::/96"
+
+// IPv6AddrMode : method used by clients to obtain IPv6 addresses.
+type IPv6AddrMode uint8
+
+const (
+	// IPv6SLAAC : Stateless Address Autoconfiguration using prefix from RA.
+	IPv6SLAAC IPv6AddrMode = iota
+	// IPv6DHCPv6Stateless : SLAAC for addresses, other configuration
+	// (DNS, NTP, domain name) is obtained from the stateless DHCPv6.
+	IPv6DHCPv6Stateless
+	// IPv6DHCPv6Stateful : addresses and other configuration are obtained from DHCPv6.
+	IPv6DHCPv6Stateful
+	// IPv6Static : clients are expected to use statically configured addresses.
+	// RA still announces the default gateway (unless disabled).
+	IPv6Static
+)
+
+// IPv6AddrModeToString : convert IPv6AddrMode to string representation used in JSON.
+var IPv6AddrModeToString = map[IPv6AddrMode]string{
+	IPv6SLAAC:           "slaac",
+	IPv6DHCPv6Stateless: "dhcpv6-stateless",
+	IPv6DHCPv6Stateful:  "dhcpv6-stateful",
+	IPv6Static:          "static",
+}
+
+// IPv6AddrModeToID : get IPv6AddrMode from a string representation.
+var IPv6AddrModeToID = map[string]IPv6AddrMode{
+	"":                 IPv6SLAAC, // default value
+	"slaac":            IPv6SLAAC,
+	"dhcpv6-stateless": IPv6DHCPv6Stateless,
+	"dhcpv6-stateful":  IPv6DHCPv6Stateful,
+	"static":           IPv6Static,
+}
+
+// MarshalJSON marshals the enum as a quoted json string.
+func (s IPv6AddrMode) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString(`"`)
+	buffer.WriteString(IPv6AddrModeToString[s])
+	buffer.WriteString(`"`)
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON un-marshals a quoted json string to the enum value.
+func (s *IPv6AddrMode) UnmarshalJSON(b []byte) error {
+	var j string
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = IPv6AddrModeToID[j]
+	return nil
+}
+
 // DNSClientConfig : DNS configuration for a client.
 type DNSClientConfig struct {
 	// PublicDNS : list of IP addresses of public DNS servers to announce via DHCP option 6.
@@ -350,6 +521,34 @@ type Router struct {
 	ReachableNetworks []string `json:"reachableNetworks"`
 }
 
+// Impairment : emulated degradation of a link, used to reproduce poor connectivity
+// (e.g. cellular or satellite uplinks).
+// It is implemented using netem and tbf queueing disciplines and applied to traffic
+// in both directions. This means that, for example, Delay is added to packets sent
+// as well as received and therefore RTT increases by 2*Delay.
+type Impairment struct {
+	// Delay : added latency in milliseconds.
+	Delay uint32 `json:"delay,omitempty"`
+	// Jitter : random variation of the delay in milliseconds.
+	// Can be used only with non-zero Delay.
+	Jitter uint32 `json:"jitter,omitempty"`
+	// Loss : percentage of packets to drop (0-100).
+	Loss float32 `json:"loss,omitempty"`
+	// Duplicate : percentage of packets to duplicate (0-100).
+	Duplicate float32 `json:"duplicate,omitempty"`
+	// Reorder : percentage of packets to send immediately, i.e. before packets
+	// which are being delayed (0-100).
+	// Can be used only with non-zero Delay.
+	Reorder float32 `json:"reorder,omitempty"`
+	// Rate : bandwidth limit in kbit/s. Zero means unlimited.
+	Rate uint32 `json:"rate,omitempty"`
+}
+
+// IsEmpty returns true if impairment does not degrade the link in any way.
+func (i Impairment) IsEmpty() bool {
+	return i == Impairment{}
+}
+
 // Firewall : network firewall.
 // Note that traffic not matched by any rule is allowed!
 type Firewall struct {
@@ -362,28 +561,74 @@ type Firewall struct {
 	//   - endpoint -> another endpoint
 	//   - endpoint -> outside of SDN VM (controller, Internet)
 	// Note that once a connection is allowed, established and related traffic
-	// (going in the opposite direction) is automatically allowed as well.
+	// (going in the opposite direction) is automatically allowed as well
+	// (unless ExplicitConnState is enabled).
+	// Rules are applied to both IPv4 and IPv6 traffic, except for rules matching
+	// IP addresses of only one IP version (e.g. SrcSubnet is IPv4 subnet).
 	Rules []FwRule `json:"rules"`
+	// ExplicitConnState : do not implicitly allow established and related traffic.
+	// Instead, rules with ConnStates should be used to decide what to do with
+	// packets of already established connections.
+	ExplicitConnState bool `json:"explicitConnState,omitempty"`
 }
 
 // FwRule : a firewall rule.
+// All configured matches must be satisfied for the rule to apply.
 type FwRule struct {
+	// Name : name of the rule used to report rule hit counters (see SDNStatus.Firewall)
+	// and as a prefix of logged packets.
+	// Should be unique. Can be empty, in which case "rule-<index>" is used.
+	Name string `json:"name,omitempty"`
 	// SrcSubnet : subnet to match the source IP address with.
 	// Can be empty to disable filtering based on source IP address.
 	SrcSubnet string `json:"srcSubnet"`
 	// DstSubnet : subnet to match the destination IP address with.
 	// Can be empty to disable filtering based on destination IP address.
 	DstSubnet string `json:"dstSubnet"`
+	// SrcEndpoints : logical labels of endpoints to match the source IP address with.
+	// Rule matches if the source IP address is an IP address of any of these endpoints.
+	SrcEndpoints []string `json:"srcEndpoints,omitempty"`
+	// DstEndpoints : logical labels of endpoints to match the destination IP address with.
+	// Rule matches if the destination IP address is an IP address of any of these endpoints.
+	DstEndpoints []string `json:"dstEndpoints,omitempty"`
+	// DstFQDNs : set of domain names to match the destination IP address with.
+	// Rule matches if the destination IP address is an IP address of any of these
+	// domain names. FQDNs of endpoints are translated to endpoint IPs, other domain
+	// names are resolved by the SDN VM (and periodically re-resolved). Until a name
+	// is resolved, the rule does not match any of its addresses.
+	DstFQDNs []string `json:"dstFQDNs,omitempty"`
 	// Protocol : filter by protocol.
 	Protocol FwProto `json:"protocol"`
 	// Ports : list of destination port to which the rule applies.
 	// For a non empty list, Protocol must be either TCP or UDP.
 	// Empty = any.
 	Ports []uint16 `json:"ports"`
+	// ConnStates : match packets only if their connection is in one of these states.
+	// Empty = any.
+	// Note that unless Firewall.ExplicitConnState is enabled, established and related
+	// packets are allowed before any rule is evaluated.
+	ConnStates []FwConnState `json:"connStates,omitempty"`
+	// RateLimit : match packets only until the rate limit is reached.
+	// Packets above the limit continue to the next rule.
+	// Can be used together with FwAllow to limit the rate of some traffic
+	// (with a subsequent rule dropping the rest), or with FwLog to limit
+	// the number of logged packets.
+	RateLimit *FwRateLimit `json:"rateLimit,omitempty"`
 	// Action to take.
 	Action FwAction `json:"action"`
 }
 
+// FwRateLimit : rate limit applied to packets matched by a firewall rule.
+type FwRateLimit struct {
+	// Rate : maximum average matching rate, formatted as <number>/<unit>,
+	// where unit is one of: second, minute, hour, day.
+	// For example: "10/second".
+	Rate string `json:"rate"`
+	// Burst : maximum number of packets to match in a burst (before the average
+	// rate is enforced). Zero means the iptables default (5).
+	Burst uint32 `json:"burst,omitempty"`
+}
+
 // HostConfig : host configuration that Eden-SDN needs to be informed about.
 type HostConfig struct {
 	// HostIPs : list of IP addresses used by the host system (on top of which
@@ -399,6 +644,20 @@ type HostConfig struct {
 	NetworkType NetworkType `json:"networkType"`
 	// ControllerPort : port on which controller listens for device requests.
 	ControllerPort uint16 `json:"controllerPort"`
+	// EserverPort : port on which eserver (image cache) listens for HTTP requests.
+	// Required to resolve HTTPContent references to eserver files.
+	EserverPort uint16 `json:"eserverPort,omitempty"`
+	// EserverTLS : eserver is accessed using HTTPS instead of HTTP.
+	EserverTLS bool `json:"eserverTLS,omitempty"`
+	// EserverCACertPEM : certificate (in the PEM format) of the CA that issued
+	// the eserver certificate. Needed with EserverTLS unless the certificate is issued
+	// by a publicly trusted CA.
+	EserverCACertPEM string `json:"eserverCACertPEM,omitempty"`
+	// EserverUser : username to download files from eserver with.
+	// Leave empty if eserver does not require download credentials.
+	EserverUser string `json:"eserverUser,omitempty"`
+	// EserverPassword : password to download files from eserver with.
+	EserverPassword string `json:"eserverPassword,omitempty"`
 }
 
 // IPRange : a range of IP addresses.
@@ -467,6 +726,10 @@ const (
 	// FwDrop : drop traffic.
 	// Traffic is silently dropped.
 	FwDrop
+	// FwLog : log traffic into the kernel log of SDN VM.
+	// Logged packets continue to the next rule (log action is not terminating).
+	// Consider using together with FwRule.RateLimit to avoid flooding the log.
+	FwLog
 )
 
 // FwActionToString : convert FwAction to string representation used in JSON.
@@ -474,6 +737,7 @@ var FwActionToString = map[FwAction]string{
 	FwAllow:  "allow",
 	FwReject: "reject",
 	FwDrop:   "drop",
+	FwLog:    "log",
 }
 
 // FwActionToID : get FwAction from a string representation.
@@ -482,6 +746,7 @@ var FwActionToID = map[string]FwAction{
 	"allow":  FwAllow,
 	"reject": FwReject,
 	"drop":   FwDrop,
+	"log":    FwLog,
 }
 
 // MarshalJSON marshals the enum as a quoted json string.
@@ -546,3 +811,54 @@ func (s *FwProto) UnmarshalJSON(b []byte) error {
 	*s = FwProtoToID[j]
 	return nil
 }
+
+// FwConnState : state of a connection tracked by the firewall.
+type FwConnState uint8
+
+const (
+	// FwConnNew : packet starts a new connection.
+	FwConnNew FwConnState = iota
+	// FwConnEstablished : packet belongs to a connection which has seen packets
+	// in both directions.
+	FwConnEstablished
+	// FwConnRelated : packet starts a new connection associated with an existing
+	// connection (e.g. FTP data transfer or ICMP error).
+	FwConnRelated
+	// FwConnInvalid : packet is not associated with any known connection.
+	FwConnInvalid
+)
+
+// FwConnStateToString : convert FwConnState to string representation used in JSON.
+var FwConnStateToString = map[FwConnState]string{
+	FwConnNew:         "new",
+	FwConnEstablished: "established",
+	FwConnRelated:     "related",
+	FwConnInvalid:     "invalid",
+}
+
+// FwConnStateToID : get FwConnState from a string representation.
+var FwConnStateToID = map[string]FwConnState{
+	"":            FwConnNew, // default value
+	"new":         FwConnNew,
+	"established": FwConnEstablished,
+	"related":     FwConnRelated,
+	"invalid":     FwConnInvalid,
+}
+
+// MarshalJSON marshals the enum as a quoted json string.
+func (s FwConnState) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString(`"`)
+	buffer.WriteString(FwConnStateToString[s])
+	buffer.WriteString(`"`)
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON un-marshals a quoted json string to the enum value.
+func (s *FwConnState) UnmarshalJSON(b []byte) error {
+	var j string
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = FwConnStateToID[j]
+	return nil
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"github.com/lf-edge/eve/libs/depgraph"
 )
 
@@ -10,7 +12,23 @@ type SDNStatus struct {
 	MgmtIPs []string `json:"mgmtIPs"`
 	// ConfigErrors : a set of current configuration errors. Normally this should be empty.
 	ConfigErrors []ConfigError `json:"configErrors,omitempty"`
-	// TODO: more fields...
+	// Ports : state and counters of every port from the network model.
+	Ports []PortStatus `json:"ports,omitempty"`
+	// DHCPServers : leases handed out by DHCP servers of networks with DHCP enabled.
+	DHCPServers []DHCPServerStatus `json:"dhcpServers,omitempty"`
+	// DNSServers : statistics of DNS queries served by DNS server endpoints.
+	DNSServers []DNSServerStatus `json:"dnsServers,omitempty"`
+	// HTTPProxies : request statistics of explicit and transparent proxies.
+	HTTPProxies []HTTPProxyStatus `json:"httpProxies,omitempty"`
+	// HTTPServers : request statistics of HTTP server endpoints.
+	HTTPServers []HTTPServerStatus `json:"httpServers,omitempty"`
+	// CaptivePortals : clients logged in through captive portal endpoints.
+	CaptivePortals []CaptivePortalStatus `json:"captivePortals,omitempty"`
+	// Firewall : hit counters of firewall rules (in the order of Firewall.Rules).
+	Firewall []FwRuleStatus `json:"firewall,omitempty"`
+	// Conntrack : summary of the connection tracking table of the main network
+	// namespace, where traffic is routed (and NATed) between networks.
+	Conntrack ConntrackSummary `json:"conntrack"`
 }
 
 // ConfigError : error returned if the SDN agent failed to configure some configuration item.
@@ -20,3 +38,196 @@ type ConfigError struct {
 	// ErrMsg : error message
 	ErrMsg string
 }
+
+// PortStatus : state of a port (physical interface connecting SDN with EVE).
+type PortStatus struct {
+	// LogicalLabel : label of the port from the network model.
+	LogicalLabel string `json:"logicalLabel"`
+	// IfName : name of the interface inside SDN VM.
+	// Empty if the interface was not found.
+	IfName string `json:"ifName,omitempty"`
+	// MAC address of the port.
+	MAC string `json:"mac"`
+	// AdminUp : true if the interface is administratively enabled.
+	AdminUp bool `json:"adminUp"`
+	// OperState : operational state of the interface (e.g. "up", "down").
+	OperState string `json:"operState,omitempty"`
+	// MTU : Maximum transmission unit configured for the interface.
+	MTU int `json:"mtu,omitempty"`
+	// Counters : interface traffic counters.
+	Counters LinkCounters `json:"counters"`
+}
+
+// LinkCounters : traffic counters of a network interface.
+type LinkCounters struct {
+	RxPackets uint64 `json:"rxPackets"`
+	TxPackets uint64 `json:"txPackets"`
+	RxBytes   uint64 `json:"rxBytes"`
+	TxBytes   uint64 `json:"txBytes"`
+	RxErrors  uint64 `json:"rxErrors"`
+	TxErrors  uint64 `json:"txErrors"`
+	RxDropped uint64 `json:"rxDropped"`
+	TxDropped uint64 `json:"txDropped"`
+}
+
+// DHCPServerStatus : status of the DHCP (and DHCPv6) server running for a network.
+type DHCPServerStatus struct {
+	// Network : logical label of the network.
+	Network string `json:"network"`
+	// Leases : currently active DHCP leases.
+	Leases []DHCPLease `json:"leases,omitempty"`
+}
+
+// DHCPLease : IP address leased to a client.
+type DHCPLease struct {
+	// MAC address of the client (for DHCPv6 only available if the client
+	// uses link-layer based DUID).
+	MAC string `json:"mac,omitempty"`
+	// IP : leased IP address or delegated IPv6 prefix (in the CIDR notation).
+	IP string `json:"ip"`
+	// Hostname : hostname reported by the client (can be empty).
+	Hostname string `json:"hostname,omitempty"`
+	// Expiry : lease expiration time. Zero value is used for infinite leases.
+	Expiry time.Time `json:"expiry"`
+}
+
+// DNSServerStatus : statistics of a DNS server endpoint.
+type DNSServerStatus struct {
+	// Endpoint : logical label of the DNS server endpoint.
+	Endpoint string `json:"endpoint"`
+	// Queries : total number of DNS queries received.
+	Queries uint64 `json:"queries"`
+	// Forwarded : number of queries forwarded to upstream servers.
+	Forwarded uint64 `json:"forwarded"`
+	// Static : number of queries answered from static entries.
+	Static uint64 `json:"static"`
+	// Cached : number of queries answered from the cache.
+	Cached uint64 `json:"cached"`
+	// Faulted : number of queries answered (or dropped) by fault rules
+	// without being resolved.
+	Faulted uint64 `json:"faulted,omitempty"`
+	// QueriedNames : number of queries received per domain name.
+	QueriedNames map[string]uint64 `json:"queriedNames,omitempty"`
+	// FaultRules : statistics of fault rules (in the order of DNSServer.FaultRules).
+	FaultRules []DNSFaultRuleStatus `json:"faultRules,omitempty"`
+}
+
+// DNSFaultRuleStatus : statistics of a DNS fault rule.
+type DNSFaultRuleStatus struct {
+	// FQDN : domain name that the rule applies to (with endpoint reference resolved).
+	FQDN string `json:"fqdn"`
+	// QueryType : type of DNS queries that the rule applies to.
+	QueryType string `json:"queryType,omitempty"`
+	// Action : action applied by the rule.
+	Action DNSFaultAction `json:"action"`
+	// Hits : number of queries that the rule was applied to.
+	Hits uint64 `json:"hits"`
+}
+
+// HTTPProxyStatus : statistics of an HTTP(S) proxy endpoint.
+type HTTPProxyStatus struct {
+	// Endpoint : logical label of the proxy endpoint.
+	Endpoint string `json:"endpoint"`
+	// Stats : proxy request statistics.
+	Stats ProxyStats `json:"stats"`
+}
+
+// ProxyStats : request statistics collected by goproxy.
+type ProxyStats struct {
+	// Requests : total number of proxied requests (CONNECT included).
+	Requests uint64 `json:"requests"`
+	// Forwarded : number of requests forwarded to the destination.
+	Forwarded uint64 `json:"forwarded"`
+	// Rejected : number of requests rejected by proxy rules.
+	Rejected uint64 `json:"rejected"`
+	// MITM : number of connections intercepted by the proxy (TLS split in two).
+	MITM uint64 `json:"mitm"`
+	// AuthFailures : number of requests rejected due to failed authentication.
+	AuthFailures uint64 `json:"authFailures"`
+	// Hosts : number of requests per destination host.
+	Hosts map[string]uint64 `json:"hosts,omitempty"`
+}
+
+// HTTPServerStatus : request statistics of an HTTP server endpoint.
+type HTTPServerStatus struct {
+	// Endpoint : logical label of the HTTP server endpoint.
+	Endpoint string `json:"endpoint"`
+	// Requests : total number of received requests.
+	Requests uint64 `json:"requests"`
+	// Paths : number of requests per configured path (see HTTPServer.Paths).
+	// Requests not matching any path are only included in Requests.
+	Paths map[string]uint64 `json:"paths,omitempty"`
+	// RecentRequests : log of the most recent requests (oldest first).
+	RecentRequests []HTTPRequestRecord `json:"recentRequests,omitempty"`
+}
+
+// HTTPRequestRecord : record of a request received by an HTTP server.
+type HTTPRequestRecord struct {
+	// Time when the request was received.
+	Time time.Time `json:"time"`
+	// Client : address of the client (<ip>:<port>).
+	Client string `json:"client"`
+	// Method : HTTP method.
+	Method string `json:"method"`
+	// Host : requested host.
+	Host string `json:"host"`
+	// URL : requested URL (path and query).
+	URL string `json:"url"`
+	// TLS : true if the request was received over HTTPS.
+	TLS bool `json:"tls,omitempty"`
+	// UserAgent : user agent reported by the client.
+	UserAgent string `json:"userAgent,omitempty"`
+	// StatusCode : status code of the response.
+	StatusCode int `json:"statusCode"`
+}
+
+// CaptivePortalStatus : state of a captive portal endpoint.
+type CaptivePortalStatus struct {
+	// Endpoint : logical label of the captive portal endpoint.
+	Endpoint string `json:"endpoint"`
+	// Clients : clients currently logged in through the portal.
+	Clients []CaptivePortalClient `json:"clients,omitempty"`
+}
+
+// CaptivePortalClient : client logged in through a captive portal.
+type CaptivePortalClient struct {
+	// Network : logical label of the network where the client is connected.
+	Network string `json:"network"`
+	// IP : IP address of the client.
+	IP string `json:"ip"`
+	// Timeout : number of seconds remaining until the client is logged out.
+	// Zero if the session does not expire.
+	Timeout uint32 `json:"timeout,omitempty"`
+}
+
+// FwRuleStatus : hit counters of a firewall rule.
+type FwRuleStatus struct {
+	// Name of the rule (see FwRule.Name).
+	Name string `json:"name"`
+	// Action applied by the rule.
+	Action FwAction `json:"action"`
+	// Packets : number of packets matched by the rule (IPv4 and IPv6 combined).
+	Packets uint64 `json:"packets"`
+	// Bytes : number of bytes matched by the rule (IPv4 and IPv6 combined).
+	Bytes uint64 `json:"bytes"`
+	// DstFQDNIPs : IP addresses that FwRule.DstFQDNs were last resolved to.
+	DstFQDNIPs []string `json:"dstFQDNIPs,omitempty"`
+}
+
+// ConntrackSummary : summary of a connection tracking table.
+type ConntrackSummary struct {
+	// Flows : total number of tracked flows.
+	Flows uint64 `json:"flows"`
+	// IPv4Flows : number of tracked IPv4 flows.
+	IPv4Flows uint64 `json:"ipv4Flows"`
+	// IPv6Flows : number of tracked IPv6 flows.
+	IPv6Flows uint64 `json:"ipv6Flows"`
+	// FlowsByProto : number of tracked flows per L4 protocol (e.g. "tcp", "udp").
+	FlowsByProto map[string]uint64 `json:"flowsByProto,omitempty"`
+	// Packets : total number of packets (both directions) accounted to tracked flows.
+	// Zero unless conntrack accounting is enabled (net.netfilter.nf_conntrack_acct).
+	Packets uint64 `json:"packets"`
+	// Bytes : total number of bytes (both directions) accounted to tracked flows.
+	// Zero unless conntrack accounting is enabled (net.netfilter.nf_conntrack_acct).
+	Bytes uint64 `json:"bytes"`
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lf-edge/eden/sdn/vm/cmd/captiveportal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	httpPort  = 80
+	httpsPort = 443
+	dnsPort   = 53
+)
+
+// Captive portal intercepting traffic of clients which have not logged in yet.
+// Clients are redirected to the login page and once they log in, their IP addresses
+// are added into IP sets of their networks, which lets their traffic pass.
+// Interception itself is done by iptables rules installed by the SDN agent.
+func main() {
+	log.SetReportCaller(true)
+	configFile := flag.String("c", "/etc/captiveportal.conf", "Captive portal config file")
+	flag.Parse()
+
+	// Read and parse config file.
+	configBytes, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Fatalf("failed to read config file %s: %v", *configFile, err)
+	}
+	var portalConfig config.CaptivePortalConfig
+	if err = json.Unmarshal(configBytes, &portalConfig); err != nil {
+		log.Fatalf("failed to unmarshal captive portal config: %v", err)
+	}
+	if portalConfig.LogFile != "" {
+		logFile, err := os.OpenFile(portalConfig.LogFile, os.O_WRONLY|os.O_CREATE, 0755)
+		if err != nil {
+			log.Fatalf("failed to open log file %s: %v", portalConfig.LogFile, err)
+		}
+		log.SetOutput(logFile)
+	}
+	if portalConfig.Verbose {
+		log.SetLevel(log.DebugLevel)
+	} else {
+		log.SetLevel(log.InfoLevel)
+	}
+
+	p, err := newPortal(portalConfig)
+	if err != nil {
+		log.Fatalf("invalid captive portal config: %v", err)
+	}
+	httpAddr := net.JoinHostPort(portalConfig.ListenIP, fmt.Sprint(httpPort))
+	go func() {
+		log.Debugf("HTTP server listening on %s", httpAddr)
+		log.Fatalln(http.ListenAndServe(httpAddr, p))
+	}()
+	cert, err := p.certificate()
+	if err != nil {
+		log.Fatalf("failed to prepare certificate: %v", err)
+	}
+	httpsSrv := &http.Server{
+		Addr:      net.JoinHostPort(portalConfig.ListenIP, fmt.Sprint(httpsPort)),
+		Handler:   p,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+	go func() {
+		log.Debugf("HTTPS server listening on %s", httpsSrv.Addr)
+		log.Fatalln(httpsSrv.ListenAndServeTLS("", ""))
+	}()
+	if portalConfig.InterceptDNS {
+		dnsAddr := net.JoinHostPort(portalConfig.ListenIP, fmt.Sprint(dnsPort))
+		udpConn, err := net.ListenPacket("udp", dnsAddr)
+		if err != nil {
+			log.Fatalf("failed to listen on UDP %s: %v", dnsAddr, err)
+		}
+		tcpListener, err := net.Listen("tcp", dnsAddr)
+		if err != nil {
+			log.Fatalf("failed to listen on TCP %s: %v", dnsAddr, err)
+		}
+		go p.serveDNSOverUDP(udpConn)
+		go p.serveDNSOverTCP(tcpListener)
+	}
+
+	if portalConfig.PidFile != "" {
+		pidBytes := []byte(fmt.Sprintf("%d", os.Getpid()))
+		err = os.WriteFile(portalConfig.PidFile, pidBytes, 0664)
+		if err != nil {
+			log.Fatalf("failed to write PID file %s: %v", portalConfig.PidFile, err)
+		}
+		defer os.Remove(portalConfig.PidFile)
+	}
+
+	cancelChan := make(chan os.Signal, 1)
+	// Catch termination or interrupt signal.
+	signal.Notify(cancelChan, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-cancelChan
+	log.Infof("Caught terimation/interrupt signal: %v, exiting...", sig)
+}
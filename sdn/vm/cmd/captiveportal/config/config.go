@@ -0,0 +1,49 @@
+package config
+
+import (
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+)
+
+// CaptivePortalConfig : captive portal configuration formatted with JSON and passed
+// to captiveportal using the "-c" command line argument.
+type CaptivePortalConfig struct {
+	// ListenIP : IP address of the portal. Used to listen on and in redirects
+	// to the login page.
+	ListenIP string `json:"listenIP"`
+	// Hostname : FQDN of the portal, used as the subject of the generated
+	// self-signed certificate.
+	Hostname string `json:"hostname"`
+	// LogFile : file to write all log messages into.
+	LogFile string `json:"logFile"`
+	// PidFile : file to write captiveportal process PID.
+	PidFile string `json:"pidFile"`
+	// Verbose : enable to have all requests logged.
+	Verbose bool `json:"verbose"`
+	// LoginPage : HTML page served to clients which have not logged in yet.
+	// Leave empty to use the default login page.
+	LoginPage string `json:"loginPage"`
+	// Users : define for username/password authentication, leave empty
+	// to accept any login.
+	Users []sdnapi.UserCredentials `json:"users"`
+	// InterceptDNS : run DNS server answering every query with ListenIP.
+	InterceptDNS bool `json:"interceptDNS"`
+	// CertPEM : certificate in the PEM format used for HTTPS.
+	// Leave empty to generate a self-signed certificate.
+	CertPEM string `json:"certPEM"`
+	// KeyPEM : key in the PEM format used for HTTPS.
+	KeyPEM string `json:"keyPEM"`
+	// Networks : networks with the captive portal attached.
+	Networks []Network `json:"networks"`
+}
+
+// Network with the captive portal attached.
+type Network struct {
+	// LogicalLabel : logical label of the network.
+	LogicalLabel string `json:"logicalLabel"`
+	// Subnet : network subnet (IPv4 or IPv6) with clients of the portal.
+	Subnet string `json:"subnet"`
+	// NetNamespace : network namespace of the network.
+	NetNamespace string `json:"netNamespace"`
+	// IPSet : name of the IP set (inside NetNamespace) with logged-in clients.
+	IPSet string `json:"ipSet"`
+}
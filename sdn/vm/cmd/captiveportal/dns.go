@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	tcpIdleTimeout = 30 * time.Second
+	maxMessageSize = 65535
+)
+
+func (p *portal) serveDNSOverUDP(conn net.PacketConn) {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, clientAddr, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Fatalf("failed to read from UDP socket: %v", err)
+		}
+		resp := p.answerDNSQuery(buf[:n])
+		if resp == nil {
+			continue
+		}
+		if _, err := conn.WriteTo(resp, clientAddr); err != nil {
+			log.Errorf("Failed to send DNS response to %v: %v", clientAddr, err)
+		}
+	}
+}
+
+func (p *portal) serveDNSOverTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Fatalf("failed to accept TCP connection: %v", err)
+		}
+		go p.serveDNSOverTCPConn(conn)
+	}
+}
+
+func (p *portal) serveDNSOverTCPConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		// DNS messages sent over TCP are prefixed with two-byte length field.
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		resp := p.answerDNSQuery(query)
+		if resp == nil {
+			return
+		}
+		buf := make([]byte, 2, 2+len(resp))
+		binary.BigEndian.PutUint16(buf, uint16(len(resp)))
+		if _, err := conn.Write(append(buf, resp...)); err != nil {
+			log.Errorf("Failed to send DNS response to %v: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// answerDNSQuery answers every A (or AAAA for IPv6 portal) query with the portal IP.
+// Queries for other record types are answered with no records.
+// Zero TTL prevents clients from caching the answer after they log in.
+// Returns nil if the query cannot be parsed.
+func (p *portal) answerDNSQuery(query []byte) []byte {
+	var parser dnsmessage.Parser
+	hdr, err := parser.Start(query)
+	if err != nil {
+		log.Warnf("Failed to parse DNS query: %v", err)
+		return nil
+	}
+	q, err := parser.Question()
+	if err != nil {
+		log.Warnf("Failed to parse DNS question: %v", err)
+		return nil
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 hdr.ID,
+			Response:           true,
+			OpCode:             hdr.OpCode,
+			Authoritative:      true,
+			RecursionDesired:   hdr.RecursionDesired,
+			RecursionAvailable: true,
+		},
+		Questions: []dnsmessage.Question{q},
+	}
+	var body dnsmessage.ResourceBody
+	if ip4 := p.listenIP.To4(); ip4 != nil && q.Type == dnsmessage.TypeA {
+		a := &dnsmessage.AResource{}
+		copy(a.A[:], ip4)
+		body = a
+	} else if ip4 == nil && q.Type == dnsmessage.TypeAAAA {
+		aaaa := &dnsmessage.AAAAResource{}
+		copy(aaaa.AAAA[:], p.listenIP.To16())
+		body = aaaa
+	}
+	if body != nil {
+		msg.Answers = append(msg.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{
+				Name:  q.Name,
+				Type:  q.Type,
+				Class: q.Class,
+				TTL:   0,
+			},
+			Body: body,
+		})
+	}
+	log.Debugf("Answering DNS query %s %s (answers: %d)", q.Type, q.Name, len(msg.Answers))
+	reply, err := msg.Pack()
+	if err != nil {
+		log.Errorf("Failed to pack DNS reply: %v", err)
+		return nil
+	}
+	return reply
+}
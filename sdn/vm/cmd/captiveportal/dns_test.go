@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func packQuery(t *testing.T, name string, qType dnsmessage.Type) []byte {
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  qType,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	query, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query
+}
+
+func TestAnswerDNSQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		listenIP string
+		qType    dnsmessage.Type
+		answer   net.IP
+	}{
+		{name: "A query", listenIP: "10.0.0.1", qType: dnsmessage.TypeA,
+			answer: net.ParseIP("10.0.0.1")},
+		{name: "AAAA query with IPv4 portal", listenIP: "10.0.0.1", qType: dnsmessage.TypeAAAA},
+		{name: "AAAA query", listenIP: "fd00::1", qType: dnsmessage.TypeAAAA,
+			answer: net.ParseIP("fd00::1")},
+		{name: "A query with IPv6 portal", listenIP: "fd00::1", qType: dnsmessage.TypeA},
+		{name: "MX query", listenIP: "10.0.0.1", qType: dnsmessage.TypeMX},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &portal{listenIP: net.ParseIP(tt.listenIP)}
+			reply := p.answerDNSQuery(packQuery(t, "example.com.", tt.qType))
+			var msg dnsmessage.Message
+			if err := msg.Unpack(reply); err != nil {
+				t.Fatalf("failed to parse reply: %v", err)
+			}
+			if msg.ID != 42 || !msg.Response || !msg.Authoritative || msg.RCode != dnsmessage.RCodeSuccess {
+				t.Errorf("unexpected reply header: %+v", msg.Header)
+			}
+			if tt.answer == nil {
+				if len(msg.Answers) != 0 {
+					t.Errorf("expected no answers, got %v", msg.Answers)
+				}
+				return
+			}
+			if len(msg.Answers) != 1 {
+				t.Fatalf("expected one answer, got %d", len(msg.Answers))
+			}
+			answer := msg.Answers[0]
+			if answer.Header.TTL != 0 {
+				t.Errorf("answer TTL %d, expected 0", answer.Header.TTL)
+			}
+			var ip net.IP
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				ip = body.A[:]
+			case *dnsmessage.AAAAResource:
+				ip = body.AAAA[:]
+			}
+			if !ip.Equal(tt.answer) {
+				t.Errorf("answered %v, expected %v", ip, tt.answer)
+			}
+		})
+	}
+}
+
+func TestAnswerInvalidDNSQuery(t *testing.T) {
+	p := &portal{listenIP: net.ParseIP("10.0.0.1")}
+	if reply := p.answerDNSQuery([]byte{1, 2, 3}); reply != nil {
+		t.Errorf("expected no reply, got %v", reply)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eden/sdn/vm/cmd/captiveportal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultLoginPage = `<!DOCTYPE html>
+<html>
+<head><title>Eden-SDN Captive Portal</title></head>
+<body>
+<h1>Welcome to Eden-SDN</h1>
+<p>Please log in to access the Internet.</p>
+<form method="POST" action="/login">
+<input type="hidden" name="url" value="{{.URL}}">
+<label>Username: <input type="text" name="username"></label><br>
+<label>Password: <input type="password" name="password"></label><br>
+<input type="submit" value="Log in">
+</form>
+</body>
+</html>
+`
+
+const loggedInPage = `<!DOCTYPE html>
+<html>
+<head><title>Eden-SDN Captive Portal</title></head>
+<body><p>You are now logged in.</p></body>
+</html>
+`
+
+var defaultLoginTemplate = template.Must(template.New("login").Parse(defaultLoginPage))
+
+type portal struct {
+	config.CaptivePortalConfig
+	listenIP net.IP
+	networks []network
+}
+
+type network struct {
+	config.Network
+	subnet *net.IPNet
+}
+
+func newPortal(cfg config.CaptivePortalConfig) (*portal, error) {
+	p := &portal{
+		CaptivePortalConfig: cfg,
+		listenIP:            net.ParseIP(cfg.ListenIP),
+	}
+	if p.listenIP == nil {
+		return nil, fmt.Errorf("invalid listen IP %s", cfg.ListenIP)
+	}
+	for _, netCfg := range cfg.Networks {
+		_, subnet, err := net.ParseCIDR(netCfg.Subnet)
+		if err != nil {
+			return nil, fmt.Errorf("network %s has invalid subnet: %w",
+				netCfg.LogicalLabel, err)
+		}
+		p.networks = append(p.networks, network{Network: netCfg, subnet: subnet})
+	}
+	return p, nil
+}
+
+// ServeHTTP redirects requests for other hosts to the login page and handles
+// requests for the portal itself.
+func (p *portal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	var statusCode int
+	if !p.isPortalHost(host) {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		origURL := fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.RequestURI())
+		statusCode = p.redirectToLogin(w, r, origURL)
+	} else {
+		switch {
+		case r.URL.Path == "/login" && r.Method == http.MethodPost:
+			statusCode = p.login(w, r)
+		case r.URL.Path == "/logout" && r.Method == http.MethodPost:
+			statusCode = p.logout(w, r)
+		case r.URL.Path == "/login":
+			statusCode = p.serveLoginPage(w, r.URL.Query().Get("url"), http.StatusOK)
+		default:
+			statusCode = p.redirectToLogin(w, r, "")
+		}
+	}
+	log.Infof("%s %s %s%s -> %d", r.RemoteAddr, r.Method, r.Host,
+		r.URL.RequestURI(), statusCode)
+}
+
+func (p *portal) isPortalHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.Equal(p.listenIP)
+	}
+	return p.Hostname != "" && strings.EqualFold(host, p.Hostname)
+}
+
+func (p *portal) loginURL() string {
+	host := p.listenIP.String()
+	if p.listenIP.To4() == nil {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("http://%s/login", host)
+}
+
+func (p *portal) redirectToLogin(w http.ResponseWriter, r *http.Request, origURL string) int {
+	location := p.loginURL()
+	if origURL != "" {
+		location += "?url=" + url.QueryEscape(origURL)
+	}
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	http.Redirect(w, r, location, http.StatusFound)
+	return http.StatusFound
+}
+
+func (p *portal) serveLoginPage(w http.ResponseWriter, origURL string, statusCode int) int {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	w.WriteHeader(statusCode)
+	if p.LoginPage != "" {
+		_, _ = w.Write([]byte(p.LoginPage))
+		return statusCode
+	}
+	if err := defaultLoginTemplate.Execute(w, struct{ URL string }{origURL}); err != nil {
+		log.Errorf("Failed to render login page: %v", err)
+	}
+	return statusCode
+}
+
+// login adds the client into the IP set of its network.
+// By default, the client is the sender of the request, but it can be also
+// selected using the "client" form field (useful for scripted logins).
+func (p *portal) login(w http.ResponseWriter, r *http.Request) int {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid form: %v", err), http.StatusBadRequest)
+		return http.StatusBadRequest
+	}
+	if !p.authenticate(r.PostForm.Get("username"), r.PostForm.Get("password")) {
+		log.Infof("Failed login attempt from %s (user: %s)", r.RemoteAddr,
+			r.PostForm.Get("username"))
+		return p.serveLoginPage(w, r.PostForm.Get("url"), http.StatusUnauthorized)
+	}
+	clientIP, netw, statusCode, err := p.getClient(r)
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return statusCode
+	}
+	if err = ipsetCmd(netw, "add", clientIP); err != nil {
+		log.Error(err)
+		http.Error(w, "login failed", http.StatusInternalServerError)
+		return http.StatusInternalServerError
+	}
+	log.Infof("Client %s from network %s logged in", clientIP, netw.LogicalLabel)
+	if origURL := r.PostForm.Get("url"); origURL != "" {
+		http.Redirect(w, r, origURL, http.StatusFound)
+		return http.StatusFound
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(loggedInPage))
+	return http.StatusOK
+}
+
+// logout removes the client from the IP set of its network.
+func (p *portal) logout(w http.ResponseWriter, r *http.Request) int {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid form: %v", err), http.StatusBadRequest)
+		return http.StatusBadRequest
+	}
+	clientIP, netw, statusCode, err := p.getClient(r)
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return statusCode
+	}
+	if err = ipsetCmd(netw, "del", clientIP); err != nil {
+		log.Error(err)
+		http.Error(w, "logout failed", http.StatusInternalServerError)
+		return http.StatusInternalServerError
+	}
+	log.Infof("Client %s from network %s logged out", clientIP, netw.LogicalLabel)
+	return p.redirectToLogin(w, r, "")
+}
+
+func (p *portal) authenticate(username, password string) bool {
+	if len(p.Users) == 0 {
+		return true
+	}
+	for _, user := range p.Users {
+		if user.Username == username && user.Password == password {
+			return true
+		}
+	}
+	return false
+}
+
+// getClient returns IP address of the client to log in/out and the network
+// where the client is connected.
+func (p *portal) getClient(r *http.Request) (net.IP, network, int, error) {
+	clientAddr := r.PostForm.Get("client")
+	if clientAddr == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return nil, network{}, http.StatusInternalServerError,
+				fmt.Errorf("failed to parse client address %s: %w", r.RemoteAddr, err)
+		}
+		clientAddr = host
+	}
+	clientIP := net.ParseIP(clientAddr)
+	if clientIP == nil {
+		return nil, network{}, http.StatusBadRequest,
+			fmt.Errorf("invalid client IP address %s", clientAddr)
+	}
+	for _, netw := range p.networks {
+		if netw.subnet.Contains(clientIP) {
+			return clientIP, netw, http.StatusOK, nil
+		}
+	}
+	return nil, network{}, http.StatusBadRequest,
+		fmt.Errorf("client %s is not from a network with this captive portal", clientIP)
+}
+
+// ipsetCmd adds or removes client IP into/from the IP set of the network.
+func ipsetCmd(netw network, op string, clientIP net.IP) error {
+	out, err := exec.Command("ip", "netns", "exec", netw.NetNamespace,
+		"ipset", op, "-exist", netw.IPSet, clientIP.String()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ipset %s %s %s failed: %v, output: %s",
+			op, netw.IPSet, clientIP, err, out)
+	}
+	return nil
+}
+
+// certificate returns the configured certificate or generates a self-signed one.
+func (p *portal) certificate() (tls.Certificate, error) {
+	if p.CertPEM != "" {
+		return tls.X509KeyPair([]byte(p.CertPEM), []byte(p.KeyPEM))
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	subject := p.Hostname
+	if subject == "" {
+		subject = p.listenIP.String()
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: subject},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{p.listenIP},
+	}
+	if p.Hostname != "" {
+		template.DNSNames = []string{p.Hostname}
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: key}, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	"github.com/lf-edge/eden/sdn/vm/cmd/captiveportal/config"
+)
+
+func newTestPortal(t *testing.T, listenIP string) *portal {
+	p, err := newPortal(config.CaptivePortalConfig{
+		ListenIP: listenIP,
+		Hostname: "portal.sdn",
+		Users:    []sdnapi.UserCredentials{{Username: "user", Password: "secret"}},
+		Networks: []config.Network{{LogicalLabel: "net0", Subnet: "10.0.0.0/24"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestServeHTTPRedirects(t *testing.T) {
+	tests := []struct {
+		name     string
+		listenIP string
+		method   string
+		target   string
+		location string
+	}{
+		{name: "other host", listenIP: "10.0.0.1", method: http.MethodGet,
+			target: "http://example.com/some/path?a=b",
+			location: "http://10.0.0.1/login?url=" +
+				url.QueryEscape("http://example.com/some/path?a=b")},
+		{name: "other host over HTTPS", listenIP: "10.0.0.1", method: http.MethodGet,
+			target:   "https://example.com:8443/",
+			location: "http://10.0.0.1/login?url=" + url.QueryEscape("https://example.com:8443/")},
+		{name: "IPv6 portal", listenIP: "fd00::1", method: http.MethodGet,
+			target:   "http://example.com/",
+			location: "http://[fd00::1]/login?url=" + url.QueryEscape("http://example.com/")},
+		{name: "portal hostname", listenIP: "10.0.0.1", method: http.MethodGet,
+			target: "http://Portal.SDN/", location: "http://10.0.0.1/login"},
+		{name: "portal IP", listenIP: "10.0.0.1", method: http.MethodGet,
+			target: "http://10.0.0.1:80/index.html", location: "http://10.0.0.1/login"},
+		{name: "GET logout", listenIP: "10.0.0.1", method: http.MethodGet,
+			target: "http://10.0.0.1/logout", location: "http://10.0.0.1/login"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPortal(t, tt.listenIP)
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+			if rec.Code != http.StatusFound {
+				t.Errorf("status code %d, expected %d", rec.Code, http.StatusFound)
+			}
+			if location := rec.Header().Get("Location"); location != tt.location {
+				t.Errorf("location %s, expected %s", location, tt.location)
+			}
+		})
+	}
+}
+
+func TestServeHTTPLoginPage(t *testing.T) {
+	p := newTestPortal(t, "10.0.0.1")
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet,
+		"http://portal.sdn/login?url="+url.QueryEscape("http://example.com/"), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status code %d, expected %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), `value="http://example.com/"`) {
+		t.Errorf("login page does not contain the original URL: %s", rec.Body.String())
+	}
+
+	p.LoginPage = "custom login page"
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://portal.sdn/login", nil))
+	if rec.Body.String() != p.LoginPage {
+		t.Errorf("unexpected login page: %s", rec.Body.String())
+	}
+}
+
+func TestServeHTTPLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		form       url.Values
+		statusCode int
+	}{
+		{name: "wrong password", form: url.Values{"username": {"user"}, "password": {"wrong"}},
+			statusCode: http.StatusUnauthorized},
+		{name: "client from unknown network", form: url.Values{"username": {"user"},
+			"password": {"secret"}, "client": {"192.168.1.10"}}, statusCode: http.StatusBadRequest},
+		{name: "invalid client IP", form: url.Values{"username": {"user"},
+			"password": {"secret"}, "client": {"not-an-ip"}}, statusCode: http.StatusBadRequest},
+	}
+	p := newTestPortal(t, "10.0.0.1")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://10.0.0.1/login",
+				strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, req)
+			if rec.Code != tt.statusCode {
+				t.Errorf("status code %d, expected %d", rec.Code, tt.statusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import "time"
+
+// Dhcpv6SrvConfig : DHCPv6 server configuration formatted with JSON and passed
+// to dhcpv6srv using the "-c" command line argument.
+type Dhcpv6SrvConfig struct {
+	// Interface : name of the interface on which the server should listen.
+	Interface string `json:"interface"`
+	// LogFile : file to write all log messages into.
+	LogFile string `json:"logFile"`
+	// PidFile : file to write dhcpv6srv process PID.
+	PidFile string `json:"pidFile"`
+	// LeaseFile : file where the server publishes current leases (JSON-formatted
+	// list of Lease entries).
+	LeaseFile string `json:"leaseFile"`
+	// Verbose : enable to have all messages logged.
+	Verbose bool `json:"verbose"`
+	// Subnet : IPv6 subnet of the served network.
+	// Used to check if addresses sent by clients with Confirm are on-link.
+	Subnet string `json:"subnet"`
+	// AddrRange : range of addresses to allocate from (stateful DHCPv6).
+	// Leave nil to disable address allocation (stateless DHCPv6).
+	AddrRange *IPRange `json:"addrRange,omitempty"`
+	// PrefixDelegation : pool of prefixes to delegate from.
+	// Leave nil to disable prefix delegation.
+	PrefixDelegation *PrefixDelegation `json:"prefixDelegation,omitempty"`
+	// PreferredLifetime : preferred lifetime of leased addresses and prefixes in seconds.
+	PreferredLifetime uint32 `json:"preferredLifetime"`
+	// ValidLifetime : valid lifetime of leased addresses and prefixes in seconds.
+	ValidLifetime uint32 `json:"validLifetime"`
+	// DNSServers : IPv6 addresses of DNS servers to announce (option 23).
+	DNSServers []string `json:"dnsServers"`
+	// DomainName : domain name to announce in the domain search list (option 24).
+	DomainName string `json:"domainName"`
+	// NTPServer : IPv6 address or FQDN of an NTP server to announce (option 56).
+	NTPServer string `json:"ntpServer"`
+}
+
+// IPRange : a range of IP addresses.
+type IPRange struct {
+	// FromIP : start of the range (includes the address itself).
+	FromIP string `json:"fromIP"`
+	// ToIP : end of the range (includes the address itself).
+	ToIP string `json:"toIP"`
+}
+
+// PrefixDelegation : pool of prefixes to delegate from.
+type PrefixDelegation struct {
+	// Prefix : pool of IPv6 addresses to delegate prefixes from.
+	Prefix string `json:"prefix"`
+	// DelegatedLength : length of delegated prefixes.
+	DelegatedLength uint8 `json:"delegatedLength"`
+}
+
+// Lease : address or prefix leased to a client.
+type Lease struct {
+	// DUID : client DUID (hex-encoded).
+	DUID string `json:"duid"`
+	// MAC : client MAC address, if it can be obtained from DUID.
+	MAC string `json:"mac,omitempty"`
+	// IAID : identity association ID.
+	IAID uint32 `json:"iaid"`
+	// Address : leased IPv6 address (IA_NA) or delegated prefix in the CIDR
+	// notation (IA_PD).
+	Address string `json:"address"`
+	// Prefix : true if Address is a delegated prefix.
+	Prefix bool `json:"prefix"`
+	// Expiry : time when the lease expires.
+	Expiry time.Time `json:"expiry"`
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lf-edge/eden/sdn/vm/cmd/dhcpv6srv/config"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	serverPort = 547
+	// All_DHCP_Relay_Agents_and_Servers
+	serversMulticastIP = "ff02::1:2"
+)
+
+// Minimalistic DHCPv6 server (RFC 8415) supporting stateless DHCPv6, stateful address
+// allocation and prefix delegation. Delegated prefixes are routed towards the clients.
+// Relay agents are not supported.
+func main() {
+	log.SetReportCaller(true)
+	configFile := flag.String("c", "/etc/dhcpv6srv.conf", "DHCPv6 server config file")
+	flag.Parse()
+
+	// Read and parse config file.
+	configBytes, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Fatalf("failed to read config file %s: %v", *configFile, err)
+	}
+	var srvConfig config.Dhcpv6SrvConfig
+	if err = json.Unmarshal(configBytes, &srvConfig); err != nil {
+		log.Fatalf("failed to unmarshal DHCPv6 server config: %v", err)
+	}
+	if srvConfig.LogFile != "" {
+		logFile, err := os.OpenFile(srvConfig.LogFile, os.O_WRONLY|os.O_CREATE, 0755)
+		if err != nil {
+			log.Fatalf("failed to open log file %s: %v", srvConfig.LogFile, err)
+		}
+		log.SetOutput(logFile)
+	}
+	if srvConfig.Verbose {
+		log.SetLevel(log.DebugLevel)
+	} else {
+		log.SetLevel(log.InfoLevel)
+	}
+
+	intf, err := net.InterfaceByName(srvConfig.Interface)
+	if err != nil {
+		log.Fatalf("failed to get interface %s: %v", srvConfig.Interface, err)
+	}
+	srv, err := newServer(srvConfig, intf)
+	if err != nil {
+		log.Fatalf("invalid DHCPv6 server config: %v", err)
+	}
+	conn, err := listen(intf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv.publishLeases()
+	go srv.expireBindings()
+	go serve(srv, conn)
+
+	if srvConfig.PidFile != "" {
+		pidBytes := []byte(fmt.Sprintf("%d", os.Getpid()))
+		err = os.WriteFile(srvConfig.PidFile, pidBytes, 0664)
+		if err != nil {
+			log.Fatalf("failed to write PID file %s: %v", srvConfig.PidFile, err)
+		}
+		defer os.Remove(srvConfig.PidFile)
+	}
+
+	cancelChan := make(chan os.Signal, 1)
+	// Catch termination or interrupt signal.
+	signal.Notify(cancelChan, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-cancelChan
+	log.Infof("Caught terimation/interrupt signal: %v, exiting...", sig)
+}
+
+// listen opens UDP socket bound to the given interface and joined
+// to the multicast group of DHCPv6 servers.
+func listen(intf *net.Interface) (*net.UDPConn, error) {
+	listenConfig := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET,
+					unix.SO_BINDTODEVICE, intf.Name)
+				if sockErr != nil {
+					return
+				}
+				mreq := &unix.IPv6Mreq{Interface: uint32(intf.Index)}
+				copy(mreq.Multiaddr[:], net.ParseIP(serversMulticastIP))
+				sockErr = unix.SetsockoptIPv6Mreq(int(fd), unix.IPPROTO_IPV6,
+					unix.IPV6_JOIN_GROUP, mreq)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	// Interface may still be doing duplicate address detection for the link-local
+	// address, retry until the socket is successfully opened.
+	var err error
+	for i := 0; i < 10; i++ {
+		var conn net.PacketConn
+		conn, err = listenConfig.ListenPacket(context.Background(), "udp6",
+			fmt.Sprintf("[::]:%d", serverPort))
+		if err == nil {
+			return conn.(*net.UDPConn), nil
+		}
+		time.Sleep(time.Second)
+	}
+	return nil, fmt.Errorf("failed to listen on interface %s: %w", intf.Name, err)
+}
+
+func serve(srv *server, conn *net.UDPConn) {
+	buf := make([]byte, 1500)
+	for {
+		n, clientAddr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			log.Fatalf("failed to read from socket: %v", err)
+		}
+		msg, err := parseMessage(buf[:n])
+		if err != nil {
+			log.Warnf("Failed to parse message from %v: %v", clientAddr, err)
+			continue
+		}
+		log.Debugf("Received %v from %v", msg, clientAddr)
+		reply := srv.handleMessage(msg, clientAddr.IP)
+		if reply == nil {
+			log.Debugf("Ignoring %v from %v", msg, clientAddr)
+			continue
+		}
+		if _, err = conn.WriteToUDP(reply.marshal(), clientAddr); err != nil {
+			log.Errorf("Failed to send %v to %v: %v", reply, clientAddr, err)
+			continue
+		}
+		log.Debugf("Sent %v to %v", reply, clientAddr)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DHCPv6 message types (RFC 8415).
+const (
+	msgSolicit     = 1
+	msgAdvertise   = 2
+	msgRequest     = 3
+	msgConfirm     = 4
+	msgRenew       = 5
+	msgRebind      = 6
+	msgReply       = 7
+	msgRelease     = 8
+	msgDecline     = 9
+	msgInfoRequest = 11
+)
+
+// DHCPv6 option codes.
+const (
+	optClientID    = 1
+	optServerID    = 2
+	optIANA        = 3
+	optIAAddr      = 5
+	optORO         = 6
+	optPreference  = 7
+	optStatusCode  = 13
+	optRapidCommit = 14
+	optDNSServers  = 23
+	optDomainList  = 24
+	optIAPD        = 25
+	optIAPrefix    = 26
+	optNTPServer   = 56
+
+	ntpSubOptSrvAddr = 1
+	ntpSubOptFQDN    = 3
+)
+
+// DHCPv6 status codes.
+const (
+	statusSuccess       = 0
+	statusNoAddrsAvail  = 2
+	statusNoBinding     = 3
+	statusNotOnLink     = 4
+	statusNoPrefixAvail = 6
+)
+
+var msgTypeNames = map[uint8]string{
+	msgSolicit:     "Solicit",
+	msgAdvertise:   "Advertise",
+	msgRequest:     "Request",
+	msgConfirm:     "Confirm",
+	msgRenew:       "Renew",
+	msgRebind:      "Rebind",
+	msgReply:       "Reply",
+	msgRelease:     "Release",
+	msgDecline:     "Decline",
+	msgInfoRequest: "Information-request",
+}
+
+type option struct {
+	code uint16
+	data []byte
+}
+
+type message struct {
+	msgType uint8
+	txID    [3]byte
+	options []option
+}
+
+func (m *message) String() string {
+	name, ok := msgTypeNames[m.msgType]
+	if !ok {
+		name = fmt.Sprintf("type-%d", m.msgType)
+	}
+	return fmt.Sprintf("%s (xid: %x)", name, m.txID)
+}
+
+// getOption returns the first option with the given code.
+func (m *message) getOption(code uint16) []byte {
+	return findOption(m.options, code)
+}
+
+func (m *message) addOption(code uint16, data []byte) {
+	m.options = append(m.options, option{code: code, data: data})
+}
+
+func (m *message) marshal() []byte {
+	buf := []byte{m.msgType, m.txID[0], m.txID[1], m.txID[2]}
+	return append(buf, marshalOptions(m.options)...)
+}
+
+func parseMessage(data []byte) (*message, error) {
+	if len(data) < 4 {
+		return nil, errors.New("message is too short")
+	}
+	msg := &message{msgType: data[0]}
+	copy(msg.txID[:], data[1:4])
+	var err error
+	msg.options, err = parseOptions(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func parseOptions(data []byte) (options []option, err error) {
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, errors.New("truncated option header")
+		}
+		code := binary.BigEndian.Uint16(data[0:2])
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if len(data) < 4+length {
+			return nil, fmt.Errorf("truncated option %d", code)
+		}
+		options = append(options, option{code: code, data: data[4 : 4+length]})
+		data = data[4+length:]
+	}
+	return options, nil
+}
+
+func marshalOptions(options []option) (buf []byte) {
+	for _, opt := range options {
+		buf = appendUint16(buf, opt.code)
+		buf = appendUint16(buf, uint16(len(opt.data)))
+		buf = append(buf, opt.data...)
+	}
+	return buf
+}
+
+func findOption(options []option, code uint16) []byte {
+	for _, opt := range options {
+		if opt.code == code {
+			return opt.data
+		}
+	}
+	return nil
+}
+
+// identityAssoc : IA_NA or IA_PD option.
+type identityAssoc struct {
+	prefix  bool // IA_PD
+	iaid    uint32
+	options []option
+}
+
+func parseIA(opt option) (ia identityAssoc, err error) {
+	if len(opt.data) < 12 {
+		return ia, fmt.Errorf("IA option %d is too short", opt.code)
+	}
+	ia.prefix = opt.code == optIAPD
+	ia.iaid = binary.BigEndian.Uint32(opt.data[0:4])
+	// T1 and T2 suggested by the client are ignored.
+	ia.options, err = parseOptions(opt.data[12:])
+	return ia, err
+}
+
+// addresses returns addresses (IA_NA) or prefixes (IA_PD) included by the client.
+func (ia identityAssoc) addresses() (addrs []*net.IPNet) {
+	for _, opt := range ia.options {
+		switch {
+		case !ia.prefix && opt.code == optIAAddr && len(opt.data) >= 24:
+			ip := net.IP(append([]byte{}, opt.data[0:16]...))
+			addrs = append(addrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+		case ia.prefix && opt.code == optIAPrefix && len(opt.data) >= 25:
+			ip := net.IP(append([]byte{}, opt.data[9:25]...))
+			addrs = append(addrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(int(opt.data[8]), 128)})
+		}
+	}
+	return addrs
+}
+
+// marshalIA builds IA_NA or IA_PD option with a single address/prefix or with
+// a status code (if addr is nil).
+func marshalIA(isPrefix bool, iaid uint32, addr *net.IPNet, preferred, valid uint32,
+	status uint16, statusMsg string) option {
+	var data []byte
+	data = appendUint32(data, iaid)
+	var t1, t2 uint32
+	if addr != nil {
+		t1, t2 = preferred/2, preferred/5*4
+	}
+	data = appendUint32(data, t1)
+	data = appendUint32(data, t2)
+	var subOpts []option
+	if addr != nil {
+		var addrData []byte
+		if isPrefix {
+			ones, _ := addr.Mask.Size()
+			addrData = appendUint32(addrData, preferred)
+			addrData = appendUint32(addrData, valid)
+			addrData = append(addrData, uint8(ones))
+			addrData = append(addrData, addr.IP.To16()...)
+			subOpts = append(subOpts, option{code: optIAPrefix, data: addrData})
+		} else {
+			addrData = append(addrData, addr.IP.To16()...)
+			addrData = appendUint32(addrData, preferred)
+			addrData = appendUint32(addrData, valid)
+			subOpts = append(subOpts, option{code: optIAAddr, data: addrData})
+		}
+	}
+	if addr == nil || status != statusSuccess {
+		subOpts = append(subOpts, statusOption(status, statusMsg))
+	}
+	data = append(data, marshalOptions(subOpts)...)
+	code := uint16(optIANA)
+	if isPrefix {
+		code = optIAPD
+	}
+	return option{code: code, data: data}
+}
+
+func statusOption(status uint16, statusMsg string) option {
+	data := appendUint16(nil, status)
+	return option{code: optStatusCode, data: append(data, statusMsg...)}
+}
+
+// marshalDomainName encodes domain name in the DNS wire format (RFC 1035).
+func marshalDomainName(name string) (data []byte) {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		data = append(data, uint8(len(label)))
+		data = append(data, label...)
+	}
+	return append(data, 0)
+}
+
+// macFromDUID returns MAC address embedded in DUID-LLT or DUID-LL
+// with the Ethernet hardware type. Returns nil for other DUID types.
+func macFromDUID(duid []byte) net.HardwareAddr {
+	if len(duid) < 4 || binary.BigEndian.Uint16(duid[2:4]) != 1 {
+		return nil
+	}
+	switch binary.BigEndian.Uint16(duid[0:2]) {
+	case 1: // DUID-LLT
+		if len(duid) == 14 {
+			return net.HardwareAddr(duid[8:14])
+		}
+	case 3: // DUID-LL
+		if len(duid) == 10 {
+			return net.HardwareAddr(duid[4:10])
+		}
+	}
+	return nil
+}
+
+func appendUint16(buf []byte, value uint16) []byte {
+	return append(buf, byte(value>>8), byte(value))
+}
+
+func appendUint32(buf []byte, value uint32) []byte {
+	return append(buf, byte(value>>24), byte(value>>16), byte(value>>8), byte(value))
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *message
+		wantErr  bool
+	}{
+		{
+			name:     "no options",
+			data:     []byte{msgSolicit, 1, 2, 3},
+			expected: &message{msgType: msgSolicit, txID: [3]byte{1, 2, 3}},
+		},
+		{
+			name: "options",
+			data: []byte{msgRequest, 0xa, 0xb, 0xc,
+				0, optClientID, 0, 2, 0xde, 0xad,
+				0, optRapidCommit, 0, 0},
+			expected: &message{msgType: msgRequest, txID: [3]byte{0xa, 0xb, 0xc}, options: []option{
+				{code: optClientID, data: []byte{0xde, 0xad}},
+				{code: optRapidCommit, data: []byte{}},
+			}},
+		},
+		{name: "too short", data: []byte{msgSolicit, 1, 2}, wantErr: true},
+		{name: "truncated option header", data: []byte{msgSolicit, 1, 2, 3, 0, 1, 0}, wantErr: true},
+		{name: "truncated option", data: []byte{msgSolicit, 1, 2, 3, 0, 1, 0, 4, 1, 2}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseMessage(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(msg, tt.expected) {
+				t.Errorf("parsed %+v, expected %+v", msg, tt.expected)
+			}
+			if !bytes.Equal(msg.marshal(), tt.data) {
+				t.Errorf("marshaled %v, expected %v", msg.marshal(), tt.data)
+			}
+		})
+	}
+}
+
+func TestParseIA(t *testing.T) {
+	addr := net.ParseIP("fd00::100")
+	iaNA := option{code: optIANA, data: append([]byte{0, 0, 0, 7, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, optIAAddr, 0, 24}, append(addr.To16(), 0, 0, 0, 0, 0, 0, 0, 0)...)}
+	prefix := net.ParseIP("fd01:0:0:100::")
+	iaPD := option{code: optIAPD, data: append([]byte{0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, optIAPrefix, 0, 25, 0, 0, 0, 0, 0, 0, 0, 0, 56}, prefix.To16()...)}
+	tests := []struct {
+		name    string
+		opt     option
+		prefix  bool
+		iaid    uint32
+		addrs   []string
+		wantErr bool
+	}{
+		{name: "IA_NA", opt: iaNA, iaid: 7, addrs: []string{"fd00::100/128"}},
+		{name: "IA_PD", opt: iaPD, prefix: true, iaid: 8, addrs: []string{"fd01:0:0:100::/56"}},
+		{name: "empty IA_NA", opt: option{code: optIANA, data: make([]byte, 12)}},
+		{name: "too short", opt: option{code: optIANA, data: make([]byte, 11)}, wantErr: true},
+		{name: "truncated address", opt: option{code: optIANA, data: append(make([]byte, 12), 0, optIAAddr, 0, 24, 1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ia, err := parseIA(tt.opt)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ia.prefix != tt.prefix || ia.iaid != tt.iaid {
+				t.Errorf("parsed %+v", ia)
+			}
+			var addrs []string
+			for _, addr := range ia.addresses() {
+				addrs = append(addrs, addr.String())
+			}
+			if !reflect.DeepEqual(addrs, tt.addrs) {
+				t.Errorf("addresses %v, expected %v", addrs, tt.addrs)
+			}
+		})
+	}
+}
+
+func TestMarshalIA(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("fd01:0:0:100::/56")
+	addr := &net.IPNet{IP: net.ParseIP("fd00::100"), Mask: net.CIDRMask(128, 128)}
+	tests := []struct {
+		name   string
+		prefix bool
+		addr   *net.IPNet
+	}{
+		{name: "IA_NA", addr: addr},
+		{name: "IA_PD", prefix: true, addr: prefix},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := marshalIA(tt.prefix, 42, tt.addr, 3600, 7200, statusSuccess, "")
+			ia, err := parseIA(opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ia.prefix != tt.prefix || ia.iaid != 42 {
+				t.Errorf("parsed %+v", ia)
+			}
+			if addrs := ia.addresses(); len(addrs) != 1 || addrs[0].String() != tt.addr.String() {
+				t.Errorf("addresses %v, expected %v", addrs, tt.addr)
+			}
+			// T1 and T2
+			if !bytes.Equal(opt.data[4:12], []byte{0, 0, 0x07, 0x08, 0, 0, 0x0b, 0x40}) {
+				t.Errorf("unexpected T1/T2 %v", opt.data[4:12])
+			}
+			if findOption(ia.options, optStatusCode) != nil {
+				t.Error("status code included on success")
+			}
+		})
+	}
+}
+
+func TestMarshalIAStatus(t *testing.T) {
+	opt := marshalIA(true, 1, nil, 3600, 7200, statusNoPrefixAvail, "no prefixes available")
+	if opt.code != optIAPD {
+		t.Errorf("option code %d", opt.code)
+	}
+	ia, err := parseIA(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ia.addresses()) != 0 {
+		t.Error("address included with error status")
+	}
+	status := findOption(ia.options, optStatusCode)
+	if !bytes.Equal(status, append([]byte{0, statusNoPrefixAvail}, "no prefixes available"...)) {
+		t.Errorf("status %v", status)
+	}
+	if !bytes.Equal(opt.data[4:12], make([]byte, 8)) {
+		t.Errorf("non-zero T1/T2 with error status: %v", opt.data[4:12])
+	}
+}
+
+func TestMarshalDomainName(t *testing.T) {
+	expected := []byte{3, 's', 'd', 'n', 4, 't', 'e', 's', 't', 0}
+	for _, name := range []string{"sdn.test", "sdn.test."} {
+		if data := marshalDomainName(name); !bytes.Equal(data, expected) {
+			t.Errorf("%s: %v", name, data)
+		}
+	}
+}
+
+func TestMacFromDUID(t *testing.T) {
+	mac := []byte{2, 0xfe, 0, 1, 2, 3}
+	tests := []struct {
+		name string
+		duid []byte
+		mac  net.HardwareAddr
+	}{
+		{name: "DUID-LL", duid: append([]byte{0, 3, 0, 1}, mac...), mac: mac},
+		{name: "DUID-LLT", duid: append([]byte{0, 1, 0, 1, 1, 2, 3, 4}, mac...), mac: mac},
+		{name: "DUID-EN", duid: []byte{0, 2, 0, 0, 0, 9, 1, 2}},
+		{name: "not Ethernet", duid: append([]byte{0, 3, 0, 6}, mac...)},
+		{name: "short", duid: []byte{0, 3}},
+	}
+	for _, tt := range tests {
+		if got := macFromDUID(tt.duid); !bytes.Equal(got, tt.mac) {
+			t.Errorf("%s: MAC %v, expected %v", tt.name, got, tt.mac)
+		}
+	}
+}
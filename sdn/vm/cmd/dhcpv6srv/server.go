@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eden/sdn/vm/cmd/dhcpv6srv/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	// Binding offered with Advertise is kept reserved only for a short time,
+	// unless it is requested by the client.
+	offerTimeout = time.Minute
+	// Limit on the number of candidates checked when looking for a free address/prefix.
+	maxAllocAttempts = 1 << 16
+)
+
+type bindingKey struct {
+	duid   string // hex-encoded
+	iaid   uint32
+	prefix bool
+}
+
+type binding struct {
+	addr      *net.IPNet
+	mac       net.HardwareAddr
+	expiry    time.Time
+	committed bool
+	// Link-local address of the client, used as the next hop for the delegated prefix.
+	routeVia net.IP
+}
+
+type server struct {
+	sync.Mutex
+	config   config.Dhcpv6SrvConfig
+	ifIndex  int
+	serverID []byte
+	subnet   *net.IPNet
+	// Stateful DHCPv6 address range.
+	rangeFrom, rangeTo *big.Int
+	// Prefix delegation pool.
+	pdPool *net.IPNet
+	// Announced options.
+	dnsServers []byte
+	domainList []byte
+	ntpServer  []byte
+	bindings   map[bindingKey]*binding
+}
+
+func newServer(cfg config.Dhcpv6SrvConfig, intf *net.Interface) (*server, error) {
+	srv := &server{
+		config:   cfg,
+		ifIndex:  intf.Index,
+		bindings: make(map[bindingKey]*binding),
+	}
+	// DUID-LL based on the interface MAC address.
+	srv.serverID = append([]byte{0, 3, 0, 1}, intf.HardwareAddr...)
+	var err error
+	if _, srv.subnet, err = net.ParseCIDR(cfg.Subnet); err != nil {
+		return nil, fmt.Errorf("invalid subnet: %w", err)
+	}
+	if cfg.AddrRange != nil {
+		fromIP := net.ParseIP(cfg.AddrRange.FromIP)
+		toIP := net.ParseIP(cfg.AddrRange.ToIP)
+		if fromIP == nil || toIP == nil {
+			return nil, fmt.Errorf("invalid address range: %+v", *cfg.AddrRange)
+		}
+		srv.rangeFrom = new(big.Int).SetBytes(fromIP.To16())
+		srv.rangeTo = new(big.Int).SetBytes(toIP.To16())
+	}
+	if cfg.PrefixDelegation != nil {
+		if _, srv.pdPool, err = net.ParseCIDR(cfg.PrefixDelegation.Prefix); err != nil {
+			return nil, fmt.Errorf("invalid prefix delegation pool: %w", err)
+		}
+	}
+	for _, dnsServer := range cfg.DNSServers {
+		ip := net.ParseIP(dnsServer)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid DNS server IP: %s", dnsServer)
+		}
+		srv.dnsServers = append(srv.dnsServers, ip.To16()...)
+	}
+	if cfg.DomainName != "" {
+		srv.domainList = marshalDomainName(cfg.DomainName)
+	}
+	if cfg.NTPServer != "" {
+		var subOpt option
+		if ip := net.ParseIP(cfg.NTPServer); ip != nil {
+			subOpt = option{code: ntpSubOptSrvAddr, data: ip.To16()}
+		} else {
+			subOpt = option{code: ntpSubOptFQDN, data: marshalDomainName(cfg.NTPServer)}
+		}
+		srv.ntpServer = marshalOptions([]option{subOpt})
+	}
+	return srv, nil
+}
+
+// handleMessage processes message received from a client and returns reply
+// to send back (nil if the message should be ignored).
+func (s *server) handleMessage(msg *message, clientIP net.IP) *message {
+	clientID := msg.getOption(optClientID)
+	serverID := msg.getOption(optServerID)
+	switch msg.msgType {
+	case msgSolicit, msgConfirm, msgRebind:
+		if clientID == nil || serverID != nil {
+			return nil
+		}
+	case msgRequest, msgRenew, msgRelease, msgDecline:
+		if clientID == nil || !bytes.Equal(serverID, s.serverID) {
+			return nil
+		}
+	case msgInfoRequest:
+		if serverID != nil && !bytes.Equal(serverID, s.serverID) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	reply := &message{msgType: msgReply, txID: msg.txID}
+	reply.addOption(optServerID, s.serverID)
+	if clientID != nil {
+		reply.addOption(optClientID, clientID)
+	}
+	s.Lock()
+	defer s.Unlock()
+	switch msg.msgType {
+	case msgSolicit:
+		commit := msg.getOption(optRapidCommit) != nil
+		if commit {
+			reply.addOption(optRapidCommit, nil)
+		} else {
+			reply.msgType = msgAdvertise
+			// Make the client to select this server immediately.
+			reply.addOption(optPreference, []byte{255})
+		}
+		s.assignIAs(msg, reply, clientID, clientIP, commit)
+	case msgRequest, msgRenew, msgRebind:
+		s.assignIAs(msg, reply, clientID, clientIP, true)
+	case msgRelease, msgDecline:
+		s.releaseIAs(msg, clientID)
+		reply.addOption(optStatusCode, statusOption(statusSuccess, "").data)
+		// Other configuration is not included.
+		return reply
+	case msgConfirm:
+		var addrs []*net.IPNet
+		for _, ia := range s.getIAs(msg) {
+			addrs = append(addrs, ia.addresses()...)
+		}
+		if len(addrs) == 0 {
+			return nil
+		}
+		status := uint16(statusSuccess)
+		for _, addr := range addrs {
+			if !s.subnet.Contains(addr.IP) {
+				status = statusNotOnLink
+			}
+		}
+		reply.addOption(optStatusCode, statusOption(status, "").data)
+	}
+	s.addConfigOptions(reply)
+	return reply
+}
+
+func (s *server) getIAs(msg *message) (ias []identityAssoc) {
+	for _, opt := range msg.options {
+		if opt.code != optIANA && opt.code != optIAPD {
+			continue
+		}
+		ia, err := parseIA(opt)
+		if err != nil {
+			log.Warnf("Invalid IA option in %v: %v", msg, err)
+			continue
+		}
+		ias = append(ias, ia)
+	}
+	return ias
+}
+
+func (s *server) assignIAs(msg *message, reply *message, clientID []byte,
+	clientIP net.IP, commit bool) {
+	preferred := s.config.PreferredLifetime
+	valid := s.config.ValidLifetime
+	for _, ia := range s.getIAs(msg) {
+		key := bindingKey{duid: hex.EncodeToString(clientID), iaid: ia.iaid, prefix: ia.prefix}
+		var addr *net.IPNet
+		if ia.prefix && s.pdPool != nil {
+			addr = s.allocate(key, ia.addresses())
+		}
+		if !ia.prefix && s.rangeFrom != nil {
+			addr = s.allocate(key, ia.addresses())
+		}
+		if addr == nil {
+			status, statusMsg := uint16(statusNoAddrsAvail), "no addresses available"
+			if ia.prefix {
+				status, statusMsg = statusNoPrefixAvail, "no prefixes available"
+			}
+			if msg.msgType == msgRenew || msg.msgType == msgRebind {
+				status, statusMsg = statusNoBinding, "no binding"
+			}
+			reply.options = append(reply.options,
+				marshalIA(ia.prefix, ia.iaid, nil, 0, 0, status, statusMsg))
+			continue
+		}
+		b := s.bindings[key]
+		b.mac = macFromDUID(clientID)
+		if commit {
+			if !b.committed {
+				log.Infof("Leased %v to client %s (IAID %x)", addr, key.duid, key.iaid)
+			}
+			b.committed = true
+			b.expiry = time.Now().Add(time.Duration(valid) * time.Second)
+			if ia.prefix && !clientIP.Equal(b.routeVia) {
+				s.routeDelegatedPrefix(b, clientIP)
+			}
+		} else if !b.committed {
+			b.expiry = time.Now().Add(offerTimeout)
+		}
+		reply.options = append(reply.options,
+			marshalIA(ia.prefix, ia.iaid, addr, preferred, valid, statusSuccess, ""))
+	}
+	if commit {
+		s.publishLeases()
+	}
+}
+
+// allocate returns address/prefix already bound to the IA or allocates a new one
+// (preferring the one requested by the client if it is available).
+func (s *server) allocate(key bindingKey, requested []*net.IPNet) *net.IPNet {
+	if b, exists := s.bindings[key]; exists {
+		return b.addr
+	}
+	for _, addr := range requested {
+		// Prefix hint from the client may have host bits set.
+		addr = &net.IPNet{IP: addr.IP.Mask(addr.Mask), Mask: addr.Mask}
+		if s.canAllocate(key.prefix, addr) {
+			s.bindings[key] = &binding{addr: addr}
+			return addr
+		}
+	}
+	for i := 0; i < maxAllocAttempts; i++ {
+		var addr *net.IPNet
+		if key.prefix {
+			addr = s.nthPrefix(i)
+		} else {
+			addr = s.nthAddress(i)
+		}
+		if addr == nil {
+			break
+		}
+		if s.canAllocate(key.prefix, addr) {
+			s.bindings[key] = &binding{addr: addr}
+			return addr
+		}
+	}
+	return nil
+}
+
+func (s *server) nthAddress(n int) *net.IPNet {
+	num := new(big.Int).Add(s.rangeFrom, big.NewInt(int64(n)))
+	if num.Cmp(s.rangeTo) > 0 {
+		return nil
+	}
+	return &net.IPNet{IP: bigToIP(num), Mask: net.CIDRMask(128, 128)}
+}
+
+func (s *server) nthPrefix(n int) *net.IPNet {
+	poolLen, _ := s.pdPool.Mask.Size()
+	prefixLen := int(s.config.PrefixDelegation.DelegatedLength)
+	if prefixLen-poolLen < 31 && n >= 1<<uint(prefixLen-poolLen) {
+		return nil
+	}
+	num := new(big.Int).Lsh(big.NewInt(int64(n)), uint(128-prefixLen))
+	num.Add(num, new(big.Int).SetBytes(s.pdPool.IP.To16()))
+	return &net.IPNet{IP: bigToIP(num), Mask: net.CIDRMask(prefixLen, 128)}
+}
+
+// canAllocate returns true if addr is inside the configured range/pool and does not
+// overlap with any address/prefix already bound.
+func (s *server) canAllocate(prefix bool, addr *net.IPNet) bool {
+	if prefix {
+		ones, _ := addr.Mask.Size()
+		if ones != int(s.config.PrefixDelegation.DelegatedLength) ||
+			!s.pdPool.Contains(addr.IP) {
+			return false
+		}
+	} else {
+		num := new(big.Int).SetBytes(addr.IP.To16())
+		if num.Cmp(s.rangeFrom) < 0 || num.Cmp(s.rangeTo) > 0 {
+			return false
+		}
+	}
+	for key, b := range s.bindings {
+		if key.prefix == prefix && (b.addr.Contains(addr.IP) || addr.Contains(b.addr.IP)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *server) releaseIAs(msg *message, clientID []byte) {
+	for _, ia := range s.getIAs(msg) {
+		key := bindingKey{duid: hex.EncodeToString(clientID), iaid: ia.iaid, prefix: ia.prefix}
+		if b, exists := s.bindings[key]; exists {
+			s.removeBinding(key, b)
+		}
+	}
+	s.publishLeases()
+}
+
+func (s *server) removeBinding(key bindingKey, b *binding) {
+	if key.prefix && b.routeVia != nil {
+		route := &netlink.Route{LinkIndex: s.ifIndex, Dst: b.addr, Gw: b.routeVia}
+		if err := netlink.RouteDel(route); err != nil {
+			log.Warnf("Failed to remove route for delegated prefix %v: %v", b.addr, err)
+		}
+	}
+	delete(s.bindings, key)
+}
+
+func (s *server) routeDelegatedPrefix(b *binding, clientIP net.IP) {
+	route := &netlink.Route{LinkIndex: s.ifIndex, Dst: b.addr, Gw: clientIP}
+	if err := netlink.RouteReplace(route); err != nil {
+		log.Errorf("Failed to route delegated prefix %v via %v: %v", b.addr, clientIP, err)
+		return
+	}
+	log.Infof("Delegated prefix %v routed via %v", b.addr, clientIP)
+	b.routeVia = clientIP
+}
+
+func (s *server) addConfigOptions(reply *message) {
+	if len(s.dnsServers) > 0 {
+		reply.addOption(optDNSServers, s.dnsServers)
+	}
+	if len(s.domainList) > 0 {
+		reply.addOption(optDomainList, s.domainList)
+	}
+	if len(s.ntpServer) > 0 {
+		reply.addOption(optNTPServer, s.ntpServer)
+	}
+}
+
+// expireBindings periodically removes expired bindings.
+func (s *server) expireBindings() {
+	for range time.Tick(5 * time.Second) {
+		s.Lock()
+		var expired bool
+		for key, b := range s.bindings {
+			if time.Now().After(b.expiry) {
+				expired = expired || b.committed
+				s.removeBinding(key, b)
+			}
+		}
+		if expired {
+			s.publishLeases()
+		}
+		s.Unlock()
+	}
+}
+
+// publishLeases writes committed bindings into the lease file.
+func (s *server) publishLeases() {
+	if s.config.LeaseFile == "" {
+		return
+	}
+	leases := []config.Lease{}
+	for key, b := range s.bindings {
+		if !b.committed {
+			continue
+		}
+		lease := config.Lease{
+			DUID:   key.duid,
+			IAID:   key.iaid,
+			Prefix: key.prefix,
+			Expiry: b.expiry,
+		}
+		if b.mac != nil {
+			lease.MAC = b.mac.String()
+		}
+		if key.prefix {
+			lease.Address = b.addr.String()
+		} else {
+			lease.Address = b.addr.IP.String()
+		}
+		leases = append(leases, lease)
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].Address < leases[j].Address
+	})
+	leasesJSON, err := json.MarshalIndent(leases, "", " ")
+	if err != nil {
+		log.Errorf("Failed to marshal leases: %v", err)
+		return
+	}
+	tmpFile := s.config.LeaseFile + ".tmp"
+	if err = os.WriteFile(tmpFile, leasesJSON, 0644); err != nil {
+		log.Errorf("Failed to write leases: %v", err)
+		return
+	}
+	if err = os.Rename(tmpFile, s.config.LeaseFile); err != nil {
+		log.Errorf("Failed to write leases: %v", err)
+	}
+}
+
+func bigToIP(num *big.Int) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	numBytes := num.Bytes()
+	if len(numBytes) > net.IPv6len {
+		numBytes = numBytes[len(numBytes)-net.IPv6len:]
+	}
+	copy(ip[net.IPv6len-len(numBytes):], numBytes)
+	return ip
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lf-edge/eden/sdn/vm/cmd/dhcpv6srv/config"
+)
+
+func newTestServer(t *testing.T, cfg config.Dhcpv6SrvConfig) *server {
+	t.Helper()
+	if cfg.Subnet == "" {
+		cfg.Subnet = "fd00::/64"
+	}
+	srv, err := newServer(cfg, &net.Interface{Index: 1, HardwareAddr: net.HardwareAddr{2, 0xfe, 0, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func mustCIDR(t *testing.T, cidr string) *net.IPNet {
+	t.Helper()
+	ip, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subnet.IP = ip
+	return subnet
+}
+
+func TestNthAddress(t *testing.T) {
+	srv := newTestServer(t, config.Dhcpv6SrvConfig{
+		AddrRange: &config.IPRange{FromIP: "fd00::100", ToIP: "fd00::101"},
+	})
+	for n, expected := range []string{"fd00::100/128", "fd00::101/128", "<nil>"} {
+		if addr := srv.nthAddress(n); addr.String() != expected {
+			t.Errorf("address %d: %v, expected %s", n, addr, expected)
+		}
+	}
+}
+
+func TestNthPrefix(t *testing.T) {
+	srv := newTestServer(t, config.Dhcpv6SrvConfig{
+		PrefixDelegation: &config.PrefixDelegation{Prefix: "fd01::/54", DelegatedLength: 56},
+	})
+	for n, expected := range []string{"fd01::/56", "fd01:0:0:100::/56", "fd01:0:0:200::/56", "fd01:0:0:300::/56", "<nil>"} {
+		if prefix := srv.nthPrefix(n); prefix.String() != expected {
+			t.Errorf("prefix %d: %v, expected %s", n, prefix, expected)
+		}
+	}
+}
+
+func TestAllocatePrefix(t *testing.T) {
+	srv := newTestServer(t, config.Dhcpv6SrvConfig{
+		PrefixDelegation: &config.PrefixDelegation{Prefix: "fd01::/54", DelegatedLength: 56},
+	})
+	tests := []struct {
+		name      string
+		key       bindingKey
+		requested []string
+		expected  string
+	}{
+		{name: "first free", key: bindingKey{duid: "a", prefix: true}, expected: "fd01::/56"},
+		{name: "requested", key: bindingKey{duid: "b", prefix: true}, requested: []string{"fd01:0:0:200::/56"},
+			expected: "fd01:0:0:200::/56"},
+		{name: "existing binding", key: bindingKey{duid: "a", prefix: true}, requested: []string{"fd01:0:0:300::/56"},
+			expected: "fd01::/56"},
+		{name: "misaligned hint inside bound prefix", key: bindingKey{duid: "c", prefix: true},
+			requested: []string{"fd01:0:0:201::/56"}, expected: "fd01:0:0:100::/56"},
+		{name: "misaligned hint inside free prefix", key: bindingKey{duid: "d", prefix: true},
+			requested: []string{"fd01:0:0:301::/56"}, expected: "fd01:0:0:300::/56"},
+		{name: "other length", key: bindingKey{duid: "e", prefix: true}, requested: []string{"fd01::/55"},
+			expected: "<nil>"},
+		{name: "outside of pool", key: bindingKey{duid: "f", prefix: true}, requested: []string{"fd02::/56"},
+			expected: "<nil>"},
+	}
+	for _, tt := range tests {
+		var requested []*net.IPNet
+		for _, el := range tt.requested {
+			requested = append(requested, mustCIDR(t, el))
+		}
+		if addr := srv.allocate(tt.key, requested); addr.String() != tt.expected {
+			t.Errorf("%s: allocated %v, expected %s", tt.name, addr, tt.expected)
+		}
+	}
+}
+
+func TestCanAllocateOverlap(t *testing.T) {
+	srv := newTestServer(t, config.Dhcpv6SrvConfig{
+		PrefixDelegation: &config.PrefixDelegation{Prefix: "fd01::/48", DelegatedLength: 56},
+	})
+	// binding with another length (e.g. from config before change)
+	srv.bindings[bindingKey{duid: "a", prefix: true}] = &binding{addr: mustCIDR(t, "fd01:0:0:100::/55")}
+	tests := []struct {
+		prefix   string
+		expected bool
+	}{
+		{prefix: "fd01::/56", expected: false},
+		{prefix: "fd01:0:0:100::/56", expected: false},
+		{prefix: "fd01:0:0:200::/56", expected: true},
+	}
+	for _, tt := range tests {
+		if got := srv.canAllocate(true, mustCIDR(t, tt.prefix)); got != tt.expected {
+			t.Errorf("%s: %t, expected %t", tt.prefix, got, tt.expected)
+		}
+	}
+}
+
+func TestAllocateAddress(t *testing.T) {
+	srv := newTestServer(t, config.Dhcpv6SrvConfig{
+		AddrRange: &config.IPRange{FromIP: "fd00::100", ToIP: "fd00::102"},
+	})
+	allocate := func(duid string, requested ...string) string {
+		var addrs []*net.IPNet
+		for _, el := range requested {
+			addrs = append(addrs, mustCIDR(t, el))
+		}
+		return srv.allocate(bindingKey{duid: duid}, addrs).String()
+	}
+	for _, tt := range []struct{ duid, requested, expected string }{
+		{duid: "a", expected: "fd00::100/128"},
+		{duid: "b", requested: "fd00::102/128", expected: "fd00::102/128"},
+		{duid: "c", requested: "fd00::102/128", expected: "fd00::101/128"},
+		{duid: "d", requested: "fd00::200/128", expected: "<nil>"},
+	} {
+		var requested []string
+		if tt.requested != "" {
+			requested = append(requested, tt.requested)
+		}
+		if got := allocate(tt.duid, requested...); got != tt.expected {
+			t.Errorf("client %s: allocated %s, expected %s", tt.duid, got, tt.expected)
+		}
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	leaseFile := filepath.Join(t.TempDir(), "leases.json")
+	srv := newTestServer(t, config.Dhcpv6SrvConfig{
+		AddrRange:         &config.IPRange{FromIP: "fd00::100", ToIP: "fd00::1ff"},
+		PreferredLifetime: 3600,
+		ValidLifetime:     7200,
+		DNSServers:        []string{"fd00::1"},
+		LeaseFile:         leaseFile,
+	})
+	clientID := []byte{0, 3, 0, 1, 2, 0xfe, 0, 0, 0, 2}
+	clientIP := net.ParseIP("fe80::2")
+	solicit := &message{msgType: msgSolicit, txID: [3]byte{1, 2, 3}}
+	solicit.addOption(optClientID, clientID)
+	solicit.addOption(optIANA, make([]byte, 12))
+	advertise := srv.handleMessage(solicit, clientIP)
+	if advertise == nil || advertise.msgType != msgAdvertise || advertise.txID != solicit.txID {
+		t.Fatalf("unexpected reply to solicit: %v", advertise)
+	}
+	if dns := advertise.getOption(optDNSServers); !net.IP(dns).Equal(net.ParseIP("fd00::1")) {
+		t.Errorf("DNS servers %v", dns)
+	}
+	if _, err := os.Stat(leaseFile); !os.IsNotExist(err) {
+		t.Error("lease published for advertised address")
+	}
+
+	request := &message{msgType: msgRequest, txID: [3]byte{4, 5, 6}}
+	request.addOption(optClientID, clientID)
+	request.addOption(optServerID, advertise.getOption(optServerID))
+	request.addOption(optIANA, advertise.getOption(optIANA))
+	reply := srv.handleMessage(request, clientIP)
+	if reply == nil || reply.msgType != msgReply {
+		t.Fatalf("unexpected reply to request: %v", reply)
+	}
+	ia, err := parseIA(option{code: optIANA, data: reply.getOption(optIANA)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs := ia.addresses(); len(addrs) != 1 || !addrs[0].IP.Equal(net.ParseIP("fd00::100")) {
+		t.Errorf("leased %v", addrs)
+	}
+	data, err := os.ReadFile(leaseFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var leases []config.Lease
+	if err = json.Unmarshal(data, &leases); err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 1 || leases[0].Address != "fd00::100" || leases[0].MAC != "02:fe:00:00:00:02" {
+		t.Errorf("unexpected leases %+v", leases)
+	}
+
+	// request for another server is ignored
+	request.options[1] = option{code: optServerID, data: []byte{0, 3, 0, 1, 1, 1, 1, 1, 1, 1}}
+	if reply = srv.handleMessage(request, clientIP); reply != nil {
+		t.Errorf("replied to request for another server: %v", reply)
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+)
+
+// DNSFaultProxyConfig : configuration formatted with JSON and passed to dnsfaultproxy
+// using the "-c" command line argument.
+type DNSFaultProxyConfig struct {
+	// ListenPort : port to listen for DNS queries (over both UDP and TCP).
+	ListenPort uint16 `json:"listenPort"`
+	// UpstreamAddr : address (<ip>:<port>) of the DNS server to forward queries to
+	// (unless answered by a fault rule).
+	UpstreamAddr string `json:"upstreamAddr"`
+	// LogFile : file to write all log messages into.
+	LogFile string `json:"logFile"`
+	// PidFile : file to write dnsfaultproxy process PID.
+	PidFile string `json:"pidFile"`
+	// StatsFile : file where dnsfaultproxy periodically publishes statistics
+	// (Stats formatted with JSON). Leave empty to disable.
+	StatsFile string `json:"statsFile"`
+	// Verbose : enable to have all queries logged.
+	Verbose bool `json:"verbose"`
+	// FaultRules : rules for injecting faults into DNS responses.
+	// Symbolic references (to endpoint FQDNs and IPs) should be already resolved.
+	FaultRules []sdnapi.DNSFaultRule `json:"faultRules"`
+}
+
+// Stats : statistics published by dnsfaultproxy.
+type Stats struct {
+	// FaultRules : statistics of fault rules (in the order of the configured rules).
+	FaultRules []sdnapi.DNSFaultRuleStatus `json:"faultRules"`
+	// Intercepted : number of queries answered (or dropped) by fault rules
+	// without being forwarded upstream, per domain name.
+	Intercepted map[string]uint64 `json:"intercepted,omitempty"`
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lf-edge/eden/sdn/vm/cmd/dnsfaultproxy/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	upstreamTimeout = 5 * time.Second
+	tcpIdleTimeout  = 30 * time.Second
+	maxMessageSize  = 65535
+)
+
+type proxy struct {
+	upstreamAddr string
+	rules        []*faultRule
+	stats        *statsCollector
+}
+
+// DNS proxy which injects faults into responses to queries for selected domain names.
+// Queries not matched by any fault rule are forwarded to the upstream DNS server
+// (dnsmasq) unchanged.
+func main() {
+	log.SetReportCaller(true)
+	configFile := flag.String("c", "/etc/dnsfaultproxy.conf", "DNS fault proxy config file")
+	flag.Parse()
+
+	// Read and parse config file.
+	configBytes, err := os.ReadFile(*configFile)
+	if err != nil {
+		log.Fatalf("failed to read config file %s: %v", *configFile, err)
+	}
+	var proxyConfig config.DNSFaultProxyConfig
+	if err = json.Unmarshal(configBytes, &proxyConfig); err != nil {
+		log.Fatalf("failed to unmarshal DNS fault proxy config: %v", err)
+	}
+	if proxyConfig.LogFile != "" {
+		logFile, err := os.OpenFile(proxyConfig.LogFile, os.O_WRONLY|os.O_CREATE, 0755)
+		if err != nil {
+			log.Fatalf("failed to open log file %s: %v", proxyConfig.LogFile, err)
+		}
+		log.SetOutput(logFile)
+	}
+	if proxyConfig.Verbose {
+		log.SetLevel(log.DebugLevel)
+	} else {
+		log.SetLevel(log.InfoLevel)
+	}
+	rand.Seed(time.Now().UnixNano())
+
+	p := &proxy{
+		upstreamAddr: proxyConfig.UpstreamAddr,
+		stats:        newStatsCollector(proxyConfig.FaultRules),
+	}
+	for i, rule := range proxyConfig.FaultRules {
+		r, err := newFaultRule(i, rule)
+		if err != nil {
+			log.Fatalf("invalid fault rule %d: %v", i, err)
+		}
+		p.rules = append(p.rules, r)
+	}
+	listenAddr := fmt.Sprintf(":%d", proxyConfig.ListenPort)
+	udpConn, err := net.ListenPacket("udp", listenAddr)
+	if err != nil {
+		log.Fatalf("failed to listen on UDP %s: %v", listenAddr, err)
+	}
+	tcpListener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		log.Fatalf("failed to listen on TCP %s: %v", listenAddr, err)
+	}
+	go p.serveUDP(udpConn)
+	go p.serveTCP(tcpListener)
+	if proxyConfig.StatsFile != "" {
+		go p.stats.publish(proxyConfig.StatsFile)
+	}
+
+	if proxyConfig.PidFile != "" {
+		pidBytes := []byte(fmt.Sprintf("%d", os.Getpid()))
+		err = os.WriteFile(proxyConfig.PidFile, pidBytes, 0664)
+		if err != nil {
+			log.Fatalf("failed to write PID file %s: %v", proxyConfig.PidFile, err)
+		}
+		defer os.Remove(proxyConfig.PidFile)
+	}
+
+	cancelChan := make(chan os.Signal, 1)
+	// Catch termination or interrupt signal.
+	signal.Notify(cancelChan, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-cancelChan
+	log.Infof("Caught terimation/interrupt signal: %v, exiting...", sig)
+}
+
+func (p *proxy) serveUDP(conn net.PacketConn) {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, clientAddr, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Fatalf("failed to read from UDP socket: %v", err)
+		}
+		query := make([]byte, n)
+		copy(query, buf[:n])
+		// Handle every query in a separate Go routine - rules may delay responses.
+		go func() {
+			resp := p.handleQuery(query, false)
+			if resp == nil {
+				return
+			}
+			if _, err := conn.WriteTo(resp, clientAddr); err != nil {
+				log.Errorf("Failed to send DNS response to %v: %v", clientAddr, err)
+			}
+		}()
+	}
+}
+
+func (p *proxy) serveTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Fatalf("failed to accept TCP connection: %v", err)
+		}
+		go p.serveTCPConn(conn)
+	}
+}
+
+func (p *proxy) serveTCPConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			if err != io.EOF {
+				log.Debugf("Closing TCP connection from %v: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		resp := p.handleQuery(query, true)
+		if resp == nil {
+			continue
+		}
+		if err = writeTCPMessage(conn, resp); err != nil {
+			log.Errorf("Failed to send DNS response to %v: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// forward sends the query to the upstream server and returns the response.
+// Returns nil if the upstream server failed to respond.
+func (p *proxy) forward(query []byte, overTCP bool) []byte {
+	network := "udp"
+	if overTCP {
+		network = "tcp"
+	}
+	conn, err := net.DialTimeout(network, p.upstreamAddr, upstreamTimeout)
+	if err != nil {
+		log.Errorf("Failed to connect to upstream server %s: %v", p.upstreamAddr, err)
+		return nil
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(upstreamTimeout))
+	if overTCP {
+		if err = writeTCPMessage(conn, query); err == nil {
+			var resp []byte
+			if resp, err = readTCPMessage(conn); err == nil {
+				return resp
+			}
+		}
+	} else {
+		if _, err = conn.Write(query); err == nil {
+			buf := make([]byte, maxMessageSize)
+			var n int
+			if n, err = conn.Read(buf); err == nil {
+				return buf[:n]
+			}
+		}
+	}
+	log.Errorf("Failed to forward DNS query to upstream server %s: %v",
+		p.upstreamAddr, err)
+	return nil
+}
+
+// DNS messages sent over TCP are prefixed with two-byte length field.
+func readTCPMessage(conn net.Conn) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeTCPMessage(conn net.Conn, msg []byte) error {
+	buf := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	_, err := conn.Write(append(buf, msg...))
+	return err
+}
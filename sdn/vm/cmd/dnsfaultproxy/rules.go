@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TTL used for answers with rotating IPs if not overridden by the rule.
+const defaultRotatingIPsTTL = 60
+
+type faultRule struct {
+	sdnapi.DNSFaultRule
+	index    int
+	fqdn     string
+	wildcard bool
+	// Zero value matches any query type.
+	queryType dnsmessage.Type
+	ipv4s     []net.IP
+	ipv6s     []net.IP
+	// Incremented with every answer containing one of the rotating IPs.
+	rotation uint64
+}
+
+func newFaultRule(index int, rule sdnapi.DNSFaultRule) (*faultRule, error) {
+	r := &faultRule{
+		DNSFaultRule: rule,
+		index:        index,
+		fqdn:         normalizeName(rule.FQDN),
+	}
+	if strings.HasPrefix(r.fqdn, "*.") {
+		r.fqdn = strings.TrimPrefix(r.fqdn, "*.")
+		r.wildcard = true
+	}
+	if rule.QueryType != "" {
+		queryType, ok := sdnapi.DNSFaultQueryTypes[strings.ToUpper(rule.QueryType)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported query type %s", rule.QueryType)
+		}
+		r.queryType = dnsmessage.Type(queryType)
+	}
+	for _, ipStr := range rule.RotatingIPs {
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid rotating IP %s", ipStr)
+		}
+		if ip.To4() != nil {
+			r.ipv4s = append(r.ipv4s, ip.To4())
+		} else {
+			r.ipv6s = append(r.ipv6s, ip)
+		}
+	}
+	return r, nil
+}
+
+// matches returns true if the rule should be applied to the given query.
+func (r *faultRule) matches(q dnsmessage.Question) bool {
+	name := normalizeName(q.Name.String())
+	if r.wildcard {
+		if !strings.HasSuffix(name, "."+r.fqdn) {
+			return false
+		}
+	} else if name != r.fqdn {
+		return false
+	}
+	if r.queryType != 0 && r.queryType != q.Type {
+		return false
+	}
+	if r.Probability > 0 && r.Probability < 100 {
+		return rand.Float32()*100 < r.Probability
+	}
+	return true
+}
+
+// rotatingIPs returns IPs to choose from when answering the given query.
+// Returns false if the query should not be answered with rotating IPs.
+func (r *faultRule) rotatingIPs(q dnsmessage.Question) ([]net.IP, bool) {
+	if len(r.RotatingIPs) == 0 {
+		return nil, false
+	}
+	switch q.Type {
+	case dnsmessage.TypeA:
+		return r.ipv4s, true
+	case dnsmessage.TypeAAAA:
+		return r.ipv6s, true
+	}
+	return nil, false
+}
+
+func (r *faultRule) nextIP(ips []net.IP) net.IP {
+	n := atomic.AddUint64(&r.rotation, 1)
+	return ips[(n-1)%uint64(len(ips))]
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// handleQuery returns response for the given query.
+// Returns nil if the query should not be responded to.
+func (p *proxy) handleQuery(query []byte, overTCP bool) []byte {
+	var parser dnsmessage.Parser
+	hdr, err := parser.Start(query)
+	if err != nil {
+		log.Warnf("Failed to parse DNS query: %v", err)
+		return nil
+	}
+	q, err := parser.Question()
+	if err != nil {
+		return p.forward(query, overTCP)
+	}
+	var rule *faultRule
+	for _, r := range p.rules {
+		if r.matches(q) {
+			rule = r
+			break
+		}
+	}
+	if rule == nil {
+		return p.forward(query, overTCP)
+	}
+	name := normalizeName(q.Name.String())
+	log.Debugf("Applying fault rule %d (%s) to query %s %s",
+		rule.index, sdnapi.DNSFaultActionToString[rule.Action], q.Type, name)
+	p.stats.recordHit(rule.index)
+	if rule.Action == sdnapi.DNSDrop {
+		p.stats.recordIntercepted(name)
+		return nil
+	}
+	if rule.Delay > 0 {
+		time.Sleep(time.Duration(rule.Delay) * time.Millisecond)
+	}
+	switch rule.Action {
+	case sdnapi.DNSNXDomain:
+		p.stats.recordIntercepted(name)
+		return buildReply(hdr, q, dnsmessage.RCodeNameError, false, nil, 0)
+	case sdnapi.DNSServFail:
+		p.stats.recordIntercepted(name)
+		return buildReply(hdr, q, dnsmessage.RCodeServerFailure, false, nil, 0)
+	case sdnapi.DNSTruncate:
+		if !overTCP {
+			p.stats.recordIntercepted(name)
+			return buildReply(hdr, q, dnsmessage.RCodeSuccess, true, nil, 0)
+		}
+	}
+	if ips, ok := rule.rotatingIPs(q); ok {
+		p.stats.recordIntercepted(name)
+		ttl := uint32(defaultRotatingIPsTTL)
+		if rule.TTL != nil {
+			ttl = *rule.TTL
+		}
+		var ip net.IP
+		if len(ips) > 0 {
+			ip = rule.nextIP(ips)
+		}
+		return buildReply(hdr, q, dnsmessage.RCodeSuccess, false, ip, ttl)
+	}
+	resp := p.forward(query, overTCP)
+	if resp != nil && rule.TTL != nil {
+		resp = overrideTTL(resp, *rule.TTL)
+	}
+	return resp
+}
+
+// buildReply builds response to the given query.
+// Answer is optional (without answer the response contains no records).
+func buildReply(hdr dnsmessage.Header, q dnsmessage.Question, rcode dnsmessage.RCode,
+	truncated bool, answer net.IP, ttl uint32) []byte {
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 hdr.ID,
+			Response:           true,
+			OpCode:             hdr.OpCode,
+			Truncated:          truncated,
+			RecursionDesired:   hdr.RecursionDesired,
+			RecursionAvailable: true,
+			RCode:              rcode,
+		},
+		Questions: []dnsmessage.Question{q},
+	}
+	if answer != nil {
+		var body dnsmessage.ResourceBody
+		if ip4 := answer.To4(); ip4 != nil {
+			a := &dnsmessage.AResource{}
+			copy(a.A[:], ip4)
+			body = a
+		} else {
+			aaaa := &dnsmessage.AAAAResource{}
+			copy(aaaa.AAAA[:], answer.To16())
+			body = aaaa
+		}
+		msg.Answers = append(msg.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{
+				Name:  q.Name,
+				Type:  q.Type,
+				Class: q.Class,
+				TTL:   ttl,
+			},
+			Body: body,
+		})
+	}
+	reply, err := msg.Pack()
+	if err != nil {
+		log.Errorf("Failed to pack DNS reply: %v", err)
+		return nil
+	}
+	return reply
+}
+
+// overrideTTL sets the given TTL for all records of the response.
+// Returns the response unchanged if it cannot be parsed.
+func overrideTTL(resp []byte, ttl uint32) []byte {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		log.Warnf("Failed to parse DNS response: %v", err)
+		return resp
+	}
+	for i := range msg.Answers {
+		msg.Answers[i].Header.TTL = ttl
+	}
+	for i := range msg.Authorities {
+		msg.Authorities[i].Header.TTL = ttl
+	}
+	for i := range msg.Additionals {
+		// TTL of the OPT pseudo-record carries EDNS flags.
+		if msg.Additionals[i].Header.Type != dnsmessage.TypeOPT {
+			msg.Additionals[i].Header.TTL = ttl
+		}
+	}
+	modified, err := msg.Pack()
+	if err != nil {
+		log.Warnf("Failed to pack DNS response: %v", err)
+		return resp
+	}
+	return modified
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func question(name string, qType dnsmessage.Type) dnsmessage.Question {
+	return dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  qType,
+		Class: dnsmessage.ClassINET,
+	}
+}
+
+func TestFaultRuleMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    sdnapi.DNSFaultRule
+		query   dnsmessage.Question
+		matches bool
+	}{
+		{name: "exact name", rule: sdnapi.DNSFaultRule{FQDN: "example.com"},
+			query: question("example.com.", dnsmessage.TypeA), matches: true},
+		{name: "name is case-insensitive", rule: sdnapi.DNSFaultRule{FQDN: "Example.COM."},
+			query: question("example.com.", dnsmessage.TypeA), matches: true},
+		{name: "subdomain without wildcard", rule: sdnapi.DNSFaultRule{FQDN: "example.com"},
+			query: question("www.example.com.", dnsmessage.TypeA)},
+		{name: "wildcard subdomain", rule: sdnapi.DNSFaultRule{FQDN: "*.example.com"},
+			query: question("www.example.com.", dnsmessage.TypeA), matches: true},
+		{name: "wildcard does not match parent", rule: sdnapi.DNSFaultRule{FQDN: "*.example.com"},
+			query: question("example.com.", dnsmessage.TypeA)},
+		{name: "wildcard does not match suffix", rule: sdnapi.DNSFaultRule{FQDN: "*.example.com"},
+			query: question("badexample.com.", dnsmessage.TypeA)},
+		{name: "matching query type", rule: sdnapi.DNSFaultRule{FQDN: "example.com", QueryType: "aaaa"},
+			query: question("example.com.", dnsmessage.TypeAAAA), matches: true},
+		{name: "different query type", rule: sdnapi.DNSFaultRule{FQDN: "example.com", QueryType: "AAAA"},
+			query: question("example.com.", dnsmessage.TypeA)},
+		{name: "always applied", rule: sdnapi.DNSFaultRule{FQDN: "example.com", Probability: 100},
+			query: question("example.com.", dnsmessage.TypeA), matches: true},
+	}
+	for _, tt := range tests {
+		r, err := newFaultRule(0, tt.rule)
+		if err != nil {
+			t.Fatalf("%s: newFaultRule failed: %v", tt.name, err)
+		}
+		if matches := r.matches(tt.query); matches != tt.matches {
+			t.Errorf("%s: matches %t, expected %t", tt.name, matches, tt.matches)
+		}
+	}
+}
+
+func TestNewFaultRuleInvalid(t *testing.T) {
+	if _, err := newFaultRule(0, sdnapi.DNSFaultRule{FQDN: "example.com", QueryType: "HINFO"}); err == nil {
+		t.Error("expected error for unsupported query type")
+	}
+	if _, err := newFaultRule(0, sdnapi.DNSFaultRule{FQDN: "example.com",
+		RotatingIPs: []string{"not-an-ip"}}); err == nil {
+		t.Error("expected error for invalid rotating IP")
+	}
+}
+
+func TestBuildReply(t *testing.T) {
+	hdr := dnsmessage.Header{ID: 1234, RecursionDesired: true}
+	q := question("example.com.", dnsmessage.TypeA)
+	reply := buildReply(hdr, q, dnsmessage.RCodeSuccess, false, net.ParseIP("10.0.0.1"), 30)
+	var msg dnsmessage.Message
+	if err := msg.Unpack(reply); err != nil {
+		t.Fatalf("failed to parse reply: %v", err)
+	}
+	if msg.ID != hdr.ID || !msg.Response || !msg.RecursionDesired || msg.Truncated {
+		t.Errorf("unexpected reply header: %+v", msg.Header)
+	}
+	if len(msg.Questions) != 1 || msg.Questions[0] != q {
+		t.Errorf("unexpected questions: %v", msg.Questions)
+	}
+	if len(msg.Answers) != 1 {
+		t.Fatalf("expected one answer, got %d", len(msg.Answers))
+	}
+	a, ok := msg.Answers[0].Body.(*dnsmessage.AResource)
+	if !ok || a.A != [4]byte{10, 0, 0, 1} {
+		t.Errorf("unexpected answer: %v", msg.Answers[0].Body)
+	}
+	if msg.Answers[0].Header.TTL != 30 {
+		t.Errorf("answer TTL %d, expected 30", msg.Answers[0].Header.TTL)
+	}
+
+	q = question("example.com.", dnsmessage.TypeAAAA)
+	reply = buildReply(hdr, q, dnsmessage.RCodeSuccess, false, net.ParseIP("fd00::1"), 30)
+	if err := msg.Unpack(reply); err != nil {
+		t.Fatalf("failed to parse reply: %v", err)
+	}
+	if len(msg.Answers) != 1 {
+		t.Fatalf("expected one answer, got %d", len(msg.Answers))
+	}
+	if aaaa, ok := msg.Answers[0].Body.(*dnsmessage.AAAAResource); !ok ||
+		!net.IP(aaaa.AAAA[:]).Equal(net.ParseIP("fd00::1")) {
+		t.Errorf("unexpected answer: %v", msg.Answers[0].Body)
+	}
+
+	reply = buildReply(hdr, q, dnsmessage.RCodeNameError, true, nil, 0)
+	if err := msg.Unpack(reply); err != nil {
+		t.Fatalf("failed to parse reply: %v", err)
+	}
+	if msg.RCode != dnsmessage.RCodeNameError || !msg.Truncated || len(msg.Answers) != 0 {
+		t.Errorf("unexpected reply: %+v", msg)
+	}
+}
+
+func TestOverrideTTL(t *testing.T) {
+	name := dnsmessage.MustNewName("example.com.")
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 1, Response: true},
+		Questions: []dnsmessage.Question{question("example.com.", dnsmessage.TypeA)},
+		Answers: []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA,
+				Class: dnsmessage.ClassINET, TTL: 3600},
+			Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+		}},
+		Authorities: []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeNS,
+				Class: dnsmessage.ClassINET, TTL: 3600},
+			Body: &dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns.example.com.")},
+		}},
+	}
+	var opt dnsmessage.Resource
+	if err := opt.Header.SetEDNS0(1232, dnsmessage.RCodeSuccess, true); err != nil {
+		t.Fatal(err)
+	}
+	opt.Body = &dnsmessage.OPTResource{}
+	resp.Additionals = append(resp.Additionals, opt)
+	respBytes, err := resp.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg dnsmessage.Message
+	if err = msg.Unpack(overrideTTL(respBytes, 5)); err != nil {
+		t.Fatalf("failed to parse modified response: %v", err)
+	}
+	if msg.Answers[0].Header.TTL != 5 || msg.Authorities[0].Header.TTL != 5 {
+		t.Errorf("TTL not overridden: answer %d, authority %d",
+			msg.Answers[0].Header.TTL, msg.Authorities[0].Header.TTL)
+	}
+	if !msg.Additionals[0].Header.DNSSECAllowed() {
+		t.Error("EDNS flags carried in the OPT record TTL were modified")
+	}
+
+	invalid := []byte{1, 2, 3}
+	if modified := overrideTTL(invalid, 5); string(modified) != string(invalid) {
+		t.Error("invalid response was modified")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	"github.com/lf-edge/eden/sdn/vm/cmd/dnsfaultproxy/config"
+	"github.com/lf-edge/eden/sdn/vm/pkg/statsfile"
+)
+
+type statsCollector struct {
+	statsfile.Publisher
+	stats config.Stats
+}
+
+func newStatsCollector(rules []sdnapi.DNSFaultRule) *statsCollector {
+	c := &statsCollector{}
+	for _, rule := range rules {
+		c.stats.FaultRules = append(c.stats.FaultRules, sdnapi.DNSFaultRuleStatus{
+			FQDN:      rule.FQDN,
+			QueryType: rule.QueryType,
+			Action:    rule.Action,
+		})
+	}
+	return c
+}
+
+// recordHit : record query that the given rule was applied to.
+func (c *statsCollector) recordHit(ruleIndex int) {
+	c.Lock()
+	defer c.Unlock()
+	c.stats.FaultRules[ruleIndex].Hits++
+	c.SetChanged()
+}
+
+// recordIntercepted : record query answered (or dropped) without being forwarded upstream.
+func (c *statsCollector) recordIntercepted(name string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.stats.Intercepted == nil {
+		c.stats.Intercepted = make(map[string]uint64)
+	}
+	c.stats.Intercepted[name]++
+	c.SetChanged()
+}
+
+// publish : periodically write statistics into the given file (if they have changed).
+func (c *statsCollector) publish(statsFile string) {
+	c.Publish(statsFile, "DNS fault stats", func() interface{} { return c.stats })
+}
@@ -33,6 +33,9 @@ type ProxyConfig struct {
 	LogFile string `json:"logFile"`
 	// PidFile : file to write goproxy process PID.
 	PidFile string `json:"pidFile"`
+	// StatsFile : file where goproxy periodically publishes request statistics
+	// (sdnapi.ProxyStats formatted with JSON). Leave empty to disable.
+	StatsFile string `json:"statsFile"`
 	// Verbose : enable to have all proxied requests logged.
 	Verbose bool `json:"verbose"`
 	// CertPEM : Proxy certificate of the certificate authority in the PEM format.
@@ -91,6 +91,11 @@ func main() {
 		}
 	}
 
+	if proxyConfig.StatsFile != "" {
+		go proxyStats.publish(proxyConfig.StatsFile)
+		defer os.Remove(proxyConfig.StatsFile)
+	}
+
 	// Run HTTP and HTTPS proxies.
 	if proxyConfig.Transparent {
 		runTransparentProxy(proxyConfig)
@@ -30,7 +30,7 @@ func installProxyHandler(rule sdnapi.ProxyRule, https bool, proxy *goproxy.Proxy
 	case sdnapi.PxReject:
 		// Reject HTTP/HTTPS CONNECT
 		proxy.OnRequest(dstHostIs(rule.ReqHost)).HandleConnect(
-			goproxy.AlwaysReject)
+			goproxy.FuncHttpsHandler(rejectConnect))
 		if !https {
 			// Reject HTTP GET, POST, etc. (but not CONNECT)
 			proxy.OnRequest(dstHostIs(rule.ReqHost), notConnect).DoFunc(
@@ -41,7 +41,7 @@ func installProxyHandler(rule sdnapi.ProxyRule, https bool, proxy *goproxy.Proxy
 		if https {
 			// CONNECT before establishing TLS tunnel
 			proxy.OnRequest(dstHostIs(rule.ReqHost)).HandleConnect(
-				goproxy.AlwaysMitm)
+				goproxy.FuncHttpsHandler(mitmConnect))
 		} else {
 			// CONNECT is plain HTTP tunneling
 			proxy.OnRequest(dstHostIs(rule.ReqHost)).HandleConnect(
@@ -70,7 +70,7 @@ func installProxyHandlers(proxyConfig config.ProxyConfig, https, transparent boo
 			// No such user.
 			return false
 		}
-		proxy.OnRequest(notConnect).Do(auth.Basic(authRealm, userAuth))
+		proxy.OnRequest(notConnect).Do(basicAuth(userAuth))
 		proxy.OnRequest().HandleConnect(basicAuthForConnect(userAuth))
 	}
 	// Make sure HTTP and HTTPS are not mixed.
@@ -149,11 +149,25 @@ func nonProxyHandler(proxy *goproxy.ProxyHttpServer) http.HandlerFunc {
 
 func forwardConnect(host string, ctx *goproxy.ProxyCtx) (*goproxy.ConnectAction, string) {
 	log.Debugf("forwardConnect: %s", host)
+	proxyStats.recordRequest(host, sdnapi.PxForward)
 	return goproxy.OkConnect, host
 }
 
+func rejectConnect(host string, ctx *goproxy.ProxyCtx) (*goproxy.ConnectAction, string) {
+	log.Debugf("rejectConnect: %s", host)
+	proxyStats.recordRequest(host, sdnapi.PxReject)
+	return goproxy.RejectConnect, host
+}
+
+func mitmConnect(host string, ctx *goproxy.ProxyCtx) (*goproxy.ConnectAction, string) {
+	log.Debugf("mitmConnect: %s", host)
+	proxyStats.recordRequest(host, sdnapi.PxMITM)
+	return goproxy.MitmConnect, host
+}
+
 func mitmHTTPConnect(host string, ctx *goproxy.ProxyCtx) (*goproxy.ConnectAction, string) {
 	log.Debugf("mitmHTTPConnect: %s", host)
+	proxyStats.recordRequest(host, sdnapi.PxMITM)
 	return goproxy.HTTPMitmConnect, host
 }
 
@@ -178,12 +192,26 @@ func basicAuthForConnect(f func(user, passwd string) bool) goproxy.HttpsHandler
 				// Return nil action, do not overshadow other handlers in the queue.
 				return nil, host
 			}
+			proxyStats.recordAuthFailure()
 			return action, host
 		})
 }
 
+func basicAuth(f func(user, passwd string) bool) goproxy.ReqHandler {
+	authHandler := auth.Basic(authRealm, f)
+	return goproxy.FuncReqHandler(
+		func(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
+			req, resp := authHandler.Handle(req, ctx)
+			if isProxyAuthRequired(resp) {
+				proxyStats.recordAuthFailure()
+			}
+			return req, resp
+		})
+}
+
 func forwardHTTP(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
 	log.Debugf("forwardHTTP: %v", req)
+	proxyStats.recordRequest(req.URL.Host, sdnapi.PxForward)
 	resp, err := ctx.RoundTrip(req)
 	if err != nil {
 		return req, goproxy.NewResponse(req,
@@ -194,6 +222,7 @@ func forwardHTTP(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http
 
 func rejectHTTP(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
 	log.Debugf("rejectHTTP: %v", req)
+	proxyStats.recordRequest(req.URL.Host, sdnapi.PxReject)
 	return req, goproxy.NewResponse(req,
 		goproxy.ContentTypeText, http.StatusForbidden, "Forbidden by proxy!")
 }
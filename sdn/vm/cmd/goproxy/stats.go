@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net"
+	"net/http"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	"github.com/lf-edge/eden/sdn/vm/pkg/statsfile"
+)
+
+// proxyStats : request statistics collected by all proxy handlers.
+var proxyStats = &statsCollector{}
+
+type statsCollector struct {
+	statsfile.Publisher
+	stats sdnapi.ProxyStats
+}
+
+// recordRequest : record request for the given host:port and the action taken.
+func (c *statsCollector) recordRequest(hostPort string, action sdnapi.ProxyAction) {
+	c.Lock()
+	defer c.Unlock()
+	c.stats.Requests++
+	switch action {
+	case sdnapi.PxForward:
+		c.stats.Forwarded++
+	case sdnapi.PxReject:
+		c.stats.Rejected++
+	case sdnapi.PxMITM:
+		c.stats.MITM++
+	}
+	if c.stats.Hosts == nil {
+		c.stats.Hosts = make(map[string]uint64)
+	}
+	host := hostPort
+	if h, _, err := net.SplitHostPort(hostPort); err == nil {
+		host = h
+	}
+	c.stats.Hosts[host]++
+	c.SetChanged()
+}
+
+// recordAuthFailure : record request rejected due to failed authentication.
+func (c *statsCollector) recordAuthFailure() {
+	c.Lock()
+	defer c.Unlock()
+	c.stats.Requests++
+	c.stats.AuthFailures++
+	c.SetChanged()
+}
+
+// publish : periodically write statistics into the given file (if they have changed).
+func (c *statsCollector) publish(statsFile string) {
+	c.Publish(statsFile, "proxy stats", func() interface{} { return c.stats })
+}
+
+// isProxyAuthRequired returns true if the response is a rejection
+// of a request due to missing or invalid proxy credentials.
+func isProxyAuthRequired(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusProxyAuthRequired
+}
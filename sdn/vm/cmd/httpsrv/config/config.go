@@ -15,6 +15,9 @@ type HttpSrvConfig struct {
 	LogFile string `json:"logFile"`
 	// PidFile : file to write httpsrv process PID.
 	PidFile string `json:"pidFile"`
+	// StatsFile : file where httpsrv periodically publishes request statistics
+	// (sdnapi.HTTPServerStatus formatted with JSON). Leave empty to disable.
+	StatsFile string `json:"statsFile"`
 	// Verbose : enable to have all requests logged.
 	Verbose bool `json:"verbose"`
 	// HTTPPort : port to listen for HTTP requests.
@@ -29,4 +32,7 @@ type HttpSrvConfig struct {
 	KeyPEM string `json:"keyPEM"`
 	// Maps URL Path to a content to be returned inside the HTTP(S) response body.
 	Paths map[string]sdnapi.HTTPContent `json:"paths"`
+	// ContentCACertPEM : additional CA certificate (in the PEM format) to trust
+	// when downloading content from HTTPContent.ContentFromURL.
+	ContentCACertPEM string `json:"contentCACertPEM,omitempty"`
 }
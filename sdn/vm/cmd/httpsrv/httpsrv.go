@@ -1,27 +1,143 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
 	"github.com/lf-edge/eden/sdn/vm/cmd/httpsrv/config"
 	log "github.com/sirupsen/logrus"
 )
 
-func handler(content sdnapi.HTTPContent) func(http.ResponseWriter, *http.Request) {
+// Response headers copied from the response received for HTTPContent.ContentFromURL.
+var forwardedHeaders = []string{"Content-Type", "Content-Length", "Content-Range",
+	"Accept-Ranges", "Last-Modified", "ETag"}
+
+// Request headers copied into the request made for HTTPContent.ContentFromURL.
+var forwardedReqHeaders = []string{"Range", "If-Range", "If-None-Match",
+	"If-Modified-Since"}
+
+var downloadTransport = &http.Transport{
+	Proxy:                 http.ProxyFromEnvironment,
+	ResponseHeaderTimeout: 30 * time.Second,
+}
+
+var downloadClient = &http.Client{Transport: downloadTransport}
+
+// trustContentCA makes downloadClient trust the given CA certificate
+// (on top of the system CAs).
+func trustContentCA(caCertPEM string) error {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if !rootCAs.AppendCertsFromPEM([]byte(caCertPEM)) {
+		return errors.New("failed to parse CA certificate")
+	}
+	downloadTransport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+	return nil
+}
+
+// redactURL replaces password included in the URL (if any) with "xxxxx".
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+func handler(content sdnapi.HTTPContent) (func(http.ResponseWriter, *http.Request), error) {
+	body := []byte(content.Content)
+	if content.ContentBase64 != "" {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(content.ContentBase64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 content: %w", err)
+		}
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Debugf("Received request: %+v", r)
-		w.Header().Add("Content-Type", content.ContentType)
-		_, err := w.Write([]byte(content.Content))
-		if err != nil {
-			log.Errorf("Failed to write content for request %+v: %v", r, err)
+		if content.Delay > 0 {
+			time.Sleep(time.Duration(content.Delay) * time.Millisecond)
+		}
+		for key, value := range content.Headers {
+			w.Header().Set(key, value)
+		}
+		if content.ContentType != "" {
+			w.Header().Set("Content-Type", content.ContentType)
+		}
+		switch {
+		case content.RedirectTo != "":
+			statusCode := content.StatusCode
+			if statusCode == 0 {
+				statusCode = http.StatusFound
+			}
+			w.Header().Set("Location", content.RedirectTo)
+			w.WriteHeader(statusCode)
+		case content.ContentFromURL != "":
+			forwardContent(w, r, content)
+		default:
+			if content.StatusCode != 0 {
+				w.WriteHeader(content.StatusCode)
+			}
+			_, err := w.Write(body)
+			if err != nil {
+				log.Errorf("Failed to write content for request %+v: %v", r, err)
+			}
 		}
+	}, nil
+}
+
+// forwardContent downloads content from the configured URL and forwards it to the client.
+// Content is always downloaded using GET (eserver does not serve HEAD requests),
+// for HEAD requests only the headers are forwarded.
+func forwardContent(w http.ResponseWriter, r *http.Request, content sdnapi.HTTPContent) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, content.ContentFromURL, nil)
+	if err != nil {
+		log.Errorf("Failed to create request for %s: %v", redactURL(content.ContentFromURL), err)
+		http.Error(w, "invalid content URL", http.StatusInternalServerError)
+		return
+	}
+	for _, header := range forwardedReqHeaders {
+		if value := r.Header.Get(header); value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		log.Errorf("Failed to download content from %s: %v", redactURL(content.ContentFromURL), err)
+		http.Error(w, "failed to download content", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for _, header := range forwardedHeaders {
+		if value := resp.Header.Get(header); value != "" && w.Header().Get(header) == "" {
+			w.Header().Set(header, value)
+		}
+	}
+	statusCode := resp.StatusCode
+	if content.StatusCode != 0 {
+		statusCode = content.StatusCode
+	}
+	w.WriteHeader(statusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		log.Errorf("Failed to forward content from %s: %v", redactURL(content.ContentFromURL), err)
 	}
 }
 
@@ -62,15 +178,30 @@ func main() {
 		defer os.Remove(httpSrvConfig.PidFile)
 	}
 
+	if httpSrvConfig.ContentCACertPEM != "" {
+		if err = trustContentCA(httpSrvConfig.ContentCACertPEM); err != nil {
+			log.Fatalf("failed to load content CA certificate: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
 	for path, content := range httpSrvConfig.Paths {
-		http.HandleFunc(path, handler(content))
+		pathHandler, err := handler(content)
+		if err != nil {
+			log.Fatalf("invalid content for path %s: %v", path, err)
+		}
+		mux.HandleFunc(path, pathHandler)
+	}
+	srvHandler := withRequestLog(mux)
+	if httpSrvConfig.StatsFile != "" {
+		go requestStats.publish(httpSrvConfig.StatsFile)
 	}
 
 	if httpSrvConfig.HTTPPort != 0 {
 		srvAddr := fmt.Sprintf("%s:%d", httpSrvConfig.ListenIP, httpSrvConfig.HTTPPort)
 		go func() {
 			log.Debugf("HTTP server listening on %s", srvAddr)
-			log.Fatalln(http.ListenAndServe(srvAddr, nil))
+			log.Fatalln(http.ListenAndServe(srvAddr, srvHandler))
 		}()
 	}
 
@@ -102,7 +233,7 @@ func main() {
 		log.Debugf("Storing server key to file %s", keyFile.Name())
 		go func() {
 			log.Debugf("HTTPS server listening on %s", srvAddr)
-			log.Fatalln(http.ListenAndServeTLS(srvAddr, certFile.Name(), keyFile.Name(), nil))
+			log.Fatalln(http.ListenAndServeTLS(srvAddr, certFile.Name(), keyFile.Name(), srvHandler))
 		}()
 	}
 
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	"github.com/lf-edge/eden/sdn/vm/pkg/statsfile"
+	log "github.com/sirupsen/logrus"
+)
+
+// Number of most recent requests to include in the published stats.
+const maxRecentRequests = 50
+
+// requestStats : statistics of all requests received by the server.
+var requestStats = &statsCollector{}
+
+type statsCollector struct {
+	statsfile.Publisher
+	stats sdnapi.HTTPServerStatus
+}
+
+// recordRequest : record request handled by the handler registered for the given path
+// (empty if the request did not match any path).
+func (c *statsCollector) recordRequest(path string, record sdnapi.HTTPRequestRecord) {
+	c.Lock()
+	defer c.Unlock()
+	c.stats.Requests++
+	if path != "" {
+		if c.stats.Paths == nil {
+			c.stats.Paths = make(map[string]uint64)
+		}
+		c.stats.Paths[path]++
+	}
+	c.stats.RecentRequests = append(c.stats.RecentRequests, record)
+	if len(c.stats.RecentRequests) > maxRecentRequests {
+		c.stats.RecentRequests = c.stats.RecentRequests[1:]
+	}
+	c.SetChanged()
+}
+
+// publish : periodically write statistics into the given file (if they have changed).
+func (c *statsCollector) publish(statsFile string) {
+	c.Publish(statsFile, "HTTP server stats", func() interface{} { return c.stats })
+}
+
+// statusRecorder : wraps http.ResponseWriter to capture the response status code.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// withRequestLog wraps the handler to log every request and record it into stats.
+func withRequestLog(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record := sdnapi.HTTPRequestRecord{
+			Time:      time.Now(),
+			Client:    r.RemoteAddr,
+			Method:    r.Method,
+			Host:      r.Host,
+			URL:       r.URL.RequestURI(),
+			TLS:       r.TLS != nil,
+			UserAgent: r.UserAgent(),
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		mux.ServeHTTP(recorder, r)
+		record.StatusCode = recorder.statusCode
+		if record.StatusCode == 0 {
+			record.StatusCode = http.StatusOK
+		}
+		// Pattern is empty if the request does not match any configured path.
+		_, pattern := mux.Handler(r)
+		log.Infof("%s %s %s%s -> %d (%v)", record.Client, record.Method, record.Host,
+			record.URL, record.StatusCode, time.Since(record.Time))
+		requestStats.recordRequest(pattern, record)
+	})
+}
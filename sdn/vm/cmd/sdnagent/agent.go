@@ -8,11 +8,14 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/lf-edge/eden/sdn/vm/api"
 	"github.com/lf-edge/eden/sdn/vm/pkg/configitems"
 	"github.com/lf-edge/eden/sdn/vm/pkg/maclookup"
@@ -25,6 +28,10 @@ import (
 const (
 	// Port connecting SDN VM with the host
 	hostPortLogicalLabel = "Host-Port"
+
+	// How often to re-resolve domain names used in firewall rules.
+	fwFQDNRefreshPeriod  = time.Minute
+	fwFQDNResolveTimeout = 5 * time.Second
 )
 
 // suffix is randomly generated by Eden
@@ -38,6 +45,11 @@ type agent struct {
 	// Network model
 	netModel    parsedNetModel
 	newNetModel chan parsedNetModel
+	// The most recently submitted network model (possibly not yet applied
+	// by the run loop). Guarded by submitLock, which also keeps models sent
+	// to newNetModel in the order of submission.
+	submitLock     sync.Mutex
+	submittedModel parsedNetModel
 
 	// Configuration state
 	currentState  dg.Graph
@@ -50,6 +62,18 @@ type agent struct {
 	resumeReconciliation <-chan string      // nil if no async ops
 	cancelAsyncOps       context.CancelFunc // nil if no async ops
 	waitForAsyncOps      func()             // NOOP if no async ops
+
+	// Packet captures running in the background.
+	ringCaptures map[string]*ringCapture // key: <item-type>/<logical-label>
+
+	// IP addresses of domain names used in firewall rules (FwRule.DstFQDNs).
+	// The map is never modified in place, only replaced.
+	fwFQDNIPs map[string][]net.IP // key: FQDN
+	// Domain names are resolved in the background (one resolution at a time),
+	// results are received by the run loop.
+	resolvedFwFQDNs    chan map[string][]net.IP
+	fwFQDNResolving    bool
+	fwFQDNResolveAgain bool
 }
 
 func (a *agent) init() error {
@@ -64,6 +88,8 @@ func (a *agent) init() error {
 	a.registry = registry
 	a.newNetModel = make(chan parsedNetModel, 10)
 	a.failingItems = make(map[dg.ItemRef]error)
+	a.ringCaptures = make(map[string]*ringCapture)
+	a.resolvedFwFQDNs = make(chan map[string][]net.IP, 1)
 	// Initially start with an empty network model.
 	// Ever-present config items will get created.
 	// (e.g. DHCP client for the interface connecting SDN with the host)
@@ -76,22 +102,50 @@ func (a *agent) init() error {
 }
 
 func (a *agent) run(linkChan chan netlink.LinkUpdate) {
+	fwFQDNTicker := time.NewTicker(fwFQDNRefreshPeriod)
+	defer fwFQDNTicker.Stop()
 	for {
 		select {
 		case netModel := <-a.newNetModel:
 			// Network model is already validated, applying...
+			// Previously resolved IPs are used for domain names that are still
+			// referenced by firewall rules, new names get resolved in the background.
 			a.Lock()
 			a.netModel = netModel
+			a.stopRemovedRingCaptures()
+			a.fwFQDNIPs = mergeFwFQDNIPs(fwFQDNsToResolve(netModel), a.fwFQDNIPs)
 			a.updateCurrentState()
 			a.updateIntendedState()
 			a.reconcile()
 			a.Unlock()
+			a.startFwFQDNResolution()
 
 		case <-a.resumeReconciliation:
 			a.Lock()
 			a.reconcile()
 			a.Unlock()
 
+		case <-fwFQDNTicker.C:
+			a.startFwFQDNResolution()
+
+		case resolved := <-a.resolvedFwFQDNs:
+			a.fwFQDNResolving = false
+			a.Lock()
+			// Network model may have changed during the resolution.
+			fwFQDNIPs := mergeFwFQDNIPs(fwFQDNsToResolve(a.netModel), resolved, a.fwFQDNIPs)
+			if !reflect.DeepEqual(fwFQDNIPs, a.fwFQDNIPs) {
+				log.Infof("IP addresses of domain names used in firewall rules have changed: %v",
+					fwFQDNIPs)
+				a.fwFQDNIPs = fwFQDNIPs
+				a.updateIntendedState()
+				a.reconcile()
+			}
+			a.Unlock()
+			if a.fwFQDNResolveAgain {
+				a.fwFQDNResolveAgain = false
+				a.startFwFQDNResolution()
+			}
+
 		case linkUpdate, ok := <-linkChan:
 			if !ok {
 				log.Warn("Link subscription was closed")
@@ -198,6 +252,92 @@ func (a *agent) reconcile() {
 	}
 }
 
+// startFwFQDNResolution starts resolving domain names used in firewall rules
+// in the background. If a resolution is already running, another one is started
+// after it completes.
+// Called only from the run loop.
+func (a *agent) startFwFQDNResolution() {
+	if a.fwFQDNResolving {
+		a.fwFQDNResolveAgain = true
+		return
+	}
+	a.Lock()
+	fqdns := fwFQDNsToResolve(a.netModel)
+	prevResolved := a.fwFQDNIPs
+	a.Unlock()
+	if len(fqdns) == 0 {
+		return
+	}
+	a.fwFQDNResolving = true
+	go func() {
+		a.resolvedFwFQDNs <- resolveFwFQDNs(a.ctx, fqdns, prevResolved)
+	}()
+}
+
+// fwFQDNsToResolve returns domain names used in firewall rules which need
+// to be resolved. FQDNs of endpoints are skipped, their IPs are known without
+// resolving.
+func fwFQDNsToResolve(netModel parsedNetModel) (fqdns []string) {
+	added := make(map[string]bool)
+	for _, rule := range netModel.Firewall.Rules {
+		for _, fqdn := range rule.DstFQDNs {
+			if _, isEp := getEndpointByFQDN(netModel, fqdn); isEp || added[fqdn] {
+				continue
+			}
+			added[fqdn] = true
+			fqdns = append(fqdns, fqdn)
+		}
+	}
+	return fqdns
+}
+
+// resolveFwFQDNs resolves the given domain names used in firewall rules.
+// If resolution fails, previously resolved IPs are kept.
+func resolveFwFQDNs(ctx context.Context, fqdns []string,
+	prevResolved map[string][]net.IP) map[string][]net.IP {
+	resolved := make(map[string][]net.IP)
+	for _, fqdn := range fqdns {
+		lookupCtx, cancel := context.WithTimeout(ctx, fwFQDNResolveTimeout)
+		addrs, err := net.DefaultResolver.LookupIPAddr(lookupCtx, fqdn)
+		cancel()
+		if err != nil {
+			log.Warnf("Failed to resolve %s used in firewall rule: %v", fqdn, err)
+			resolved[fqdn] = prevResolved[fqdn]
+			continue
+		}
+		var ips []net.IP
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+		// Sort to avoid config changes when DNS server rotates the answers.
+		sort.Slice(ips, func(i, j int) bool {
+			return bytes.Compare(ips[i].To16(), ips[j].To16()) < 0
+		})
+		resolved[fqdn] = ips
+	}
+	return resolved
+}
+
+// mergeFwFQDNIPs returns IPs of the given domain names, each taken from the first
+// of the sources where the name is present. Names not present in any of the sources
+// are omitted. Returns nil if no IPs are known.
+func mergeFwFQDNIPs(fqdns []string, sources ...map[string][]net.IP) (merged map[string][]net.IP) {
+	for _, fqdn := range fqdns {
+		for _, source := range sources {
+			ips, found := source[fqdn]
+			if !found {
+				continue
+			}
+			if merged == nil {
+				merged = make(map[string][]net.IP)
+			}
+			merged[fqdn] = ips
+			break
+		}
+	}
+	return merged
+}
+
 func (a *agent) linkSubscribe(doneChan <-chan struct{}) chan netlink.LinkUpdate {
 	linkChan := make(chan netlink.LinkUpdate, 64)
 	linkErrFunc := func(err error) {
@@ -282,10 +422,157 @@ func (a *agent) applyNetModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Debugf("Parsed network model: %+v", parsedNetModel)
-	a.newNetModel <- parsedNetModel
+	a.submitLock.Lock()
+	a.submitNetModel(parsedNetModel)
+	a.submitLock.Unlock()
 	w.WriteHeader(http.StatusOK)
 }
 
+// submitNetModel passes validated network model to the run loop to apply.
+// Must be called with submitLock held.
+func (a *agent) submitNetModel(netModel parsedNetModel) {
+	a.submittedModel = netModel
+	a.newNetModel <- netModel
+}
+
+// setImpairment changes impairment of a single port, bridge or network
+// without the need to submit the whole network model.
+// Request with empty body (or "null") removes the impairment.
+func (a *agent) setImpairment(w http.ResponseWriter, r *http.Request) {
+	itemType := mux.Vars(r)["itemType"]
+	logicalLabel := mux.Vars(r)["logicalLabel"]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to read impairment from HTTP request: %v", err)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	var impairment *api.Impairment
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err = json.Unmarshal(body, &impairment); err != nil {
+			errMsg := fmt.Sprintf("Failed to unmarshal impairment from JSON: %v", err)
+			log.Error(errMsg)
+			http.Error(w, errMsg, http.StatusBadRequest)
+			return
+		}
+	}
+	// Change is applied on top of the most recently submitted model
+	// (which may be still waiting for the run loop to apply it).
+	a.submitLock.Lock()
+	defer a.submitLock.Unlock()
+	// Slices are copied to not modify the submitted model
+	// in case the change is rejected.
+	netModel := a.submittedModel.NetworkModel
+	var found bool
+	switch itemType {
+	case api.Port{}.ItemType():
+		netModel.Ports = append([]api.Port(nil), netModel.Ports...)
+		for i := range netModel.Ports {
+			if netModel.Ports[i].LogicalLabel == logicalLabel {
+				netModel.Ports[i].Impairment = impairment
+				found = true
+			}
+		}
+	case api.Bridge{}.ItemType():
+		netModel.Bridges = append([]api.Bridge(nil), netModel.Bridges...)
+		for i := range netModel.Bridges {
+			if netModel.Bridges[i].LogicalLabel == logicalLabel {
+				netModel.Bridges[i].Impairment = impairment
+				found = true
+			}
+		}
+	case api.Network{}.ItemType():
+		netModel.Networks = append([]api.Network(nil), netModel.Networks...)
+		for i := range netModel.Networks {
+			if netModel.Networks[i].LogicalLabel == logicalLabel {
+				netModel.Networks[i].Impairment = impairment
+				found = true
+			}
+		}
+	default:
+		errMsg := fmt.Sprintf("Impairment is not supported for %s", itemType)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	if !found {
+		errMsg := fmt.Sprintf("No %s with logical label %s", itemType, logicalLabel)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusNotFound)
+		return
+	}
+	parsedNetModel, err := a.parseNetModel(netModel)
+	if err != nil {
+		errMsg := fmt.Sprintf("Impairment is invalid: %v", err)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	a.submitNetModel(parsedNetModel)
+	w.WriteHeader(http.StatusOK)
+}
+
+// captivePortalLogin logs a client in through the captive portal
+// without the need to submit the login form.
+func (a *agent) captivePortalLogin(w http.ResponseWriter, r *http.Request) {
+	a.setCaptivePortalClient(w, r, true)
+}
+
+// captivePortalLogout logs out a client previously logged in through the captive portal.
+func (a *agent) captivePortalLogout(w http.ResponseWriter, r *http.Request) {
+	a.setCaptivePortalClient(w, r, false)
+}
+
+func (a *agent) setCaptivePortalClient(w http.ResponseWriter, r *http.Request, login bool) {
+	logicalLabel := mux.Vars(r)["logicalLabel"]
+	clientIP := net.ParseIP(mux.Vars(r)["ip"])
+	if clientIP == nil {
+		errMsg := fmt.Sprintf("Invalid client IP address: %s", mux.Vars(r)["ip"])
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	a.Lock()
+	netModel := a.netModel
+	a.Unlock()
+	item := netModel.items.getItem(api.Endpoint{}.ItemType(), logicalLabel)
+	if item == nil || item.category != (api.CaptivePortal{}).ItemCategory() {
+		errMsg := fmt.Sprintf("No captive portal with logical label %s", logicalLabel)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusNotFound)
+		return
+	}
+	portalIP := net.ParseIP(item.LabeledItem.(api.CaptivePortal).IP)
+	for _, network := range netModel.Networks {
+		if network.CaptivePortal != logicalLabel {
+			continue
+		}
+		subnet := a.captivePortalSubnet(network, portalIP)
+		if subnet == nil || !subnet.Contains(clientIP) {
+			continue
+		}
+		nsName := a.networkNsName(network.LogicalLabel)
+		var err error
+		if login {
+			err = configitems.AddIPSetEntry(nsName, captivePortalIPSet, clientIP)
+		} else {
+			err = configitems.DelIPSetEntry(nsName, captivePortalIPSet, clientIP)
+		}
+		if err != nil {
+			log.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	errMsg := fmt.Sprintf("Client %s is not from any network with captive portal %s",
+		clientIP, logicalLabel)
+	log.Error(errMsg)
+	http.Error(w, errMsg, http.StatusBadRequest)
+}
+
 func (a *agent) getNetConfig(w http.ResponseWriter, r *http.Request) {
 	dotExporter := &dg.DotExporter{CheckDeps: true}
 	a.Lock()
@@ -315,7 +602,20 @@ func (a *agent) getSDNStatus(w http.ResponseWriter, r *http.Request) {
 			ErrMsg:  err.Error(),
 		})
 	}
+	netModel := a.netModel
+	fwDstFQDNIPs := make([][]net.IP, len(netModel.Firewall.Rules))
+	for i, rule := range netModel.Firewall.Rules {
+		fwDstFQDNIPs[i] = a.getFwFQDNIPs(rule.DstFQDNs)
+	}
 	a.Unlock()
+	status.Ports = a.getPortsStatus(netModel.Ports)
+	status.DHCPServers = a.getDHCPServersStatus(netModel.Networks)
+	status.DNSServers = a.getDNSServersStatus(netModel.Endpoints.DNSServers)
+	status.HTTPProxies = a.getHTTPProxiesStatus(netModel.Endpoints)
+	status.HTTPServers = a.getHTTPServersStatus(netModel.Endpoints.HTTPServers)
+	status.CaptivePortals = a.getCaptivePortalsStatus(netModel)
+	status.Firewall = a.getFirewallStatus(netModel.Firewall.Rules, fwDstFQDNIPs)
+	status.Conntrack = a.getConntrackSummary()
 	resp, err := json.Marshal(status)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to marshal SDN status to JSON: %v", err)
@@ -364,6 +664,223 @@ func (a *agent) getMgmtIPs() (ips []string) {
 	return
 }
 
+func (a *agent) getPortsStatus(ports []api.Port) (statuses []api.PortStatus) {
+	for _, port := range ports {
+		status := api.PortStatus{
+			LogicalLabel: port.LogicalLabel,
+			MAC:          port.MAC,
+		}
+		mac, _ := net.ParseMAC(port.MAC)
+		netIf, found := a.macLookup.GetInterfaceByMAC(mac, false)
+		if !found {
+			statuses = append(statuses, status)
+			continue
+		}
+		status.IfName = netIf.IfName
+		link, err := netlink.LinkByName(netIf.IfName)
+		if err != nil {
+			log.Warnf("Failed to get link for interface %s: %v", netIf.IfName, err)
+			statuses = append(statuses, status)
+			continue
+		}
+		attrs := link.Attrs()
+		status.AdminUp = attrs.Flags&net.FlagUp != 0
+		status.OperState = attrs.OperState.String()
+		status.MTU = attrs.MTU
+		if stats := attrs.Statistics; stats != nil {
+			status.Counters = api.LinkCounters{
+				RxPackets: stats.RxPackets,
+				TxPackets: stats.TxPackets,
+				RxBytes:   stats.RxBytes,
+				TxBytes:   stats.TxBytes,
+				RxErrors:  stats.RxErrors,
+				TxErrors:  stats.TxErrors,
+				RxDropped: stats.RxDropped,
+				TxDropped: stats.TxDropped,
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (a *agent) getDHCPServersStatus(networks []api.Network) (statuses []api.DHCPServerStatus) {
+	for _, network := range networks {
+		runsDhcpv6 := runsDhcpv6Server(network)
+		if !network.DHCP.Enable && !runsDhcpv6 {
+			continue
+		}
+		var leases []api.DHCPLease
+		if network.DHCP.Enable {
+			var err error
+			leases, err = configitems.ReadDhcpLeases(network.LogicalLabel)
+			if err != nil {
+				log.Warnf("Failed to read DHCP leases for network %s: %v",
+					network.LogicalLabel, err)
+			}
+		}
+		if runsDhcpv6 {
+			v6Leases, err := configitems.ReadDhcpv6Leases(network.LogicalLabel)
+			if err != nil {
+				log.Warnf("Failed to read DHCPv6 leases for network %s: %v",
+					network.LogicalLabel, err)
+			}
+			leases = append(leases, v6Leases...)
+		}
+		statuses = append(statuses, api.DHCPServerStatus{
+			Network: network.LogicalLabel,
+			Leases:  leases,
+		})
+	}
+	return statuses
+}
+
+func (a *agent) getDNSServersStatus(dnsServers []api.DNSServer) (statuses []api.DNSServerStatus) {
+	for _, dnsSrv := range dnsServers {
+		status, err := configitems.ReadDnsServerStats(dnsSrv.LogicalLabel)
+		if err != nil {
+			log.Warnf("Failed to read stats of DNS server %s: %v",
+				dnsSrv.LogicalLabel, err)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (a *agent) getHTTPServersStatus(httpSrvs []api.HTTPServer) (statuses []api.HTTPServerStatus) {
+	for _, httpSrv := range httpSrvs {
+		status, err := configitems.ReadHttpServerStats(httpSrv.LogicalLabel)
+		if err != nil {
+			log.Warnf("Failed to read stats of HTTP server %s: %v",
+				httpSrv.LogicalLabel, err)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (a *agent) getCaptivePortalsStatus(netModel parsedNetModel) (statuses []api.CaptivePortalStatus) {
+	for _, portal := range netModel.Endpoints.CaptivePortals {
+		status := api.CaptivePortalStatus{Endpoint: portal.LogicalLabel}
+		for _, network := range netModel.Networks {
+			if network.CaptivePortal != portal.LogicalLabel {
+				continue
+			}
+			nsName := a.networkNsName(network.LogicalLabel)
+			entries, err := configitems.ListIPSetEntries(nsName, captivePortalIPSet)
+			if err != nil {
+				log.Warnf("Failed to list clients of captive portal %s in network %s: %v",
+					portal.LogicalLabel, network.LogicalLabel, err)
+				continue
+			}
+			for _, entry := range entries {
+				status.Clients = append(status.Clients, api.CaptivePortalClient{
+					Network: network.LogicalLabel,
+					IP:      entry.IP.String(),
+					Timeout: entry.Timeout,
+				})
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// getFirewallStatus returns hit counters of firewall rules, summed over
+// iptables and ip6tables.
+func (a *agent) getFirewallStatus(rules []api.FwRule,
+	dstFQDNIPs [][]net.IP) (statuses []api.FwRuleStatus) {
+	if len(rules) == 0 {
+		return nil
+	}
+	statuses = make([]api.FwRuleStatus, len(rules))
+	for i, rule := range rules {
+		statuses[i] = api.FwRuleStatus{
+			Name:   fwRuleName(i, rule),
+			Action: rule.Action,
+		}
+		for _, ip := range dstFQDNIPs[i] {
+			statuses[i].DstFQDNIPs = append(statuses[i].DstFQDNIPs, ip.String())
+		}
+	}
+	for _, forIPv6 := range []bool{false, true} {
+		counters, err := configitems.GetIptablesRuleCounters(configitems.MainNsName,
+			"filter", fwIptablesChain, forIPv6)
+		if err != nil {
+			log.Warnf("Failed to get firewall rule counters: %v", err)
+			continue
+		}
+		for _, ruleCounters := range counters {
+			index, isFwRule := parseFwRuleComment(ruleCounters.Comment)
+			if !isFwRule || index >= len(statuses) {
+				continue
+			}
+			statuses[index].Packets += ruleCounters.Packets
+			statuses[index].Bytes += ruleCounters.Bytes
+		}
+	}
+	return statuses
+}
+
+func (a *agent) getHTTPProxiesStatus(endpoints api.Endpoints) (statuses []api.HTTPProxyStatus) {
+	var proxies []string
+	for _, proxy := range endpoints.ExplicitProxies {
+		proxies = append(proxies, proxy.LogicalLabel)
+	}
+	for _, proxy := range endpoints.TransparentProxies {
+		proxies = append(proxies, proxy.LogicalLabel)
+	}
+	for _, proxy := range proxies {
+		stats, err := configitems.ReadHttpProxyStats(proxy)
+		if err != nil {
+			log.Warnf("Failed to read stats of HTTP proxy %s: %v", proxy, err)
+		}
+		statuses = append(statuses, api.HTTPProxyStatus{
+			Endpoint: proxy,
+			Stats:    stats,
+		})
+	}
+	return statuses
+}
+
+// Conntrack entries are summarized only for the main network namespace,
+// where all the routing and NAT between networks and endpoints takes place.
+func (a *agent) getConntrackSummary() (summary api.ConntrackSummary) {
+	protoNames := map[uint8]string{
+		syscall.IPPROTO_TCP:    "tcp",
+		syscall.IPPROTO_UDP:    "udp",
+		syscall.IPPROTO_ICMP:   "icmp",
+		syscall.IPPROTO_ICMPV6: "icmpv6",
+		syscall.IPPROTO_SCTP:   "sctp",
+	}
+	for _, family := range []netlink.InetFamily{syscall.AF_INET, syscall.AF_INET6} {
+		flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, family)
+		if err != nil {
+			log.Warnf("Failed to list conntrack entries (family %d): %v", family, err)
+			continue
+		}
+		for _, flow := range flows {
+			summary.Flows++
+			if family == syscall.AF_INET {
+				summary.IPv4Flows++
+			} else {
+				summary.IPv6Flows++
+			}
+			proto, known := protoNames[flow.Forward.Protocol]
+			if !known {
+				proto = fmt.Sprintf("proto-%d", flow.Forward.Protocol)
+			}
+			if summary.FlowsByProto == nil {
+				summary.FlowsByProto = make(map[string]uint64)
+			}
+			summary.FlowsByProto[proto]++
+			summary.Packets += flow.Forward.Packets + flow.Reverse.Packets
+			summary.Bytes += flow.Forward.Bytes + flow.Reverse.Bytes
+		}
+	}
+	return summary
+}
+
 // Gateway to use to route traffic towards host OS.
 func (a *agent) getHostGwIP(ipv6 bool) net.IP {
 	hostPort, found := a.macLookup.GetInterfaceByMAC(hostPortMACPrefix, true)
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/lf-edge/eden/sdn/vm/api"
+)
+
+func TestSetImpairmentOnSubmittedModel(t *testing.T) {
+	a := &agent{newNetModel: make(chan parsedNetModel, 10)}
+	netModel := api.NetworkModel{
+		Ports: []api.Port{{LogicalLabel: "eth0", MAC: "02:fe:00:00:00:01",
+			EVEConnect: api.EVEConnect{MAC: "02:fe:00:00:01:01"}}},
+		Host: &api.HostConfig{HostIPs: []string{"192.168.0.10"}, ControllerPort: 3000},
+	}
+	body, err := json.Marshal(netModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	a.applyNetModel(rec, httptest.NewRequest(http.MethodPut, "/net-model.json",
+		bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("failed to apply network model: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// The model is still queued (not applied by the run loop), impairment
+	// should be nevertheless applied on top of it.
+	setImpairment := func(itemType, logicalLabel, impairment string) int {
+		req := httptest.NewRequest(http.MethodPut, "/impairment", bytes.NewBufferString(impairment))
+		req = mux.SetURLVars(req, map[string]string{
+			"itemType": itemType, "logicalLabel": logicalLabel})
+		rec := httptest.NewRecorder()
+		a.setImpairment(rec, req)
+		return rec.Code
+	}
+	if code := setImpairment(api.Port{}.ItemType(), "eth0", `{"delay": 100}`); code != http.StatusOK {
+		t.Fatalf("failed to set impairment: %d", code)
+	}
+	if code := setImpairment(api.Port{}.ItemType(), "eth1", `{"delay": 100}`); code != http.StatusNotFound {
+		t.Errorf("impairment of unknown port: %d, expected %d", code, http.StatusNotFound)
+	}
+	if code := setImpairment(api.Port{}.ItemType(), "eth0", `{"jitter": 10}`); code != http.StatusBadRequest {
+		t.Errorf("invalid impairment: %d, expected %d", code, http.StatusBadRequest)
+	}
+
+	if len(a.newNetModel) != 2 {
+		t.Fatalf("expected 2 submitted models, got %d", len(a.newNetModel))
+	}
+	if first := <-a.newNetModel; first.Ports[0].Impairment != nil {
+		t.Error("impairment modified the previously submitted model")
+	}
+	second := <-a.newNetModel
+	if second.Ports[0].Impairment == nil || second.Ports[0].Impairment.Delay != 100 {
+		t.Errorf("unexpected impairment: %+v", second.Ports[0].Impairment)
+	}
+	if a.netModel.Ports != nil {
+		t.Error("applied model should be only changed by the run loop")
+	}
+}
+
+func TestStopRemovedRingCaptures(t *testing.T) {
+	newRingCapture := func(itemType, logicalLabel string, stopped *bool) *ringCapture {
+		done := make(chan struct{})
+		return &ringCapture{
+			status: api.RingCapture{ItemType: itemType, LogicalLabel: logicalLabel},
+			cancel: func() {
+				*stopped = true
+				close(done)
+			},
+			done: done,
+		}
+	}
+	var eth0Stopped, eth1Stopped bool
+	portType := api.Port{}.ItemType()
+	a := &agent{ringCaptures: map[string]*ringCapture{
+		ringCaptureKey(portType, "eth0"): newRingCapture(portType, "eth0", &eth0Stopped),
+		ringCaptureKey(portType, "eth1"): newRingCapture(portType, "eth1", &eth1Stopped),
+	}}
+	a.netModel.items = labeledItems{
+		itemID{typename: portType, logicalLabel: "eth0"}: &labeledItem{},
+	}
+	a.stopRemovedRingCaptures()
+	if eth0Stopped {
+		t.Error("ring capture of existing port was stopped")
+	}
+	if !eth1Stopped {
+		t.Error("ring capture of removed port was not stopped")
+	}
+	if len(a.ringCaptures) != 1 || a.ringCaptures[ringCaptureKey(portType, "eth0")] == nil {
+		t.Errorf("unexpected ring captures: %v", a.ringCaptures)
+	}
+}
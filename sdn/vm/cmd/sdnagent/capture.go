@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lf-edge/eden/sdn/vm/api"
+	"github.com/lf-edge/eden/sdn/vm/pkg/configitems"
+	"github.com/lf-edge/eden/sdn/vm/pkg/pcapng"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	tcpdumpBinary = "tcpdump"
+	// Content type of captured packets returned over HTTP.
+	pcapngContentType = "application/x-pcapng"
+	// Recorded in the pcapng section header as the application that created the capture.
+	captureUserAppl = "Eden-SDN agent"
+	// Capture entire packets by default.
+	defaultCaptureSnapLen = 262144
+	// By default, ring capture keeps up to 10MB of the most recent packets.
+	defaultRingCaptureSize = 10 << 20
+	// Link type used until the actual one is reported by tcpdump.
+	linkTypeEthernet = 1
+)
+
+// captureTarget : interface (and its network namespace) where packets of a given
+// port, network or endpoint can be captured.
+type captureTarget struct {
+	itemType     string
+	logicalLabel string
+	netNamespace string
+	ifName       string
+}
+
+// captureOpts : options for packet capture, passed as URL query parameters.
+type captureOpts struct {
+	filter   string
+	snapLen  uint32
+	count    uint64
+	duration time.Duration
+	size     int // only for ring capture
+}
+
+// capture : running tcpdump process with its output parsed by PcapReader.
+type capture struct {
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	reader *pcapng.PcapReader
+	intf   pcapng.Interface
+}
+
+// ringCapture : capture running in the background, keeping the most recent
+// packets in memory.
+type ringCapture struct {
+	sync.Mutex
+	status  api.RingCapture
+	intf    pcapng.Interface
+	packets []pcapng.Packet
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func ringCaptureKey(itemType, logicalLabel string) string {
+	return itemType + "/" + logicalLabel
+}
+
+func parseCaptureOpts(r *http.Request) (opts captureOpts, err error) {
+	query := r.URL.Query()
+	opts.filter = query.Get("filter")
+	opts.snapLen = defaultCaptureSnapLen
+	if snapLen := query.Get("snaplen"); snapLen != "" {
+		value, err := strconv.ParseUint(snapLen, 10, 32)
+		if err != nil || value == 0 {
+			return opts, fmt.Errorf("invalid snapshot length: %s", snapLen)
+		}
+		opts.snapLen = uint32(value)
+	}
+	if count := query.Get("count"); count != "" {
+		if opts.count, err = strconv.ParseUint(count, 10, 64); err != nil {
+			return opts, fmt.Errorf("invalid packet count: %s", count)
+		}
+	}
+	if duration := query.Get("duration"); duration != "" {
+		if opts.duration, err = time.ParseDuration(duration); err != nil {
+			return opts, fmt.Errorf("invalid capture duration: %s", duration)
+		}
+	}
+	opts.size = defaultRingCaptureSize
+	if size := query.Get("size"); size != "" {
+		if opts.size, err = strconv.Atoi(size); err != nil || opts.size <= 0 {
+			return opts, fmt.Errorf("invalid ring capture size: %s", size)
+		}
+	}
+	return opts, nil
+}
+
+// getCaptureTarget returns interface to capture packets from for the given item.
+// Returned httpStatus describes the error (if any).
+func (a *agent) getCaptureTarget(itemType, logicalLabel string) (
+	target captureTarget, httpStatus int, err error) {
+	a.Lock()
+	defer a.Unlock()
+	target.itemType = itemType
+	target.logicalLabel = logicalLabel
+	switch itemType {
+	case api.Port{}.ItemType(), api.Network{}.ItemType(), api.Endpoint{}.ItemType():
+	default:
+		return target, http.StatusBadRequest,
+			fmt.Errorf("packet capture is not supported for %s", itemType)
+	}
+	item := a.netModel.items.getItem(itemType, logicalLabel)
+	if item == nil {
+		return target, http.StatusNotFound,
+			fmt.Errorf("no %s with logical label %s", itemType, logicalLabel)
+	}
+	switch itemType {
+	case api.Port{}.ItemType():
+		port := item.LabeledItem.(api.Port)
+		mac, _ := net.ParseMAC(port.MAC) // already validated
+		netIf, found := a.macLookup.GetInterfaceByMAC(mac, false)
+		if !found {
+			return target, http.StatusServiceUnavailable,
+				fmt.Errorf("interface for port %s was not found", logicalLabel)
+		}
+		target.netNamespace = configitems.MainNsName
+		target.ifName = netIf.IfName
+	case api.Network{}.ItemType():
+		// Capture on the network side of the veth connecting network with the bridge.
+		// This is where the DHCP server is running and where all traffic of the network
+		// passes through.
+		_, inIfName, _ := a.networkBrVethName(logicalLabel)
+		target.netNamespace = a.networkNsName(logicalLabel)
+		target.ifName = inIfName
+	case api.Endpoint{}.ItemType():
+		_, inIfName, _ := a.endpointVethName(logicalLabel)
+		target.netNamespace = a.endpointNsName(logicalLabel)
+		target.ifName = inIfName
+	}
+	return target, http.StatusOK, nil
+}
+
+func (t captureTarget) tcpdumpCmd(ctx context.Context, args ...string) *exec.Cmd {
+	tcpdumpArgs := append([]string{"-i", t.ifName}, args...)
+	if t.netNamespace == configitems.MainNsName {
+		return exec.CommandContext(ctx, tcpdumpBinary, tcpdumpArgs...)
+	}
+	nsArgs := []string{"netns", "exec", t.netNamespace, tcpdumpBinary}
+	return exec.CommandContext(ctx, "ip", append(nsArgs, tcpdumpArgs...)...)
+}
+
+// validateFilter uses tcpdump to compile the filter without capturing any packets.
+func (t captureTarget) validateFilter(ctx context.Context, filter string) error {
+	if filter == "" {
+		return nil
+	}
+	output, err := t.tcpdumpCmd(ctx, "-d", filter).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("invalid capture filter '%s': %s",
+			filter, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (t captureTarget) interfaceDesc(opts captureOpts) pcapng.Interface {
+	return pcapng.Interface{
+		Name:        t.ifName,
+		Description: fmt.Sprintf("%s %s", t.itemType, t.logicalLabel),
+		Filter:      opts.filter,
+		LinkType:    linkTypeEthernet,
+		SnapLen:     opts.snapLen,
+	}
+}
+
+// startCapture starts tcpdump writing captured packets in the pcap format
+// to stdout. Capture stops when the context is canceled.
+func startCapture(ctx context.Context, target captureTarget, opts captureOpts) (
+	*capture, error) {
+	args := []string{"-U", "-w", "-", "-s", strconv.Itoa(int(opts.snapLen))}
+	if opts.count > 0 {
+		args = append(args, "-c", strconv.FormatUint(opts.count, 10))
+	}
+	if opts.filter != "" {
+		args = append(args, opts.filter)
+	}
+	c := &capture{
+		cmd:  target.tcpdumpCmd(ctx, args...),
+		intf: target.interfaceDesc(opts),
+	}
+	c.cmd.Stderr = &c.stderr
+	stdout, err := c.cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tcpdump stdout: %w", err)
+	}
+	if err = c.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start tcpdump: %w", err)
+	}
+	c.reader, err = pcapng.NewPcapReader(stdout)
+	if err != nil {
+		c.stop()
+		return nil, fmt.Errorf("tcpdump failed: %s (%w)",
+			strings.TrimSpace(c.stderr.String()), err)
+	}
+	c.intf.LinkType = c.reader.LinkType
+	c.intf.SnapLen = c.reader.SnapLen
+	return c, nil
+}
+
+func (c *capture) stop() {
+	// Ignore errors - tcpdump may have already exited.
+	_ = c.cmd.Process.Kill()
+	_ = c.cmd.Wait()
+}
+
+// capture streams packets captured on the given item in the pcapng format.
+func (a *agent) capture(w http.ResponseWriter, r *http.Request) {
+	target, httpStatus, err := a.getCaptureTarget(
+		mux.Vars(r)["itemType"], mux.Vars(r)["logicalLabel"])
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	opts, err := parseCaptureOpts(r)
+	if err == nil {
+		err = target.validateFilter(r.Context(), opts.filter)
+	}
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	if opts.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.duration)
+		defer cancel()
+	}
+	c, err := startCapture(ctx, target, opts)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer c.stop()
+	w.Header().Set("Content-Type", pcapngContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	writer, err := pcapng.NewWriter(w, captureUserAppl, c.intf)
+	for err == nil {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		var packet pcapng.Packet
+		packet, err = c.reader.ReadPacket()
+		if err == nil {
+			err = writer.WritePacket(packet)
+		}
+	}
+	if err != io.EOF && ctx.Err() == nil {
+		log.Errorf("Packet capture on %s %s failed: %v",
+			target.itemType, target.logicalLabel, err)
+	}
+}
+
+// startRingCapture starts (or restarts) ring capture on the given item.
+func (a *agent) startRingCapture(w http.ResponseWriter, r *http.Request) {
+	target, httpStatus, err := a.getCaptureTarget(
+		mux.Vars(r)["itemType"], mux.Vars(r)["logicalLabel"])
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	opts, err := parseCaptureOpts(r)
+	if err == nil {
+		err = target.validateFilter(r.Context(), opts.filter)
+	}
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	if opts.duration > 0 {
+		ctx, cancel = context.WithTimeout(a.ctx, opts.duration)
+	}
+	rc := &ringCapture{
+		status: api.RingCapture{
+			ItemType:     target.itemType,
+			LogicalLabel: target.logicalLabel,
+			Filter:       opts.filter,
+			MaxSize:      opts.size,
+			Started:      time.Now(),
+		},
+		intf:   target.interfaceDesc(opts),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	key := ringCaptureKey(target.itemType, target.logicalLabel)
+	a.Lock()
+	if prevCapture, exists := a.ringCaptures[key]; exists {
+		prevCapture.stop()
+	}
+	a.ringCaptures[key] = rc
+	a.Unlock()
+	go rc.run(ctx, target, opts)
+	w.WriteHeader(http.StatusOK)
+}
+
+// getRingCapture returns packets currently buffered by the ring capture
+// in the pcapng format.
+func (a *agent) getRingCapture(w http.ResponseWriter, r *http.Request) {
+	rc := a.lookupRingCapture(w, r)
+	if rc == nil {
+		return
+	}
+	rc.Lock()
+	packets := append([]pcapng.Packet(nil), rc.packets...)
+	intf := rc.intf
+	rc.Unlock()
+	w.Header().Set("Content-Type", pcapngContentType)
+	w.WriteHeader(http.StatusOK)
+	writer, err := pcapng.NewWriter(w, captureUserAppl, intf)
+	for i := 0; err == nil && i < len(packets); i++ {
+		err = writer.WritePacket(packets[i])
+	}
+	if err != nil {
+		log.Errorf("Failed to write ring capture to HTTP response: %v", err)
+	}
+}
+
+// stopRingCapture stops ring capture and discards all buffered packets.
+func (a *agent) stopRingCapture(w http.ResponseWriter, r *http.Request) {
+	rc := a.lookupRingCapture(w, r)
+	if rc == nil {
+		return
+	}
+	a.Lock()
+	key := ringCaptureKey(rc.status.ItemType, rc.status.LogicalLabel)
+	if a.ringCaptures[key] == rc {
+		delete(a.ringCaptures, key)
+	}
+	a.Unlock()
+	rc.stop()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *agent) listRingCaptures(w http.ResponseWriter, r *http.Request) {
+	statuses := []api.RingCapture{}
+	a.Lock()
+	for _, rc := range a.ringCaptures {
+		rc.Lock()
+		statuses = append(statuses, rc.status)
+		rc.Unlock()
+	}
+	a.Unlock()
+	resp, err := json.Marshal(statuses)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to marshal ring captures to JSON: %v", err)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(resp); err != nil {
+		log.Errorf("Failed to write ring captures to HTTP response: %v", err)
+	}
+}
+
+// stopRemovedRingCaptures stops ring captures of items that are no longer
+// present in the network model. Caller must hold the agent lock.
+func (a *agent) stopRemovedRingCaptures() {
+	for key, rc := range a.ringCaptures {
+		if a.netModel.items.getItem(rc.status.ItemType, rc.status.LogicalLabel) != nil {
+			continue
+		}
+		delete(a.ringCaptures, key)
+		rc.stop()
+	}
+}
+
+func (a *agent) lookupRingCapture(w http.ResponseWriter, r *http.Request) *ringCapture {
+	itemType := mux.Vars(r)["itemType"]
+	logicalLabel := mux.Vars(r)["logicalLabel"]
+	a.Lock()
+	rc := a.ringCaptures[ringCaptureKey(itemType, logicalLabel)]
+	a.Unlock()
+	if rc == nil {
+		errMsg := fmt.Sprintf("No ring capture running for %s %s", itemType, logicalLabel)
+		log.Error(errMsg)
+		http.Error(w, errMsg, http.StatusNotFound)
+	}
+	return rc
+}
+
+func (rc *ringCapture) run(ctx context.Context, target captureTarget, opts captureOpts) {
+	defer close(rc.done)
+	c, err := startCapture(ctx, target, opts)
+	if err != nil {
+		rc.setError(err)
+		return
+	}
+	rc.Lock()
+	rc.intf = c.intf
+	rc.Unlock()
+	for {
+		packet, err := c.reader.ReadPacket()
+		if err != nil {
+			// Wait for tcpdump to exit before reading its stderr.
+			c.stop()
+			// With packet count limit tcpdump exits once the count is reached.
+			completed := err == io.EOF && opts.count > 0
+			if ctx.Err() == nil && !completed {
+				rc.setError(fmt.Errorf("capture stopped: %v (%s)",
+					err, strings.TrimSpace(c.stderr.String())))
+			}
+			return
+		}
+		rc.addPacket(packet)
+	}
+}
+
+func (rc *ringCapture) addPacket(packet pcapng.Packet) {
+	rc.Lock()
+	defer rc.Unlock()
+	rc.packets = append(rc.packets, packet)
+	rc.status.Size += len(packet.Data)
+	for rc.status.Size > rc.status.MaxSize && len(rc.packets) > 1 {
+		rc.status.Size -= len(rc.packets[0].Data)
+		rc.packets = rc.packets[1:]
+		rc.status.Dropped++
+	}
+	rc.status.Packets = len(rc.packets)
+}
+
+func (rc *ringCapture) setError(err error) {
+	log.Errorf("Ring capture on %s %s failed: %v",
+		rc.status.ItemType, rc.status.LogicalLabel, err)
+	rc.Lock()
+	rc.status.Error = err.Error()
+	rc.Unlock()
+}
+
+func (rc *ringCapture) stop() {
+	rc.cancel()
+	<-rc.done
+}
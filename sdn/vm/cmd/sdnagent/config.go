@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"net"
+	"net/url"
 	"strconv"
 	"strings"
 	"syscall"
@@ -28,6 +29,14 @@ const (
 
 	// Iptables chain used to implement firewall rules.
 	fwIptablesChain = "firewall"
+	// Prefix for comments of iptables rules implementing firewall rules.
+	// Followed by the index of the firewall rule.
+	fwRuleCommentPrefix = "fw-rule-"
+	// Iptables chain (nat table) used to redirect traffic into a captive portal.
+	captivePortalChain = "captive-portal"
+	// IP set with clients logged in through a captive portal.
+	// There is one in the network namespace of every network with a portal attached.
+	captivePortalIPSet = "captive-portal"
 
 	// ifNameMaxLen is a limit for interface names in the Linux kernel (IFNAMSIZ).
 	ifNameMaxLen = 15
@@ -112,6 +121,9 @@ func (a *agent) updateIntendedState() {
 	for _, httpSrv := range a.netModel.Endpoints.HTTPServers {
 		a.intendedState.PutSubGraph(a.getIntendedHttpSrvEp(httpSrv))
 	}
+	for _, portal := range a.netModel.Endpoints.CaptivePortals {
+		a.intendedState.PutSubGraph(a.getIntendedCaptivePortalEp(portal))
+	}
 
 	// TODO (ntp servers, netboot servers)
 }
@@ -180,6 +192,17 @@ func (a *agent) getIntendedHostConnectivity() dg.Graph {
 			},
 		},
 	}, nil)
+	intendedCfg.PutItem(configitems.IptablesChain{
+		ChainName: "POSTROUTING",
+		Table:     "nat",
+		ForIPv6:   true,
+		Rules: []configitems.IptablesRule{
+			{
+				Args:        []string{"-o", netIf.IfName, "-j", "MASQUERADE"},
+				Description: "S-NAT IPv6 traffic leaving SDN VM towards the host OS",
+			},
+		},
+	}, nil)
 	return intendedCfg
 }
 
@@ -211,6 +234,19 @@ func (a *agent) getIntendedBridges() dg.Graph {
 			AdminUP:  port.AdminUP,
 			MTU:      port.MTU,
 		}, nil)
+		if impairment := a.getPortImpairment(port); impairment != nil {
+			intendedCfg.PutItem(configitems.LinkImpairment{
+				NetNamespace: configitems.MainNsName,
+				Interface: configitems.ImpairedIf{
+					PhysIf: configitems.PhysIf{
+						MAC:          mac,
+						LogicalLabel: port.LogicalLabel,
+					},
+				},
+				Impairment: *impairment,
+				IfbName:    a.portIfbName(port.LogicalLabel),
+			}, nil)
+		}
 	}
 	for _, bond := range a.netModel.Bonds {
 		labeledItem := a.netModel.items.getItem(api.Bond{}.ItemType(), bond.LogicalLabel)
@@ -271,6 +307,50 @@ func (a *agent) getIntendedBridges() dg.Graph {
 	return intendedCfg
 }
 
+// networkAddrs : IP addresses of a network (nil for IP version not used).
+type networkAddrs struct {
+	ipv4Subnet *net.IPNet
+	ipv4GwIP   *net.IPNet
+	ipv6Subnet *net.IPNet
+	ipv6GwIP   *net.IPNet
+	// Pool of prefixes delegated to clients using DHCPv6.
+	pdPrefix *net.IPNet
+}
+
+// subnets returns all subnets routed into the network.
+func (addrs networkAddrs) subnets() (subnets []*net.IPNet) {
+	for _, subnet := range []*net.IPNet{addrs.ipv4Subnet, addrs.ipv6Subnet, addrs.pdPrefix} {
+		if subnet != nil {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
+
+func (a *agent) getNetworkAddrs(network api.Network) (addrs networkAddrs) {
+	// Already validated.
+	_, subnet, _ := net.ParseCIDR(network.Subnet)
+	gwIP := &net.IPNet{IP: net.ParseIP(network.GwIP), Mask: subnet.Mask}
+	if len(subnet.IP) == net.IPv6len {
+		addrs.ipv6Subnet, addrs.ipv6GwIP = subnet, gwIP
+	} else {
+		addrs.ipv4Subnet, addrs.ipv4GwIP = subnet, gwIP
+	}
+	if network.IPv6 != nil {
+		if network.IPv6.Subnet != "" {
+			_, addrs.ipv6Subnet, _ = net.ParseCIDR(network.IPv6.Subnet)
+			addrs.ipv6GwIP = &net.IPNet{
+				IP:   net.ParseIP(network.IPv6.GwIP),
+				Mask: addrs.ipv6Subnet.Mask,
+			}
+		}
+		if pd := network.IPv6.PrefixDelegation; pd != nil {
+			_, addrs.pdPrefix, _ = net.ParseCIDR(pd.Prefix)
+		}
+	}
+	return addrs
+}
+
 func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 	index, hasIndex := a.networkIndex[network.LogicalLabel]
 	if !hasIndex {
@@ -281,19 +361,29 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 
 	// Network namespace connected with the bridge using veth.
 	brVethName, brInIfName, brOutIfName := a.networkBrVethName(network.LogicalLabel)
-	_, subnet, _ := net.ParseCIDR(network.Subnet) // already validated
-	gwIP := &net.IPNet{IP: net.ParseIP(network.GwIP), Mask: subnet.Mask}
+	addrs := a.getNetworkAddrs(network)
+	var gwIPs []*net.IPNet
+	for _, gwIP := range []*net.IPNet{addrs.ipv4GwIP, addrs.ipv6GwIP} {
+		if gwIP != nil {
+			gwIPs = append(gwIPs, gwIP)
+		}
+	}
 	nsName := a.networkNsName(network.LogicalLabel)
 	netNs := configitems.NetNamespace{
 		NsName: nsName,
 	}
 	intendedCfg.PutItem(netNs, nil)
+	intendedCfg.PutItem(configitems.Sysctl{
+		NetNamespace:         nsName,
+		EnableIPv4Forwarding: true,
+		EnableIPv6Forwarding: true,
+	}, nil)
 	intendedCfg.PutItem(configitems.Veth{
 		VethName: brVethName,
 		Peer1: configitems.VethPeer{
 			IfName:       brInIfName,
 			NetNamespace: nsName,
-			IPAddresses:  []*net.IPNet{gwIP},
+			IPAddresses:  gwIPs,
 		},
 		Peer2: configitems.VethPeer{
 			IfName:       brOutIfName,
@@ -305,30 +395,60 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 		},
 	}, nil)
 
+	// Impairment of the network traffic, applied on both sides of the veth
+	// to affect both directions.
+	if network.Impairment != nil && !network.Impairment.IsEmpty() {
+		intendedCfg.PutItem(configitems.LinkImpairment{
+			NetNamespace: nsName,
+			Interface: configitems.ImpairedIf{
+				VethName:       brVethName,
+				VethPeerIfName: brInIfName,
+			},
+			Impairment: *network.Impairment,
+		}, nil)
+		intendedCfg.PutItem(configitems.LinkImpairment{
+			NetNamespace: configitems.MainNsName,
+			Interface: configitems.ImpairedIf{
+				VethName:       brVethName,
+				VethPeerIfName: brOutIfName,
+			},
+			Impairment: *network.Impairment,
+		}, nil)
+	}
+
 	// Another veth used to connect network with the main "router".
 	rtVethName, rtInIfName, rtOutIfName := a.networkRtVethName(network.LogicalLabel)
-	isIPv6 := len(subnet.IP) == net.IPv6len
-	inIP, outIP := a.genVethIPsForNetwork(network.LogicalLabel, isIPv6)
+	var inIPs, outIPs []*net.IPNet
+	if addrs.ipv4Subnet != nil {
+		inIP, outIP := a.genVethIPsForNetwork(network.LogicalLabel, false)
+		inIPs = append(inIPs, inIP)
+		outIPs = append(outIPs, outIP)
+	}
+	if addrs.ipv6Subnet != nil {
+		inIP, outIP := a.genVethIPsForNetwork(network.LogicalLabel, true)
+		inIPs = append(inIPs, inIP)
+		outIPs = append(outIPs, outIP)
+	}
 	intendedCfg.PutItem(configitems.Veth{
 		VethName: rtVethName,
 		Peer1: configitems.VethPeer{
 			IfName:       rtInIfName,
 			NetNamespace: nsName,
-			IPAddresses:  []*net.IPNet{inIP},
+			IPAddresses:  inIPs,
 			MTU:          maxMTU, // do not limit MTU on this link
 		},
 		Peer2: configitems.VethPeer{
 			IfName:       rtOutIfName,
 			NetNamespace: configitems.MainNsName,
-			IPAddresses:  []*net.IPNet{outIP},
+			IPAddresses:  outIPs,
 			MTU:          maxMTU, // do not limit MTU on this link
 		},
 	}, nil)
 
 	// DHCP server.
 	dhcp := network.DHCP
-	if dhcp.Enable {
-		ipRange := a.subnetToHostIPRange(subnet)
+	if dhcp.Enable && addrs.ipv4Subnet != nil {
+		ipRange := a.subnetToHostIPRange(addrs.ipv4Subnet)
 		if dhcp.IPRange.FromIP != "" {
 			ipRange.FromIP = net.ParseIP(dhcp.IPRange.FromIP)
 			ipRange.ToIP = net.ParseIP(dhcp.IPRange.ToIP)
@@ -351,9 +471,9 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 			NetNamespace:   nsName,
 			VethName:       brVethName,
 			VethPeerIfName: brInIfName,
-			Subnet:         subnet,
+			Subnet:         addrs.ipv4Subnet,
 			IPRange:        ipRange,
-			GatewayIP:      gwIP.IP,
+			GatewayIP:      addrs.ipv4GwIP.IP,
 			DomainName:     dhcp.DomainName,
 			DNSServers:     dnsServers,
 			NTPServer:      ntpServer,
@@ -361,33 +481,42 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 		}, nil)
 	}
 
+	// IPv6 address autoconfiguration.
+	if addrs.ipv6Subnet != nil {
+		a.putIPv6AutoconfConfig(intendedCfg, network, addrs)
+	}
+
 	// Routing.
 	rt := networkRTBaseIndex + index
-	intendedCfg.PutItem(configitems.IPRule{
-		SrcNet:   subnet,
-		Table:    rt,
-		Priority: networkIPRulePriority,
-	}, nil)
-	intendedCfg.PutItem(configitems.IPRule{
-		DstNet:   subnet,
-		Table:    rt,
-		Priority: networkIPRulePriority,
-	}, nil)
-	defaultDst := allIPv4
-	if isIPv6 {
-		defaultDst = allIPv6
+	for _, subnet := range addrs.subnets() {
+		intendedCfg.PutItem(configitems.IPRule{
+			SrcNet:   subnet,
+			Table:    rt,
+			Priority: networkIPRulePriority,
+		}, nil)
+		intendedCfg.PutItem(configitems.IPRule{
+			DstNet:   subnet,
+			Table:    rt,
+			Priority: networkIPRulePriority,
+		}, nil)
 	}
 	// - default route from inside of the network namespace
-	intendedCfg.PutItem(configitems.Route{
-		NetNamespace: nsName,
-		Table:        syscall.RT_TABLE_MAIN,
-		DstNet:       defaultDst,
-		OutputIf: configitems.RouteOutIf{
-			VethName:       rtVethName,
-			VethPeerIfName: rtInIfName,
-		},
-		GwIP: outIP.IP,
-	}, nil)
+	for _, outIP := range outIPs {
+		defaultDst := allIPv4
+		if outIP.IP.To4() == nil {
+			defaultDst = allIPv6
+		}
+		intendedCfg.PutItem(configitems.Route{
+			NetNamespace: nsName,
+			Table:        syscall.RT_TABLE_MAIN,
+			DstNet:       defaultDst,
+			OutputIf: configitems.RouteOutIf{
+				VethName:       rtVethName,
+				VethPeerIfName: rtInIfName,
+			},
+			GwIP: outIP.IP,
+		}, nil)
+	}
 	// - route for every endpoint
 	epTypename := api.Endpoint{}.ItemType()
 	for itemID, item := range a.netModel.items {
@@ -396,8 +525,10 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 		}
 		ep := a.labeledItemToEndpoint(item)
 		_, epSubnet, _ := net.ParseCIDR(ep.Subnet)
+		// Captive portal must be always reachable for clients to be able to log in.
 		reachable := network.Router == nil ||
-			strListContains(network.Router.ReachableEndpoints, ep.LogicalLabel)
+			strListContains(network.Router.ReachableEndpoints, ep.LogicalLabel) ||
+			ep.LogicalLabel == network.CaptivePortal
 		if reachable {
 			epVethName, _, epOutIfName := a.endpointVethName(ep.LogicalLabel)
 			intendedCfg.PutItem(configitems.Route{
@@ -420,11 +551,19 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 	}
 	// - route for every other network (including itself)
 	for _, network2 := range a.netModel.Networks {
-		_, net2Subnet, _ := net.ParseCIDR(network2.Subnet)
 		reachable := network.Router == nil ||
 			network2.LogicalLabel == network.LogicalLabel ||
 			strListContains(network.Router.ReachableNetworks, network2.LogicalLabel)
-		if reachable {
+		for _, net2Subnet := range a.getNetworkAddrs(network2).subnets() {
+			if !reachable {
+				intendedCfg.PutItem(configitems.Route{
+					NetNamespace: configitems.MainNsName,
+					Table:        rt,
+					DstNet:       net2Subnet,
+				}, nil)
+				continue
+			}
+			isIPv6 := len(net2Subnet.IP) == net.IPv6len
 			net2VethName, _, net2OutIfName := a.networkRtVethName(network2.LogicalLabel)
 			net2InIP, _ := a.genVethIPsForNetwork(network2.LogicalLabel, isIPv6)
 			intendedCfg.PutItem(configitems.Route{
@@ -437,39 +576,86 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 				},
 				GwIP: net2InIP.IP,
 			}, nil)
-		} else {
-			intendedCfg.PutItem(configitems.Route{
-				NetNamespace: configitems.MainNsName,
-				Table:        rt,
-				DstNet:       net2Subnet,
-			}, nil)
 		}
 	}
 	// - route for the outside world if enabled
 	outsideRechability := network.Router == nil || network.Router.OutsideReachability
 	hostPort, hostPortfound := a.macLookup.GetInterfaceByMAC(hostPortMACPrefix, true)
-	hostGwIP := a.getHostGwIP(isIPv6)
-	if outsideRechability && hostPortfound && hostGwIP != nil {
+	for _, defaultDst := range []*net.IPNet{allIPv4, allIPv6} {
+		isIPv6 := defaultDst == allIPv6
+		hostGwIP := a.getHostGwIP(isIPv6)
+		if outsideRechability && hostPortfound && hostGwIP != nil {
+			intendedCfg.PutItem(configitems.Route{
+				NetNamespace: configitems.MainNsName,
+				Table:        rt,
+				DstNet:       defaultDst,
+				OutputIf: configitems.RouteOutIf{
+					PhysIf: configitems.PhysIf{
+						MAC:          hostPort.MAC,
+						LogicalLabel: hostPortLogicalLabel,
+					},
+				},
+				GwIP: hostGwIP,
+			}, nil)
+		}
+		// - everything else is unreachable
 		intendedCfg.PutItem(configitems.Route{
 			NetNamespace: configitems.MainNsName,
 			Table:        rt,
 			DstNet:       defaultDst,
+			Metric:       ^uint32(0), // Lowest prio.
+		}, nil)
+	}
+
+	// NAT64 gateway.
+	if network.IPv6 != nil && network.IPv6.NAT64 != nil && addrs.ipv6Subnet != nil {
+		nat64Prefix := a.getNAT64Prefix(network)
+		ipv4Pool, ipv6Addr := a.genNAT64AddrsForNetwork(network.LogicalLabel)
+		nat64GwName := a.nat64GatewayName(network.LogicalLabel)
+		intendedCfg.PutItem(configitems.NAT64Gateway{
+			GatewayName: nat64GwName,
+			Prefix:      nat64Prefix,
+			IPv4Pool:    ipv4Pool,
+			IPv6Addr:    ipv6Addr,
+		}, nil)
+		intendedCfg.PutItem(configitems.Route{
+			NetNamespace: configitems.MainNsName,
+			Table:        rt,
+			DstNet:       nat64Prefix,
 			OutputIf: configitems.RouteOutIf{
-				PhysIf: configitems.PhysIf{
-					MAC:          hostPort.MAC,
-					LogicalLabel: hostPortLogicalLabel,
-				},
+				NAT64Gateway: nat64GwName,
 			},
-			GwIP: hostGwIP,
+		}, nil)
+		// Translated IPv4 traffic is routed using the routing table of the network.
+		intendedCfg.PutItem(configitems.IPRule{
+			SrcNet:   ipv4Pool,
+			Table:    rt,
+			Priority: networkIPRulePriority,
 		}, nil)
 	}
-	// - everything else is unreachable
-	intendedCfg.PutItem(configitems.Route{
-		NetNamespace: configitems.MainNsName,
-		Table:        rt,
-		DstNet:       defaultDst,
-		Metric:       ^uint32(0), // Lowest prio.
-	}, nil)
+
+	// D-NAT rules redirecting traffic into the captive portal and/or the transparent proxy.
+	var dnatRules, dnat6Rules []configitems.IptablesRule
+	var dnatRefersChains, dnat6RefersChains []string
+	var dnatRefersVeths, dnat6RefersVeths []string
+
+	// Captive portal.
+	if network.CaptivePortal != "" {
+		forIPv6 := a.putCaptivePortalConfig(intendedCfg, network)
+		rule := configitems.IptablesRule{
+			Args:        []string{"-i", brInIfName, "-j", captivePortalChain},
+			Description: "Redirect traffic of clients not logged in into the captive portal",
+		}
+		if forIPv6 {
+			dnat6Rules = append(dnat6Rules, rule)
+			dnat6RefersChains = append(dnat6RefersChains, captivePortalChain)
+			dnat6RefersVeths = append(dnat6RefersVeths, brVethName)
+		} else {
+			dnatRules = append(dnatRules, rule)
+			dnatRefersChains = append(dnatRefersChains, captivePortalChain)
+			dnatRefersVeths = append(dnatRefersVeths, brVethName)
+		}
+	}
 
 	// Transparent proxy.
 	if network.TransparentProxy != "" {
@@ -480,13 +666,11 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 			httpsPorts = append(httpsPorts, api.ProxyPort{Port: controllerPort})
 		}
 		// iptables to transparently redirect traffic into the proxy
-		dnatRules := []configitems.IptablesRule{
-			{
-				Args: []string{"-p", "tcp", "--dport", "80", "-j", "DNAT",
-					"--to-destination", ep.IP},
-				Description: "Send HTTP traffic into the proxy",
-			},
-		}
+		dnatRules = append(dnatRules, configitems.IptablesRule{
+			Args: []string{"-p", "tcp", "--dport", "80", "-j", "DNAT",
+				"--to-destination", ep.IP},
+			Description: "Send HTTP traffic into the proxy",
+		})
 		for _, httpsPort := range httpsPorts {
 			dnatRules = append(dnatRules, configitems.IptablesRule{
 				Args: []string{"-p", "tcp", "--dport", strconv.Itoa(int(httpsPort.Port)),
@@ -495,38 +679,321 @@ func (a *agent) getIntendedNetwork(network api.Network) dg.Graph {
 					httpsPort.Port),
 			})
 		}
+	}
+	if len(dnatRules) > 0 {
 		intendedCfg.PutItem(configitems.IptablesChain{
 			NetNamespace: nsName,
 			ChainName:    "PREROUTING",
 			Table:        "nat",
 			ForIPv6:      false,
 			Rules:        dnatRules,
+			RefersChains: dnatRefersChains,
+			RefersVeths:  dnatRefersVeths,
+		}, nil)
+	}
+	if len(dnat6Rules) > 0 {
+		intendedCfg.PutItem(configitems.IptablesChain{
+			NetNamespace: nsName,
+			ChainName:    "PREROUTING",
+			Table:        "nat",
+			ForIPv6:      true,
+			Rules:        dnat6Rules,
+			RefersChains: dnat6RefersChains,
+			RefersVeths:  dnat6RefersVeths,
 		}, nil)
 	}
 	return intendedCfg
 }
 
+// putCaptivePortalConfig adds IP set with logged-in clients and iptables rules
+// redirecting (and blocking) traffic of other clients into the captive portal.
+// Captive portal is only applied to traffic of the same IP version as is the portal IP.
+// Returns true if the portal is for IPv6 traffic.
+func (a *agent) putCaptivePortalConfig(graph dg.Graph, network api.Network) (forIPv6 bool) {
+	ep := a.getEndpoint(network.CaptivePortal)
+	portal := a.getCaptivePortal(network.CaptivePortal)
+	portalIP := net.ParseIP(ep.IP)
+	forIPv6 = portalIP.To4() == nil
+	brVethName, brInIfName, _ := a.networkBrVethName(network.LogicalLabel)
+	nsName := a.networkNsName(network.LogicalLabel)
+	graph.PutItem(configitems.IPSet{
+		NetNamespace: nsName,
+		SetName:      captivePortalIPSet,
+		ForIPv6:      forIPv6,
+		Timeout:      portal.SessionTimeout,
+	}, nil)
+
+	// D-NAT HTTP(S) (and optionally DNS) traffic of clients not logged in yet.
+	dstIP := ep.IP
+	if forIPv6 {
+		dstIP = "[" + dstIP + "]"
+	}
+	notLoggedIn := []string{"-m", "set", "!", "--match-set", captivePortalIPSet, "src"}
+	dnatRule := func(proto string, dport, toPort uint16, description string) configitems.IptablesRule {
+		args := append([]string{}, notLoggedIn...)
+		args = append(args, "-p", proto, "--dport", strconv.Itoa(int(dport)),
+			"-j", "DNAT", "--to-destination", fmt.Sprintf("%s:%d", dstIP, toPort))
+		return configitems.IptablesRule{Args: args, Description: description}
+	}
+	dnatRules := []configitems.IptablesRule{
+		dnatRule("tcp", 80, 80, "Send HTTP traffic into the captive portal"),
+		dnatRule("tcp", 443, 443, "Send HTTPS traffic into the captive portal"),
+	}
+	controllerPort := a.netModel.Host.ControllerPort
+	if controllerPort != 0 && controllerPort != 443 && controllerPort != 80 {
+		dnatRules = append(dnatRules, dnatRule("tcp", controllerPort, 443,
+			fmt.Sprintf("Send controller traffic (port %d) into the captive portal",
+				controllerPort)))
+	}
+	if portal.InterceptDNS {
+		dnatRules = append(dnatRules,
+			dnatRule("udp", 53, 53, "Send DNS traffic (UDP) into the captive portal"),
+			dnatRule("tcp", 53, 53, "Send DNS traffic (TCP) into the captive portal"))
+	}
+	graph.PutItem(configitems.IptablesChain{
+		NetNamespace: nsName,
+		ChainName:    captivePortalChain,
+		Table:        "nat",
+		ForIPv6:      forIPv6,
+		Rules:        dnatRules,
+		RefersIPSets: []string{captivePortalIPSet},
+	}, nil)
+
+	// Block any other traffic of clients not logged in yet.
+	fwdRules := []configitems.IptablesRule{
+		{
+			Args: []string{"-i", brInIfName, "-m", "set", "--match-set",
+				captivePortalIPSet, "src", "-j", "ACCEPT"},
+			Description: "Allow traffic of logged-in clients",
+		},
+		{
+			Args:        []string{"-i", brInIfName, "-d", ep.IP, "-j", "ACCEPT"},
+			Description: "Allow access to the captive portal",
+		},
+	}
+	if !portal.InterceptDNS {
+		for _, proto := range []string{"udp", "tcp"} {
+			fwdRules = append(fwdRules, configitems.IptablesRule{
+				Args: []string{"-i", brInIfName, "-p", proto, "--dport", "53",
+					"-j", "ACCEPT"},
+				Description: fmt.Sprintf("Allow DNS traffic (%s)", strings.ToUpper(proto)),
+			})
+		}
+	}
+	fwdRules = append(fwdRules, configitems.IptablesRule{
+		Args:        []string{"-i", brInIfName, "-j", "REJECT"},
+		Description: "Block other traffic of clients not logged in",
+	})
+	graph.PutItem(configitems.IptablesChain{
+		NetNamespace: nsName,
+		ChainName:    "FORWARD",
+		Table:        "filter",
+		ForIPv6:      forIPv6,
+		Rules:        fwdRules,
+		RefersVeths:  []string{brVethName},
+		RefersIPSets: []string{captivePortalIPSet},
+	}, nil)
+	return forIPv6
+}
+
+// captivePortalSubnet returns subnet of the network with clients of the captive portal
+// (i.e. subnet of the same IP version as is the portal IP).
+func (a *agent) captivePortalSubnet(network api.Network, portalIP net.IP) *net.IPNet {
+	addrs := a.getNetworkAddrs(network)
+	if portalIP.To4() != nil {
+		return addrs.ipv4Subnet
+	}
+	return addrs.ipv6Subnet
+}
+
+// ipv6AddrMode returns how clients of the network obtain IPv6 addresses.
+func ipv6AddrMode(network api.Network) api.IPv6AddrMode {
+	if network.IPv6 == nil {
+		// IPv6-only network with default config.
+		// With DHCP enabled, DNS servers, domain name and NTP server are announced
+		// using the stateless DHCPv6.
+		if network.DHCP.Enable {
+			return api.IPv6DHCPv6Stateless
+		}
+		return api.IPv6SLAAC
+	}
+	return network.IPv6.AddrMode
+}
+
+// runsDhcpv6Server returns true if DHCPv6 server is needed for the given network.
+func runsDhcpv6Server(network api.Network) bool {
+	mode := ipv6AddrMode(network)
+	return mode == api.IPv6DHCPv6Stateless || mode == api.IPv6DHCPv6Stateful ||
+		(network.IPv6 != nil && network.IPv6.PrefixDelegation != nil)
+}
+
+// putIPv6AutoconfConfig adds Router Advertisement daemon and DHCPv6 server
+// configured for the network.
+func (a *agent) putIPv6AutoconfConfig(graph dg.Graph, network api.Network,
+	addrs networkAddrs) {
+	var ipv6Cfg api.NetworkIPv6
+	if network.IPv6 != nil {
+		ipv6Cfg = *network.IPv6
+	}
+	brVethName, brInIfName, _ := a.networkBrVethName(network.LogicalLabel)
+	nsName := a.networkNsName(network.LogicalLabel)
+	dhcp := network.DHCP
+	var dnsServers []net.IP
+	for _, dnsServer := range dhcp.PublicDNS {
+		dnsServers = append(dnsServers, net.ParseIP(dnsServer))
+	}
+	for _, dnsServer := range dhcp.PrivateDNS {
+		ep := a.getEndpoint(dnsServer)
+		dnsServers = append(dnsServers, net.ParseIP(ep.IP))
+	}
+	var ipv6DNSServers []net.IP
+	for _, dnsServer := range dnsServers {
+		if dnsServer.To4() == nil {
+			ipv6DNSServers = append(ipv6DNSServers, dnsServer)
+		}
+	}
+	mode := ipv6AddrMode(network)
+
+	// Router Advertisements.
+	ra := ipv6Cfg.RA
+	if !ra.Disable {
+		managedFlag := mode == api.IPv6DHCPv6Stateful
+		otherFlag := mode == api.IPv6DHCPv6Stateful || mode == api.IPv6DHCPv6Stateless
+		autonomousFlag := mode == api.IPv6SLAAC || mode == api.IPv6DHCPv6Stateless
+		if ra.ManagedFlag != nil {
+			managedFlag = *ra.ManagedFlag
+		}
+		if ra.OtherFlag != nil {
+			otherFlag = *ra.OtherFlag
+		}
+		if ra.AutonomousFlag != nil {
+			autonomousFlag = *ra.AutonomousFlag
+		}
+		radvd := configitems.RouterAdvertiser{
+			AdvertiserName:    network.LogicalLabel,
+			NetNamespace:      nsName,
+			VethName:          brVethName,
+			VethPeerIfName:    brInIfName,
+			Prefix:            addrs.ipv6Subnet,
+			ManagedFlag:       managedFlag,
+			OtherFlag:         otherFlag,
+			AutonomousFlag:    autonomousFlag,
+			Interval:          ra.Interval,
+			RouterLifetime:    ra.RouterLifetime,
+			NotDefaultRouter:  ra.NotDefaultRouter,
+			ValidLifetime:     ra.ValidLifetime,
+			PreferredLifetime: ra.PreferredLifetime,
+			MTU:               ra.MTU,
+		}
+		if ra.RDNSS {
+			radvd.RDNSS = ipv6DNSServers
+		}
+		if ra.DNSSL && dhcp.DomainName != "" {
+			radvd.DNSSL = []string{dhcp.DomainName}
+		}
+		if ipv6Cfg.NAT64 != nil && ipv6Cfg.NAT64.AnnouncePrefix {
+			radvd.NAT64Prefix = a.getNAT64Prefix(network)
+		}
+		graph.PutItem(radvd, nil)
+	}
+
+	// DHCPv6 server.
+	pd := ipv6Cfg.PrefixDelegation
+	if runsDhcpv6Server(network) {
+		var ntpServer string
+		if dhcp.PublicNTP != "" {
+			if ip := net.ParseIP(dhcp.PublicNTP); ip == nil || ip.To4() == nil {
+				// FQDN or IPv6 address.
+				ntpServer = dhcp.PublicNTP
+			}
+		}
+		if dhcp.PrivateNTP != "" {
+			ep := a.getEndpoint(dhcp.PrivateNTP)
+			if ip := net.ParseIP(ep.IP); ip.To4() == nil {
+				ntpServer = ep.IP
+			}
+		}
+		dhcpv6Srv := configitems.Dhcpv6Server{
+			ServerName:     network.LogicalLabel,
+			NetNamespace:   nsName,
+			VethName:       brVethName,
+			VethPeerIfName: brInIfName,
+			Subnet:         addrs.ipv6Subnet,
+			DomainName:     dhcp.DomainName,
+			DNSServers:     ipv6DNSServers,
+			NTPServer:      ntpServer,
+		}
+		if mode == api.IPv6DHCPv6Stateful {
+			if ipv6Cfg.DHCPv6Range.FromIP != "" {
+				dhcpv6Srv.IPRange.FromIP = net.ParseIP(ipv6Cfg.DHCPv6Range.FromIP)
+				dhcpv6Srv.IPRange.ToIP = net.ParseIP(ipv6Cfg.DHCPv6Range.ToIP)
+			} else {
+				subnetIP := ipToInt(addrs.ipv6Subnet.IP)
+				dhcpv6Srv.IPRange.FromIP = subnetIP.Copy().Inc(0x100).ToIP()
+				dhcpv6Srv.IPRange.ToIP = subnetIP.Copy().Inc(0x1ff).ToIP()
+			}
+		}
+		if pd != nil {
+			dhcpv6Srv.PrefixDelegation = addrs.pdPrefix
+			dhcpv6Srv.DelegatedLength = pd.DelegatedLength
+		}
+		graph.PutItem(dhcpv6Srv, nil)
+	}
+}
+
+func (a *agent) getNAT64Prefix(network api.Network) *net.IPNet {
+	prefix := network.IPv6.NAT64.Prefix
+	if prefix == "" {
+		prefix = api.DefaultNAT64Prefix
+	}
+	_, nat64Prefix, _ := net.ParseCIDR(prefix) // already validated
+	return nat64Prefix
+}
+
 func (a *agent) getIntendedFirewall() dg.Graph {
 	graphArgs := dg.InitArgs{Name: firewallSG}
 	intendedCfg := dg.New(graphArgs)
-	iptablesRules := make([]configitems.IptablesRule, 0, 2+len(a.netModel.Firewall.Rules))
-	// Allow any subsequent traffic that results from an already allowed connection.
-	iptablesRules = append(iptablesRules, configitems.IptablesRule{
-		Args: []string{"-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
-	})
+	for _, forIPv6 := range []bool{false, true} {
+		a.putFirewallConfig(intendedCfg, forIPv6)
+	}
+	return intendedCfg
+}
+
+// putFirewallConfig adds iptables (or ip6tables) chains and IP sets implementing
+// the firewall for one IP version.
+func (a *agent) putFirewallConfig(graph dg.Graph, forIPv6 bool) {
+	fwRules := a.netModel.Firewall.Rules
+	iptablesRules := make([]configitems.IptablesRule, 0, 2+len(fwRules))
+	if !a.netModel.Firewall.ExplicitConnState {
+		// Allow any subsequent traffic that results from an already allowed connection.
+		iptablesRules = append(iptablesRules, configitems.IptablesRule{
+			Args: []string{"-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+		})
+	}
 	// Add explicitly configured firewall rules.
-	for _, rule := range a.netModel.Firewall.Rules {
-		iptablesRules = append(iptablesRules, a.getIntendedFwRule(rule))
+	var ipSets []string
+	for i, fwRule := range fwRules {
+		rule, ruleIPSets, applies := a.getIntendedFwRule(i, fwRule, forIPv6)
+		if !applies {
+			continue
+		}
+		iptablesRules = append(iptablesRules, rule)
+		for _, ipSet := range ruleIPSets {
+			graph.PutItem(ipSet, nil)
+			ipSets = append(ipSets, ipSet.SetName)
+		}
 	}
 	// Implicitly allow everything not matched by the rules above.
-	allowTheRest := api.FwRule{Action: api.FwAllow}
-	iptablesRules = append(iptablesRules, a.getIntendedFwRule(allowTheRest))
-	intendedCfg.PutItem(configitems.IptablesChain{
+	iptablesRules = append(iptablesRules, configitems.IptablesRule{
+		Args: []string{"-p", "all", "-j", "ACCEPT"},
+	})
+	graph.PutItem(configitems.IptablesChain{
 		NetNamespace: configitems.MainNsName,
 		ChainName:    fwIptablesChain,
 		Table:        "filter",
-		ForIPv6:      false,
+		ForIPv6:      forIPv6,
 		Rules:        iptablesRules,
+		RefersIPSets: ipSets,
 	}, nil)
 	// Link the firewall chain with every network and endpoint (outside) interface.
 	veths := make([]string, 0, len(a.netModel.Networks)+len(a.netModel.Endpoints.GetAll()))
@@ -545,31 +1012,73 @@ func (a *agent) getIntendedFirewall() dg.Graph {
 			Args: []string{"-i", epOutIfName, "-j", fwIptablesChain},
 		})
 	}
-	intendedCfg.PutItem(configitems.IptablesChain{
+	graph.PutItem(configitems.IptablesChain{
 		NetNamespace: configitems.MainNsName,
 		ChainName:    "FORWARD",
 		Table:        "filter",
-		ForIPv6:      false,
+		ForIPv6:      forIPv6,
 		Rules:        iptablesRules,
 		RefersVeths:  veths,
 		RefersChains: []string{fwIptablesChain},
 	}, nil)
-	return intendedCfg
 }
 
-func (a *agent) getIntendedFwRule(rule api.FwRule) configitems.IptablesRule {
+// getIntendedFwRule returns iptables rule implementing the firewall rule for the given
+// IP version, together with IP sets that the rule refers to.
+// Returns applies=false if the rule cannot match any traffic of this IP version.
+// Rule with DstFQDNs is installed even if the names are not resolved yet,
+// with an empty IP set that gets filled once the resolution succeeds.
+func (a *agent) getIntendedFwRule(index int, rule api.FwRule, forIPv6 bool) (
+	iptablesRule configitems.IptablesRule, ipSets []configitems.IPSet, applies bool) {
 	var ruleArgs []string
 	if rule.SrcSubnet != "" {
+		if isIPv6Subnet(rule.SrcSubnet) != forIPv6 {
+			return iptablesRule, nil, false
+		}
 		ruleArgs = append(ruleArgs, "-s", rule.SrcSubnet)
 	}
 	if rule.DstSubnet != "" {
+		if isIPv6Subnet(rule.DstSubnet) != forIPv6 {
+			return iptablesRule, nil, false
+		}
 		ruleArgs = append(ruleArgs, "-d", rule.DstSubnet)
 	}
+	for _, addrSet := range []struct {
+		name      string
+		dir       string
+		ips       []net.IP
+		used      bool
+		keepEmpty bool
+	}{
+		{"src", "src", a.getEndpointIPs(rule.SrcEndpoints), len(rule.SrcEndpoints) > 0, false},
+		{"dst", "dst", a.getEndpointIPs(rule.DstEndpoints), len(rule.DstEndpoints) > 0, false},
+		{"fqdn", "dst", a.getFwFQDNIPs(rule.DstFQDNs), len(rule.DstFQDNs) > 0, true},
+	} {
+		if !addrSet.used {
+			continue
+		}
+		ips := filterIPsByVersion(addrSet.ips, forIPv6)
+		if len(ips) == 0 && !addrSet.keepEmpty {
+			return iptablesRule, nil, false
+		}
+		ipSet := configitems.IPSet{
+			NetNamespace: configitems.MainNsName,
+			SetName:      fwIPSetName(index, addrSet.name, forIPv6),
+			ForIPv6:      forIPv6,
+			Entries:      ips,
+		}
+		ipSets = append(ipSets, ipSet)
+		ruleArgs = append(ruleArgs, "-m", "set", "--match-set", ipSet.SetName, addrSet.dir)
+	}
 	switch rule.Protocol {
 	case api.AnyProto:
 		ruleArgs = append(ruleArgs, "-p", "all")
 	case api.ICMP:
-		ruleArgs = append(ruleArgs, "-p", "icmp")
+		if forIPv6 {
+			ruleArgs = append(ruleArgs, "-p", "ipv6-icmp")
+		} else {
+			ruleArgs = append(ruleArgs, "-p", "icmp")
+		}
 	case api.TCP:
 		ruleArgs = append(ruleArgs, "-p", "tcp")
 	case api.UDP:
@@ -583,6 +1092,23 @@ func (a *agent) getIntendedFwRule(rule api.FwRule) configitems.IptablesRule {
 		ruleArgs = append(ruleArgs, "--match", "multiport",
 			"--dport", strings.Join(ports, ","))
 	}
+	if len(rule.ConnStates) > 0 {
+		var states []string
+		for _, state := range rule.ConnStates {
+			states = append(states, strings.ToUpper(api.FwConnStateToString[state]))
+		}
+		ruleArgs = append(ruleArgs, "-m", "conntrack",
+			"--ctstate", strings.Join(states, ","))
+	}
+	if rule.RateLimit != nil {
+		ruleArgs = append(ruleArgs, "-m", "limit", "--limit", rule.RateLimit.Rate)
+		if rule.RateLimit.Burst != 0 {
+			ruleArgs = append(ruleArgs, "--limit-burst",
+				strconv.Itoa(int(rule.RateLimit.Burst)))
+		}
+	}
+	// Comment is used to find the rule when reading hit counters.
+	ruleArgs = append(ruleArgs, "-m", "comment", "--comment", fwRuleComment(index))
 	switch rule.Action {
 	case api.FwAllow:
 		ruleArgs = append(ruleArgs, "-j", "ACCEPT")
@@ -590,10 +1116,110 @@ func (a *agent) getIntendedFwRule(rule api.FwRule) configitems.IptablesRule {
 		ruleArgs = append(ruleArgs, "-j", "REJECT")
 	case api.FwDrop:
 		ruleArgs = append(ruleArgs, "-j", "DROP")
+	case api.FwLog:
+		ruleArgs = append(ruleArgs, "-j", "LOG", "--log-prefix", fwLogPrefix(index, rule))
+	}
+	iptablesRule = configitems.IptablesRule{
+		Args:        ruleArgs,
+		Description: fmt.Sprintf("Firewall rule %s", fwRuleName(index, rule)),
 	}
-	return configitems.IptablesRule{
-		Args: ruleArgs,
+	return iptablesRule, ipSets, true
+}
+
+// getEndpointIPs returns IP addresses of the given endpoints.
+func (a *agent) getEndpointIPs(logicalLabels []string) (ips []net.IP) {
+	for _, logicalLabel := range logicalLabels {
+		ips = append(ips, net.ParseIP(a.getEndpoint(logicalLabel).IP))
 	}
+	return ips
+}
+
+// getFwFQDNIPs returns IP addresses of the given domain names.
+// FQDNs of endpoints are translated to endpoint IPs, other names are looked up
+// in the cache of names resolved by resolveFwFQDNs.
+func (a *agent) getFwFQDNIPs(fqdns []string) (ips []net.IP) {
+	for _, fqdn := range fqdns {
+		if ep, isEp := getEndpointByFQDN(a.netModel, fqdn); isEp {
+			ips = append(ips, net.ParseIP(ep.IP))
+			continue
+		}
+		ips = append(ips, a.fwFQDNIPs[fqdn]...)
+	}
+	return ips
+}
+
+func getEndpointByFQDN(netModel parsedNetModel, fqdn string) (api.Endpoint, bool) {
+	fqdn = strings.TrimSuffix(fqdn, ".")
+	for _, ep := range netModel.Endpoints.GetAll() {
+		if ep.FQDN != "" && strings.EqualFold(strings.TrimSuffix(ep.FQDN, "."), fqdn) {
+			return ep, true
+		}
+	}
+	return api.Endpoint{}, false
+}
+
+func isIPv6Subnet(subnet string) bool {
+	_, ipNet, _ := net.ParseCIDR(subnet) // already validated
+	return len(ipNet.IP) == net.IPv6len
+}
+
+// filterIPsByVersion returns IP addresses of the given IP version without duplicates.
+func filterIPsByVersion(ips []net.IP, ipv6 bool) (filtered []net.IP) {
+	for _, ip := range ips {
+		if (ip.To4() == nil) != ipv6 {
+			continue
+		}
+		var duplicate bool
+		for _, prevIP := range filtered {
+			if prevIP.Equal(ip) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			filtered = append(filtered, ip)
+		}
+	}
+	return filtered
+}
+
+func fwRuleName(index int, rule api.FwRule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("rule-%d", index)
+}
+
+func fwRuleComment(index int) string {
+	return fmt.Sprintf("%s%d", fwRuleCommentPrefix, index)
+}
+
+// parseFwRuleComment returns index of the firewall rule with the given comment.
+func parseFwRuleComment(comment string) (index int, isFwRule bool) {
+	if !strings.HasPrefix(comment, fwRuleCommentPrefix) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(comment, fwRuleCommentPrefix))
+	return index, err == nil
+}
+
+func fwIPSetName(index int, name string, forIPv6 bool) string {
+	ipVersion := 4
+	if forIPv6 {
+		ipVersion = 6
+	}
+	return fmt.Sprintf("fw%d-%d-%s", ipVersion, index, name)
+}
+
+// fwLogPrefix returns prefix for packets logged by the firewall rule.
+// Prefix is limited by iptables to 29 characters.
+func fwLogPrefix(index int, rule api.FwRule) string {
+	const maxNameLen = 24
+	name := fwRuleName(index, rule)
+	if len(name) > maxNameLen {
+		name = name[:maxNameLen]
+	}
+	return "fw:" + name + ": "
 }
 
 func (a *agent) getIntendedClientEp(client api.Client) dg.Graph {
@@ -618,31 +1244,21 @@ func (a *agent) getIntendedDNSSrvEp(dnsSrv api.DNSServer) dg.Graph {
 		upstreamServers = append(upstreamServers, net.ParseIP(upstreamServer))
 	}
 	for _, staticEntry := range dnsSrv.StaticEntries {
-		var fqdn string
-		var ip net.IP
-		switch {
-		case strings.HasPrefix(staticEntry.FQDN, api.EndpointFQDNRefPrefix):
-			epLL := strings.TrimPrefix(staticEntry.FQDN, api.EndpointFQDNRefPrefix)
-			ep := a.getEndpoint(epLL)
-			fqdn = ep.FQDN
-		default:
-			fqdn = staticEntry.FQDN
-		}
-		switch {
-		case staticEntry.IP == api.AdamIPRef:
-			ip = a.netModel.hostIP
-		case strings.HasPrefix(staticEntry.IP, api.EndpointIPRefPrefix):
-			epLL := strings.TrimPrefix(staticEntry.IP, api.EndpointIPRefPrefix)
-			ep := a.getEndpoint(epLL)
-			ip = net.ParseIP(ep.IP)
-		default:
-			ip = net.ParseIP(staticEntry.IP)
-		}
 		staticEntries = append(staticEntries, configitems.DnsEntry{
-			FQDN: fqdn,
-			IP:   ip,
+			FQDN: a.resolveDNSEntryFQDN(staticEntry.FQDN),
+			IP:   a.resolveDNSEntryIP(staticEntry.IP),
 		})
 	}
+	var faultRules []api.DNSFaultRule
+	for _, rule := range dnsSrv.FaultRules {
+		rule.FQDN = a.resolveDNSEntryFQDN(rule.FQDN)
+		var rotatingIPs []string
+		for _, ip := range rule.RotatingIPs {
+			rotatingIPs = append(rotatingIPs, a.resolveDNSEntryIP(ip).String())
+		}
+		rule.RotatingIPs = rotatingIPs
+		faultRules = append(faultRules, rule)
+	}
 	intendedCfg.PutItem(configitems.DnsServer{
 		ServerName:      dnsSrv.LogicalLabel,
 		NetNamespace:    nsName,
@@ -650,10 +1266,33 @@ func (a *agent) getIntendedDNSSrvEp(dnsSrv api.DNSServer) dg.Graph {
 		VethPeerIfName:  inIfName,
 		StaticEntries:   staticEntries,
 		UpstreamServers: upstreamServers,
+		FaultRules:      faultRules,
 	}, nil)
 	return intendedCfg
 }
 
+// resolveDNSEntryFQDN translates symbolic reference to endpoint FQDN (if used).
+func (a *agent) resolveDNSEntryFQDN(fqdn string) string {
+	if strings.HasPrefix(fqdn, api.EndpointFQDNRefPrefix) {
+		epLL := strings.TrimPrefix(fqdn, api.EndpointFQDNRefPrefix)
+		return a.getEndpoint(epLL).FQDN
+	}
+	return fqdn
+}
+
+// resolveDNSEntryIP translates symbolic reference to endpoint or adam IP (if used).
+func (a *agent) resolveDNSEntryIP(ip string) net.IP {
+	switch {
+	case ip == api.AdamIPRef:
+		return a.netModel.hostIP
+	case strings.HasPrefix(ip, api.EndpointIPRefPrefix):
+		epLL := strings.TrimPrefix(ip, api.EndpointIPRefPrefix)
+		return net.ParseIP(a.getEndpoint(epLL).IP)
+	default:
+		return net.ParseIP(ip)
+	}
+}
+
 func (a *agent) getIntendedExProxyEp(proxy api.ExplicitProxy) dg.Graph {
 	graphArgs := dg.InitArgs{Name: endpointSGPrefix + proxy.LogicalLabel}
 	intendedCfg := dg.New(graphArgs)
@@ -713,19 +1352,85 @@ func (a *agent) getIntendedHttpSrvEp(httpSrv api.HTTPServer) dg.Graph {
 	vethName, _, _ := a.endpointVethName(httpSrv.LogicalLabel)
 	epIP := net.ParseIP(httpSrv.IP)
 	intendedCfg.PutItem(configitems.HttpServer{
-		ServerName:   httpSrv.LogicalLabel,
+		ServerName:       httpSrv.LogicalLabel,
+		NetNamespace:     nsName,
+		VethName:         vethName,
+		ListenIP:         epIP,
+		HTTPPort:         httpSrv.HTTPPort,
+		HTTPSPort:        httpSrv.HTTPSPort,
+		CertPEM:          httpSrv.CertPEM,
+		KeyPEM:           httpSrv.KeyPEM,
+		Paths:            a.resolveHTTPContentRefs(httpSrv.Paths),
+		ContentCACertPEM: a.netModel.Host.EserverCACertPEM,
+	}, nil)
+	return intendedCfg
+}
+
+func (a *agent) getIntendedCaptivePortalEp(portal api.CaptivePortal) dg.Graph {
+	graphArgs := dg.InitArgs{Name: endpointSGPrefix + portal.LogicalLabel}
+	intendedCfg := dg.New(graphArgs)
+	a.putEpCommonConfig(intendedCfg, portal.Endpoint, nil)
+	nsName := a.endpointNsName(portal.LogicalLabel)
+	vethName, _, _ := a.endpointVethName(portal.LogicalLabel)
+	epIP := net.ParseIP(portal.IP)
+	var networks []configitems.CaptivePortalNetwork
+	for _, network := range a.netModel.Networks {
+		if network.CaptivePortal != portal.LogicalLabel {
+			continue
+		}
+		networks = append(networks, configitems.CaptivePortalNetwork{
+			LogicalLabel: network.LogicalLabel,
+			Subnet:       a.captivePortalSubnet(network, epIP),
+			NetNamespace: a.networkNsName(network.LogicalLabel),
+			IPSet:        captivePortalIPSet,
+		})
+	}
+	intendedCfg.PutItem(configitems.CaptivePortal{
+		PortalName:   portal.LogicalLabel,
 		NetNamespace: nsName,
 		VethName:     vethName,
 		ListenIP:     epIP,
-		HTTPPort:     httpSrv.HTTPPort,
-		HTTPSPort:    httpSrv.HTTPSPort,
-		CertPEM:      httpSrv.CertPEM,
-		KeyPEM:       httpSrv.KeyPEM,
-		Paths:        httpSrv.Paths,
+		Hostname:     portal.FQDN,
+		LoginPage:    portal.LoginPage,
+		Users:        portal.Users,
+		InterceptDNS: portal.InterceptDNS,
+		CertPEM:      portal.CertPEM,
+		KeyPEM:       portal.KeyPEM,
+		Networks:     networks,
 	}, nil)
 	return intendedCfg
 }
 
+// resolveHTTPContentRefs translates references to eserver files into URLs.
+// Eserver download credentials (if any) are included in the URL.
+func (a *agent) resolveHTTPContentRefs(paths map[string]api.HTTPContent) map[string]api.HTTPContent {
+	resolved := make(map[string]api.HTTPContent, len(paths))
+	for path, content := range paths {
+		if strings.HasPrefix(content.ContentFromURL, api.EserverFileRefPrefix) {
+			filename := strings.TrimPrefix(content.ContentFromURL, api.EserverFileRefPrefix)
+			content.ContentFromURL = eserverFileURL(a.netModel.Host, a.netModel.hostIP, filename)
+		}
+		resolved[path] = content
+	}
+	return resolved
+}
+
+// eserverFileURL returns URL to download the given file from eserver.
+func eserverFileURL(host *api.HostConfig, hostIP net.IP, filename string) string {
+	fileURL := url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(hostIP.String(), strconv.Itoa(int(host.EserverPort))),
+		Path:   "/eserver/" + strings.TrimPrefix(filename, "/"),
+	}
+	if host.EserverTLS {
+		fileURL.Scheme = "https"
+	}
+	if host.EserverUser != "" {
+		fileURL.User = url.UserPassword(host.EserverUser, host.EserverPassword)
+	}
+	return fileURL.String()
+}
+
 func (a *agent) putEpCommonConfig(graph dg.Graph, ep api.Endpoint, dnsClient *api.DNSClientConfig) {
 	vethName, inIfName, outIfName := a.endpointVethName(ep.LogicalLabel)
 	_, subnet, _ := net.ParseCIDR(ep.Subnet) // already validated
@@ -781,6 +1486,34 @@ func (a *agent) putEpCommonConfig(graph dg.Graph, ep api.Endpoint, dnsClient *ap
 	}, nil)
 }
 
+// getPortImpairment returns impairment configured for the port itself or for the bridge
+// to which the port is attached (directly or via bond). Returns nil if the port is not impaired.
+func (a *agent) getPortImpairment(port api.Port) *api.Impairment {
+	if port.Impairment != nil {
+		if port.Impairment.IsEmpty() {
+			return nil
+		}
+		return port.Impairment
+	}
+	labeledItem := a.netModel.items.getItem(api.Port{}.ItemType(), port.LogicalLabel)
+	masterID, hasMaster := labeledItem.referencedBy[api.PortMasterRef]
+	if !hasMaster {
+		return nil
+	}
+	if masterID.typename == (api.Bond{}).ItemType() {
+		bond := a.netModel.items[masterID]
+		masterID, hasMaster = bond.referencedBy[api.PortMasterRef]
+		if !hasMaster {
+			return nil
+		}
+	}
+	bridge := a.netModel.items[masterID].LabeledItem.(api.Bridge)
+	if bridge.Impairment == nil || bridge.Impairment.IsEmpty() {
+		return nil
+	}
+	return bridge.Impairment
+}
+
 func (a *agent) bondIfName(logicalLabel string) string {
 	return a.genIfName("bond-", logicalLabel)
 }
@@ -789,6 +1522,10 @@ func (a *agent) bridgeIfName(logicalLabel string) string {
 	return a.genIfName("br-", logicalLabel)
 }
 
+func (a *agent) portIfbName(logicalLabel string) string {
+	return a.genIfName("ifb-", logicalLabel)
+}
+
 func (a *agent) networkNsName(logicalLabel string) string {
 	return "network-" + logicalLabel
 }
@@ -805,6 +1542,10 @@ func (a *agent) networkBrVethName(logicalLabel string) (
 	return
 }
 
+func (a *agent) nat64GatewayName(logicalLabel string) string {
+	return a.genIfName("nat64-", logicalLabel)
+}
+
 func (a *agent) networkRtVethName(logicalLabel string) (
 	vethName, inIfName, outIfName string) {
 	vethName = "net-rt-" + logicalLabel
@@ -826,6 +1567,11 @@ func (a *agent) getNetwork(logicalLabel string) api.Network {
 	return item.LabeledItem.(api.Network)
 }
 
+func (a *agent) getCaptivePortal(logicalLabel string) api.CaptivePortal {
+	item := a.netModel.items.getItem(api.Endpoint{}.ItemType(), logicalLabel)
+	return item.LabeledItem.(api.CaptivePortal)
+}
+
 func (a *agent) getEndpoint(logicalLabel string) api.Endpoint {
 	item := a.netModel.items.getItem(api.Endpoint{}.ItemType(), logicalLabel)
 	return a.labeledItemToEndpoint(item)
@@ -848,6 +1594,8 @@ func (a *agent) labeledItemToEndpoint(item *labeledItem) api.Endpoint {
 		return item.LabeledItem.(api.TransparentProxy).Endpoint
 	case api.NetbootServer{}.ItemCategory():
 		return item.LabeledItem.(api.NetbootServer).Endpoint
+	case api.CaptivePortal{}.ItemCategory():
+		return item.LabeledItem.(api.CaptivePortal).Endpoint
 	default:
 		log.Fatalf("Unexpected endpoint category: %s", item.category)
 	}
@@ -9,11 +9,16 @@ import (
 )
 
 var intOne = big.NewInt(1)
-var internalIPv4Base *ipAsInt
+var internalIPv4Base, internalIPv6Base *ipAsInt
+var nat64IPv4Base, nat64IPv6Base *ipAsInt
 
 func init() {
 	// 240.0.0.0/4 is reserved
 	internalIPv4Base = ipToInt(net.ParseIP("240.0.0.0"))
+	nat64IPv4Base = ipToInt(net.ParseIP("241.0.0.0"))
+	// Unique local addresses (fd00::/8) with a randomly selected Global ID.
+	internalIPv6Base = ipToInt(net.ParseIP("fd3e:d3a1:5d4e::"))
+	nat64IPv6Base = ipToInt(net.ParseIP("fd3e:d3a1:5d4e:1::"))
 }
 
 type ipAsInt struct {
@@ -91,23 +96,38 @@ func (ip *ipAsInt) ToIP() net.IP {
 }
 
 func (a *agent) genVethIPsForNetwork(logicalLabel string, ipv6 bool) (ip1, ip2 *net.IPNet) {
-	if ipv6 {
-		// TODO
-		log.Fatal("IPv6 is not yet implemented")
-	}
 	index, hasIndex := a.networkIndex[logicalLabel]
 	if !hasIndex {
 		log.Fatalf("missing index for network %s", logicalLabel)
 	}
-	// Each network is allocated /30 subnet for internally used veths.
+	// Each network is allocated /30 (/126 for IPv6) subnet for internally used veths.
 	mask := net.CIDRMask(30, 32)
 	base := internalIPv4Base.Copy()
+	if ipv6 {
+		mask = net.CIDRMask(126, 128)
+		base = internalIPv6Base.Copy()
+	}
 	base.Inc(4 * index)
 	ip1 = &net.IPNet{IP: base.Inc(1).ToIP(), Mask: mask}
 	ip2 = &net.IPNet{IP: base.Inc(1).ToIP(), Mask: mask}
 	return
 }
 
+// genNAT64AddrsForNetwork returns IPv4 pool and IPv6 address for the NAT64 gateway
+// of the given network.
+func (a *agent) genNAT64AddrsForNetwork(logicalLabel string) (
+	ipv4Pool *net.IPNet, ipv6Addr net.IP) {
+	index, hasIndex := a.networkIndex[logicalLabel]
+	if !hasIndex {
+		log.Fatalf("missing index for network %s", logicalLabel)
+	}
+	// Each network is allocated /24 IPv4 subnet for the NAT64 pool.
+	poolBase := nat64IPv4Base.Copy().Inc(256 * index)
+	ipv4Pool = &net.IPNet{IP: poolBase.ToIP().To4(), Mask: net.CIDRMask(24, 32)}
+	ipv6Addr = nat64IPv6Base.Copy().Inc(index + 1).ToIP()
+	return
+}
+
 func (a *agent) genEndpointGwIP(subnet *net.IPNet, epIP net.IP) (gwIP *net.IPNet) {
 	epInt := ipToInt(epIP)
 	gwInt := ipToInt(subnet.IP).Inc(1)
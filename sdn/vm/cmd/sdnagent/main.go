@@ -44,8 +44,16 @@ func main() {
 
 	router.HandleFunc("/net-model.json", agent.getNetModel).Methods("GET")
 	router.HandleFunc("/net-model.json", agent.applyNetModel).Methods("PUT")
+	router.HandleFunc("/impairment/{itemType}/{logicalLabel}", agent.setImpairment).Methods("PUT")
 	router.HandleFunc("/net-config.gv", agent.getNetConfig).Methods("GET")
 	router.HandleFunc("/sdn-status.json", agent.getSDNStatus).Methods("GET")
+	router.HandleFunc("/capture/{itemType}/{logicalLabel}", agent.capture).Methods("GET")
+	router.HandleFunc("/ring-capture/{itemType}/{logicalLabel}", agent.startRingCapture).Methods("PUT")
+	router.HandleFunc("/ring-capture/{itemType}/{logicalLabel}", agent.getRingCapture).Methods("GET")
+	router.HandleFunc("/ring-capture/{itemType}/{logicalLabel}", agent.stopRingCapture).Methods("DELETE")
+	router.HandleFunc("/ring-captures.json", agent.listRingCaptures).Methods("GET")
+	router.HandleFunc("/captive-portal/{logicalLabel}/client/{ip}", agent.captivePortalLogin).Methods("PUT")
+	router.HandleFunc("/captive-portal/{logicalLabel}/client/{ip}", agent.captivePortalLogout).Methods("DELETE")
 	// TODO: metrics?
 
 	srv := &http.Server{
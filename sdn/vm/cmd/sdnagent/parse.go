@@ -7,11 +7,15 @@ import (
 	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/lf-edge/eden/sdn/vm/api"
@@ -20,6 +24,8 @@ import (
 
 const maxMTU = 9000
 
+var fwRateLimitRegexp = regexp.MustCompile(`^[1-9][0-9]*/(second|minute|hour|day)$`)
+
 type parsedNetModel struct {
 	api.NetworkModel
 	items  labeledItems
@@ -60,7 +66,8 @@ func (a *agent) parseNetModel(netModel api.NetworkModel) (parsedModel parsedNetM
 	eps := netModel.Endpoints
 	items := a.slicesToLabeledItems(netModel.Ports, netModel.Bonds, netModel.Bridges,
 		netModel.Networks, eps.DNSServers, eps.NTPServers, eps.NetbootServers,
-		eps.HTTPServers, eps.ExplicitProxies, eps.TransparentProxies, eps.Clients)
+		eps.HTTPServers, eps.ExplicitProxies, eps.TransparentProxies, eps.CaptivePortals,
+		eps.Clients)
 	parsedModel.items, err = a.parseLabeledItems(items)
 	if err != nil {
 		return
@@ -81,6 +88,9 @@ func (a *agent) parseNetModel(netModel api.NetworkModel) (parsedModel parsedNetM
 	if err = a.validateFirewall(&parsedModel); err != nil {
 		return
 	}
+	if err = a.validateImpairments(&parsedModel); err != nil {
+		return
+	}
 	return
 }
 
@@ -142,10 +152,11 @@ func (a *agent) validateNetworks(netModel *parsedNetModel) (err error) {
 	for _, network := range netModel.Networks {
 		_, subnet, _ := net.ParseCIDR(network.Subnet)
 		dhcp := network.DHCP
-		if !dhcp.Enable {
+		if !dhcp.Enable && network.IPv6 == nil {
+			// DHCP config is also used for DHCPv6 and RA.
 			continue
 		}
-		if dhcp.IPRange.FromIP != "" {
+		if dhcp.Enable && dhcp.IPRange.FromIP != "" {
 			fromIP := net.ParseIP(dhcp.IPRange.FromIP)
 			if fromIP == nil {
 				err = fmt.Errorf("network %s has invalid DHCP range FromIP (%s)",
@@ -206,7 +217,108 @@ func (a *agent) validateNetworks(netModel *parsedNetModel) (err error) {
 		}
 	}
 
-	// TODO: check that within a network it is IPv4 or IPv6, not both (for now)
+	// Validate IPv6 config.
+	for _, network := range netModel.Networks {
+		if err = a.validateNetworkIPv6(network); err != nil {
+			err = fmt.Errorf("network %s has invalid IPv6 config: %w",
+				network.LogicalLabel, err)
+			return
+		}
+	}
+	return nil
+}
+
+func (a *agent) validateNetworkIPv6(network api.Network) error {
+	_, subnet, _ := net.ParseCIDR(network.Subnet)
+	ipv6Cfg := network.IPv6
+	if ipv6Cfg == nil {
+		return nil
+	}
+	if len(subnet.IP) == net.IPv6len {
+		if ipv6Cfg.Subnet != "" || ipv6Cfg.GwIP != "" {
+			return errors.New("IPv6 subnet and gateway are already defined by the network " +
+				"(use ipv6.subnet and ipv6.gwIP only for dual-stack network)")
+		}
+	} else {
+		var err error
+		if _, subnet, err = net.ParseCIDR(ipv6Cfg.Subnet); err != nil {
+			return fmt.Errorf("invalid subnet: %w", err)
+		}
+		if len(subnet.IP) != net.IPv6len {
+			return fmt.Errorf("subnet %s is not IPv6", ipv6Cfg.Subnet)
+		}
+		gwIP := net.ParseIP(ipv6Cfg.GwIP)
+		if gwIP == nil || !subnet.Contains(gwIP) {
+			return fmt.Errorf("invalid gateway IP (%s)", ipv6Cfg.GwIP)
+		}
+	}
+	prefixLen, _ := subnet.Mask.Size()
+	mode := ipv6Cfg.AddrMode
+	if (mode == api.IPv6SLAAC || mode == api.IPv6DHCPv6Stateless) && prefixLen != 64 {
+		return fmt.Errorf("SLAAC requires /64 subnet, have /%d", prefixLen)
+	}
+	if ipv6Cfg.DHCPv6Range.FromIP != "" || ipv6Cfg.DHCPv6Range.ToIP != "" {
+		if mode != api.IPv6DHCPv6Stateful {
+			return errors.New("DHCPv6 range is only used with stateful DHCPv6")
+		}
+		fromIP := net.ParseIP(ipv6Cfg.DHCPv6Range.FromIP)
+		toIP := net.ParseIP(ipv6Cfg.DHCPv6Range.ToIP)
+		if fromIP == nil || toIP == nil {
+			return fmt.Errorf("invalid DHCPv6 range (%s - %s)",
+				ipv6Cfg.DHCPv6Range.FromIP, ipv6Cfg.DHCPv6Range.ToIP)
+		}
+		if !subnet.Contains(fromIP) || !subnet.Contains(toIP) {
+			return errors.New("DHCPv6 range outside of the subnet")
+		}
+		if bytes.Compare(fromIP, toIP) > 0 {
+			return errors.New("DHCPv6 range where FromIP > ToIP")
+		}
+	} else if mode == api.IPv6DHCPv6Stateful && prefixLen > 119 {
+		return errors.New("subnet is too small for the default DHCPv6 range")
+	}
+	ra := ipv6Cfg.RA
+	if ra.Interval != 0 && (ra.Interval < 4 || ra.Interval > 1800) {
+		return fmt.Errorf("RA interval %d is not within 4-1800 seconds", ra.Interval)
+	}
+	if ra.RouterLifetime != 0 {
+		interval := ra.Interval
+		if interval == 0 {
+			interval = 600 // radvd default
+		}
+		if ra.RouterLifetime < interval || ra.RouterLifetime > 9000 {
+			return fmt.Errorf("RA router lifetime %d is not within %d-9000 seconds",
+				ra.RouterLifetime, interval)
+		}
+	}
+	if ra.ValidLifetime != 0 && ra.PreferredLifetime > ra.ValidLifetime {
+		return errors.New("RA prefix preferred lifetime exceeds the valid lifetime")
+	}
+	if ra.MTU != 0 && ra.MTU < 1280 {
+		return fmt.Errorf("RA MTU %d is below the IPv6 minimum (1280)", ra.MTU)
+	}
+	if pd := ipv6Cfg.PrefixDelegation; pd != nil {
+		_, pdPrefix, err := net.ParseCIDR(pd.Prefix)
+		if err != nil || len(pdPrefix.IP) != net.IPv6len {
+			return fmt.Errorf("invalid prefix delegation pool (%s)", pd.Prefix)
+		}
+		if pdPrefix.Contains(subnet.IP) || subnet.Contains(pdPrefix.IP) {
+			return errors.New("prefix delegation pool overlaps with the subnet")
+		}
+		poolLen, _ := pdPrefix.Mask.Size()
+		if int(pd.DelegatedLength) <= poolLen || pd.DelegatedLength > 64 {
+			return fmt.Errorf("delegated prefix length %d is not within %d-64",
+				pd.DelegatedLength, poolLen+1)
+		}
+	}
+	if nat64 := ipv6Cfg.NAT64; nat64 != nil && nat64.Prefix != "" {
+		_, nat64Prefix, err := net.ParseCIDR(nat64.Prefix)
+		if err != nil || len(nat64Prefix.IP) != net.IPv6len {
+			return fmt.Errorf("invalid NAT64 prefix (%s)", nat64.Prefix)
+		}
+		if ones, _ := nat64Prefix.Mask.Size(); ones != 96 {
+			return fmt.Errorf("NAT64 prefix %s is not /96", nat64.Prefix)
+		}
+	}
 	return nil
 }
 
@@ -248,6 +360,13 @@ func (a *agent) validateEndpoints(netModel *parsedNetModel) (err error) {
 			}
 
 		}
+		for i, rule := range dnsSrv.FaultRules {
+			if err = validateDNSFaultRule(rule); err != nil {
+				err = fmt.Errorf("DNS server %s has invalid fault rule %d: %w",
+					dnsSrv.LogicalLabel, i, err)
+				return
+			}
+		}
 	}
 	for _, proxy := range netModel.Endpoints.ExplicitProxies {
 		if err = a.validateEndpoint(proxy.Endpoint); err != nil {
@@ -343,6 +462,13 @@ func (a *agent) validateEndpoints(netModel *parsedNetModel) (err error) {
 				httpSrv.LogicalLabel)
 			return
 		}
+		for path, content := range httpSrv.Paths {
+			if err = validateHTTPContent(netModel.Host, path, content); err != nil {
+				err = fmt.Errorf("HTTP server %s has invalid content for path %s: %w",
+					httpSrv.LogicalLabel, path, err)
+				return
+			}
+		}
 	}
 	for _, netbootSrv := range netModel.Endpoints.NetbootServers {
 		if err = a.validateEndpoint(netbootSrv.Endpoint); err != nil {
@@ -354,6 +480,46 @@ func (a *agent) validateEndpoints(netModel *parsedNetModel) (err error) {
 			return
 		}
 	}
+	for _, portal := range netModel.Endpoints.CaptivePortals {
+		if err = a.validateCaptivePortal(netModel, portal); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+func (a *agent) validateCaptivePortal(netModel *parsedNetModel, portal api.CaptivePortal) (err error) {
+	if err = a.validateEndpoint(portal.Endpoint); err != nil {
+		return
+	}
+	for _, user := range portal.Users {
+		if user.Username == "" {
+			return fmt.Errorf("captive portal %s with empty username", portal.LogicalLabel)
+		}
+	}
+	if portal.CertPEM != "" {
+		if err = a.validateCertPEM(portal.CertPEM, portal.KeyPEM, false); err != nil {
+			return
+		}
+	} else if portal.KeyPEM != "" {
+		return fmt.Errorf("captive portal %s with key but without certificate",
+			portal.LogicalLabel)
+	}
+	// Clients are matched by IP addresses of the same IP version as the portal IP.
+	portalIP := net.ParseIP(portal.IP)
+	for _, network := range netModel.Networks {
+		if network.CaptivePortal != portal.LogicalLabel {
+			continue
+		}
+		if a.captivePortalSubnet(network, portalIP) == nil {
+			ipVersion := "IPv4"
+			if portalIP.To4() == nil {
+				ipVersion = "IPv6"
+			}
+			return fmt.Errorf("network %s has no %s subnet to use with captive portal %s",
+				network.LogicalLabel, ipVersion, portal.LogicalLabel)
+		}
+	}
 	return nil
 }
 
@@ -393,28 +559,109 @@ func (a *agent) validateEndpoint(endpoint api.Endpoint) (err error) {
 }
 
 func (a *agent) validateFirewall(netModel *parsedNetModel) (err error) {
-	for _, rule := range netModel.Firewall.Rules {
+	ruleNames := make(map[string]struct{})
+	for i, rule := range netModel.Firewall.Rules {
+		name := fwRuleName(i, rule)
+		if _, duplicate := ruleNames[name]; duplicate {
+			err = fmt.Errorf("duplicate firewall rule name '%s'", name)
+			return
+		}
+		ruleNames[name] = struct{}{}
 		if rule.SrcSubnet != "" {
 			if _, _, err = net.ParseCIDR(rule.SrcSubnet); err != nil {
-				err = fmt.Errorf("firewall rule with invalid subnet '%s': %w",
-					rule.SrcSubnet, err)
+				err = fmt.Errorf("firewall rule %s with invalid subnet '%s': %w",
+					name, rule.SrcSubnet, err)
 				return
 			}
 		}
 		if rule.DstSubnet != "" {
 			if _, _, err = net.ParseCIDR(rule.DstSubnet); err != nil {
-				err = fmt.Errorf("firewall rule with invalid subnet '%s': %w",
-					rule.DstSubnet, err)
+				err = fmt.Errorf("firewall rule %s with invalid subnet '%s': %w",
+					name, rule.DstSubnet, err)
+				return
+			}
+		}
+		if rule.SrcSubnet != "" && rule.DstSubnet != "" &&
+			isIPv6Subnet(rule.SrcSubnet) != isIPv6Subnet(rule.DstSubnet) {
+			err = fmt.Errorf("firewall rule %s combines IPv4 and IPv6 subnets", name)
+			return
+		}
+		endpoints := append(append([]string{}, rule.SrcEndpoints...), rule.DstEndpoints...)
+		for _, epLL := range endpoints {
+			if netModel.items.getItem(api.Endpoint{}.ItemType(), epLL) == nil {
+				err = fmt.Errorf("firewall rule %s references unknown endpoint %s",
+					name, epLL)
+				return
+			}
+		}
+		for _, fqdn := range rule.DstFQDNs {
+			if fqdn == "" {
+				err = fmt.Errorf("firewall rule %s with empty FQDN", name)
 				return
 			}
 		}
 		if len(rule.Ports) > 0 {
 			if rule.Protocol != api.TCP && rule.Protocol != api.UDP {
-				err = fmt.Errorf("firewall rule with non-empty set of ports (%v) "+
-					" but protocol is neither TCP nor UDP (%v)", rule.Ports, rule.Protocol)
+				err = fmt.Errorf("firewall rule %s with non-empty set of ports (%v) "+
+					" but protocol is neither TCP nor UDP (%v)", name, rule.Ports, rule.Protocol)
 				return
 			}
 		}
+		if rule.RateLimit != nil && !fwRateLimitRegexp.MatchString(rule.RateLimit.Rate) {
+			err = fmt.Errorf("firewall rule %s with invalid rate limit '%s' "+
+				"(expected <number>/<second|minute|hour|day>)", name, rule.RateLimit.Rate)
+			return
+		}
+	}
+	return nil
+}
+
+func (a *agent) validateImpairments(netModel *parsedNetModel) (err error) {
+	for _, port := range netModel.Ports {
+		if err = a.validateImpairment(port.Impairment); err != nil {
+			err = fmt.Errorf("port %s has invalid impairment: %w", port.LogicalLabel, err)
+			return
+		}
+	}
+	for _, bridge := range netModel.Bridges {
+		if err = a.validateImpairment(bridge.Impairment); err != nil {
+			err = fmt.Errorf("bridge %s has invalid impairment: %w", bridge.LogicalLabel, err)
+			return
+		}
+	}
+	for _, network := range netModel.Networks {
+		if err = a.validateImpairment(network.Impairment); err != nil {
+			err = fmt.Errorf("network %s has invalid impairment: %w", network.LogicalLabel, err)
+			return
+		}
+	}
+	return nil
+}
+
+func (a *agent) validateImpairment(impairment *api.Impairment) error {
+	if impairment == nil {
+		return nil
+	}
+	percentages := []struct {
+		name  string
+		value float32
+	}{
+		{"loss", impairment.Loss},
+		{"duplicate", impairment.Duplicate},
+		{"reorder", impairment.Reorder},
+	}
+	for _, p := range percentages {
+		if p.value < 0 || p.value > 100 {
+			return fmt.Errorf("%s percentage %v is out of range <0, 100>", p.name, p.value)
+		}
+	}
+	if impairment.Delay == 0 {
+		if impairment.Jitter != 0 {
+			return errors.New("jitter requires non-zero delay")
+		}
+		if impairment.Reorder != 0 {
+			return errors.New("reorder requires non-zero delay")
+		}
 	}
 	return nil
 }
@@ -446,6 +693,11 @@ func (a *agent) validateHostConfig(netModel *parsedNetModel) (err error) {
 		err = errors.New("missing controller port")
 		return
 	}
+	if netModel.Host.EserverCACertPEM != "" &&
+		!x509.NewCertPool().AppendCertsFromPEM([]byte(netModel.Host.EserverCACertPEM)) {
+		err = errors.New("failed to parse eserver CA certificate")
+		return
+	}
 	return nil
 }
 
@@ -597,3 +849,71 @@ func (a *agent) slicesToLabeledItems(slices ...interface{}) (items []api.Labeled
 	}
 	return items
 }
+
+func validateDNSFaultRule(rule api.DNSFaultRule) error {
+	if rule.FQDN == "" || rule.FQDN == "*." {
+		return errors.New("empty FQDN")
+	}
+	if rule.QueryType != "" {
+		if _, supported := api.DNSFaultQueryTypes[strings.ToUpper(rule.QueryType)]; !supported {
+			var queryTypes []string
+			for queryType := range api.DNSFaultQueryTypes {
+				queryTypes = append(queryTypes, queryType)
+			}
+			sort.Strings(queryTypes)
+			return fmt.Errorf("unsupported query type %s (supported: %s)",
+				rule.QueryType, strings.Join(queryTypes, ", "))
+		}
+	}
+	if rule.Probability < 0 || rule.Probability > 100 {
+		return fmt.Errorf("probability %f is not a valid percentage", rule.Probability)
+	}
+	if len(rule.RotatingIPs) > 0 && rule.Action != api.DNSRespond {
+		return fmt.Errorf("rotating IPs cannot be used with action %s",
+			api.DNSFaultActionToString[rule.Action])
+	}
+	for _, ip := range rule.RotatingIPs {
+		if strings.HasPrefix(ip, api.EndpointIPRefPrefix) || ip == api.AdamIPRef {
+			// Do not try to parse IP, it is a symbolic reference.
+			continue
+		}
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid rotating IP (%s)", ip)
+		}
+	}
+	return nil
+}
+
+func validateHTTPContent(host *api.HostConfig, path string, content api.HTTPContent) error {
+	if !strings.HasPrefix(path, "/") {
+		return errors.New("path should start with '/'")
+	}
+	if content.ContentBase64 != "" {
+		if _, err := base64.StdEncoding.DecodeString(content.ContentBase64); err != nil {
+			return fmt.Errorf("invalid base64 content: %w", err)
+		}
+	}
+	if content.RedirectTo != "" && content.ContentFromURL != "" {
+		return errors.New("content from URL cannot be combined with redirect")
+	}
+	if content.StatusCode != 0 && (content.StatusCode < 100 || content.StatusCode > 599) {
+		return fmt.Errorf("invalid status code %d", content.StatusCode)
+	}
+	if strings.HasPrefix(content.ContentFromURL, api.EserverFileRefPrefix) {
+		if host == nil || host.EserverPort == 0 {
+			return errors.New("eserver file referenced but eserver port is not known")
+		}
+		if strings.TrimPrefix(content.ContentFromURL, api.EserverFileRefPrefix) == "" {
+			return errors.New("empty eserver file name")
+		}
+	} else if content.ContentFromURL != "" {
+		contentURL, err := url.Parse(content.ContentFromURL)
+		if err != nil {
+			return fmt.Errorf("invalid content URL: %w", err)
+		}
+		if contentURL.Scheme != "http" && contentURL.Scheme != "https" {
+			return fmt.Errorf("unsupported content URL scheme: %s", contentURL.Scheme)
+		}
+	}
+	return nil
+}
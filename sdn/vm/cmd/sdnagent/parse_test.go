@@ -0,0 +1,165 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lf-edge/eden/sdn/vm/api"
+)
+
+func TestValidateImpairment(t *testing.T) {
+	tests := []struct {
+		name       string
+		impairment *api.Impairment
+		wantErr    bool
+	}{
+		{name: "nil"},
+		{name: "empty", impairment: &api.Impairment{}},
+		{name: "all", impairment: &api.Impairment{Delay: 100, Jitter: 10, Loss: 1.5, Duplicate: 100, Reorder: 25, Rate: 512}},
+		{name: "rate only", impairment: &api.Impairment{Rate: 512}},
+		{name: "loss over 100", impairment: &api.Impairment{Loss: 100.5}, wantErr: true},
+		{name: "negative duplicate", impairment: &api.Impairment{Duplicate: -1}, wantErr: true},
+		{name: "reorder over 100", impairment: &api.Impairment{Delay: 10, Reorder: 101}, wantErr: true},
+		{name: "jitter without delay", impairment: &api.Impairment{Jitter: 10}, wantErr: true},
+		{name: "reorder without delay", impairment: &api.Impairment{Reorder: 10}, wantErr: true},
+	}
+	a := &agent{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.validateImpairment(tt.impairment)
+			if tt.wantErr && err == nil {
+				t.Error("expected error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateImpairments(t *testing.T) {
+	netModel := &parsedNetModel{}
+	netModel.Networks = []api.Network{
+		{LogicalLabel: "net0", Impairment: &api.Impairment{Delay: 50}},
+		{LogicalLabel: "net1", Impairment: &api.Impairment{Jitter: 50}},
+	}
+	err := (&agent{}).validateImpairments(netModel)
+	if err == nil || err.Error() != "network net1 has invalid impairment: jitter requires non-zero delay" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateNetworkIPv6(t *testing.T) {
+	dualStack := func(ipv6 api.NetworkIPv6) api.Network {
+		if ipv6.Subnet == "" {
+			ipv6.Subnet, ipv6.GwIP = "fd00::/64", "fd00::1"
+		}
+		return api.Network{Subnet: "172.22.12.0/24", GwIP: "172.22.12.1", IPv6: &ipv6}
+	}
+	ipv6Only := func(subnet string, ipv6 api.NetworkIPv6) api.Network {
+		return api.Network{Subnet: subnet, GwIP: "fd00::1", IPv6: &ipv6}
+	}
+	tests := []struct {
+		name    string
+		network api.Network
+		wantErr bool
+	}{
+		{name: "no IPv6 config", network: api.Network{Subnet: "fd00::/64", GwIP: "fd00::1"}},
+		{name: "dual-stack SLAAC", network: dualStack(api.NetworkIPv6{})},
+		{name: "dual-stack without IPv6 subnet", network: api.Network{Subnet: "172.22.12.0/24", GwIP: "172.22.12.1",
+			IPv6: &api.NetworkIPv6{}}, wantErr: true},
+		{name: "dual-stack IPv4 as IPv6 subnet", network: dualStack(api.NetworkIPv6{Subnet: "10.0.0.0/24", GwIP: "10.0.0.1"}),
+			wantErr: true},
+		{name: "gateway outside of subnet", network: dualStack(api.NetworkIPv6{Subnet: "fd00::/64", GwIP: "fd01::1"}),
+			wantErr: true},
+		{name: "IPv6-only with IPv6 subnet", network: ipv6Only("fd00::/64", api.NetworkIPv6{Subnet: "fd01::/64"}),
+			wantErr: true},
+		{name: "SLAAC requires /64", network: ipv6Only("fd00::/80", api.NetworkIPv6{}), wantErr: true},
+		{name: "stateful /80", network: ipv6Only("fd00::/80", api.NetworkIPv6{AddrMode: api.IPv6DHCPv6Stateful})},
+		{name: "stateful too small", network: ipv6Only("fd00::/120", api.NetworkIPv6{AddrMode: api.IPv6DHCPv6Stateful}),
+			wantErr: true},
+		{name: "stateful range", network: dualStack(api.NetworkIPv6{AddrMode: api.IPv6DHCPv6Stateful,
+			DHCPv6Range: api.IPRange{FromIP: "fd00::10", ToIP: "fd00::20"}})},
+		{name: "range with SLAAC", network: dualStack(api.NetworkIPv6{
+			DHCPv6Range: api.IPRange{FromIP: "fd00::10", ToIP: "fd00::20"}}), wantErr: true},
+		{name: "range outside of subnet", network: dualStack(api.NetworkIPv6{AddrMode: api.IPv6DHCPv6Stateful,
+			DHCPv6Range: api.IPRange{FromIP: "fd00::10", ToIP: "fd01::20"}}), wantErr: true},
+		{name: "reversed range", network: dualStack(api.NetworkIPv6{AddrMode: api.IPv6DHCPv6Stateful,
+			DHCPv6Range: api.IPRange{FromIP: "fd00::20", ToIP: "fd00::10"}}), wantErr: true},
+		{name: "RA", network: dualStack(api.NetworkIPv6{RA: api.RouterAdvert{Interval: 60, RouterLifetime: 180,
+			ValidLifetime: 600, PreferredLifetime: 300, MTU: 1400}})},
+		{name: "RA interval", network: dualStack(api.NetworkIPv6{RA: api.RouterAdvert{Interval: 3}}), wantErr: true},
+		{name: "RA router lifetime below default interval", network: dualStack(api.NetworkIPv6{
+			RA: api.RouterAdvert{RouterLifetime: 300}}), wantErr: true},
+		{name: "RA preferred lifetime", network: dualStack(api.NetworkIPv6{
+			RA: api.RouterAdvert{ValidLifetime: 300, PreferredLifetime: 600}}), wantErr: true},
+		{name: "RA MTU", network: dualStack(api.NetworkIPv6{RA: api.RouterAdvert{MTU: 1000}}), wantErr: true},
+		{name: "prefix delegation", network: dualStack(api.NetworkIPv6{
+			PrefixDelegation: &api.PrefixDelegation{Prefix: "fd01::/48", DelegatedLength: 56}})},
+		{name: "prefix delegation overlap", network: dualStack(api.NetworkIPv6{
+			PrefixDelegation: &api.PrefixDelegation{Prefix: "fd00::/48", DelegatedLength: 56}}), wantErr: true},
+		{name: "delegated length", network: dualStack(api.NetworkIPv6{
+			PrefixDelegation: &api.PrefixDelegation{Prefix: "fd01::/48", DelegatedLength: 48}}), wantErr: true},
+		{name: "delegated length over 64", network: dualStack(api.NetworkIPv6{
+			PrefixDelegation: &api.PrefixDelegation{Prefix: "fd01::/48", DelegatedLength: 65}}), wantErr: true},
+		{name: "NAT64", network: dualStack(api.NetworkIPv6{NAT64: &api.NAT64{Prefix: "64:ff9b::/96"}})},
+		{name: "NAT64 prefix length", network: dualStack(api.NetworkIPv6{NAT64: &api.NAT64{Prefix: "64:ff9b::/64"}}),
+			wantErr: true},
+	}
+	a := &agent{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.validateNetworkIPv6(tt.network)
+			if tt.wantErr && err == nil {
+				t.Error("expected error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateHTTPContent(t *testing.T) {
+	host := &api.HostConfig{EserverPort: 8888}
+	tests := []struct {
+		name    string
+		host    *api.HostConfig
+		path    string
+		content api.HTTPContent
+		wantErr bool
+	}{
+		{name: "plain content", path: "/index.html", content: api.HTTPContent{Content: "hello"}},
+		{name: "path without slash", path: "index.html", content: api.HTTPContent{Content: "hello"},
+			wantErr: true},
+		{name: "base64 content", path: "/bin", content: api.HTTPContent{ContentBase64: "AAEC"}},
+		{name: "invalid base64", path: "/bin", content: api.HTTPContent{ContentBase64: "not base64!"},
+			wantErr: true},
+		{name: "redirect", path: "/old", content: api.HTTPContent{RedirectTo: "http://new.sdn/",
+			StatusCode: 301}},
+		{name: "redirect with URL", path: "/old", content: api.HTTPContent{RedirectTo: "http://new.sdn/",
+			ContentFromURL: "http://other.sdn/file"}, wantErr: true},
+		{name: "invalid status code", path: "/", content: api.HTTPContent{StatusCode: 600}, wantErr: true},
+		{name: "content from URL", path: "/file", content: api.HTTPContent{
+			ContentFromURL: "https://example.com/file"}},
+		{name: "unsupported URL scheme", path: "/file", content: api.HTTPContent{
+			ContentFromURL: "ftp://example.com/file"}, wantErr: true},
+		{name: "eserver file", host: host, path: "/file", content: api.HTTPContent{
+			ContentFromURL: api.EserverFileRefPrefix + "rootfs.img"}},
+		{name: "eserver file with unknown port", host: &api.HostConfig{}, path: "/file",
+			content: api.HTTPContent{ContentFromURL: api.EserverFileRefPrefix + "rootfs.img"},
+			wantErr: true},
+		{name: "empty eserver file name", host: host, path: "/file", content: api.HTTPContent{
+			ContentFromURL: api.EserverFileRefPrefix}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHTTPContent(tt.host, tt.path, tt.content)
+			if tt.wantErr && err == nil {
+				t.Error("expected error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package configitems
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	portalcfg "github.com/lf-edge/eden/sdn/vm/cmd/captiveportal/config"
+	"github.com/lf-edge/eve/libs/depgraph"
+	"github.com/lf-edge/eve/libs/reconciler"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	captivePortalBinary  = "/bin/captiveportal"
+	captivePortalConfDir = "/etc/captiveportal"
+	captivePortalRunDir  = "/run/captiveportal"
+
+	captivePortalStartTimeout = 3 * time.Second
+	captivePortalStopTimeout  = 10 * time.Second
+)
+
+// CaptivePortal : captive portal (see sdn/cmd/captiveportal).
+type CaptivePortal struct {
+	// PortalName : logical name for the captive portal.
+	PortalName string
+	// NetNamespace : network namespace where the portal should be running.
+	NetNamespace string
+	// VethName : logical name of the veth pair on which the portal operates.
+	// (other types of interfaces are currently not supported)
+	VethName string
+	// ListenIP : IP address on which the portal should listen.
+	ListenIP net.IP
+	// Hostname : FQDN of the portal.
+	Hostname string
+	// LoginPage : HTML page served to clients which have not logged in yet.
+	// Leave empty to use the default login page.
+	LoginPage string
+	// Users : define for username/password authentication, leave empty
+	// to accept any login.
+	Users []sdnapi.UserCredentials
+	// InterceptDNS : answer every DNS query with ListenIP.
+	InterceptDNS bool
+	// CertPEM : certificate in the PEM format used for HTTPS.
+	// Leave empty to generate a self-signed certificate.
+	CertPEM string
+	// KeyPEM : key in the PEM format used for HTTPS.
+	KeyPEM string
+	// Networks : networks with the captive portal attached.
+	Networks []CaptivePortalNetwork
+}
+
+// CaptivePortalNetwork : network with a captive portal attached.
+type CaptivePortalNetwork struct {
+	// LogicalLabel : logical label of the network.
+	LogicalLabel string
+	// Subnet : network subnet with clients of the portal.
+	Subnet *net.IPNet
+	// NetNamespace : network namespace of the network.
+	NetNamespace string
+	// IPSet : name of the IP set (inside NetNamespace) with logged-in clients.
+	IPSet string
+}
+
+// Name
+func (p CaptivePortal) Name() string {
+	return p.PortalName
+}
+
+// Label
+func (p CaptivePortal) Label() string {
+	return p.PortalName + " (captive portal)"
+}
+
+// Type
+func (p CaptivePortal) Type() string {
+	return CaptivePortalTypename
+}
+
+// Equal is a comparison method for two equally-named CaptivePortal instances.
+func (p CaptivePortal) Equal(other depgraph.Item) bool {
+	p2 := other.(CaptivePortal)
+	if len(p.Networks) != len(p2.Networks) {
+		return false
+	}
+	for i := range p.Networks {
+		net1, net2 := p.Networks[i], p2.Networks[i]
+		if net1.LogicalLabel != net2.LogicalLabel ||
+			!equalIPNets(net1.Subnet, net2.Subnet) ||
+			net1.NetNamespace != net2.NetNamespace ||
+			net1.IPSet != net2.IPSet {
+			return false
+		}
+	}
+	return p.NetNamespace == p2.NetNamespace &&
+		p.VethName == p2.VethName &&
+		p.ListenIP.Equal(p2.ListenIP) &&
+		p.Hostname == p2.Hostname &&
+		p.LoginPage == p2.LoginPage &&
+		reflect.DeepEqual(p.Users, p2.Users) &&
+		p.InterceptDNS == p2.InterceptDNS &&
+		p.CertPEM == p2.CertPEM &&
+		p.KeyPEM == p2.KeyPEM
+}
+
+// External returns false.
+func (p CaptivePortal) External() bool {
+	return false
+}
+
+// String describes the captive portal.
+func (p CaptivePortal) String() string {
+	return fmt.Sprintf("Captive portal: %#+v", p)
+}
+
+// Dependencies lists the veth and network namespace as dependencies.
+// IP sets of networks are not dependencies - the portal only adds clients
+// into them on login, which fails gracefully if a set does not exist.
+func (p CaptivePortal) Dependencies() (deps []depgraph.Dependency) {
+	return []depgraph.Dependency{
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: NetNamespaceTypename,
+				ItemName: normNetNsName(p.NetNamespace),
+			},
+			Description: "Network namespace must exist",
+		},
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: VethTypename,
+				ItemName: p.VethName,
+			},
+			Description: "veth interface must exist",
+		},
+	}
+}
+
+// CaptivePortalConfigurator implements Configurator interface for CaptivePortal.
+type CaptivePortalConfigurator struct{}
+
+// Create starts captiveportal.
+func (c *CaptivePortalConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	config := item.(CaptivePortal)
+	if err := c.createCaptivePortalConfFile(config); err != nil {
+		return err
+	}
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := startCaptivePortal(config.PortalName, config.NetNamespace)
+		done(err)
+	}()
+	return nil
+}
+
+func (c *CaptivePortalConfigurator) createCaptivePortalConfFile(portal CaptivePortal) error {
+	if err := ensureDir(captivePortalConfDir); err != nil {
+		return err
+	}
+	portalName := portal.PortalName
+	config := portalcfg.CaptivePortalConfig{
+		ListenIP:     portal.ListenIP.String(),
+		Hostname:     portal.Hostname,
+		LogFile:      captivePortalLogFile(portalName),
+		PidFile:      captivePortalPidFile(portalName),
+		Verbose:      true,
+		LoginPage:    portal.LoginPage,
+		Users:        portal.Users,
+		InterceptDNS: portal.InterceptDNS,
+		CertPEM:      portal.CertPEM,
+		KeyPEM:       portal.KeyPEM,
+	}
+	for _, network := range portal.Networks {
+		config.Networks = append(config.Networks, portalcfg.Network{
+			LogicalLabel: network.LogicalLabel,
+			Subnet:       network.Subnet.String(),
+			NetNamespace: network.NetNamespace,
+			IPSet:        network.IPSet,
+		})
+	}
+	configBytes, err := json.MarshalIndent(config, "", " ")
+	if err != nil {
+		err = fmt.Errorf("failed to marshal config to JSON: %w", err)
+		log.Error(err)
+		return err
+	}
+	// Write configuration to file.
+	cfgPath := captivePortalConfigPath(portalName)
+	err = os.WriteFile(cfgPath, configBytes, 0644)
+	if err != nil {
+		err = fmt.Errorf("failed to create config file %s: %w", cfgPath, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *CaptivePortalConfigurator) Modify(ctx context.Context, oldItem, newItem depgraph.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete stops captiveportal.
+func (c *CaptivePortalConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	config := item.(CaptivePortal)
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		portalName := config.PortalName
+		err := stopProcess(captivePortalPidFile(portalName), captivePortalStopTimeout)
+		if err == nil {
+			// ignore errors from here
+			_ = removeCaptivePortalFile(captivePortalConfigPath(portalName))
+			_ = removeCaptivePortalFile(captivePortalLogFile(portalName))
+			_ = removeCaptivePortalFile(captivePortalPidFile(portalName))
+		}
+		done(err)
+	}()
+	return nil
+}
+
+// NeedsRecreate always returns true - Modify is not implemented.
+func (c *CaptivePortalConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	return true
+}
+
+func captivePortalConfigPath(portalName string) string {
+	return filepath.Join(captivePortalConfDir, portalName+".conf")
+}
+
+func captivePortalPidFile(portalName string) string {
+	return filepath.Join(captivePortalRunDir, portalName+".pid")
+}
+
+func captivePortalLogFile(portalName string) string {
+	return filepath.Join(captivePortalRunDir, portalName+".log")
+}
+
+func removeCaptivePortalFile(path string) error {
+	if err := os.Remove(path); err != nil {
+		err = fmt.Errorf("failed to remove captive portal file %s: %w", path, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func startCaptivePortal(portalName, netNamespace string) error {
+	if err := ensureDir(captivePortalRunDir); err != nil {
+		return err
+	}
+	args := []string{
+		"-c",
+		captivePortalConfigPath(portalName),
+	}
+	pidFile := captivePortalPidFile(portalName)
+	return startProcess(netNamespace, captivePortalBinary, args, pidFile,
+		captivePortalStartTimeout, true)
+}
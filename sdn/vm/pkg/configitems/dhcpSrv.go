@@ -1,9 +1,11 @@
 package configitems
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -12,6 +14,7 @@ import (
 	"syscall"
 	"time"
 
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
 	"github.com/lf-edge/eve/libs/depgraph"
 	"github.com/lf-edge/eve/libs/reconciler"
 	log "github.com/sirupsen/logrus"
@@ -39,10 +42,10 @@ type DhcpServer struct {
 	// VethPeerIfName : interface name of that side of the veth pair on which
 	// the server should listen. It should be inside NetNamespace.
 	VethPeerIfName string
-	// Subnet : network address + netmask (IPv4 or IPv6).
+	// Subnet : IPv4 network address + netmask.
+	// IPv6 is served by Dhcpv6Server and RouterAdvertiser.
 	Subnet *net.IPNet
 	// IPRange : a range of IP addresses to allocate from.
-	// Not applicable for IPv6 (SLAAC is used instead).
 	IPRange IPRange
 	// GatewayIP : address of the default gateway to advertise (DHCP option 3).
 	GatewayIP net.IP
@@ -158,7 +161,6 @@ func (c *DhcpServerConfigurator) Create(ctx context.Context, item depgraph.Item)
 }
 
 func (c *DhcpServerConfigurator) createDnsmasqConfFile(server DhcpServer) error {
-	isIPv6 := len(server.Subnet.IP) == net.IPv6len
 	if err := ensureDir(dnsmasqConfDir); err != nil {
 		return err
 	}
@@ -174,16 +176,9 @@ func (c *DhcpServerConfigurator) createDnsmasqConfFile(server DhcpServer) error
 	// PID file is also used by Delete method.
 	file.WriteString(fmt.Sprintf("pid-file=%s\n", dnsmasqPidFile(srvName)))
 	// To enable dnsmasq's DHCP server functionality.
-	if isIPv6 {
-		// Do Router Advertisements and stateless DHCPv6 for this subnet. Clients will
-		// not get addresses from DHCP, but they will get other configuration information.
-		// They will use SLAAC for addresses.
-		file.WriteString(fmt.Sprintf("dhcp-range=%s,ra-stateless\n", server.Subnet))
-	} else {
-		netmask := net.IP(server.Subnet.Mask)
-		file.WriteString(fmt.Sprintf("dhcp-range=%s,%s,%s,60m\n",
-			server.IPRange.FromIP, server.IPRange.ToIP, netmask))
-	}
+	netmask := net.IP(server.Subnet.Mask)
+	file.WriteString(fmt.Sprintf("dhcp-range=%s,%s,%s,60m\n",
+		server.IPRange.FromIP, server.IPRange.ToIP, netmask))
 	file.WriteString(fmt.Sprintf("dhcp-leasefile=%s\n",
 		dnsmasqLeaseFile(srvName)))
 	// To disable dnsmasq's DNS server functionality.
@@ -195,21 +190,13 @@ func (c *DhcpServerConfigurator) createDnsmasqConfFile(server DhcpServer) error
 	file.WriteString(fmt.Sprintf("log-facility=%s\n", dnsmasqLogFile(srvName)))
 	// Domain name.
 	if server.DomainName != "" {
-		if isIPv6 {
-			file.WriteString(fmt.Sprintf("dhcp-option=option:domain-search,%s\n",
-				server.DomainName))
-		} else {
-			file.WriteString(fmt.Sprintf("dhcp-option=option:domain-name,%s\n",
-				server.DomainName))
-		}
+		file.WriteString(fmt.Sprintf("dhcp-option=option:domain-name,%s\n",
+			server.DomainName))
 	}
 	// Default gateway.
 	if len(server.GatewayIP) != 0 {
-		// IPv6 needs to be handled with radvd.
-		if !isIPv6 {
-			gwIP := server.GatewayIP.String()
-			file.WriteString(fmt.Sprintf("dhcp-option=option:router,%s\n", gwIP))
-		}
+		gwIP := server.GatewayIP.String()
+		file.WriteString(fmt.Sprintf("dhcp-option=option:router,%s\n", gwIP))
 	}
 	// DNS servers.
 	if len(server.DNSServers) > 0 {
@@ -281,6 +268,53 @@ func dnsmasqLeaseFile(srvName string) string {
 	return filepath.Join(dnsmasqRunDir, srvName+".leases")
 }
 
+// ReadDhcpLeases : read leases currently handed out by the DHCP server.
+// Returns empty list if the server has not leased any IP address yet.
+func ReadDhcpLeases(serverName string) (leases []sdnapi.DHCPLease, err error) {
+	leaseFile := dnsmasqLeaseFile(dhcpSrvNamePrefix + serverName)
+	file, err := os.Open(leaseFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open lease file %s: %w", leaseFile, err)
+	}
+	defer file.Close()
+	if leases, err = parseDhcpLeases(file); err != nil {
+		return nil, fmt.Errorf("failed to read lease file %s: %w", leaseFile, err)
+	}
+	return leases, nil
+}
+
+// parseDhcpLeases : parse content of the dnsmasq lease file.
+func parseDhcpLeases(r io.Reader) (leases []sdnapi.DHCPLease, err error) {
+	// Every line has the format: <expiry> <MAC|IAID> <IP> <hostname> <client-ID>
+	// DHCPv6 leases are preceded by a line with the server DUID.
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] == "duid" {
+			continue
+		}
+		var lease sdnapi.DHCPLease
+		if expiry, err := strconv.ParseInt(fields[0], 10, 64); err == nil && expiry != 0 {
+			lease.Expiry = time.Unix(expiry, 0)
+		}
+		if mac, err := net.ParseMAC(fields[1]); err == nil {
+			lease.MAC = mac.String()
+		}
+		lease.IP = fields[2]
+		if fields[3] != "*" {
+			lease.Hostname = fields[3]
+		}
+		leases = append(leases, lease)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
 func startDnsmasq(srvName, netNamespace string) error {
 	if err := ensureDir(dnsmasqRunDir); err != nil {
 		return err
@@ -450,3 +484,12 @@ func equalIPLists(ips1, ips2 []net.IP) bool {
 	}
 	return true
 }
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for i := range ips {
+		if ips[i].Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
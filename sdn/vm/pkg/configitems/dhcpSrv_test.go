@@ -0,0 +1,38 @@
+package configitems
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+)
+
+func TestParseDhcpLeases(t *testing.T) {
+	leaseFile := `1700000000 02:fe:00:01:02:03 172.22.12.10 eve-client 01:02:fe:00:01:02:03
+0 02:fe:00:01:02:04 172.22.12.11 * *
+duid 00:01:00:01:2c:4d:6e:7f:02:fe:00:00:00:01
+1700000100 12345 fd00:1::10 eve-client 00:03:00:01:02:fe:00:01:02:03
+malformed line
+`
+	leases, err := parseDhcpLeases(strings.NewReader(leaseFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []sdnapi.DHCPLease{
+		{MAC: "02:fe:00:01:02:03", IP: "172.22.12.10", Hostname: "eve-client", Expiry: time.Unix(1700000000, 0)},
+		{MAC: "02:fe:00:01:02:04", IP: "172.22.12.11"},
+		{IP: "fd00:1::10", Hostname: "eve-client", Expiry: time.Unix(1700000100, 0)},
+	}
+	if !reflect.DeepEqual(leases, expected) {
+		t.Errorf("leases %+v, expected %+v", leases, expected)
+	}
+}
+
+func TestParseDhcpLeasesEmpty(t *testing.T) {
+	leases, err := parseDhcpLeases(strings.NewReader(""))
+	if err != nil || len(leases) != 0 {
+		t.Errorf("unexpected leases %+v (error %v)", leases, err)
+	}
+}
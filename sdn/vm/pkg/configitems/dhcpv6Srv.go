@@ -0,0 +1,279 @@
+package configitems
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	dhcpv6cfg "github.com/lf-edge/eden/sdn/vm/cmd/dhcpv6srv/config"
+	"github.com/lf-edge/eve/libs/depgraph"
+	"github.com/lf-edge/eve/libs/reconciler"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	dhcpv6SrvBinary  = "/bin/dhcpv6srv"
+	dhcpv6SrvConfDir = "/etc/dhcpv6srv"
+	dhcpv6SrvRunDir  = "/run/dhcpv6srv"
+
+	dhcpv6SrvStartTimeout = 15 * time.Second
+	dhcpv6SrvStopTimeout  = 10 * time.Second
+)
+
+// Dhcpv6Server : DHCPv6 server (see sdn/cmd/dhcpv6srv).
+type Dhcpv6Server struct {
+	// ServerName : logical name for the DHCPv6 server.
+	ServerName string
+	// NetNamespace : network namespace where the server should be running.
+	NetNamespace string
+	// VethName : logical name of the veth pair on which the server operates.
+	// (other types of interfaces are currently not supported)
+	VethName string
+	// VethPeerIfName : interface name of that side of the veth pair on which
+	// the server should listen. It should be inside NetNamespace.
+	VethPeerIfName string
+	// Subnet : IPv6 network address + netmask.
+	Subnet *net.IPNet
+	// IPRange : a range of IPv6 addresses to allocate from.
+	// Leave empty for stateless DHCPv6.
+	IPRange IPRange
+	// PrefixDelegation : pool of prefixes to delegate from.
+	// Leave nil to disable prefix delegation.
+	PrefixDelegation *net.IPNet
+	// DelegatedLength : length of delegated prefixes.
+	DelegatedLength uint8
+	// DomainName : name of the domain assigned to the network.
+	// It is propagated to clients using the DHCPv6 option 24.
+	DomainName string
+	// DNSServers : list of IPv6 addresses of DNS servers to announce via DHCPv6 option 23.
+	DNSServers []net.IP
+	// NTPServer : NTP server (IPv6 address or FQDN) to announce via DHCPv6 option 56.
+	// Optional argument, leave empty to disable.
+	NTPServer string
+}
+
+// Name
+func (s Dhcpv6Server) Name() string {
+	return s.ServerName
+}
+
+// Label
+func (s Dhcpv6Server) Label() string {
+	return s.ServerName + " (DHCPv6 server)"
+}
+
+// Type
+func (s Dhcpv6Server) Type() string {
+	return Dhcpv6ServerTypename
+}
+
+// Equal is a comparison method for two equally-named Dhcpv6Server instances.
+func (s Dhcpv6Server) Equal(other depgraph.Item) bool {
+	s2 := other.(Dhcpv6Server)
+	return s.NetNamespace == s2.NetNamespace &&
+		s.VethName == s2.VethName &&
+		s.VethPeerIfName == s2.VethPeerIfName &&
+		equalIPNets(s.Subnet, s2.Subnet) &&
+		s.IPRange.FromIP.Equal(s2.IPRange.FromIP) &&
+		s.IPRange.ToIP.Equal(s2.IPRange.ToIP) &&
+		equalIPNets(s.PrefixDelegation, s2.PrefixDelegation) &&
+		s.DelegatedLength == s2.DelegatedLength &&
+		s.DomainName == s2.DomainName &&
+		equalIPLists(s.DNSServers, s2.DNSServers) &&
+		s.NTPServer == s2.NTPServer
+}
+
+// External returns false.
+func (s Dhcpv6Server) External() bool {
+	return false
+}
+
+// String describes the DHCPv6 server config.
+func (s Dhcpv6Server) String() string {
+	return fmt.Sprintf("DHCPv6 Server: %#+v", s)
+}
+
+// Dependencies lists the veth and network namespace as dependencies.
+func (s Dhcpv6Server) Dependencies() (deps []depgraph.Dependency) {
+	return []depgraph.Dependency{
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: NetNamespaceTypename,
+				ItemName: normNetNsName(s.NetNamespace),
+			},
+			Description: "Network namespace must exist",
+		},
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: VethTypename,
+				ItemName: s.VethName,
+			},
+			Description: "veth interface must exist",
+		},
+	}
+}
+
+// Dhcpv6ServerConfigurator implements Configurator interface for Dhcpv6Server.
+type Dhcpv6ServerConfigurator struct{}
+
+// Create starts dhcpv6srv.
+func (c *Dhcpv6ServerConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	config := item.(Dhcpv6Server)
+	if err := c.createDhcpv6SrvConfFile(config); err != nil {
+		return err
+	}
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := startDhcpv6Srv(config.ServerName, config.NetNamespace)
+		done(err)
+	}()
+	return nil
+}
+
+func (c *Dhcpv6ServerConfigurator) createDhcpv6SrvConfFile(server Dhcpv6Server) error {
+	if err := ensureDir(dhcpv6SrvConfDir); err != nil {
+		return err
+	}
+	if err := ensureDir(dhcpv6SrvRunDir); err != nil {
+		return err
+	}
+	srvName := server.ServerName
+	config := dhcpv6cfg.Dhcpv6SrvConfig{
+		Interface:         server.VethPeerIfName,
+		LogFile:           dhcpv6SrvLogFile(srvName),
+		PidFile:           dhcpv6SrvPidFile(srvName),
+		LeaseFile:         dhcpv6SrvLeaseFile(srvName),
+		Verbose:           true,
+		Subnet:            server.Subnet.String(),
+		PreferredLifetime: 3600,
+		ValidLifetime:     7200,
+		DomainName:        server.DomainName,
+		NTPServer:         server.NTPServer,
+	}
+	if len(server.IPRange.FromIP) != 0 {
+		config.AddrRange = &dhcpv6cfg.IPRange{
+			FromIP: server.IPRange.FromIP.String(),
+			ToIP:   server.IPRange.ToIP.String(),
+		}
+	}
+	if server.PrefixDelegation != nil {
+		config.PrefixDelegation = &dhcpv6cfg.PrefixDelegation{
+			Prefix:          server.PrefixDelegation.String(),
+			DelegatedLength: server.DelegatedLength,
+		}
+	}
+	for _, dnsServer := range server.DNSServers {
+		config.DNSServers = append(config.DNSServers, dnsServer.String())
+	}
+	configBytes, err := json.MarshalIndent(config, "", " ")
+	if err != nil {
+		err = fmt.Errorf("failed to marshal config to JSON: %w", err)
+		log.Error(err)
+		return err
+	}
+	// Write configuration to file.
+	cfgPath := dhcpv6SrvConfigPath(srvName)
+	err = os.WriteFile(cfgPath, configBytes, 0644)
+	if err != nil {
+		err = fmt.Errorf("failed to create config file %s: %w", cfgPath, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *Dhcpv6ServerConfigurator) Modify(ctx context.Context, oldItem, newItem depgraph.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete stops dhcpv6srv.
+func (c *Dhcpv6ServerConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	config := item.(Dhcpv6Server)
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		srvName := config.ServerName
+		err := stopProcess(dhcpv6SrvPidFile(srvName), dhcpv6SrvStopTimeout)
+		if err == nil {
+			// ignore errors from here
+			_ = removeDhcpv6SrvFile(dhcpv6SrvConfigPath(srvName))
+			_ = removeDhcpv6SrvFile(dhcpv6SrvLeaseFile(srvName))
+			_ = removeDhcpv6SrvFile(dhcpv6SrvLogFile(srvName))
+			_ = removeDhcpv6SrvFile(dhcpv6SrvPidFile(srvName))
+		}
+		done(err)
+	}()
+	return nil
+}
+
+// NeedsRecreate always returns true - Modify is not implemented.
+func (c *Dhcpv6ServerConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	return true
+}
+
+func dhcpv6SrvConfigPath(srvName string) string {
+	return filepath.Join(dhcpv6SrvConfDir, srvName+".conf")
+}
+
+func dhcpv6SrvPidFile(srvName string) string {
+	return filepath.Join(dhcpv6SrvRunDir, srvName+".pid")
+}
+
+func dhcpv6SrvLogFile(srvName string) string {
+	return filepath.Join(dhcpv6SrvRunDir, srvName+".log")
+}
+
+func dhcpv6SrvLeaseFile(srvName string) string {
+	return filepath.Join(dhcpv6SrvRunDir, srvName+".leases")
+}
+
+func removeDhcpv6SrvFile(path string) error {
+	if err := os.Remove(path); err != nil {
+		err = fmt.Errorf("failed to remove DHCPv6 server file %s: %w", path, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// ReadDhcpv6Leases : read addresses and prefixes currently leased by the DHCPv6 server.
+// Delegated prefixes are reported in the CIDR notation.
+func ReadDhcpv6Leases(serverName string) (leases []sdnapi.DHCPLease, err error) {
+	leaseFile := dhcpv6SrvLeaseFile(serverName)
+	leasesJSON, err := os.ReadFile(leaseFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read lease file %s: %w", leaseFile, err)
+	}
+	var srvLeases []dhcpv6cfg.Lease
+	if err = json.Unmarshal(leasesJSON, &srvLeases); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lease file %s: %w", leaseFile, err)
+	}
+	for _, lease := range srvLeases {
+		leases = append(leases, sdnapi.DHCPLease{
+			MAC:    lease.MAC,
+			IP:     lease.Address,
+			Expiry: lease.Expiry,
+		})
+	}
+	return leases, nil
+}
+
+func startDhcpv6Srv(srvName, netNamespace string) error {
+	cfgPath := dhcpv6SrvConfigPath(srvName)
+	args := []string{
+		"-c",
+		cfgPath,
+	}
+	pidFile := dhcpv6SrvPidFile(srvName)
+	return startProcess(netNamespace, dhcpv6SrvBinary, args, pidFile,
+		dhcpv6SrvStartTimeout, true)
+}
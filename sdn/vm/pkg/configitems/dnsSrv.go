@@ -1,18 +1,40 @@
 package configitems
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+	dnsfaultcfg "github.com/lf-edge/eden/sdn/vm/cmd/dnsfaultproxy/config"
 	"github.com/lf-edge/eve/libs/depgraph"
 	"github.com/lf-edge/eve/libs/reconciler"
 	log "github.com/sirupsen/logrus"
 )
 
-const dnsSrvNamePrefix = "dnssrv-"
+const (
+	dnsSrvNamePrefix = "dnssrv-"
+
+	dnsFaultProxyBinary  = "/bin/dnsfaultproxy"
+	dnsFaultProxyConfDir = "/etc/dnsfaultproxy"
+	dnsFaultProxyRunDir  = "/run/dnsfaultproxy"
+
+	dnsFaultProxyStartTimeout = 3 * time.Second
+	dnsFaultProxyStopTimeout  = 10 * time.Second
+
+	// With fault rules, dnsmasq listens on this port and dnsfaultproxy
+	// listens on the standard DNS port instead.
+	dnsmasqFaultInjPort = 5353
+)
 
 // DnsServer : DNS server.
 type DnsServer struct {
@@ -31,6 +53,11 @@ type DnsServer struct {
 	// UpstreamServers : list of IP addresses of public DNS servers to forward
 	// requests to (unless there is a static entry).
 	UpstreamServers []net.IP
+	// FaultRules : rules for injecting faults into DNS responses.
+	// Symbolic references should be already resolved.
+	// If non-empty, dnsfaultproxy (see sdn/cmd/dnsfaultproxy) is deployed in front
+	// of dnsmasq.
+	FaultRules []sdnapi.DNSFaultRule
 }
 
 // DnsEntry : Mapping between FQDN and an IP address.
@@ -76,7 +103,8 @@ func (s DnsServer) Equal(other depgraph.Item) bool {
 	}
 	return s.NetNamespace == s2.NetNamespace &&
 		s.VethName == s2.VethName &&
-		s.VethPeerIfName == s2.VethPeerIfName
+		s.VethPeerIfName == s2.VethPeerIfName &&
+		reflect.DeepEqual(s.FaultRules, s2.FaultRules)
 }
 
 // External returns false.
@@ -112,20 +140,61 @@ func (s DnsServer) Dependencies() (deps []depgraph.Dependency) {
 // DnsServerConfigurator implements Configurator interface for DnsServer.
 type DnsServerConfigurator struct{}
 
-// Create starts dnsmasq (in DNS-only mode).
+// Create starts dnsmasq (in DNS-only mode) and, if there are any fault rules,
+// also dnsfaultproxy.
 func (c *DnsServerConfigurator) Create(ctx context.Context, item depgraph.Item) error {
 	config := item.(DnsServer)
 	if err := c.createDnsmasqConfFile(config); err != nil {
 		return err
 	}
+	withFaultProxy := len(config.FaultRules) > 0
+	if withFaultProxy {
+		if err := c.createDNSFaultProxyConfFile(config); err != nil {
+			return err
+		}
+	}
 	done := reconciler.ContinueInBackground(ctx)
 	go func() {
 		err := startDnsmasq(dnsSrvNamePrefix+config.ServerName, config.NetNamespace)
+		if err == nil && withFaultProxy {
+			err = startDNSFaultProxy(config.ServerName, config.NetNamespace)
+		}
 		done(err)
 	}()
 	return nil
 }
 
+func (c *DnsServerConfigurator) createDNSFaultProxyConfFile(server DnsServer) error {
+	if err := ensureDir(dnsFaultProxyConfDir); err != nil {
+		return err
+	}
+	srvName := server.ServerName
+	config := dnsfaultcfg.DNSFaultProxyConfig{
+		ListenPort:   53,
+		UpstreamAddr: fmt.Sprintf("127.0.0.1:%d", dnsmasqFaultInjPort),
+		LogFile:      dnsFaultProxyLogFile(srvName),
+		PidFile:      dnsFaultProxyPidFile(srvName),
+		StatsFile:    dnsFaultProxyStatsFile(srvName),
+		Verbose:      true,
+		FaultRules:   server.FaultRules,
+	}
+	configBytes, err := json.MarshalIndent(config, "", " ")
+	if err != nil {
+		err = fmt.Errorf("failed to marshal config to JSON: %w", err)
+		log.Error(err)
+		return err
+	}
+	// Write configuration to file.
+	cfgPath := dnsFaultProxyConfigPath(srvName)
+	err = os.WriteFile(cfgPath, configBytes, 0644)
+	if err != nil {
+		err = fmt.Errorf("failed to create config file %s: %w", cfgPath, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
 func (c *DnsServerConfigurator) createDnsmasqConfFile(server DnsServer) error {
 	if err := ensureDir(dnsmasqConfDir); err != nil {
 		return err
@@ -145,6 +214,11 @@ func (c *DnsServerConfigurator) createDnsmasqConfFile(server DnsServer) error {
 	file.WriteString(fmt.Sprintf("interface=%s\n", server.VethPeerIfName))
 	// Disable DHCP.
 	file.WriteString(fmt.Sprintf("no-dhcp-interface=%s\n", server.VethPeerIfName))
+	if len(server.FaultRules) > 0 {
+		// Queries are received by dnsfaultproxy and forwarded to dnsmasq
+		// via the loopback interface.
+		file.WriteString(fmt.Sprintf("port=%d\n", dnsmasqFaultInjPort))
+	}
 	// Logging.
 	file.WriteString("log-queries\n")
 	file.WriteString(fmt.Sprintf("log-facility=%s\n", dnsmasqLogFile(srvName)))
@@ -171,11 +245,24 @@ func (c *DnsServerConfigurator) Modify(ctx context.Context, oldItem, newItem dep
 	return errors.New("not implemented")
 }
 
-// Delete stops dnsmasq.
+// Delete stops dnsmasq (and dnsfaultproxy if running).
 func (c *DnsServerConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
 	config := item.(DnsServer)
 	done := reconciler.ContinueInBackground(ctx)
 	go func() {
+		if len(config.FaultRules) > 0 {
+			err := stopProcess(dnsFaultProxyPidFile(config.ServerName),
+				dnsFaultProxyStopTimeout)
+			if err != nil {
+				done(err)
+				return
+			}
+			// ignore errors from here
+			_ = removeDNSFaultProxyFile(dnsFaultProxyConfigPath(config.ServerName))
+			_ = removeDNSFaultProxyFile(dnsFaultProxyLogFile(config.ServerName))
+			_ = removeDNSFaultProxyFile(dnsFaultProxyStatsFile(config.ServerName))
+			_ = removeDNSFaultProxyFile(dnsFaultProxyPidFile(config.ServerName))
+		}
 		srvName := dnsSrvNamePrefix + config.ServerName
 		err := stopDnsmasq(srvName)
 		if err == nil {
@@ -193,3 +280,136 @@ func (c *DnsServerConfigurator) Delete(ctx context.Context, item depgraph.Item)
 func (c *DnsServerConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
 	return true
 }
+
+// ReadDnsServerStats : collect statistics of DNS queries served by the given
+// DNS server. Statistics are obtained from the dnsmasq log of queries.
+func ReadDnsServerStats(serverName string) (stats sdnapi.DNSServerStatus, err error) {
+	stats.Endpoint = serverName
+	logFile := dnsmasqLogFile(dnsSrvNamePrefix + serverName)
+	file, err := os.Open(logFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, fmt.Errorf("failed to open log file %s: %w", logFile, err)
+	}
+	defer file.Close()
+	if err = parseDnsmasqQueryLog(file, &stats); err != nil {
+		return stats, fmt.Errorf("failed to read log file %s: %w", logFile, err)
+	}
+	return stats, readDNSFaultProxyStats(serverName, &stats)
+}
+
+// parseDnsmasqQueryLog : count DNS queries logged by dnsmasq.
+func parseDnsmasqQueryLog(r io.Reader, stats *sdnapi.DNSServerStatus) error {
+	// Queries waiting to be resolved. A single query may produce multiple
+	// "config" or "cached" lines (one per answer record) but should be counted once.
+	pending := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Lines of interest look like this:
+		//   <date> dnsmasq[<pid>]: query[A] example.com from 10.16.16.10
+		//   <date> dnsmasq[<pid>]: forwarded example.com to 1.1.1.1
+		//   <date> dnsmasq[<pid>]: config example.com is 10.16.16.25
+		//   <date> dnsmasq[<pid>]: cached example.com is 93.184.216.34
+		fields := strings.Fields(scanner.Text())
+		for len(fields) > 0 && !strings.HasPrefix(fields[0], "dnsmasq[") {
+			fields = fields[1:]
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		action, name := fields[1], fields[2]
+		if strings.HasPrefix(action, "query[") {
+			stats.Queries++
+			if stats.QueriedNames == nil {
+				stats.QueriedNames = make(map[string]uint64)
+			}
+			stats.QueriedNames[name]++
+			pending[name] = true
+			continue
+		}
+		if !pending[name] {
+			continue
+		}
+		switch action {
+		case "forwarded":
+			stats.Forwarded++
+		case "config":
+			stats.Static++
+		case "cached":
+			stats.Cached++
+		default:
+			continue
+		}
+		delete(pending, name)
+	}
+	return scanner.Err()
+}
+
+// readDNSFaultProxyStats : merge statistics published by dnsfaultproxy (if deployed)
+// into the DNS server stats. Queries intercepted by fault rules never reach dnsmasq
+// and therefore are not included in the dnsmasq log.
+func readDNSFaultProxyStats(serverName string, stats *sdnapi.DNSServerStatus) error {
+	statsPath := dnsFaultProxyStatsFile(serverName)
+	statsBytes, err := os.ReadFile(statsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read DNS fault proxy stats file %s: %w",
+			statsPath, err)
+	}
+	var proxyStats dnsfaultcfg.Stats
+	if err = json.Unmarshal(statsBytes, &proxyStats); err != nil {
+		return fmt.Errorf("failed to unmarshal DNS fault proxy stats: %w", err)
+	}
+	stats.FaultRules = proxyStats.FaultRules
+	for name, count := range proxyStats.Intercepted {
+		stats.Queries += count
+		stats.Faulted += count
+		if stats.QueriedNames == nil {
+			stats.QueriedNames = make(map[string]uint64)
+		}
+		stats.QueriedNames[name] += count
+	}
+	return nil
+}
+
+func dnsFaultProxyConfigPath(srvName string) string {
+	return filepath.Join(dnsFaultProxyConfDir, srvName+".conf")
+}
+
+func dnsFaultProxyPidFile(srvName string) string {
+	return filepath.Join(dnsFaultProxyRunDir, srvName+".pid")
+}
+
+func dnsFaultProxyLogFile(srvName string) string {
+	return filepath.Join(dnsFaultProxyRunDir, srvName+".log")
+}
+
+func dnsFaultProxyStatsFile(srvName string) string {
+	return filepath.Join(dnsFaultProxyRunDir, srvName+".stats")
+}
+
+func removeDNSFaultProxyFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("failed to remove DNS fault proxy file %s: %w", path, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func startDNSFaultProxy(srvName, netNamespace string) error {
+	if err := ensureDir(dnsFaultProxyRunDir); err != nil {
+		return err
+	}
+	args := []string{
+		"-c",
+		dnsFaultProxyConfigPath(srvName),
+	}
+	pidFile := dnsFaultProxyPidFile(srvName)
+	return startProcess(netNamespace, dnsFaultProxyBinary, args, pidFile,
+		dnsFaultProxyStartTimeout, true)
+}
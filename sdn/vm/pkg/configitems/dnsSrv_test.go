@@ -0,0 +1,38 @@
+package configitems
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
+)
+
+func TestParseDnsmasqQueryLog(t *testing.T) {
+	queryLog := `Jan  1 00:00:00 dnsmasq[42]: started, version 2.86 cachesize 150
+Jan  1 00:00:01 dnsmasq[42]: query[A] example.com from 10.16.16.10
+Jan  1 00:00:01 dnsmasq[42]: forwarded example.com to 1.1.1.1
+Jan  1 00:00:01 dnsmasq[42]: reply example.com is 93.184.216.34
+Jan  1 00:00:02 dnsmasq[42]: query[A] example.com from 10.16.16.10
+Jan  1 00:00:02 dnsmasq[42]: cached example.com is 93.184.216.34
+Jan  1 00:00:03 dnsmasq[42]: query[A] mydomain.adam from 10.16.16.10
+Jan  1 00:00:03 dnsmasq[42]: config mydomain.adam is 10.16.16.25
+Jan  1 00:00:03 dnsmasq[42]: config mydomain.adam is 10.16.16.26
+Jan  1 00:00:04 dnsmasq[42]: cached other.com is 1.2.3.4
+Jan  1 00:00:05 dnsmasq[42]: query[AAAA] example.com from 10.16.16.10
+`
+	var stats sdnapi.DNSServerStatus
+	if err := parseDnsmasqQueryLog(strings.NewReader(queryLog), &stats); err != nil {
+		t.Fatal(err)
+	}
+	expected := sdnapi.DNSServerStatus{
+		Queries:      4,
+		Forwarded:    1,
+		Static:       1,
+		Cached:       1,
+		QueriedNames: map[string]uint64{"example.com": 3, "mydomain.adam": 1},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("stats %+v, expected %+v", stats, expected)
+	}
+}
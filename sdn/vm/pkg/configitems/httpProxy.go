@@ -175,6 +175,7 @@ func (c *HttpProxyConfigurator) createGoproxyConfFile(proxy HttpProxy) error {
 		Transparent: proxy.Transparent,
 		LogFile:     goproxyLogFile(proxyName),
 		PidFile:     goproxyPidFile(proxyName),
+		StatsFile:   goproxyStatsFile(proxyName),
 		Verbose:     true,
 		CACertPEM:   proxy.CACertPEM,
 		CAKeyPEM:    proxy.CAKeyPEM,
@@ -214,6 +215,7 @@ func (c *HttpProxyConfigurator) Delete(ctx context.Context, item depgraph.Item)
 			_ = removeGoproxyConfFile(config.ProxyName)
 			_ = removeGoproxyLogFile(config.ProxyName)
 			_ = removeGoproxyPidFile(config.ProxyName)
+			_ = removeGoproxyStatsFile(config.ProxyName)
 		}
 		done(err)
 	}()
@@ -237,6 +239,28 @@ func goproxyLogFile(proxyName string) string {
 	return filepath.Join(goproxyRunDir, proxyName+".log")
 }
 
+func goproxyStatsFile(proxyName string) string {
+	return filepath.Join(goproxyRunDir, proxyName+".stats")
+}
+
+// ReadHttpProxyStats : read request statistics published by the given proxy.
+// Returns zero stats if the proxy has not published any statistics yet.
+func ReadHttpProxyStats(proxyName string) (stats sdnapi.ProxyStats, err error) {
+	statsPath := goproxyStatsFile(proxyName)
+	statsBytes, err := os.ReadFile(statsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, fmt.Errorf("failed to read proxy stats file %s: %w",
+			statsPath, err)
+	}
+	if err = json.Unmarshal(statsBytes, &stats); err != nil {
+		return stats, fmt.Errorf("failed to unmarshal proxy stats: %w", err)
+	}
+	return stats, nil
+}
+
 func removeGoproxyConfFile(proxyName string) error {
 	cfgPath := goproxyConfigPath(proxyName)
 	if err := os.Remove(cfgPath); err != nil {
@@ -259,6 +283,17 @@ func removeGoproxyPidFile(proxyName string) error {
 	return nil
 }
 
+func removeGoproxyStatsFile(proxyName string) error {
+	statsPath := goproxyStatsFile(proxyName)
+	if err := os.Remove(statsPath); err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("failed to remove goproxy stats file %s: %w",
+			statsPath, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
 func removeGoproxyLogFile(proxyName string) error {
 	logPath := goproxyLogFile(proxyName)
 	if err := os.Remove(logPath); err != nil {
@@ -8,6 +8,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"time"
 
 	sdnapi "github.com/lf-edge/eden/sdn/vm/api"
@@ -52,6 +53,9 @@ type HttpServer struct {
 	KeyPEM string
 	// Maps URL Path to a content to be returned inside the HTTP(s) response body.
 	Paths map[string]sdnapi.HTTPContent
+	// ContentCACertPEM : additional CA certificate (PEM) to trust when downloading
+	// content from HTTPContent.ContentFromURL.
+	ContentCACertPEM string
 }
 
 // Name
@@ -76,7 +80,7 @@ func (s HttpServer) Equal(other depgraph.Item) bool {
 		return false
 	}
 	for path, content := range s.Paths {
-		if content2, ok := s2.Paths[path]; !ok || content != content2 {
+		if content2, ok := s2.Paths[path]; !ok || !reflect.DeepEqual(content, content2) {
 			return false
 		}
 	}
@@ -86,7 +90,8 @@ func (s HttpServer) Equal(other depgraph.Item) bool {
 		s.HTTPPort == s2.HTTPPort &&
 		s.HTTPSPort == s2.HTTPSPort &&
 		s.CertPEM == s2.CertPEM &&
-		s.KeyPEM == s2.KeyPEM
+		s.KeyPEM == s2.KeyPEM &&
+		s.ContentCACertPEM == s2.ContentCACertPEM
 }
 
 // External returns false.
@@ -148,15 +153,17 @@ func (c *HttpServerConfigurator) createHttpSrvConfFile(httpSrv HttpServer) error
 		listenIP = httpSrv.ListenIP.String()
 	}
 	config := httpsrvcfg.HttpSrvConfig{
-		ListenIP:  listenIP,
-		LogFile:   httpSrvLogFile(serverName),
-		PidFile:   httpSrvPidFile(serverName),
-		Verbose:   true,
-		HTTPPort:  httpSrv.HTTPPort,
-		HTTPSPort: httpSrv.HTTPSPort,
-		CertPEM:   httpSrv.CertPEM,
-		KeyPEM:    httpSrv.KeyPEM,
-		Paths:     httpSrv.Paths,
+		ListenIP:         listenIP,
+		LogFile:          httpSrvLogFile(serverName),
+		PidFile:          httpSrvPidFile(serverName),
+		StatsFile:        httpSrvStatsFile(serverName),
+		Verbose:          true,
+		HTTPPort:         httpSrv.HTTPPort,
+		HTTPSPort:        httpSrv.HTTPSPort,
+		CertPEM:          httpSrv.CertPEM,
+		KeyPEM:           httpSrv.KeyPEM,
+		Paths:            httpSrv.Paths,
+		ContentCACertPEM: httpSrv.ContentCACertPEM,
 	}
 	configBytes, err := json.MarshalIndent(config, "", " ")
 	if err != nil {
@@ -191,6 +198,7 @@ func (c *HttpServerConfigurator) Delete(ctx context.Context, item depgraph.Item)
 			_ = removeHttpSrvConfFile(config.ServerName)
 			_ = removeHttpSrvLogFile(config.ServerName)
 			_ = removeHttpSrvPidFile(config.ServerName)
+			_ = removeHttpSrvStatsFile(config.ServerName)
 		}
 		done(err)
 	}()
@@ -214,6 +222,41 @@ func httpSrvLogFile(srvName string) string {
 	return filepath.Join(httpSrvRunDir, srvName+".log")
 }
 
+func httpSrvStatsFile(srvName string) string {
+	return filepath.Join(httpSrvRunDir, srvName+".stats")
+}
+
+// ReadHttpServerStats : read request statistics published by the given HTTP server.
+// Returns zero stats if the server has not published any statistics yet.
+func ReadHttpServerStats(srvName string) (stats sdnapi.HTTPServerStatus, err error) {
+	stats.Endpoint = srvName
+	statsPath := httpSrvStatsFile(srvName)
+	statsBytes, err := os.ReadFile(statsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, fmt.Errorf("failed to read HTTP server stats file %s: %w",
+			statsPath, err)
+	}
+	if err = json.Unmarshal(statsBytes, &stats); err != nil {
+		return stats, fmt.Errorf("failed to unmarshal HTTP server stats: %w", err)
+	}
+	stats.Endpoint = srvName
+	return stats, nil
+}
+
+func removeHttpSrvStatsFile(srvName string) error {
+	statsPath := httpSrvStatsFile(srvName)
+	if err := os.Remove(statsPath); err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("failed to remove HTTP server stats file %s: %w",
+			statsPath, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
 func removeHttpSrvConfFile(srvName string) error {
 	cfgPath := httpSrvConfigPath(srvName)
 	if err := os.Remove(cfgPath); err != nil {
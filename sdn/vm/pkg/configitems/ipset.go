@@ -0,0 +1,223 @@
+package configitems
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/eve/libs/depgraph"
+	log "github.com/sirupsen/logrus"
+)
+
+const ipsetBinary = "ipset"
+
+// IPSet : set of IP addresses (created using ipset), which can be matched
+// by iptables rules (see IptablesChain.RefersIPSets).
+// The set is created with the (optional) static entries, other entries can be
+// added and removed at run-time (e.g. by a captive portal).
+type IPSet struct {
+	// NetNamespace : network namespace where the set should be created.
+	NetNamespace string
+	// SetName : name of the set (at most 31 characters).
+	SetName string
+	// ForIPv6 : create set of IPv6 addresses (otherwise IPv4).
+	ForIPv6 bool
+	// Timeout : number of seconds after which an entry is automatically removed
+	// from the set. Zero value means that entries never expire.
+	Timeout uint32
+	// Entries : static entries of the set.
+	// Changes in static entries are applied without re-creating the set.
+	Entries []net.IP
+}
+
+// IPSetEntry : single entry of an IP set.
+type IPSetEntry struct {
+	IP net.IP
+	// Timeout : number of seconds remaining until the entry expires.
+	// Zero if the entry does not expire.
+	Timeout uint32
+}
+
+// Name
+func (s IPSet) Name() string {
+	return fmt.Sprintf("%s/%s", normNetNsName(s.NetNamespace), s.SetName)
+}
+
+// Label
+func (s IPSet) Label() string {
+	return s.Name() + " (IP set)"
+}
+
+// Type
+func (s IPSet) Type() string {
+	return IPSetTypename
+}
+
+// Equal is a comparison method for two equally-named IPSet instances.
+func (s IPSet) Equal(other depgraph.Item) bool {
+	s2 := other.(IPSet)
+	return s.ForIPv6 == s2.ForIPv6 &&
+		s.Timeout == s2.Timeout &&
+		equalIPLists(s.Entries, s2.Entries)
+}
+
+// External returns false.
+func (s IPSet) External() bool {
+	return false
+}
+
+// String describes the IP set.
+func (s IPSet) String() string {
+	return fmt.Sprintf("IP set: %#+v", s)
+}
+
+// Dependencies lists the network namespace as the only dependency.
+func (s IPSet) Dependencies() (deps []depgraph.Dependency) {
+	return []depgraph.Dependency{
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: NetNamespaceTypename,
+				ItemName: normNetNsName(s.NetNamespace),
+			},
+			Description: "Network namespace must exist",
+		},
+	}
+}
+
+func (s IPSet) family() string {
+	if s.ForIPv6 {
+		return "inet6"
+	}
+	return "inet"
+}
+
+// IPSetConfigurator implements Configurator interface for IPSet.
+type IPSetConfigurator struct{}
+
+// Create creates the IP set and adds static entries.
+func (c *IPSetConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	set := item.(IPSet)
+	// Set may have been left behind by a previous run of the agent.
+	_ = namespacedCmd(set.NetNamespace, ipsetBinary, "destroy", set.SetName).Run()
+	args := []string{"create", set.SetName, "hash:ip", "family", set.family()}
+	if set.Timeout != 0 {
+		args = append(args, "timeout", strconv.Itoa(int(set.Timeout)))
+	}
+	out, err := namespacedCmd(set.NetNamespace, ipsetBinary, args...).CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to create IP set %s: %v, output: %s",
+			set.SetName, err, out)
+		log.Error(err)
+		return err
+	}
+	for _, ip := range set.Entries {
+		if err = AddIPSetEntry(set.NetNamespace, set.SetName, ip); err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Modify updates static entries of the IP set.
+func (c *IPSetConfigurator) Modify(ctx context.Context, oldItem, newItem depgraph.Item) (err error) {
+	oldSet := oldItem.(IPSet)
+	newSet := newItem.(IPSet)
+	for _, ip := range oldSet.Entries {
+		if containsIP(newSet.Entries, ip) {
+			continue
+		}
+		if err = DelIPSetEntry(newSet.NetNamespace, newSet.SetName, ip); err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+	for _, ip := range newSet.Entries {
+		if containsIP(oldSet.Entries, ip) {
+			continue
+		}
+		if err = AddIPSetEntry(newSet.NetNamespace, newSet.SetName, ip); err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete destroys the IP set.
+func (c *IPSetConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	set := item.(IPSet)
+	out, err := namespacedCmd(set.NetNamespace, ipsetBinary, "destroy", set.SetName).
+		CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to destroy IP set %s: %v, output: %s",
+			set.SetName, err, out)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NeedsRecreate returns true if anything but static entries have changed.
+func (c *IPSetConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	oldSet := oldItem.(IPSet)
+	newSet := newItem.(IPSet)
+	return oldSet.ForIPv6 != newSet.ForIPv6 || oldSet.Timeout != newSet.Timeout
+}
+
+// AddIPSetEntry : add IP address into the IP set.
+// If the IP is already in the set, its timeout is refreshed.
+func AddIPSetEntry(netNamespace, setName string, ip net.IP) error {
+	out, err := namespacedCmd(netNamespace, ipsetBinary,
+		"add", "-exist", setName, ip.String()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to add %s into IP set %s: %v, output: %s",
+			ip, setName, err, out)
+	}
+	return nil
+}
+
+// DelIPSetEntry : remove IP address from the IP set.
+// It is not an error if the IP is not in the set.
+func DelIPSetEntry(netNamespace, setName string, ip net.IP) error {
+	out, err := namespacedCmd(netNamespace, ipsetBinary,
+		"del", "-exist", setName, ip.String()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remove %s from IP set %s: %v, output: %s",
+			ip, setName, err, out)
+	}
+	return nil
+}
+
+// ListIPSetEntries : list entries of the IP set.
+func ListIPSetEntries(netNamespace, setName string) (entries []IPSetEntry, err error) {
+	out, err := namespacedCmd(netNamespace, ipsetBinary,
+		"list", setName, "-output", "save").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP set %s: %w", setName, err)
+	}
+	// Entries are printed as: add <set-name> <ip> [timeout <seconds>]
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "add" || fields[1] != setName {
+			continue
+		}
+		entry := IPSetEntry{IP: net.ParseIP(fields[2])}
+		if entry.IP == nil {
+			continue
+		}
+		for i := 3; i+1 < len(fields); i++ {
+			if fields[i] == "timeout" {
+				timeout, _ := strconv.ParseUint(fields[i+1], 10, 32)
+				entry.Timeout = uint32(timeout)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/lf-edge/eve/libs/depgraph"
@@ -24,6 +25,9 @@ type IptablesChain struct {
 	RefersChains []string
 	// RefersVeths : names of VETH interfaces referred from rules.
 	RefersVeths []string
+	// RefersIPSets : names of IP sets referred from rules.
+	// IP sets are expected to be in the same network namespace as the chain.
+	RefersIPSets []string
 	// PreCreated : a custom chain which already exists (as empty).
 	PreCreated bool
 }
@@ -82,7 +86,7 @@ func (ch IptablesChain) String() string {
 	return str
 }
 
-// Dependencies lists all referenced chains + net namespace as dependencies.
+// Dependencies lists all referenced chains, veths, IP sets + net namespace as dependencies.
 func (ch IptablesChain) Dependencies() (deps []depgraph.Dependency) {
 	for _, referredChain := range ch.RefersChains {
 		deps = append(deps, depgraph.Dependency{
@@ -103,6 +107,15 @@ func (ch IptablesChain) Dependencies() (deps []depgraph.Dependency) {
 			Description: "veth interface must exist",
 		})
 	}
+	for _, referredSet := range ch.RefersIPSets {
+		deps = append(deps, depgraph.Dependency{
+			RequiredItem: depgraph.Reference(IPSet{
+				NetNamespace: ch.NetNamespace,
+				SetName:      referredSet,
+			}),
+			Description: "IP set must exist",
+		})
+	}
 	deps = append(deps, depgraph.Dependency{
 		RequiredItem: depgraph.ItemRef{
 			ItemType: NetNamespaceTypename,
@@ -238,3 +251,55 @@ func (c *IptablesChainConfigurator) Delete(ctx context.Context, item depgraph.It
 func (c *IptablesChainConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
 	return false
 }
+
+// IptablesRuleCounters : packet and byte counters of an iptables rule.
+type IptablesRuleCounters struct {
+	// Comment attached to the rule using "-m comment --comment <comment>".
+	// Empty if the rule has no comment.
+	Comment string
+	Packets uint64
+	Bytes   uint64
+}
+
+// GetIptablesRuleCounters : return counters of all rules of the given chain
+// (in the order of rules).
+func GetIptablesRuleCounters(netNamespace, table, chainName string,
+	forIPv6 bool) (counters []IptablesRuleCounters, err error) {
+	chain := IptablesChain{NetNamespace: netNamespace, Table: table,
+		ChainName: chainName, ForIPv6: forIPv6}
+	// With -v, counters are printed as: -c <packets> <bytes>
+	out, err := namespacedCmd(netNamespace, chain.command(),
+		"-t", chain.table(), "-S", chainName, "-v").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s chain %s: %w",
+			chain.command(), chainName, err)
+	}
+	return parseIptablesRuleCounters(string(out), chainName), nil
+}
+
+// parseIptablesRuleCounters parses counters of rules of the given chain
+// from the output of "iptables -S <chain> -v".
+func parseIptablesRuleCounters(out, chainName string) (counters []IptablesRuleCounters) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "-A" || fields[1] != chainName {
+			continue
+		}
+		var ruleCounters IptablesRuleCounters
+		for i := 2; i < len(fields); i++ {
+			switch fields[i] {
+			case "--comment":
+				if i+1 < len(fields) {
+					ruleCounters.Comment = strings.Trim(fields[i+1], `"`)
+				}
+			case "-c":
+				if i+2 < len(fields) {
+					ruleCounters.Packets, _ = strconv.ParseUint(fields[i+1], 10, 64)
+					ruleCounters.Bytes, _ = strconv.ParseUint(fields[i+2], 10, 64)
+				}
+			}
+		}
+		counters = append(counters, ruleCounters)
+	}
+	return counters
+}
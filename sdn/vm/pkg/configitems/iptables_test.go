@@ -0,0 +1,28 @@
+package configitems
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIptablesRuleCounters(t *testing.T) {
+	out := `-P FORWARD ACCEPT -c 100 5000
+-N SDN-FW
+-A SDN-FW -s 10.0.0.0/24 -p tcp -m comment --comment fw-rule-0 -c 10 840 -j ACCEPT
+-A SDN-FW -c 5 300 -m comment --comment "fw-rule-1" -j DROP
+-A SDN-FW -p all -c 0 0 -j ACCEPT
+-A OTHER -m comment --comment fw-rule-2 -c 1 60 -j ACCEPT
+`
+	expected := []IptablesRuleCounters{
+		{Comment: "fw-rule-0", Packets: 10, Bytes: 840},
+		{Comment: "fw-rule-1", Packets: 5, Bytes: 300},
+		{Packets: 0, Bytes: 0},
+	}
+	counters := parseIptablesRuleCounters(out, "SDN-FW")
+	if !reflect.DeepEqual(counters, expected) {
+		t.Errorf("unexpected counters: %+v", counters)
+	}
+	if counters = parseIptablesRuleCounters("-N SDN-FW\n", "SDN-FW"); counters != nil {
+		t.Errorf("expected no counters for empty chain, got %+v", counters)
+	}
+}
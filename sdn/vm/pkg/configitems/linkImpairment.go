@@ -0,0 +1,391 @@
+package configitems
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lf-edge/eden/sdn/vm/api"
+	"github.com/lf-edge/eden/sdn/vm/pkg/maclookup"
+	"github.com/lf-edge/eve/libs/depgraph"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Limit for the number of packets queued by netem.
+	// The default (1000) is too low for larger delays with higher traffic rates.
+	netemQueueLimit = 10000
+	// Maximum amount of time for which packets can wait in the TBF queue.
+	tbfLatencyMs = 100
+	// Size of the Ethernet header which is not included in MTU.
+	ethHeaderLen = 14
+)
+
+// LinkImpairment : emulated degradation of a link, implemented using netem and tbf qdiscs.
+type LinkImpairment struct {
+	// NetNamespace : network namespace where the impaired interface is.
+	NetNamespace string
+	// Interface : impaired interface.
+	Interface ImpairedIf
+	// Impairment : how the link should be degraded.
+	Impairment api.Impairment
+	// IfbName : name of the IFB device used to impair ingress traffic.
+	// Leave empty to only impair traffic leaving the interface.
+	IfbName string
+}
+
+// ImpairedIf : interface with impairment - either veth peer or physical interface.
+type ImpairedIf struct {
+	// VethName : logical name of the veth pair with the impaired peer.
+	// Define either PhysIf or VethName + VethPeerIfName.
+	VethName string
+	// VethPeerIfName : interface name of the impaired side of the veth pair.
+	VethPeerIfName string
+	// PhysIf : impaired physical interface.
+	// Define either PhysIf or VethName + VethPeerIfName.
+	PhysIf PhysIf
+}
+
+// Name
+func (l LinkImpairment) Name() string {
+	return fmt.Sprintf("%s/%s", normNetNsName(l.NetNamespace), l.ifRef())
+}
+
+// Label
+func (l LinkImpairment) Label() string {
+	return fmt.Sprintf("impairment for %s", l.ifLabel())
+}
+
+func (l LinkImpairment) ifRef() string {
+	if l.Interface.VethName != "" {
+		return l.Interface.VethPeerIfName
+	}
+	return l.Interface.PhysIf.MAC.String()
+}
+
+func (l LinkImpairment) ifLabel() string {
+	if l.Interface.VethName != "" {
+		return l.Interface.VethPeerIfName
+	}
+	return l.Interface.PhysIf.LogicalLabel
+}
+
+// Type
+func (l LinkImpairment) Type() string {
+	return LinkImpairmentTypename
+}
+
+// Equal is a comparison method for two equally-named LinkImpairment instances.
+func (l LinkImpairment) Equal(other depgraph.Item) bool {
+	l2 := other.(LinkImpairment)
+	return l.Impairment == l2.Impairment &&
+		l.IfbName == l2.IfbName
+}
+
+// External returns false.
+func (l LinkImpairment) External() bool {
+	return false
+}
+
+// String describes LinkImpairment.
+func (l LinkImpairment) String() string {
+	return fmt.Sprintf("Link impairment: %#+v", l)
+}
+
+// Dependencies lists the namespace and the impaired interface as dependencies.
+func (l LinkImpairment) Dependencies() (deps []depgraph.Dependency) {
+	deps = append(deps, depgraph.Dependency{
+		RequiredItem: depgraph.ItemRef{
+			ItemType: NetNamespaceTypename,
+			ItemName: normNetNsName(l.NetNamespace),
+		},
+		Description: "Network namespace must exist",
+	})
+	if l.Interface.VethName != "" {
+		deps = append(deps, depgraph.Dependency{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: VethTypename,
+				ItemName: l.Interface.VethName,
+			},
+			Description: "veth interface must exist",
+		})
+	} else {
+		deps = append(deps, depgraph.Dependency{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: IfHandleTypename,
+				ItemName: l.Interface.PhysIf.MAC.String(),
+			},
+			Description: "Physical network interface must exist",
+		})
+	}
+	return deps
+}
+
+// LinkImpairmentConfigurator implements Configurator interface for LinkImpairment.
+type LinkImpairmentConfigurator struct {
+	MacLookup *maclookup.MacLookup
+}
+
+// Create installs qdiscs emulating the link impairment.
+func (c *LinkImpairmentConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	impairment := item.(LinkImpairment)
+	ns := normNetNsName(impairment.NetNamespace)
+	if ns != MainNsName {
+		// Move into the namespace with the interface (leave on defer).
+		revertNs, err := switchToNamespace(ns)
+		if err != nil {
+			return fmt.Errorf("failed to switch to net namespace %s: %w", ns, err)
+		}
+		defer revertNs()
+	}
+	link, err := c.getLink(impairment.Interface)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if err = c.setQdiscs(link, impairment.Impairment); err != nil {
+		log.Error(err)
+		return err
+	}
+	if impairment.IfbName == "" {
+		return nil
+	}
+	// Remove leftovers from a previously failed attempt.
+	if staleIfb, err := netlink.LinkByName(impairment.IfbName); err == nil {
+		_ = netlink.LinkDel(staleIfb)
+	}
+	ifb := &netlink.Ifb{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:   impairment.IfbName,
+			TxQLen: 1000,
+		},
+	}
+	if err = netlink.LinkAdd(ifb); err != nil {
+		err = fmt.Errorf("failed to add IFB %s: %w", impairment.IfbName, err)
+		log.Error(err)
+		return err
+	}
+	ifbLink, err := netlink.LinkByName(impairment.IfbName)
+	if err != nil {
+		err = fmt.Errorf("failed to get link for IFB %s: %w", impairment.IfbName, err)
+		log.Error(err)
+		return err
+	}
+	if err = netlink.LinkSetUp(ifbLink); err != nil {
+		err = fmt.Errorf("failed to set IFB %s UP: %w", impairment.IfbName, err)
+		log.Error(err)
+		return err
+	}
+	if err = c.setQdiscs(ifbLink, impairment.Impairment); err != nil {
+		log.Error(err)
+		return err
+	}
+	// Redirect all ingress traffic into the IFB, where it gets impaired
+	// as egress traffic.
+	ingress := &netlink.Ingress{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+	}
+	// Removal of the ingress qdisc also removes filters of a previously failed attempt.
+	_ = netlink.QdiscDel(ingress)
+	if err = netlink.QdiscAdd(ingress); err != nil {
+		err = fmt.Errorf("failed to add ingress qdisc for %s: %w",
+			link.Attrs().Name, err)
+		log.Error(err)
+		return err
+	}
+	// U32 filter without selector matches all packets.
+	filter := &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.MakeHandle(0xffff, 0),
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Actions: []netlink.Action{netlink.NewMirredAction(ifbLink.Attrs().Index)},
+	}
+	if err = netlink.FilterAdd(filter); err != nil {
+		err = fmt.Errorf("failed to redirect ingress traffic of %s to IFB %s: %w",
+			link.Attrs().Name, impairment.IfbName, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (c *LinkImpairmentConfigurator) getLink(netIf ImpairedIf) (netlink.Link, error) {
+	ifName := netIf.VethPeerIfName
+	if netIf.VethName == "" {
+		mac := netIf.PhysIf.MAC
+		physIf, found := c.MacLookup.GetInterfaceByMAC(mac, false)
+		if !found {
+			return nil, fmt.Errorf("failed to get physical interface with MAC %v", mac)
+		}
+		ifName = physIf.IfName
+	}
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link for interface %s: %w", ifName, err)
+	}
+	return link, nil
+}
+
+// setQdiscs (re)creates netem as the root qdisc of the link, with tbf as a child
+// if the rate is limited.
+func (c *LinkImpairmentConfigurator) setQdiscs(link netlink.Link, impairment api.Impairment) error {
+	c.delRootQdisc(link)
+	netemAttrs := netlink.NetemQdiscAttrs{
+		Latency:     impairment.Delay * 1000,
+		Jitter:      impairment.Jitter * 1000,
+		Loss:        impairment.Loss,
+		Duplicate:   impairment.Duplicate,
+		ReorderProb: impairment.Reorder,
+		Limit:       netemQueueLimit,
+	}
+	netem := netlink.NewNetem(netlink.QdiscAttrs{
+		LinkIndex: link.Attrs().Index,
+		Handle:    netlink.MakeHandle(1, 0),
+		Parent:    netlink.HANDLE_ROOT,
+	}, netemAttrs)
+	if err := netlink.QdiscAdd(netem); err != nil {
+		return fmt.Errorf("failed to add netem qdisc for %s: %w", link.Attrs().Name, err)
+	}
+	if impairment.Rate == 0 {
+		return nil
+	}
+	rate := uint64(impairment.Rate) * 1000 / 8 // bytes per second
+	// Burst should allow at least one full-size frame.
+	burst := uint32(rate / 100)
+	if minBurst := uint32(link.Attrs().MTU + ethHeaderLen); burst < minBurst {
+		burst = minBurst
+	}
+	tbf := &netlink.Tbf{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(10, 0),
+			Parent:    netlink.MakeHandle(1, 1),
+		},
+		Rate:   rate,
+		Limit:  burst + uint32(rate*tbfLatencyMs/1000),
+		Buffer: netlink.Xmittime(rate, burst),
+	}
+	if err := netlink.QdiscAdd(tbf); err != nil {
+		return fmt.Errorf("failed to add tbf qdisc for %s: %w", link.Attrs().Name, err)
+	}
+	return nil
+}
+
+// delRootQdisc reverts link to the default root qdisc.
+func (c *LinkImpairmentConfigurator) delRootQdisc(link netlink.Link) {
+	qdiscs, err := netlink.QdiscList(link)
+	if err != nil {
+		log.Warnf("failed to list qdiscs for %s: %v", link.Attrs().Name, err)
+		return
+	}
+	for _, qdisc := range qdiscs {
+		if qdisc.Attrs().Parent != netlink.HANDLE_ROOT || qdisc.Type() != "netem" {
+			continue
+		}
+		if err = netlink.QdiscDel(qdisc); err != nil {
+			log.Warnf("failed to remove %s qdisc from %s: %v",
+				qdisc.Type(), link.Attrs().Name, err)
+		}
+	}
+}
+
+// Modify updates qdiscs with the new impairment parameters.
+func (c *LinkImpairmentConfigurator) Modify(ctx context.Context, oldItem, newItem depgraph.Item) (err error) {
+	impairment := newItem.(LinkImpairment)
+	ns := normNetNsName(impairment.NetNamespace)
+	if ns != MainNsName {
+		// Move into the namespace with the interface (leave on defer).
+		revertNs, err := switchToNamespace(ns)
+		if err != nil {
+			return fmt.Errorf("failed to switch to net namespace %s: %w", ns, err)
+		}
+		defer revertNs()
+	}
+	link, err := c.getLink(impairment.Interface)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if err = c.setQdiscs(link, impairment.Impairment); err != nil {
+		log.Error(err)
+		return err
+	}
+	if impairment.IfbName == "" {
+		return nil
+	}
+	ifbLink, err := netlink.LinkByName(impairment.IfbName)
+	if err != nil {
+		err = fmt.Errorf("failed to get link for IFB %s: %w", impairment.IfbName, err)
+		log.Error(err)
+		return err
+	}
+	if err = c.setQdiscs(ifbLink, impairment.Impairment); err != nil {
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Delete removes qdiscs and the IFB device.
+func (c *LinkImpairmentConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	impairment := item.(LinkImpairment)
+	ns := normNetNsName(impairment.NetNamespace)
+	if ns != MainNsName {
+		// Move into the namespace with the interface (leave on defer).
+		revertNs, err := switchToNamespace(ns)
+		if err != nil {
+			return fmt.Errorf("failed to switch to net namespace %s: %w", ns, err)
+		}
+		defer revertNs()
+	}
+	link, err := c.getLink(impairment.Interface)
+	if err != nil {
+		// Interface is already gone (and qdiscs with it).
+		log.Warn(err)
+	} else {
+		c.delRootQdisc(link)
+		if impairment.IfbName != "" {
+			ingress := &netlink.Ingress{
+				QdiscAttrs: netlink.QdiscAttrs{
+					LinkIndex: link.Attrs().Index,
+					Handle:    netlink.MakeHandle(0xffff, 0),
+					Parent:    netlink.HANDLE_INGRESS,
+				},
+			}
+			if err = netlink.QdiscDel(ingress); err != nil {
+				log.Warnf("failed to remove ingress qdisc from %s: %v",
+					link.Attrs().Name, err)
+			}
+		}
+	}
+	if impairment.IfbName == "" {
+		return nil
+	}
+	ifbLink, err := netlink.LinkByName(impairment.IfbName)
+	if err != nil {
+		err = fmt.Errorf("failed to get link for IFB %s: %w", impairment.IfbName, err)
+		log.Error(err)
+		return err
+	}
+	if err = netlink.LinkDel(ifbLink); err != nil {
+		err = fmt.Errorf("failed to remove IFB %s: %w", impairment.IfbName, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NeedsRecreate returns true if IFB changed, Modify is able to update
+// impairment parameters.
+func (c *LinkImpairmentConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	oldImpairment := oldItem.(LinkImpairment)
+	newImpairment := newItem.(LinkImpairment)
+	return oldImpairment.IfbName != newImpairment.IfbName
+}
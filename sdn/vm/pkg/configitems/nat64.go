@@ -0,0 +1,217 @@
+package configitems
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eve/libs/depgraph"
+	"github.com/lf-edge/eve/libs/reconciler"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	taygaBinary       = "/usr/sbin/tayga"
+	taygaConfDir      = "/etc/tayga"
+	taygaRunDir       = "/run/tayga"
+	taygaStartTimeout = 3 * time.Second
+	taygaStopTimeout  = 10 * time.Second
+)
+
+// NAT64Gateway : stateful NAT64 (using tayga) running in the main network namespace.
+// IPv6 packets routed into the gateway's TUN interface are translated into IPv4
+// with a source address from IPv4Pool. Translated traffic is then routed using the main
+// routing table and it is expected to be S-NATed on the way out.
+type NAT64Gateway struct {
+	// GatewayName : logical name for the NAT64 gateway.
+	// It is also used as the name of the TUN interface (so it should be at most
+	// 15 characters long).
+	GatewayName string
+	// Prefix : IPv6 prefix used to represent IPv4 addresses (/96).
+	Prefix *net.IPNet
+	// IPv4Pool : IPv4 addresses that IPv6 hosts are dynamically mapped to.
+	// The first host address of the pool is used by the gateway itself.
+	IPv4Pool *net.IPNet
+	// IPv6Addr : IPv6 address used by the gateway itself (e.g. to send ICMPv6 errors).
+	IPv6Addr net.IP
+}
+
+// Name
+func (g NAT64Gateway) Name() string {
+	return g.GatewayName
+}
+
+// Label
+func (g NAT64Gateway) Label() string {
+	return g.GatewayName + " (NAT64 gateway)"
+}
+
+// Type
+func (g NAT64Gateway) Type() string {
+	return NAT64GatewayTypename
+}
+
+// Equal is a comparison method for two equally-named NAT64Gateway instances.
+func (g NAT64Gateway) Equal(other depgraph.Item) bool {
+	g2 := other.(NAT64Gateway)
+	return equalIPNets(g.Prefix, g2.Prefix) &&
+		equalIPNets(g.IPv4Pool, g2.IPv4Pool) &&
+		g.IPv6Addr.Equal(g2.IPv6Addr)
+}
+
+// External returns false.
+func (g NAT64Gateway) External() bool {
+	return false
+}
+
+// String describes the NAT64 gateway config.
+func (g NAT64Gateway) String() string {
+	return fmt.Sprintf("NAT64 Gateway: %#+v", g)
+}
+
+// Dependencies lists the main network namespace as the only dependency.
+func (g NAT64Gateway) Dependencies() (deps []depgraph.Dependency) {
+	return []depgraph.Dependency{
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: NetNamespaceTypename,
+				ItemName: MainNsName,
+			},
+			Description: "Network namespace must exist",
+		},
+	}
+}
+
+// NAT64GatewayConfigurator implements Configurator interface for NAT64Gateway.
+type NAT64GatewayConfigurator struct{}
+
+// Create creates TUN interface and starts tayga.
+func (c *NAT64GatewayConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	config := item.(NAT64Gateway)
+	if err := c.createTaygaConfFile(config); err != nil {
+		return err
+	}
+	if err := c.createTun(config); err != nil {
+		return err
+	}
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := startTayga(config.GatewayName)
+		done(err)
+	}()
+	return nil
+}
+
+func (c *NAT64GatewayConfigurator) createTaygaConfFile(config NAT64Gateway) error {
+	if err := ensureDir(taygaConfDir); err != nil {
+		return err
+	}
+	taygaIP := make(net.IP, net.IPv4len)
+	copy(taygaIP, config.IPv4Pool.IP.To4())
+	taygaIP[net.IPv4len-1]++
+	var cfg strings.Builder
+	cfg.WriteString(fmt.Sprintf("tun-device %s\n", config.GatewayName))
+	cfg.WriteString(fmt.Sprintf("ipv4-addr %s\n", taygaIP))
+	cfg.WriteString(fmt.Sprintf("ipv6-addr %s\n", config.IPv6Addr))
+	cfg.WriteString(fmt.Sprintf("prefix %s\n", config.Prefix))
+	cfg.WriteString(fmt.Sprintf("dynamic-pool %s\n", config.IPv4Pool))
+	cfgPath := taygaConfigPath(config.GatewayName)
+	if err := os.WriteFile(cfgPath, []byte(cfg.String()), 0644); err != nil {
+		err = fmt.Errorf("failed to create config file %s: %w", cfgPath, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (c *NAT64GatewayConfigurator) createTun(config NAT64Gateway) error {
+	cfgPath := taygaConfigPath(config.GatewayName)
+	out, err := namespacedCmd(MainNsName, taygaBinary, "-c", cfgPath, "--mktun").
+		CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to create NAT64 TUN interface: %v, output: %s", err, out)
+		log.Error(err)
+		return err
+	}
+	link, err := netlink.LinkByName(config.GatewayName)
+	if err != nil {
+		err = fmt.Errorf("failed to get NAT64 TUN interface %s: %w", config.GatewayName, err)
+		log.Error(err)
+		return err
+	}
+	if err = netlink.LinkSetUp(link); err != nil {
+		err = fmt.Errorf("failed to set NAT64 TUN interface %s UP: %w",
+			config.GatewayName, err)
+		log.Error(err)
+		return err
+	}
+	// Route translated traffic returning back from IPv4 hosts into tayga.
+	route := &netlink.Route{LinkIndex: link.Attrs().Index, Dst: config.IPv4Pool}
+	if err = netlink.RouteAdd(route); err != nil {
+		err = fmt.Errorf("failed to add route %+v: %w", route, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *NAT64GatewayConfigurator) Modify(ctx context.Context, oldItem, newItem depgraph.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete stops tayga and removes the TUN interface.
+func (c *NAT64GatewayConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	config := item.(NAT64Gateway)
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		name := config.GatewayName
+		err := stopProcess(taygaPidFile(name), taygaStopTimeout)
+		if err == nil {
+			// Route for the IPv4 pool is removed together with the interface.
+			// ignore errors from here
+			out, rmErr := namespacedCmd(MainNsName, taygaBinary,
+				"-c", taygaConfigPath(name), "--rmtun").CombinedOutput()
+			if rmErr != nil {
+				log.Errorf("failed to remove NAT64 TUN interface: %v, output: %s",
+					rmErr, out)
+			}
+			_ = os.Remove(taygaConfigPath(name))
+			_ = os.Remove(taygaPidFile(name))
+		}
+		done(err)
+	}()
+	return nil
+}
+
+// NeedsRecreate always returns true - Modify is not implemented.
+func (c *NAT64GatewayConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	return true
+}
+
+func taygaConfigPath(name string) string {
+	return filepath.Join(taygaConfDir, name+".conf")
+}
+
+func taygaPidFile(name string) string {
+	return filepath.Join(taygaRunDir, name+".pid")
+}
+
+func startTayga(name string) error {
+	if err := ensureDir(taygaRunDir); err != nil {
+		return err
+	}
+	args := []string{
+		"-c", taygaConfigPath(name),
+		"-p", taygaPidFile(name),
+	}
+	// Do not run in background - tayga will detach itself.
+	return startProcess(MainNsName, taygaBinary, args, taygaPidFile(name),
+		taygaStartTimeout, false)
+}
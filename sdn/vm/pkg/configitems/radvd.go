@@ -0,0 +1,276 @@
+package configitems
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eve/libs/depgraph"
+	"github.com/lf-edge/eve/libs/reconciler"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	radvdBinary       = "/usr/sbin/radvd"
+	radvdConfDir      = "/etc/radvd"
+	radvdRunDir       = "/run/radvd"
+	radvdStartTimeout = 3 * time.Second
+	radvdStopTimeout  = 10 * time.Second
+)
+
+// RouterAdvertiser : sends IPv6 Router Advertisements (using radvd).
+type RouterAdvertiser struct {
+	// AdvertiserName : logical name for the router advertiser.
+	AdvertiserName string
+	// NetNamespace : network namespace where radvd should be running.
+	NetNamespace string
+	// VethName : logical name of the veth pair on which RAs are sent.
+	// (other types of interfaces are currently not supported)
+	VethName string
+	// VethPeerIfName : interface name of that side of the veth pair from which
+	// RAs should be sent. It should be inside NetNamespace.
+	VethPeerIfName string
+	// Prefix : IPv6 prefix to advertise.
+	Prefix *net.IPNet
+	// ManagedFlag : M flag (addresses are available via DHCPv6).
+	ManagedFlag bool
+	// OtherFlag : O flag (other configuration is available via DHCPv6).
+	OtherFlag bool
+	// AutonomousFlag : A flag of the advertised prefix (SLAAC enabled).
+	AutonomousFlag bool
+	// Interval : maximum time between unsolicited RAs in seconds.
+	// Zero means to use the radvd default.
+	Interval uint32
+	// RouterLifetime : lifetime of the default route in seconds.
+	// Zero means to use the radvd default (unless NotDefaultRouter is true).
+	RouterLifetime uint32
+	// NotDefaultRouter : advertise zero router lifetime.
+	NotDefaultRouter bool
+	// ValidLifetime : valid lifetime of the prefix in seconds.
+	// Zero means to use the radvd default.
+	ValidLifetime uint32
+	// PreferredLifetime : preferred lifetime of the prefix in seconds.
+	// Zero means to use the radvd default.
+	PreferredLifetime uint32
+	// MTU : link MTU to advertise. Zero means to not advertise MTU.
+	MTU uint16
+	// RDNSS : DNS servers to advertise (RFC 8106).
+	RDNSS []net.IP
+	// DNSSL : DNS search list to advertise (RFC 8106).
+	DNSSL []string
+	// NAT64Prefix : NAT64 prefix to advertise using the PREF64 option (RFC 8781).
+	// Leave nil to not advertise.
+	NAT64Prefix *net.IPNet
+}
+
+// Name
+func (r RouterAdvertiser) Name() string {
+	return r.AdvertiserName
+}
+
+// Label
+func (r RouterAdvertiser) Label() string {
+	return r.AdvertiserName + " (router advertiser)"
+}
+
+// Type
+func (r RouterAdvertiser) Type() string {
+	return RouterAdvertiserTypename
+}
+
+// Equal is a comparison method for two equally-named RouterAdvertiser instances.
+func (r RouterAdvertiser) Equal(other depgraph.Item) bool {
+	r2 := other.(RouterAdvertiser)
+	return r.NetNamespace == r2.NetNamespace &&
+		r.VethName == r2.VethName &&
+		r.VethPeerIfName == r2.VethPeerIfName &&
+		equalIPNets(r.Prefix, r2.Prefix) &&
+		r.ManagedFlag == r2.ManagedFlag &&
+		r.OtherFlag == r2.OtherFlag &&
+		r.AutonomousFlag == r2.AutonomousFlag &&
+		r.Interval == r2.Interval &&
+		r.RouterLifetime == r2.RouterLifetime &&
+		r.NotDefaultRouter == r2.NotDefaultRouter &&
+		r.ValidLifetime == r2.ValidLifetime &&
+		r.PreferredLifetime == r2.PreferredLifetime &&
+		r.MTU == r2.MTU &&
+		equalIPLists(r.RDNSS, r2.RDNSS) &&
+		strings.Join(r.DNSSL, " ") == strings.Join(r2.DNSSL, " ") &&
+		equalIPNets(r.NAT64Prefix, r2.NAT64Prefix)
+}
+
+// External returns false.
+func (r RouterAdvertiser) External() bool {
+	return false
+}
+
+// String describes the router advertiser config.
+func (r RouterAdvertiser) String() string {
+	return fmt.Sprintf("Router Advertiser: %#+v", r)
+}
+
+// Dependencies lists the veth and network namespace as dependencies.
+func (r RouterAdvertiser) Dependencies() (deps []depgraph.Dependency) {
+	return []depgraph.Dependency{
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: NetNamespaceTypename,
+				ItemName: normNetNsName(r.NetNamespace),
+			},
+			Description: "Network namespace must exist",
+		},
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: VethTypename,
+				ItemName: r.VethName,
+			},
+			Description: "veth interface must exist",
+		},
+	}
+}
+
+// RouterAdvertiserConfigurator implements Configurator interface for RouterAdvertiser.
+type RouterAdvertiserConfigurator struct{}
+
+// Create starts radvd.
+func (c *RouterAdvertiserConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	config := item.(RouterAdvertiser)
+	if err := c.createRadvdConfFile(config); err != nil {
+		return err
+	}
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := startRadvd(config.AdvertiserName, config.NetNamespace)
+		done(err)
+	}()
+	return nil
+}
+
+func (c *RouterAdvertiserConfigurator) createRadvdConfFile(config RouterAdvertiser) error {
+	if err := ensureDir(radvdConfDir); err != nil {
+		return err
+	}
+	onOff := func(flag bool) string {
+		if flag {
+			return "on"
+		}
+		return "off"
+	}
+	var cfg strings.Builder
+	cfg.WriteString(fmt.Sprintf("interface %s {\n", config.VethPeerIfName))
+	cfg.WriteString("\tAdvSendAdvert on;\n")
+	if config.Interval != 0 {
+		cfg.WriteString(fmt.Sprintf("\tMaxRtrAdvInterval %d;\n", config.Interval))
+	}
+	cfg.WriteString(fmt.Sprintf("\tAdvManagedFlag %s;\n", onOff(config.ManagedFlag)))
+	cfg.WriteString(fmt.Sprintf("\tAdvOtherConfigFlag %s;\n", onOff(config.OtherFlag)))
+	if config.NotDefaultRouter {
+		cfg.WriteString("\tAdvDefaultLifetime 0;\n")
+	} else if config.RouterLifetime != 0 {
+		cfg.WriteString(fmt.Sprintf("\tAdvDefaultLifetime %d;\n", config.RouterLifetime))
+	}
+	if config.MTU != 0 {
+		cfg.WriteString(fmt.Sprintf("\tAdvLinkMTU %d;\n", config.MTU))
+	}
+	cfg.WriteString(fmt.Sprintf("\tprefix %s {\n", config.Prefix))
+	cfg.WriteString("\t\tAdvOnLink on;\n")
+	cfg.WriteString(fmt.Sprintf("\t\tAdvAutonomous %s;\n", onOff(config.AutonomousFlag)))
+	if config.ValidLifetime != 0 {
+		cfg.WriteString(fmt.Sprintf("\t\tAdvValidLifetime %d;\n", config.ValidLifetime))
+	}
+	if config.PreferredLifetime != 0 {
+		cfg.WriteString(fmt.Sprintf("\t\tAdvPreferredLifetime %d;\n",
+			config.PreferredLifetime))
+	}
+	cfg.WriteString("\t};\n")
+	if len(config.RDNSS) > 0 {
+		var servers []string
+		for _, server := range config.RDNSS {
+			servers = append(servers, server.String())
+		}
+		cfg.WriteString(fmt.Sprintf("\tRDNSS %s {\n\t};\n", strings.Join(servers, " ")))
+	}
+	if len(config.DNSSL) > 0 {
+		cfg.WriteString(fmt.Sprintf("\tDNSSL %s {\n\t};\n", strings.Join(config.DNSSL, " ")))
+	}
+	if config.NAT64Prefix != nil {
+		cfg.WriteString(fmt.Sprintf("\tnat64prefix %s {\n\t};\n", config.NAT64Prefix))
+	}
+	cfg.WriteString("};\n")
+	cfgPath := radvdConfigPath(config.AdvertiserName)
+	if err := os.WriteFile(cfgPath, []byte(cfg.String()), 0644); err != nil {
+		err = fmt.Errorf("failed to create config file %s: %w", cfgPath, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *RouterAdvertiserConfigurator) Modify(ctx context.Context, oldItem, newItem depgraph.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete stops radvd.
+func (c *RouterAdvertiserConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	config := item.(RouterAdvertiser)
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		name := config.AdvertiserName
+		err := stopProcess(radvdPidFile(name), radvdStopTimeout)
+		if err == nil {
+			// ignore errors from here
+			_ = removeRadvdFile(radvdConfigPath(name))
+			_ = removeRadvdFile(radvdLogFile(name))
+			_ = removeRadvdFile(radvdPidFile(name))
+		}
+		done(err)
+	}()
+	return nil
+}
+
+// NeedsRecreate always returns true - Modify is not implemented.
+func (c *RouterAdvertiserConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	return true
+}
+
+func radvdConfigPath(name string) string {
+	return filepath.Join(radvdConfDir, name+".conf")
+}
+
+func radvdPidFile(name string) string {
+	return filepath.Join(radvdRunDir, name+".pid")
+}
+
+func radvdLogFile(name string) string {
+	return filepath.Join(radvdRunDir, name+".log")
+}
+
+func removeRadvdFile(path string) error {
+	if err := os.Remove(path); err != nil {
+		err = fmt.Errorf("failed to remove radvd file %s: %w", path, err)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func startRadvd(name, netNamespace string) error {
+	if err := ensureDir(radvdRunDir); err != nil {
+		return err
+	}
+	args := []string{
+		"-C", radvdConfigPath(name),
+		"-p", radvdPidFile(name),
+		"-m", "logfile",
+		"-l", radvdLogFile(name),
+	}
+	// Do not run in background - radvd will detach itself.
+	return startProcess(netNamespace, radvdBinary, args, radvdPidFile(name),
+		radvdStartTimeout, false)
+}
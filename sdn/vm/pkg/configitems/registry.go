@@ -18,6 +18,8 @@ func RegisterItems(
 		{c: &IfHandleConfigurator{MacLookup: macLookup}, t: IfHandleTypename},
 		{c: &DhcpClientConfigurator{MacLookup: macLookup}, t: DhcpClientTypename},
 		{c: &DhcpServerConfigurator{}, t: DhcpServerTypename},
+		{c: &Dhcpv6ServerConfigurator{}, t: Dhcpv6ServerTypename},
+		{c: &RouterAdvertiserConfigurator{}, t: RouterAdvertiserTypename},
 		{c: &DnsServerConfigurator{}, t: DnsServerTypename},
 		{c: &BondConfigurator{MacLookup: macLookup}, t: BondTypename},
 		{c: &BridgeConfigurator{MacLookup: macLookup}, t: BridgeTypename},
@@ -26,8 +28,12 @@ func RegisterItems(
 		{c: &IPRuleConfigurator{}, t: IPRuleTypename},
 		{c: &IptablesChainConfigurator{}, t: IPtablesChainTypename},
 		{c: &IptablesChainConfigurator{}, t: IP6tablesChainTypename},
+		{c: &IPSetConfigurator{}, t: IPSetTypename},
+		{c: &NAT64GatewayConfigurator{}, t: NAT64GatewayTypename},
 		{c: &HttpProxyConfigurator{}, t: HTTPProxyTypename},
 		{c: &HttpServerConfigurator{}, t: HTTPServerTypename},
+		{c: &CaptivePortalConfigurator{}, t: CaptivePortalTypename},
+		{c: &LinkImpairmentConfigurator{MacLookup: macLookup}, t: LinkImpairmentTypename},
 	}
 	for _, configurator := range configurators {
 		err := registry.Register(configurator.c, configurator.t)
@@ -35,17 +35,22 @@ type Route struct {
 	Metric uint32
 }
 
-// RouteOutIf : output interface for the route - either veth or physical interface.
+// RouteOutIf : output interface for the route - either veth, physical interface
+// or TUN interface of a NAT64 gateway.
 type RouteOutIf struct {
 	// VethName : logical name of the veth pair used as the output device for the route.
-	// Define either PhysIf or VethName + VethPeerIfName.
+	// Define either PhysIf, NAT64Gateway or VethName + VethPeerIfName.
 	VethName string
 	// VethPeerIfName : interface name of that side of the veth pair which the routed
 	// traffic is entering.
 	VethPeerIfName string
 	// PhysIf : physical interface to use as the output device.
-	// Define either PhysIf or VethName + VethPeerIfName.
+	// Define either PhysIf, NAT64Gateway or VethName + VethPeerIfName.
 	PhysIf PhysIf
+	// NAT64Gateway : name of the NAT64 gateway whose TUN interface should be used
+	// as the output device.
+	// Define either PhysIf, NAT64Gateway or VethName + VethPeerIfName.
+	NAT64Gateway string
 }
 
 // Name
@@ -75,7 +80,7 @@ func (r Route) outputIfRef() string {
 	if len(r.OutputIf.PhysIf.MAC) > 0 {
 		return r.OutputIf.PhysIf.MAC.String()
 	}
-	return ""
+	return r.OutputIf.NAT64Gateway
 }
 
 // Type
@@ -132,6 +137,14 @@ func (r Route) Dependencies() (deps []depgraph.Dependency) {
 			},
 			Description: "Physical network interface must exist and be used in the L3 mode",
 		})
+	} else if r.OutputIf.NAT64Gateway != "" {
+		deps = append(deps, depgraph.Dependency{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: NAT64GatewayTypename,
+				ItemName: r.OutputIf.NAT64Gateway,
+			},
+			Description: "NAT64 gateway must exist",
+		})
 	}
 	return deps
 }
@@ -190,6 +203,14 @@ func (c *RouteConfigurator) buildNetlinkRoute(route Route) (*netlink.Route, erro
 			return nil, fmt.Errorf("failed to get physical interface with MAC %v", mac)
 		}
 		outLinkIndex = netIf.IfIndex
+	} else if route.OutputIf.NAT64Gateway != "" {
+		// TUN interface is named after the gateway.
+		ifName := route.OutputIf.NAT64Gateway
+		link, err := netlink.LinkByName(ifName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get link for NAT64 gateway %s: %w", ifName, err)
+		}
+		outLinkIndex = link.Attrs().Index
 	} else {
 		routeType = unix.RTN_UNREACHABLE
 	}
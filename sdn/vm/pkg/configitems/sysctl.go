@@ -12,6 +12,7 @@ const (
 	ipv6ForwardingKey  = "net.ipv6.conf.all.forwarding"
 	bridgeIptablesKey  = "net.bridge.bridge-nf-call-iptables"
 	bridgeIp6tablesKey = "net.bridge.bridge-nf-call-ip6tables"
+	bridgeSysctlDir    = "/proc/sys/net/bridge"
 )
 
 // Sysctl : item representing kernel parameters set using sysctl.
@@ -123,6 +124,10 @@ func (c *SysctlConfigurator) setIPForwarding(netNs string, v4, v6 bool) error {
 }
 
 func (c *SysctlConfigurator) setBridgeIptables(netNs string, v4, v6 bool) error {
+	if err := namespacedCmd(netNs, "test", "-d", bridgeSysctlDir).Run(); err != nil {
+		// br_netfilter is not enabled for this namespace, nothing to configure.
+		return nil
+	}
 	sysctlKV := fmt.Sprintf("%s=%s", bridgeIptablesKey, c.boolValueToStr(v4))
 	out, err := namespacedCmd(netNs, "sysctl", "-w", sysctlKV).CombinedOutput()
 	if err != nil {
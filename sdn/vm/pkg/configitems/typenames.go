@@ -22,6 +22,10 @@ const (
 	DhcpClientTypename = "DHCP-Client"
 	// DhcpServerTypename : typename for DHCP/DHCPv6 server.
 	DhcpServerTypename = "DHCP-Server"
+	// Dhcpv6ServerTypename : typename for DHCPv6 server (stateful, stateless and PD).
+	Dhcpv6ServerTypename = "DHCPv6-Server"
+	// RouterAdvertiserTypename : typename for IPv6 router advertisement daemon.
+	RouterAdvertiserTypename = "Router-Advertiser"
 	// DnsServerTypename : typename for DNS server.
 	DnsServerTypename = "DNS-Server"
 	// RouteTypename : typename for IP route.
@@ -32,8 +36,16 @@ const (
 	IPtablesChainTypename = "Iptables-Chain"
 	// IP6tablesChainTypename : typename for a single ip6tables chain (IPv6).
 	IP6tablesChainTypename = "Ip6tables-Chain"
+	// IPSetTypename : typename for a set of IP addresses matched by iptables rules.
+	IPSetTypename = "IP-Set"
+	// NAT64GatewayTypename : typename for NAT64 gateway.
+	NAT64GatewayTypename = "NAT64-Gateway"
 	// HTTPProxyTypename : typename for HTTP proxy.
 	HTTPProxyTypename = "HTTP-Proxy"
 	// HTTPServerTypename : typename for HTTP server.
 	HTTPServerTypename = "HTTP-Server"
+	// CaptivePortalTypename : typename for captive portal.
+	CaptivePortalTypename = "Captive-Portal"
+	// LinkImpairmentTypename : typename for emulated link impairment.
+	LinkImpairmentTypename = "Link-Impairment"
 )
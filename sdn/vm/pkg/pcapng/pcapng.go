@@ -0,0 +1,219 @@
+// Package pcapng converts packets captured in the libpcap format (as written
+// by "tcpdump -w") into the pcapng format.
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	pcapMagicMicrosec = 0xa1b2c3d4
+	pcapMagicNanosec  = 0xa1b23c4d
+	pcapHeaderLen     = 24
+	pcapRecordLen     = 16
+
+	blockTypeSHB   = 0x0A0D0D0A
+	blockTypeIDB   = 0x00000001
+	blockTypeEPB   = 0x00000006
+	byteOrderMagic = 0x1A2B3C4D
+
+	optEndOfOpt     = 0
+	optSHBUserAppl  = 4
+	optIfName       = 2
+	optIfDesc       = 3
+	optIfTsResol    = 9
+	optIfFilter     = 11
+	tsResolNanosec  = 9
+	maxPacketLength = 256 * 1024
+)
+
+// Packet : single captured packet.
+type Packet struct {
+	// Timestamp : time when the packet was captured.
+	Timestamp time.Time
+	// OrigLen : length of the packet as it was on the wire.
+	OrigLen uint32
+	// Data : captured packet bytes (possibly truncated to the snapshot length).
+	Data []byte
+}
+
+// PcapReader : reads packets stored in the libpcap format.
+type PcapReader struct {
+	r       io.Reader
+	order   binary.ByteOrder
+	nanosec bool
+	// LinkType : link-layer header type of captured packets.
+	LinkType uint16
+	// SnapLen : maximum number of captured bytes per packet.
+	SnapLen uint32
+}
+
+// NewPcapReader reads the libpcap file header and returns reader for packets
+// that follow.
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	header := make([]byte, pcapHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read pcap header: %w", err)
+	}
+	reader := &PcapReader{r: r}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header[0:4]) {
+		case pcapMagicMicrosec:
+			reader.order = order
+		case pcapMagicNanosec:
+			reader.order = order
+			reader.nanosec = true
+		}
+		if reader.order != nil {
+			break
+		}
+	}
+	if reader.order == nil {
+		return nil, errors.New("unrecognized pcap magic number")
+	}
+	reader.SnapLen = reader.order.Uint32(header[16:20])
+	// Upper bits of the link type field may carry FCS information.
+	reader.LinkType = uint16(reader.order.Uint32(header[20:24]))
+	return reader, nil
+}
+
+// ReadPacket reads the next packet. Returns io.EOF when there are no more packets.
+func (r *PcapReader) ReadPacket() (packet Packet, err error) {
+	record := make([]byte, pcapRecordLen)
+	if _, err = io.ReadFull(r.r, record); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return packet, err
+	}
+	sec := r.order.Uint32(record[0:4])
+	frac := r.order.Uint32(record[4:8])
+	if !r.nanosec {
+		frac *= 1000
+	}
+	capLen := r.order.Uint32(record[8:12])
+	if capLen > maxPacketLength {
+		return packet, fmt.Errorf("invalid captured packet length: %d", capLen)
+	}
+	packet.Timestamp = time.Unix(int64(sec), int64(frac))
+	packet.OrigLen = r.order.Uint32(record[12:16])
+	packet.Data = make([]byte, capLen)
+	if _, err = io.ReadFull(r.r, packet.Data); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return packet, err
+	}
+	return packet, nil
+}
+
+// Interface : description of the interface on which packets were captured.
+type Interface struct {
+	// Name : interface name.
+	Name string
+	// Description : optional interface description.
+	Description string
+	// Filter : capture filter (in the libpcap syntax) applied on the interface.
+	Filter string
+	// LinkType : link-layer header type of captured packets.
+	LinkType uint16
+	// SnapLen : maximum number of captured bytes per packet.
+	SnapLen uint32
+}
+
+// Writer : writes packets in the pcapng format.
+// Only a single section with a single interface is supported.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the pcapng section header and the interface description
+// and returns writer for packets captured on that interface.
+func NewWriter(w io.Writer, userAppl string, intf Interface) (*Writer, error) {
+	var shb bytes.Buffer
+	writeUint32(&shb, byteOrderMagic)
+	writeUint16(&shb, 1) // major version
+	writeUint16(&shb, 0) // minor version
+	writeUint32(&shb, 0xFFFFFFFF)
+	writeUint32(&shb, 0xFFFFFFFF) // section length is not specified
+	if userAppl != "" {
+		writeOption(&shb, optSHBUserAppl, []byte(userAppl))
+	}
+	writeOption(&shb, optEndOfOpt, nil)
+	if err := writeBlock(w, blockTypeSHB, shb.Bytes()); err != nil {
+		return nil, err
+	}
+	var idb bytes.Buffer
+	writeUint16(&idb, intf.LinkType)
+	writeUint16(&idb, 0) // reserved
+	writeUint32(&idb, intf.SnapLen)
+	if intf.Name != "" {
+		writeOption(&idb, optIfName, []byte(intf.Name))
+	}
+	if intf.Description != "" {
+		writeOption(&idb, optIfDesc, []byte(intf.Description))
+	}
+	writeOption(&idb, optIfTsResol, []byte{tsResolNanosec})
+	if intf.Filter != "" {
+		// The first byte 0 stands for the libpcap filter string.
+		writeOption(&idb, optIfFilter, append([]byte{0}, intf.Filter...))
+	}
+	writeOption(&idb, optEndOfOpt, nil)
+	if err := writeBlock(w, blockTypeIDB, idb.Bytes()); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// WritePacket writes packet as an Enhanced Packet Block.
+func (w *Writer) WritePacket(packet Packet) error {
+	var epb bytes.Buffer
+	ts := uint64(packet.Timestamp.UnixNano())
+	writeUint32(&epb, 0) // interface ID
+	writeUint32(&epb, uint32(ts>>32))
+	writeUint32(&epb, uint32(ts))
+	writeUint32(&epb, uint32(len(packet.Data)))
+	writeUint32(&epb, packet.OrigLen)
+	epb.Write(packet.Data)
+	epb.Write(padding(len(packet.Data)))
+	return writeBlock(w.w, blockTypeEPB, epb.Bytes())
+}
+
+func writeBlock(w io.Writer, blockType uint32, body []byte) error {
+	var block bytes.Buffer
+	totalLen := uint32(12 + len(body))
+	writeUint32(&block, blockType)
+	writeUint32(&block, totalLen)
+	block.Write(body)
+	writeUint32(&block, totalLen)
+	_, err := w.Write(block.Bytes())
+	return err
+}
+
+func writeOption(buf *bytes.Buffer, code uint16, value []byte) {
+	writeUint16(buf, code)
+	writeUint16(buf, uint16(len(value)))
+	buf.Write(value)
+	buf.Write(padding(len(value)))
+}
+
+func padding(length int) []byte {
+	return make([]byte, (4-length%4)%4)
+}
+
+func writeUint16(buf *bytes.Buffer, value uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], value)
+	buf.Write(b[:])
+}
+
+func writeUint32(buf *bytes.Buffer, value uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], value)
+	buf.Write(b[:])
+}
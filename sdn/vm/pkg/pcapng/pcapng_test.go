@@ -0,0 +1,199 @@
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// pcapFile returns packets in the libpcap format
+func pcapFile(order binary.ByteOrder, magic uint32, packets []Packet) []byte {
+	var buf bytes.Buffer
+	write := func(values ...interface{}) {
+		for _, value := range values {
+			_ = binary.Write(&buf, order, value)
+		}
+	}
+	write(magic, uint16(2), uint16(4), int32(0), uint32(0), uint32(65535), uint32(1))
+	for _, packet := range packets {
+		frac := uint32(packet.Timestamp.Nanosecond())
+		if magic == pcapMagicMicrosec {
+			frac /= 1000
+		}
+		write(uint32(packet.Timestamp.Unix()), frac, uint32(len(packet.Data)), packet.OrigLen)
+		buf.Write(packet.Data)
+	}
+	return buf.Bytes()
+}
+
+var testPackets = []Packet{
+	{Timestamp: time.Unix(1700000000, 123456000), OrigLen: 5, Data: []byte{1, 2, 3, 4, 5}},
+	{Timestamp: time.Unix(1700000001, 0), OrigLen: 1500, Data: []byte{6, 7, 8, 9}},
+}
+
+func readAll(t *testing.T, reader *PcapReader) []Packet {
+	t.Helper()
+	var packets []Packet
+	for {
+		packet, err := reader.ReadPacket()
+		if err == io.EOF {
+			return packets
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, packet)
+	}
+}
+
+func TestPcapReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		order binary.ByteOrder
+		magic uint32
+	}{
+		{name: "little endian microseconds", order: binary.LittleEndian, magic: pcapMagicMicrosec},
+		{name: "big endian nanoseconds", order: binary.BigEndian, magic: pcapMagicNanosec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewPcapReader(bytes.NewReader(pcapFile(tt.order, tt.magic, testPackets)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reader.LinkType != 1 || reader.SnapLen != 65535 {
+				t.Errorf("link type %d, snaplen %d", reader.LinkType, reader.SnapLen)
+			}
+			packets := readAll(t, reader)
+			if len(packets) != len(testPackets) {
+				t.Fatalf("read %d packets, expected %d", len(packets), len(testPackets))
+			}
+			for i := range packets {
+				if !packets[i].Timestamp.Equal(testPackets[i].Timestamp) || packets[i].OrigLen != testPackets[i].OrigLen ||
+					!bytes.Equal(packets[i].Data, testPackets[i].Data) {
+					t.Errorf("packet %d: %+v, expected %+v", i, packets[i], testPackets[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPcapReaderErrors(t *testing.T) {
+	if _, err := NewPcapReader(bytes.NewReader(make([]byte, pcapHeaderLen))); err == nil {
+		t.Error("expected error for unknown magic")
+	}
+	if _, err := NewPcapReader(bytes.NewReader([]byte{0xd4, 0xc3})); err == nil {
+		t.Error("expected error for short header")
+	}
+	// truncated packet is reported as the end of capture
+	data := pcapFile(binary.LittleEndian, pcapMagicMicrosec, testPackets[:1])
+	reader, err := NewPcapReader(bytes.NewReader(data[:len(data)-2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reader.ReadPacket(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+// block is pcapng block parsed by tests
+type block struct {
+	blockType uint32
+	body      []byte
+}
+
+func parseBlocks(t *testing.T, data []byte) []block {
+	t.Helper()
+	var blocks []block
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("truncated block: %v", data)
+		}
+		length := binary.LittleEndian.Uint32(data[4:8])
+		if length%4 != 0 || int(length) > len(data) || binary.LittleEndian.Uint32(data[length-4:length]) != length {
+			t.Fatalf("invalid block length %d", length)
+		}
+		blocks = append(blocks, block{blockType: binary.LittleEndian.Uint32(data[0:4]), body: data[8 : length-4]})
+		data = data[length:]
+	}
+	return blocks
+}
+
+// parseOptions returns options of block body by code
+func parseOptions(t *testing.T, data []byte) map[uint16][]byte {
+	t.Helper()
+	options := map[uint16][]byte{}
+	for len(data) >= 4 {
+		code := binary.LittleEndian.Uint16(data[0:2])
+		length := int(binary.LittleEndian.Uint16(data[2:4]))
+		if code == optEndOfOpt {
+			return options
+		}
+		options[code] = data[4 : 4+length]
+		data = data[4+length+len(padding(length)):]
+	}
+	t.Fatal("missing end of options")
+	return nil
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	reader, err := NewPcapReader(bytes.NewReader(pcapFile(binary.LittleEndian, pcapMagicMicrosec, testPackets)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	intf := Interface{Name: "eth0", Description: "port eth0", Filter: "tcp port 80", LinkType: reader.LinkType, SnapLen: reader.SnapLen}
+	writer, err := NewWriter(&out, "eden-sdn", intf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, packet := range readAll(t, reader) {
+		if err = writer.WritePacket(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blocks := parseBlocks(t, out.Bytes())
+	if len(blocks) != 2+len(testPackets) {
+		t.Fatalf("%d blocks written", len(blocks))
+	}
+	shb := blocks[0]
+	if shb.blockType != blockTypeSHB || binary.LittleEndian.Uint32(shb.body[0:4]) != byteOrderMagic {
+		t.Errorf("invalid section header block %+v", shb)
+	}
+	if appl := parseOptions(t, shb.body[16:])[optSHBUserAppl]; string(appl) != "eden-sdn" {
+		t.Errorf("user application %q", appl)
+	}
+	idb := blocks[1]
+	if idb.blockType != blockTypeIDB || binary.LittleEndian.Uint16(idb.body[0:2]) != 1 ||
+		binary.LittleEndian.Uint32(idb.body[4:8]) != 65535 {
+		t.Errorf("invalid interface description block %+v", idb)
+	}
+	expectedOpts := map[uint16][]byte{
+		optIfName:    []byte("eth0"),
+		optIfDesc:    []byte("port eth0"),
+		optIfTsResol: {tsResolNanosec},
+		optIfFilter:  append([]byte{0}, "tcp port 80"...),
+	}
+	if opts := parseOptions(t, idb.body[8:]); !reflect.DeepEqual(opts, expectedOpts) {
+		t.Errorf("interface options %q, expected %q", opts, expectedOpts)
+	}
+	for i, epb := range blocks[2:] {
+		if epb.blockType != blockTypeEPB {
+			t.Fatalf("block %d is not enhanced packet block", i)
+		}
+		ts := uint64(binary.LittleEndian.Uint32(epb.body[4:8]))<<32 | uint64(binary.LittleEndian.Uint32(epb.body[8:12]))
+		capLen := binary.LittleEndian.Uint32(epb.body[12:16])
+		packet := Packet{
+			Timestamp: time.Unix(0, int64(ts)),
+			OrigLen:   binary.LittleEndian.Uint32(epb.body[16:20]),
+			Data:      epb.body[20 : 20+capLen],
+		}
+		if !packet.Timestamp.Equal(testPackets[i].Timestamp) || packet.OrigLen != testPackets[i].OrigLen ||
+			!bytes.Equal(packet.Data, testPackets[i].Data) {
+			t.Errorf("packet %d: %+v, expected %+v", i, packet, testPackets[i])
+		}
+	}
+}
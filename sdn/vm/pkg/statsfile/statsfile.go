@@ -0,0 +1,71 @@
+// Package statsfile publishes statistics collected by SDN helper processes
+// (goproxy, dnsfaultproxy, httpsrv) into a JSON file read by the SDN agent.
+package statsfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PublishPeriod : how often changed statistics are written into the file.
+const PublishPeriod = time.Second
+
+// Publisher : embed into a statistics collector to have the collected stats
+// periodically published. The embedded mutex guards both the changed flag
+// and the stats of the collector.
+type Publisher struct {
+	sync.Mutex
+	changed bool
+}
+
+// SetChanged : mark statistics as changed. Must be called with the lock held.
+func (p *Publisher) SetChanged() {
+	p.changed = true
+}
+
+// Publish : periodically write statistics returned by getStats into statsFile
+// (if they have changed). getStats is called with the lock held.
+// Description of the statistics is used in log messages. Never returns.
+func (p *Publisher) Publish(statsFile, description string, getStats func() interface{}) {
+	// Publish (empty) stats immediately to let the SDN agent know that they are available.
+	p.Lock()
+	p.changed = true
+	p.Unlock()
+	for {
+		if err := p.WriteIfChanged(statsFile, getStats); err != nil {
+			log.Errorf("Failed to publish %s: %v", description, err)
+		}
+		time.Sleep(PublishPeriod)
+	}
+}
+
+// WriteIfChanged : write statistics returned by getStats into statsFile
+// if they have changed since the last write.
+func (p *Publisher) WriteIfChanged(statsFile string, getStats func() interface{}) error {
+	p.Lock()
+	if !p.changed {
+		p.Unlock()
+		return nil
+	}
+	statsBytes, err := json.Marshal(getStats())
+	p.changed = false
+	p.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats: %w", err)
+	}
+	// Write to a temporary file first and rename to avoid readers
+	// seeing partially written content.
+	tmpFile := statsFile + ".tmp"
+	if err = os.WriteFile(tmpFile, statsBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write stats file %s: %w", tmpFile, err)
+	}
+	if err = os.Rename(tmpFile, statsFile); err != nil {
+		return fmt.Errorf("failed to rename stats file %s: %w", tmpFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package statsfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteIfChanged(t *testing.T) {
+	statsFile := filepath.Join(t.TempDir(), "stats.json")
+	stats := map[string]int{"requests": 1}
+	getStats := func() interface{} { return stats }
+	var p Publisher
+
+	// Nothing has changed yet.
+	if err := p.WriteIfChanged(statsFile, getStats); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(statsFile); !os.IsNotExist(err) {
+		t.Fatalf("stats file should not exist yet: %v", err)
+	}
+
+	p.Lock()
+	p.SetChanged()
+	p.Unlock()
+	if err := p.WriteIfChanged(statsFile, getStats); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(statsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `{"requests":1}` {
+		t.Errorf("unexpected stats file content: %s", content)
+	}
+	if _, err = os.Stat(statsFile + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file was not renamed: %v", err)
+	}
+
+	// Unchanged stats are not re-written.
+	stats["requests"] = 2
+	if err = p.WriteIfChanged(statsFile, getStats); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ = os.ReadFile(statsFile); string(content) != `{"requests":1}` {
+		t.Errorf("stats file was re-written without change: %s", content)
+	}
+
+	p.Lock()
+	p.SetChanged()
+	p.Unlock()
+	if err = p.WriteIfChanged(statsFile, getStats); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ = os.ReadFile(statsFile); string(content) != `{"requests":2}` {
+		t.Errorf("unexpected stats file content: %s", content)
+	}
+}